@@ -23,8 +23,11 @@ import (
 	"github.com/spf13/pflag"
 	"github.com/spf13/viper"
 
+	"github.com/banzaicloud/telescopes/internal/app/telescopes/operator"
 	"github.com/banzaicloud/telescopes/internal/platform/log"
 	"github.com/banzaicloud/telescopes/internal/platform/metrics"
+	"github.com/banzaicloud/telescopes/pkg/recommender"
+	"github.com/banzaicloud/telescopes/pkg/recommender/nodepools"
 )
 
 // configuration holds any kind of configuration that comes from the outside world and
@@ -48,6 +51,36 @@ type configuration struct {
 
 		DevMode bool
 
+		// EvenNodeCounts rounds up the node counts of the recommended worker node pools to even numbers
+		EvenNodeCounts bool
+
+		// NodePoolLabels are added to the recommended node pools, in key=value format
+		NodePoolLabels []string
+
+		// TenantsFile holds the tenants served by the deployment, the API is not multi-tenant if empty
+		TenantsFile string
+
+		// AdminTokens authenticate the calls of the admin and configuration endpoints if the API is multi-tenant
+		AdminTokens []string
+
+		// SigningKeyFile holds the PEM encoded RSA private key the recommendations are signed with, not signed if empty
+		SigningKeyFile string
+
+		// UsageWindow is the sliding window the usage of the service is tracked for, not tracked if zero
+		UsageWindow time.Duration
+
+		// QueueWorkers is the number of recommendations served at the same time, at least 2; not limited if zero
+		QueueWorkers int
+
+		// QueueBatchWorkers is the number of batch recommendations served at the same time, one less than the workers if zero
+		QueueBatchWorkers int
+
+		// QueueCapacity is the number of recommendations of a priority class that may wait for a worker
+		QueueCapacity int
+
+		// FaultInjection makes the admin API inject artificial latencies and failures into the cloud info calls
+		FaultInjection bool
+
 		// nolint: unused
 		Vault struct {
 			TokenSigningKey string
@@ -56,7 +89,96 @@ type configuration struct {
 
 	Cloudinfo struct {
 		Address string
+
+		// FixtureDir makes the application serve product data from fixture files instead of the cloud info service
+		FixtureDir string
+	}
+
+	Recommender struct {
+		// NodePoolConstraints overrides the node pool size limits of managed services
+		NodePoolConstraints map[string]nodepools.Constraints
+
+		// IncompatibleTypes overrides the instance types the node pools of managed services can't be launched with
+		IncompatibleTypes recommender.IncompatibleTypes
+
+		// Deprecations holds the instance types scheduled for deprecation per provider, marked in the recommendations
+		Deprecations recommender.Deprecations
+
+		// ProviderDefaults holds the request defaults per provider, merged into the cluster recommendation requests
+		ProviderDefaults map[string]recommender.Profile
+
+		// NodeCosts holds the hourly costs per node by provider and cost name, added to the prices of the requests including them
+		NodeCosts recommender.NodeCosts
+
+		// SpotEnabled tells per provider whether spot instances may be recommended, enabled for the providers not listed
+		SpotEnabled map[string]bool
+
+		// BenchmarksFile is the path of the JSON file holding benchmark scores per provider and instance type
+		BenchmarksFile string
+
+		// MaxPodsFile is the path of the JSON file holding the max pods per provider and instance type
+		MaxPodsFile string
+
+		// AfterburnerTTL is the time the candidate vms of scale out layouts are reused for, the fast path is disabled if zero
+		AfterburnerTTL time.Duration
+
+		// MetadataTTL is the time the providers, services, regions and zones served as metadata are reused for, not cached if zero
+		MetadataTTL time.Duration
+
+		// CallBudget is the maximum number of calls a recommendation may make to the cloud info service, unlimited if zero
+		CallBudget int
+
+		// PriceHistoryAddress is the address of the Prometheus server holding the spot price history, backtesting is disabled if empty
+		PriceHistoryAddress string
+
+		// PriceHistoryQuery is the Prometheus query template of the spot price of an instance type
+		PriceHistoryQuery string
+
+		// InterruptionsAddress is the address of the Prometheus server holding the spot interruption events, disabled if empty
+		InterruptionsAddress string
+
+		// InterruptionsQuery is the Prometheus query template of the recent interruptions per instance type and zone
+		InterruptionsQuery string
+
+		// InterruptionsTTL is the time the heat of the capacity pools of a region is reused for
+		InterruptionsTTL time.Duration
+
+		// MinSpotCoverage is the minimum ratio of the zones of a vm having a spot price for the vm to take part in the spot pools
+		MinSpotCoverage float64
+
+		// MaxSpotPriceRatio is the ratio of the on-demand price a spot price is considered implausible from
+		MaxSpotPriceRatio float64
+
+		// DecisionLogFile is the file the decisions of the engine are appended to in JSON lines format, not recorded if empty
+		DecisionLogFile string
+
+		// SnapshotCapacity is the number of product snapshots kept for reproducing the recommendations, disabled if zero
+		SnapshotCapacity int
+
+		// SnapshotMaxBytes is the approximate memory the product snapshots may use, not limited if zero
+		SnapshotMaxBytes int64
+
+		// CanaryStrategy is the experimental strategy evaluated against the stable one, canary evaluation is disabled if empty
+		CanaryStrategy string
+
+		// CanarySamplePct is the percentage of the cluster recommendations repeated with the experimental strategy
+		CanarySamplePct int
+
+		// CatalogWatchInterval is the interval of checking the product catalogs of the regions for changes, disabled if zero
+		CatalogWatchInterval time.Duration
+
+		// CatalogPriceChangePct is the minimum price change in percent reported in the catalog change summaries
+		CatalogPriceChangePct float64
+
+		// CatalogWatchProviderIntervals overrides the catalog watch interval per provider [format=provider=duration,...]
+		CatalogWatchProviderIntervals []string
+
+		// CatalogWatchIdleTimeout is the time after which the regions without recommendations are not watched, never if zero
+		CatalogWatchIdleTimeout time.Duration
 	}
+
+	// Operator configuration
+	Operator operator.Config
 }
 
 // Configure configures some defaults in the Viper instance.
@@ -104,11 +226,189 @@ func Configure(v *viper.Viper, p *pflag.FlagSet) {
 	_ = v.BindPFlag("cloudinfo.address", p.Lookup("cloudinfo-address"))
 	_ = v.BindEnv("cloudinfo.address", "CLOUDINFO_ADDRESS")
 
+	p.String("fixture-dir", "", "directory of product fixtures to serve recommendations from instead of the Cloud Info "+
+		"service, for demos and integration tests [layout=provider/service/region.json]")
+	_ = v.BindPFlag("cloudinfo.fixturedir", p.Lookup("fixture-dir"))
+	_ = v.BindEnv("cloudinfo.fixturedir", "FIXTURE_DIR")
+
+	p.String("benchmarks-file", "", "JSON file with benchmark scores per provider and instance type, "+
+		"enables optimizing for price per benchmark unit")
+	_ = v.BindPFlag("recommender.benchmarksfile", p.Lookup("benchmarks-file"))
+	_ = v.BindEnv("recommender.benchmarksfile", "BENCHMARKS_FILE")
+
+	p.String("max-pods-file", "", "JSON file with the maximum number of pods per provider and instance type, "+
+		"defaults to 110 pods per node")
+	_ = v.BindPFlag("recommender.maxpodsfile", p.Lookup("max-pods-file"))
+	_ = v.BindEnv("recommender.maxpodsfile", "MAX_PODS_FILE")
+
+	p.Duration("afterburner-ttl", 0, "time the candidate vms of a scale out layout are reused for by repeated scale out "+
+		"recommendations, spot prices may be this old; disabled if zero")
+	_ = v.BindPFlag("recommender.afterburnerttl", p.Lookup("afterburner-ttl"))
+	_ = v.BindEnv("recommender.afterburnerttl", "AFTERBURNER_TTL")
+
+	p.Duration("metadata-ttl", time.Hour, "time the providers, services, regions and zones served on "+
+		"/api/v1/recommender/metadata are reused for, they are retrieved on every call if zero")
+	_ = v.BindPFlag("recommender.metadatattl", p.Lookup("metadata-ttl"))
+	_ = v.BindEnv("recommender.metadatattl", "METADATA_TTL")
+
+	p.Int("cloudinfo-call-budget", 0, "maximum number of calls a single recommendation may make to the Cloud Info "+
+		"service, recommendations exceeding it are aborted; unlimited if zero")
+	_ = v.BindPFlag("recommender.callbudget", p.Lookup("cloudinfo-call-budget"))
+	_ = v.BindEnv("recommender.callbudget", "CLOUDINFO_CALL_BUDGET")
+
+	p.String("price-history-address", "", "address of the Prometheus server holding the spot prices exported by "+
+		"the Cloud Info service, enables backtesting layouts [format=scheme://host:port]")
+	_ = v.BindPFlag("recommender.pricehistoryaddress", p.Lookup("price-history-address"))
+	_ = v.BindEnv("recommender.pricehistoryaddress", "PRICE_HISTORY_ADDRESS")
+
+	p.String("price-history-query", recommender.DefaultSpotPriceQuery, "Prometheus query template of the spot price "+
+		"of an instance type, gets the Provider, Service, Region and Type")
+	_ = v.BindPFlag("recommender.pricehistoryquery", p.Lookup("price-history-query"))
+	_ = v.BindEnv("recommender.pricehistoryquery", "PRICE_HISTORY_QUERY")
+
+	p.String("interruptions-address", "", "address of the Prometheus server holding the spot interruption events "+
+		"(eg. from Hollowtrees or CloudWatch), enables avoiding recently interrupted capacity [format=scheme://host:port]")
+	_ = v.BindPFlag("recommender.interruptionsaddress", p.Lookup("interruptions-address"))
+	_ = v.BindEnv("recommender.interruptionsaddress", "INTERRUPTIONS_ADDRESS")
+
+	p.String("interruptions-query", recommender.DefaultInterruptionQuery, "Prometheus query template of the recent "+
+		"interruptions by type and zone, gets the Provider, Service and Region")
+	_ = v.BindPFlag("recommender.interruptionsquery", p.Lookup("interruptions-query"))
+	_ = v.BindEnv("recommender.interruptionsquery", "INTERRUPTIONS_QUERY")
+
+	p.Duration("interruptions-ttl", 5*time.Minute, "time the recent interruptions of a region are reused for")
+	_ = v.BindPFlag("recommender.interruptionsttl", p.Lookup("interruptions-ttl"))
+	_ = v.BindEnv("recommender.interruptionsttl", "INTERRUPTIONS_TTL")
+
+	p.Float64("min-spot-coverage", 0, "minimum ratio (0-1) of the zones of an instance type having a spot price for "+
+		"the instance type to take part in the spot pools, not checked if zero")
+	_ = v.BindPFlag("recommender.minspotcoverage", p.Lookup("min-spot-coverage"))
+	_ = v.BindEnv("recommender.minspotcoverage", "MIN_SPOT_COVERAGE")
+
+	p.Float64("max-spot-price-ratio", 0.95, "ratio (0-1) of the on-demand price from which the spot price of an "+
+		"instance type is considered implausible and the instance type is left out of the spot pools, only the spot "+
+		"prices above the on-demand price are checked if zero")
+	_ = v.BindPFlag("recommender.maxspotpriceratio", p.Lookup("max-spot-price-ratio"))
+	_ = v.BindEnv("recommender.maxspotpriceratio", "MAX_SPOT_PRICE_RATIO")
+
+	p.String("decision-log-file", "", "file the decisions of the engine (anonymized request features, candidate "+
+		"counts, the chosen layout and its price) are appended to in JSON lines format, not recorded if empty")
+	_ = v.BindPFlag("recommender.decisionlogfile", p.Lookup("decision-log-file"))
+	_ = v.BindEnv("recommender.decisionlogfile", "DECISION_LOG_FILE")
+
+	p.Int("snapshot-capacity", 100, "number of the most recently used product snapshots kept for reproducing "+
+		"the recommendations, the responses don't reference snapshots if zero")
+	_ = v.BindPFlag("recommender.snapshotcapacity", p.Lookup("snapshot-capacity"))
+	_ = v.BindEnv("recommender.snapshotcapacity", "SNAPSHOT_CAPACITY")
+
+	p.Int64("snapshot-max-bytes", 0, "approximate memory the product snapshots may use, the least recently used "+
+		"snapshots are evicted over it, not limited if zero")
+	_ = v.BindPFlag("recommender.snapshotmaxbytes", p.Lookup("snapshot-max-bytes"))
+	_ = v.BindEnv("recommender.snapshotmaxbytes", "SNAPSHOT_MAX_BYTES")
+
+	p.String("canary-strategy", "", fmt.Sprintf("experimental strategy evaluated against the stable one with the "+
+		"cluster recommendations, the results are compared in the logs; one of %v, disabled if empty", recommender.CanaryStrategies()))
+	_ = v.BindPFlag("recommender.canarystrategy", p.Lookup("canary-strategy"))
+	_ = v.BindEnv("recommender.canarystrategy", "CANARY_STRATEGY")
+
+	p.Int("canary-sample-pct", 10, "percentage of the cluster recommendations repeated with the experimental strategy")
+	_ = v.BindPFlag("recommender.canarysamplepct", p.Lookup("canary-sample-pct"))
+	_ = v.BindEnv("recommender.canarysamplepct", "CANARY_SAMPLE_PCT")
+
+	p.Duration("catalog-watch-interval", 0, "interval of checking the product catalogs of the regions served for "+
+		"changes, changes are not detected if zero")
+	_ = v.BindPFlag("recommender.catalogwatchinterval", p.Lookup("catalog-watch-interval"))
+	_ = v.BindEnv("recommender.catalogwatchinterval", "CATALOG_WATCH_INTERVAL")
+
+	p.Float64("catalog-price-change-pct", 10, "minimum price change in percent reported in the catalog change summaries")
+	_ = v.BindPFlag("recommender.catalogpricechangepct", p.Lookup("catalog-price-change-pct"))
+	_ = v.BindEnv("recommender.catalogpricechangepct", "CATALOG_PRICE_CHANGE_PCT")
+
+	p.StringSlice("catalog-watch-provider-intervals", nil, "catalog watch interval per provider, overrides "+
+		"catalog-watch-interval [format=provider=duration,...]")
+	_ = v.BindPFlag("recommender.catalogwatchproviderintervals", p.Lookup("catalog-watch-provider-intervals"))
+	_ = v.BindEnv("recommender.catalogwatchproviderintervals", "CATALOG_WATCH_PROVIDER_INTERVALS")
+
+	p.Duration("catalog-watch-idle-timeout", time.Hour, "time after which the regions without recommendations are "+
+		"not watched until they are used again, the regions are always watched if zero")
+	_ = v.BindPFlag("recommender.catalogwatchidletimeout", p.Lookup("catalog-watch-idle-timeout"))
+	_ = v.BindEnv("recommender.catalogwatchidletimeout", "CATALOG_WATCH_IDLE_TIMEOUT")
+
+	// Operator
+	p.Bool("operator-enabled", false, "watch ClusterRecommendation resources and write the recommendations into their status")
+	_ = v.BindPFlag("operator.enabled", p.Lookup("operator-enabled"))
+	_ = v.BindEnv("operator.enabled", "OPERATOR_ENABLED")
+
+	p.String("kubeconfig", "", "kubeconfig file of the cluster watched by the operator, the in-cluster config is used if empty")
+	_ = v.BindPFlag("operator.kubeconfig", p.Lookup("kubeconfig"))
+	_ = v.BindEnv("operator.kubeconfig", "KUBECONFIG")
+
+	p.String("operator-namespace", "", "namespace watched by the operator, all namespaces are watched if empty")
+	_ = v.BindPFlag("operator.namespace", p.Lookup("operator-namespace"))
+	_ = v.BindEnv("operator.namespace", "OPERATOR_NAMESPACE")
+
+	p.Duration("operator-resync-period", 10*time.Minute, "interval of refreshing the recommendations of unchanged resources")
+	_ = v.BindPFlag("operator.resyncperiod", p.Lookup("operator-resync-period"))
+	_ = v.BindEnv("operator.resyncperiod", "OPERATOR_RESYNC_PERIOD")
+
+	p.Int("operator-workers", 2, "number of resources reconciled by the operator at the same time")
+	_ = v.BindPFlag("operator.workers", p.Lookup("operator-workers"))
+	_ = v.BindEnv("operator.workers", "OPERATOR_WORKERS")
+
+	p.String("tenants-file", "", "JSON file with the tenants served by the deployment, API calls are authenticated "+
+		"by the tokens of the tenants if set")
+	_ = v.BindPFlag("app.tenantsfile", p.Lookup("tenants-file"))
+	_ = v.BindEnv("app.tenantsfile", "TENANTS_FILE")
+
+	p.StringSlice("admin-tokens", nil, "tokens authenticating the calls of the admin and configuration endpoints "+
+		"with tenancy enabled, the endpoints are closed if empty")
+	_ = v.BindPFlag("app.admintokens", p.Lookup("admin-tokens"))
+	_ = v.BindEnv("app.admintokens", "ADMIN_TOKENS")
+
+	p.String("signing-key-file", "", "PEM encoded RSA private key the recommendation responses are signed with (JWS), "+
+		"responses are not signed if empty")
+	_ = v.BindPFlag("app.signingkeyfile", p.Lookup("signing-key-file"))
+	_ = v.BindEnv("app.signingkeyfile", "SIGNING_KEY_FILE")
+
+	p.Duration("usage-window", 24*time.Hour, "sliding window the usage of the service is tracked for and summarized "+
+		"on /admin/usage, usage is not tracked if zero")
+	_ = v.BindPFlag("app.usagewindow", p.Lookup("usage-window"))
+	_ = v.BindEnv("app.usagewindow", "USAGE_WINDOW")
+
+	p.Int("queue-workers", 0, "number of recommendations served at the same time (at least 2), the others wait in the queue "+
+		"of their priority class, not limited if zero")
+	_ = v.BindPFlag("app.queueworkers", p.Lookup("queue-workers"))
+	_ = v.BindEnv("app.queueworkers", "QUEUE_WORKERS")
+
+	p.Int("queue-batch-workers", 0, "number of batch recommendations served at the same time, one less than the "+
+		"queue workers if zero")
+	_ = v.BindPFlag("app.queuebatchworkers", p.Lookup("queue-batch-workers"))
+	_ = v.BindEnv("app.queuebatchworkers", "QUEUE_BATCH_WORKERS")
+
+	p.Int("queue-capacity", 100, "number of recommendations of a priority class that may wait for a worker, "+
+		"the others are rejected")
+	_ = v.BindPFlag("app.queuecapacity", p.Lookup("queue-capacity"))
+	_ = v.BindEnv("app.queuecapacity", "QUEUE_CAPACITY")
+
+	p.Bool("fault-injection", false, "inject artificial latencies and failures into the cloud info calls through "+
+		"/admin/faults, for resilience testing only")
+	_ = v.BindPFlag("app.faultinjection", p.Lookup("fault-injection"))
+	_ = v.BindEnv("app.faultinjection", "FAULT_INJECTION")
+
 	// operating mode
 	p.Bool("dev-mode", false, "development mode, if true token based authentication is disabled, false by default")
 	_ = v.BindPFlag("app.devmode", p.Lookup("dev-mode"))
 	_ = v.BindEnv("app.devmode", "DEV_MODE")
 
+	// response processing
+	p.Bool("even-node-counts", false, "round up the node counts of the recommended worker node pools to even numbers")
+	_ = v.BindPFlag("app.evennodecounts", p.Lookup("even-node-counts"))
+	_ = v.BindEnv("app.evennodecounts", "EVEN_NODE_COUNTS")
+
+	p.StringSlice("node-pool-labels", nil, "labels added to the recommended node pools [format=key=value,...]")
+	_ = v.BindPFlag("app.nodepoollabels", p.Lookup("node-pool-labels"))
+	_ = v.BindEnv("app.nodepoollabels", "NODE_POOL_LABELS")
+
 	p.String("tokensigningkey", "", "The token signing key for the authentication process")
 	_ = v.BindPFlag("app.vault.tokensigningkey", p.Lookup("tokensigningkey"))
 	_ = v.BindEnv("app.vault.tokensigningkey", "TOKENSIGNINIGKEY")
@@ -28,17 +28,25 @@ package main
 
 import (
 	"fmt"
+	"io/ioutil"
 	"net/url"
+	"os"
+	"sort"
 	"strings"
+	"time"
 
 	"github.com/banzaicloud/telescopes/internal/app/telescopes/api"
+	"github.com/banzaicloud/telescopes/internal/app/telescopes/operator"
 	"github.com/banzaicloud/telescopes/internal/platform/buildinfo"
 	"github.com/banzaicloud/telescopes/internal/platform/log"
+	"github.com/banzaicloud/telescopes/internal/platform/metrics"
+	"github.com/banzaicloud/telescopes/pkg/jws"
 	"github.com/banzaicloud/telescopes/pkg/recommender"
 	"github.com/banzaicloud/telescopes/pkg/recommender/nodepools"
 	"github.com/banzaicloud/telescopes/pkg/recommender/vms"
 	"github.com/gin-gonic/gin"
 	"github.com/goph/emperror"
+	"github.com/goph/logur"
 	"github.com/pkg/errors"
 	"github.com/spf13/pflag"
 	"github.com/spf13/viper"
@@ -85,19 +93,202 @@ func main() {
 	logger.Info("initializing the application",
 		map[string]interface{}{"version": version, "commit_hash": commitHash, "build_date": buildDate})
 
-	piUrl := parseCloudInfoAddress(config.Cloudinfo.Address)
-	ciCli := recommender.NewCloudInfoClient(piUrl.String(), logger)
+	ciSource, err := newCloudInfoSource(config, logger)
+	emperror.Panic(err)
+
+	if pflag.Arg(0) == verifyCommand {
+		violations, err := verify(ciSource, pflag.Args()[1:], logger)
+		emperror.Panic(err)
+		if violations > 0 {
+			os.Exit(1)
+		}
+		return
+	}
+
+	// the cloud info source is swapped when the configuration is reloaded
+	ciCli := recommender.NewReloadableCloudInfoSource(ciSource)
+	reloadOnSignal(viper.GetViper(), ciCli, logger)
+
+	var catalog recommender.CloudInfoSource = ciCli
+	var faults *recommender.FaultInjector
+	if config.App.FaultInjection {
+		faults = recommender.NewFaultInjector(ciCli)
+		catalog = faults
+		logger.Warn("fault injection enabled, faults may be injected into the cloud info calls through the admin API")
+	}
+
+	var watcher *recommender.CatalogWatcher
+	if config.Recommender.CatalogWatchInterval > 0 {
+		intervals, err := parseIntervals(config.Recommender.CatalogWatchProviderIntervals)
+		emperror.Panic(err)
+		watcherOpts := []recommender.CatalogWatcherOption{recommender.WithIdleTimeout(config.Recommender.CatalogWatchIdleTimeout)}
+		for provider, interval := range intervals {
+			watcherOpts = append(watcherOpts, recommender.WithProviderInterval(provider, interval))
+		}
+		watcher = recommender.NewCatalogWatcher(catalog, config.Recommender.CatalogPriceChangePct, logger, watcherOpts...)
+		if config.Metrics.Enabled {
+			watcher.OnChange(metrics.CatalogChangeListener())
+		}
+		go watcher.Run(config.Recommender.CatalogWatchInterval, make(chan struct{}))
+		catalog = watcher
+	}
 
 	// configure the gin validator
 	err = api.ConfigureValidator()
 	emperror.Panic(err)
 
 	vmSelector := vms.NewVmSelector(logger)
-	nodePoolSelector := nodepools.NewNodePoolSelector(logger)
-	engine := recommender.NewEngine(logger, ciCli, vmSelector, nodePoolSelector)
+	npConstraints := nodepools.DefaultConstraints()
+	for svc, c := range config.Recommender.NodePoolConstraints {
+		npConstraints[svc] = c
+	}
+	nodePoolSelector := nodepools.NewNodePoolSelector(logger, npConstraints)
+	incompatible := recommender.DefaultIncompatibleTypes()
+	for svc, types := range config.Recommender.IncompatibleTypes {
+		incompatible[svc] = types
+	}
+	engineOpts := []recommender.EngineOption{recommender.WithIncompatibleTypes(incompatible)}
+	if config.Recommender.BenchmarksFile != "" {
+		benchmarks, err := recommender.LoadBenchmarks(config.Recommender.BenchmarksFile)
+		emperror.Panic(err)
+		engineOpts = append(engineOpts, recommender.WithBenchmarks(benchmarks))
+	}
+	if config.Recommender.MaxPodsFile != "" {
+		maxPods, err := recommender.LoadMaxPods(config.Recommender.MaxPodsFile)
+		emperror.Panic(err)
+		engineOpts = append(engineOpts, recommender.WithMaxPods(maxPods))
+	}
+	if config.Recommender.AfterburnerTTL > 0 {
+		engineOpts = append(engineOpts, recommender.WithAfterburner(config.Recommender.AfterburnerTTL))
+	}
+	if config.Recommender.MetadataTTL > 0 {
+		engineOpts = append(engineOpts, recommender.WithMetadataCache(config.Recommender.MetadataTTL))
+	}
+	if config.Recommender.CallBudget > 0 {
+		engineOpts = append(engineOpts, recommender.WithCallBudget(config.Recommender.CallBudget))
+	}
+	if config.Recommender.PriceHistoryAddress != "" {
+		history, err := recommender.NewPrometheusPriceHistory(config.Recommender.PriceHistoryAddress, config.Recommender.PriceHistoryQuery)
+		emperror.Panic(err)
+		engineOpts = append(engineOpts, recommender.WithPriceHistory(history))
+	}
+	if config.Recommender.InterruptionsAddress != "" {
+		heat, err := recommender.NewPrometheusInterruptionHeat(config.Recommender.InterruptionsAddress,
+			config.Recommender.InterruptionsQuery, config.Recommender.InterruptionsTTL)
+		emperror.Panic(err)
+		engineOpts = append(engineOpts, recommender.WithInterruptionHeat(heat))
+	}
+	if config.Recommender.MinSpotCoverage > 0 {
+		engineOpts = append(engineOpts, recommender.WithMinSpotCoverage(config.Recommender.MinSpotCoverage))
+	}
+	if config.Recommender.MaxSpotPriceRatio > 0 {
+		engineOpts = append(engineOpts, recommender.WithMaxSpotPriceRatio(config.Recommender.MaxSpotPriceRatio))
+	}
+	if disabled := spotDisabledProviders(config.Recommender.SpotEnabled); len(disabled) > 0 {
+		engineOpts = append(engineOpts, recommender.WithSpotDisabled(disabled...))
+	}
+	if config.Recommender.DecisionLogFile != "" {
+		file, err := os.OpenFile(config.Recommender.DecisionLogFile, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+		emperror.Panic(errors.Wrap(err, "failed to open the decision log"))
+		defer file.Close()
+		emitter := recommender.NewJSONLDecisionEmitter(file, logger)
+		defer emitter.Close()
+		engineOpts = append(engineOpts, recommender.WithDecisionEmitter(emitter))
+	}
+	if len(config.Recommender.Deprecations) > 0 {
+		engineOpts = append(engineOpts, recommender.WithDeprecations(config.Recommender.Deprecations))
+	}
+	if len(config.Recommender.NodeCosts) > 0 {
+		engineOpts = append(engineOpts, recommender.WithNodeCosts(config.Recommender.NodeCosts))
+	}
+	var snapshots recommender.SnapshotStore
+	if config.Recommender.SnapshotCapacity > 0 {
+		snapshotOpts := []recommender.SnapshotStoreOption{recommender.WithMaxBytes(config.Recommender.SnapshotMaxBytes)}
+		if config.Metrics.Enabled {
+			snapshotOpts = append(snapshotOpts, recommender.WithEvictionListener(metrics.SnapshotEvictionListener()))
+		}
+		snapshots = recommender.NewInMemorySnapshotStore(config.Recommender.SnapshotCapacity, snapshotOpts...)
+		engineOpts = append(engineOpts, recommender.WithSnapshots(snapshots))
+	}
+	engine := recommender.NewEngine(logger, catalog, vmSelector, nodePoolSelector, engineOpts...)
+
+	// the engines of the tenants are served behind the same wrappers as the shared engine
+	wrapEngine := func(e recommender.ClusterRecommender) recommender.ClusterRecommender {
+		if config.Recommender.CanaryStrategy == "" {
+			return e
+		}
+		canary, err := recommender.NewCanary(e, config.Recommender.CanaryStrategy, config.Recommender.CanarySamplePct, logger)
+		emperror.Panic(err)
+		return canary
+	}
+	clusterRecommender := wrapEngine(engine)
+	if config.Recommender.CanaryStrategy != "" {
+		logger.Info("canary evaluation enabled", map[string]interface{}{
+			"strategy": config.Recommender.CanaryStrategy, "samplePct": config.Recommender.CanarySamplePct})
+	}
+
+	if config.Operator.Enabled {
+		client, err := operator.NewDynamicClient(config.Operator.Kubeconfig)
+		emperror.Panic(err)
+
+		op := operator.NewOperator(client, clusterRecommender, config.Operator.Namespace, config.Operator.ResyncPeriod, config.Operator.Workers, logger)
+		go op.Run(make(chan struct{}))
+	}
 
 	buildInfo := buildinfo.New(version, commitHash, buildDate)
-	routeHandler := api.NewRouteHandler(engine, buildInfo, ciCli, logger)
+	routeHandler := api.NewRouteHandler(clusterRecommender, buildInfo, catalog, recommender.NewInMemoryProfileStore(), logger)
+	if config.App.EvenNodeCounts {
+		routeHandler.AddResponseProcessors(api.EvenNodeCounts())
+	}
+	if len(config.App.NodePoolLabels) > 0 {
+		labels, err := parseLabels(config.App.NodePoolLabels)
+		emperror.Panic(err)
+		routeHandler.AddResponseProcessors(api.NodePoolLabels(labels))
+	}
+	if config.App.TenantsFile != "" {
+		tenants, err := recommender.LoadTenants(config.App.TenantsFile)
+		emperror.Panic(err)
+		tenancy, err := recommender.NewTenancy(engine, catalog, tenants, func(address string) (recommender.CloudInfoSource, error) {
+			ciUrl, err := parseCloudInfoAddress(address)
+			if err != nil {
+				return nil, err
+			}
+			return recommender.NewCloudInfoClient(ciUrl.String(), logger), nil
+		}, wrapEngine)
+		emperror.Panic(err)
+		routeHandler.EnableTenancy(tenancy, config.App.AdminTokens)
+		logger.Info("multi-tenancy enabled", map[string]interface{}{"tenants": len(tenants)})
+	}
+	if config.App.SigningKeyFile != "" {
+		pemData, err := ioutil.ReadFile(config.App.SigningKeyFile)
+		emperror.Panic(errors.Wrap(err, "failed to read the signing key"))
+		key, err := jws.ParsePrivateKey(pemData)
+		emperror.Panic(err)
+		routeHandler.EnableSigning(jws.NewSigner(key))
+		logger.Info("recommendation signing enabled", map[string]interface{}{"kid": jws.KeyID(&key.PublicKey)})
+	}
+
+	if snapshots != nil {
+		routeHandler.EnableSnapshots(snapshots)
+	}
+	if watcher != nil {
+		routeHandler.EnableRenewalStatus(watcher)
+	}
+	if faults != nil {
+		routeHandler.EnableFaultInjection(faults)
+	}
+	if config.App.UsageWindow > 0 {
+		routeHandler.EnableUsage(recommender.NewInMemoryUsageStore(config.App.UsageWindow))
+	}
+	if config.App.QueueWorkers > 0 {
+		queue, err := recommender.NewWorkQueue(config.App.QueueWorkers, config.App.QueueBatchWorkers, config.App.QueueCapacity)
+		emperror.Panic(err)
+		routeHandler.EnableQueue(queue)
+	}
+	if len(config.Recommender.ProviderDefaults) > 0 {
+		routeHandler.EnableProviderDefaults(config.Recommender.ProviderDefaults)
+	}
+	routeHandler.EnableRuntimeConfig(runtimeConfig(config))
 
 	// new default gin engine (recovery, logger middleware)
 	router := gin.Default()
@@ -122,9 +313,137 @@ func main() {
 	emperror.Panic(errors.Wrap(err, "failed to run router"))
 }
 
-func parseCloudInfoAddress(ciUrl string) *url.URL {
+// newCloudInfoSource creates the source of the product data: the cloud info service or the fixtures if configured
+func newCloudInfoSource(config configuration, logger logur.Logger) (recommender.CloudInfoSource, error) {
+	if config.Cloudinfo.FixtureDir != "" {
+		logger.Info("serving product data from fixtures", map[string]interface{}{"dir": config.Cloudinfo.FixtureDir})
+		return recommender.NewFixtureCloudInfoSource(config.Cloudinfo.FixtureDir)
+	}
+
+	piUrl, err := parseCloudInfoAddress(config.Cloudinfo.Address)
+	if err != nil {
+		return nil, err
+	}
+	logger.Info("retrieving product data from cloud info", map[string]interface{}{"address": piUrl.String()})
+	return recommender.NewCloudInfoClient(piUrl.String(), logger), nil
+}
+
+func parseCloudInfoAddress(ciUrl string) (*url.URL, error) {
 	ciUrl = strings.TrimSuffix(ciUrl, "/")
 	u, err := url.ParseRequestURI(ciUrl)
-	emperror.Panic(errors.Wrap(err, fmt.Sprintf("invalid URI: %s", ciUrl)))
-	return u
+	if err != nil {
+		return nil, errors.Wrap(err, fmt.Sprintf("invalid URI: %s", ciUrl))
+	}
+	return u, nil
+}
+
+// parseLabels parses labels in key=value format
+func parseLabels(pairs []string) (map[string]string, error) {
+	labels := make(map[string]string, len(pairs))
+	for _, pair := range pairs {
+		kv := strings.SplitN(pair, "=", 2)
+		if len(kv) != 2 || kv[0] == "" {
+			return nil, errors.Errorf("invalid label: %s, the format is key=value", pair)
+		}
+		labels[kv[0]] = kv[1]
+	}
+	return labels, nil
+}
+
+// spotDisabledProviders gets the providers spot instances are disabled for, in a stable order
+func spotDisabledProviders(spotEnabled map[string]bool) []string {
+	var providers []string
+	for provider, enabled := range spotEnabled {
+		if !enabled {
+			providers = append(providers, provider)
+		}
+	}
+	sort.Strings(providers)
+	return providers
+}
+
+// parseIntervals parses intervals in key=duration format
+func parseIntervals(pairs []string) (map[string]time.Duration, error) {
+	intervals := make(map[string]time.Duration, len(pairs))
+	for _, pair := range pairs {
+		kv := strings.SplitN(pair, "=", 2)
+		if len(kv) != 2 || kv[0] == "" {
+			return nil, errors.Errorf("invalid interval: %s, the format is key=duration", pair)
+		}
+		interval, err := time.ParseDuration(kv[1])
+		if err != nil || interval <= 0 {
+			return nil, errors.Errorf("invalid interval: %s, the duration must be positive", pair)
+		}
+		intervals[kv[0]] = interval
+	}
+	return intervals, nil
+}
+
+// runtimeConfig describes the effective configuration for the operators, leaving out the secrets
+func runtimeConfig(config configuration) api.RuntimeConfig {
+	rc := api.RuntimeConfig{
+		Environment:  config.Environment,
+		FixtureDir:   config.Cloudinfo.FixtureDir,
+		SpotDisabled: spotDisabledProviders(config.Recommender.SpotEnabled),
+		Cache: api.CacheConfig{
+			Backend:          "memory",
+			SnapshotCapacity: config.Recommender.SnapshotCapacity,
+			SnapshotMaxBytes: config.Recommender.SnapshotMaxBytes,
+		},
+		Features: make([]string, 0),
+	}
+	if config.Cloudinfo.FixtureDir == "" {
+		rc.CloudInfoAddress = redactAddress(config.Cloudinfo.Address)
+	}
+	if config.Recommender.AfterburnerTTL > 0 {
+		rc.Cache.AfterburnerTTL = config.Recommender.AfterburnerTTL.String()
+	}
+	if config.Recommender.MetadataTTL > 0 {
+		rc.Cache.MetadataTTL = config.Recommender.MetadataTTL.String()
+	}
+	if config.Recommender.CatalogWatchInterval > 0 {
+		rc.Renewal.Interval = config.Recommender.CatalogWatchInterval.String()
+		rc.Renewal.IdleTimeout = config.Recommender.CatalogWatchIdleTimeout.String()
+		if intervals, err := parseIntervals(config.Recommender.CatalogWatchProviderIntervals); err == nil && len(intervals) > 0 {
+			rc.Renewal.ProviderIntervals = make(map[string]string, len(intervals))
+			for provider, interval := range intervals {
+				rc.Renewal.ProviderIntervals[provider] = interval.String()
+			}
+		}
+	}
+
+	features := []struct {
+		name    string
+		enabled bool
+	}{
+		{"tenancy", config.App.TenantsFile != ""},
+		{"signing", config.App.SigningKeyFile != ""},
+		{"usage", config.App.UsageWindow > 0},
+		{"queue", config.App.QueueWorkers > 0},
+		{"faultInjection", config.App.FaultInjection},
+		{"evenNodeCounts", config.App.EvenNodeCounts},
+		{"priceHistory", config.Recommender.PriceHistoryAddress != ""},
+		{"interruptions", config.Recommender.InterruptionsAddress != ""},
+		{"decisionLog", config.Recommender.DecisionLogFile != ""},
+		{"canary", config.Recommender.CanaryStrategy != ""},
+		{"operator", config.Operator.Enabled},
+		{"metrics", config.Metrics.Enabled},
+	}
+	for _, feature := range features {
+		if feature.enabled {
+			rc.Features = append(rc.Features, feature.name)
+		}
+	}
+	return rc
+}
+
+// redactAddress removes the credentials from the address, the address is left out if it can't be parsed
+func redactAddress(address string) string {
+	u, err := parseCloudInfoAddress(address)
+	if err != nil {
+		return ""
+	}
+	u.User = nil
+	u.RawQuery = ""
+	return u.String()
 }
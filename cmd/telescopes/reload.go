@@ -0,0 +1,65 @@
+// Copyright © 2019 Banzai Cloud
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"os"
+	"os/signal"
+	"syscall"
+
+	"github.com/banzaicloud/telescopes/pkg/recommender"
+	"github.com/goph/logur"
+	"github.com/pkg/errors"
+	"github.com/spf13/viper"
+)
+
+// reloadOnSignal reloads the cloud info source from the configuration on SIGHUP, eg. to change the cloud info address
+func reloadOnSignal(v *viper.Viper, source *recommender.ReloadableCloudInfoSource, logger logur.Logger) {
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, syscall.SIGHUP)
+
+	go func() {
+		for range signals {
+			logger.Info("reloading configuration")
+			if err := reloadCloudInfoSource(v, source, logger); err != nil {
+				logger.Error("failed to reload configuration, keeping the current one", map[string]interface{}{"err": err.Error()})
+				continue
+			}
+			logger.Info("configuration reloaded")
+		}
+	}()
+}
+
+// reloadCloudInfoSource re-reads the configuration and swaps the cloud info source for one built from it
+func reloadCloudInfoSource(v *viper.Viper, source *recommender.ReloadableCloudInfoSource, logger logur.Logger) error {
+	if err := v.ReadInConfig(); err != nil {
+		if _, configFileNotFound := err.(viper.ConfigFileNotFoundError); !configFileNotFound {
+			return errors.Wrap(err, "failed to read configuration")
+		}
+	}
+
+	var config configuration
+	if err := v.Unmarshal(&config); err != nil {
+		return errors.Wrap(err, "failed to unmarshal configuration")
+	}
+
+	ciSource, err := newCloudInfoSource(config, logger)
+	if err != nil {
+		return err
+	}
+
+	source.Swap(ciSource)
+	return nil
+}
@@ -0,0 +1,69 @@
+// Copyright © 2019 Banzai Cloud
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/banzaicloud/telescopes/pkg/recommender"
+	"github.com/goph/logur"
+	"github.com/spf13/viper"
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_reloadCloudInfoSource(t *testing.T) {
+	newServer := func(vmType string) *httptest.Server {
+		return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			_, _ = fmt.Fprintf(w, `{"products": [{"type": "%s", "cpusPerVm": 2, "memPerVm": 4}]}`, vmType)
+		}))
+	}
+	current, next := newServer("c5.large"), newServer("m5.large")
+	defer current.Close()
+	defer next.Close()
+
+	dir, err := ioutil.TempDir("", "telescopes")
+	assert.Nil(t, err)
+	defer os.RemoveAll(dir)
+	configFile := filepath.Join(dir, "config.toml")
+
+	writeConfig := func(address string) {
+		err := ioutil.WriteFile(configFile, []byte(fmt.Sprintf("[cloudinfo]\naddress = %q\n", address)), 0600)
+		assert.Nil(t, err)
+	}
+
+	v := viper.New()
+	v.SetConfigFile(configFile)
+	source := recommender.NewReloadableCloudInfoSource(recommender.NewCloudInfoClient(current.URL, logur.NewTestLogger()))
+
+	checkType := func(expected string) {
+		vms, err := source.GetProductDetails("amazon", "compute", "eu-west-1")
+		assert.Nil(t, err)
+		assert.Equal(t, expected, vms[0].Type)
+	}
+
+	writeConfig("invalid address")
+	assert.NotNil(t, reloadCloudInfoSource(v, source, logur.NewTestLogger()))
+	checkType("c5.large")
+
+	writeConfig(next.URL)
+	assert.Nil(t, reloadCloudInfoSource(v, source, logur.NewTestLogger()))
+	checkType("m5.large")
+}
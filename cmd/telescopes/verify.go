@@ -0,0 +1,55 @@
+// Copyright © 2019 Banzai Cloud
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"strings"
+
+	"github.com/banzaicloud/telescopes/pkg/recommender"
+	"github.com/banzaicloud/telescopes/pkg/recommender/recommendertest"
+	"github.com/goph/emperror"
+	"github.com/goph/logur"
+	"github.com/pkg/errors"
+)
+
+// verifyCommand is the hidden subcommand checking the conformance of the cloud info source instead of serving
+const verifyCommand = "verify"
+
+// verify checks the conformance of the products of the cloud info source for the provider/service[/region] targets,
+// it returns the number of violations found
+func verify(source recommender.CloudInfoSource, targets []string, logger logur.Logger) (int, error) {
+	if len(targets) == 0 {
+		return 0, errors.New("no provider/service[/region] to verify")
+	}
+
+	violations := 0
+	for _, target := range targets {
+		parts := strings.Split(target, "/")
+		if len(parts) < 2 || len(parts) > 3 {
+			return violations, emperror.With(errors.New("invalid target, expected provider/service[/region]"), "target", target)
+		}
+		found, err := recommendertest.CheckConformance(source, parts[0], parts[1], parts[2:]...)
+		if err != nil {
+			return violations, emperror.With(errors.Wrap(err, "failed to check conformance"), "target", target)
+		}
+		for _, v := range found {
+			logger.Error("conformance check failed", map[string]interface{}{"provider": v.Provider,
+				"service": v.Service, "region": v.Region, "check": v.Check, "message": v.Message})
+		}
+		logger.Info("conformance checked", map[string]interface{}{"target": target, "violations": len(found)})
+		violations += len(found)
+	}
+	return violations, nil
+}
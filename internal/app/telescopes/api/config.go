@@ -0,0 +1,82 @@
+// Copyright © 2019 Banzai Cloud
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package api
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+// RuntimeConfig is the effective runtime configuration of the service, without secrets
+// swagger:model runtimeConfig
+type RuntimeConfig struct {
+	// Environment the service runs in, eg. production
+	Environment string `json:"environment"`
+	// CloudInfoAddress is the address of the cloud info service, without credentials
+	CloudInfoAddress string `json:"cloudInfoAddress,omitempty"`
+	// FixtureDir is the directory of the product fixtures served instead of the cloud info service
+	FixtureDir string `json:"fixtureDir,omitempty"`
+	// SpotDisabled lists the providers spot instances are not recommended for
+	SpotDisabled []string `json:"spotDisabled,omitempty"`
+	// Renewal describes how the product catalogs of the regions are watched for changes
+	Renewal RenewalConfig `json:"renewal"`
+	// Cache describes the caches of the service
+	Cache CacheConfig `json:"cache"`
+	// Features lists the optional features turned on
+	Features []string `json:"features"`
+}
+
+// RenewalConfig describes how the product catalogs of the regions are watched for changes
+type RenewalConfig struct {
+	// Interval of checking the product catalogs, catalogs are not watched if empty
+	Interval string `json:"interval,omitempty"`
+	// ProviderIntervals overrides the interval per provider
+	ProviderIntervals map[string]string `json:"providerIntervals,omitempty"`
+	// IdleTimeout is the time after which the regions without recommendations are not watched
+	IdleTimeout string `json:"idleTimeout,omitempty"`
+}
+
+// CacheConfig describes the caches of the service
+type CacheConfig struct {
+	// Backend of the caches, they are kept in the memory of the service
+	Backend string `json:"backend"`
+	// AfterburnerTTL is the time the candidate vms of scale out layouts are reused for, disabled if empty
+	AfterburnerTTL string `json:"afterburnerTtl,omitempty"`
+	// MetadataTTL is the time the providers, services, regions and zones are reused for, disabled if empty
+	MetadataTTL string `json:"metadataTtl,omitempty"`
+	// SnapshotCapacity is the number of product snapshots kept, disabled if zero
+	SnapshotCapacity int `json:"snapshotCapacity"`
+	// SnapshotMaxBytes is the approximate memory the product snapshots may use, not limited if zero
+	SnapshotMaxBytes int64 `json:"snapshotMaxBytes,omitempty"`
+}
+
+// EnableRuntimeConfig makes the API serve the effective runtime configuration on /config; it must not hold secrets
+func (r *RouteHandler) EnableRuntimeConfig(config RuntimeConfig) {
+	r.config = &config
+}
+
+// swagger:operation GET /config config runtimeConfig
+// ---
+// summary: Returns the effective runtime configuration.
+// description: Returns the effective runtime configuration without secrets, so operators can verify the configuration (eg. the Helm values) took effect.
+// responses:
+//   "200":
+//     description: runtime configuration
+//     schema:
+//       "$ref": "#/definitions/runtimeConfig"
+func (r *RouteHandler) runtimeConfig(c *gin.Context) {
+	c.JSON(http.StatusOK, r.config)
+}
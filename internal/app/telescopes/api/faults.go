@@ -0,0 +1,89 @@
+// Copyright © 2019 Banzai Cloud
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package api
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/goph/emperror"
+
+	"github.com/banzaicloud/telescopes/internal/platform/classifier"
+	"github.com/banzaicloud/telescopes/internal/platform/errorresponse"
+	"github.com/banzaicloud/telescopes/pkg/recommender"
+)
+
+// EnableFaultInjection makes the admin API manage the faults injected into the cloud info calls by the injector
+func (r *RouteHandler) EnableFaultInjection(injector *recommender.FaultInjector) {
+	r.faults = injector
+}
+
+// swagger:operation GET /admin/faults admin listFaults
+// ---
+// summary: Lists the faults injected into the cloud info calls.
+// description: Lists the artificial latencies and failures injected into the cloud info calls per operation and provider.
+// responses:
+//   "200":
+//     description: faults response
+//     schema:
+//       "$ref": "#/definitions/faultsResponse"
+func (r *RouteHandler) listFaults(c *gin.Context) {
+	c.JSON(http.StatusOK, FaultsResponse{Faults: r.faults.Faults()})
+}
+
+// swagger:operation PUT /admin/faults admin putFault
+// ---
+// summary: Injects a fault into the cloud info calls.
+// description: Injects an artificial latency and/or failure into the cloud info calls of the operation and provider, replacing the fault injected for them before.
+// parameters:
+// - name: fault
+//   in: body
+//   required: true
+//   schema:
+//     "$ref": "#/definitions/fault"
+// responses:
+//   "200":
+//     description: faults response
+//     schema:
+//       "$ref": "#/definitions/faultsResponse"
+func (r *RouteHandler) putFault(c *gin.Context) {
+	fault := recommender.Fault{}
+	if err := c.ShouldBindJSON(&fault); err != nil {
+		errorresponse.NewErrorResponder(c).Respond(
+			emperror.WrapWith(err, "failed to bind request body", classifier.ValidationErrTag))
+		return
+	}
+
+	if err := r.faults.SetFault(fault); err != nil {
+		errorresponse.NewErrorResponder(c).Respond(err)
+		return
+	}
+	r.log.Warn("fault injected into the cloud info calls", map[string]interface{}{
+		"operation": fault.Operation, "provider": fault.Provider, "latencyMs": fault.LatencyMs, "failurePct": fault.FailurePct})
+	c.JSON(http.StatusOK, FaultsResponse{Faults: r.faults.Faults()})
+}
+
+// swagger:operation DELETE /admin/faults admin clearFaults
+// ---
+// summary: Removes the faults injected into the cloud info calls.
+// description: Removes all faults injected into the cloud info calls.
+// responses:
+//   "204":
+//     description: the faults are removed
+func (r *RouteHandler) clearFaults(c *gin.Context) {
+	r.faults.ClearFaults()
+	r.log.Info("faults removed from the cloud info calls")
+	c.Status(http.StatusNoContent)
+}
@@ -16,14 +16,19 @@ package api
 
 import (
 	"net/http"
+	"strconv"
 
 	"github.com/banzaicloud/telescopes/internal/platform/classifier"
 	"github.com/banzaicloud/telescopes/internal/platform/errorresponse"
 	"github.com/banzaicloud/telescopes/internal/platform/log"
 	"github.com/banzaicloud/telescopes/pkg/recommender"
+	"github.com/banzaicloud/telescopes/pkg/recommender/formats"
+	"github.com/banzaicloud/telescopes/pkg/recommender/vms"
 	"github.com/gin-gonic/gin"
+	"github.com/gin-gonic/gin/binding"
 	"github.com/goph/emperror"
 	"github.com/mitchellh/mapstructure"
+	"github.com/pkg/errors"
 )
 
 // swagger:operation POST /recommender/provider/{provider}/service/{service}/region/{region}/cluster recommend recommendCluster
@@ -49,6 +54,18 @@ import (
 //   schema:
 //     "$ref": "#/definitions/recommendClusterRequest"
 //   required: true
+// - name: format
+//   in: query
+//   description: output format, asg-mixed renders an AWS AutoScalingGroup MixedInstancesPolicy, asg-capacity-optimized renders it for the capacity-optimized-prioritized spot allocation strategy weighted by the node pool weights, mig renders a GCP regional managed instance group of the spot node pools, capi renders Cluster API MachineDeployments as YAML, capi-machinepool renders Cluster API MachinePools as YAML (amazon and azure), csv renders the node pools with their costs as CSV (also selected by Accept text/csv)
+//   required: false
+// - name: clusterName
+//   in: query
+//   description: name of the cluster the capi manifests are rendered for, a DNS-1123 label
+//   required: false
+// - name: debugVm
+//   in: query
+//   description: vm type the reasons of the exclusion from the candidates are reported for in the vmDebug field
+//   required: false
 // responses:
 //   "200":
 //     description: recommendation response
@@ -68,26 +85,48 @@ func (r *RouteHandler) recommendCluster() gin.HandlerFunc {
 
 		logger.Info("recommend cluster setup")
 
-		if err := NewCloudInfoValidator(r.ciCli).ValidatePathParams(pathParams); err != nil {
+		if err := NewCloudInfoValidator(r.ciCliFor(c)).ValidatePathParams(pathParams); err != nil {
 			errorresponse.NewErrorResponder(c).Respond(err)
 			return
 		}
 
 		// request decorated with provider and region - used to validate the request
-		req := recommender.SingleClusterRecommendationReq{}
+		req, defaulted, err := r.bindClusterRecommendationReq(c, pathParams.Provider)
+		if err != nil {
+			errorresponse.NewErrorResponder(c).Respond(err)
+			return
+		}
+		var effective *recommender.SingleClusterRecommendationReq
+		if defaulted {
+			effectiveReq := req
+			effective = &effectiveReq
+		}
+		req.NormalizeUnits()
 
-		if err := c.BindJSON(&req); err != nil {
-			errorresponse.NewErrorResponder(c).Respond(
-				emperror.WrapWith(err, "failed to bind request body", classifier.ValidationErrTag))
+		if len(req.Metadata) > 0 {
+			logger.Info("request metadata attached", map[string]interface{}{"metadata": req.Metadata})
+		}
+
+		if err := r.validateZone(c, pathParams, req.Zone); err != nil {
+			errorresponse.NewErrorResponder(c).Respond(err)
 			return
 		}
 
-		response, err := r.engine.RecommendCluster(pathParams.Provider, pathParams.Service, pathParams.Region, req, nil)
+		response, err := r.engineFor(c).RecommendCluster(pathParams.Provider, pathParams.Service, pathParams.Region, req, nil)
 		if err != nil {
 			errorresponse.NewErrorResponder(c).Respond(err)
 			return
 		}
-		c.JSON(http.StatusOK, RecommendationResponse{*response})
+		response.Request = effective
+		if vmType := c.Query("debugVm"); vmType != "" {
+			response.VmDebug, err = r.engineFor(c).DebugVm(pathParams.Provider, pathParams.Service, pathParams.Region, req, vmType)
+			if err != nil {
+				errorresponse.NewErrorResponder(c).Respond(err)
+				return
+			}
+		}
+		r.recordUsage(recommender.UsageOf(recommender.UsageCluster, req.SumCpu, req.SumMem, *response))
+		r.respondRecommendation(c, &req, response, req.Units)
 	}
 }
 
@@ -114,6 +153,14 @@ func (r *RouteHandler) recommendCluster() gin.HandlerFunc {
 //   schema:
 //     "$ref": "#/definitions/recommendClusterScaleOutRequest"
 //   required: true
+// - name: format
+//   in: query
+//   description: output format, asg-mixed renders an AWS AutoScalingGroup MixedInstancesPolicy, asg-capacity-optimized renders it for the capacity-optimized-prioritized spot allocation strategy weighted by the node pool weights, mig renders a GCP regional managed instance group of the spot node pools, capi renders Cluster API MachineDeployments as YAML, capi-machinepool renders Cluster API MachinePools as YAML (amazon and azure), csv renders the node pools with their costs as CSV (also selected by Accept text/csv)
+//   required: false
+// - name: clusterName
+//   in: query
+//   description: name of the cluster the capi manifests are rendered for, a DNS-1123 label
+//   required: false
 // responses:
 //   "200":
 //     description: recommendation response
@@ -133,7 +180,7 @@ func (r *RouteHandler) recommendClusterScaleOut() gin.HandlerFunc {
 
 		logger.Info("recommend cluster scale out")
 
-		if e := NewCloudInfoValidator(r.ciCli).ValidatePathParams(pathParams); e != nil {
+		if e := NewCloudInfoValidator(r.ciCliFor(c)).ValidatePathParams(pathParams); e != nil {
 			errorresponse.NewErrorResponder(c).Respond(e)
 			return
 		}
@@ -145,13 +192,24 @@ func (r *RouteHandler) recommendClusterScaleOut() gin.HandlerFunc {
 				emperror.WrapWith(err, "failed to bind request body", classifier.ValidationErrTag))
 			return
 		}
+		req.NormalizeUnits()
 
-		response, err := r.engine.RecommendClusterScaleOut(pathParams.Provider, pathParams.Service, pathParams.Region, req)
+		if len(req.Metadata) > 0 {
+			logger.Info("request metadata attached", map[string]interface{}{"metadata": req.Metadata})
+		}
+
+		if err := r.validateZone(c, pathParams, req.Zone); err != nil {
+			errorresponse.NewErrorResponder(c).Respond(err)
+			return
+		}
+
+		response, err := r.engineFor(c).RecommendClusterScaleOut(pathParams.Provider, pathParams.Service, pathParams.Region, req)
 		if err != nil {
 			errorresponse.NewErrorResponder(c).Respond(err)
 			return
 		}
-		c.JSON(http.StatusOK, RecommendationResponse{*response})
+		r.recordUsage(recommender.UsageOf(recommender.UsageScaleOut, req.DesiredCpu, req.DesiredMem, *response))
+		r.respondRecommendation(c, nil, response, req.Units)
 	}
 }
 
@@ -184,22 +242,624 @@ func (r *RouteHandler) recommendMultiCluster() gin.HandlerFunc {
 				emperror.WrapWith(err, "failed to bind request body", classifier.ValidationErrTag))
 			return
 		}
+		req.NormalizeUnits()
+
+		if len(req.Metadata) > 0 {
+			logger.Info("request metadata attached", map[string]interface{}{"metadata": req.Metadata})
+		}
 
-		if err := NewCloudInfoValidator(r.ciCli).ValidateContinents(req.Continents); err != nil {
+		if err := NewCloudInfoValidator(r.ciCliFor(c)).ValidateContinents(req.Continents); err != nil {
 			errorresponse.NewErrorResponder(c).Respond(emperror.With(err, classifier.ValidationErrTag))
 			return
 		}
 
-		response, err := r.engine.RecommendMultiCluster(req)
+		response, err := r.engineFor(c).RecommendMultiCluster(req)
 		if err != nil {
 			errorresponse.NewErrorResponder(c).Respond(err)
 			return
 		}
+		var usage []recommender.ClusterRecommendationResp
+		for _, responses := range response {
+			for _, resp := range responses {
+				usage = append(usage, *resp)
+			}
+		}
+		r.recordUsage(recommender.UsageOf(recommender.UsageMultiCloud, req.SumCpu, req.SumMem, usage...))
+		for _, responses := range response {
+			for i, resp := range responses {
+				if responses[i], err = r.process(resp); err != nil {
+					errorresponse.NewErrorResponder(c).Respond(err)
+					return
+				}
+				responses[i].ConvertUnits(req.Units)
+			}
+		}
+		for _, responses := range response {
+			if len(responses) > 0 {
+				setCloudInfoCalls(c, responses[0].CloudInfoCalls)
+				break
+			}
+		}
 
 		c.JSON(http.StatusOK, response)
 	}
 }
 
+// swagger:operation POST /recommender/provider/{provider}/service/{service}/multiregion recommend recommendMultiRegion
+// ---
+// summary: Provides a recommended set of node pools per region, for a cluster spread across regions.
+// description: Provides a recommended set of node pools per region, the requested capacity is split across the regions by the given percentages.
+// parameters:
+// - name: provider
+//   in: path
+//   description: provider
+//   required: true
+// - name: service
+//   in: path
+//   description: service
+//   required: true
+// - name: recommendRequestBody
+//   in: body
+//   description: request params
+//   schema:
+//     "$ref": "#/definitions/recommendMultiRegionRequest"
+//   required: true
+// responses:
+//   "200":
+//     description: recommendation response
+//     schema:
+//       "$ref": "#/definitions/multiRegionRecommendationResponse"
+func (r *RouteHandler) recommendMultiRegion() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		provider, service := c.Param("provider"), c.Param("service")
+
+		logger := log.WithFieldsForHandlers(c, r.log, map[string]interface{}{"provider": provider, "service": service})
+
+		logger.Info("recommend multi-region cluster setup")
+
+		req := recommender.MultiRegionRecommendationReq{}
+		if err := c.BindJSON(&req); err != nil {
+			errorresponse.NewErrorResponder(c).Respond(
+				emperror.WrapWith(err, "failed to bind request body", classifier.ValidationErrTag))
+			return
+		}
+		req.NormalizeUnits()
+
+		if len(req.Metadata) > 0 {
+			logger.Info("request metadata attached", map[string]interface{}{"metadata": req.Metadata})
+		}
+
+		for _, share := range req.Regions {
+			pathParams := GetRecommendationParams{Provider: provider, Service: service, Region: share.Region}
+			if err := NewCloudInfoValidator(r.ciCliFor(c)).ValidatePathParams(pathParams); err != nil {
+				errorresponse.NewErrorResponder(c).Respond(err)
+				return
+			}
+		}
+
+		response, err := r.engineFor(c).RecommendMultiRegion(provider, service, req)
+		if err != nil {
+			errorresponse.NewErrorResponder(c).Respond(err)
+			return
+		}
+		r.recordUsage(recommender.UsageOf(recommender.UsageMultiRegion, req.SumCpu, req.SumMem, response.Regions...))
+		if err := r.processMultiRegion(response); err != nil {
+			errorresponse.NewErrorResponder(c).Respond(err)
+			return
+		}
+		response.ConvertUnits(req.Units)
+		setCloudInfoCalls(c, response.CloudInfoCalls)
+
+		c.JSON(http.StatusOK, MultiRegionRecommendationResponse{*response})
+	}
+}
+
+// swagger:operation POST /recommender/provider/{provider}/service/{service}/region/{region}/backtest recommend backtestLayout
+// ---
+// summary: Replays a layout against the spot price history.
+// description: Reports what the layout would have cost over a window in the past and how often the spot prices of its pools exceeded the on-demand price.
+// parameters:
+// - name: provider
+//   in: path
+//   description: provider
+//   required: true
+// - name: service
+//   in: path
+//   description: service
+//   required: true
+// - name: region
+//   in: path
+//   description: region
+//   required: true
+// - name: backtestRequestBody
+//   in: body
+//   description: layout and window to replay
+//   schema:
+//     "$ref": "#/definitions/backtestRequest"
+//   required: true
+// responses:
+//   "200":
+//     description: backtest
+//     schema:
+//       "$ref": "#/definitions/backtestResponse"
+func (r *RouteHandler) backtestLayout() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		pathParams := GetRecommendationParams{}
+
+		if err := mapstructure.Decode(getPathParamMap(c), &pathParams); err != nil {
+			errorresponse.NewErrorResponder(c).Respond(emperror.Wrap(err, "failed to decode path parameters"))
+			return
+		}
+
+		logger := log.WithFieldsForHandlers(c, r.log,
+			map[string]interface{}{"provider": pathParams.Provider, "service": pathParams.Service, "region": pathParams.Region})
+
+		logger.Info("backtest layout")
+
+		if err := NewCloudInfoValidator(r.ciCliFor(c)).ValidatePathParams(pathParams); err != nil {
+			errorresponse.NewErrorResponder(c).Respond(err)
+			return
+		}
+
+		req := recommender.BacktestReq{}
+		if err := c.BindJSON(&req); err != nil {
+			errorresponse.NewErrorResponder(c).Respond(
+				emperror.WrapWith(err, "failed to bind request body", classifier.ValidationErrTag))
+			return
+		}
+
+		response, err := r.engineFor(c).Backtest(pathParams.Provider, pathParams.Service, pathParams.Region, req)
+		if err != nil {
+			errorresponse.NewErrorResponder(c).Respond(err)
+			return
+		}
+
+		c.JSON(http.StatusOK, BacktestResponse{*response})
+	}
+}
+
+// swagger:operation POST /recommender/provider/{provider}/service/{service}/region/{region}/rebalance recommend rebalanceLayout
+// ---
+// summary: Provides a plan evening out the nodes of an existing layout between the zones.
+// description: Recommends the nodes to move between the zones and the resulting node counts of the node pools per zone, keeping the cost increase within the requested threshold.
+// parameters:
+// - name: provider
+//   in: path
+//   description: provider
+//   required: true
+// - name: service
+//   in: path
+//   description: service
+//   required: true
+// - name: region
+//   in: path
+//   description: region
+//   required: true
+// - name: rebalanceRequestBody
+//   in: body
+//   description: layout with the nodes per zone
+//   schema:
+//     "$ref": "#/definitions/rebalanceRequest"
+//   required: true
+// responses:
+//   "200":
+//     description: rebalancing plan
+//     schema:
+//       "$ref": "#/definitions/rebalanceResponse"
+func (r *RouteHandler) rebalanceLayout() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		pathParams := GetRecommendationParams{}
+
+		if err := mapstructure.Decode(getPathParamMap(c), &pathParams); err != nil {
+			errorresponse.NewErrorResponder(c).Respond(emperror.Wrap(err, "failed to decode path parameters"))
+			return
+		}
+
+		logger := log.WithFieldsForHandlers(c, r.log,
+			map[string]interface{}{"provider": pathParams.Provider, "service": pathParams.Service, "region": pathParams.Region})
+
+		logger.Info("rebalance layout")
+
+		validator := NewCloudInfoValidator(r.ciCliFor(c))
+		if err := validator.ValidatePathParams(pathParams); err != nil {
+			errorresponse.NewErrorResponder(c).Respond(err)
+			return
+		}
+
+		req := recommender.RebalanceReq{}
+		if err := c.BindJSON(&req); err != nil {
+			errorresponse.NewErrorResponder(c).Respond(
+				emperror.WrapWith(err, "failed to bind request body", classifier.ValidationErrTag))
+			return
+		}
+
+		zones := append([]string(nil), req.Zones...)
+		for _, npd := range req.Layout {
+			for zone := range npd.ZoneNodes {
+				zones = append(zones, zone)
+			}
+		}
+		if err := validator.ValidateZones(pathParams.Provider, pathParams.Service, pathParams.Region, zones); err != nil {
+			errorresponse.NewErrorResponder(c).Respond(emperror.With(err, classifier.ValidationErrTag))
+			return
+		}
+
+		response, err := r.engineFor(c).Rebalance(pathParams.Provider, pathParams.Service, pathParams.Region, req)
+		if err != nil {
+			errorresponse.NewErrorResponder(c).Respond(err)
+			return
+		}
+
+		c.JSON(http.StatusOK, RebalanceResponse{*response})
+	}
+}
+
+// swagger:operation POST /recommender/provider/{provider}/service/{service}/region/{region}/replacements recommend recommendReplacements
+// ---
+// summary: Provides the closest current generation equivalents of instance types.
+// description: Lists the current generation instance types providing at least the cpus, memory, gpus and network performance of the requested ones, with the change of the on-demand price.
+// parameters:
+// - name: provider
+//   in: path
+//   description: provider
+//   required: true
+// - name: service
+//   in: path
+//   description: service
+//   required: true
+// - name: region
+//   in: path
+//   description: region
+//   required: true
+// - name: replacementsRequestBody
+//   in: body
+//   description: instance types to replace
+//   schema:
+//     "$ref": "#/definitions/replacementsRequest"
+//   required: true
+// responses:
+//   "200":
+//     description: replacements per instance type
+//     schema:
+//       "$ref": "#/definitions/replacementsResponse"
+func (r *RouteHandler) recommendReplacements() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		pathParams := GetRecommendationParams{}
+
+		if err := mapstructure.Decode(getPathParamMap(c), &pathParams); err != nil {
+			errorresponse.NewErrorResponder(c).Respond(emperror.Wrap(err, "failed to decode path parameters"))
+			return
+		}
+
+		logger := log.WithFieldsForHandlers(c, r.log,
+			map[string]interface{}{"provider": pathParams.Provider, "service": pathParams.Service, "region": pathParams.Region})
+
+		logger.Info("recommend instance type replacements")
+
+		if err := NewCloudInfoValidator(r.ciCliFor(c)).ValidatePathParams(pathParams); err != nil {
+			errorresponse.NewErrorResponder(c).Respond(err)
+			return
+		}
+
+		req := recommender.ReplacementsReq{}
+		if err := c.BindJSON(&req); err != nil {
+			errorresponse.NewErrorResponder(c).Respond(
+				emperror.WrapWith(err, "failed to bind request body", classifier.ValidationErrTag))
+			return
+		}
+
+		response, err := r.engineFor(c).Replacements(pathParams.Provider, pathParams.Service, pathParams.Region, req)
+		if err != nil {
+			errorresponse.NewErrorResponder(c).Respond(err)
+			return
+		}
+
+		c.JSON(http.StatusOK, ReplacementsResponse{*response})
+	}
+}
+
+// swagger:operation GET /recommender/provider/{provider}/service/{service}/region/{region}/candidates recommend listCandidates
+// ---
+// summary: Lists the candidate vms of a recommendation.
+// description: Provides the filtered vms the node pools of the requested cluster would be selected from, per attribute, ordered by their price per attribute unit.
+// parameters:
+// - name: provider
+//   in: path
+//   description: provider
+//   type: string
+//   required: true
+// - name: service
+//   in: path
+//   description: service
+//   type: string
+//   required: true
+// - name: region
+//   in: path
+//   description: region
+//   type: string
+//   required: true
+// responses:
+//   "200":
+//     description: candidates
+//     schema:
+//       "$ref": "#/definitions/candidatesResponse"
+func (r *RouteHandler) listCandidates() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		pathParams := GetRecommendationParams{}
+
+		if err := mapstructure.Decode(getPathParamMap(c), &pathParams); err != nil {
+			errorresponse.NewErrorResponder(c).Respond(emperror.Wrap(err, "failed to decode path parameters"))
+			return
+		}
+
+		logger := log.WithFieldsForHandlers(c, r.log,
+			map[string]interface{}{"provider": pathParams.Provider, "service": pathParams.Service, "region": pathParams.Region})
+
+		logger.Info("list candidates")
+
+		if err := NewCloudInfoValidator(r.ciCliFor(c)).ValidatePathParams(pathParams); err != nil {
+			errorresponse.NewErrorResponder(c).Respond(err)
+			return
+		}
+
+		query := CandidatesQuery{}
+		if err := c.ShouldBindQuery(&query); err != nil {
+			errorresponse.NewErrorResponder(c).Respond(
+				emperror.WrapWith(err, "failed to bind query parameters", classifier.ValidationErrTag))
+			return
+		}
+
+		if err := r.validateZone(c, pathParams, query.Zone); err != nil {
+			errorresponse.NewErrorResponder(c).Respond(err)
+			return
+		}
+
+		response, err := r.engineFor(c).Candidates(pathParams.Provider, pathParams.Service, pathParams.Region, query.request())
+		if err != nil {
+			errorresponse.NewErrorResponder(c).Respond(err)
+			return
+		}
+
+		c.JSON(http.StatusOK, CandidatesResponse{*response})
+	}
+}
+
+// swagger:operation GET /recommender/provider/{provider}/service/{service}/region/{region}/attributes/{attr} recommend listAttributeValues
+// ---
+// summary: Lists the distinct values of an attribute of the instance types.
+// description: Lists the cpu or memory values of the instance types of the region with their units and the forms reported by the provider, eg. to build node size controls snapping to the real instance sizes.
+// parameters:
+// - name: provider
+//   in: path
+//   description: provider
+//   required: true
+// - name: service
+//   in: path
+//   description: service
+//   required: true
+// - name: region
+//   in: path
+//   description: region
+//   required: true
+// - name: attr
+//   in: path
+//   description: attribute, cpu or memory
+//   required: true
+// responses:
+//   "200":
+//     description: attribute values response
+//     schema:
+//       "$ref": "#/definitions/attributeValuesResponse"
+func (r *RouteHandler) listAttributeValues() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		pathParams := GetRecommendationParams{}
+
+		if err := mapstructure.Decode(getPathParamMap(c), &pathParams); err != nil {
+			errorresponse.NewErrorResponder(c).Respond(emperror.Wrap(err, "failed to decode path parameters"))
+			return
+		}
+
+		attr := c.Param("attr")
+		logger := log.WithFieldsForHandlers(c, r.log, map[string]interface{}{
+			"provider": pathParams.Provider, "service": pathParams.Service, "region": pathParams.Region, "attribute": attr})
+
+		logger.Info("list attribute values")
+
+		if err := NewCloudInfoValidator(r.ciCliFor(c)).ValidatePathParams(pathParams); err != nil {
+			errorresponse.NewErrorResponder(c).Respond(err)
+			return
+		}
+
+		query := AttributeValuesQuery{}
+		if err := c.ShouldBindQuery(&query); err != nil {
+			errorresponse.NewErrorResponder(c).Respond(
+				emperror.WrapWith(err, "failed to bind query parameters", classifier.ValidationErrTag))
+			return
+		}
+
+		units := recommender.Units{Memory: query.MemoryUnit}
+		response, err := r.engineFor(c).AttributeValues(pathParams.Provider, pathParams.Service, pathParams.Region, attr, units)
+		if err != nil {
+			errorresponse.NewErrorResponder(c).Respond(err)
+			return
+		}
+
+		c.JSON(http.StatusOK, AttributeValuesResponse{*response})
+	}
+}
+
+// swagger:operation GET /recommender/filters recommend listFilters
+// ---
+// summary: Lists the filters of the vm types.
+// description: Lists the filters of the vm types taking part in a recommendation, with the providers and attributes they apply to and the request fields controlling them.
+// responses:
+//   "200":
+//     description: filters response
+//     schema:
+//       "$ref": "#/definitions/filtersResponse"
+func (r *RouteHandler) listFilters(c *gin.Context) {
+	c.JSON(http.StatusOK, FiltersResponse{Filters: vms.Filters()})
+}
+
+// swagger:operation POST /recommender/compare recommend compareLayouts
+// ---
+// summary: Compares two layouts.
+// description: Provides the differences of a target layout relative to a base layout, eg. a new recommendation relative to the current cluster.
+// parameters:
+// - name: compareRequestBody
+//   in: body
+//   description: layouts to compare
+//   schema:
+//     "$ref": "#/definitions/compareLayoutsRequest"
+//   required: true
+// responses:
+//   "200":
+//     description: layout comparison
+//     schema:
+//       "$ref": "#/definitions/layoutComparisonResponse"
+func (r *RouteHandler) compareLayouts() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		req := recommender.LayoutComparisonReq{}
+		if err := c.BindJSON(&req); err != nil {
+			errorresponse.NewErrorResponder(c).Respond(
+				emperror.WrapWith(err, "failed to bind request body", classifier.ValidationErrTag))
+			return
+		}
+
+		c.JSON(http.StatusOK, LayoutComparisonResponse{recommender.CompareLayouts(req)})
+	}
+}
+
+// validateZone checks that the requested zone, if any, belongs to the region in the path
+func (r *RouteHandler) validateZone(c *gin.Context, pathParams GetRecommendationParams, zone string) error {
+	if zone == "" {
+		return nil
+	}
+	if err := NewCloudInfoValidator(r.ciCliFor(c)).ValidateZones(pathParams.Provider, pathParams.Service, pathParams.Region,
+		[]string{zone}); err != nil {
+		return emperror.With(err, classifier.ValidationErrTag)
+	}
+	return nil
+}
+
+// bindClusterRecommendationReq binds the request body on top of the defaults of the provider and the referenced profile,
+// if any; the second value reports whether defaults were merged into the request
+func (r *RouteHandler) bindClusterRecommendationReq(c *gin.Context, provider string) (recommender.SingleClusterRecommendationReq, bool, error) {
+	var ref struct {
+		Profile string `json:"profile"`
+	}
+	if err := c.ShouldBindBodyWith(&ref, binding.JSON); err != nil {
+		return recommender.SingleClusterRecommendationReq{}, false, emperror.WrapWith(err, "failed to bind request body", classifier.ValidationErrTag)
+	}
+
+	req := recommender.SingleClusterRecommendationReq{}
+	defaults, defaulted := r.defaults[provider]
+	if defaulted {
+		defaults.ApplyTo(&req)
+	}
+	if ref.Profile != "" {
+		profile, ok := r.profilesFor(c).Get(ref.Profile)
+		if !ok {
+			return req, false, emperror.With(recommender.ErrProfileNotFound, "profile", ref.Profile)
+		}
+		profile.ApplyTo(&req)
+		req.Profile = profile.Name
+		defaulted = true
+	}
+
+	if err := c.ShouldBindBodyWith(&req, binding.JSON); err != nil {
+		return req, false, emperror.WrapWith(err, "failed to bind request body", classifier.ValidationErrTag)
+	}
+	return req, defaulted, nil
+}
+
+// respondRecommendation renders the recommendation in the format requested by the format query parameter, CSV may be requested by the Accept header too
+// The memory and the prices are converted to the units of the request after the response processors ran; the worker
+// nodes are split between the weighted zones of the request, if any, again as the processors may change the node counts
+func (r *RouteHandler) respondRecommendation(c *gin.Context, req *recommender.SingleClusterRecommendationReq, response *recommender.ClusterRecommendationResp, units recommender.Units) {
+	response, err := r.process(response)
+	if err != nil {
+		errorresponse.NewErrorResponder(c).Respond(err)
+		return
+	}
+	if req != nil && len(r.processors) > 0 {
+		if err := recommender.RedistributeZoneNodes(*req, response); err != nil {
+			errorresponse.NewErrorResponder(c).Respond(err)
+			return
+		}
+	}
+	response.ConvertUnits(units)
+	setCloudInfoCalls(c, response.CloudInfoCalls)
+
+	format := c.Query("format")
+	if format == "" && c.NegotiateFormat(gin.MIMEJSON, formats.CSVContentType) == formats.CSVContentType {
+		format = formats.CSV
+	}
+
+	switch format {
+	case "":
+		c.JSON(http.StatusOK, RecommendationResponse{*response})
+	case formats.CSV:
+		data, err := formats.ToCSV(*response)
+		if err != nil {
+			errorresponse.NewErrorResponder(c).Respond(err)
+			return
+		}
+		c.Header("Content-Disposition", `attachment; filename="recommendation.csv"`)
+		c.Data(http.StatusOK, formats.CSVContentType, data)
+	case formats.AsgMixed:
+		asg, err := formats.ToAutoScalingGroup(*response)
+		if err != nil {
+			errorresponse.NewErrorResponder(c).Respond(emperror.With(err, classifier.ValidationErrTag))
+			return
+		}
+		c.JSON(http.StatusOK, asg)
+	case formats.AsgCapacityOptimized:
+		asg, err := formats.ToCapacityOptimizedAutoScalingGroup(*response)
+		if err != nil {
+			errorresponse.NewErrorResponder(c).Respond(emperror.With(err, classifier.ValidationErrTag))
+			return
+		}
+		c.JSON(http.StatusOK, asg)
+	case formats.Mig:
+		mig, err := formats.ToManagedInstanceGroup(*response)
+		if err != nil {
+			errorresponse.NewErrorResponder(c).Respond(emperror.With(err, classifier.ValidationErrTag))
+			return
+		}
+		c.JSON(http.StatusOK, mig)
+	case formats.ClusterAPI, formats.ClusterAPIMachinePool:
+		if c.Query("delta") != "" {
+			// the manifests are applied declaratively, they always hold the replicas of the whole layout
+			errorresponse.NewErrorResponder(c).Respond(emperror.With(
+				errors.Errorf("delta manifests are not supported by the %s format, the manifests describe the whole layout", format),
+				classifier.ValidationErrTag))
+			return
+		}
+		var manifests interface {
+			YAML() ([]byte, error)
+		}
+		if format == formats.ClusterAPI {
+			manifests, err = formats.ToClusterAPI(*response, c.DefaultQuery("clusterName", "cluster"))
+		} else {
+			manifests, err = formats.ToClusterAPIMachinePools(*response, c.DefaultQuery("clusterName", "cluster"))
+		}
+		if err != nil {
+			errorresponse.NewErrorResponder(c).Respond(emperror.With(err, classifier.ValidationErrTag))
+			return
+		}
+		data, err := manifests.YAML()
+		if err != nil {
+			errorresponse.NewErrorResponder(c).Respond(err)
+			return
+		}
+		c.Data(http.StatusOK, "application/yaml", data)
+	default:
+		errorresponse.NewErrorResponder(c).Respond(
+			emperror.With(errors.Errorf("unsupported format: %s", format), classifier.ValidationErrTag))
+	}
+}
+
 func (r *RouteHandler) versionHandler(c *gin.Context) {
 	c.JSON(http.StatusOK, r.buildInfo)
 }
@@ -212,3 +872,8 @@ func getPathParamMap(c *gin.Context) map[string]string {
 	}
 	return pm
 }
+
+// setCloudInfoCalls reports the number of calls the recommendation made to the cloud info service in a response header
+func setCloudInfoCalls(c *gin.Context, calls int) {
+	c.Header(CloudInfoCallsHeader, strconv.Itoa(calls))
+}
@@ -0,0 +1,80 @@
+// Copyright © 2019 Banzai Cloud
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package api
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/banzaicloud/telescopes/internal/platform/buildinfo"
+	"github.com/banzaicloud/telescopes/pkg/recommender"
+	"github.com/gin-gonic/gin"
+	"github.com/goph/logur"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMain(m *testing.M) {
+	gin.SetMode(gin.TestMode)
+	if err := ConfigureValidator(); err != nil {
+		panic(err)
+	}
+	os.Exit(m.Run())
+}
+
+func TestRouteHandler_bindClusterRecommendationReq(t *testing.T) {
+	allowOlderGen := false
+	profiles := recommender.NewInMemoryProfileStore()
+	profiles.Put(recommender.Profile{Name: "prod-eu", Includes: []string{"m5.large", "m5.xlarge"}})
+	r := NewRouteHandler(nil, buildinfo.BuildInfo{}, nil, profiles, logur.NewTestLogger())
+	r.EnableProviderDefaults(map[string]recommender.Profile{
+		"amazon": {Excludes: []string{"t2.micro"}, NetworkPerf: []string{"high"}, AllowOlderGen: &allowOlderGen},
+	})
+
+	bind := func(body string) (recommender.SingleClusterRecommendationReq, error) {
+		c, _ := gin.CreateTestContext(httptest.NewRecorder())
+		c.Request = httptest.NewRequest(http.MethodPost, "/", strings.NewReader(body))
+		req, _, err := r.bindClusterRecommendationReq(c, "amazon")
+		return req, err
+	}
+
+	bodies := []string{
+		`{"sumCpu": 8, "sumMem": 32, "minNodes": 1, "maxNodes": 4, "profile": "prod-eu", "excludes": ["c5.large"], "includes": ["r5.large"]}`,
+		`{"sumCpu": 8, "sumMem": 32, "minNodes": 1, "maxNodes": 4, "networkPerf": ["low"], "allowOlderGen": true}`,
+	}
+	var wg sync.WaitGroup
+	for _, body := range bodies {
+		body := body
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			_, err := bind(body)
+			assert.NoError(t, err)
+		}()
+	}
+	wg.Wait()
+
+	req, err := bind(`{"sumCpu": 8, "sumMem": 32, "minNodes": 1, "maxNodes": 4, "profile": "prod-eu"}`)
+	if !assert.NoError(t, err) {
+		return
+	}
+	assert.Equal(t, []string{"t2.micro"}, req.Excludes, "the provider defaults should not be changed by the requests")
+	assert.Equal(t, []string{"high"}, req.NetworkPerf, "the provider defaults should not be changed by the requests")
+	assert.False(t, *req.AllowOlderGen, "the provider defaults should not be changed by the requests")
+	assert.Equal(t, []string{"m5.large", "m5.xlarge"}, req.Includes, "the profile should not be changed by the requests")
+}
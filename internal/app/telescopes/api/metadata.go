@@ -0,0 +1,46 @@
+// Copyright © 2019 Banzai Cloud
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package api
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/banzaicloud/telescopes/internal/platform/errorresponse"
+	"github.com/banzaicloud/telescopes/internal/platform/log"
+)
+
+// swagger:operation GET /recommender/metadata recommend getMetadata
+// ---
+// summary: Lists the providers, services, regions and zones.
+// description: Provides the providers with their services, and the regions and zones of the services in a single payload, eg. for the selection lists of user interfaces.
+// responses:
+//   "200":
+//     description: metadata response
+//     schema:
+//       "$ref": "#/definitions/metadataResponse"
+func (r *RouteHandler) getMetadata(c *gin.Context) {
+	logger := log.WithFieldsForHandlers(c, r.log, nil)
+	logger.Info("list metadata")
+
+	metadata, err := r.engineFor(c).Metadata()
+	if err != nil {
+		errorresponse.NewErrorResponder(c).Respond(err)
+		return
+	}
+
+	c.JSON(http.StatusOK, MetadataResponse{*metadata})
+}
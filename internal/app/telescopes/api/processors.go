@@ -0,0 +1,99 @@
+// Copyright © 2019 Banzai Cloud
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package api
+
+import (
+	"github.com/goph/emperror"
+
+	"github.com/banzaicloud/telescopes/pkg/recommender"
+)
+
+// ResponseProcessor transforms the cluster recommendations before they are returned,
+// so deployments can apply their conventions without modifying the engine
+type ResponseProcessor interface {
+	// Process transforms the recommendation, the transformed recommendation is passed to the next processor
+	Process(resp *recommender.ClusterRecommendationResp) (*recommender.ClusterRecommendationResp, error)
+}
+
+// ResponseProcessorFunc adapts a function to a ResponseProcessor
+type ResponseProcessorFunc func(resp *recommender.ClusterRecommendationResp) (*recommender.ClusterRecommendationResp, error)
+
+// Process calls the function
+func (f ResponseProcessorFunc) Process(resp *recommender.ClusterRecommendationResp) (*recommender.ClusterRecommendationResp, error) {
+	return f(resp)
+}
+
+// AddResponseProcessors registers processors applied to every cluster recommendation, in the order of registration
+func (r *RouteHandler) AddResponseProcessors(processors ...ResponseProcessor) {
+	r.processors = append(r.processors, processors...)
+}
+
+// process runs the registered processors on the recommendation
+func (r *RouteHandler) process(resp *recommender.ClusterRecommendationResp) (*recommender.ClusterRecommendationResp, error) {
+	var err error
+	for _, p := range r.processors {
+		if resp, err = p.Process(resp); err != nil {
+			return nil, emperror.Wrap(err, "failed to process the recommendation")
+		}
+	}
+	return resp, nil
+}
+
+// processMultiRegion runs the registered processors on the recommendation of each region
+func (r *RouteHandler) processMultiRegion(resp *recommender.MultiRegionRecommendationResp) error {
+	if len(r.processors) == 0 {
+		return nil
+	}
+	for i := range resp.Regions {
+		processed, err := r.process(&resp.Regions[i])
+		if err != nil {
+			return err
+		}
+		resp.Regions[i] = *processed
+	}
+	resp.RecalculateAccuracy()
+	return nil
+}
+
+// EvenNodeCounts rounds up the node counts of the worker node pools to even numbers, pools to be drained are left as they are
+func EvenNodeCounts() ResponseProcessor {
+	return ResponseProcessorFunc(func(resp *recommender.ClusterRecommendationResp) (*recommender.ClusterRecommendationResp, error) {
+		for i, np := range resp.NodePools {
+			if np.Role == recommender.Worker && np.SuggestedAction != recommender.ActionDrain && np.SumNodes%2 != 0 {
+				resp.NodePools[i].SumNodes++
+			}
+		}
+		resp.RecalculateAccuracy()
+		return resp, nil
+	})
+}
+
+// NodePoolLabels adds the labels to the node pools of the recommendation, the labels of a node pool take precedence
+func NodePoolLabels(labels map[string]string) ResponseProcessor {
+	return ResponseProcessorFunc(func(resp *recommender.ClusterRecommendationResp) (*recommender.ClusterRecommendationResp, error) {
+		for i := range resp.NodePools {
+			np := &resp.NodePools[i]
+			if np.Labels == nil {
+				np.Labels = make(map[string]string, len(labels))
+			}
+			for k, v := range labels {
+				if _, ok := np.Labels[k]; !ok {
+					np.Labels[k] = v
+				}
+			}
+		}
+		return resp, nil
+	})
+}
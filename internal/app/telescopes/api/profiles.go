@@ -0,0 +1,121 @@
+// Copyright © 2019 Banzai Cloud
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package api
+
+import (
+	"net/http"
+
+	"github.com/banzaicloud/telescopes/internal/platform/classifier"
+	"github.com/banzaicloud/telescopes/internal/platform/errorresponse"
+	"github.com/banzaicloud/telescopes/pkg/recommender"
+	"github.com/gin-gonic/gin"
+	"github.com/goph/emperror"
+)
+
+// EnableProviderDefaults merges the request defaults of the provider into the cluster recommendation requests, before
+// the defaults of the referenced profile and the request body; the requests get copies of the defaults, the bodies
+// decoded on top of them don't change the defaults shared by the requests
+func (r *RouteHandler) EnableProviderDefaults(defaults map[string]recommender.Profile) {
+	r.defaults = defaults
+}
+
+// swagger:operation GET /profiles profiles listProfiles
+// ---
+// summary: Lists the recommendation profiles.
+// description: Lists the recommendation profiles, requests may reference them to inherit their defaults.
+// responses:
+//   "200":
+//     description: profiles response
+//     schema:
+//       "$ref": "#/definitions/profilesResponse"
+func (r *RouteHandler) listProfiles(c *gin.Context) {
+	c.JSON(http.StatusOK, ProfilesResponse{Profiles: r.profilesFor(c).List()})
+}
+
+// swagger:operation GET /profiles/{name} profiles getProfile
+// ---
+// summary: Gets a recommendation profile.
+// description: Gets a recommendation profile.
+// parameters:
+// - name: name
+//   in: path
+//   description: name of the profile
+//   required: true
+// responses:
+//   "200":
+//     description: profile
+//     schema:
+//       "$ref": "#/definitions/Profile"
+func (r *RouteHandler) getProfile(c *gin.Context) {
+	profile, ok := r.profilesFor(c).Get(c.Param("name"))
+	if !ok {
+		errorresponse.NewErrorResponder(c).Respond(emperror.With(recommender.ErrProfileNotFound, "profile", c.Param("name")))
+		return
+	}
+	c.JSON(http.StatusOK, profile)
+}
+
+// swagger:operation PUT /profiles/{name} profiles putProfile
+// ---
+// summary: Creates or replaces a recommendation profile.
+// description: Creates or replaces a recommendation profile, the name in the path takes precedence over the one in the body.
+// parameters:
+// - name: name
+//   in: path
+//   description: name of the profile
+//   required: true
+// - name: profile
+//   in: body
+//   description: request defaults
+//   schema:
+//     "$ref": "#/definitions/Profile"
+//   required: true
+// responses:
+//   "200":
+//     description: profile
+//     schema:
+//       "$ref": "#/definitions/Profile"
+func (r *RouteHandler) putProfile(c *gin.Context) {
+	profile := recommender.Profile{}
+	if err := c.ShouldBindJSON(&profile); err != nil {
+		errorresponse.NewErrorResponder(c).Respond(
+			emperror.WrapWith(err, "failed to bind request body", classifier.ValidationErrTag))
+		return
+	}
+	profile.Name = c.Param("name")
+
+	r.profilesFor(c).Put(profile)
+	c.JSON(http.StatusOK, profile)
+}
+
+// swagger:operation DELETE /profiles/{name} profiles deleteProfile
+// ---
+// summary: Deletes a recommendation profile.
+// description: Deletes a recommendation profile.
+// parameters:
+// - name: name
+//   in: path
+//   description: name of the profile
+//   required: true
+// responses:
+//   "204":
+//     description: the profile is deleted
+func (r *RouteHandler) deleteProfile(c *gin.Context) {
+	if !r.profilesFor(c).Delete(c.Param("name")) {
+		errorresponse.NewErrorResponder(c).Respond(emperror.With(recommender.ErrProfileNotFound, "profile", c.Param("name")))
+		return
+	}
+	c.Status(http.StatusNoContent)
+}
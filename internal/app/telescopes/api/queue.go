@@ -0,0 +1,53 @@
+// Copyright © 2019 Banzai Cloud
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package api
+
+import (
+	"github.com/gin-gonic/gin"
+
+	"github.com/banzaicloud/telescopes/internal/platform/errorresponse"
+	"github.com/banzaicloud/telescopes/pkg/recommender"
+)
+
+// PriorityHeader is the request header selecting the priority class of a recommendation (interactive or batch)
+const PriorityHeader = "X-Recommendation-Priority"
+
+// EnableQueue makes the recommendations wait for a worker of the queue, in the order of their priority class
+func (r *RouteHandler) EnableQueue(queue *recommender.WorkQueue) {
+	r.queue = queue
+}
+
+// queueMiddleware serves the API call when the queue grants a worker to its priority class; calls are rejected
+// when the queue of their class is full
+func (r *RouteHandler) queueMiddleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		priority, err := recommender.ParsePriority(c.GetHeader(PriorityHeader))
+		if err != nil {
+			errorresponse.NewErrorResponder(c).Respond(err)
+			c.Abort()
+			return
+		}
+		if err := r.queue.Acquire(c.Request.Context(), priority); err != nil {
+			if err == recommender.ErrQueueFull {
+				c.Header("Retry-After", "1")
+			}
+			errorresponse.NewErrorResponder(c).Respond(err)
+			c.Abort()
+			return
+		}
+		defer r.queue.Release(priority)
+		c.Next()
+	}
+}
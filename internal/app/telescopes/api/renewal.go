@@ -0,0 +1,41 @@
+// Copyright © 2019 Banzai Cloud
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package api
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/banzaicloud/telescopes/pkg/recommender"
+)
+
+// EnableRenewalStatus makes the admin API serve the status of the periodic product catalog retrievals of the watcher
+func (r *RouteHandler) EnableRenewalStatus(watcher *recommender.CatalogWatcher) {
+	r.catalog = watcher
+}
+
+// swagger:operation GET /admin/renewal/status admin renewalStatus
+// ---
+// summary: Provides the status of the periodic product catalog retrievals.
+// description: Provides the last successful retrieval time, duration, number of products and the last error per provider, service and region.
+// responses:
+//   "200":
+//     description: renewal status
+//     schema:
+//       "$ref": "#/definitions/renewalStatusResponse"
+func (r *RouteHandler) renewalStatus(c *gin.Context) {
+	c.JSON(http.StatusOK, RenewalStatusResponse{Renewals: r.catalog.RenewalStatus()})
+}
@@ -26,28 +26,48 @@ import (
 
 	"github.com/banzaicloud/telescopes/internal/platform/buildinfo"
 	"github.com/banzaicloud/telescopes/internal/platform/log"
+	"github.com/banzaicloud/telescopes/pkg/jws"
 	"github.com/banzaicloud/telescopes/pkg/recommender"
 )
 
 const (
 	// environment variable name to override base path if necessary
 	appBasePath = "TELESCOPES_BASEPATH"
+
+	// CloudInfoCallsHeader is the response header holding the number of calls a recommendation made to the cloud info service
+	CloudInfoCallsHeader = "X-Cloud-Info-Calls"
+
+	// SignatureHeader is the response header holding the detached JWS of the response body, if signing is enabled
+	SignatureHeader = "X-Recommendation-Signature"
 )
 
 // RouteHandler struct that wraps the recommender engine
 type RouteHandler struct {
-	engine    recommender.ClusterRecommender
-	buildInfo buildinfo.BuildInfo
-	ciCli     recommender.CloudInfoSource
-	log       logur.Logger
+	engine      recommender.ClusterRecommender
+	buildInfo   buildinfo.BuildInfo
+	ciCli       recommender.CloudInfoSource
+	profiles    recommender.ProfileStore
+	defaults    map[string]recommender.Profile
+	processors  []ResponseProcessor
+	tenancy     *recommender.Tenancy
+	adminTokens map[string]bool
+	signer      *jws.Signer
+	usage       recommender.UsageStore
+	snapshots   recommender.SnapshotStore
+	catalog     *recommender.CatalogWatcher
+	queue       *recommender.WorkQueue
+	faults      *recommender.FaultInjector
+	config      *RuntimeConfig
+	log         logur.Logger
 }
 
 // NewRouteHandler creates a new RouteHandler and returns a reference to it
-func NewRouteHandler(engine recommender.ClusterRecommender, info buildinfo.BuildInfo, ciCli recommender.CloudInfoSource, log logur.Logger) *RouteHandler {
+func NewRouteHandler(engine recommender.ClusterRecommender, info buildinfo.BuildInfo, ciCli recommender.CloudInfoSource, profiles recommender.ProfileStore, log logur.Logger) *RouteHandler {
 	return &RouteHandler{
 		engine:    engine,
 		buildInfo: info,
 		ciCli:     ciCli,
+		profiles:  profiles,
 		log:       log,
 	}
 }
@@ -76,13 +96,69 @@ func (r *RouteHandler) ConfigureRoutes(router *gin.Engine) {
 		base.GET("/version", r.versionHandler)
 	}
 
+	// the endpoints exposing the data of all the tenants or changing the shared state need an admin token with tenancy
+	restricted := base.Group("")
+	if r.tenancy != nil {
+		restricted.Use(r.adminMiddleware())
+	}
+	restricted.GET("/status/freshness", r.dataFreshnessStatus)
+	if r.config != nil {
+		restricted.GET("/config", r.runtimeConfig)
+	}
+
+	adminGroup := restricted.Group("/admin")
+	if r.usage != nil {
+		adminGroup.GET("/usage", r.usageSummary)
+	}
+	if r.catalog != nil {
+		adminGroup.GET("/renewal/status", r.renewalStatus)
+	}
+	if r.faults != nil {
+		adminGroup.GET("/faults", r.listFaults)
+		adminGroup.PUT("/faults", r.putFault)
+		adminGroup.DELETE("/faults", r.clearFaults)
+	}
+
 	v1 := base.Group("/api/v1")
+	if r.tenancy != nil {
+		v1.Use(r.tenantMiddleware())
+	}
 
 	recGroup := v1.Group("/recommender")
+	if r.queue != nil {
+		recGroup.Use(r.queueMiddleware())
+	}
+	if r.signer != nil {
+		recGroup.Use(r.signingMiddleware())
+	}
 	{
 		recGroup.POST("/multicloud", r.recommendMultiCluster())
 		recGroup.POST("/provider/:provider/service/:service/region/:region/cluster", r.recommendCluster())
 		recGroup.PUT("/provider/:provider/service/:service/region/:region/cluster", r.recommendClusterScaleOut())
+		recGroup.POST("/provider/:provider/service/:service/multiregion", r.recommendMultiRegion())
+		recGroup.POST("/provider/:provider/service/:service/region/:region/backtest", r.backtestLayout())
+		recGroup.POST("/provider/:provider/service/:service/region/:region/rebalance", r.rebalanceLayout())
+		recGroup.POST("/provider/:provider/service/:service/region/:region/replacements", r.recommendReplacements())
+		recGroup.GET("/provider/:provider/service/:service/region/:region/candidates", r.listCandidates())
+		recGroup.GET("/provider/:provider/service/:service/region/:region/attributes/:attr", r.listAttributeValues())
+		recGroup.POST("/compare", r.compareLayouts())
+		recGroup.GET("/filters", r.listFilters)
+		recGroup.GET("/metadata", r.getMetadata)
+	}
+
+	if r.snapshots != nil {
+		snapshotGroup := v1.Group("/snapshots")
+		{
+			snapshotGroup.GET("/:id", r.getSnapshot)
+		}
+	}
+
+	profileGroup := v1.Group("/profiles")
+	{
+		profileGroup.GET("", r.listProfiles)
+		profileGroup.GET("/:name", r.getProfile)
+		profileGroup.PUT("/:name", r.putProfile)
+		profileGroup.DELETE("/:name", r.deleteProfile)
 	}
 }
 
@@ -95,6 +171,11 @@ func (r *RouteHandler) signalStatus(c *gin.Context) {
 	c.JSON(http.StatusOK, "ok")
 }
 
+// dataFreshnessStatus summarizes the age of the product and price data retrieved per provider/service/region
+func (r *RouteHandler) dataFreshnessStatus(c *gin.Context) {
+	c.JSON(http.StatusOK, DataFreshnessResponse{Freshness: r.ciCli.GetAllDataFreshness()})
+}
+
 func (r *RouteHandler) EnableMetrics(router *gin.Engine, metricsAddr string) {
 	p := ginprometheus.NewPrometheus("http", []string{"provider", "service", "region"})
 	p.SetListenAddress(metricsAddr)
@@ -0,0 +1,65 @@
+// Copyright © 2019 Banzai Cloud
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package api
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/banzaicloud/telescopes/internal/platform/buildinfo"
+	"github.com/banzaicloud/telescopes/pkg/recommender"
+	"github.com/gin-gonic/gin"
+	"github.com/goph/logur"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRouteHandler_ConfigureRoutes_adminTokens(t *testing.T) {
+	engine := recommender.NewEngine(logur.NewTestLogger(), nil, nil, nil)
+	tenancy, err := recommender.NewTenancy(engine, nil, []recommender.Tenant{{Name: "team-a", Tokens: []string{"tenant"}}}, nil, nil)
+	if !assert.NoError(t, err) {
+		return
+	}
+
+	r := NewRouteHandler(engine, buildinfo.BuildInfo{}, nil, recommender.NewInMemoryProfileStore(), logur.NewTestLogger())
+	r.EnableTenancy(tenancy, []string{"admin"})
+	r.EnableRuntimeConfig(RuntimeConfig{})
+	r.EnableUsage(recommender.NewInMemoryUsageStore(time.Hour))
+	router := gin.New()
+	r.ConfigureRoutes(router)
+
+	tests := []struct {
+		path   string
+		token  string
+		status int
+	}{
+		{path: "/status", status: http.StatusOK},
+		{path: "/config", status: http.StatusUnauthorized},
+		{path: "/config", token: "tenant", status: http.StatusUnauthorized},
+		{path: "/config", token: "admin", status: http.StatusOK},
+		{path: "/admin/usage", token: "tenant", status: http.StatusUnauthorized},
+		{path: "/admin/usage", token: "admin", status: http.StatusOK},
+	}
+	for _, test := range tests {
+		req := httptest.NewRequest(http.MethodGet, test.path, nil)
+		if test.token != "" {
+			req.Header.Set("Authorization", "Bearer "+test.token)
+		}
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		assert.Equal(t, test.status, w.Code, "%s with token %q", test.path, test.token)
+	}
+}
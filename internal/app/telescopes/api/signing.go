@@ -0,0 +1,65 @@
+// Copyright © 2019 Banzai Cloud
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package api
+
+import (
+	"bytes"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/banzaicloud/telescopes/pkg/jws"
+)
+
+// EnableSigning makes the recommender sign the successful responses of the recommendation routes,
+// the detached JWS of the response body is returned in the SignatureHeader
+func (r *RouteHandler) EnableSigning(signer *jws.Signer) {
+	r.signer = signer
+}
+
+// signingWriter holds back the response body until it's signed
+type signingWriter struct {
+	gin.ResponseWriter
+	body bytes.Buffer
+}
+
+func (w *signingWriter) Write(data []byte) (int, error) {
+	return w.body.Write(data)
+}
+
+func (w *signingWriter) WriteString(s string) (int, error) {
+	return w.body.WriteString(s)
+}
+
+// signingMiddleware signs the response body of successful calls
+func (r *RouteHandler) signingMiddleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		writer := &signingWriter{ResponseWriter: c.Writer}
+		c.Writer = writer
+		c.Next()
+		c.Writer = writer.ResponseWriter
+
+		if writer.Status() < http.StatusMultipleChoices {
+			signature, err := r.signer.Sign(writer.body.Bytes())
+			if err != nil {
+				r.log.Error("failed to sign the response", map[string]interface{}{"error": err.Error()})
+				c.AbortWithStatus(http.StatusInternalServerError)
+				return
+			}
+			c.Header(SignatureHeader, signature)
+		}
+		_, _ = c.Writer.Write(writer.body.Bytes())
+	}
+}
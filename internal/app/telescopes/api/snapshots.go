@@ -0,0 +1,53 @@
+// Copyright © 2019 Banzai Cloud
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package api
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/goph/emperror"
+
+	"github.com/banzaicloud/telescopes/internal/platform/errorresponse"
+	"github.com/banzaicloud/telescopes/pkg/recommender"
+)
+
+// EnableSnapshots makes the API serve the product snapshots the recommendations were made with
+func (r *RouteHandler) EnableSnapshots(store recommender.SnapshotStore) {
+	r.snapshots = store
+}
+
+// swagger:operation GET /snapshots/{id} snapshots getSnapshot
+// ---
+// summary: Gets a product snapshot.
+// description: Gets the products and prices a recommendation was made with, referenced by the snapshot field of the recommendation.
+// parameters:
+// - name: id
+//   in: path
+//   description: ID of the snapshot
+//   required: true
+// responses:
+//   "200":
+//     description: product snapshot
+//     schema:
+//       "$ref": "#/definitions/ProductSnapshot"
+func (r *RouteHandler) getSnapshot(c *gin.Context) {
+	snapshot, ok := r.snapshots.Get(c.Param("id"))
+	if !ok {
+		errorresponse.NewErrorResponder(c).Respond(emperror.With(recommender.ErrSnapshotNotFound, "snapshot", c.Param("id")))
+		return
+	}
+	c.JSON(http.StatusOK, snapshot)
+}
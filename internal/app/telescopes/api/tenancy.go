@@ -0,0 +1,103 @@
+// Copyright © 2019 Banzai Cloud
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package api
+
+import (
+	"strings"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/banzaicloud/telescopes/internal/platform/errorresponse"
+	"github.com/banzaicloud/telescopes/pkg/recommender"
+)
+
+// tenantContextKey is the key of the tenant context of the API call in the gin context
+const tenantContextKey = "tenant"
+
+// EnableTenancy makes the API calls resolve their tenant from the bearer token of the Authorization header,
+// the recommendations of a tenant are served by its own engine, product data source and recommendation profiles
+// The admin and configuration endpoints, exposing the data of all the tenants or changing the shared state, are
+// served for the admin tokens only, they are closed if there are none
+func (r *RouteHandler) EnableTenancy(tenancy *recommender.Tenancy, adminTokens []string) {
+	r.tenancy = tenancy
+	r.adminTokens = make(map[string]bool, len(adminTokens))
+	for _, token := range adminTokens {
+		if token != "" {
+			r.adminTokens[token] = true
+		}
+	}
+}
+
+// tenantMiddleware resolves the tenant of the API call, calls with unknown tokens are rejected
+func (r *RouteHandler) tenantMiddleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		token := strings.TrimPrefix(c.GetHeader("Authorization"), "Bearer ")
+		tc, ok := r.tenancy.Resolve(token)
+		if !ok {
+			errorresponse.NewErrorResponder(c).Respond(recommender.ErrUnknownTenant)
+			c.Abort()
+			return
+		}
+		c.Set(tenantContextKey, tc)
+		c.Next()
+	}
+}
+
+// adminMiddleware rejects the calls without an admin token
+func (r *RouteHandler) adminMiddleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		token := strings.TrimPrefix(c.GetHeader("Authorization"), "Bearer ")
+		if !r.adminTokens[token] {
+			errorresponse.NewErrorResponder(c).Respond(recommender.ErrAdminTokenRequired)
+			c.Abort()
+			return
+		}
+		c.Next()
+	}
+}
+
+// tenantContext gets the tenant context of the API call, returns false if tenancy is disabled
+func tenantContext(c *gin.Context) (*recommender.TenantContext, bool) {
+	v, ok := c.Get(tenantContextKey)
+	if !ok {
+		return nil, false
+	}
+	tc, ok := v.(*recommender.TenantContext)
+	return tc, ok
+}
+
+// engineFor gets the engine serving the API call: the engine of its tenant, or the shared one
+func (r *RouteHandler) engineFor(c *gin.Context) recommender.ClusterRecommender {
+	if tc, ok := tenantContext(c); ok {
+		return tc.Recommender
+	}
+	return r.engine
+}
+
+// ciCliFor gets the product data source of the API call: the source of its tenant, or the shared one
+func (r *RouteHandler) ciCliFor(c *gin.Context) recommender.CloudInfoSource {
+	if tc, ok := tenantContext(c); ok {
+		return tc.CiSource
+	}
+	return r.ciCli
+}
+
+// profilesFor gets the recommendation profiles of the API call: the profiles of its tenant, or the shared ones
+func (r *RouteHandler) profilesFor(c *gin.Context) recommender.ProfileStore {
+	if tc, ok := tenantContext(c); ok {
+		return tc.Profiles
+	}
+	return r.profiles
+}
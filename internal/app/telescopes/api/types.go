@@ -34,3 +34,148 @@ type GetRecommendationParams struct {
 type RecommendationResponse struct {
 	recommender.ClusterRecommendationResp
 }
+
+// MultiRegionRecommendationResponse encapsulates the multi-region recommendation response
+// swagger:model multiRegionRecommendationResponse
+type MultiRegionRecommendationResponse struct {
+	recommender.MultiRegionRecommendationResp
+}
+
+// DataFreshnessResponse encapsulates the data freshness summary
+// swagger:model dataFreshnessResponse
+type DataFreshnessResponse struct {
+	Freshness []recommender.DataFreshness `json:"freshness"`
+}
+
+// ProfilesResponse encapsulates the recommendation profiles
+// swagger:model profilesResponse
+type ProfilesResponse struct {
+	Profiles []recommender.Profile `json:"profiles"`
+}
+
+// LayoutComparisonResponse encapsulates the differences of two layouts
+// swagger:model layoutComparisonResponse
+type LayoutComparisonResponse struct {
+	recommender.LayoutComparison
+}
+
+// BacktestResponse encapsulates the cost of a layout over a window in the past
+// swagger:model backtestResponse
+type BacktestResponse struct {
+	recommender.Backtest
+}
+
+// RebalanceResponse encapsulates the plan evening out the nodes of a layout between the zones
+// swagger:model rebalanceResponse
+type RebalanceResponse struct {
+	recommender.Rebalance
+}
+
+// ReplacementsResponse encapsulates the current generation replacements of instance types
+// swagger:model replacementsResponse
+type ReplacementsResponse struct {
+	recommender.Replacements
+}
+
+// AttributeValuesResponse encapsulates the distinct values of an attribute of the instance types
+// swagger:model attributeValuesResponse
+type AttributeValuesResponse struct {
+	recommender.AttributeValues
+}
+
+// AttributeValuesQuery holds the query parameters of the attribute values route
+// swagger:parameters listAttributeValues
+type AttributeValuesQuery struct {
+	// Unit of the memory values: GiB (the default) or GB
+	// in:query
+	MemoryUnit string `form:"memoryUnit" binding:"omitempty,memoryUnit"`
+}
+
+// FiltersResponse encapsulates the capabilities of the vm filters
+// swagger:model filtersResponse
+type FiltersResponse struct {
+	Filters []recommender.FilterCapability `json:"filters"`
+}
+
+// CandidatesResponse encapsulates the candidate vms of a recommendation
+// swagger:model candidatesResponse
+type CandidatesResponse struct {
+	recommender.CandidatesResp
+}
+
+// CandidatesQuery holds the query parameters of the candidates route
+// swagger:parameters listCandidates
+type CandidatesQuery struct {
+	// Total number of CPUs requested for the cluster
+	// in:query
+	Cpu float64 `form:"cpu" binding:"min=1"`
+	// Total memory requested for the cluster (GB)
+	// in:query
+	Mem float64 `form:"mem" binding:"min=1"`
+	// Minimum number of nodes in the cluster, 1 if not set
+	// in:query
+	MinNodes int `form:"minNodes"`
+	// Maximum number of nodes in the cluster, the minimum number of nodes if not set
+	// in:query
+	MaxNodes int `form:"maxNodes"`
+	// Availability zone of the cluster
+	// in:query
+	Zone string `form:"zone"`
+	// Instance types to select from
+	// in:query
+	Includes []string `form:"includes"`
+	// Instance types excluded from the candidates
+	// in:query
+	Excludes []string `form:"excludes"`
+	// Optimize for the price per benchmark unit instead of the price per cpu
+	// in:query
+	OptimizeFor string `form:"optimizeFor" binding:"omitempty,optimizeFor"`
+	// Rank the spot candidates by their recent interruptions as well
+	// in:query
+	AvoidRecentlyInterrupted bool `form:"avoidRecentlyInterrupted"`
+}
+
+// request builds the recommendation request the candidates are selected for
+func (q CandidatesQuery) request() recommender.SingleClusterRecommendationReq {
+	req := recommender.SingleClusterRecommendationReq{}
+	req.SumCpu = q.Cpu
+	req.SumMem = q.Mem
+	req.MinNodes = q.MinNodes
+	if req.MinNodes == 0 {
+		req.MinNodes = 1
+	}
+	req.MaxNodes = q.MaxNodes
+	if req.MaxNodes == 0 {
+		req.MaxNodes = req.MinNodes
+	}
+	req.Zone = q.Zone
+	req.Includes = q.Includes
+	req.Excludes = q.Excludes
+	req.OptimizeFor = q.OptimizeFor
+	req.AvoidRecentlyInterrupted = q.AvoidRecentlyInterrupted
+	return req
+}
+
+// RenewalStatusResponse encapsulates the status of the periodic product catalog retrievals
+// swagger:model renewalStatusResponse
+type RenewalStatusResponse struct {
+	Renewals []recommender.RenewalStatus `json:"renewals"`
+}
+
+// MetadataResponse encapsulates the providers, services, regions and zones
+// swagger:model metadataResponse
+type MetadataResponse struct {
+	recommender.Metadata
+}
+
+// FaultsResponse encapsulates the faults injected into the cloud info calls
+// swagger:model faultsResponse
+type FaultsResponse struct {
+	Faults []recommender.Fault `json:"faults"`
+}
+
+// UsageResponse encapsulates the usage summary of the service
+// swagger:model usageResponse
+type UsageResponse struct {
+	recommender.UsageSummary
+}
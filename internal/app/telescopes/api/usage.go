@@ -0,0 +1,75 @@
+// Copyright © 2019 Banzai Cloud
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package api
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/goph/emperror"
+	"github.com/pkg/errors"
+
+	"github.com/banzaicloud/telescopes/internal/platform/classifier"
+	"github.com/banzaicloud/telescopes/internal/platform/errorresponse"
+	"github.com/banzaicloud/telescopes/pkg/recommender"
+)
+
+// EnableUsage makes the API record the recommendations served into the usage store, and serve their summary on the admin API
+func (r *RouteHandler) EnableUsage(store recommender.UsageStore) {
+	r.usage = store
+}
+
+// recordUsage records the recommendation served, if usage tracking is enabled
+func (r *RouteHandler) recordUsage(record recommender.UsageRecord) {
+	if r.usage != nil {
+		r.usage.Record(record)
+	}
+}
+
+// swagger:operation GET /admin/usage admin usageSummary
+// ---
+// summary: Summarizes the usage of the service.
+// description: Provides the number of recommendations served, the top providers and regions, the average requested resources and the spot adoption over a sliding window.
+// parameters:
+// - name: window
+//   in: query
+//   description: length of the window (eg. 1h), at most the window the usage is tracked for
+//   type: string
+// responses:
+//   "200":
+//     description: usage summary
+//     schema:
+//       "$ref": "#/definitions/usageResponse"
+func (r *RouteHandler) usageSummary(c *gin.Context) {
+	window := r.usage.Window()
+	if w := c.Query("window"); w != "" {
+		d, err := time.ParseDuration(w)
+		if err == nil && d <= 0 {
+			err = errors.New("window must be positive")
+		}
+		if err != nil {
+			errorresponse.NewErrorResponder(c).Respond(
+				emperror.WrapWith(err, "failed to parse the window", classifier.ValidationErrTag))
+			return
+		}
+		if d < window {
+			window = d
+		}
+	}
+
+	since := time.Now().Add(-window)
+	c.JSON(http.StatusOK, UsageResponse{recommender.SummarizeUsage(r.usage.Since(since), since)})
+}
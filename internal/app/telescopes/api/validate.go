@@ -48,6 +48,21 @@ func ConfigureValidator() error {
 	if err := v.RegisterValidation("category", categoryValidator()); err != nil {
 		return emperror.Wrap(err, "could not register category validator")
 	}
+	if err := v.RegisterValidation("optimizeFor", optimizeForValidator()); err != nil {
+		return emperror.Wrap(err, "could not register optimizeFor validator")
+	}
+	if err := v.RegisterValidation("resiliency", resiliencyValidator()); err != nil {
+		return emperror.Wrap(err, "could not register resiliency validator")
+	}
+	if err := v.RegisterValidation("nodeImage", nodeImageValidator()); err != nil {
+		return emperror.Wrap(err, "could not register nodeImage validator")
+	}
+	if err := v.RegisterValidation("memoryUnit", memoryUnitValidator()); err != nil {
+		return emperror.Wrap(err, "could not register memoryUnit validator")
+	}
+	if err := v.RegisterValidation("priceUnit", priceUnitValidator()); err != nil {
+		return emperror.Wrap(err, "could not register priceUnit validator")
+	}
 
 	return nil
 }
@@ -79,6 +94,52 @@ func categoryValidator() validator.Func {
 	}
 }
 
+// optimizeForValidator validates the optimization unit in the recommendation request.
+func optimizeForValidator() validator.Func {
+	return func(v *validator.Validate, topStruct reflect.Value, currentStruct reflect.Value, field reflect.Value,
+		fieldtype reflect.Type, fieldKind reflect.Kind, param string) bool {
+		for _, o := range []string{recommender.Cpu, recommender.OptimizeForBenchmark} {
+			if field.String() == o {
+				return true
+			}
+		}
+		return false
+	}
+}
+
+// resiliencyValidator validates the resiliency level in the recommendation request.
+func resiliencyValidator() validator.Func {
+	return func(v *validator.Validate, topStruct reflect.Value, currentStruct reflect.Value, field reflect.Value,
+		fieldtype reflect.Type, fieldKind reflect.Kind, param string) bool {
+		_, ok := recommender.ResiliencyFor(field.String())
+		return ok
+	}
+}
+
+// nodeImageValidator validates the node image in the recommendation request.
+func nodeImageValidator() validator.Func {
+	return func(v *validator.Validate, topStruct reflect.Value, currentStruct reflect.Value, field reflect.Value,
+		fieldtype reflect.Type, fieldKind reflect.Kind, param string) bool {
+		return recommender.IsNodeImage(field.String())
+	}
+}
+
+// memoryUnitValidator validates the memory unit in the recommendation request.
+func memoryUnitValidator() validator.Func {
+	return func(v *validator.Validate, topStruct reflect.Value, currentStruct reflect.Value, field reflect.Value,
+		fieldtype reflect.Type, fieldKind reflect.Kind, param string) bool {
+		return recommender.IsMemoryUnit(field.String())
+	}
+}
+
+// priceUnitValidator validates the price unit in the recommendation request.
+func priceUnitValidator() validator.Func {
+	return func(v *validator.Validate, topStruct reflect.Value, currentStruct reflect.Value, field reflect.Value,
+		fieldtype reflect.Type, fieldKind reflect.Kind, param string) bool {
+		return recommender.IsPriceUnit(field.String())
+	}
+}
+
 // CloudInfoValidator contract for validating cloud info data
 type CloudInfoValidator interface {
 	// Validate checks the existence, correctness etc... of the parameters
@@ -86,6 +147,9 @@ type CloudInfoValidator interface {
 
 	// ValidateContinents checks the existence of provided continents
 	ValidateContinents(continents []string) error
+
+	// ValidateZones checks that the provided zones belong to the region
+	ValidateZones(provider, service, region string, zones []string) error
 }
 
 type pathParamValidator struct {
@@ -130,6 +194,38 @@ func (ppV *pathParamValidator) ValidateContinents(continents []string) error {
 	return nil
 }
 
+// ValidateZones validates the zones against the zones of the region retrieved from the connected cloud info service
+func (ppV *pathParamValidator) ValidateZones(provider, service, region string, zones []string) error {
+	if len(zones) == 0 {
+		return nil
+	}
+
+	ciZones, err := ppV.ciCli.GetZones(provider, service, region)
+	if err != nil {
+		return err
+	}
+
+	notfound := make([]string, 0)
+	for _, zone := range zones {
+		found := false
+		for _, ciZone := range ciZones {
+			if zone == ciZone {
+				found = true
+				break
+			}
+		}
+		if !found {
+			notfound = append(notfound, zone)
+		}
+	}
+
+	if len(notfound) > 0 {
+		return errors.Errorf("unsupported zone(s) %s in region %s", notfound, region)
+	}
+
+	return nil
+}
+
 // Validate validates path parameters against the connected cloud info service
 func (ppV *pathParamValidator) ValidatePathParams(params interface{}) error {
 
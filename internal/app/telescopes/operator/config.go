@@ -0,0 +1,35 @@
+// Copyright © 2019 Banzai Cloud
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package operator
+
+import "time"
+
+// Config holds the configuration of the operator mode
+type Config struct {
+	// Enabled turns on watching ClusterRecommendation resources
+	Enabled bool
+
+	// Kubeconfig is the path of the kubeconfig file, the in-cluster config is used if empty
+	Kubeconfig string
+
+	// Namespace restricts the watched resources to a single namespace, all namespaces are watched if empty
+	Namespace string
+
+	// ResyncPeriod is the interval of refreshing the recommendations of unchanged resources, eg. to follow price changes
+	ResyncPeriod time.Duration
+
+	// Workers is the number of resources reconciled at the same time
+	Workers int
+}
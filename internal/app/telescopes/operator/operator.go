@@ -0,0 +1,270 @@
+// Copyright © 2019 Banzai Cloud
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package operator
+
+import (
+	"encoding/json"
+	"time"
+
+	"github.com/banzaicloud/telescopes/pkg/recommender"
+	"github.com/gin-gonic/gin/binding"
+	"github.com/goph/emperror"
+	"github.com/goph/logur"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/util/wait"
+	"k8s.io/apimachinery/pkg/watch"
+	"k8s.io/client-go/dynamic"
+	"k8s.io/client-go/tools/cache"
+	"k8s.io/client-go/tools/clientcmd"
+	"k8s.io/client-go/util/workqueue"
+)
+
+// maxRetries is the number of times a failed reconciliation is retried before waiting for the next resync
+const maxRetries = 10
+
+// queueItem is a resource queued for reconciliation by its namespace/name key,
+// force refreshes the recommendation of an already observed generation
+type queueItem struct {
+	key   string
+	force bool
+}
+
+// Operator watches ClusterRecommendation resources and writes the recommended node pools into their status
+type Operator struct {
+	client       dynamic.Interface
+	engine       recommender.ClusterRecommender
+	namespace    string
+	resyncPeriod time.Duration
+	workers      int
+	log          logur.Logger
+}
+
+// NewOperator creates a new Operator instance reconciling the resources with the given number of workers
+// The specs are validated like the HTTP API requests, the validator needs to be configured with api.ConfigureValidator
+func NewOperator(client dynamic.Interface, engine recommender.ClusterRecommender, namespace string, resyncPeriod time.Duration, workers int, log logur.Logger) *Operator {
+	if workers < 1 {
+		workers = 1
+	}
+	return &Operator{
+		client:       client,
+		engine:       engine,
+		namespace:    namespace,
+		resyncPeriod: resyncPeriod,
+		workers:      workers,
+		log:          logur.WithFields(log, map[string]interface{}{"component": "operator"}),
+	}
+}
+
+// NewDynamicClient creates a client for the cluster in the kubeconfig, or the one the application runs in
+func NewDynamicClient(kubeconfig string) (dynamic.Interface, error) {
+	config, err := clientcmd.BuildConfigFromFlags("", kubeconfig)
+	if err != nil {
+		return nil, emperror.Wrap(err, "failed to build kubernetes client config")
+	}
+
+	client, err := dynamic.NewForConfig(config)
+	if err != nil {
+		return nil, emperror.Wrap(err, "failed to create kubernetes client")
+	}
+	return client, nil
+}
+
+// Run watches the resources until the stop channel is closed
+// The changed resources are queued and reconciled by the workers, failed reconciliations are retried with backoff
+func (o *Operator) Run(stopCh <-chan struct{}) {
+	lw := &cache.ListWatch{
+		ListFunc: func(options metav1.ListOptions) (runtime.Object, error) {
+			return o.resources().List(options)
+		},
+		WatchFunc: func(options metav1.ListOptions) (watch.Interface, error) {
+			return o.resources().Watch(options)
+		},
+	}
+
+	queue := workqueue.NewNamedRateLimitingQueue(workqueue.DefaultControllerRateLimiter(), "clusterrecommendations")
+	defer queue.ShutDown()
+
+	store, controller := cache.NewInformer(lw, &unstructured.Unstructured{}, o.resyncPeriod, cache.ResourceEventHandlerFuncs{
+		AddFunc: func(obj interface{}) {
+			o.enqueue(queue, obj, false)
+		},
+		UpdateFunc: func(oldObj, newObj interface{}) {
+			// a resync delivers the same version again, the recommendation is refreshed then;
+			// otherwise only spec changes trigger a recommendation, status updates are ignored
+			resync := oldObj.(*unstructured.Unstructured).GetResourceVersion() == newObj.(*unstructured.Unstructured).GetResourceVersion()
+			o.enqueue(queue, newObj, resync)
+		},
+	})
+
+	o.log.Info("watching cluster recommendations", map[string]interface{}{"namespace": o.namespace, "workers": o.workers})
+	go controller.Run(stopCh)
+	if !cache.WaitForCacheSync(stopCh, controller.HasSynced) {
+		return
+	}
+
+	for i := 0; i < o.workers; i++ {
+		go wait.Until(func() {
+			for o.processNextItem(queue, store) {
+			}
+		}, time.Second, stopCh)
+	}
+	<-stopCh
+}
+
+// enqueue queues the resource for reconciliation
+func (o *Operator) enqueue(queue workqueue.Interface, obj interface{}, force bool) {
+	key, err := cache.MetaNamespaceKeyFunc(obj)
+	if err != nil {
+		o.log.Error("failed to get the key of the cluster recommendation", map[string]interface{}{"error": err.Error()})
+		return
+	}
+	queue.Add(queueItem{key: key, force: force})
+}
+
+// processNextItem reconciles the next queued resource, failed reconciliations are queued again with backoff until
+// they run out of retries; returns false if the queue is shut down
+func (o *Operator) processNextItem(queue workqueue.RateLimitingInterface, store cache.Store) bool {
+	item, shutdown := queue.Get()
+	if shutdown {
+		return false
+	}
+	defer queue.Done(item)
+
+	qi := item.(queueItem)
+	obj, exists, err := store.GetByKey(qi.key)
+	if err == nil && exists {
+		err = o.reconcile(obj.(*unstructured.Unstructured), qi.force)
+	}
+
+	if err != nil {
+		fields := map[string]interface{}{"key": qi.key, "error": err.Error(), "retries": queue.NumRequeues(item)}
+		if queue.NumRequeues(item) < maxRetries {
+			o.log.Warn("failed to reconcile cluster recommendation, retrying", fields)
+			queue.AddRateLimited(item)
+			return true
+		}
+		o.log.Error("failed to reconcile cluster recommendation, waiting for the next resync", fields)
+	}
+	queue.Forget(item)
+	return true
+}
+
+func (o *Operator) resources() dynamic.ResourceInterface {
+	return o.client.Resource(ClusterRecommendationResource).Namespace(o.namespace)
+}
+
+// reconcile recommends a cluster for the spec of the resource and writes the outcome into its status
+// Failed recommendations are written into the status, the error of writing the status is returned
+func (o *Operator) reconcile(obj *unstructured.Unstructured, force bool) error {
+	fields := map[string]interface{}{"namespace": obj.GetNamespace(), "name": obj.GetName()}
+
+	observed, found, _ := unstructured.NestedInt64(obj.Object, "status", "observedGeneration")
+	if !force && found && observed == obj.GetGeneration() {
+		return nil
+	}
+
+	status := o.recommend(obj)
+	if status.Phase == PhaseFailed {
+		o.log.Warn("failed to recommend cluster", mergeFields(fields, map[string]interface{}{"reason": status.Message}))
+	}
+
+	content, err := toUnstructured(status)
+	if err != nil {
+		return err
+	}
+
+	obj = obj.DeepCopy()
+	obj.Object["status"] = content
+	if _, err := o.resources().UpdateStatus(obj, metav1.UpdateOptions{}); err != nil {
+		return emperror.Wrap(err, "failed to update cluster recommendation status")
+	}
+	o.log.Info("cluster recommendation status updated", mergeFields(fields, map[string]interface{}{"phase": status.Phase}))
+	return nil
+}
+
+// recommend runs the engine with the spec of the resource
+func (o *Operator) recommend(obj *unstructured.Unstructured) ClusterRecommendationStatus {
+	status := ClusterRecommendationStatus{
+		ObservedGeneration:     obj.GetGeneration(),
+		LastRecommendationTime: time.Now().UTC(),
+	}
+
+	fail := func(err error) ClusterRecommendationStatus {
+		status.Phase = PhaseFailed
+		status.Message = err.Error()
+		return status
+	}
+
+	spec, err := specOf(obj)
+	if err != nil {
+		return fail(err)
+	}
+	if err := binding.Validator.ValidateStruct(&spec.SingleClusterRecommendationReq); err != nil {
+		return fail(emperror.Wrap(err, "invalid spec"))
+	}
+
+	resp, err := o.engine.RecommendCluster(spec.Provider, spec.Service, spec.Region, spec.SingleClusterRecommendationReq, nil)
+	if err != nil {
+		return fail(err)
+	}
+
+	status.Phase = PhaseRecommended
+	status.NodePools = resp.NodePools
+	status.Accuracy = &resp.Accuracy
+	status.LayoutHash = resp.LayoutHash
+	status.Warnings = resp.Warnings
+	return status
+}
+
+// specOf decodes the spec of the resource the same way the HTTP API decodes the request body
+func specOf(obj *unstructured.Unstructured) (ClusterRecommendationSpec, error) {
+	var spec ClusterRecommendationSpec
+
+	content, _, err := unstructured.NestedFieldNoCopy(obj.Object, "spec")
+	if err != nil {
+		return spec, emperror.Wrap(err, "failed to get spec")
+	}
+
+	raw, err := json.Marshal(content)
+	if err != nil {
+		return spec, emperror.Wrap(err, "failed to encode spec")
+	}
+	if err := json.Unmarshal(raw, &spec); err != nil {
+		return spec, emperror.Wrap(err, "failed to decode spec")
+	}
+	return spec, nil
+}
+
+// toUnstructured converts the status into its generic representation
+func toUnstructured(status ClusterRecommendationStatus) (map[string]interface{}, error) {
+	content, err := runtime.DefaultUnstructuredConverter.ToUnstructured(&status)
+	if err != nil {
+		return nil, emperror.Wrap(err, "failed to convert status")
+	}
+	return content, nil
+}
+
+func mergeFields(fields, other map[string]interface{}) map[string]interface{} {
+	merged := make(map[string]interface{}, len(fields)+len(other))
+	for k, v := range fields {
+		merged[k] = v
+	}
+	for k, v := range other {
+		merged[k] = v
+	}
+	return merged
+}
@@ -0,0 +1,229 @@
+// Copyright © 2019 Banzai Cloud
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package operator
+
+import (
+	"os"
+	"testing"
+	"time"
+
+	"github.com/banzaicloud/telescopes/internal/app/telescopes/api"
+	"github.com/banzaicloud/telescopes/pkg/recommender"
+	"github.com/goph/logur"
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/dynamic/fake"
+	k8stesting "k8s.io/client-go/testing"
+	"k8s.io/client-go/tools/cache"
+	"k8s.io/client-go/util/workqueue"
+)
+
+func TestMain(m *testing.M) {
+	// the specs are validated with the custom validators of the HTTP API
+	if err := api.ConfigureValidator(); err != nil {
+		panic(err)
+	}
+	os.Exit(m.Run())
+}
+
+type dummyEngine struct {
+	recommender.ClusterRecommender
+
+	calls int
+	err   error
+}
+
+func (e *dummyEngine) RecommendCluster(provider string, service string, region string, req recommender.SingleClusterRecommendationReq, layoutDesc []recommender.NodePoolDesc) (*recommender.ClusterRecommendationResp, error) {
+	e.calls++
+	if e.err != nil {
+		return nil, e.err
+	}
+	return &recommender.ClusterRecommendationResp{
+		Provider: provider,
+		Service:  service,
+		Region:   region,
+		NodePools: []recommender.NodePool{{
+			VmType:   recommender.VirtualMachine{Type: "m5.xlarge", Cpus: 4, Mem: 16},
+			SumNodes: int(req.SumCpu / 4),
+			VmClass:  recommender.Regular,
+			Role:     recommender.Worker,
+		}},
+		Accuracy:   recommender.ClusterRecommendationAccuracy{RecCpu: req.SumCpu},
+		LayoutHash: "abc123",
+	}, nil
+}
+
+func newClusterRecommendation(generation int64, status map[string]interface{}) *unstructured.Unstructured {
+	obj := &unstructured.Unstructured{Object: map[string]interface{}{
+		"apiVersion": "recommender.banzaicloud.io/v1alpha1",
+		"kind":       "ClusterRecommendation",
+		"metadata": map[string]interface{}{
+			"name":       "cluster",
+			"namespace":  "default",
+			"generation": generation,
+		},
+		"spec": map[string]interface{}{
+			"provider": "amazon",
+			"service":  "compute",
+			"region":   "eu-west-1",
+			"sumCpu":   int64(8),
+			"sumMem":   int64(32),
+			"minNodes": int64(1),
+			"maxNodes": int64(4),
+		},
+	}}
+	if status != nil {
+		obj.Object["status"] = status
+	}
+	return obj
+}
+
+func TestOperator_reconcile(t *testing.T) {
+	tests := []struct {
+		name   string
+		obj    *unstructured.Unstructured
+		force  bool
+		engine *dummyEngine
+		check  func(engine *dummyEngine, obj *unstructured.Unstructured)
+	}{
+		{
+			name:   "recommendation written into the status",
+			obj:    newClusterRecommendation(1, nil),
+			engine: &dummyEngine{},
+			check: func(engine *dummyEngine, obj *unstructured.Unstructured) {
+				assert.Equal(t, 1, engine.calls)
+				phase, _, _ := unstructured.NestedString(obj.Object, "status", "phase")
+				assert.Equal(t, PhaseRecommended, phase)
+				observed, _, _ := unstructured.NestedInt64(obj.Object, "status", "observedGeneration")
+				assert.Equal(t, int64(1), observed)
+				pools, _, _ := unstructured.NestedSlice(obj.Object, "status", "nodePools")
+				if assert.Len(t, pools, 1) {
+					assert.Equal(t, int64(2), pools[0].(map[string]interface{})["sumNodes"])
+				}
+				hash, _, _ := unstructured.NestedString(obj.Object, "status", "layoutHash")
+				assert.Equal(t, "abc123", hash)
+			},
+		},
+		{
+			name:   "engine error written into the status",
+			obj:    newClusterRecommendation(1, nil),
+			engine: &dummyEngine{err: errors.New("no feasible layout")},
+			check: func(engine *dummyEngine, obj *unstructured.Unstructured) {
+				phase, _, _ := unstructured.NestedString(obj.Object, "status", "phase")
+				assert.Equal(t, PhaseFailed, phase)
+				message, _, _ := unstructured.NestedString(obj.Object, "status", "message")
+				assert.Equal(t, "no feasible layout", message)
+			},
+		},
+		{
+			name: "invalid spec is not passed to the engine",
+			obj: func() *unstructured.Unstructured {
+				obj := newClusterRecommendation(1, nil)
+				_ = unstructured.SetNestedField(obj.Object, int64(0), "spec", "sumCpu")
+				return obj
+			}(),
+			engine: &dummyEngine{},
+			check: func(engine *dummyEngine, obj *unstructured.Unstructured) {
+				assert.Equal(t, 0, engine.calls)
+				phase, _, _ := unstructured.NestedString(obj.Object, "status", "phase")
+				assert.Equal(t, PhaseFailed, phase)
+			},
+		},
+		{
+			name:   "observed generation is skipped",
+			obj:    newClusterRecommendation(2, map[string]interface{}{"observedGeneration": int64(2), "phase": PhaseRecommended}),
+			engine: &dummyEngine{},
+			check: func(engine *dummyEngine, obj *unstructured.Unstructured) {
+				assert.Equal(t, 0, engine.calls)
+			},
+		},
+		{
+			name:   "observed generation is refreshed on resync",
+			obj:    newClusterRecommendation(2, map[string]interface{}{"observedGeneration": int64(2), "phase": PhaseRecommended}),
+			force:  true,
+			engine: &dummyEngine{},
+			check: func(engine *dummyEngine, obj *unstructured.Unstructured) {
+				assert.Equal(t, 1, engine.calls)
+			},
+		},
+	}
+	for _, test := range tests {
+		test := test
+		t.Run(test.name, func(t *testing.T) {
+			client := fake.NewSimpleDynamicClient(runtime.NewScheme(), test.obj)
+			operator := NewOperator(client, test.engine, "default", 0, 1, logur.NewTestLogger())
+
+			err := operator.reconcile(test.obj, test.force)
+			assert.NoError(t, err)
+
+			obj, err := client.Resource(ClusterRecommendationResource).Namespace("default").Get("cluster", metav1.GetOptions{})
+			assert.NoError(t, err)
+			test.check(test.engine, obj)
+		})
+	}
+}
+
+func TestOperator_processNextItem(t *testing.T) {
+	obj := newClusterRecommendation(1, nil)
+	client := fake.NewSimpleDynamicClient(runtime.NewScheme(), obj)
+	conflicts := 1
+	client.PrependReactor("update", "*", func(action k8stesting.Action) (bool, runtime.Object, error) {
+		if conflicts > 0 {
+			conflicts--
+			return true, nil, apierrors.NewConflict(ClusterRecommendationResource.GroupResource(), "cluster", errors.New("stale object"))
+		}
+		return false, nil, nil
+	})
+	engine := &dummyEngine{}
+	operator := NewOperator(client, engine, "default", 0, 1, logur.NewTestLogger())
+
+	store := cache.NewStore(cache.MetaNamespaceKeyFunc)
+	if !assert.NoError(t, store.Add(obj)) {
+		return
+	}
+	queue := workqueue.NewRateLimitingQueue(workqueue.NewItemExponentialFailureRateLimiter(time.Millisecond, time.Millisecond))
+	operator.enqueue(queue, obj, false)
+	item := queueItem{key: "default/cluster"}
+
+	// the conflicting status update is queued again
+	assert.True(t, operator.processNextItem(queue, store))
+	assert.Equal(t, 1, queue.NumRequeues(item))
+
+	// and written by the retry
+	assert.True(t, operator.processNextItem(queue, store))
+	assert.Equal(t, 0, queue.NumRequeues(item))
+	assert.Equal(t, 2, engine.calls)
+
+	stored, err := client.Resource(ClusterRecommendationResource).Namespace("default").Get("cluster", metav1.GetOptions{})
+	if !assert.NoError(t, err) {
+		return
+	}
+	phase, _, _ := unstructured.NestedString(stored.Object, "status", "phase")
+	assert.Equal(t, PhaseRecommended, phase)
+
+	// deleted resources are dropped
+	_ = store.Delete(obj)
+	operator.enqueue(queue, obj, false)
+	assert.True(t, operator.processNextItem(queue, store))
+	assert.Equal(t, 0, queue.Len())
+	assert.Equal(t, 2, engine.calls)
+
+	queue.ShutDown()
+	assert.False(t, operator.processNextItem(queue, store))
+}
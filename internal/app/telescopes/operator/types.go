@@ -0,0 +1,68 @@
+// Copyright © 2019 Banzai Cloud
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package operator
+
+import (
+	"time"
+
+	"github.com/banzaicloud/telescopes/pkg/recommender"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+)
+
+const (
+	// PhaseRecommended signals that the status holds the recommendation for the current spec
+	PhaseRecommended = "Recommended"
+	// PhaseFailed signals that no recommendation could be made for the current spec
+	PhaseFailed = "Failed"
+)
+
+// ClusterRecommendationResource identifies the ClusterRecommendation custom resource
+var ClusterRecommendationResource = schema.GroupVersionResource{
+	Group:    "recommender.banzaicloud.io",
+	Version:  "v1alpha1",
+	Resource: "clusterrecommendations",
+}
+
+// ClusterRecommendationSpec is the desired cluster of a ClusterRecommendation resource, it mirrors the HTTP API request
+type ClusterRecommendationSpec struct {
+	// The cloud provider
+	Provider string `json:"provider"`
+	// Provider's service
+	Service string `json:"service"`
+	// Service's region
+	Region string `json:"region"`
+
+	recommender.SingleClusterRecommendationReq
+}
+
+// ClusterRecommendationStatus holds the outcome of the recommendation for a ClusterRecommendation resource
+type ClusterRecommendationStatus struct {
+	// Generation of the spec the status was computed for
+	ObservedGeneration int64 `json:"observedGeneration"`
+	// Phase is either Recommended or Failed
+	Phase string `json:"phase"`
+	// Reason of the failure, empty if the recommendation succeeded
+	Message string `json:"message,omitempty"`
+	// Time of the last recommendation
+	LastRecommendationTime time.Time `json:"lastRecommendationTime"`
+	// Recommended node pools
+	NodePools []recommender.NodePool `json:"nodePools,omitempty"`
+	// Accuracy of the recommendation
+	Accuracy *recommender.ClusterRecommendationAccuracy `json:"accuracy,omitempty"`
+	// Deterministic hash of the recommended node pool set, controllers may skip reconciliations while it's unchanged
+	LayoutHash string `json:"layoutHash,omitempty"`
+	// Warnings about the request or the recommendation
+	Warnings []recommender.Warning `json:"warnings,omitempty"`
+}
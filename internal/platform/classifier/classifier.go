@@ -19,6 +19,7 @@ import (
 	"net/url"
 
 	"github.com/banzaicloud/telescopes/internal/platform/problems"
+	"github.com/banzaicloud/telescopes/pkg/recommender"
 	"github.com/go-openapi/runtime"
 	"github.com/goph/emperror"
 	"github.com/pkg/errors"
@@ -59,6 +60,10 @@ func (erc *errClassifier) Classify(inErr interface{}) (interface{}, error) {
 
 	cause := errors.Cause(err)
 
+	if problem, ok = erc.classifyDomainError(cause); ok {
+		return problem, nil
+	}
+
 	switch e := cause.(type) {
 
 	case *runtime.APIError:
@@ -121,6 +126,35 @@ func (erc *errClassifier) classifyUrlError(e *url.Error, ctx []interface{}) *pro
 	return problem
 }
 
+// classifyDomainError maps the errors exported by the recommender to problems, the second value reports whether the error is known
+func (erc *errClassifier) classifyDomainError(cause error) (*problems.ProblemWrapper, bool) {
+	switch cause {
+	case recommender.ErrMinGreaterThanMax, recommender.ErrUnsupportedAttribute, recommender.ErrInvalidRegionShares,
+		recommender.ErrOnDemandCountConflict, recommender.ErrOnDemandCountExceedsMax, recommender.ErrInvalidBacktestWindow,
+		recommender.ErrUnknownInstanceType, recommender.ErrRegionNotAllowed, recommender.ErrMinPerNodeGreaterThanMax,
+		recommender.ErrInvalidZoneWeights, recommender.ErrUnknownPriority, recommender.ErrInvalidZoneNodes,
+		recommender.ErrUnknownOperation, recommender.ErrInvalidRequireAllZones, recommender.ErrInvalidNodeBounds,
+		recommender.ErrUnsupportedBoundsAttribute:
+		return problems.NewValidationProblem(http.StatusBadRequest, cause.Error()), true
+	case recommender.ErrNoFeasibleLayout:
+		return problems.NewRecommendationProblem(http.StatusBadRequest, cause.Error()), true
+	case recommender.ErrProfileNotFound, recommender.ErrSnapshotNotFound:
+		return problems.NewDetailedProblem(http.StatusNotFound, cause.Error()), true
+	case recommender.ErrPriceHistoryUnavailable:
+		return problems.NewDetailedProblem(http.StatusNotImplemented, cause.Error()), true
+	case recommender.ErrProviderNotEnabled:
+		return problems.NewDetailedProblem(http.StatusForbidden, cause.Error()), true
+	case recommender.ErrUnknownTenant, recommender.ErrAdminTokenRequired:
+		return problems.NewDetailedProblem(http.StatusUnauthorized, cause.Error()), true
+	case recommender.ErrCallBudgetExceeded, recommender.ErrZonesNotCovered, recommender.ErrPodsDontFit:
+		return problems.NewRecommendationProblem(http.StatusUnprocessableEntity, cause.Error()), true
+	case recommender.ErrQueueFull, recommender.ErrInjectedFault:
+		return problems.NewDetailedProblem(http.StatusServiceUnavailable, cause.Error()), true
+	}
+
+	return nil, false
+}
+
 func (erc *errClassifier) classifyGenericError(e error, ctx []interface{}) *problems.ProblemWrapper {
 	var problem = problems.NewUnknownProblem(e)
 
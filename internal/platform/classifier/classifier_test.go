@@ -20,6 +20,7 @@ import (
 	"testing"
 
 	"github.com/banzaicloud/telescopes/internal/platform/problems"
+	"github.com/banzaicloud/telescopes/pkg/recommender"
 	"github.com/go-openapi/runtime"
 	"github.com/goph/emperror"
 	"github.com/pkg/errors"
@@ -64,6 +65,172 @@ func TestErrResponseClassifier_Classify(t *testing.T) {
 				assert.Equal(t, http.StatusBadRequest, pb.Status, "invalid http status code")
 			},
 		},
+		{
+			name:  "domain error - min nodes greater than max nodes",
+			error: emperror.With(recommender.ErrMinGreaterThanMax, "minNodes", 3, "maxNodes", 1),
+			checker: func(t *testing.T, pb *problems.ProblemWrapper, e error) {
+				assert.Nil(t, e, "could not create classifier")
+				assert.Equal(t, http.StatusBadRequest, pb.Status, "invalid http status code")
+				assert.Equal(t, "validation problem", pb.Title, "invalid problem title")
+			},
+		},
+		{
+			name:  "domain error - on-demand count and percentage both requested",
+			error: emperror.With(recommender.ErrOnDemandCountConflict, "onDemandCount", 3, "onDemandPct", 30),
+			checker: func(t *testing.T, pb *problems.ProblemWrapper, e error) {
+				assert.Nil(t, e, "could not create classifier")
+				assert.Equal(t, http.StatusBadRequest, pb.Status, "invalid http status code")
+				assert.Equal(t, "validation problem", pb.Title, "invalid problem title")
+			},
+		},
+		{
+			name:  "domain error - no feasible layout",
+			error: emperror.With(recommender.ErrNoFeasibleLayout),
+			checker: func(t *testing.T, pb *problems.ProblemWrapper, e error) {
+				assert.Nil(t, e, "could not create classifier")
+				assert.Equal(t, http.StatusBadRequest, pb.Status, "invalid http status code")
+				assert.Equal(t, "recommendation problem", pb.Title, "invalid problem title")
+			},
+		},
+		{
+			name:  "domain error - pods don't fit",
+			error: emperror.With(recommender.ErrPodsDontFit, "sumPods", 500, "maxNodes", 2),
+			checker: func(t *testing.T, pb *problems.ProblemWrapper, e error) {
+				assert.Nil(t, e, "could not create classifier")
+				assert.Equal(t, http.StatusUnprocessableEntity, pb.Status, "invalid http status code")
+				assert.Equal(t, "recommendation problem", pb.Title, "invalid problem title")
+			},
+		},
+		{
+			name:  "domain error - zones not covered",
+			error: emperror.With(recommender.ErrZonesNotCovered, "zones", []string{"eu-west-1c"}),
+			checker: func(t *testing.T, pb *problems.ProblemWrapper, e error) {
+				assert.Nil(t, e, "could not create classifier")
+				assert.Equal(t, http.StatusUnprocessableEntity, pb.Status, "invalid http status code")
+				assert.Equal(t, "recommendation problem", pb.Title, "invalid problem title")
+			},
+		},
+		{
+			name:  "domain error - call budget exceeded",
+			error: emperror.With(recommender.ErrCallBudgetExceeded, "budget", 5, "calls", 6),
+			checker: func(t *testing.T, pb *problems.ProblemWrapper, e error) {
+				assert.Nil(t, e, "could not create classifier")
+				assert.Equal(t, http.StatusUnprocessableEntity, pb.Status, "invalid http status code")
+				assert.Equal(t, "recommendation problem", pb.Title, "invalid problem title")
+			},
+		},
+		{
+			name:  "domain error - no price history",
+			error: emperror.With(recommender.ErrPriceHistoryUnavailable),
+			checker: func(t *testing.T, pb *problems.ProblemWrapper, e error) {
+				assert.Nil(t, e, "could not create classifier")
+				assert.Equal(t, http.StatusNotImplemented, pb.Status, "invalid http status code")
+			},
+		},
+		{
+			name:  "domain error - region not allowed",
+			error: emperror.With(recommender.ErrRegionNotAllowed, "region", "eu-west-1"),
+			checker: func(t *testing.T, pb *problems.ProblemWrapper, e error) {
+				assert.Nil(t, e, "could not create classifier")
+				assert.Equal(t, http.StatusBadRequest, pb.Status, "invalid http status code")
+			},
+		},
+		{
+			name:  "domain error - minimum per node greater than the maximum",
+			error: emperror.With(recommender.ErrMinPerNodeGreaterThanMax, "attribute", "cpu", "min", 8, "max", 4),
+			checker: func(t *testing.T, pb *problems.ProblemWrapper, e error) {
+				assert.Nil(t, e, "could not create classifier")
+				assert.Equal(t, http.StatusBadRequest, pb.Status, "invalid http status code")
+			},
+		},
+		{
+			name:  "domain error - queue full",
+			error: emperror.With(recommender.ErrQueueFull, "priority", "batch"),
+			checker: func(t *testing.T, pb *problems.ProblemWrapper, e error) {
+				assert.Nil(t, e, "could not create classifier")
+				assert.Equal(t, http.StatusServiceUnavailable, pb.Status, "invalid http status code")
+			},
+		},
+		{
+			name:  "domain error - injected fault",
+			error: emperror.With(recommender.ErrInjectedFault, "operation", "products", "provider", "amazon"),
+			checker: func(t *testing.T, pb *problems.ProblemWrapper, e error) {
+				assert.Nil(t, e, "could not create classifier")
+				assert.Equal(t, http.StatusServiceUnavailable, pb.Status, "invalid http status code")
+			},
+		},
+		{
+			name:  "domain error - invalid zone weights",
+			error: emperror.With(recommender.ErrInvalidZoneWeights, "zone", "eu-west-1a", "weight", -1),
+			checker: func(t *testing.T, pb *problems.ProblemWrapper, e error) {
+				assert.Nil(t, e, "could not create classifier")
+				assert.Equal(t, http.StatusBadRequest, pb.Status, "invalid http status code")
+			},
+		},
+		{
+			name:  "domain error - invalid node bounds",
+			error: emperror.With(recommender.ErrInvalidNodeBounds, "attribute", "cpu", "minNodes", 0, "maxNodes", -1),
+			checker: func(t *testing.T, pb *problems.ProblemWrapper, e error) {
+				assert.Nil(t, e, "could not create classifier")
+				assert.Equal(t, http.StatusBadRequest, pb.Status, "invalid http status code")
+			},
+		},
+		{
+			name:  "domain error - unsupported bounds attribute",
+			error: emperror.With(recommender.ErrUnsupportedBoundsAttribute, "attribute", "gpu"),
+			checker: func(t *testing.T, pb *problems.ProblemWrapper, e error) {
+				assert.Nil(t, e, "could not create classifier")
+				assert.Equal(t, http.StatusBadRequest, pb.Status, "invalid http status code")
+			},
+		},
+		{
+			name:  "domain error - invalid requireAllZones",
+			error: emperror.With(recommender.ErrInvalidRequireAllZones, "zone", "eu-west-1a", "zoneWeights", 0),
+			checker: func(t *testing.T, pb *problems.ProblemWrapper, e error) {
+				assert.Nil(t, e, "could not create classifier")
+				assert.Equal(t, http.StatusBadRequest, pb.Status, "invalid http status code")
+			},
+		},
+		{
+			name:  "domain error - invalid zone nodes",
+			error: emperror.With(recommender.ErrInvalidZoneNodes, "zone", "eu-west-1a", "nodes", -1),
+			checker: func(t *testing.T, pb *problems.ProblemWrapper, e error) {
+				assert.Nil(t, e, "could not create classifier")
+				assert.Equal(t, http.StatusBadRequest, pb.Status, "invalid http status code")
+			},
+		},
+		{
+			name:  "domain error - snapshot not found",
+			error: emperror.With(recommender.ErrSnapshotNotFound, "snapshot", "0123"),
+			checker: func(t *testing.T, pb *problems.ProblemWrapper, e error) {
+				assert.Nil(t, e, "could not create classifier")
+				assert.Equal(t, http.StatusNotFound, pb.Status, "invalid http status code")
+			},
+		},
+		{
+			name:  "domain error - provider not enabled for the tenant",
+			error: emperror.With(recommender.ErrProviderNotEnabled, "tenant", "team-a"),
+			checker: func(t *testing.T, pb *problems.ProblemWrapper, e error) {
+				assert.Nil(t, e, "could not create classifier")
+				assert.Equal(t, http.StatusForbidden, pb.Status, "invalid http status code")
+			},
+		},
+		{
+			name:  "domain error - unknown tenant",
+			error: recommender.ErrUnknownTenant,
+			checker: func(t *testing.T, pb *problems.ProblemWrapper, e error) {
+				assert.Nil(t, e, "could not create classifier")
+				assert.Equal(t, http.StatusUnauthorized, pb.Status, "invalid http status code")
+			},
+		},
+		{
+			name:  "domain error - admin token required",
+			error: recommender.ErrAdminTokenRequired,
+			checker: func(t *testing.T, pb *problems.ProblemWrapper, e error) {
+				assert.Nil(t, e, "could not create classifier")
+				assert.Equal(t, http.StatusUnauthorized, pb.Status, "invalid http status code")
+			},
+		},
 		{
 			name:  "generic error -  no tags",
 			error: emperror.With(errors.New("test error - no context")),
@@ -0,0 +1,68 @@
+// Copyright © 2019 Banzai Cloud
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package metrics
+
+import (
+	"github.com/banzaicloud/telescopes/pkg/recommender"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+var catalogLabels = []string{"provider", "service", "region"}
+
+// CatalogChangeListener registers the product catalog change metrics and returns the listener updating them
+func CatalogChangeListener() func(recommender.CatalogChange) {
+	changes := prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: "telescopes",
+		Subsystem: "catalog",
+		Name:      "changes_total",
+		Help:      "Number of the changes of the product catalogs",
+	}, catalogLabels)
+	added := prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: "telescopes",
+		Subsystem: "catalog",
+		Name:      "types_added_total",
+		Help:      "Number of the instance types added to the product catalogs",
+	}, catalogLabels)
+	removed := prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: "telescopes",
+		Subsystem: "catalog",
+		Name:      "types_removed_total",
+		Help:      "Number of the instance types removed from the product catalogs",
+	}, catalogLabels)
+	prices := prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: "telescopes",
+		Subsystem: "catalog",
+		Name:      "price_changes_total",
+		Help:      "Number of the price changes of the product catalogs over the threshold",
+	}, append(catalogLabels, "kind"))
+	lastChange := prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: "telescopes",
+		Subsystem: "catalog",
+		Name:      "last_change_timestamp_seconds",
+		Help:      "Time of the last change of the product catalogs",
+	}, catalogLabels)
+	prometheus.MustRegister(changes, added, removed, prices, lastChange)
+
+	return func(change recommender.CatalogChange) {
+		labels := prometheus.Labels{"provider": change.Provider, "service": change.Service, "region": change.Region}
+		changes.With(labels).Inc()
+		added.With(labels).Add(float64(len(change.Added)))
+		removed.With(labels).Add(float64(len(change.Removed)))
+		for _, pc := range change.PriceChanges {
+			prices.WithLabelValues(change.Provider, change.Service, change.Region, pc.Kind).Inc()
+		}
+		lastChange.With(labels).Set(float64(change.DetectedAt.Unix()))
+	}
+}
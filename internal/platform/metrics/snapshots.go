@@ -0,0 +1,34 @@
+// Copyright © 2019 Banzai Cloud
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package metrics
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// SnapshotEvictionListener registers the product snapshot eviction metrics and returns the listener updating them
+func SnapshotEvictionListener() func(reason string) {
+	evictions := prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: "telescopes",
+		Subsystem: "snapshots",
+		Name:      "evictions_total",
+		Help:      "Number of the product snapshots evicted",
+	}, []string{"reason"})
+	prometheus.MustRegister(evictions)
+
+	return func(reason string) {
+		evictions.WithLabelValues(reason).Inc()
+	}
+}
@@ -0,0 +1,162 @@
+// Copyright © 2019 Banzai Cloud
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package jws signs and verifies payloads with detached JSON Web Signatures (RFC 7515, appendix F), using RS256.
+package jws
+
+import (
+	"crypto"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"encoding/pem"
+	"strings"
+
+	"github.com/goph/emperror"
+	"github.com/pkg/errors"
+)
+
+// Algorithm is the only signature algorithm signed and accepted
+const Algorithm = "RS256"
+
+// ErrInvalidSignature is returned when the signature doesn't match the payload or the key
+var ErrInvalidSignature = errors.New("invalid signature")
+
+var encoding = base64.RawURLEncoding
+
+// header is the protected header of the signatures
+type header struct {
+	Alg string `json:"alg"`
+	Kid string `json:"kid,omitempty"`
+}
+
+// Signer signs payloads with an RSA private key
+type Signer struct {
+	key *rsa.PrivateKey
+	kid string
+}
+
+// NewSigner creates a signer, the signatures carry the id of the key
+func NewSigner(key *rsa.PrivateKey) *Signer {
+	return &Signer{key: key, kid: KeyID(&key.PublicKey)}
+}
+
+// Sign signs the payload, the signature is in the detached compact serialization (header..signature)
+func (s *Signer) Sign(payload []byte) (string, error) {
+	h, err := json.Marshal(header{Alg: Algorithm, Kid: s.kid})
+	if err != nil {
+		return "", err
+	}
+	protected := encoding.EncodeToString(h)
+
+	digest := signingDigest(protected, payload)
+	sig, err := rsa.SignPKCS1v15(rand.Reader, s.key, crypto.SHA256, digest)
+	if err != nil {
+		return "", emperror.Wrap(err, "failed to sign the payload")
+	}
+
+	return protected + ".." + encoding.EncodeToString(sig), nil
+}
+
+// Verify checks the detached signature of the payload with the public key
+func Verify(key *rsa.PublicKey, payload []byte, signature string) error {
+	parts := strings.Split(signature, ".")
+	if len(parts) != 3 || parts[1] != "" {
+		return emperror.With(ErrInvalidSignature, "reason", "not a detached compact serialization")
+	}
+
+	h, err := encoding.DecodeString(parts[0])
+	if err != nil {
+		return emperror.With(ErrInvalidSignature, "reason", "malformed header")
+	}
+	var hdr header
+	if err := json.Unmarshal(h, &hdr); err != nil {
+		return emperror.With(ErrInvalidSignature, "reason", "malformed header")
+	}
+	if hdr.Alg != Algorithm {
+		return emperror.With(ErrInvalidSignature, "reason", "unsupported algorithm", "alg", hdr.Alg)
+	}
+	if kid := KeyID(key); hdr.Kid != "" && hdr.Kid != kid {
+		return emperror.With(ErrInvalidSignature, "reason", "signed with another key", "kid", hdr.Kid)
+	}
+
+	sig, err := encoding.DecodeString(parts[2])
+	if err != nil {
+		return emperror.With(ErrInvalidSignature, "reason", "malformed signature")
+	}
+	if err := rsa.VerifyPKCS1v15(key, crypto.SHA256, signingDigest(parts[0], payload), sig); err != nil {
+		return emperror.With(ErrInvalidSignature, "reason", "signature mismatch")
+	}
+	return nil
+}
+
+// KeyID identifies the public key by the first 16 hex digits of the SHA-256 hash of its DER encoding
+func KeyID(key *rsa.PublicKey) string {
+	der, err := x509.MarshalPKIXPublicKey(key)
+	if err != nil {
+		return ""
+	}
+	sum := sha256.Sum256(der)
+	return hex.EncodeToString(sum[:8])
+}
+
+// ParsePrivateKey parses a PEM encoded RSA private key in PKCS #1 or PKCS #8 format
+func ParsePrivateKey(data []byte) (*rsa.PrivateKey, error) {
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, errors.New("no PEM encoded key found")
+	}
+	if key, err := x509.ParsePKCS1PrivateKey(block.Bytes); err == nil {
+		return key, nil
+	}
+	key, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+	if err != nil {
+		return nil, emperror.Wrap(err, "failed to parse the private key")
+	}
+	rsaKey, ok := key.(*rsa.PrivateKey)
+	if !ok {
+		return nil, errors.New("not an RSA private key")
+	}
+	return rsaKey, nil
+}
+
+// ParsePublicKey parses a PEM encoded RSA public key in PKIX or PKCS #1 format
+func ParsePublicKey(data []byte) (*rsa.PublicKey, error) {
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, errors.New("no PEM encoded key found")
+	}
+	if key, err := x509.ParsePKCS1PublicKey(block.Bytes); err == nil {
+		return key, nil
+	}
+	key, err := x509.ParsePKIXPublicKey(block.Bytes)
+	if err != nil {
+		return nil, emperror.Wrap(err, "failed to parse the public key")
+	}
+	rsaKey, ok := key.(*rsa.PublicKey)
+	if !ok {
+		return nil, errors.New("not an RSA public key")
+	}
+	return rsaKey, nil
+}
+
+// signingDigest hashes the signing input of the protected header and the payload
+func signingDigest(protected string, payload []byte) []byte {
+	sum := sha256.Sum256([]byte(protected + "." + encoding.EncodeToString(payload)))
+	return sum[:]
+}
@@ -0,0 +1,82 @@
+// Copyright © 2019 Banzai Cloud
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package jws
+
+import (
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/pem"
+	"strings"
+	"testing"
+
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSignVerify(t *testing.T) {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if !assert.Nil(t, err, "the error should be nil") {
+		return
+	}
+	other, err := rsa.GenerateKey(rand.Reader, 2048)
+	if !assert.Nil(t, err, "the error should be nil") {
+		return
+	}
+
+	payload := []byte(`{"provider":"amazon","nodePools":[]}`)
+	signature, err := NewSigner(key).Sign(payload)
+	assert.Nil(t, err, "the error should be nil")
+	assert.Len(t, strings.Split(signature, "."), 3)
+	assert.Contains(t, signature, "..", "the payload should be detached")
+
+	assert.Nil(t, Verify(&key.PublicKey, payload, signature), "the signature should be valid")
+
+	tampered := []byte(`{"provider":"amazon","nodePools":[{}]}`)
+	assert.Equal(t, ErrInvalidSignature, errors.Cause(Verify(&key.PublicKey, tampered, signature)))
+	assert.Equal(t, ErrInvalidSignature, errors.Cause(Verify(&other.PublicKey, payload, signature)))
+
+	// alg none must not be accepted
+	unsigned := encoding.EncodeToString([]byte(`{"alg":"none"}`)) + ".."
+	assert.Equal(t, ErrInvalidSignature, errors.Cause(Verify(&key.PublicKey, payload, unsigned)))
+	assert.Equal(t, ErrInvalidSignature, errors.Cause(Verify(&key.PublicKey, payload, "garbage")))
+}
+
+func TestParseKeys(t *testing.T) {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if !assert.Nil(t, err, "the error should be nil") {
+		return
+	}
+
+	pkcs1 := pem.EncodeToMemory(&pem.Block{Type: "RSA PRIVATE KEY", Bytes: x509.MarshalPKCS1PrivateKey(key)})
+	parsed, err := ParsePrivateKey(pkcs1)
+	assert.Nil(t, err, "the error should be nil")
+	assert.Equal(t, key.D, parsed.D)
+
+	der, err := x509.MarshalPKCS8PrivateKey(key)
+	assert.Nil(t, err, "the error should be nil")
+	parsed, err = ParsePrivateKey(pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: der}))
+	assert.Nil(t, err, "the error should be nil")
+	assert.Equal(t, key.D, parsed.D)
+
+	der, err = x509.MarshalPKIXPublicKey(&key.PublicKey)
+	assert.Nil(t, err, "the error should be nil")
+	pub, err := ParsePublicKey(pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: der}))
+	assert.Nil(t, err, "the error should be nil")
+	assert.Equal(t, KeyID(&key.PublicKey), KeyID(pub))
+
+	_, err = ParsePrivateKey([]byte("not a key"))
+	assert.NotNil(t, err, "the error should not be nil")
+}
@@ -0,0 +1,89 @@
+// Copyright © 2019 Banzai Cloud
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package verify checks that the recommendations were signed by the trusted recommender,
+// and weren't altered in transit or at rest.
+package verify
+
+import (
+	"bytes"
+	"crypto/rsa"
+	"io/ioutil"
+	"net/http"
+
+	"github.com/goph/emperror"
+	"github.com/pkg/errors"
+
+	"github.com/banzaicloud/telescopes/pkg/jws"
+)
+
+// SignatureHeader is the response header holding the detached signature of the response body
+const SignatureHeader = "X-Recommendation-Signature"
+
+// ErrUnsigned is returned when a response has no signature
+var ErrUnsigned = errors.New("the response is not signed")
+
+// Verifier verifies the signatures of recommendations with the public key of the recommender
+type Verifier struct {
+	key *rsa.PublicKey
+}
+
+// NewVerifier creates a verifier from the PEM encoded public key of the recommender
+func NewVerifier(publicKeyPEM []byte) (*Verifier, error) {
+	key, err := jws.ParsePublicKey(publicKeyPEM)
+	if err != nil {
+		return nil, err
+	}
+	return &Verifier{key: key}, nil
+}
+
+// Verify checks the signature of a stored or received recommendation, the body must be the exact bytes signed
+func (v *Verifier) Verify(body []byte, signature string) error {
+	if signature == "" {
+		return ErrUnsigned
+	}
+	return jws.Verify(v.key, body, signature)
+}
+
+// Transport wraps the transport of the client SDK, successful responses failing the verification are turned into errors
+//
+//	transport := httptransport.New(host, client.DefaultBasePath, client.DefaultSchemes)
+//	transport.Transport = verifier.Transport(http.DefaultTransport)
+//	cli := client.New(transport, strfmt.Default)
+func (v *Verifier) Transport(base http.RoundTripper) http.RoundTripper {
+	return roundTripperFunc(func(req *http.Request) (*http.Response, error) {
+		resp, err := base.RoundTrip(req)
+		if err != nil || resp.StatusCode >= http.StatusMultipleChoices {
+			return resp, err
+		}
+
+		body, err := ioutil.ReadAll(resp.Body)
+		_ = resp.Body.Close()
+		if err != nil {
+			return nil, emperror.Wrap(err, "failed to read the response")
+		}
+		if err := v.Verify(body, resp.Header.Get(SignatureHeader)); err != nil {
+			return nil, emperror.WrapWith(err, "recommendation verification failed", "url", req.URL.String())
+		}
+
+		resp.Body = ioutil.NopCloser(bytes.NewReader(body))
+		return resp, nil
+	})
+}
+
+type roundTripperFunc func(req *http.Request) (*http.Response, error)
+
+func (f roundTripperFunc) RoundTrip(req *http.Request) (*http.Response, error) {
+	return f(req)
+}
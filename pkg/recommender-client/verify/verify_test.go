@@ -0,0 +1,79 @@
+// Copyright © 2019 Banzai Cloud
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package verify
+
+import (
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/pem"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/banzaicloud/telescopes/pkg/jws"
+)
+
+func TestVerifier_Transport(t *testing.T) {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if !assert.Nil(t, err, "the error should be nil") {
+		return
+	}
+	signer := jws.NewSigner(key)
+
+	body := []byte(`{"provider":"amazon"}`)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/signed":
+			signature, _ := signer.Sign(body)
+			w.Header().Set(SignatureHeader, signature)
+			_, _ = w.Write(body)
+		case "/tampered":
+			signature, _ := signer.Sign(body)
+			w.Header().Set(SignatureHeader, signature)
+			_, _ = w.Write([]byte(`{"provider":"google"}`))
+		default:
+			_, _ = w.Write(body)
+		}
+	}))
+	defer server.Close()
+
+	der, err := x509.MarshalPKIXPublicKey(&key.PublicKey)
+	if !assert.Nil(t, err, "the error should be nil") {
+		return
+	}
+	verifier, err := NewVerifier(pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: der}))
+	if !assert.Nil(t, err, "the error should be nil") {
+		return
+	}
+	client := &http.Client{Transport: verifier.Transport(http.DefaultTransport)}
+
+	resp, err := client.Get(server.URL + "/signed")
+	if assert.Nil(t, err, "a signed response should be verified") {
+		received, _ := ioutil.ReadAll(resp.Body)
+		assert.Equal(t, body, received, "the body should be passed on")
+	}
+
+	_, err = client.Get(server.URL + "/tampered")
+	assert.NotNil(t, err, "an altered response should be refused")
+
+	_, err = client.Get(server.URL + "/unsigned")
+	assert.NotNil(t, err, "an unsigned response should be refused")
+	assert.Equal(t, ErrUnsigned, errors.Cause(verifier.Verify(body, "")))
+}
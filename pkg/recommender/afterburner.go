@@ -0,0 +1,128 @@
+// Copyright © 2019 Banzai Cloud
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package recommender
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// afterburner is the fast path of repeated scale out recommendations: autoscalers ask for scale outs of the same layout
+// frequently, the candidate vms of the layout are kept for a while instead of retrieving and enriching the products every time
+type afterburner struct {
+	ttl time.Duration
+	now func() time.Time
+
+	mux     sync.Mutex
+	entries map[string]afterburnerEntry
+}
+
+type afterburnerEntry struct {
+	products  []VirtualMachine
+	freshness *DataFreshness
+	expiresAt time.Time
+}
+
+func newAfterburner(ttl time.Duration) *afterburner {
+	return &afterburner{
+		ttl:     ttl,
+		now:     time.Now,
+		entries: make(map[string]afterburnerEntry),
+	}
+}
+
+// layoutFingerprint identifies the candidate vms of a scale out; the node counts of the layout and the desired resources
+// don't change the candidates, so they are not part of the fingerprint
+func layoutFingerprint(provider, service, region string, req SingleClusterRecommendationReq, layoutDesc []NodePoolDesc) string {
+	pools := make([]string, len(layoutDesc))
+	for i, npd := range layoutDesc {
+		pools[i] = npd.InstanceType + ":" + npd.GetVmClass()
+	}
+	sort.Strings(pools)
+
+	includes := append([]string(nil), req.Includes...)
+	sort.Strings(includes)
+	excludes := append([]string(nil), req.Excludes...)
+	sort.Strings(excludes)
+
+	h := sha256.New()
+	for _, part := range [][]string{{provider, service, region, req.Zone}, pools, includes, excludes} {
+		h.Write([]byte(strings.Join(part, ",")))
+		h.Write([]byte{'|'})
+	}
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// get returns a copy of the candidate vms stored for the fingerprint, the caller is free to modify them
+func (a *afterburner) get(fingerprint string) ([]VirtualMachine, *DataFreshness, bool) {
+	a.mux.Lock()
+	defer a.mux.Unlock()
+
+	entry, ok := a.entries[fingerprint]
+	if !ok || a.now().After(entry.expiresAt) {
+		return nil, nil, false
+	}
+
+	products := make([]VirtualMachine, len(entry.products))
+	copy(products, entry.products)
+
+	var freshness *DataFreshness
+	if entry.freshness != nil {
+		df := entry.freshness.WithAges(a.now())
+		freshness = &df
+	}
+	return products, freshness, true
+}
+
+// put stores the candidate vms for the fingerprint, and drops the expired entries
+func (a *afterburner) put(fingerprint string, products []VirtualMachine, freshness *DataFreshness) {
+	a.mux.Lock()
+	defer a.mux.Unlock()
+
+	now := a.now()
+	for key, entry := range a.entries {
+		if now.After(entry.expiresAt) {
+			delete(a.entries, key)
+		}
+	}
+
+	stored := make([]VirtualMachine, len(products))
+	copy(stored, products)
+	a.entries[fingerprint] = afterburnerEntry{
+		products:  stored,
+		freshness: freshness,
+		expiresAt: now.Add(a.ttl),
+	}
+}
+
+// candidates restricts the products to the instance types the scale out may recommend
+func candidates(products []VirtualMachine, includes []string) []VirtualMachine {
+	types := make(map[string]bool, len(includes))
+	for _, t := range includes {
+		types[t] = true
+	}
+
+	var vms []VirtualMachine
+	for _, vm := range products {
+		if types[vm.Type] {
+			vms = append(vms, vm)
+		}
+	}
+	return vms
+}
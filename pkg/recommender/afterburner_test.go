@@ -0,0 +1,104 @@
+// Copyright © 2019 Banzai Cloud
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package recommender
+
+import (
+	"testing"
+	"time"
+
+	"github.com/goph/logur"
+	"github.com/stretchr/testify/assert"
+)
+
+// countingProducts counts the product retrievals
+type countingProducts struct {
+	dummyProducts
+	calls int
+}
+
+func (p *countingProducts) GetProductDetails(provider string, service string, region string) ([]VirtualMachine, error) {
+	p.calls++
+	return []VirtualMachine{
+		{Type: "m5.xlarge", Cpus: 4, Mem: 16, OnDemandPrice: 0.192, AvgPrice: 0.07},
+		{Type: "c5.xlarge", Cpus: 4, Mem: 8, OnDemandPrice: 0.17, AvgPrice: 0.06},
+		{Type: "r5.xlarge", Cpus: 4, Mem: 32, OnDemandPrice: 0.252, AvgPrice: 0.08},
+	}, nil
+}
+
+func (p *countingProducts) GetProductDetailsFiltered(provider string, service string, region string, filter ProductFilter) ([]VirtualMachine, error) {
+	vms, err := p.GetProductDetails(provider, service, region)
+	if err != nil {
+		return nil, err
+	}
+	return filter.Apply(vms), nil
+}
+
+func Test_layoutFingerprint(t *testing.T) {
+	req := SingleClusterRecommendationReq{Includes: []string{"m5.xlarge", "c5.xlarge"}, Zone: "eu-west-1a"}
+	layout := []NodePoolDesc{
+		{InstanceType: "m5.xlarge", VmClass: Regular, SumNodes: 3},
+		{InstanceType: "c5.xlarge", VmClass: Spot, SumNodes: 5},
+	}
+	fingerprint := layoutFingerprint("amazon", "compute", "eu-west-1", req, layout)
+
+	resized := []NodePoolDesc{
+		{InstanceType: "c5.xlarge", VmClass: Spot, SumNodes: 8},
+		{InstanceType: "m5.xlarge", VmClass: Regular, SumNodes: 4},
+	}
+	reordered := SingleClusterRecommendationReq{Includes: []string{"c5.xlarge", "m5.xlarge"}, Zone: "eu-west-1a"}
+	assert.Equal(t, fingerprint, layoutFingerprint("amazon", "compute", "eu-west-1", reordered, resized),
+		"node counts and ordering should not change the fingerprint")
+
+	reclassed := []NodePoolDesc{
+		{InstanceType: "m5.xlarge", VmClass: Spot, SumNodes: 3},
+		{InstanceType: "c5.xlarge", VmClass: Spot, SumNodes: 5},
+	}
+	assert.NotEqual(t, fingerprint, layoutFingerprint("amazon", "compute", "eu-west-1", req, reclassed))
+	assert.NotEqual(t, fingerprint, layoutFingerprint("amazon", "compute", "eu-west-2", req, layout))
+}
+
+func TestEngine_getProductsAfterburner(t *testing.T) {
+	layout := []NodePoolDesc{{InstanceType: "m5.xlarge", VmClass: Regular, SumNodes: 3}}
+	req := SingleClusterRecommendationReq{Includes: []string{"m5.xlarge"}}
+
+	source := &countingProducts{}
+	engine := NewEngine(logur.NewTestLogger(), source, nil, nil, WithAfterburner(time.Minute))
+	now := time.Now()
+	engine.afterburner.now = func() time.Time { return now }
+
+	vms, _, err := engine.getProducts("amazon", "compute", "eu-west-1", req, layout)
+	assert.NoError(t, err)
+	assert.Equal(t, 1, source.calls)
+	if assert.Len(t, vms, 1, "only the candidates of the layout should be kept") {
+		assert.Equal(t, "m5.xlarge", vms[0].Type)
+	}
+
+	// the returned vms are modified by the recommendation, the stored ones should not change
+	vms[0].OnDemandPrice = 1
+
+	vms, _, err = engine.getProducts("amazon", "compute", "eu-west-1", req, layout)
+	assert.NoError(t, err)
+	assert.Equal(t, 1, source.calls, "the candidates should be reused")
+	assert.Equal(t, 0.192, vms[0].OnDemandPrice)
+
+	_, _, err = engine.getProducts("amazon", "compute", "eu-west-1", req, nil)
+	assert.NoError(t, err)
+	assert.Equal(t, 2, source.calls, "recommendations without a layout should not use the afterburner")
+
+	now = now.Add(2 * time.Minute)
+	_, _, err = engine.getProducts("amazon", "compute", "eu-west-1", req, layout)
+	assert.NoError(t, err)
+	assert.Equal(t, 3, source.calls, "expired candidates should be retrieved again")
+}
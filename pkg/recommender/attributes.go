@@ -0,0 +1,117 @@
+// Copyright © 2019 Banzai Cloud
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package recommender
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"strconv"
+
+	"github.com/goph/emperror"
+)
+
+// AttributeValues lists the distinct values of an attribute of the instance types of a region, eg. to build min/max
+// node size controls that snap to the real instance sizes
+type AttributeValues struct {
+	// The attribute: cpu or memory
+	Attribute string `json:"attribute"`
+	// Unit of the values: vCPU for cpu, GiB (or GB if requested) for memory
+	Unit string `json:"unit"`
+	// Values of the attribute, ascending
+	Values []AttributeValue `json:"values"`
+}
+
+// AttributeValue is a distinct value of an attribute
+type AttributeValue struct {
+	// The value of the attribute in the unit of the response
+	Value float64 `json:"value"`
+	// The value as reported by the provider (eg. "3,904 GiB"), formatted from the value if the provider's form is unknown
+	Label string `json:"label"`
+	// Number of the instance types having the value
+	InstanceTypes int `json:"instanceTypes"`
+}
+
+// AttributeValues lists the distinct values of the attribute of the instance types of the region; the memory
+// is converted to the memory unit of the units
+func (e *Engine) AttributeValues(provider string, service string, region string, attr string, units Units) (*AttributeValues, error) {
+	if attr != Cpu && attr != Memory {
+		return nil, emperror.With(ErrUnsupportedAttribute, RecommenderErrorTag, "attribute", attr)
+	}
+	if err := e.checkTenantProvider(provider); err != nil {
+		return nil, err
+	}
+
+	products, err := e.ciSource.GetProductDetails(provider, service, region)
+	if err != nil {
+		return nil, err
+	}
+	normalizeAttributes(provider, products)
+
+	excludes := make(map[string]bool)
+	for _, t := range e.tenantExcludes(nil) {
+		excludes[t] = true
+	}
+
+	values := &AttributeValues{Attribute: attr, Unit: "vCPU", Values: make([]AttributeValue, 0)}
+	factor := 1.0
+	if attr == Memory {
+		values.Unit = GiB
+		if units.Memory != "" {
+			values.Unit = units.Memory
+		}
+		factor = units.memFactor()
+	}
+
+	byValue := make(map[float64]*AttributeValue)
+	for _, vm := range products {
+		value := vm.GetAttrValue(attr)
+		if excludes[vm.Type] || value <= 0 {
+			continue
+		}
+		av, ok := byValue[value]
+		if !ok {
+			av = &AttributeValue{Value: value * factor}
+			byValue[value] = av
+		}
+		av.InstanceTypes++
+		if av.Label == "" {
+			av.Label = attrLabel(vm, attr, factor)
+		}
+	}
+
+	for _, av := range byValue {
+		if av.Label == "" {
+			av.Label = fmt.Sprintf("%s %s", strconv.FormatFloat(math.Round(av.Value*100)/100, 'f', -1, 64), values.Unit)
+		}
+		values.Values = append(values.Values, *av)
+	}
+	sort.Slice(values.Values, func(i, j int) bool {
+		return values.Values[i].Value < values.Values[j].Value
+	})
+	return values, nil
+}
+
+// attrLabel gets the attribute of the vm as reported by the provider, empty if it's unknown or it's not in the unit
+// of the response; the reported cpus of shared-core types don't match their normalized value
+func attrLabel(vm VirtualMachine, attr string, factor float64) string {
+	switch {
+	case attr == Cpu && vm.ReportedCpus == 0:
+		return vm.CpuLabel
+	case attr == Memory && factor == 1:
+		return vm.MemLabel
+	}
+	return ""
+}
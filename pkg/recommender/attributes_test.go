@@ -0,0 +1,94 @@
+// Copyright © 2019 Banzai Cloud
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package recommender
+
+import (
+	"testing"
+
+	"github.com/goph/logur"
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestEngine_AttributeValues(t *testing.T) {
+	vms := []VirtualMachine{
+		{Type: "x1e.32xlarge", Cpus: 128, Mem: 3904, CpuLabel: "128", MemLabel: "3,904 GiB"},
+		{Type: "m5.xlarge", Cpus: 4, Mem: 16, MemLabel: "16 GiB"},
+		{Type: "c5.2xlarge", Cpus: 8, Mem: 16},
+		{Type: "c5.xlarge", Cpus: 4, Mem: 8},
+	}
+	tests := []struct {
+		name  string
+		attr  string
+		units Units
+		check func(values *AttributeValues, err error)
+	}{
+		{
+			name: "memory values with the provider's forms",
+			attr: Memory,
+			check: func(values *AttributeValues, err error) {
+				assert.Nil(t, err, "the error should be nil")
+				assert.Equal(t, GiB, values.Unit)
+				assert.Equal(t, []AttributeValue{
+					{Value: 8, Label: "8 GiB", InstanceTypes: 1},
+					{Value: 16, Label: "16 GiB", InstanceTypes: 2},
+					{Value: 3904, Label: "3,904 GiB", InstanceTypes: 1},
+				}, values.Values)
+			},
+		},
+		{
+			name:  "memory values converted",
+			attr:  Memory,
+			units: Units{Memory: GB},
+			check: func(values *AttributeValues, err error) {
+				assert.Nil(t, err, "the error should be nil")
+				assert.Equal(t, GB, values.Unit)
+				if assert.Len(t, values.Values, 3) {
+					assert.InDelta(t, 8.59, values.Values[0].Value, 0.01)
+					assert.Equal(t, "8.59 GB", values.Values[0].Label, "the forms in GiB should not be used")
+				}
+			},
+		},
+		{
+			name: "cpu values",
+			attr: Cpu,
+			check: func(values *AttributeValues, err error) {
+				assert.Nil(t, err, "the error should be nil")
+				assert.Equal(t, "vCPU", values.Unit)
+				assert.Equal(t, []AttributeValue{
+					{Value: 4, Label: "4 vCPU", InstanceTypes: 2},
+					{Value: 8, Label: "8 vCPU", InstanceTypes: 1},
+					{Value: 128, Label: "128", InstanceTypes: 1},
+				}, values.Values)
+			},
+		},
+		{
+			name: "unsupported attribute",
+			attr: "gpu",
+			check: func(values *AttributeValues, err error) {
+				assert.Nil(t, values, "the response should be nil")
+				assert.Equal(t, ErrUnsupportedAttribute, errors.Cause(err))
+			},
+		},
+	}
+	for _, test := range tests {
+		test := test
+		t.Run(test.name, func(t *testing.T) {
+			engine := NewEngine(logur.NewTestLogger(), &candidateProducts{vms: vms}, nil, nil)
+
+			test.check(engine.AttributeValues("amazon", "compute", "eu-west-1", test.attr, test.units))
+		})
+	}
+}
@@ -0,0 +1,175 @@
+// Copyright © 2019 Banzai Cloud
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package recommender
+
+import (
+	"fmt"
+	"math"
+	"time"
+
+	"github.com/goph/emperror"
+)
+
+const (
+	// defaultBacktestStep is the sampling interval of the spot prices if not requested otherwise
+	defaultBacktestStep = time.Hour
+	// maxBacktestSamples is the maximum number of samples per pool, Prometheus refuses larger range queries
+	maxBacktestSamples = 11000
+)
+
+// BacktestReq encapsulates the layout to replay against the spot price history
+// swagger:model backtestRequest
+type BacktestReq struct {
+	// The layout to replay
+	Layout []NodePoolDesc `json:"layout" binding:"required,dive"`
+	// Start of the window
+	Start time.Time `json:"start" binding:"required"`
+	// End of the window
+	End time.Time `json:"end" binding:"required"`
+	// Sampling interval of the spot prices in minutes, defaults to an hour
+	StepMinutes int `json:"stepMinutes,omitempty" binding:"min=0"`
+}
+
+// step returns the sampling interval of the spot prices
+func (r BacktestReq) step() time.Duration {
+	if r.StepMinutes == 0 {
+		return defaultBacktestStep
+	}
+	return time.Duration(r.StepMinutes) * time.Minute
+}
+
+// Backtest is the cost of a layout over a window in the past
+type Backtest struct {
+	// Start of the window
+	Start time.Time `json:"start"`
+	// End of the window
+	End time.Time `json:"end"`
+	// Node pools of the layout
+	NodePools []PoolBacktest `json:"nodePools"`
+	// Cost of the layout over the window
+	Cost float64 `json:"cost"`
+	// Cost of the same nodes over the window, were they all on-demand
+	OnDemandCost float64 `json:"onDemandCost"`
+	// Warnings about the backtest that API consumers should be aware of
+	Warnings []Warning `json:"warnings,omitempty"`
+}
+
+// PoolBacktest is the cost of a node pool over the backtest window
+type PoolBacktest struct {
+	// Instance type of the pool
+	VmType string `json:"vmType"`
+	// Signals that the pool consists of regular, reserved or spot/preemptible instances
+	VmClass string `json:"vmClass"`
+	// Number of nodes in the pool
+	SumNodes int `json:"sumNodes"`
+	// Cost of the pool over the window
+	Cost float64 `json:"cost"`
+	// Current on-demand price of the instance type
+	OnDemandPrice float64 `json:"onDemandPrice"`
+	// Number of spot price samples
+	Samples int `json:"samples,omitempty"`
+	// Lowest, average and highest spot price in the window
+	MinSpotPrice float64 `json:"minSpotPrice,omitempty"`
+	AvgSpotPrice float64 `json:"avgSpotPrice,omitempty"`
+	MaxSpotPrice float64 `json:"maxSpotPrice,omitempty"`
+	// Number of samples the spot price exceeded the on-demand price in, and their percentage
+	ExceededOnDemand    int     `json:"exceededOnDemand,omitempty"`
+	ExceededOnDemandPct float64 `json:"exceededOnDemandPct,omitempty"`
+}
+
+// Backtest replays the layout against the spot price history, reporting what it would have cost over the window and
+// how often the spot prices of its pools exceeded the on-demand price
+// The on-demand prices are the current ones, cloud info doesn't keep their history
+func (e *Engine) Backtest(provider string, service string, region string, req BacktestReq) (*Backtest, error) {
+	if err := e.checkTenantProvider(provider); err != nil {
+		return nil, err
+	}
+	if e.priceHistory == nil {
+		return nil, emperror.With(ErrPriceHistoryUnavailable, RecommenderErrorTag)
+	}
+
+	step := req.step()
+	if !req.End.After(req.Start) || req.End.Sub(req.Start)/step > maxBacktestSamples {
+		return nil, emperror.With(ErrInvalidBacktestWindow, RecommenderErrorTag, "start", req.Start, "end", req.End, "step", step)
+	}
+
+	products, err := e.ciSource.GetProductDetails(provider, service, region)
+	if err != nil {
+		return nil, err
+	}
+	vms := make(map[string]VirtualMachine, len(products))
+	for _, vm := range products {
+		vms[vm.Type] = vm
+	}
+
+	hours := req.End.Sub(req.Start).Hours()
+	backtest := &Backtest{Start: req.Start, End: req.End, NodePools: make([]PoolBacktest, 0, len(req.Layout))}
+	for _, npd := range splitMixedPools(req.Layout) {
+		vm, ok := vms[npd.InstanceType]
+		if !ok {
+			return nil, emperror.With(ErrUnknownInstanceType, RecommenderErrorTag, "instanceType", npd.InstanceType)
+		}
+
+		pool := PoolBacktest{
+			VmType:        npd.InstanceType,
+			VmClass:       npd.GetVmClass(),
+			SumNodes:      npd.SumNodes,
+			OnDemandPrice: vm.OnDemandPrice,
+		}
+
+		switch pool.VmClass {
+		case Regular:
+			pool.Cost = vm.OnDemandPrice * float64(npd.SumNodes) * hours
+		case Spot:
+			points, err := e.priceHistory.SpotPrices(provider, service, region, npd.InstanceType, req.Start, req.End, step)
+			if err != nil {
+				return nil, emperror.WrapWith(err, "failed to retrieve the spot price history", RecommenderErrorTag, "instanceType", npd.InstanceType)
+			}
+			if len(points) == 0 {
+				backtest.Warnings = append(backtest.Warnings, Warning{
+					Code:    WarnSpotPriceHistoryMissing,
+					Message: fmt.Sprintf("no spot price history of %s, the current spot price is used", npd.InstanceType),
+				})
+				points = []PricePoint{{Time: req.End, Price: vm.AvgPrice}}
+			}
+			pool.applySpotPrices(points)
+			pool.Cost = pool.AvgSpotPrice * float64(npd.SumNodes) * hours
+		}
+		// reserved pools are already paid for, they cost nothing
+
+		backtest.NodePools = append(backtest.NodePools, pool)
+		backtest.Cost += pool.Cost
+		backtest.OnDemandCost += vm.OnDemandPrice * float64(npd.SumNodes) * hours
+	}
+
+	return backtest, nil
+}
+
+// applySpotPrices summarizes the spot price samples of the pool
+func (p *PoolBacktest) applySpotPrices(points []PricePoint) {
+	p.Samples = len(points)
+	p.MinSpotPrice = math.Inf(1)
+	var sum float64
+	for _, point := range points {
+		sum += point.Price
+		p.MinSpotPrice = math.Min(p.MinSpotPrice, point.Price)
+		p.MaxSpotPrice = math.Max(p.MaxSpotPrice, point.Price)
+		if point.Price > p.OnDemandPrice {
+			p.ExceededOnDemand++
+		}
+	}
+	p.AvgSpotPrice = sum / float64(len(points))
+	p.ExceededOnDemandPct = float64(p.ExceededOnDemand) / float64(len(points)) * 100
+}
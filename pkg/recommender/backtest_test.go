@@ -0,0 +1,137 @@
+// Copyright © 2019 Banzai Cloud
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package recommender
+
+import (
+	"testing"
+	"time"
+
+	"github.com/goph/logur"
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+)
+
+// dummyPriceHistory serves the spot prices per instance type
+type dummyPriceHistory map[string][]float64
+
+func (h dummyPriceHistory) SpotPrices(provider, service, region, vmType string, start, end time.Time, step time.Duration) ([]PricePoint, error) {
+	var points []PricePoint
+	for i, price := range h[vmType] {
+		points = append(points, PricePoint{Time: start.Add(time.Duration(i) * step), Price: price})
+	}
+	return points, nil
+}
+
+func TestEngine_Backtest(t *testing.T) {
+	start := time.Date(2019, 5, 1, 0, 0, 0, 0, time.UTC)
+	history := dummyPriceHistory{
+		"m5.xlarge": {0.06, 0.08, 0.25, 0.09},
+	}
+	tests := []struct {
+		name    string
+		history PriceHistory
+		req     BacktestReq
+		check   func(backtest *Backtest, err error)
+	}{
+		{
+			name:    "spot and regular pools",
+			history: history,
+			req: BacktestReq{
+				Layout: []NodePoolDesc{
+					{InstanceType: "m5.xlarge", VmClass: Spot, SumNodes: 2},
+					{InstanceType: "c5.xlarge", VmClass: Regular, SumNodes: 1},
+					{InstanceType: "r5.xlarge", VmClass: Reserved, SumNodes: 1},
+				},
+				Start: start,
+				End:   start.Add(10 * time.Hour),
+			},
+			check: func(backtest *Backtest, err error) {
+				assert.Nil(t, err, "the error should be nil")
+				spot := backtest.NodePools[0]
+				assert.Equal(t, 4, spot.Samples)
+				assert.Equal(t, 1, spot.ExceededOnDemand, "the spot price exceeded the on-demand price once")
+				assert.Equal(t, float64(25), spot.ExceededOnDemandPct)
+				assert.InDelta(t, 0.12, spot.AvgSpotPrice, 1e-9)
+				assert.InDelta(t, 0.12*2*10, spot.Cost, 1e-9)
+				assert.InDelta(t, 0.17*10, backtest.NodePools[1].Cost, 1e-9)
+				assert.Equal(t, float64(0), backtest.NodePools[2].Cost, "reserved pools are already paid for")
+				assert.InDelta(t, 0.12*2*10+0.17*10, backtest.Cost, 1e-9)
+				assert.InDelta(t, (0.192*2+0.17+0.252)*10, backtest.OnDemandCost, 1e-9)
+				assert.Empty(t, backtest.Warnings)
+			},
+		},
+		{
+			name:    "missing spot price history",
+			history: history,
+			req: BacktestReq{
+				Layout: []NodePoolDesc{{InstanceType: "c5.xlarge", VmClass: Spot, SumNodes: 1}},
+				Start:  start,
+				End:    start.Add(time.Hour),
+			},
+			check: func(backtest *Backtest, err error) {
+				assert.Nil(t, err, "the error should be nil")
+				assert.Equal(t, 0.06, backtest.NodePools[0].AvgSpotPrice, "the current spot price should be used")
+				assert.Equal(t, WarnSpotPriceHistoryMissing, backtest.Warnings[0].Code)
+			},
+		},
+		{
+			name:    "unknown instance type",
+			history: history,
+			req: BacktestReq{
+				Layout: []NodePoolDesc{{InstanceType: "x1.32xlarge", VmClass: Spot, SumNodes: 1}},
+				Start:  start,
+				End:    start.Add(time.Hour),
+			},
+			check: func(backtest *Backtest, err error) {
+				assert.Equal(t, ErrUnknownInstanceType, errors.Cause(err))
+			},
+		},
+		{
+			name:    "window ending before its start",
+			history: history,
+			req:     BacktestReq{Start: start, End: start.Add(-time.Hour)},
+			check: func(backtest *Backtest, err error) {
+				assert.Equal(t, ErrInvalidBacktestWindow, errors.Cause(err))
+			},
+		},
+		{
+			name:    "too many samples",
+			history: history,
+			req:     BacktestReq{Start: start, End: start.Add(365 * 24 * time.Hour), StepMinutes: 1},
+			check: func(backtest *Backtest, err error) {
+				assert.Equal(t, ErrInvalidBacktestWindow, errors.Cause(err))
+			},
+		},
+		{
+			name: "no price history",
+			req:  BacktestReq{Start: start, End: start.Add(time.Hour)},
+			check: func(backtest *Backtest, err error) {
+				assert.Equal(t, ErrPriceHistoryUnavailable, errors.Cause(err))
+			},
+		},
+	}
+	for _, test := range tests {
+		test := test
+		t.Run(test.name, func(t *testing.T) {
+			var opts []EngineOption
+			if test.history != nil {
+				opts = append(opts, WithPriceHistory(test.history))
+			}
+			engine := NewEngine(logur.NewTestLogger(), &countingProducts{}, nil, nil, opts...)
+
+			test.check(engine.Backtest("amazon", "compute", "eu-west-1", test.req))
+		})
+	}
+}
@@ -0,0 +1,58 @@
+// Copyright © 2019 Banzai Cloud
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package recommender
+
+import (
+	"encoding/json"
+	"io/ioutil"
+
+	"github.com/goph/emperror"
+)
+
+// Benchmarks holds performance scores (eg. coremark) per provider and instance type
+type Benchmarks map[string]map[string]float64
+
+// LoadBenchmarks reads the benchmark scores from a JSON file in the {"provider": {"instanceType": score}} format
+func LoadBenchmarks(path string) (Benchmarks, error) {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, emperror.WrapWith(err, "failed to read benchmarks file", "path", path)
+	}
+
+	var benchmarks Benchmarks
+	if err := json.Unmarshal(data, &benchmarks); err != nil {
+		return nil, emperror.WrapWith(err, "failed to parse benchmarks file", "path", path)
+	}
+
+	return benchmarks, nil
+}
+
+// Score gets the benchmark score of the instance type, returns false if there's no score available
+func (b Benchmarks) Score(provider string, vmType string) (float64, bool) {
+	score, ok := b[provider][vmType]
+	return score, ok && score > 0
+}
+
+// applyBenchmarks sets the benchmark scores of the given vms, returns true if at least one vm has a score
+func (b Benchmarks) applyBenchmarks(provider string, vms []VirtualMachine) bool {
+	scored := false
+	for i := range vms {
+		if score, ok := b.Score(provider, vms[i].Type); ok {
+			vms[i].BenchmarkScore = score
+			scored = true
+		}
+	}
+	return scored
+}
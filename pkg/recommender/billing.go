@@ -0,0 +1,77 @@
+// Copyright © 2019 Banzai Cloud
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package recommender
+
+// HoursPerMonth is the average number of hours in a month
+const HoursPerMonth = 730
+
+// MonthsPerYear is the number of months the yearly cost is projected for
+const MonthsPerYear = 12
+
+// BillingModel estimates the monthly bill of node pools running for a whole month
+// Billing granularity (per second, per minute or per hour) makes no difference for nodes running all month long,
+// so the models differ only in the discounts applied to the list prices
+type BillingModel interface {
+	// MonthlyCost gets the estimated monthly cost of the node pool
+	MonthlyCost(np NodePool) float64
+}
+
+// listPriceBilling bills the hourly list price for every hour of the month
+type listPriceBilling struct{}
+
+func (b listPriceBilling) MonthlyCost(np NodePool) float64 {
+	return np.PoolPrice() * HoursPerMonth
+}
+
+// sustainedUseBilling discounts the on-demand price of instances running for a large part of the month (GCE)
+type sustainedUseBilling struct {
+	// discount for instances running for the whole month
+	fullMonthDiscountPct float64
+}
+
+func (b sustainedUseBilling) MonthlyCost(np NodePool) float64 {
+	cost := np.PoolPrice() * HoursPerMonth
+	// preemptible instances and flat fees (eg. control planes with no cpus) are not discounted
+	if np.VmClass == Regular && np.VmType.Cpus > 0 {
+		cost *= 1 - b.fullMonthDiscountPct/100
+	}
+	return cost
+}
+
+// BillingModelFor gets the billing model of the provider
+func BillingModelFor(provider string) BillingModel {
+	switch provider {
+	case "google":
+		return sustainedUseBilling{fullMonthDiscountPct: 30}
+	default:
+		return listPriceBilling{}
+	}
+}
+
+// monthlyCost gets the estimated monthly bill of the node pools on the provider
+func monthlyCost(provider string, nodePools []NodePool) float64 {
+	bm := BillingModelFor(provider)
+
+	var cost float64
+	for _, np := range nodePools {
+		cost += bm.MonthlyCost(np)
+	}
+	return cost
+}
+
+// yearlyCost projects the monthly bill of the node pools on the provider for a year
+func yearlyCost(provider string, nodePools []NodePool) float64 {
+	return monthlyCost(provider, nodePools) * MonthsPerYear
+}
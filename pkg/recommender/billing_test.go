@@ -0,0 +1,63 @@
+// Copyright © 2019 Banzai Cloud
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package recommender
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_monthlyCost(t *testing.T) {
+	nodePools := []NodePool{
+		{VmType: VirtualMachine{Cpus: 2, OnDemandPrice: 0.1, AvgPrice: 0.03}, SumNodes: 2, VmClass: Regular, Role: Worker},
+		{VmType: VirtualMachine{Cpus: 2, OnDemandPrice: 0.1, AvgPrice: 0.03}, SumNodes: 2, VmClass: Spot, Role: Worker},
+		{VmType: VirtualMachine{OnDemandPrice: 0.1}, SumNodes: 1, VmClass: Regular, Role: Master},
+	}
+	tests := []struct {
+		name     string
+		provider string
+		check    func(cost float64)
+	}{
+		{
+			name:     "list prices",
+			provider: "amazon",
+			check: func(cost float64) {
+				assert.InDelta(t, (0.2+0.06+0.1)*HoursPerMonth, cost, 0.0001)
+			},
+		},
+		{
+			name:     "sustained use discount on regular instances",
+			provider: "google",
+			check: func(cost float64) {
+				assert.InDelta(t, (0.2*0.7+0.06+0.1)*HoursPerMonth, cost, 0.0001)
+			},
+		},
+	}
+	for _, test := range tests {
+		test := test
+		t.Run(test.name, func(t *testing.T) {
+			test.check(monthlyCost(test.provider, nodePools))
+		})
+	}
+}
+
+func Test_yearlyCost(t *testing.T) {
+	nodePools := []NodePool{
+		{VmType: VirtualMachine{Cpus: 2, OnDemandPrice: 0.1}, SumNodes: 2, VmClass: Regular, Role: Worker},
+	}
+	assert.InDelta(t, 0.2*0.7*HoursPerMonth*12, yearlyCost("google", nodePools), 0.0001,
+		"the yearly cost should be projected from the discounted monthly bill")
+}
@@ -0,0 +1,128 @@
+// Copyright © 2019 Banzai Cloud
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package recommender
+
+import (
+	"sync/atomic"
+
+	"github.com/banzaicloud/telescopes/.gen/cloudinfo"
+	"github.com/goph/emperror"
+)
+
+// callBudget counts the calls a single recommendation makes to the cloud info service
+type callBudget struct {
+	// limit is the maximum number of calls, unlimited if zero
+	limit int
+	calls int32
+}
+
+// spend registers a call, returns an error if the call exceeds the limit
+func (b *callBudget) spend() error {
+	calls := int(atomic.AddInt32(&b.calls, 1))
+	if b.limit > 0 && calls > b.limit {
+		return emperror.With(ErrCallBudgetExceeded, RecommenderErrorTag, "budget", b.limit, "calls", calls)
+	}
+	return nil
+}
+
+// count returns the number of calls registered so far
+func (b *callBudget) count() int {
+	return int(atomic.LoadInt32(&b.calls))
+}
+
+// budgetedSource is a CloudInfoSource spending the budget on every call reaching the cloud info service
+// The data freshness is tracked locally, retrieving it isn't counted
+type budgetedSource struct {
+	CloudInfoSource
+	budget *callBudget
+}
+
+func (s *budgetedSource) GetProductDetails(provider string, service string, region string) ([]VirtualMachine, error) {
+	if err := s.budget.spend(); err != nil {
+		return nil, err
+	}
+	return s.CloudInfoSource.GetProductDetails(provider, service, region)
+}
+
+func (s *budgetedSource) GetProductDetailsFiltered(provider string, service string, region string, filter ProductFilter) ([]VirtualMachine, error) {
+	if err := s.budget.spend(); err != nil {
+		return nil, err
+	}
+	return s.CloudInfoSource.GetProductDetailsFiltered(provider, service, region, filter)
+}
+
+func (s *budgetedSource) GetRegions(provider, service string) ([]cloudinfo.Region, error) {
+	if err := s.budget.spend(); err != nil {
+		return nil, err
+	}
+	return s.CloudInfoSource.GetRegions(provider, service)
+}
+
+func (s *budgetedSource) GetContinentsData(provider, service string) ([]cloudinfo.Continent, error) {
+	if err := s.budget.spend(); err != nil {
+		return nil, err
+	}
+	return s.CloudInfoSource.GetContinentsData(provider, service)
+}
+
+func (s *budgetedSource) GetZones(provider, service, region string) ([]string, error) {
+	if err := s.budget.spend(); err != nil {
+		return nil, err
+	}
+	return s.CloudInfoSource.GetZones(provider, service, region)
+}
+
+func (s *budgetedSource) GetContinents() ([]string, error) {
+	if err := s.budget.spend(); err != nil {
+		return nil, err
+	}
+	return s.CloudInfoSource.GetContinents()
+}
+
+func (s *budgetedSource) GetProviders() ([]cloudinfo.Provider, error) {
+	if err := s.budget.spend(); err != nil {
+		return nil, err
+	}
+	return s.CloudInfoSource.GetProviders()
+}
+
+func (s *budgetedSource) GetRegion(provider string, service string, region string) (string, error) {
+	if err := s.budget.spend(); err != nil {
+		return "", err
+	}
+	return s.CloudInfoSource.GetRegion(provider, service, region)
+}
+
+func (s *budgetedSource) GetProvider(provider string) (string, error) {
+	if err := s.budget.spend(); err != nil {
+		return "", err
+	}
+	return s.CloudInfoSource.GetProvider(provider)
+}
+
+func (s *budgetedSource) GetService(provider string, service string) (string, error) {
+	if err := s.budget.spend(); err != nil {
+		return "", err
+	}
+	return s.CloudInfoSource.GetService(provider, service)
+}
+
+// withCallBudget returns a copy of the engine counting its calls to the cloud info service against a new budget
+func (e *Engine) withCallBudget() (*Engine, *callBudget) {
+	budget := &callBudget{limit: e.callBudget}
+	budgeted := *e
+	budgeted.ciSource = &budgetedSource{CloudInfoSource: e.ciSource, budget: budget}
+	return &budgeted, budget
+}
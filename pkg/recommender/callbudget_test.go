@@ -0,0 +1,64 @@
+// Copyright © 2019 Banzai Cloud
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package recommender
+
+import (
+	"testing"
+
+	"github.com/goph/logur"
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestEngine_RecommendClusterCallBudget(t *testing.T) {
+	req := SingleClusterRecommendationReq{
+		ClusterRecommendationReq: ClusterRecommendationReq{
+			MinNodes: 1,
+			MaxNodes: 1,
+			SumMem:   32,
+			SumCpu:   16,
+		},
+	}
+
+	engine := NewEngine(logur.NewTestLogger(), &dummyProducts{}, &dummyVms{}, &dummyNodePools{})
+	resp, err := engine.RecommendCluster("dummyProvider", "dummyService", "dummyRegion", req, nil)
+	assert.Nil(t, err, "the error should be nil")
+	calls := resp.CloudInfoCalls
+	assert.True(t, calls > 0, "the cloud info calls should be counted")
+
+	resp, err = engine.RecommendCluster("dummyProvider", "dummyService", "dummyRegion", req, nil)
+	assert.Nil(t, err, "the error should be nil")
+	assert.Equal(t, calls, resp.CloudInfoCalls, "the calls should be counted per recommendation")
+
+	engine = NewEngine(logur.NewTestLogger(), &dummyProducts{}, &dummyVms{}, &dummyNodePools{}, WithCallBudget(calls))
+	_, err = engine.RecommendCluster("dummyProvider", "dummyService", "dummyRegion", req, nil)
+	assert.Nil(t, err, "a recommendation within the budget should succeed")
+}
+
+func TestBudgetedSource(t *testing.T) {
+	budget := &callBudget{limit: 2}
+	source := &budgetedSource{CloudInfoSource: &dummyProducts{}, budget: budget}
+
+	_, err := source.GetRegions("dummyProvider", "dummyService")
+	assert.Nil(t, err)
+	_, err = source.GetProductDetails("dummyProvider", "dummyService", "dummyRegion")
+	assert.Nil(t, err)
+	source.GetAllDataFreshness()
+	assert.Equal(t, 2, budget.count(), "retrieving the data freshness should not be counted")
+
+	_, err = source.GetProductDetailsFiltered("dummyProvider", "dummyService", "dummyRegion", ProductFilter{})
+	assert.Equal(t, ErrCallBudgetExceeded, errors.Cause(err))
+	assert.Equal(t, 3, budget.count())
+}
@@ -0,0 +1,133 @@
+// Copyright © 2019 Banzai Cloud
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package recommender
+
+import (
+	"math/rand"
+	"sort"
+	"sync"
+
+	"github.com/goph/emperror"
+	"github.com/goph/logur"
+)
+
+// canaryStrategy is an experimental variation of the recommendation heuristics, expressed as a rewrite of the request
+type canaryStrategy func(req SingleClusterRecommendationReq) SingleClusterRecommendationReq
+
+// canaryStrategies holds the experimental strategies by name
+var canaryStrategies = map[string]canaryStrategy{
+	// fewer, larger nodes spread across fewer types
+	"largerNodes": func(req SingleClusterRecommendationReq) SingleClusterRecommendationReq {
+		req.PreferLargerNodes = true
+		return req
+	},
+	// the per node size window widened by 20%
+	"relaxedWindow": func(req SingleClusterRecommendationReq) SingleClusterRecommendationReq {
+		if req.CandidateWindow.RelaxPct < 20 {
+			req.CandidateWindow.RelaxPct = 20
+		}
+		return req
+	},
+}
+
+// CanaryStrategies lists the names of the experimental strategies, ordered by name
+func CanaryStrategies() []string {
+	names := make([]string, 0, len(canaryStrategies))
+	for name := range canaryStrategies {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Canary is a ClusterRecommender evaluating an experimental strategy against the stable one with production traffic:
+// the sampled cluster recommendations are repeated with the experimental strategy in the background and the results
+// are compared in the logs; only the stable result is returned
+type Canary struct {
+	ClusterRecommender
+
+	name      string
+	strategy  canaryStrategy
+	samplePct int
+	log       logur.Logger
+
+	// pending tracks the experimental recommendations in progress
+	pending sync.WaitGroup
+}
+
+// NewCanary creates a canary evaluation of the named experimental strategy on the given percentage of the requests
+func NewCanary(stable ClusterRecommender, name string, samplePct int, log logur.Logger) (*Canary, error) {
+	strategy, ok := canaryStrategies[name]
+	if !ok {
+		return nil, emperror.With(ErrUnknownCanaryStrategy, "strategy", name, "strategies", CanaryStrategies())
+	}
+	return &Canary{
+		ClusterRecommender: stable,
+		name:               name,
+		strategy:           strategy,
+		samplePct:          samplePct,
+		log:                logur.WithFields(log, map[string]interface{}{"strategy": name}),
+	}, nil
+}
+
+// RecommendCluster returns the stable recommendation, the experimental one is made in the background if sampled
+func (c *Canary) RecommendCluster(provider string, service string, region string, req SingleClusterRecommendationReq, layoutDesc []NodePoolDesc) (*ClusterRecommendationResp, error) {
+	resp, err := c.ClusterRecommender.RecommendCluster(provider, service, region, req, layoutDesc)
+	if err != nil || rand.Intn(100) >= c.samplePct {
+		return resp, err
+	}
+
+	c.pending.Add(1)
+	go func() {
+		defer c.pending.Done()
+
+		experimental, err := c.ClusterRecommender.RecommendCluster(provider, service, region, c.strategy(req), layoutDesc)
+		if err != nil {
+			c.log.Warn("experimental recommendation failed",
+				map[string]interface{}{"provider": provider, "service": service, "region": region, "err": err.Error()})
+			return
+		}
+		c.compare(resp, experimental)
+	}()
+
+	return resp, nil
+}
+
+// compare logs the price and node count differences of the experimental recommendation relative to the stable one
+func (c *Canary) compare(stable, experimental *ClusterRecommendationResp) {
+	comparison := CompareLayouts(LayoutComparisonReq{
+		Provider: stable.Provider,
+		Base:     stable.NodePools,
+		Target:   experimental.NodePools,
+	})
+
+	var priceDeltaPct float64
+	if comparison.HourlyPrice.Base > 0 {
+		priceDeltaPct = comparison.HourlyPrice.Delta / comparison.HourlyPrice.Base * 100
+	}
+
+	c.log.Info("canary comparison", map[string]interface{}{
+		"provider":          stable.Provider,
+		"service":           stable.Service,
+		"region":            stable.Region,
+		"stablePrice":       comparison.HourlyPrice.Base,
+		"experimentalPrice": comparison.HourlyPrice.Target,
+		"priceDelta":        comparison.HourlyPrice.Delta,
+		"priceDeltaPct":     priceDeltaPct,
+		"stableNodes":       comparison.Nodes.Base,
+		"experimentalNodes": comparison.Nodes.Target,
+		"nodeDelta":         comparison.Nodes.Delta,
+	})
+}
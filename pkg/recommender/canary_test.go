@@ -0,0 +1,88 @@
+// Copyright © 2019 Banzai Cloud
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package recommender
+
+import (
+	"sync"
+	"testing"
+
+	"github.com/goph/logur"
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+)
+
+// sizingRecommender recommends 2 larger nodes if larger nodes are preferred, 4 smaller ones otherwise
+type sizingRecommender struct {
+	ClusterRecommender
+
+	mux   sync.Mutex
+	calls int
+}
+
+func (r *sizingRecommender) RecommendCluster(provider string, service string, region string, req SingleClusterRecommendationReq, layoutDesc []NodePoolDesc) (*ClusterRecommendationResp, error) {
+	r.mux.Lock()
+	r.calls++
+	r.mux.Unlock()
+
+	pool := NodePool{VmClass: Regular, Role: Worker, SumNodes: 4, VmType: VirtualMachine{Type: "m5.xlarge", OnDemandPrice: 0.2}}
+	if req.PreferLargerNodes {
+		pool = NodePool{VmClass: Regular, Role: Worker, SumNodes: 2, VmType: VirtualMachine{Type: "m5.2xlarge", OnDemandPrice: 0.38}}
+	}
+	return &ClusterRecommendationResp{Provider: provider, Service: service, Region: region, NodePools: []NodePool{pool}}, nil
+}
+
+func TestCanary_RecommendCluster(t *testing.T) {
+	stable := &sizingRecommender{}
+	log := logur.NewTestLogger()
+	canary, err := NewCanary(stable, "largerNodes", 100, log)
+	if !assert.NoError(t, err) {
+		return
+	}
+
+	resp, err := canary.RecommendCluster("amazon", "compute", "eu-west-1", SingleClusterRecommendationReq{}, nil)
+	if !assert.NoError(t, err) {
+		return
+	}
+	canary.pending.Wait()
+
+	assert.Equal(t, 4, resp.NodePools[0].SumNodes, "the stable recommendation should be returned")
+	assert.Equal(t, 2, stable.calls)
+
+	event := log.LastEvent()
+	if !assert.NotNil(t, event) {
+		return
+	}
+	assert.Equal(t, "canary comparison", event.Line)
+	assert.Equal(t, -2.0, event.Fields["nodeDelta"])
+	assert.InDelta(t, -0.04, event.Fields["priceDelta"], 0.0001)
+	assert.InDelta(t, -5, event.Fields["priceDeltaPct"], 0.0001)
+}
+
+func TestCanary_notSampled(t *testing.T) {
+	stable := &sizingRecommender{}
+	canary, err := NewCanary(stable, "relaxedWindow", 0, logur.NewTestLogger())
+	if !assert.NoError(t, err) {
+		return
+	}
+
+	_, _ = canary.RecommendCluster("amazon", "compute", "eu-west-1", SingleClusterRecommendationReq{}, nil)
+	canary.pending.Wait()
+	assert.Equal(t, 1, stable.calls, "the experimental strategy should not run unsampled")
+}
+
+func TestNewCanary_unknownStrategy(t *testing.T) {
+	_, err := NewCanary(&sizingRecommender{}, "magic", 100, logur.NewTestLogger())
+	assert.Equal(t, ErrUnknownCanaryStrategy, errors.Cause(err))
+}
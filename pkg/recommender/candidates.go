@@ -0,0 +1,139 @@
+// Copyright © 2019 Banzai Cloud
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package recommender
+
+import (
+	"sort"
+
+	"github.com/goph/emperror"
+)
+
+// CandidatesResp lists the vms a recommendation would select the node pools from
+type CandidatesResp struct {
+	// The cloud provider
+	Provider string `json:"provider"`
+	// Provider's service
+	Service string `json:"service"`
+	// Service's region
+	Region string `json:"region"`
+	// Availability zone of the candidates, if requested
+	Zone string `json:"zone,omitempty"`
+	// Candidates per attribute (cpu or memory) the node pools are recommended by
+	Candidates map[string]AttrCandidates `json:"candidates"`
+	// Warnings about the adjustments of the request
+	Warnings []Warning `json:"warnings,omitempty"`
+	// Freshness of the product data the candidates are selected from
+	DataFreshness *DataFreshness `json:"dataFreshness,omitempty"`
+	// ID of the snapshot of the products the candidates are selected from, if snapshots are enabled
+	Snapshot string `json:"snapshot,omitempty"`
+}
+
+// AttrCandidates holds the candidate vms of the node pools recommended by an attribute
+type AttrCandidates struct {
+	// Candidates of the regular node pools, the cheapest per attribute unit first
+	OnDemand []Candidate `json:"onDemand"`
+	// Candidates of the spot/preemptible node pools, the cheapest per attribute unit first
+	Spot []Candidate `json:"spot"`
+}
+
+// Candidate is a vm taking part in the recommendation, with the price per attribute unit it's ranked by
+type Candidate struct {
+	VirtualMachine
+	// Price per attribute unit, for spot vms it's raised by the interruption heat
+	PricePerUnit float64 `json:"pricePerUnit"`
+}
+
+// Candidates lists the vms the node pools of the requested cluster would be selected from, per attribute
+func (e *Engine) Candidates(provider string, service string, region string, req SingleClusterRecommendationReq) (*CandidatesResp, error) {
+	if err := e.checkTenantProvider(provider); err != nil {
+		return nil, err
+	}
+	req.Excludes = e.tenantExcludes(req.Excludes)
+
+	if req.MinNodes > req.MaxNodes {
+		return nil, emperror.With(ErrMinGreaterThanMax, RecommenderErrorTag, "minNodes", req.MinNodes, "maxNodes", req.MaxNodes)
+	}
+	if err := validateAttributeNodes(req.ClusterRecommendationReq); err != nil {
+		return nil, err
+	}
+
+	if err := validatePerNodeBounds(req.ClusterRecommendationReq); err != nil {
+		return nil, err
+	}
+
+	if err := validateZoneWeights(req.ZoneWeights); err != nil {
+		return nil, err
+	}
+
+	budgeted, _ := e.withCallBudget()
+	allProducts, freshness, snapshot, warnings, err := budgeted.prepareProducts(provider, service, region, &req, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	resp := &CandidatesResp{
+		Provider:      provider,
+		Service:       service,
+		Region:        region,
+		Zone:          req.Zone,
+		Candidates:    make(map[string]AttrCandidates, 2),
+		Warnings:      warnings,
+		DataFreshness: freshness,
+		Snapshot:      snapshot,
+	}
+	for _, attr := range []string{Cpu, Memory} {
+		attrReq := req
+		attrReq.MinNodes, attrReq.MaxNodes = req.nodeBounds(attr)
+
+		vmsInRange, err := e.vmSelector.FindVmsWithAttrValues(attr, attrReq, nil, allProducts)
+		if err != nil {
+			return nil, emperror.With(err, RecommenderErrorTag, "vms")
+		}
+
+		// all the filtered vms are listed, the spot candidates are selected regardless of the requested on-demand percentage
+		attrReq.OnDemandPct = 100
+		vms, _, err := e.vmSelector.RecommendVms(provider, vmsInRange, attr, attrReq, nil)
+		if err != nil {
+			return nil, emperror.WrapWith(err, "failed to recommend virtual machines", RecommenderErrorTag)
+		}
+
+		resp.Candidates[attr] = AttrCandidates{
+			OnDemand: rankCandidates(vms, attr, req.OptimizeFor, false),
+			Spot:     rankCandidates(vms, attr, req.OptimizeFor, true),
+		}
+	}
+
+	return resp, nil
+}
+
+// rankCandidates orders the vms by their price per attribute unit, the spot candidates are the vms having a spot price
+func rankCandidates(vms []VirtualMachine, attr string, optimizeFor string, spot bool) []Candidate {
+	candidates := make([]Candidate, 0, len(vms))
+	for _, vm := range vms {
+		price := vm.OnDemandPrice
+		if spot {
+			if vm.AvgPrice == 0 {
+				continue
+			}
+			price = vm.RankingPrice()
+		}
+		candidates = append(candidates, Candidate{VirtualMachine: vm, PricePerUnit: vm.PricePerUnit(price, attr, optimizeFor)})
+	}
+
+	sort.SliceStable(candidates, func(i, j int) bool {
+		return candidates[i].PricePerUnit < candidates[j].PricePerUnit
+	})
+	return candidates
+}
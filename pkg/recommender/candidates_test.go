@@ -0,0 +1,93 @@
+// Copyright © 2019 Banzai Cloud
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package recommender
+
+import (
+	"testing"
+
+	"github.com/goph/logur"
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+)
+
+// candidateProducts serves the given products
+type candidateProducts struct {
+	dummyProducts
+	vms []VirtualMachine
+}
+
+func (p *candidateProducts) GetProductDetails(provider string, service string, region string) ([]VirtualMachine, error) {
+	return p.vms, nil
+}
+
+func (p *candidateProducts) GetProductDetailsFiltered(provider string, service string, region string, filter ProductFilter) ([]VirtualMachine, error) {
+	return filter.Apply(p.vms), nil
+}
+
+// passthroughVms selects all the products
+type passthroughVms struct {
+	dummyVms
+}
+
+func (v *passthroughVms) RecommendVms(provider string, vms []VirtualMachine, attr string, req SingleClusterRecommendationReq, layout []NodePool) ([]VirtualMachine, []VirtualMachine, error) {
+	return vms, vms, nil
+}
+
+func (v *passthroughVms) FindVmsWithAttrValues(attr string, req SingleClusterRecommendationReq, layoutDesc []NodePoolDesc, allProducts []VirtualMachine) ([]VirtualMachine, error) {
+	return allProducts, nil
+}
+
+func TestEngine_Candidates(t *testing.T) {
+	products := &candidateProducts{vms: []VirtualMachine{
+		{Type: "m5.xlarge", Cpus: 4, Mem: 16, OnDemandPrice: 0.192, AvgPrice: 0.07},
+		{Type: "c5.xlarge", Cpus: 4, Mem: 16, OnDemandPrice: 0.17},
+		{Type: "t3.medium", Cpus: 2, Mem: 4, OnDemandPrice: 0.0416, AvgPrice: 0.0125},
+		{Type: "r5.xlarge", Cpus: 4, Mem: 32, OnDemandPrice: 0.252, AvgPrice: 0.05},
+	}}
+	engine := NewEngine(logur.NewTestLogger(), products, &passthroughVms{}, &dummyNodePools{})
+	req := SingleClusterRecommendationReq{
+		ClusterRecommendationReq: ClusterRecommendationReq{SumCpu: 16, SumMem: 64, MinNodes: 1, MaxNodes: 4},
+	}
+
+	resp, err := engine.Candidates("amazon", "compute", "eu-west-1", req)
+	if !assert.Nil(t, err, "the error should be nil") {
+		return
+	}
+
+	cpu := resp.Candidates[Cpu]
+	assert.Equal(t, []string{"c5.xlarge", "m5.xlarge", "r5.xlarge"}, candidateTypes(cpu.OnDemand))
+	assert.Equal(t, []string{"r5.xlarge", "m5.xlarge"}, candidateTypes(cpu.Spot), "vms without spot price should not be spot candidates")
+	assert.InDelta(t, 0.0425, cpu.OnDemand[0].PricePerUnit, 1e-9)
+
+	mem := resp.Candidates[Memory]
+	assert.Equal(t, []string{"r5.xlarge", "c5.xlarge", "m5.xlarge"}, candidateTypes(mem.OnDemand), "vms smaller than the per node minimum should be filtered out")
+	assert.InDelta(t, 0.0015625, mem.Spot[0].PricePerUnit, 1e-9)
+
+	req.MinNodes = 5
+	_, err = engine.Candidates("amazon", "compute", "eu-west-1", req)
+	assert.Equal(t, ErrMinGreaterThanMax, errors.Cause(err))
+
+	tenantEngine := engine.forTenant(Tenant{Name: "team-a", Providers: []string{"google"}}, products)
+	_, err = tenantEngine.Candidates("amazon", "compute", "eu-west-1", req)
+	assert.Equal(t, ErrProviderNotEnabled, errors.Cause(err))
+}
+
+func candidateTypes(candidates []Candidate) []string {
+	types := make([]string, 0, len(candidates))
+	for _, c := range candidates {
+		types = append(types, c.Type)
+	}
+	return types
+}
@@ -0,0 +1,52 @@
+// Copyright © 2019 Banzai Cloud
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package recommender
+
+import "strings"
+
+// xenFamilies holds the EC2 instance families running on the Xen hypervisor, the rest of the families are built on the Nitro system
+// The cloud info service doesn't expose the hypervisor, the networking capabilities are implied by the instance family
+var xenFamilies = map[string]bool{
+	"c1": true, "c3": true, "c4": true, "cc1": true, "cc2": true, "cg1": true, "cr1": true,
+	"d2": true, "f1": true, "g2": true, "g3": true, "g3s": true, "h1": true, "hi1": true, "hs1": true,
+	"i2": true, "i3": true, "m1": true, "m2": true, "m3": true, "m4": true, "p2": true, "p3": true,
+	"r3": true, "r4": true, "t1": true, "t2": true, "x1": true, "x1e": true,
+}
+
+// xenEnaTypes holds the Xen instance families (or types) supporting the Elastic Network Adapter, Nitro instances support it all
+var xenEnaTypes = map[string]bool{
+	"f1": true, "g3": true, "g3s": true, "h1": true, "i3": true, "m4.16xlarge": true,
+	"p2": true, "p3": true, "r4": true, "x1": true, "x1e": true,
+}
+
+// noIpv6Families holds the EC2 instance families that can't have IPv6 addresses
+var noIpv6Families = map[string]bool{
+	"c1": true, "cc1": true, "cc2": true, "cg1": true, "cr1": true, "g2": true,
+	"hi1": true, "hs1": true, "m1": true, "m2": true, "m3": true, "t1": true,
+}
+
+// applyInstanceCapabilities sets the hypervisor and networking capabilities of the given vms (amazon only)
+func applyInstanceCapabilities(provider string, vms []VirtualMachine) {
+	if provider != "amazon" {
+		return
+	}
+	for i := range vms {
+		family := instanceFamily(provider, vms[i].Type)
+		// the bare metal types of the Xen families are built on the Nitro system too
+		vms[i].Nitro = !xenFamilies[family] || strings.HasSuffix(vms[i].Type, ".metal")
+		vms[i].Ena = vms[i].Nitro || xenEnaTypes[family] || xenEnaTypes[vms[i].Type]
+		vms[i].Ipv6 = !noIpv6Families[family]
+	}
+}
@@ -0,0 +1,42 @@
+// Copyright © 2019 Banzai Cloud
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package recommender
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_applyInstanceCapabilities(t *testing.T) {
+	vms := []VirtualMachine{
+		{Type: "m5.xlarge"},
+		{Type: "m4.large"},
+		{Type: "m4.16xlarge"},
+		{Type: "i3.metal"},
+		{Type: "m3.medium"},
+	}
+	applyInstanceCapabilities("amazon", vms)
+
+	assert.Equal(t, VirtualMachine{Type: "m5.xlarge", Nitro: true, Ena: true, Ipv6: true}, vms[0])
+	assert.Equal(t, VirtualMachine{Type: "m4.large", Ipv6: true}, vms[1])
+	assert.Equal(t, VirtualMachine{Type: "m4.16xlarge", Ena: true, Ipv6: true}, vms[2])
+	assert.Equal(t, VirtualMachine{Type: "i3.metal", Nitro: true, Ena: true, Ipv6: true}, vms[3], "bare metal types should be nitro")
+	assert.Equal(t, VirtualMachine{Type: "m3.medium"}, vms[4])
+
+	others := []VirtualMachine{{Type: "n1-standard-4"}}
+	applyInstanceCapabilities("google", others)
+	assert.Equal(t, VirtualMachine{Type: "n1-standard-4"}, others[0], "capabilities are only known for amazon")
+}
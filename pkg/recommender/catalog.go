@@ -0,0 +1,454 @@
+// Copyright © 2019 Banzai Cloud
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package recommender
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"math"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/goph/logur"
+)
+
+// kinds of the prices compared in a catalog change
+const (
+	OnDemandPriceChange = "onDemand"
+	SpotPriceChange     = "spot"
+)
+
+// CatalogChange summarizes the change of the products and prices of a region between two snapshots
+type CatalogChange struct {
+	Provider string
+	Service  string
+	Region   string
+	// Checksum of the new snapshot
+	Checksum string
+	// Checksum of the previous snapshot
+	PreviousChecksum string
+	// DetectedAt is the time the change was detected
+	DetectedAt time.Time
+	// Instance types added to the catalog
+	Added []string
+	// Instance types removed from the catalog
+	Removed []string
+	// Prices changed more than the threshold
+	PriceChanges []PriceChange
+}
+
+// PriceChange is the change of the on-demand or the average spot price of an instance type
+type PriceChange struct {
+	Type string
+	// Kind of the price: onDemand or spot
+	Kind     string
+	Previous float64
+	Current  float64
+	// Change relative to the previous price, in percent
+	ChangePct float64
+}
+
+// catalogSnapshot is the checksum and the prices of the products of a region; the snapshots of the regions are
+// kept between the renewals, so only the prices are held, ordered by instance type, and indexed by instance type
+type catalogSnapshot struct {
+	checksum string
+	prices   []snapshotPrice
+	index    map[string]int
+}
+
+// snapshotPrice is the on-demand and the average spot price of an instance type in a snapshot
+type snapshotPrice struct {
+	vmType   string
+	onDemand float64
+	spot     float64
+}
+
+// price gets the prices of the instance type in the snapshot
+func (s catalogSnapshot) price(vmType string) (snapshotPrice, bool) {
+	i, ok := s.index[vmType]
+	if !ok {
+		return snapshotPrice{}, false
+	}
+	return s.prices[i], true
+}
+
+// CatalogWatcher is a CloudInfoSource detecting the changes of the product catalogs of the regions retrieved;
+// the listeners are notified when the products or the prices of a region change
+// Filtered product details are partial snapshots, only the regions are remembered from them: the full snapshots are
+// retrieved by Run periodically
+type CatalogWatcher struct {
+	CloudInfoSource
+
+	thresholdPct float64
+	intervals    map[string]time.Duration
+	idleTimeout  time.Duration
+	log          logur.Logger
+
+	mux       sync.Mutex
+	snapshots map[string]catalogSnapshot
+	regions   map[string][3]string
+	lastUsed  map[string]time.Time
+	attempts  map[string]time.Time
+	renewals  map[string]*RenewalStatus
+	listeners []func(CatalogChange)
+}
+
+// CatalogWatcherOption configures optional features of the CatalogWatcher
+type CatalogWatcherOption func(w *CatalogWatcher)
+
+// WithProviderInterval overrides the interval the catalogs of the provider's regions are retrieved in
+func WithProviderInterval(provider string, interval time.Duration) CatalogWatcherOption {
+	return func(w *CatalogWatcher) {
+		w.intervals[provider] = interval
+	}
+}
+
+// WithIdleTimeout makes the watcher skip the regions without recommendations for the given time, so the idle
+// regions don't cost cloud info calls; the regions are renewed again once they are used
+func WithIdleTimeout(timeout time.Duration) CatalogWatcherOption {
+	return func(w *CatalogWatcher) {
+		w.idleTimeout = timeout
+	}
+}
+
+// NewCatalogWatcher creates a catalog watcher over the source; price changes below the threshold percentage are
+// left out of the change summaries, but they change the checksum
+func NewCatalogWatcher(source CloudInfoSource, thresholdPct float64, log logur.Logger, opts ...CatalogWatcherOption) *CatalogWatcher {
+	w := &CatalogWatcher{
+		CloudInfoSource: source,
+		thresholdPct:    thresholdPct,
+		intervals:       make(map[string]time.Duration),
+		log:             log,
+		snapshots:       make(map[string]catalogSnapshot),
+		regions:         make(map[string][3]string),
+		lastUsed:        make(map[string]time.Time),
+		attempts:        make(map[string]time.Time),
+		renewals:        make(map[string]*RenewalStatus),
+	}
+	for _, opt := range opts {
+		opt(w)
+	}
+	return w
+}
+
+// OnChange registers a listener notified of the catalog changes
+func (w *CatalogWatcher) OnChange(listener func(CatalogChange)) {
+	w.mux.Lock()
+	defer w.mux.Unlock()
+
+	w.listeners = append(w.listeners, listener)
+}
+
+func (w *CatalogWatcher) GetProductDetails(provider string, service string, region string) ([]VirtualMachine, error) {
+	w.use(provider, service, region)
+	return w.getProductDetails(provider, service, region)
+}
+
+func (w *CatalogWatcher) GetProductDetailsFiltered(provider string, service string, region string, filter ProductFilter) ([]VirtualMachine, error) {
+	w.use(provider, service, region)
+	return w.CloudInfoSource.GetProductDetailsFiltered(provider, service, region, filter)
+}
+
+// GetDataFreshness gets the freshness of the product data of the region, its catalog version is the checksum of
+// the last full snapshot of the region, omitted until the region is retrieved in full
+func (w *CatalogWatcher) GetDataFreshness(provider string, service string, region string) (DataFreshness, bool) {
+	df, ok := w.CloudInfoSource.GetDataFreshness(provider, service, region)
+	if !ok {
+		return df, false
+	}
+
+	w.mux.Lock()
+	defer w.mux.Unlock()
+	if snapshot, seen := w.snapshots[freshnessKey(provider, service, region)]; seen {
+		df.CatalogVersion = snapshot.checksum
+	}
+	return df, true
+}
+
+// use records that the products of the region are used by a recommendation
+func (w *CatalogWatcher) use(provider, service, region string) {
+	w.mux.Lock()
+	defer w.mux.Unlock()
+
+	key := freshnessKey(provider, service, region)
+	w.regions[key] = [3]string{provider, service, region}
+	w.lastUsed[key] = time.Now()
+}
+
+// getProductDetails retrieves the full product catalog of the region and compares it with the previous one
+func (w *CatalogWatcher) getProductDetails(provider string, service string, region string) ([]VirtualMachine, error) {
+	vms, err := w.CloudInfoSource.GetProductDetails(provider, service, region)
+	if err != nil {
+		return nil, err
+	}
+	w.observe(provider, service, region, vms)
+	return vms, nil
+}
+
+// RenewalStatus describes the last periodic retrievals of the product catalog of a region
+type RenewalStatus struct {
+	Provider string `json:"provider"`
+	Service  string `json:"service"`
+	Region   string `json:"region"`
+	// Time of the last successful retrieval
+	LastSuccess *time.Time `json:"lastSuccess,omitempty"`
+	// Duration of the last successful retrieval, in seconds
+	DurationSeconds float64 `json:"durationSeconds"`
+	// Number of products retrieved the last time
+	Products int `json:"products"`
+	// Error of the last failed retrieval
+	LastError string `json:"lastError,omitempty"`
+	// Time of the last failed retrieval
+	LastErrorAt *time.Time `json:"lastErrorAt,omitempty"`
+}
+
+// Run retrieves the full product catalogs of the regions seen so far periodically, until the stop channel is closed;
+// the providers are renewed in parallel, the regions of a provider one by one
+// The interval applies to the providers without an interval of their own
+func (w *CatalogWatcher) Run(interval time.Duration, stop <-chan struct{}) {
+	tick := interval
+	for _, i := range w.intervals {
+		if i < tick {
+			tick = i
+		}
+	}
+	ticker := time.NewTicker(tick)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-stop:
+			return
+		case now := <-ticker.C:
+			var wg sync.WaitGroup
+			for provider, regions := range w.dueRegions(now, interval, tick) {
+				wg.Add(1)
+				go func(provider string, regions [][3]string) {
+					defer wg.Done()
+					w.renewProvider(provider, regions)
+				}(provider, regions)
+			}
+			wg.Wait()
+		}
+	}
+}
+
+// dueRegions collects the regions due to renewal by provider, the regions idle for too long are skipped
+// A region is due if its interval passed since its last attempt, less half a tick: the ticks may come slightly early,
+// and a region skipped by a tick would wait for a full tick more
+func (w *CatalogWatcher) dueRegions(now time.Time, interval, tick time.Duration) map[string][][3]string {
+	w.mux.Lock()
+	defer w.mux.Unlock()
+
+	byProvider := make(map[string][][3]string)
+	for key, r := range w.regions {
+		if w.idleTimeout > 0 && now.Sub(w.lastUsed[key]) > w.idleTimeout {
+			w.log.Debug("skipping the renewal of the idle region", map[string]interface{}{"provider": r[0], "service": r[1], "region": r[2]})
+			continue
+		}
+		providerInterval := interval
+		if i, ok := w.intervals[r[0]]; ok {
+			providerInterval = i
+		}
+		if last, ok := w.attempts[key]; ok && now.Sub(last) < providerInterval-tick/2 {
+			continue
+		}
+		w.attempts[key] = now
+		byProvider[r[0]] = append(byProvider[r[0]], r)
+	}
+	return byProvider
+}
+
+// renewProvider renews the regions of the provider one by one, and logs the summary of the renewal
+func (w *CatalogWatcher) renewProvider(provider string, regions [][3]string) {
+	start := time.Now()
+	var items, failed int
+	for _, r := range regions {
+		count, err := w.renew(r[0], r[1], r[2])
+		if err != nil {
+			failed++
+			continue
+		}
+		items += count
+	}
+
+	fields := map[string]interface{}{
+		"provider": provider, "regions": len(regions), "failedRegions": failed, "itemCount": items,
+		"duration": time.Since(start).Seconds()}
+	if failed > 0 {
+		w.log.Warn("product catalog renewal finished with failures", fields)
+		return
+	}
+	w.log.Info("product catalog renewal finished", fields)
+}
+
+// renew retrieves the full product catalog of the region and records the status of the retrieval,
+// the number of the products retrieved is returned
+func (w *CatalogWatcher) renew(provider, service, region string) (int, error) {
+	start := time.Now()
+	vms, err := w.getProductDetails(provider, service, region)
+	end := time.Now()
+	duration := end.Sub(start).Seconds()
+
+	w.mux.Lock()
+	defer w.mux.Unlock()
+
+	key := freshnessKey(provider, service, region)
+	status, ok := w.renewals[key]
+	if !ok {
+		status = &RenewalStatus{Provider: provider, Service: service, Region: region}
+		w.renewals[key] = status
+	}
+
+	if err != nil {
+		status.LastError = err.Error()
+		status.LastErrorAt = &end
+		w.log.Warn("failed to retrieve the product catalog", map[string]interface{}{
+			"provider": provider, "service": service, "region": region, "duration": duration, "err": err.Error()})
+		return 0, err
+	}
+	status.LastSuccess = &end
+	status.DurationSeconds = duration
+	status.Products = len(vms)
+	w.log.Debug("product catalog renewed", map[string]interface{}{
+		"provider": provider, "service": service, "region": region, "duration": duration, "itemCount": len(vms)})
+	return len(vms), nil
+}
+
+// RenewalStatus gets the status of the periodic retrievals of the regions renewed so far, ordered by key
+func (w *CatalogWatcher) RenewalStatus() []RenewalStatus {
+	w.mux.Lock()
+	defer w.mux.Unlock()
+
+	keys := make([]string, 0, len(w.renewals))
+	for k := range w.renewals {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	statuses := make([]RenewalStatus, 0, len(keys))
+	for _, k := range keys {
+		statuses = append(statuses, *w.renewals[k])
+	}
+	return statuses
+}
+
+// observe compares the snapshot with the previous one of the region, the listeners are notified if it changed
+func (w *CatalogWatcher) observe(provider, service, region string, vms []VirtualMachine) {
+	key := freshnessKey(provider, service, region)
+	snapshot := newCatalogSnapshot(vms)
+
+	w.mux.Lock()
+	previous, seen := w.snapshots[key]
+	w.snapshots[key] = snapshot
+	w.regions[key] = [3]string{provider, service, region}
+	listeners := w.listeners
+	w.mux.Unlock()
+
+	if !seen || previous.checksum == snapshot.checksum {
+		return
+	}
+
+	change := diffCatalogs(previous, snapshot, w.thresholdPct)
+	change.Provider, change.Service, change.Region = provider, service, region
+	change.DetectedAt = time.Now()
+
+	w.log.Info("product catalog changed", map[string]interface{}{
+		"provider": provider, "service": service, "region": region, "checksum": change.Checksum,
+		"added": len(change.Added), "removed": len(change.Removed), "priceChanges": len(change.PriceChanges)})
+	for _, listener := range listeners {
+		listener(change)
+	}
+}
+
+// newCatalogSnapshot computes the checksum of the products from the attributes the recommendations depend on,
+// the last product of an instance type wins
+func newCatalogSnapshot(vms []VirtualMachine) catalogSnapshot {
+	latest := make(map[string]*VirtualMachine, len(vms))
+	types := make([]string, 0, len(vms))
+	for i := range vms {
+		if _, ok := latest[vms[i].Type]; !ok {
+			types = append(types, vms[i].Type)
+		}
+		latest[vms[i].Type] = &vms[i]
+	}
+	sort.Strings(types)
+
+	snapshot := catalogSnapshot{
+		prices: make([]snapshotPrice, 0, len(types)),
+		index:  make(map[string]int, len(types)),
+	}
+	h := sha256.New()
+	for _, t := range types {
+		vm := latest[t]
+		snapshot.index[t] = len(snapshot.prices)
+		snapshot.prices = append(snapshot.prices, snapshotPrice{vmType: t, onDemand: vm.OnDemandPrice, spot: vm.AvgPrice})
+		fmt.Fprintf(h, "%s|%g|%g|%g|%g|%g|%v\n", vm.Type, vm.OnDemandPrice, vm.AvgPrice, vm.Cpus, vm.Mem, vm.Gpus, vm.Zones)
+	}
+	snapshot.checksum = hex.EncodeToString(h.Sum(nil))
+	return snapshot
+}
+
+// diffCatalogs summarizes the differences of two snapshots
+func diffCatalogs(previous, current catalogSnapshot, thresholdPct float64) CatalogChange {
+	change := CatalogChange{Checksum: current.checksum, PreviousChecksum: previous.checksum}
+
+	for _, cur := range current.prices {
+		prev, ok := previous.price(cur.vmType)
+		if !ok {
+			change.Added = append(change.Added, cur.vmType)
+			continue
+		}
+		if pc, ok := priceChange(cur.vmType, OnDemandPriceChange, prev.onDemand, cur.onDemand, thresholdPct); ok {
+			change.PriceChanges = append(change.PriceChanges, pc)
+		}
+		if pc, ok := priceChange(cur.vmType, SpotPriceChange, prev.spot, cur.spot, thresholdPct); ok {
+			change.PriceChanges = append(change.PriceChanges, pc)
+		}
+	}
+	for _, prev := range previous.prices {
+		if _, ok := current.price(prev.vmType); !ok {
+			change.Removed = append(change.Removed, prev.vmType)
+		}
+	}
+
+	sort.Strings(change.Added)
+	sort.Strings(change.Removed)
+	sort.Slice(change.PriceChanges, func(i, j int) bool {
+		if change.PriceChanges[i].Type != change.PriceChanges[j].Type {
+			return change.PriceChanges[i].Type < change.PriceChanges[j].Type
+		}
+		return change.PriceChanges[i].Kind < change.PriceChanges[j].Kind
+	})
+	return change
+}
+
+// priceChange checks whether the price changed at least by the threshold percentage; prices appearing or
+// disappearing (eg. spot prices) are changes of 100%
+func priceChange(vmType, kind string, previous, current, thresholdPct float64) (PriceChange, bool) {
+	if previous == current {
+		return PriceChange{}, false
+	}
+	changePct := 100.0
+	if previous != 0 {
+		changePct = (current - previous) / previous * 100
+	}
+	if math.Abs(changePct) < thresholdPct {
+		return PriceChange{}, false
+	}
+	return PriceChange{Type: vmType, Kind: kind, Previous: previous, Current: current, ChangePct: changePct}, true
+}
@@ -0,0 +1,194 @@
+// Copyright © 2019 Banzai Cloud
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package recommender
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/goph/logur"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCatalogWatcher(t *testing.T) {
+	products := &candidateProducts{vms: []VirtualMachine{
+		{Type: "m5.xlarge", Cpus: 4, Mem: 16, OnDemandPrice: 0.192, AvgPrice: 0.07},
+		{Type: "c5.xlarge", Cpus: 4, Mem: 8, OnDemandPrice: 0.17, AvgPrice: 0.06},
+		{Type: "m4.xlarge", Cpus: 4, Mem: 16, OnDemandPrice: 0.2, AvgPrice: 0.08},
+	}}
+	watcher := NewCatalogWatcher(products, 10, logur.NewTestLogger())
+	var changes []CatalogChange
+	watcher.OnChange(func(change CatalogChange) {
+		changes = append(changes, change)
+	})
+
+	_, err := watcher.GetProductDetails("amazon", "compute", "eu-west-1")
+	if !assert.NoError(t, err) {
+		return
+	}
+	_, _ = watcher.GetProductDetails("amazon", "compute", "eu-west-1")
+	assert.Empty(t, changes, "the first snapshot and unchanged snapshots should not be reported")
+
+	products.vms = []VirtualMachine{
+		{Type: "m5.xlarge", Cpus: 4, Mem: 16, OnDemandPrice: 0.192, AvgPrice: 0.09},
+		{Type: "c5.xlarge", Cpus: 4, Mem: 8, OnDemandPrice: 0.17, AvgPrice: 0.062},
+		{Type: "m6i.xlarge", Cpus: 4, Mem: 16, OnDemandPrice: 0.192},
+	}
+	_, _ = watcher.GetProductDetails("amazon", "compute", "eu-west-1")
+	if !assert.Len(t, changes, 1) {
+		return
+	}
+	change := changes[0]
+	assert.Equal(t, "eu-west-1", change.Region)
+	assert.NotEqual(t, change.PreviousChecksum, change.Checksum)
+	assert.Equal(t, []string{"m6i.xlarge"}, change.Added)
+	assert.Equal(t, []string{"m4.xlarge"}, change.Removed)
+	if assert.Len(t, change.PriceChanges, 1, "changes below the threshold should be left out") {
+		assert.Equal(t, "m5.xlarge", change.PriceChanges[0].Type)
+		assert.Equal(t, SpotPriceChange, change.PriceChanges[0].Kind)
+		assert.InDelta(t, 28.57, change.PriceChanges[0].ChangePct, 0.01)
+	}
+}
+
+// failingProducts serves the given products until it's made failing
+type failingProducts struct {
+	candidateProducts
+	err error
+}
+
+func (p *failingProducts) GetProductDetails(provider string, service string, region string) ([]VirtualMachine, error) {
+	if p.err != nil {
+		return nil, p.err
+	}
+	return p.vms, nil
+}
+
+func TestCatalogWatcher_renew(t *testing.T) {
+	products := &failingProducts{candidateProducts: candidateProducts{vms: []VirtualMachine{
+		{Type: "m5.xlarge", Cpus: 4, Mem: 16, OnDemandPrice: 0.192},
+		{Type: "c5.xlarge", Cpus: 4, Mem: 8, OnDemandPrice: 0.17},
+	}}}
+	watcher := NewCatalogWatcher(products, 10, logur.NewTestLogger())
+
+	watcher.renew("amazon", "compute", "eu-west-1")
+	products.err = errors.New("cloud info unavailable")
+	watcher.renew("amazon", "compute", "eu-west-1")
+	watcher.renew("amazon", "compute", "eu-central-1")
+
+	statuses := watcher.RenewalStatus()
+	if !assert.Len(t, statuses, 2) {
+		return
+	}
+	assert.Equal(t, "eu-central-1", statuses[0].Region, "the statuses should be ordered by key")
+	assert.Nil(t, statuses[0].LastSuccess)
+	assert.Equal(t, "cloud info unavailable", statuses[0].LastError)
+
+	assert.NotNil(t, statuses[1].LastSuccess, "the last success should be kept after a failure")
+	assert.Equal(t, 2, statuses[1].Products)
+	assert.NotNil(t, statuses[1].LastErrorAt)
+}
+
+func TestCatalogWatcher_renewProvider(t *testing.T) {
+	products := &failingProducts{candidateProducts: candidateProducts{vms: []VirtualMachine{
+		{Type: "m5.xlarge", Cpus: 4, Mem: 16, OnDemandPrice: 0.192},
+		{Type: "c5.xlarge", Cpus: 4, Mem: 8, OnDemandPrice: 0.17},
+	}}}
+	logger := logur.NewTestLogger()
+	watcher := NewCatalogWatcher(products, 10, logger)
+	regions := [][3]string{{"amazon", "compute", "eu-west-1"}, {"amazon", "compute", "eu-central-1"}}
+
+	watcher.renewProvider("amazon", regions)
+	summary := logger.LastEvent()
+	if !assert.NotNil(t, summary) {
+		return
+	}
+	assert.Equal(t, logur.Info, summary.Level)
+	assert.Equal(t, "product catalog renewal finished", summary.Line)
+	assert.Equal(t, "amazon", summary.Fields["provider"])
+	assert.Equal(t, 2, summary.Fields["regions"])
+	assert.Equal(t, 0, summary.Fields["failedRegions"])
+	assert.Equal(t, 4, summary.Fields["itemCount"])
+	assert.Contains(t, summary.Fields, "duration")
+
+	products.err = errors.New("cloud info unavailable")
+	watcher.renewProvider("amazon", regions)
+	summary = logger.LastEvent()
+	assert.Equal(t, logur.Warn, summary.Level)
+	assert.Equal(t, 2, summary.Fields["failedRegions"])
+	assert.Equal(t, 0, summary.Fields["itemCount"])
+}
+
+func TestCatalogWatcher_dueRegions(t *testing.T) {
+	products := &candidateProducts{vms: []VirtualMachine{{Type: "m5.xlarge", Cpus: 4, Mem: 16, OnDemandPrice: 0.192}}}
+	watcher := NewCatalogWatcher(products, 10, logur.NewTestLogger(),
+		WithProviderInterval("amazon", time.Minute), WithIdleTimeout(time.Hour))
+
+	_, _ = watcher.GetProductDetails("amazon", "compute", "eu-west-1")
+	_, _ = watcher.GetProductDetails("google", "compute", "europe-west1")
+	now := time.Now()
+
+	due := watcher.dueRegions(now.Add(time.Minute), 10*time.Minute, time.Minute)
+	assert.Len(t, due["amazon"], 1)
+	assert.Len(t, due["google"], 1)
+
+	due = watcher.dueRegions(now.Add(2*time.Minute-time.Millisecond), 10*time.Minute, time.Minute)
+	assert.Len(t, due["amazon"], 1, "the provider interval should apply to amazon, a slightly early tick should not skip it")
+	assert.Empty(t, due["google"], "the default interval should apply to google")
+
+	due = watcher.dueRegions(now.Add(2*time.Minute+20*time.Second), 10*time.Minute, time.Minute)
+	assert.Empty(t, due, "the regions should not be renewed again within half a tick of their interval")
+
+	watcher.renew("amazon", "compute", "eu-west-1")
+	due = watcher.dueRegions(now.Add(2*time.Hour), 10*time.Minute, time.Minute)
+	assert.Empty(t, due, "the renewals should not count as usage, so idle regions should be skipped")
+}
+
+func TestPriceChange(t *testing.T) {
+	_, changed := priceChange("m5.xlarge", OnDemandPriceChange, 0.1, 0.105, 10)
+	assert.False(t, changed)
+
+	pc, changed := priceChange("m5.xlarge", OnDemandPriceChange, 0.1, 0.08, 10)
+	assert.True(t, changed)
+	assert.InDelta(t, -20, pc.ChangePct, 0.0001)
+
+	pc, changed = priceChange("m5.xlarge", SpotPriceChange, 0, 0.05, 10)
+	assert.True(t, changed, "prices appearing should be reported")
+	assert.Equal(t, 100.0, pc.ChangePct)
+}
+
+func TestNewCatalogSnapshot(t *testing.T) {
+	snapshot := newCatalogSnapshot([]VirtualMachine{
+		{Type: "m5.xlarge", Cpus: 4, Mem: 16, OnDemandPrice: 0.192, Zones: []string{"eu-west-1a"}},
+		{Type: "c5.xlarge", Cpus: 4, Mem: 8, OnDemandPrice: 0.17},
+		{Type: "m5.xlarge", Cpus: 4, Mem: 16, OnDemandPrice: 0.2, AvgPrice: 0.06},
+	})
+
+	if !assert.Len(t, snapshot.prices, 2, "the duplicate instance types should be kept once") {
+		return
+	}
+	assert.Equal(t, "c5.xlarge", snapshot.prices[0].vmType, "the prices should be ordered by instance type")
+	price, ok := snapshot.price("m5.xlarge")
+	assert.True(t, ok)
+	assert.Equal(t, snapshotPrice{vmType: "m5.xlarge", onDemand: 0.2, spot: 0.06}, price, "the last product should win")
+	_, ok = snapshot.price("r5.xlarge")
+	assert.False(t, ok)
+
+	reordered := newCatalogSnapshot([]VirtualMachine{
+		{Type: "c5.xlarge", Cpus: 4, Mem: 8, OnDemandPrice: 0.17},
+		{Type: "m5.xlarge", Cpus: 4, Mem: 16, OnDemandPrice: 0.2, AvgPrice: 0.06},
+	})
+	assert.Equal(t, snapshot.checksum, reordered.checksum, "the checksum should not depend on the order of the products")
+}
@@ -0,0 +1,190 @@
+// Copyright © 2019 Banzai Cloud
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package recommender
+
+import "sort"
+
+// pool changes in a layout comparison
+const (
+	PoolAdded     = "added"
+	PoolRemoved   = "removed"
+	PoolResized   = "resized"
+	PoolUnchanged = "unchanged"
+)
+
+// LayoutComparisonReq encapsulates the layouts to compare
+// swagger:model compareLayoutsRequest
+type LayoutComparisonReq struct {
+	// The cloud provider of the layouts, selects the billing model of the monthly cost estimation
+	Provider string `json:"provider,omitempty"`
+	// Base layout, eg. the node pools of the current cluster
+	Base []NodePool `json:"base"`
+	// Target layout, eg. the node pools of a new recommendation
+	Target []NodePool `json:"target" binding:"required"`
+}
+
+// LayoutComparison is the structured diff of two layouts
+type LayoutComparison struct {
+	// Changes of the node pools, pools are identified by their vm type, vm class and role
+	NodePools []NodePoolChange `json:"nodePools"`
+	// Change of the hourly price
+	HourlyPrice Delta `json:"hourlyPrice"`
+	// Change of the estimated monthly bill
+	MonthlyPrice Delta `json:"monthlyPrice"`
+	// Change of the number of cpus
+	Cpu Delta `json:"cpu"`
+	// Change of the memory (GB)
+	Memory Delta `json:"memory"`
+	// Change of the number of gpus
+	Gpu Delta `json:"gpu"`
+	// Change of the number of nodes
+	Nodes Delta `json:"nodes"`
+	// Change of the percentage of spot nodes, in percentage points
+	SpotSharePct Delta `json:"spotSharePct"`
+}
+
+// NodePoolChange describes the change of a node pool between the base and the target layouts
+type NodePoolChange struct {
+	// Change of the pool: added, removed, resized or unchanged
+	Change string `json:"change"`
+	// Instance type of the pool
+	VmType string `json:"vmType"`
+	// Signals that the pool consists of regular, reserved or spot/preemptible instances
+	VmClass string `json:"vmClass"`
+	// Role in the cluster, eg. master or worker
+	Role string `json:"role"`
+	// Number of nodes in the base layout
+	BaseNodes int `json:"baseNodes"`
+	// Number of nodes in the target layout
+	TargetNodes int `json:"targetNodes"`
+}
+
+// Delta holds a value in the base and the target layouts, and their difference
+type Delta struct {
+	Base   float64 `json:"base"`
+	Target float64 `json:"target"`
+	Delta  float64 `json:"delta"`
+}
+
+func newDelta(base, target float64) Delta {
+	return Delta{Base: base, Target: target, Delta: target - base}
+}
+
+// poolKey identifies a node pool across layouts
+type poolKey struct {
+	vmType  string
+	vmClass string
+	role    string
+}
+
+// CompareLayouts computes the differences of the target layout relative to the base layout
+func CompareLayouts(req LayoutComparisonReq) LayoutComparison {
+	base, target := layoutSummaryOf(req.Provider, req.Base), layoutSummaryOf(req.Provider, req.Target)
+
+	return LayoutComparison{
+		NodePools:    nodePoolChanges(base.nodes, target.nodes),
+		HourlyPrice:  newDelta(base.hourlyPrice, target.hourlyPrice),
+		MonthlyPrice: newDelta(base.monthlyPrice, target.monthlyPrice),
+		Cpu:          newDelta(base.cpu, target.cpu),
+		Memory:       newDelta(base.mem, target.mem),
+		Gpu:          newDelta(base.gpu, target.gpu),
+		Nodes:        newDelta(float64(base.sumNodes), float64(target.sumNodes)),
+		SpotSharePct: newDelta(base.spotSharePct(), target.spotSharePct()),
+	}
+}
+
+// layoutSummary holds the totals of a layout
+type layoutSummary struct {
+	nodes        map[poolKey]int
+	hourlyPrice  float64
+	monthlyPrice float64
+	cpu, mem     float64
+	gpu          float64
+	sumNodes     int
+	spotNodes    int
+}
+
+func layoutSummaryOf(provider string, nodePools []NodePool) layoutSummary {
+	summary := layoutSummary{
+		nodes:        make(map[poolKey]int, len(nodePools)),
+		monthlyPrice: monthlyCost(provider, nodePools),
+	}
+	for _, np := range nodePools {
+		summary.nodes[poolKey{vmType: np.VmType.Type, vmClass: np.VmClass, role: np.Role}] += np.SumNodes
+		summary.hourlyPrice += np.PoolPrice()
+		summary.cpu += np.GetSum(Cpu)
+		summary.mem += np.GetSum(Memory)
+		summary.gpu += float64(np.SumNodes) * np.VmType.Gpus
+		summary.sumNodes += np.SumNodes
+		if np.VmClass == Spot {
+			summary.spotNodes += np.SumNodes
+		}
+	}
+	return summary
+}
+
+func (s layoutSummary) spotSharePct() float64 {
+	if s.sumNodes == 0 {
+		return 0
+	}
+	return float64(s.spotNodes) / float64(s.sumNodes) * 100
+}
+
+// nodePoolChanges pairs the pools of the layouts, ordered by role, vm class and vm type
+func nodePoolChanges(base, target map[poolKey]int) []NodePoolChange {
+	keys := make([]poolKey, 0, len(base)+len(target))
+	for key := range base {
+		keys = append(keys, key)
+	}
+	for key := range target {
+		if _, ok := base[key]; !ok {
+			keys = append(keys, key)
+		}
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		if keys[i].role != keys[j].role {
+			return keys[i].role < keys[j].role
+		}
+		if keys[i].vmClass != keys[j].vmClass {
+			return keys[i].vmClass < keys[j].vmClass
+		}
+		return keys[i].vmType < keys[j].vmType
+	})
+
+	changes := make([]NodePoolChange, 0, len(keys))
+	for _, key := range keys {
+		baseNodes, targetNodes := base[key], target[key]
+
+		change := PoolResized
+		switch {
+		case baseNodes == targetNodes:
+			change = PoolUnchanged
+		case baseNodes == 0:
+			change = PoolAdded
+		case targetNodes == 0:
+			change = PoolRemoved
+		}
+
+		changes = append(changes, NodePoolChange{
+			Change:      change,
+			VmType:      key.vmType,
+			VmClass:     key.vmClass,
+			Role:        key.role,
+			BaseNodes:   baseNodes,
+			TargetNodes: targetNodes,
+		})
+	}
+	return changes
+}
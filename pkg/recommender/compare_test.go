@@ -0,0 +1,66 @@
+// Copyright © 2019 Banzai Cloud
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package recommender
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCompareLayouts(t *testing.T) {
+	m5 := VirtualMachine{Type: "m5.xlarge", Cpus: 4, Mem: 16, OnDemandPrice: 0.2, AvgPrice: 0.08}
+	c5 := VirtualMachine{Type: "c5.xlarge", Cpus: 4, Mem: 8, OnDemandPrice: 0.17, AvgPrice: 0.05}
+	r5 := VirtualMachine{Type: "r5.xlarge", Cpus: 4, Mem: 32, OnDemandPrice: 0.25, AvgPrice: 0.1}
+
+	comparison := CompareLayouts(LayoutComparisonReq{
+		Provider: "amazon",
+		Base: []NodePool{
+			{VmType: m5, SumNodes: 2, VmClass: Regular, Role: Worker},
+			{VmType: c5, SumNodes: 2, VmClass: Spot, Role: Worker},
+			{VmType: r5, SumNodes: 1, VmClass: Spot, Role: Worker},
+		},
+		Target: []NodePool{
+			{VmType: m5, SumNodes: 2, VmClass: Regular, Role: Worker},
+			{VmType: c5, SumNodes: 4, VmClass: Spot, Role: Worker},
+			{VmType: m5, SumNodes: 2, VmClass: Spot, Role: Worker},
+		},
+	})
+
+	assert.Equal(t, []NodePoolChange{
+		{Change: PoolUnchanged, VmType: "m5.xlarge", VmClass: Regular, Role: Worker, BaseNodes: 2, TargetNodes: 2},
+		{Change: PoolResized, VmType: "c5.xlarge", VmClass: Spot, Role: Worker, BaseNodes: 2, TargetNodes: 4},
+		{Change: PoolAdded, VmType: "m5.xlarge", VmClass: Spot, Role: Worker, BaseNodes: 0, TargetNodes: 2},
+		{Change: PoolRemoved, VmType: "r5.xlarge", VmClass: Spot, Role: Worker, BaseNodes: 1, TargetNodes: 0},
+	}, comparison.NodePools)
+
+	assert.InDelta(t, 0.1+0.16-0.1, comparison.HourlyPrice.Delta, 0.0001)
+	assert.InDelta(t, comparison.HourlyPrice.Delta*HoursPerMonth, comparison.MonthlyPrice.Delta, 0.0001)
+	assert.Equal(t, Delta{Base: 20, Target: 32, Delta: 12}, comparison.Cpu)
+	assert.Equal(t, Delta{Base: 80, Target: 96, Delta: 16}, comparison.Memory)
+	assert.Equal(t, Delta{Base: 5, Target: 8, Delta: 3}, comparison.Nodes)
+	assert.Equal(t, Delta{Base: 60, Target: 75, Delta: 15}, comparison.SpotSharePct)
+}
+
+func TestCompareLayouts_emptyBase(t *testing.T) {
+	comparison := CompareLayouts(LayoutComparisonReq{
+		Target: []NodePool{{VmType: VirtualMachine{Type: "m5.xlarge", Cpus: 4}, SumNodes: 3, VmClass: Regular, Role: Worker}},
+	})
+
+	if assert.Len(t, comparison.NodePools, 1) {
+		assert.Equal(t, PoolAdded, comparison.NodePools[0].Change)
+	}
+	assert.Equal(t, Delta{Base: 0, Target: 0, Delta: 0}, comparison.SpotSharePct)
+}
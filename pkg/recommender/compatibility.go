@@ -0,0 +1,56 @@
+// Copyright © 2019 Banzai Cloud
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package recommender
+
+import (
+	"path"
+)
+
+// IncompatibleTypes holds the instance types the node pools of the managed services can't be launched with, per
+// service; the instance types are matched as glob patterns, eg. t1.* or *.metal
+type IncompatibleTypes map[string][]string
+
+// DefaultIncompatibleTypes returns the instance types refused by the node pools of the supported managed services:
+// EKS managed node groups need HVM AMIs and can't launch the macOS dedicated hosts
+func DefaultIncompatibleTypes() IncompatibleTypes {
+	return IncompatibleTypes{
+		"eks": {"t1.*", "mac*"},
+	}
+}
+
+// excludes checks whether the node pools of the service can't be launched with the instance type
+func (it IncompatibleTypes) excludes(service, vmType string) bool {
+	for _, pattern := range it[service] {
+		if matched, _ := path.Match(pattern, vmType); matched {
+			return true
+		}
+	}
+	return false
+}
+
+// excludeIncompatibleTypes drops the vms the node pools of the service can't be launched with
+func (it IncompatibleTypes) excludeIncompatibleTypes(service string, vms []VirtualMachine) []VirtualMachine {
+	if len(it[service]) == 0 {
+		return vms
+	}
+	compatible := make([]VirtualMachine, 0, len(vms))
+	for _, vm := range vms {
+		if it.excludes(service, vm.Type) {
+			continue
+		}
+		compatible = append(compatible, vm)
+	}
+	return compatible
+}
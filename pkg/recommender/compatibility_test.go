@@ -0,0 +1,68 @@
+// Copyright © 2019 Banzai Cloud
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package recommender
+
+import (
+	"testing"
+
+	"github.com/goph/logur"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestIncompatibleTypes_excludeIncompatibleTypes(t *testing.T) {
+	vms := []VirtualMachine{
+		{Type: "t1.micro"},
+		{Type: "t3.micro"},
+		{Type: "mac1.metal"},
+		{Type: "m5.metal"},
+		{Type: "EKS Control Plane"},
+	}
+	incompatible := DefaultIncompatibleTypes()
+
+	var types []string
+	for _, vm := range incompatible.excludeIncompatibleTypes("eks", vms) {
+		types = append(types, vm.Type)
+	}
+	assert.Equal(t, []string{"t3.micro", "m5.metal", "EKS Control Plane"}, types)
+	assert.Len(t, incompatible.excludeIncompatibleTypes("pke", vms), 5, "services without incompatible types should keep all vms")
+
+	incompatible["eks"] = append(incompatible["eks"], "*.metal")
+	assert.True(t, incompatible.excludes("eks", "m5.metal"))
+	assert.False(t, incompatible.excludes("gke", "m5.metal"))
+}
+
+func TestEngine_DebugVmServiceIncompatible(t *testing.T) {
+	products := &candidateProducts{vms: []VirtualMachine{
+		{Type: "m5.xlarge", Cpus: 4, Mem: 16, OnDemandPrice: 0.192, AvgPrice: 0.07},
+		{Type: "t1.micro", Cpus: 4, Mem: 16, OnDemandPrice: 0.02, AvgPrice: 0.005},
+	}}
+	engine := NewEngine(logur.NewTestLogger(), products, &rejectingVms{cpus: 4}, &dummyNodePools{},
+		WithIncompatibleTypes(DefaultIncompatibleTypes()))
+	req := SingleClusterRecommendationReq{
+		ClusterRecommendationReq: ClusterRecommendationReq{SumCpu: 16, SumMem: 64, MinNodes: 2, MaxNodes: 4},
+	}
+
+	debug, err := engine.DebugVm("amazon", "eks", "eu-west-1", req, "t1.micro")
+	if !assert.NoError(t, err) {
+		return
+	}
+	assert.Equal(t, []VmExclusion{{Reason: ExclusionServiceIncompatible}}, debug.Exclusions)
+
+	debug, err = engine.DebugVm("amazon", "compute", "eu-west-1", req, "t1.micro")
+	if !assert.NoError(t, err) {
+		return
+	}
+	assert.Empty(t, debug.Exclusions, "the vm type should be a candidate of the other services")
+}
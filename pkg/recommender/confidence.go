@@ -0,0 +1,134 @@
+// Copyright © 2019 Banzai Cloud
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package recommender
+
+import (
+	"fmt"
+	"math"
+	"time"
+)
+
+const (
+	// costConfidenceWindow is the window of the spot price history the variance of the spot prices is computed from
+	costConfidenceWindow = 7 * 24 * time.Hour
+	// costConfidenceStep is the sampling step of the spot price history
+	costConfidenceStep = time.Hour
+	// costConfidenceZ is the z-score of the ranges, the prices are expected to be within the range 95% of the time
+	costConfidenceZ = 1.96
+)
+
+// CostConfidence is the range an hourly price is expected to be in
+type CostConfidence struct {
+	// Half-width of the range relative to the price, in percent (±)
+	Pct float64 `json:"pct"`
+	// Lower bound of the price
+	Low float64 `json:"low"`
+	// Upper bound of the price
+	High float64 `json:"high"`
+}
+
+// applyCostConfidence sets the relative cost confidence of the spot worker node pools from the variance of the spot
+// price history of their instance type; warnings are returned if the history is not available
+func (e *Engine) applyCostConfidence(provider, service, region string, nodePools []NodePool) []Warning {
+	if e.priceHistory == nil {
+		return []Warning{{
+			Code:    WarnCostConfidenceUnknown,
+			Message: "includeCostConfidence ignored, there is no spot price history configured",
+		}}
+	}
+
+	var warnings []Warning
+	end := time.Now()
+	start := end.Add(-costConfidenceWindow)
+	for i := range nodePools {
+		np := &nodePools[i]
+		if np.Role != Worker || np.VmClass != Spot || np.SumNodes == 0 {
+			continue
+		}
+		points, err := e.priceHistory.SpotPrices(provider, service, region, np.VmType.Type, start, end, costConfidenceStep)
+		if err != nil {
+			e.log.Warn("failed to retrieve the spot price history", map[string]interface{}{"instanceType": np.VmType.Type, "err": err.Error()})
+		}
+		pct, ok := variationPct(points)
+		if !ok {
+			warnings = append(warnings, Warning{
+				Code:    WarnSpotPriceHistoryMissing,
+				Message: fmt.Sprintf("no spot price history of %s, the cost confidence of its node pool is unknown", np.VmType.Type),
+			})
+			continue
+		}
+		np.CostConfidence = &CostConfidence{Pct: pct}
+	}
+	return warnings
+}
+
+// variationPct gets the half-width of the confidence range of the prices relative to their mean, in percent;
+// false if there are not enough prices to compute it
+func variationPct(points []PricePoint) (float64, bool) {
+	if len(points) < 2 {
+		return 0, false
+	}
+	var sum float64
+	for _, p := range points {
+		sum += p.Price
+	}
+	mean := sum / float64(len(points))
+	if mean <= 0 {
+		return 0, false
+	}
+	var squares float64
+	for _, p := range points {
+		squares += (p.Price - mean) * (p.Price - mean)
+	}
+	stddev := math.Sqrt(squares / float64(len(points)))
+	return costConfidenceZ * stddev / mean * 100, true
+}
+
+// updateCostConfidence computes the price ranges of the node pools from their relative cost confidence, and the
+// range of the total price; the ranges of the pools are added up, as the spot prices tend to move together
+func updateCostConfidence(nodePools []NodePool, acc *ClusterRecommendationAccuracy) {
+	var (
+		halfWidth float64
+		known     bool
+	)
+	for i := range nodePools {
+		c := nodePools[i].CostConfidence
+		if c == nil {
+			continue
+		}
+		price := nodePools[i].PoolPrice()
+		h := price * c.Pct / 100
+		nodePools[i].CostConfidence = &CostConfidence{Pct: c.Pct, Low: math.Max(0, price-h), High: price + h}
+		halfWidth += h
+		known = true
+	}
+
+	acc.CostConfidence = nil
+	if !known {
+		return
+	}
+	acc.CostConfidence = &CostConfidence{Low: math.Max(0, acc.RecTotalPrice-halfWidth), High: acc.RecTotalPrice + halfWidth}
+	if acc.RecTotalPrice > 0 {
+		acc.CostConfidence.Pct = halfWidth / acc.RecTotalPrice * 100
+	}
+}
+
+// convert returns a copy of the range with the prices multiplied by the price factor
+func (c *CostConfidence) convert(pf float64) *CostConfidence {
+	if c == nil {
+		return nil
+	}
+	return &CostConfidence{Pct: c.Pct, Low: c.Low * pf, High: c.High * pf}
+}
@@ -0,0 +1,69 @@
+// Copyright © 2019 Banzai Cloud
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package recommender
+
+import (
+	"testing"
+
+	"github.com/goph/logur"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestEngine_applyCostConfidence(t *testing.T) {
+	nodePools := []NodePool{
+		{VmType: VirtualMachine{Type: "m5.xlarge", OnDemandPrice: 0.2}, SumNodes: 2, VmClass: Regular, Role: Worker},
+		{VmType: VirtualMachine{Type: "m5.xlarge", AvgPrice: 0.1}, SumNodes: 4, VmClass: Spot, Role: Worker},
+		{VmType: VirtualMachine{Type: "c5.xlarge", AvgPrice: 0.05}, SumNodes: 2, VmClass: Spot, Role: Worker},
+	}
+	history := dummyPriceHistory{"m5.xlarge": {0.08, 0.12, 0.08, 0.12}}
+
+	engine := NewEngine(logur.NewTestLogger(), nil, nil, nil)
+	warnings := engine.applyCostConfidence("amazon", "compute", "eu-west-1", nodePools)
+	if assert.Len(t, warnings, 1) {
+		assert.Equal(t, WarnCostConfidenceUnknown, warnings[0].Code)
+	}
+
+	engine = NewEngine(logur.NewTestLogger(), nil, nil, nil, WithPriceHistory(history))
+	warnings = engine.applyCostConfidence("amazon", "compute", "eu-west-1", nodePools)
+	if assert.Len(t, warnings, 1) {
+		assert.Equal(t, WarnSpotPriceHistoryMissing, warnings[0].Code)
+	}
+	assert.Nil(t, nodePools[0].CostConfidence, "regular node pools have fixed prices")
+	if assert.NotNil(t, nodePools[1].CostConfidence) {
+		// the standard deviation is 20% of the mean
+		assert.InDelta(t, 39.2, nodePools[1].CostConfidence.Pct, 0.0001)
+	}
+	assert.Nil(t, nodePools[2].CostConfidence)
+
+	acc := findResponseSum("", nodePools, nil)
+	updateCostConfidence(nodePools, &acc)
+	assert.InDelta(t, 0.2432, nodePools[1].CostConfidence.Low, 0.0001)
+	assert.InDelta(t, 0.5568, nodePools[1].CostConfidence.High, 0.0001)
+	if assert.NotNil(t, acc.CostConfidence) {
+		// 0.4 + 0.4 + 0.1 total, ± 0.1568
+		assert.InDelta(t, 0.7432, acc.CostConfidence.Low, 0.0001)
+		assert.InDelta(t, 1.0568, acc.CostConfidence.High, 0.0001)
+		assert.InDelta(t, 17.4222, acc.CostConfidence.Pct, 0.0001)
+	}
+}
+
+func Test_variationPct(t *testing.T) {
+	_, ok := variationPct([]PricePoint{{Price: 0.1}})
+	assert.False(t, ok, "a single price has no variance")
+
+	pct, ok := variationPct([]PricePoint{{Price: 0.1}, {Price: 0.1}})
+	assert.True(t, ok)
+	assert.Equal(t, 0.0, pct)
+}
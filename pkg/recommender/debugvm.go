@@ -0,0 +1,145 @@
+// Copyright © 2019 Banzai Cloud
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package recommender
+
+import (
+	"github.com/goph/emperror"
+)
+
+// Reasons of the exclusion of a vm type from the candidates of a recommendation
+const (
+	// ExclusionNotOffered means the vm type is not offered in the region
+	ExclusionNotOffered = "notOffered"
+	// ExclusionGpuNotLaunchable means the GPU vm type can't be launched in the requested zone
+	ExclusionGpuNotLaunchable = "gpuNotLaunchable"
+	// ExclusionServiceIncompatible means the node pools of the managed service can't be launched with the vm type
+	ExclusionServiceIncompatible = "serviceIncompatible"
+	// ExclusionSizeOutsideWindow means the size of the vm type is outside the per node window of the request
+	ExclusionSizeOutsideWindow = "sizeOutsideWindow"
+	// ExclusionFilter means a vm filter turned on by the request rejects the vm type
+	ExclusionFilter = "filter"
+	// ExclusionNoSpotPrice means the vm type has no spot price, it's left out of the spot pools
+	ExclusionNoSpotPrice = "noSpotPrice"
+	// ExclusionSpotCoverageLow means the spot price of the vm type is known in too few zones, it's left out of the spot pools
+	ExclusionSpotCoverageLow = "spotCoverageLow"
+)
+
+// VmDebug explains why a vm type was excluded from the candidates of a recommendation
+type VmDebug struct {
+	// The vm type debugged
+	Type string `json:"type"`
+	// Reasons of the exclusion, empty if the vm type is a candidate of every node pool
+	Exclusions []VmExclusion `json:"exclusions"`
+}
+
+// VmExclusion is a reason of the exclusion of a vm type
+type VmExclusion struct {
+	// Machine readable reason of the exclusion
+	Reason string `json:"reason"`
+	// Attribute (cpu or memory) of the node pools the vm type is excluded from, empty if it's excluded from all of them
+	Attribute string `json:"attribute,omitempty"`
+	// Class of the node pools the vm type is excluded from (spot), empty if it's excluded from all of them
+	VmClass string `json:"vmClass,omitempty"`
+	// Name of the vm filter rejecting the vm type
+	Filter string `json:"filter,omitempty"`
+}
+
+// DebugVm explains why the vm type was excluded from the candidates of the requested cluster: the products it's
+// missing from, the per node windows it's outside of, the filters rejecting it and the lack of a spot price
+func (e *Engine) DebugVm(provider string, service string, region string, req SingleClusterRecommendationReq, vmType string) (*VmDebug, error) {
+	if err := e.checkTenantProvider(provider); err != nil {
+		return nil, err
+	}
+	req.Excludes = e.tenantExcludes(req.Excludes)
+
+	budgeted, _ := e.withCallBudget()
+	allProducts, _, _, _, err := budgeted.prepareProducts(provider, service, region, &req, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	debug := &VmDebug{Type: vmType, Exclusions: []VmExclusion{}}
+	vm, ok := vmOfType(allProducts, vmType)
+	if !ok {
+		reason, err := budgeted.missingVmReason(provider, service, region, req.Zone, vmType)
+		if err != nil {
+			return nil, err
+		}
+		debug.Exclusions = append(debug.Exclusions, VmExclusion{Reason: reason})
+		return debug, nil
+	}
+
+	for _, attr := range []string{Cpu, Memory} {
+		attrReq := req
+		attrReq.MinNodes, attrReq.MaxNodes = req.nodeBounds(attr)
+
+		vmsInRange, err := e.vmSelector.FindVmsWithAttrValues(attr, attrReq, nil, allProducts)
+		if err != nil {
+			return nil, emperror.With(err, RecommenderErrorTag, "vms")
+		}
+		if _, ok := vmOfType(vmsInRange, vmType); !ok {
+			debug.Exclusions = append(debug.Exclusions, VmExclusion{Reason: ExclusionSizeOutsideWindow, Attribute: attr})
+		}
+
+		filters, err := e.vmSelector.RejectingFilters(provider, vm, attr, attrReq)
+		if err != nil {
+			return nil, emperror.WrapWith(err, "failed to check the vm filters", RecommenderErrorTag)
+		}
+		for _, filter := range filters {
+			debug.Exclusions = append(debug.Exclusions, VmExclusion{Reason: ExclusionFilter, Attribute: attr, Filter: filter})
+		}
+	}
+
+	if vm.AvgPrice == 0 {
+		reason := ExclusionNoSpotPrice
+		if vm.SpotCoverage > 0 {
+			reason = ExclusionSpotCoverageLow
+		}
+		debug.Exclusions = append(debug.Exclusions, VmExclusion{Reason: reason, VmClass: Spot})
+	}
+
+	return debug, nil
+}
+
+// missingVmReason finds out why the vm type is missing from the products of the recommendation: it's not offered,
+// it can't be launched in the zone or by the service, or it's smaller than the per node minimums the products are
+// retrieved with
+func (e *Engine) missingVmReason(provider, service, region, zone, vmType string) (string, error) {
+	products, err := e.ciSource.GetProductDetails(provider, service, region)
+	if err != nil {
+		return "", err
+	}
+	vm, ok := vmOfType(products, vmType)
+	switch {
+	case !ok:
+		return ExclusionNotOffered, nil
+	case zone != "" && vm.Gpus > 0 && !vm.launchableIn(zone):
+		return ExclusionGpuNotLaunchable, nil
+	case e.incompatible.excludes(service, vmType):
+		return ExclusionServiceIncompatible, nil
+	default:
+		return ExclusionSizeOutsideWindow, nil
+	}
+}
+
+// vmOfType finds the vm of the given type
+func vmOfType(vms []VirtualMachine, vmType string) (VirtualMachine, bool) {
+	for _, vm := range vms {
+		if vm.Type == vmType {
+			return vm, true
+		}
+	}
+	return VirtualMachine{}, false
+}
@@ -0,0 +1,88 @@
+// Copyright © 2019 Banzai Cloud
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package recommender
+
+import (
+	"testing"
+
+	"github.com/goph/logur"
+	"github.com/stretchr/testify/assert"
+)
+
+// rejectingVms selects the vms of the given sizes and rejects the vms of the given types by a filter
+type rejectingVms struct {
+	passthroughVms
+	cpus     float64
+	rejected map[string]string
+}
+
+func (v *rejectingVms) FindVmsWithAttrValues(attr string, req SingleClusterRecommendationReq, layoutDesc []NodePoolDesc, allProducts []VirtualMachine) ([]VirtualMachine, error) {
+	var vms []VirtualMachine
+	for _, vm := range allProducts {
+		if attr == Memory || vm.Cpus == v.cpus {
+			vms = append(vms, vm)
+		}
+	}
+	return vms, nil
+}
+
+func (v *rejectingVms) RejectingFilters(provider string, vm VirtualMachine, attr string, req SingleClusterRecommendationReq) ([]string, error) {
+	if filter, ok := v.rejected[vm.Type]; ok {
+		return []string{filter}, nil
+	}
+	return nil, nil
+}
+
+func TestEngine_DebugVm(t *testing.T) {
+	products := &candidateProducts{vms: []VirtualMachine{
+		{Type: "m5.xlarge", Cpus: 4, Mem: 16, OnDemandPrice: 0.192, AvgPrice: 0.07},
+		{Type: "m5.2xlarge", Cpus: 8, Mem: 32, OnDemandPrice: 0.384},
+		{Type: "t3.xlarge", Cpus: 4, Mem: 16, OnDemandPrice: 0.1664, AvgPrice: 0.05},
+		{Type: "t3.small", Cpus: 2, Mem: 2, OnDemandPrice: 0.0208, AvgPrice: 0.0062},
+	}}
+	vms := &rejectingVms{cpus: 4, rejected: map[string]string{"t3.xlarge": "burst"}}
+	engine := NewEngine(logur.NewTestLogger(), products, vms, &dummyNodePools{})
+	req := SingleClusterRecommendationReq{
+		ClusterRecommendationReq: ClusterRecommendationReq{SumCpu: 16, SumMem: 64, MinNodes: 2, MaxNodes: 4},
+	}
+
+	tests := []struct {
+		vmType     string
+		exclusions []VmExclusion
+	}{
+		{vmType: "m5.xlarge", exclusions: []VmExclusion{}},
+		{vmType: "m5.2xlarge", exclusions: []VmExclusion{
+			{Reason: ExclusionSizeOutsideWindow, Attribute: Cpu},
+			{Reason: ExclusionNoSpotPrice, VmClass: Spot},
+		}},
+		{vmType: "t3.xlarge", exclusions: []VmExclusion{
+			{Reason: ExclusionFilter, Attribute: Cpu, Filter: "burst"},
+			{Reason: ExclusionFilter, Attribute: Memory, Filter: "burst"},
+		}},
+		{vmType: "t3.small", exclusions: []VmExclusion{{Reason: ExclusionSizeOutsideWindow}}},
+		{vmType: "x1.32xlarge", exclusions: []VmExclusion{{Reason: ExclusionNotOffered}}},
+	}
+	for _, test := range tests {
+		test := test
+		t.Run(test.vmType, func(t *testing.T) {
+			debug, err := engine.DebugVm("amazon", "compute", "eu-west-1", req, test.vmType)
+			if !assert.NoError(t, err) {
+				return
+			}
+			assert.Equal(t, test.vmType, debug.Type)
+			assert.Equal(t, test.exclusions, debug.Exclusions)
+		})
+	}
+}
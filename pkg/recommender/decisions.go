@@ -0,0 +1,226 @@
+// Copyright © 2019 Banzai Cloud
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package recommender
+
+import (
+	"encoding/json"
+	"io"
+	"sync"
+	"time"
+
+	"github.com/goph/logur"
+)
+
+// DecisionEventVersion is the version of the schema of the decision events, raised on incompatible changes
+const DecisionEventVersion = 1
+
+// DecisionEvent records a decision of the engine for training better heuristics offline; it holds the features of the
+// request, but nothing identifying the requester (metadata, tenant, instance type names of the request)
+type DecisionEvent struct {
+	// Version of the schema of the event
+	Version int `json:"version"`
+	// Time the decision was made
+	Time time.Time `json:"time"`
+	// The cloud provider
+	Provider string `json:"provider"`
+	// Provider's service
+	Service string `json:"service"`
+	// Service's region
+	Region string `json:"region"`
+	// Features of the request
+	Features DecisionFeatures `json:"features"`
+	// Number of the candidate instance types
+	Candidates DecisionCandidates `json:"candidates"`
+	// Attribute (cpu or memory) the chosen layout was recommended for
+	Attribute string `json:"attribute,omitempty"`
+	// Layouts recommended for the attributes, with their prices
+	AttributeLayouts []AttributeLayout `json:"attributeLayouts,omitempty"`
+	// Node pools of the chosen layout
+	Layout []DecisionNodePool `json:"layout"`
+	// Hourly price of the chosen layout
+	Price float64 `json:"price"`
+	// Estimated monthly bill of the chosen layout
+	MonthlyPrice float64 `json:"monthlyPrice"`
+	// Codes of the warnings of the recommendation
+	Warnings []string `json:"warnings,omitempty"`
+}
+
+// DecisionFeatures are the features of a request the decision depends on
+type DecisionFeatures struct {
+	SumCpu        float64 `json:"sumCpu"`
+	SumMem        float64 `json:"sumMem"`
+	SumGpu        int     `json:"sumGpu,omitempty"`
+	MinNodes      int     `json:"minNodes"`
+	MaxNodes      int     `json:"maxNodes"`
+	OnDemandPct   int     `json:"onDemandPct"`
+	OnDemandCount int     `json:"onDemandCount,omitempty"`
+	// Signals a single zone recommendation
+	SingleZone bool `json:"singleZone,omitempty"`
+	// Signals the scale out of an existing layout
+	ScaleOut bool `json:"scaleOut,omitempty"`
+	// Number of the instance types included and excluded by the request
+	Includes    int    `json:"includes,omitempty"`
+	Excludes    int    `json:"excludes,omitempty"`
+	Resiliency  string `json:"resiliency,omitempty"`
+	OptimizeFor string `json:"optimizeFor,omitempty"`
+}
+
+// DecisionCandidates are the number of the instance types the decision was made from
+type DecisionCandidates struct {
+	// Instance types taking part in the recommendation
+	Products int `json:"products"`
+	// Instance types having a spot price
+	SpotPriced int `json:"spotPriced"`
+}
+
+// DecisionNodePool is a node pool of the chosen layout
+type DecisionNodePool struct {
+	VmType  string  `json:"vmType"`
+	VmClass string  `json:"vmClass"`
+	Role    string  `json:"role"`
+	Nodes   int     `json:"nodes"`
+	Cpus    float64 `json:"cpusPerVm"`
+	Mem     float64 `json:"memPerVm"`
+	// Hourly price of a node in the class of the node pool
+	Price float64 `json:"price"`
+}
+
+// DecisionEmitter records the decisions of the engine, eg. into a file or a message queue
+type DecisionEmitter interface {
+	// Emit records the decision event, it must not block the recommendation for long
+	Emit(event DecisionEvent)
+}
+
+// newDecisionEvent creates the decision event of the recommendation made from the given products
+func newDecisionEvent(req SingleClusterRecommendationReq, layoutDesc []NodePoolDesc, products []VirtualMachine, resp *ClusterRecommendationResp) DecisionEvent {
+	event := DecisionEvent{
+		Version:  DecisionEventVersion,
+		Time:     time.Now().UTC(),
+		Provider: resp.Provider,
+		Service:  resp.Service,
+		Region:   resp.Region,
+		Features: DecisionFeatures{
+			SumCpu:        req.SumCpu,
+			SumMem:        req.SumMem,
+			SumGpu:        req.SumGpu,
+			MinNodes:      req.MinNodes,
+			MaxNodes:      req.MaxNodes,
+			OnDemandPct:   req.OnDemandPct,
+			OnDemandCount: req.OnDemandCount,
+			SingleZone:    req.Zone != "",
+			ScaleOut:      layoutDesc != nil,
+			Includes:      len(req.Includes),
+			Excludes:      len(req.Excludes),
+			Resiliency:    req.Resiliency,
+			OptimizeFor:   req.OptimizeFor,
+		},
+		Candidates:       DecisionCandidates{Products: len(products)},
+		Attribute:        resp.Accuracy.RecAttribute,
+		AttributeLayouts: resp.Accuracy.AttrLayouts,
+		Layout:           make([]DecisionNodePool, 0, len(resp.NodePools)),
+		Price:            resp.Accuracy.RecTotalPrice,
+		MonthlyPrice:     resp.Accuracy.RecMonthlyPrice,
+	}
+	for _, vm := range products {
+		if vm.AvgPrice > 0 {
+			event.Candidates.SpotPriced++
+		}
+	}
+	for _, np := range resp.NodePools {
+		if np.SumNodes == 0 {
+			continue
+		}
+		event.Layout = append(event.Layout, DecisionNodePool{
+			VmType:  np.VmType.Type,
+			VmClass: np.VmClass,
+			Role:    np.Role,
+			Nodes:   np.SumNodes,
+			Cpus:    np.VmType.Cpus,
+			Mem:     np.VmType.Mem,
+			Price:   np.PoolPrice() / float64(np.SumNodes),
+		})
+	}
+	for _, w := range resp.Warnings {
+		event.Warnings = append(event.Warnings, w.Code)
+	}
+	return event
+}
+
+// decisionBufferSize is the number of the decision events buffered before new ones are dropped
+const decisionBufferSize = 1024
+
+// JSONLDecisionEmitter writes the decision events to a writer, one JSON object per line; the events are written by
+// a background goroutine so a slow writer doesn't hold up the recommendations
+type JSONLDecisionEmitter struct {
+	w      io.Writer
+	log    logur.Logger
+	events chan DecisionEvent
+	done   chan struct{}
+	mux    sync.RWMutex
+	closed bool
+}
+
+// NewJSONLDecisionEmitter creates a decision emitter writing the events to the writer in JSON lines format;
+// events failed to be written or not fitting into the buffer are logged and dropped
+func NewJSONLDecisionEmitter(w io.Writer, log logur.Logger) *JSONLDecisionEmitter {
+	e := &JSONLDecisionEmitter{
+		w:      w,
+		log:    log,
+		events: make(chan DecisionEvent, decisionBufferSize),
+		done:   make(chan struct{}),
+	}
+	go e.run()
+	return e
+}
+
+// Emit queues the event for writing, it never blocks; events emitted after closing the emitter are dropped
+func (e *JSONLDecisionEmitter) Emit(event DecisionEvent) {
+	e.mux.RLock()
+	defer e.mux.RUnlock()
+	if e.closed {
+		return
+	}
+	select {
+	case e.events <- event:
+	default:
+		e.log.Warn("decision event buffer is full, dropping event", map[string]interface{}{"provider": event.Provider, "region": event.Region})
+	}
+}
+
+// Close stops accepting events and waits for the buffered ones to be written
+func (e *JSONLDecisionEmitter) Close() error {
+	e.mux.Lock()
+	if !e.closed {
+		e.closed = true
+		close(e.events)
+	}
+	e.mux.Unlock()
+	<-e.done
+	return nil
+}
+
+func (e *JSONLDecisionEmitter) run() {
+	defer close(e.done)
+	for event := range e.events {
+		data, err := json.Marshal(event)
+		if err != nil {
+			e.log.Error("failed to encode decision event", map[string]interface{}{"error": err.Error()})
+			continue
+		}
+		if _, err := e.w.Write(append(data, '\n')); err != nil {
+			e.log.Error("failed to write decision event", map[string]interface{}{"error": err.Error()})
+		}
+	}
+}
@@ -0,0 +1,109 @@
+// Copyright © 2019 Banzai Cloud
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package recommender
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/goph/logur"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestEngine_RecommendClusterDecisionEvents(t *testing.T) {
+	var buf bytes.Buffer
+	emitter := NewJSONLDecisionEmitter(&buf, logur.NewTestLogger())
+	engine := NewEngine(logur.NewTestLogger(), &dummyProducts{}, &dummyVms{}, &dummyNodePools{}, WithDecisionEmitter(emitter))
+
+	req := SingleClusterRecommendationReq{
+		ClusterRecommendationReq: ClusterRecommendationReq{
+			MinNodes: 1,
+			MaxNodes: 1,
+			SumMem:   32,
+			SumCpu:   16,
+			Metadata: map[string]string{"team": "secret"},
+		},
+		Excludes: []string{"t2.micro"},
+	}
+	for i := 0; i < 2; i++ {
+		if _, err := engine.RecommendCluster("dummyProvider", "dummyService", "dummyRegion", req, nil); !assert.Nil(t, err, "the error should be nil") {
+			return
+		}
+	}
+
+	if !assert.Nil(t, emitter.Close(), "the emitter should be closed") {
+		return
+	}
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	if !assert.Len(t, lines, 2, "an event per line is expected") {
+		return
+	}
+	assert.NotContains(t, lines[0], "secret", "the metadata should not be recorded")
+	assert.NotContains(t, lines[0], "t2.micro", "the instance types of the request should not be recorded")
+
+	var event DecisionEvent
+	if !assert.Nil(t, json.Unmarshal([]byte(lines[0]), &event), "the event should be valid JSON") {
+		return
+	}
+	assert.Equal(t, DecisionEventVersion, event.Version)
+	assert.Equal(t, "dummyProvider", event.Provider)
+	assert.Equal(t, 16.0, event.Features.SumCpu)
+	assert.Equal(t, 1, event.Features.Excludes)
+	assert.False(t, event.Features.ScaleOut)
+	assert.True(t, event.Candidates.Products > 0)
+	assert.NotEmpty(t, event.Layout)
+	assert.NotEmpty(t, event.Attribute)
+}
+
+type blockingWriter struct {
+	release chan struct{}
+	buf     bytes.Buffer
+}
+
+func (w *blockingWriter) Write(p []byte) (int, error) {
+	<-w.release
+	return w.buf.Write(p)
+}
+
+func TestJSONLDecisionEmitter_EmitDoesNotBlock(t *testing.T) {
+	w := &blockingWriter{release: make(chan struct{})}
+	emitter := NewJSONLDecisionEmitter(w, logur.NewTestLogger())
+
+	emitted := make(chan struct{})
+	go func() {
+		for i := 0; i < decisionBufferSize+10; i++ {
+			emitter.Emit(DecisionEvent{Provider: "amazon"})
+		}
+		close(emitted)
+	}()
+	select {
+	case <-emitted:
+	case <-time.After(5 * time.Second):
+		t.Fatal("emitting should not wait for the writer")
+	}
+
+	close(w.release)
+	if !assert.Nil(t, emitter.Close(), "the emitter should be closed") {
+		return
+	}
+	lines := strings.Split(strings.TrimSpace(w.buf.String()), "\n")
+	assert.True(t, len(lines) >= decisionBufferSize, "the buffered events should be written on close")
+	assert.True(t, len(lines) <= decisionBufferSize+1, "the events not fitting into the buffer should be dropped")
+
+	emitter.Emit(DecisionEvent{Provider: "amazon"})
+}
@@ -0,0 +1,108 @@
+// Copyright © 2019 Banzai Cloud
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package recommender
+
+import (
+	"fmt"
+	"path"
+	"sort"
+)
+
+// Deprecations holds the instance types scheduled for deprecation per provider
+type Deprecations map[string][]Deprecation
+
+// Deprecation describes instance types scheduled for deprecation, eg. by an end of life announcement of the provider
+type Deprecation struct {
+	// InstanceType is the deprecated instance type, matched as a glob pattern, eg. m1.* or t2.nano
+	InstanceType string
+	// Date of the deprecation as announced, eg. 2025-06-30
+	Date string
+	// Replacement is the instance type suggested instead, the closest current generation type if empty
+	Replacement string
+}
+
+// TypeDeprecation signals that the instance type of a node pool is scheduled for deprecation
+type TypeDeprecation struct {
+	// Date of the deprecation as announced
+	Date string `json:"date,omitempty"`
+	// Replacement is the instance type suggested instead of the deprecated one, omitted if there's none
+	Replacement string `json:"replacement,omitempty"`
+}
+
+// deprecation finds the deprecation of the instance type of the provider
+func (d Deprecations) deprecation(provider, vmType string) (Deprecation, bool) {
+	for _, deprecation := range d[provider] {
+		if matched, _ := path.Match(deprecation.InstanceType, vmType); matched {
+			return deprecation, true
+		}
+	}
+	return Deprecation{}, false
+}
+
+// annotateDeprecations marks the node pools of instance types scheduled for deprecation with a suggested replacement,
+// so new clusters aren't built on sunsetting hardware
+func (d Deprecations) annotateDeprecations(provider string, vms []VirtualMachine, nodePools []NodePool) []Warning {
+	if len(d[provider]) == 0 {
+		return nil
+	}
+
+	var warnings []Warning
+	for i, np := range nodePools {
+		deprecation, ok := d.deprecation(provider, np.VmType.Type)
+		if !ok {
+			continue
+		}
+		replacement := deprecation.Replacement
+		if replacement == "" {
+			replacement = d.closestReplacement(provider, np.VmType, vms)
+		}
+		nodePools[i].Deprecation = &TypeDeprecation{Date: deprecation.Date, Replacement: replacement}
+
+		message := fmt.Sprintf("%s is scheduled for deprecation", np.VmType.Type)
+		if deprecation.Date != "" {
+			message += " on " + deprecation.Date
+		}
+		if replacement != "" {
+			message += fmt.Sprintf(", consider %s instead", replacement)
+		}
+		warnings = append(warnings, Warning{Code: WarnTypeDeprecated, Message: message})
+	}
+	return warnings
+}
+
+// closestReplacement finds the current generation instance type closest in size to the vm among the ones not
+// deprecated, empty if there's none
+func (d Deprecations) closestReplacement(provider string, vm VirtualMachine, vms []VirtualMachine) string {
+	candidates := make([]Replacement, 0)
+	for _, candidate := range vms {
+		if candidate.Type == vm.Type || !replaces(candidate, vm) {
+			continue
+		}
+		if _, deprecated := d.deprecation(provider, candidate.Type); deprecated {
+			continue
+		}
+		candidates = append(candidates, newReplacement(vm, candidate))
+	}
+	if len(candidates) == 0 {
+		return ""
+	}
+	sort.SliceStable(candidates, func(i, j int) bool {
+		if candidates[i].Distance != candidates[j].Distance {
+			return candidates[i].Distance < candidates[j].Distance
+		}
+		return candidates[i].OnDemandPrice < candidates[j].OnDemandPrice
+	})
+	return candidates[0].VmType
+}
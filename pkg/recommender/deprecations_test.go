@@ -0,0 +1,56 @@
+// Copyright © 2019 Banzai Cloud
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package recommender
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDeprecations_annotateDeprecations(t *testing.T) {
+	vms := []VirtualMachine{
+		{Type: "m1.large", Cpus: 2, Mem: 7.5, OnDemandPrice: 0.175},
+		{Type: "m4.large", Cpus: 2, Mem: 8, OnDemandPrice: 0.1, CurrentGen: true},
+		{Type: "m5.large", Cpus: 2, Mem: 8, OnDemandPrice: 0.096, CurrentGen: true},
+		{Type: "m5.xlarge", Cpus: 4, Mem: 16, OnDemandPrice: 0.192, CurrentGen: true},
+		{Type: "c1.medium", Cpus: 2, Mem: 1.7, OnDemandPrice: 0.13},
+	}
+	deprecations := Deprecations{
+		"amazon": {
+			{InstanceType: "m1.*", Date: "2025-06-30"},
+			{InstanceType: "m4.*"},
+			{InstanceType: "c1.medium", Replacement: "c6i.large"},
+		},
+	}
+	nodePools := []NodePool{
+		{VmType: vms[0], SumNodes: 2, VmClass: Regular, Role: Worker},
+		{VmType: vms[3], SumNodes: 2, VmClass: Spot, Role: Worker},
+		{VmType: vms[4], SumNodes: 1, VmClass: Regular, Role: Master},
+	}
+
+	warnings := deprecations.annotateDeprecations("amazon", vms, nodePools)
+	if !assert.Len(t, warnings, 2) {
+		return
+	}
+	assert.Equal(t, WarnTypeDeprecated, warnings[0].Code)
+	assert.Equal(t, "m1.large is scheduled for deprecation on 2025-06-30, consider m5.large instead", warnings[0].Message)
+	assert.Equal(t, &TypeDeprecation{Date: "2025-06-30", Replacement: "m5.large"}, nodePools[0].Deprecation,
+		"the deprecated m4.large shouldn't be suggested")
+	assert.Nil(t, nodePools[1].Deprecation)
+	assert.Equal(t, &TypeDeprecation{Replacement: "c6i.large"}, nodePools[2].Deprecation)
+
+	assert.Empty(t, deprecations.annotateDeprecations("google", vms, nodePools))
+}
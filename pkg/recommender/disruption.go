@@ -0,0 +1,53 @@
+// Copyright © 2019 Banzai Cloud
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package recommender
+
+import "math"
+
+// DisruptionBudget limits the share of the worker capacity a spot interruption may take away
+type DisruptionBudget struct {
+	// Maximum percentage of the worker capacity on spot instances
+	MaxSpotPct int `json:"maxSpotPct,omitempty" binding:"min=0,max=100"`
+	// Maximum percentage of the worker capacity in a single spot node pool
+	MaxPoolSharePct int `json:"maxPoolSharePct,omitempty" binding:"min=0,max=100"`
+}
+
+// MinOnDemandPct returns the percentage of on-demand capacity the budget requires
+func (b DisruptionBudget) MinOnDemandPct() int {
+	if b.MaxSpotPct == 0 {
+		return 0
+	}
+	return 100 - b.MaxSpotPct
+}
+
+// SpotSpread combines the resiliency level and the disruption budget of the request into the minimum number of spot
+// node pools and the maximum percentage of the worker capacity allowed in a single spot node pool; spotPct is the
+// percentage of the worker capacity left for the spot node pools by the on-demand and reserved ones
+func (r ClusterRecommendationReq) SpotSpread(spotPct float64) (int, int) {
+	minSpotPools, maxPoolSharePct := 1, 100
+	if rc, ok := ResiliencyFor(r.Resiliency); ok {
+		minSpotPools, maxPoolSharePct = rc.MinSpotPools, rc.MaxPoolSharePct
+	}
+
+	if budget := r.DisruptionBudget.MaxPoolSharePct; budget > 0 && budget < maxPoolSharePct {
+		maxPoolSharePct = budget
+		// the spot capacity needs at least this many pools to keep each of them within the budget
+		pools := int(math.Ceil(spotPct / float64(budget)))
+		if pools > minSpotPools {
+			minSpotPools = pools
+		}
+	}
+	return minSpotPools, maxPoolSharePct
+}
@@ -0,0 +1,81 @@
+// Copyright © 2019 Banzai Cloud
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package recommender
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestClusterRecommendationReq_SpotSpread(t *testing.T) {
+	tests := []struct {
+		name            string
+		req             ClusterRecommendationReq
+		spotPct         float64
+		minSpotPools    int
+		maxPoolSharePct int
+	}{
+		{
+			name:            "no constraints",
+			req:             ClusterRecommendationReq{},
+			spotPct:         100,
+			minSpotPools:    1,
+			maxPoolSharePct: 100,
+		},
+		{
+			name:            "resiliency level",
+			req:             ClusterRecommendationReq{Resiliency: ResiliencyMedium},
+			spotPct:         100,
+			minSpotPools:    2,
+			maxPoolSharePct: 60,
+		},
+		{
+			name:            "disruption budget stricter than the resiliency level",
+			req:             ClusterRecommendationReq{Resiliency: ResiliencyMedium, OnDemandPct: 10, DisruptionBudget: DisruptionBudget{MaxPoolSharePct: 20}},
+			spotPct:         90,
+			minSpotPools:    5,
+			maxPoolSharePct: 20,
+		},
+		{
+			name:            "spot share left by an on-demand count",
+			req:             ClusterRecommendationReq{OnDemandCount: 3, DisruptionBudget: DisruptionBudget{MaxPoolSharePct: 20}},
+			spotPct:         62.5,
+			minSpotPools:    4,
+			maxPoolSharePct: 20,
+		},
+		{
+			name:            "disruption budget looser than the resiliency level",
+			req:             ClusterRecommendationReq{Resiliency: ResiliencyHigh, DisruptionBudget: DisruptionBudget{MaxPoolSharePct: 50}},
+			spotPct:         100,
+			minSpotPools:    4,
+			maxPoolSharePct: 35,
+		},
+	}
+	for _, test := range tests {
+		test := test
+		t.Run(test.name, func(t *testing.T) {
+			minSpotPools, maxPoolSharePct := test.req.SpotSpread(test.spotPct)
+			assert.Equal(t, test.minSpotPools, minSpotPools)
+			assert.Equal(t, test.maxPoolSharePct, maxPoolSharePct)
+		})
+	}
+}
+
+func TestDisruptionBudget_MinOnDemandPct(t *testing.T) {
+	assert.Equal(t, 0, DisruptionBudget{}.MinOnDemandPct(), "an unset budget allows any spot capacity")
+	assert.Equal(t, 40, DisruptionBudget{MaxSpotPct: 60}.MinOnDemandPct())
+	assert.Equal(t, 0, DisruptionBudget{MaxSpotPct: 100}.MinOnDemandPct())
+}
@@ -0,0 +1,69 @@
+// Copyright © 2019 Banzai Cloud
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package recommender
+
+import "fmt"
+
+// ActionDrain suggests draining and removing the node pool from the cluster
+const ActionDrain = "drain"
+
+// suggestPoolRemovals marks the node pools of the scale out layout that are dead weight in the cluster to be drained:
+// the pools whose instance type is no longer offered, and the spot pools whose spot price isn't below the on-demand price
+// The pools of instance types no longer offered are dropped by the recommendation, they are added back to be marked
+func suggestPoolRemovals(layoutDesc []NodePoolDesc, vms []VirtualMachine, nodePools []NodePool) ([]NodePool, []Warning) {
+	offered := make(map[string]VirtualMachine, len(vms))
+	for _, vm := range vms {
+		offered[vm.Type] = vm
+	}
+
+	var warnings []Warning
+	for _, npd := range layoutDesc {
+		if npd.SumNodes == 0 {
+			continue
+		}
+		vmClass := npd.GetVmClass()
+		vm, ok := offered[npd.InstanceType]
+		switch {
+		case !ok:
+			nodePools = append(nodePools, NodePool{
+				VmType:          VirtualMachine{Type: npd.InstanceType},
+				SumNodes:        npd.SumNodes,
+				VmClass:         vmClass,
+				Role:            Worker,
+				Pinned:          npd.Pinned,
+				SuggestedAction: ActionDrain,
+			})
+			warnings = append(warnings, Warning{
+				Code:    WarnDrainSuggested,
+				Message: fmt.Sprintf("%s %s node pool should be drained, the instance type is no longer offered", vmClass, npd.InstanceType),
+			})
+		case vmClass == Spot && (vm.AvgPrice == 0 || vm.AvgPrice >= vm.OnDemandPrice):
+			for i := range nodePools {
+				if nodePools[i].VmType.Type == npd.InstanceType && nodePools[i].VmClass == Spot {
+					nodePools[i].SuggestedAction = ActionDrain
+				}
+			}
+			reason := fmt.Sprintf("its spot price (%.4f) isn't below the on-demand price (%.4f)", vm.AvgPrice, vm.OnDemandPrice)
+			if vm.AvgPrice == 0 {
+				reason = "it's no longer available as spot instance"
+			}
+			warnings = append(warnings, Warning{
+				Code:    WarnDrainSuggested,
+				Message: fmt.Sprintf("spot %s node pool should be drained, %s", npd.InstanceType, reason),
+			})
+		}
+	}
+	return nodePools, warnings
+}
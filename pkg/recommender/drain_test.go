@@ -0,0 +1,59 @@
+// Copyright © 2019 Banzai Cloud
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package recommender
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_suggestPoolRemovals(t *testing.T) {
+	layoutDesc := []NodePoolDesc{
+		{InstanceType: "m5.xlarge", VmClass: Regular, SumNodes: 3},
+		{InstanceType: "m5.xlarge", VmClass: Spot, SumNodes: 2},
+		{InstanceType: "c5.xlarge", VmClass: Spot, SumNodes: 2},
+		{InstanceType: "r5.xlarge", VmClass: Spot, SumNodes: 1},
+		{InstanceType: "m1.large", VmClass: Regular, SumNodes: 1},
+		{InstanceType: "m1.xlarge", VmClass: Spot, SumNodes: 0},
+	}
+	vms := []VirtualMachine{
+		{Type: "m5.xlarge", OnDemandPrice: 0.192, AvgPrice: 0.07},
+		{Type: "c5.xlarge", OnDemandPrice: 0.17, AvgPrice: 0.18},
+		{Type: "r5.xlarge", OnDemandPrice: 0.252},
+	}
+	nodePools := []NodePool{
+		{VmType: vms[0], VmClass: Regular, SumNodes: 3, Role: Worker},
+		{VmType: vms[0], VmClass: Spot, SumNodes: 4, Role: Worker},
+		{VmType: vms[1], VmClass: Spot, SumNodes: 2, Role: Worker},
+		{VmType: vms[2], VmClass: Spot, SumNodes: 1, Role: Worker},
+	}
+
+	nodePools, warnings := suggestPoolRemovals(layoutDesc, vms, nodePools)
+
+	if !assert.Len(t, nodePools, 5) {
+		return
+	}
+	assert.Equal(t, "", nodePools[0].SuggestedAction)
+	assert.Equal(t, "", nodePools[1].SuggestedAction)
+	assert.Equal(t, ActionDrain, nodePools[2].SuggestedAction, "spot pools more expensive than on-demand should be drained")
+	assert.Equal(t, ActionDrain, nodePools[3].SuggestedAction, "pools without spot price should be drained")
+	assert.Equal(t, NodePool{VmType: VirtualMachine{Type: "m1.large"}, VmClass: Regular, SumNodes: 1, Role: Worker, SuggestedAction: ActionDrain},
+		nodePools[4], "pools of types no longer offered should be added back")
+	assert.Len(t, warnings, 3)
+	for _, w := range warnings {
+		assert.Equal(t, WarnDrainSuggested, w.Code)
+	}
+}
@@ -19,40 +19,156 @@ import (
 	"math"
 	"sort"
 	"strings"
+	"sync"
 
 	"github.com/goph/emperror"
 	"github.com/goph/logur"
 	"github.com/pkg/errors"
+	"golang.org/x/sync/errgroup"
 )
 
+// overshootTolerancePct is the percentage the recommended resources may exceed the requested ones without a warning
+const overshootTolerancePct = 10
+
 // Engine represents the recommendation engine, it operates on a map of provider -> VmRegistry
 type Engine struct {
 	log              logur.Logger
 	ciSource         CloudInfoSource
 	vmSelector       VmRecommender
 	nodePoolSelector NodePoolRecommender
+	benchmarks       Benchmarks
+	maxPods          MaxPods
+	priceAdjusters   []PriceAdjuster
+	afterburner      *afterburner
+	callBudget       int
+	priceHistory     PriceHistory
+	interruptionHeat InterruptionHeat
+	minSpotCoverage  float64
+	maxSpotRatio     float64
+	spotDisabled     map[string]bool
+	decisions        DecisionEmitter
+	snapshots        SnapshotStore
+	nodeCosts        NodeCosts
+	incompatible     IncompatibleTypes
+	deprecations     Deprecations
+	metadata         *metadataCache
+	tenant           *Tenant
 }
 
 // NewEngine creates a new Engine instance
-func NewEngine(log logur.Logger, ciSource CloudInfoSource, vmSelector VmRecommender, nodePoolSelector NodePoolRecommender) *Engine {
-	return &Engine{
+func NewEngine(log logur.Logger, ciSource CloudInfoSource, vmSelector VmRecommender, nodePoolSelector NodePoolRecommender, opts ...EngineOption) *Engine {
+	e := &Engine{
 		log:              log,
 		ciSource:         ciSource,
 		vmSelector:       vmSelector,
 		nodePoolSelector: nodePoolSelector,
 	}
+	for _, opt := range opts {
+		opt(e)
+	}
+	return e
 }
 
 // RecommendCluster performs recommendation based on the provided arguments
 func (e *Engine) RecommendCluster(provider string, service string, region string, req SingleClusterRecommendationReq, layoutDesc []NodePoolDesc) (*ClusterRecommendationResp, error) {
+	budgeted, budget := e.withCallBudget()
+
+	resp, err := budgeted.recommendSingleCluster(provider, service, region, req, layoutDesc)
+	if err != nil {
+		return nil, err
+	}
+	resp.CloudInfoCalls = budget.count()
+
+	return resp, nil
+}
+
+// recommendSingleCluster performs the recommendation for a single cluster, the calls to the cloud info service are counted by the caller
+func (e *Engine) recommendSingleCluster(provider string, service string, region string, req SingleClusterRecommendationReq, layoutDesc []NodePoolDesc) (*ClusterRecommendationResp, error) {
 	e.log.Info(fmt.Sprintf("recommending cluster configuration. request: [%#v]", req))
 
-	allProducts, err := e.ciSource.GetProductDetails(provider, service, region)
+	if err := e.checkTenantProvider(provider); err != nil {
+		return nil, err
+	}
+	req.Excludes = e.tenantExcludes(req.Excludes)
+
+	if req.MinNodes > req.MaxNodes {
+		return nil, emperror.With(ErrMinGreaterThanMax, RecommenderErrorTag, "minNodes", req.MinNodes, "maxNodes", req.MaxNodes)
+	}
+
+	if req.OnDemandCount > 0 {
+		if req.OnDemandPct != 0 {
+			return nil, emperror.With(ErrOnDemandCountConflict, RecommenderErrorTag, "onDemandCount", req.OnDemandCount, "onDemandPct", req.OnDemandPct)
+		}
+		if req.OnDemandCount > req.MaxNodes {
+			return nil, emperror.With(ErrOnDemandCountExceedsMax, RecommenderErrorTag, "onDemandCount", req.OnDemandCount, "maxNodes", req.MaxNodes)
+		}
+	}
+
+	if err := validateAttributeNodes(req.ClusterRecommendationReq); err != nil {
+		return nil, err
+	}
+
+	if err := validatePerNodeBounds(req.ClusterRecommendationReq); err != nil {
+		return nil, err
+	}
+
+	if err := validateZoneWeights(req.ZoneWeights); err != nil {
+		return nil, err
+	}
+
+	if err := validateRequireAllZones(req); err != nil {
+		return nil, err
+	}
+
+	podOverheadWarning := applyPodOverhead(&req)
+
+	allProducts, freshness, snapshot, warnings, err := e.prepareProducts(provider, service, region, &req, layoutDesc)
 	if err != nil {
 		return nil, err
 	}
+	if podOverheadWarning != nil {
+		warnings = append(warnings, *podOverheadWarning)
+	}
+
+	var resiliency *ResiliencyConstraints
+	if rc, ok := ResiliencyFor(req.Resiliency); ok {
+		resiliency = &rc
+		// an absolute on-demand count is taken as it is
+		if req.OnDemandCount == 0 && req.OnDemandPct < rc.MinOnDemandPct {
+			warnings = append(warnings, Warning{
+				Code: WarnOnDemandPctRaised,
+				Message: fmt.Sprintf("onDemandPct raised from %d to %d for %s resiliency",
+					req.OnDemandPct, rc.MinOnDemandPct, rc.Level),
+			})
+			req.OnDemandPct = rc.MinOnDemandPct
+		}
+	}
+
+	// with an on-demand count, the node pool recommender raises the on-demand nodes to keep the spot capacity in the budget
+	if minPct := req.DisruptionBudget.MinOnDemandPct(); req.OnDemandCount == 0 && req.OnDemandPct < minPct {
+		warnings = append(warnings, Warning{
+			Code: WarnOnDemandPctRaised,
+			Message: fmt.Sprintf("onDemandPct raised from %d to %d, at most %d%% of the capacity may be on spot instances",
+				req.OnDemandPct, minPct, req.DisruptionBudget.MaxSpotPct),
+		})
+		req.OnDemandPct = minPct
+	}
+
+	if req.OnDemandPct != 100 && e.spotDisabled[provider] {
+		warnings = append(warnings, e.excludeSpots(&req, WarnSpotDisabled,
+			fmt.Sprintf("spot instances of %s are disabled", provider)))
+	}
+
+	if req.OnDemandPct != 100 && !spotRuntimeSupported(provider, req.MinSpotRuntimeHours) {
+		p, _ := preemptionOf(provider)
+		warnings = append(warnings, e.excludeSpots(&req, WarnSpotRuntimeExceeded,
+			fmt.Sprintf("spot instances of %s run for at most %d hours, %d hours requested", provider, p.MaxRuntimeHours, req.MinSpotRuntimeHours)))
+	}
 
 	if req.OnDemandPct != 100 {
+		if warning := excludeImplausibleSpots(e.maxSpotRatio, allProducts); warning != nil {
+			warnings = append(warnings, *warning)
+		}
 		availableSpotPrice := false
 		for _, vm := range allProducts {
 			if vm.AvgPrice != 0.0 {
@@ -61,8 +177,8 @@ func (e *Engine) RecommendCluster(provider string, service string, region string
 			}
 		}
 		if !availableSpotPrice {
-			e.log.Warn("onDemand percentage in the request ignored")
-			req.OnDemandPct = 100
+			warnings = append(warnings, e.excludeSpots(&req, WarnOnDemandPctIgnored,
+				fmt.Sprintf("there are no spot prices for %s/%s in %s", provider, service, region)))
 		}
 	}
 
@@ -71,24 +187,206 @@ func (e *Engine) RecommendCluster(provider string, service string, region string
 		return nil, err
 	}
 
-	cheapestNodePoolSet, err := e.getCheapestNodePoolSet(provider, req, layoutDesc, allProducts)
+	cheapestNodePoolSet, attrLayouts, err := e.getCheapestNodePoolSetWithOverhead(provider, service, &req, layoutDesc, allProducts)
+	if err != nil {
+		return nil, err
+	}
+
+	cheapestNodePoolSet, podWarning, err := e.ensurePodCapacity(req, e.nodePoolSelector.MaxPoolNodes(service), cheapestNodePoolSet)
 	if err != nil {
 		return nil, err
 	}
+	if podWarning != nil {
+		warnings = append(warnings, *podWarning)
+	}
+	warnings = append(warnings, pinGpuNodePools(req.Zone, cheapestNodePoolSet)...)
+	if req.Zone == "" {
+		distributeZoneNodes(req.ZoneWeights, cheapestNodePoolSet)
+		if err := checkZoneCoverage(req, cheapestNodePoolSet); err != nil {
+			return nil, err
+		}
+	}
+	warnings = append(warnings, pinnedLayoutWarnings(layoutDesc, cheapestNodePoolSet)...)
+	if req.suggestRemovals {
+		var drainWarnings []Warning
+		cheapestNodePoolSet, drainWarnings = suggestPoolRemovals(layoutDesc, allProducts, cheapestNodePoolSet)
+		warnings = append(warnings, drainWarnings...)
+	}
+	if req.spotOnly {
+		if warning := spotOnlyWarning(req.OnDemandPct, cheapestNodePoolSet); warning != nil {
+			warnings = append(warnings, *warning)
+		}
+	}
+	applyPreemption(provider, cheapestNodePoolSet)
+	if req.IncludeCostConfidence {
+		warnings = append(warnings, e.applyCostConfidence(provider, service, region, cheapestNodePoolSet)...)
+	}
 	if cheapestMaster != nil {
 		cheapestNodePoolSet = append(cheapestNodePoolSet, *cheapestMaster)
 	}
+	warnings = append(warnings, e.deprecations.annotateDeprecations(provider, allProducts, cheapestNodePoolSet)...)
+
+	accuracy := findResponseSum(req.Zone, cheapestNodePoolSet, attrLayouts)
+	accuracy.RecMonthlyPrice = monthlyCost(provider, cheapestNodePoolSet)
+	accuracy.RecYearlyPrice = yearlyCost(provider, cheapestNodePoolSet)
+	updateCostConfidence(cheapestNodePoolSet, &accuracy)
+	warnings = append(warnings, overshootWarnings(req, cheapestNodePoolSet)...)
+	if warning := distinctTypesWarning(req.MaxDistinctTypes, cheapestNodePoolSet); warning != nil {
+		warnings = append(warnings, *warning)
+	}
+
+	resp := &ClusterRecommendationResp{
+		Provider:   provider,
+		Service:    service,
+		Region:     region,
+		Zone:       req.Zone,
+		NodePools:  cheapestNodePoolSet,
+		Accuracy:   accuracy,
+		LayoutHash: layoutHash(cheapestNodePoolSet),
+		Warnings:   warnings,
+		Metadata:   req.Metadata,
+		Resiliency: resiliency,
+	}
+
+	if layoutDesc == nil {
+		resp.ReservationUsage = reservationUsage(req.Reservations, cheapestNodePoolSet)
+		if warning := reservationExpiryWarning(resp.ReservationUsage); warning != nil {
+			resp.Warnings = append(resp.Warnings, *warning)
+		}
+	}
+	if req.IncludeNodeCosts {
+		resp.NodeCosts = e.nodeCosts.estimates(provider, cheapestNodePoolSet)
+	}
+
+	resp.DataFreshness = freshness
+	resp.Snapshot = snapshot
+
+	if e.decisions != nil {
+		e.decisions.Emit(newDecisionEvent(req, layoutDesc, allProducts, resp))
+	}
+
+	return resp, nil
+}
+
+// prepareProducts retrieves the products that may take part in the recommendation, and completes them with
+// the benchmark scores, max pods, gpu types and the adjusted prices
+// The optimization goal of the request is reset if there are no benchmark scores for the provider
+// The products are stored as retrieved if snapshots are enabled, the ID of the snapshot is returned
+func (e *Engine) prepareProducts(provider, service, region string, req *SingleClusterRecommendationReq, layoutDesc []NodePoolDesc) ([]VirtualMachine, *DataFreshness, string, []Warning, error) {
+	allProducts, freshness, err := e.getProducts(provider, service, region, *req, layoutDesc)
+	if err != nil {
+		return nil, nil, "", nil, err
+	}
+	snapshot := e.takeSnapshot(provider, service, region, allProducts)
+
+	normalizeAttributes(provider, allProducts)
+
+	var warnings []Warning
+
+	if req.OptimizeFor == OptimizeForBenchmark && !e.benchmarks.applyBenchmarks(provider, allProducts) {
+		e.log.Warn("no benchmark scores available, optimizing for price per cpu", map[string]interface{}{"provider": provider})
+		req.OptimizeFor = ""
+		warnings = append(warnings, Warning{
+			Code:    WarnOptimizeForIgnored,
+			Message: fmt.Sprintf("optimizeFor ignored, there are no benchmark scores for %s", provider),
+		})
+	}
+
+	e.maxPods.applyMaxPods(provider, allProducts)
+	applyGpuTypes(provider, allProducts)
+	applyInstanceCapabilities(provider, allProducts)
+	applyInstanceFamilies(provider, allProducts)
+	applyNetworkBandwidths(provider, allProducts)
+	if req.Zone == "" {
+		applyZoneWeights(req.ZoneWeights, allProducts)
+	}
+	if warning := excludeUncoveredSpots(e.minSpotCoverage, allProducts); warning != nil {
+		warnings = append(warnings, *warning)
+	}
+	if layoutDesc == nil {
+		// the instance types of an existing layout are launchable in the zone already
+		allProducts = excludeUnlaunchableGpus(req.Zone, allProducts)
+		allProducts = excludeUnreservedGpus(req.Reservations, allProducts)
+		allProducts = e.incompatible.excludeIncompatibleTypes(service, allProducts)
+	}
+	applyLicenseSurcharges(provider, req.NodeImage, allProducts)
+	applyPriceAdjusters(provider, e.priceAdjusters, allProducts)
+	if req.IncludeNodeCosts {
+		if warning := e.applyNodeCosts(provider, allProducts); warning != nil {
+			warnings = append(warnings, *warning)
+		}
+	}
+	if req.AvoidRecentlyInterrupted {
+		if warning := e.applyInterruptionHeat(provider, service, region, req.Zone, allProducts); warning != nil {
+			warnings = append(warnings, *warning)
+		}
+	}
+
+	return allProducts, freshness, snapshot, warnings, nil
+}
+
+// getProducts retrieves the products that may take part in the recommendation, and the freshness of their data
+// The candidate vms of a scale out are reused for a while if the afterburner is enabled
+func (e *Engine) getProducts(provider, service, region string, req SingleClusterRecommendationReq, layoutDesc []NodePoolDesc) ([]VirtualMachine, *DataFreshness, error) {
+	if e.afterburner == nil || layoutDesc == nil || len(req.Includes) == 0 {
+		vms, err := e.fetchProducts(provider, service, region, req, layoutDesc)
+		if err != nil {
+			return nil, nil, err
+		}
+		return vms, e.dataFreshness(provider, service, region), nil
+	}
+
+	fingerprint := layoutFingerprint(provider, service, region, req, layoutDesc)
+	if vms, freshness, ok := e.afterburner.get(fingerprint); ok {
+		e.log.Debug("reusing the candidate vms of the layout", map[string]interface{}{"fingerprint": fingerprint})
+		return vms, freshness, nil
+	}
+
+	vms, err := e.fetchProducts(provider, service, region, req, layoutDesc)
+	if err != nil {
+		return nil, nil, err
+	}
+	vms = candidates(vms, req.Includes)
+	freshness := e.dataFreshness(provider, service, region)
+	e.afterburner.put(fingerprint, vms, freshness)
+
+	return vms, freshness, nil
+}
+
+// dataFreshness gets the freshness of the product data last retrieved from the cloud info source, nil if it's unknown
+func (e *Engine) dataFreshness(provider, service, region string) *DataFreshness {
+	if df, ok := e.ciSource.GetDataFreshness(provider, service, region); ok {
+		return &df
+	}
+	return nil
+}
 
-	accuracy := findResponseSum(req.Zone, cheapestNodePoolSet)
+// fetchProducts retrieves the products from the cloud info source
+// Vms smaller than the per node minimums can't be selected, they are filtered out when retrieving the products,
+// unless the products are needed for the existing layout, the master recommendation or the widened candidate window
+func (e *Engine) fetchProducts(provider, service, region string, req SingleClusterRecommendationReq, layoutDesc []NodePoolDesc) ([]VirtualMachine, error) {
+	filter := ProductFilter{}
+	switch service {
+	case "pke", "ack", "eks", "gke":
+	default:
+		if maxNodes := req.maxNodesBound(); layoutDesc == nil && maxNodes > 0 && req.CandidateWindow.NearestValues == 0 {
+			relax := 1 - float64(req.CandidateWindow.RelaxPct)/100
+			filter.MinCpu = minPerNode(req.SumCpu/float64(maxNodes), req.MinCpuPerNode, req.MaxCpuPerNode) * relax
+			filter.MinMem = minPerNode(req.SumMem/float64(maxNodes), req.MinMemPerNode, req.MaxMemPerNode) * relax
+		}
+	}
 
-	return &ClusterRecommendationResp{
-		Provider:  provider,
-		Service:   service,
-		Region:    region,
-		Zone:      req.Zone,
-		NodePools: cheapestNodePoolSet,
-		Accuracy:  accuracy,
-	}, nil
+	vms, err := e.ciSource.GetProductDetailsFiltered(provider, service, region, filter)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(vms) == 0 && !filter.IsZero() {
+		// the requested resources exceed the largest vms, the closest ones are selected from all the products
+		return e.ciSource.GetProductDetails(provider, service, region)
+	}
+
+	return vms, nil
 }
 
 func (e *Engine) recommendMaster(provider, service string, req SingleClusterRecommendationReq, allProducts []VirtualMachine, layoutDesc []NodePoolDesc) (*NodePool, error) {
@@ -124,7 +422,7 @@ func (e *Engine) recommendMaster(provider, service string, req SingleClusterReco
 			}
 		}
 
-		masterNodePool, err := e.masterNodeRecommendation(provider, req, allProducts)
+		masterNodePool, err := e.masterNodeRecommendation(provider, service, req, allProducts)
 		if err != nil {
 			return nil, err
 		}
@@ -132,7 +430,7 @@ func (e *Engine) recommendMaster(provider, service string, req SingleClusterReco
 		return masterNodePool, nil
 
 	case "ack":
-		masterNodePool, err := e.masterNodeRecommendation(provider, req, allProducts)
+		masterNodePool, err := e.masterNodeRecommendation(provider, service, req, allProducts)
 		if err != nil {
 			return nil, err
 		}
@@ -176,7 +474,7 @@ func (e *Engine) recommendMaster(provider, service string, req SingleClusterReco
 	}
 }
 
-func (e *Engine) masterNodeRecommendation(provider string, req SingleClusterRecommendationReq, allProducts []VirtualMachine) (*NodePool, error) {
+func (e *Engine) masterNodeRecommendation(provider, service string, req SingleClusterRecommendationReq, allProducts []VirtualMachine) (*NodePool, error) {
 	request := SingleClusterRecommendationReq{
 		ClusterRecommendationReq: ClusterRecommendationReq{
 			SumCpu:      2,
@@ -189,7 +487,7 @@ func (e *Engine) masterNodeRecommendation(provider string, req SingleClusterReco
 		Includes: req.Includes,
 	}
 
-	cheapestMaster, err := e.getCheapestNodePoolSet(provider, request, nil, allProducts)
+	cheapestMaster, _, err := e.getCheapestNodePoolSet(provider, service, request, nil, allProducts)
 	if err != nil {
 		return nil, err
 	}
@@ -204,61 +502,98 @@ func (e *Engine) masterNodeRecommendation(provider string, req SingleClusterReco
 	return master, nil
 }
 
-func (e *Engine) getCheapestNodePoolSet(provider string, req SingleClusterRecommendationReq, layoutDesc []NodePoolDesc, allProducts []VirtualMachine) ([]NodePool, error) {
+// getCheapestNodePoolSet recommends node pools for every attribute and selects the cheapest ones, the layouts of the
+// attributes are summarized for the accuracy of the recommendation
+func (e *Engine) getCheapestNodePoolSet(provider, service string, req SingleClusterRecommendationReq, layoutDesc []NodePoolDesc, allProducts []VirtualMachine) ([]NodePool, []AttributeLayout, error) {
+	attributes := []string{Cpu, Memory}
+	nodePools := make(map[string][]NodePool, len(attributes))
+
+	var (
+		g   errgroup.Group
+		mux sync.Mutex
+	)
+	for _, attr := range attributes {
+		attr := attr
+		g.Go(func() error {
+			// every attribute is evaluated on its own copy of the request
+			nps, err := e.recommendAttrNodePools(provider, service, attr, req, layoutDesc, allProducts)
+			if err != nil || nps == nil {
+				return err
+			}
+
+			mux.Lock()
+			defer mux.Unlock()
+			nodePools[attr] = nps
+			return nil
+		})
+	}
+	if err := g.Wait(); err != nil {
+		return nil, nil, err
+	}
+
+	if len(nodePools) == 0 {
+		e.log.Debug(fmt.Sprintf("could not recommend node pools for request: %#v", req))
+		return nil, nil, emperror.With(ErrNoFeasibleLayout, RecommenderErrorTag)
+	}
+
+	for attr, nps := range nodePools {
+		applyPoolWeights(attr, nps)
+	}
+
+	cheapest, layouts := e.findCheapestNodePoolSet(nodePools)
+	return cheapest, layouts, nil
+}
+
+// recommendAttrNodePools recommends node pools for a single attribute, returns nil if there are no suitable node pools for the attribute
+func (e *Engine) recommendAttrNodePools(provider, service, attr string, req SingleClusterRecommendationReq, layoutDesc []NodePoolDesc, allProducts []VirtualMachine) ([]NodePool, error) {
+	req.MinNodes, req.MaxNodes = req.nodeBounds(attr)
+
 	desiredCpu := req.SumCpu
 	desiredMem := req.SumMem
 	desiredOdPct := req.OnDemandPct
 
-	attributes := []string{Cpu, Memory}
-	nodePools := make(map[string][]NodePool, 2)
+	vmsInRange, err := e.vmSelector.FindVmsWithAttrValues(attr, req, layoutDesc, allProducts)
+	if err != nil {
+		return nil, emperror.With(err, RecommenderErrorTag, "vms")
+	}
 
-	for _, attr := range attributes {
-		vmsInRange, err := e.vmSelector.FindVmsWithAttrValues(attr, req, layoutDesc, allProducts)
+	layout := e.transformLayout(layoutDesc, vmsInRange)
+	if layout != nil {
+		req.SumCpu, req.SumMem, req.OnDemandPct, err = e.computeScaleoutResources(layout, attr, desiredCpu, desiredMem, desiredOdPct)
 		if err != nil {
-			return nil, emperror.With(err, RecommenderErrorTag, "vms")
-		}
-
-		layout := e.transformLayout(layoutDesc, vmsInRange)
-		if layout != nil {
-			req.SumCpu, req.SumMem, req.OnDemandPct, err = e.computeScaleoutResources(layout, attr, desiredCpu, desiredMem, desiredOdPct)
-			if err != nil {
-				e.log.Error(emperror.Wrap(err, "failed to compute scaleout resources").Error())
-				continue
-			}
-			if req.SumCpu < 0 && req.SumMem < 0 {
-				return nil, emperror.With(
-					fmt.Errorf("there are enough resources in the cluster already. "+
-						"Total resources available: CPU: %v, Mem: %v",
-						desiredCpu-req.SumCpu, desiredMem-req.SumMem), RecommenderErrorTag)
-			}
+			e.log.Error(emperror.Wrap(err, "failed to compute scaleout resources").Error())
+			return nil, nil
 		}
-
-		odVms, spotVms, err := e.vmSelector.RecommendVms(provider, vmsInRange, attr, req, layout)
-		if err != nil {
-			return nil, emperror.WrapWith(err, "failed to recommend virtual machines", RecommenderErrorTag)
+		if req.spotOnly {
+			// the existing on-demand node pools are reused, only spot nodes are added
+			req.OnDemandPct = 0
 		}
-
-		if (len(odVms) == 0 && req.OnDemandPct > 0) || (len(spotVms) == 0 && req.OnDemandPct < 100) {
-			e.log.Debug("no vms with the requested resources found", map[string]interface{}{"attribute": attr})
-			// skip the nodepool creation, go to the next attr
-			continue
+		if req.SumCpu < 0 && req.SumMem < 0 {
+			return nil, emperror.With(
+				fmt.Errorf("there are enough resources in the cluster already. "+
+					"Total resources available: CPU: %v, Mem: %v",
+					desiredCpu-req.SumCpu, desiredMem-req.SumMem), RecommenderErrorTag)
 		}
-		e.log.Debug("recommended vms", map[string]interface{}{"attribute": attr,
-			"odVmsCount": len(odVms), "odVmsValues": odVms, "spotVmsCount": len(spotVms), "spotVmsValues": spotVms})
-
-		nps := e.nodePoolSelector.RecommendNodePools(attr, req, layout, odVms, spotVms)
-
-		e.log.Debug(fmt.Sprintf("recommended node pools for [%s]: count:[%d] , values: [%#v]", attr, len(nps), nps))
+	}
 
-		nodePools[attr] = nps
+	odVms, spotVms, err := e.vmSelector.RecommendVms(provider, vmsInRange, attr, req, layout)
+	if err != nil {
+		return nil, emperror.WrapWith(err, "failed to recommend virtual machines", RecommenderErrorTag)
 	}
 
-	if len(nodePools) == 0 {
-		e.log.Debug(fmt.Sprintf("could not recommend node pools for request: %#v", req))
-		return nil, emperror.With(errors.New("could not recommend cluster with the requested resources"), RecommenderErrorTag)
+	if (len(odVms) == 0 && req.HasOnDemand()) || (len(spotVms) == 0 && req.OnDemandPct < 100) {
+		e.log.Debug("no vms with the requested resources found", map[string]interface{}{"attribute": attr})
+		// skip the nodepool creation, go to the next attr
+		return nil, nil
 	}
+	e.log.Debug("recommended vms", map[string]interface{}{"attribute": attr,
+		"odVmsCount": len(odVms), "odVmsValues": odVms, "spotVmsCount": len(spotVms), "spotVmsValues": spotVms})
 
-	return e.findCheapestNodePoolSet(nodePools), nil
+	nps := e.nodePoolSelector.RecommendNodePools(service, attr, req, layout, odVms, spotVms)
+
+	e.log.Debug(fmt.Sprintf("recommended node pools for [%s]: count:[%d] , values: [%#v]", attr, len(nps), nps))
+
+	return nps, nil
 }
 
 // RecommendClusterScaleOut performs recommendation for an existing layout's scale out
@@ -282,24 +617,37 @@ func (e *Engine) RecommendClusterScaleOut(provider string, service string, regio
 			SumCpu:        req.DesiredCpu,
 			SumMem:        req.DesiredMem,
 			SumGpu:        req.DesiredGpu,
+			SumPods:       req.DesiredPods,
+			Metadata:      req.Metadata,
 		},
-		Includes: includes,
-		Excludes: req.Excludes,
-		Zone:     req.Zone,
+		Includes:        includes,
+		Excludes:        req.Excludes,
+		Zone:            req.Zone,
+		suggestRemovals: req.SuggestRemovals,
+		spotOnly:        req.SpotOnly,
 	}
 
-	return e.RecommendCluster(provider, service, region, clReq, req.ActualLayout)
+	return e.RecommendCluster(provider, service, region, clReq, splitMixedPools(req.ActualLayout))
 }
 
 // RecommendMultiCluster performs recommendation
 func (e *Engine) RecommendMultiCluster(req MultiClusterRecommendationReq) (map[string][]*ClusterRecommendationResp, error) {
 	respPerService := make(map[string][]*ClusterRecommendationResp)
+	budgeted, budget := e.withCallBudget()
 
 	for _, provider := range req.Providers {
+		if e.checkTenantProvider(provider.Provider) != nil {
+			e.log.Debug("provider not enabled for the tenant", map[string]interface{}{"provider": provider.Provider})
+			continue
+		}
 
 		for _, service := range provider.Services {
 
-			regions, err := e.getRegions(provider.Provider, service, req.Continents)
+			regions, err := budgeted.getRegions(provider.Provider, service, req.Continents)
+			if err != nil {
+				return nil, emperror.With(err, RecommenderErrorTag)
+			}
+			regions, err = budgeted.geoRegions(provider.Provider, service, regions, req.Geo)
 			if err != nil {
 				return nil, emperror.With(err, RecommenderErrorTag)
 			}
@@ -307,7 +655,7 @@ func (e *Engine) RecommendMultiCluster(req MultiClusterRecommendationReq) (map[s
 			var responses []*ClusterRecommendationResp
 			for _, region := range regions {
 
-				if response, err := e.recommendCluster(provider.Provider, service, region, req); err != nil {
+				if response, err := budgeted.recommendCluster(provider.Provider, service, region, req); err != nil {
 
 					return nil, emperror.With(err, RecommenderErrorTag)
 				} else if response != nil {
@@ -324,7 +672,13 @@ func (e *Engine) RecommendMultiCluster(req MultiClusterRecommendationReq) (map[s
 	}
 
 	if len(respPerService) == 0 {
-		return nil, emperror.With(errors.New("could not recommend clusters with the requested resources"), RecommenderErrorTag)
+		return nil, emperror.With(ErrNoFeasibleLayout, RecommenderErrorTag)
+	}
+
+	for _, responses := range respPerService {
+		for _, response := range responses {
+			response.CloudInfoCalls = budget.count()
+		}
 	}
 
 	return respPerService, nil
@@ -348,12 +702,15 @@ func (e *Engine) recommendCluster(provider, service, region string, req MultiClu
 				Includes:                 req.Includes[provider][service],
 				Zone:                     zone,
 			}
-			zoneResp, err := e.RecommendCluster(provider, service, region, request, nil)
+			zoneResp, err := e.recommendSingleCluster(provider, service, region, request, nil)
+			if errors.Cause(err) == ErrCallBudgetExceeded {
+				return nil, err
+			}
 			if err != nil {
 				e.log.Warn("could not recommend cluster")
 				continue
 			}
-			if response == nil || zoneResp.Accuracy.RecTotalPrice < response.Accuracy.RecTotalPrice {
+			if response == nil || zoneResp.Accuracy.RecMonthlyPrice < response.Accuracy.RecMonthlyPrice {
 				response = zoneResp
 			}
 		}
@@ -364,7 +721,10 @@ func (e *Engine) recommendCluster(provider, service, region string, req MultiClu
 			Includes:                 req.Includes[provider][service],
 		}
 
-		response, err = e.RecommendCluster(provider, service, region, request, nil)
+		response, err = e.recommendSingleCluster(provider, service, region, request, nil)
+		if errors.Cause(err) == ErrCallBudgetExceeded {
+			return nil, err
+		}
 		if err != nil {
 			e.log.Warn("could not recommend cluster")
 		}
@@ -396,7 +756,7 @@ func (e *Engine) getLimitedResponses(responses []*ClusterRecommendationResp, res
 	if len(responses) > respPerService {
 		var limit = 0
 		for i := range responses {
-			if responses[respPerService-1].Accuracy.RecTotalPrice < responses[i].Accuracy.RecTotalPrice {
+			if responses[respPerService-1].Accuracy.RecMonthlyPrice < responses[i].Accuracy.RecMonthlyPrice {
 				limit = i
 				break
 			}
@@ -409,22 +769,24 @@ func (e *Engine) getLimitedResponses(responses []*ClusterRecommendationResp, res
 	return responses
 }
 
-// ByPricePerService type for custom sorting of a slice of response
+// ByPricePerService type for custom sorting of a slice of response by the estimated monthly bill
 type ByPricePerService []*ClusterRecommendationResp
 
 func (a ByPricePerService) Len() int      { return len(a) }
 func (a ByPricePerService) Swap(i, j int) { a[i], a[j] = a[j], a[i] }
 func (a ByPricePerService) Less(i, j int) bool {
-	totalPrice1 := a[i].Accuracy.RecTotalPrice
-	totalPrice2 := a[j].Accuracy.RecTotalPrice
-	return totalPrice1 < totalPrice2
+	monthlyPrice1 := a[i].Accuracy.RecMonthlyPrice
+	monthlyPrice2 := a[j].Accuracy.RecMonthlyPrice
+	return monthlyPrice1 < monthlyPrice2
 }
 
 func boolPointer(b bool) *bool {
 	return &b
 }
 
-func findResponseSum(zone string, nodePoolSet []NodePool) ClusterRecommendationAccuracy {
+// findResponseSum sums the resources, nodes and prices of the node pool set, the node pools without nodes are left out
+// The layouts recommended for the attributes are reported along with the attribute the node pool set was selected for
+func findResponseSum(zone string, nodePoolSet []NodePool, attrLayouts []AttributeLayout) ClusterRecommendationAccuracy {
 	var sumCpus float64
 	var sumMem float64
 	var sumWorkerNodes int
@@ -435,15 +797,20 @@ func findResponseSum(zone string, nodePoolSet []NodePool) ClusterRecommendationA
 	var sumWorkerPrice float64
 	var sumMasterPrice float64
 	var sumTotalPrice float64
+	var workerPools int
 	for _, nodePool := range nodePoolSet {
+		if nodePool.SumNodes == 0 {
+			continue
+		}
 		sumCpus += nodePool.GetSum(Cpu)
 		sumMem += nodePool.GetSum(Memory)
 		switch nodePool.Role {
 		case Worker:
+			workerPools++
 			sumWorkerNodes += nodePool.SumNodes
 			sumWorkerPrice += nodePool.PoolPrice()
 
-			if nodePool.VmClass == Regular {
+			if nodePool.VmClass == Regular || nodePool.VmClass == Reserved {
 				sumRegularPrice += nodePool.PoolPrice()
 				sumRegularNodes += nodePool.SumNodes
 			} else {
@@ -469,25 +836,105 @@ func findResponseSum(zone string, nodePoolSet []NodePool) ClusterRecommendationA
 		RecWorkerPrice:  sumWorkerPrice,
 		RecMasterPrice:  sumMasterPrice,
 		RecTotalPrice:   sumTotalPrice,
+		RecNodePools:    workerPools,
+		RecAttribute:    selectedAttribute(attrLayouts),
+		AttrLayouts:     attrLayouts,
+	}
+}
+
+// excludeSpots makes the request on-demand only, the returned warning tells which field of the request is ignored and why
+func (e *Engine) excludeSpots(req *SingleClusterRecommendationReq, code string, reason string) Warning {
+	field := "onDemandPct"
+	if req.OnDemandCount > 0 {
+		field = "onDemandCount"
+	}
+	e.log.Warn("spot instances excluded", map[string]interface{}{"field": field, "reason": reason})
+	req.OnDemandPct = 100
+	req.OnDemandCount = 0
+	req.spotOnly = false
+	return Warning{
+		Code:    code,
+		Message: fmt.Sprintf("%s ignored, %s", field, reason),
+	}
+}
+
+// overshootWarnings warns about worker resources exceeding the requested ones by more than the tolerated percentage
+func overshootWarnings(req SingleClusterRecommendationReq, nodePools []NodePool) []Warning {
+	var warnings []Warning
+	for _, r := range []struct {
+		attr      string
+		requested float64
+	}{{Cpu, req.SumCpu}, {Memory, req.SumMem}} {
+		if r.requested <= 0 {
+			continue
+		}
+		var recommended float64
+		for _, np := range nodePools {
+			if np.Role == Worker {
+				recommended += np.GetSum(r.attr)
+			}
+		}
+		if overshootPct := (recommended - r.requested) / r.requested * 100; overshootPct > overshootTolerancePct {
+			warnings = append(warnings, Warning{
+				Code:    WarnResourceOvershoot,
+				Message: fmt.Sprintf("layout overshoots %s by %.0f%%", r.attr, overshootPct),
+			})
+		}
+	}
+	return warnings
+}
+
+// distinctTypesWarning warns about worker node pools having more distinct instance types than the requested maximum,
+// eg. when the regular and reserved pools use up the types and none of them has a spot price
+func distinctTypesWarning(maxDistinctTypes int, nodePools []NodePool) *Warning {
+	if maxDistinctTypes <= 0 {
+		return nil
+	}
+	types := make(map[string]bool)
+	for _, np := range nodePools {
+		if np.Role == Worker && np.SumNodes > 0 {
+			types[np.VmType.Type] = true
+		}
+	}
+	if len(types) <= maxDistinctTypes {
+		return nil
+	}
+	return &Warning{
+		Code:    WarnDistinctTypesExceeded,
+		Message: fmt.Sprintf("the layout has %d distinct instance types, maxDistinctTypes of %d can't be met", len(types), maxDistinctTypes),
 	}
 }
 
-// findCheapestNodePoolSet looks up the "cheapest" node pool set from the provided map
-func (e *Engine) findCheapestNodePoolSet(nodePoolSets map[string][]NodePool) []NodePool {
+// findCheapestNodePoolSet looks up the "cheapest" node pool set from the provided map, the node pool sets of all
+// the attributes are summarized in the returned layouts
+func (e *Engine) findCheapestNodePoolSet(nodePoolSets map[string][]NodePool) ([]NodePool, []AttributeLayout) {
 	e.log.Info("finding cheapest pool set...")
 	var cheapestNpSet []NodePool
 	var bestPrice float64
+	layouts := make([]AttributeLayout, 0, len(nodePoolSets))
+	selected := -1
+
+	// iterate in a fixed order, so equally priced node pool sets are always selected the same way
+	attrs := make([]string, 0, len(nodePoolSets))
+	for attr := range nodePoolSets {
+		attrs = append(attrs, attr)
+	}
+	sort.Strings(attrs)
 
-	for attr, nodePools := range nodePoolSets {
+	for _, attr := range attrs {
+		nodePools := nodePoolSets[attr]
 		var sumPrice float64
 		var sumCpus float64
 		var sumMem float64
+		var sumNodes int
 
 		for _, np := range nodePools {
 			sumPrice += np.PoolPrice()
 			sumCpus += np.GetSum(Cpu)
 			sumMem += np.GetSum(Memory)
+			sumNodes += np.SumNodes
 		}
+		layouts = append(layouts, AttributeLayout{Attribute: attr, Price: sumPrice, Nodes: sumNodes})
 		e.log.Debug("checking node pool",
 			map[string]interface{}{"attribute": attr, "cpu": sumCpus, "memory": sumMem, "price": sumPrice})
 
@@ -495,9 +942,23 @@ func (e *Engine) findCheapestNodePoolSet(nodePoolSets map[string][]NodePool) []N
 			e.log.Debug("cheaper node pool set is found", map[string]interface{}{"price": sumPrice})
 			bestPrice = sumPrice
 			cheapestNpSet = nodePools
+			selected = len(layouts) - 1
+		}
+	}
+	if selected >= 0 {
+		layouts[selected].Selected = true
+	}
+	return cheapestNpSet, layouts
+}
+
+// selectedAttribute gets the attribute the selected layout was recommended for, empty if unknown
+func selectedAttribute(layouts []AttributeLayout) string {
+	for _, layout := range layouts {
+		if layout.Selected {
+			return layout.Attribute
 		}
 	}
-	return cheapestNpSet
+	return ""
 }
 
 func (e *Engine) transformLayout(layoutDesc []NodePoolDesc, vms []VirtualMachine) []NodePool {
@@ -513,6 +974,7 @@ func (e *Engine) transformLayout(layoutDesc []NodePoolDesc, vms []VirtualMachine
 					VmClass:  npd.GetVmClass(),
 					SumNodes: npd.SumNodes,
 					Role:     Worker,
+					Pinned:   npd.Pinned,
 				}
 				break
 			}
@@ -525,7 +987,8 @@ func (e *Engine) computeScaleoutResources(layout []NodePool, attr string, desire
 	var currentCpuTotal, currentMemTotal, sumCurrentOdCpu, sumCurrentOdMem float64
 	var scaleoutOdPct int
 	for _, np := range layout {
-		if np.VmClass == Regular {
+		// reserved nodes are non-interruptible, they count as on-demand capacity
+		if np.VmClass == Regular || np.VmClass == Reserved {
 			sumCurrentOdCpu += float64(np.SumNodes) * np.VmType.Cpus
 			sumCurrentOdMem += float64(np.SumNodes) * np.VmType.Mem
 		}
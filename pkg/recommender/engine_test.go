@@ -19,6 +19,7 @@ import (
 
 	"github.com/banzaicloud/telescopes/.gen/cloudinfo"
 	"github.com/goph/logur"
+	"github.com/pkg/errors"
 	"github.com/stretchr/testify/assert"
 )
 
@@ -35,6 +36,10 @@ func (p *dummyProducts) GetRegion(provider string, service string, region string
 	panic("implement me")
 }
 
+func (p *dummyProducts) GetProviders() ([]cloudinfo.Provider, error) {
+	panic("implement me")
+}
+
 func (p *dummyProducts) GetProvider(provider string) (string, error) {
 	panic("implement me")
 }
@@ -62,6 +67,22 @@ func (p *dummyProducts) GetProductDetails(provider string, service string, regio
 	}, nil
 }
 
+func (p *dummyProducts) GetProductDetailsFiltered(provider string, service string, region string, filter ProductFilter) ([]VirtualMachine, error) {
+	vms, err := p.GetProductDetails(provider, service, region)
+	if err != nil {
+		return nil, err
+	}
+	return filter.Apply(vms), nil
+}
+
+func (p *dummyProducts) GetDataFreshness(provider string, service string, region string) (DataFreshness, bool) {
+	return DataFreshness{}, false
+}
+
+func (p *dummyProducts) GetAllDataFreshness() []DataFreshness {
+	return nil
+}
+
 func (p *dummyProducts) GetRegions(provider, service string) ([]cloudinfo.Region, error) {
 	return nil, nil
 }
@@ -104,12 +125,20 @@ func (v *dummyVms) FindVmsWithAttrValues(attr string, req SingleClusterRecommend
 	return nil, nil
 }
 
+func (v *dummyVms) RejectingFilters(provider string, vm VirtualMachine, attr string, req SingleClusterRecommendationReq) ([]string, error) {
+	return nil, nil
+}
+
 type dummyNodePools struct {
 	// test case id to drive the behaviour
 	TcId string
 }
 
-func (nps *dummyNodePools) RecommendNodePools(attr string, req SingleClusterRecommendationReq, layout []NodePool, odVms []VirtualMachine, spotVms []VirtualMachine) []NodePool {
+func (nps *dummyNodePools) MaxPoolNodes(service string) int {
+	return 0
+}
+
+func (nps *dummyNodePools) RecommendNodePools(service string, attr string, req SingleClusterRecommendationReq, layout []NodePool, odVms []VirtualMachine, spotVms []VirtualMachine) []NodePool {
 	return []NodePool{
 		{ // price = 2*3 +2*2 = 10
 			VmType: VirtualMachine{
@@ -192,6 +221,44 @@ func TestEngine_RecommendCluster(t *testing.T) {
 				assert.Equal(t, float64(16), resp.Accuracy.RecCpu)
 			},
 		},
+		{
+			name: "cluster recommendation fails when min nodes is greater than max nodes",
+			vms:  &dummyVms{},
+			np:   &dummyNodePools{},
+			request: SingleClusterRecommendationReq{
+				ClusterRecommendationReq: ClusterRecommendationReq{
+					MinNodes: 3,
+					MaxNodes: 1,
+					SumMem:   32,
+					SumCpu:   16,
+				},
+			},
+			ciSource: &dummyProducts{},
+			check: func(resp *ClusterRecommendationResp, err error) {
+				assert.Nil(t, resp, "the response should be nil")
+				assert.Equal(t, ErrMinGreaterThanMax, errors.Cause(err))
+			},
+		},
+		{
+			name: "cluster recommendation fails when both on-demand count and percentage are requested",
+			vms:  &dummyVms{},
+			np:   &dummyNodePools{},
+			request: SingleClusterRecommendationReq{
+				ClusterRecommendationReq: ClusterRecommendationReq{
+					MinNodes:      1,
+					MaxNodes:      5,
+					SumMem:        32,
+					SumCpu:        16,
+					OnDemandPct:   30,
+					OnDemandCount: 2,
+				},
+			},
+			ciSource: &dummyProducts{},
+			check: func(resp *ClusterRecommendationResp, err error) {
+				assert.Nil(t, resp, "the response should be nil")
+				assert.Equal(t, ErrOnDemandCountConflict, errors.Cause(err))
+			},
+		},
 	}
 	for _, test := range tests {
 		test := test
@@ -209,7 +276,7 @@ func TestEngine_findCheapestNodePoolSet(t *testing.T) {
 		vms       VmRecommender
 		np        NodePoolRecommender
 		nodePools map[string][]NodePool
-		check     func(nps []NodePool)
+		check     func(nps []NodePool, layouts []AttributeLayout)
 	}{
 		{
 			name: "find cheapest node pool set",
@@ -258,8 +325,12 @@ func TestEngine_findCheapestNodePoolSet(t *testing.T) {
 					},
 				},
 			},
-			check: func(nps []NodePool) {
+			check: func(nps []NodePool, layouts []AttributeLayout) {
 				assert.Equal(t, 3, len(nps), "wrong selection")
+				assert.Equal(t, []AttributeLayout{
+					{Attribute: Cpu, Price: 8, Nodes: 4, Selected: true},
+					{Attribute: Memory, Price: 10, Nodes: 4},
+				}, layouts)
 			},
 		},
 	}
@@ -271,3 +342,307 @@ func TestEngine_findCheapestNodePoolSet(t *testing.T) {
 		})
 	}
 }
+
+func TestEngine_RecommendClusterSpotDisabled(t *testing.T) {
+	req := SingleClusterRecommendationReq{
+		ClusterRecommendationReq: ClusterRecommendationReq{
+			MinNodes:    1,
+			MaxNodes:    1,
+			SumMem:      32,
+			SumCpu:      16,
+			OnDemandPct: 50,
+		},
+	}
+	vms := &onDemandPctVms{}
+	engine := NewEngine(logur.NewTestLogger(), &dummyProducts{}, vms, &dummyNodePools{}, WithSpotDisabled("amazon"))
+
+	resp, err := engine.RecommendCluster("amazon", "compute", "eu-west-1", req, nil)
+	if !assert.Nil(t, err, "the error should be nil") {
+		return
+	}
+	assert.Contains(t, warningCodes(resp.Warnings), WarnSpotDisabled)
+	assert.Equal(t, 100, vms.onDemandPct, "spot vms should be excluded")
+
+	req.OnDemandPct = 100
+	resp, err = engine.RecommendCluster("amazon", "compute", "eu-west-1", req, nil)
+	if !assert.Nil(t, err, "the error should be nil") {
+		return
+	}
+	assert.NotContains(t, warningCodes(resp.Warnings), WarnSpotDisabled, "on-demand requests should not be warned")
+
+	req.OnDemandPct = 50
+	resp, err = engine.RecommendCluster("google", "compute", "europe-west1", req, nil)
+	if !assert.Nil(t, err, "the error should be nil") {
+		return
+	}
+	assert.NotContains(t, warningCodes(resp.Warnings), WarnSpotDisabled)
+	assert.Equal(t, 50, vms.onDemandPct)
+}
+
+// freshProducts reports the freshness of the dummy products
+type freshProducts struct {
+	dummyProducts
+}
+
+func (p *freshProducts) GetDataFreshness(provider string, service string, region string) (DataFreshness, bool) {
+	return DataFreshness{Provider: provider, Service: service, Region: region}, true
+}
+
+func TestEngine_RecommendClusterCatalogVersion(t *testing.T) {
+	req := SingleClusterRecommendationReq{
+		ClusterRecommendationReq: ClusterRecommendationReq{MinNodes: 1, MaxNodes: 1, SumMem: 32, SumCpu: 16},
+	}
+	watcher := NewCatalogWatcher(&freshProducts{}, 10, logur.NewTestLogger())
+	engine := NewEngine(logur.NewTestLogger(), watcher, &dummyVms{}, &dummyNodePools{})
+
+	resp, err := engine.RecommendCluster("dummyProvider", "dummyService", "dummyRegion", req, nil)
+	if !assert.Nil(t, err, "the error should be nil") || !assert.NotNil(t, resp.DataFreshness) {
+		return
+	}
+	assert.Empty(t, resp.DataFreshness.CatalogVersion, "the version should be omitted until the region is retrieved in full")
+
+	// the region is renewed by the watcher
+	if _, err := watcher.GetProductDetails("dummyProvider", "dummyService", "dummyRegion"); !assert.Nil(t, err) {
+		return
+	}
+	checksum := watcher.snapshots[freshnessKey("dummyProvider", "dummyService", "dummyRegion")].checksum
+
+	for _, sumCpu := range []float64{16, 4} {
+		req.SumCpu = sumCpu
+		resp, err = engine.RecommendCluster("dummyProvider", "dummyService", "dummyRegion", req, nil)
+		if !assert.Nil(t, err, "the error should be nil") || !assert.NotNil(t, resp.DataFreshness) {
+			return
+		}
+		assert.Equal(t, checksum, resp.DataFreshness.CatalogVersion, "the requests should report the version of the whole catalog")
+	}
+}
+
+func Test_findResponseSum(t *testing.T) {
+	layouts := []AttributeLayout{
+		{Attribute: Cpu, Price: 0.6, Nodes: 3},
+		{Attribute: Memory, Price: 0.5, Nodes: 3, Selected: true},
+	}
+	nodePools := []NodePool{
+		{VmType: VirtualMachine{Cpus: 4, Mem: 32, OnDemandPrice: 0.2}, SumNodes: 2, VmClass: Regular, Role: Worker},
+		{VmType: VirtualMachine{Cpus: 2, Mem: 16, AvgPrice: 0.1}, SumNodes: 1, VmClass: Spot, Role: Worker},
+		{VmType: VirtualMachine{Cpus: 8, Mem: 64, AvgPrice: 0.3}, SumNodes: 0, VmClass: Spot, Role: Worker},
+		{VmType: VirtualMachine{Cpus: 2, Mem: 4, OnDemandPrice: 0.05}, SumNodes: 1, VmClass: Regular, Role: Master},
+	}
+
+	acc := findResponseSum("", nodePools, layouts)
+	assert.Equal(t, 3, acc.RecNodes)
+	assert.Equal(t, 2, acc.RecNodePools, "empty node pools should not be counted")
+	assert.Equal(t, 12.0, acc.RecCpu)
+	assert.InDelta(t, 0.5, acc.RecWorkerPrice, 1e-9)
+	assert.InDelta(t, 0.55, acc.RecTotalPrice, 1e-9)
+	assert.Equal(t, Memory, acc.RecAttribute)
+	assert.Equal(t, layouts, acc.AttrLayouts)
+}
+
+func Test_overshootWarnings(t *testing.T) {
+	tests := []struct {
+		name      string
+		request   SingleClusterRecommendationReq
+		nodePools []NodePool
+		check     func(warnings []Warning)
+	}{
+		{
+			name: "recommended resources within tolerance",
+			request: SingleClusterRecommendationReq{
+				ClusterRecommendationReq: ClusterRecommendationReq{SumCpu: 16, SumMem: 40},
+			},
+			nodePools: []NodePool{
+				{VmType: VirtualMachine{Cpus: 16, Mem: 42}, SumNodes: 1, Role: Worker},
+				{VmType: VirtualMachine{Cpus: 2, Mem: 4}, SumNodes: 1, Role: Master},
+			},
+			check: func(warnings []Warning) {
+				assert.Empty(t, warnings)
+			},
+		},
+		{
+			name: "recommended memory overshoots",
+			request: SingleClusterRecommendationReq{
+				ClusterRecommendationReq: ClusterRecommendationReq{SumCpu: 16, SumMem: 32},
+			},
+			nodePools: []NodePool{
+				{VmType: VirtualMachine{Cpus: 16, Mem: 42}, SumNodes: 1, Role: Worker},
+			},
+			check: func(warnings []Warning) {
+				assert.Equal(t, 1, len(warnings))
+				assert.Equal(t, WarnResourceOvershoot, warnings[0].Code)
+				assert.Equal(t, "layout overshoots memory by 31%", warnings[0].Message)
+			},
+		},
+	}
+	for _, test := range tests {
+		test := test
+		t.Run(test.name, func(t *testing.T) {
+			test.check(overshootWarnings(test.request, test.nodePools))
+		})
+	}
+}
+
+func TestEngine_computeScaleoutResources(t *testing.T) {
+	tests := []struct {
+		name   string
+		layout []NodePool
+		check  func(cpu, mem float64, odPct int, err error)
+	}{
+		{
+			name: "reserved nodes count as on-demand capacity",
+			layout: []NodePool{
+				{VmType: VirtualMachine{Cpus: 4, Mem: 16}, SumNodes: 2, VmClass: Reserved},
+				{VmType: VirtualMachine{Cpus: 4, Mem: 16}, SumNodes: 2, VmClass: Spot},
+			},
+			check: func(cpu, mem float64, odPct int, err error) {
+				assert.Nil(t, err)
+				assert.Equal(t, float64(16), cpu)
+				assert.Equal(t, float64(64), mem)
+				// desired on-demand cpu is 16, 8 of it is already reserved
+				assert.Equal(t, 50, odPct)
+			},
+		},
+	}
+	for _, test := range tests {
+		test := test
+		t.Run(test.name, func(t *testing.T) {
+			engine := NewEngine(logur.NewTestLogger(), nil, nil, nil)
+			test.check(engine.computeScaleoutResources(test.layout, Cpu, 32, 128, 50))
+		})
+	}
+}
+
+func Test_distinctTypesWarning(t *testing.T) {
+	nodePools := []NodePool{
+		{VmType: VirtualMachine{Type: "m5.xlarge"}, SumNodes: 2, VmClass: Regular, Role: Worker},
+		{VmType: VirtualMachine{Type: "c5.xlarge"}, SumNodes: 3, VmClass: Spot, Role: Worker},
+		{VmType: VirtualMachine{Type: "r5.xlarge"}, SumNodes: 0, VmClass: Spot, Role: Worker},
+		{VmType: VirtualMachine{Type: "t3.large"}, SumNodes: 1, VmClass: Regular, Role: Master},
+	}
+
+	assert.Nil(t, distinctTypesWarning(0, nodePools), "no limit")
+	assert.Nil(t, distinctTypesWarning(2, nodePools), "empty and master pools should not count")
+	warning := distinctTypesWarning(1, nodePools)
+	if !assert.NotNil(t, warning) {
+		return
+	}
+	assert.Equal(t, WarnDistinctTypesExceeded, warning.Code)
+}
+
+func TestEngine_excludeSpots(t *testing.T) {
+	engine := NewEngine(logur.NewTestLogger(), nil, nil, nil)
+
+	req := SingleClusterRecommendationReq{ClusterRecommendationReq: ClusterRecommendationReq{OnDemandPct: 30}, spotOnly: true}
+	warning := engine.excludeSpots(&req, WarnSpotDisabled, "spot instances of amazon are disabled")
+	assert.Equal(t, Warning{Code: WarnSpotDisabled, Message: "onDemandPct ignored, spot instances of amazon are disabled"}, warning)
+	assert.Equal(t, 100, req.OnDemandPct)
+	assert.False(t, req.spotOnly)
+
+	req = SingleClusterRecommendationReq{ClusterRecommendationReq: ClusterRecommendationReq{OnDemandCount: 2}}
+	warning = engine.excludeSpots(&req, WarnOnDemandPctIgnored, "there are no spot prices")
+	assert.Equal(t, "onDemandCount ignored, there are no spot prices", warning.Message)
+	assert.Equal(t, 0, req.OnDemandCount)
+	assert.Equal(t, 100, req.OnDemandPct)
+}
+
+func TestEngine_ensurePodCapacity(t *testing.T) {
+	tests := []struct {
+		name         string
+		req          SingleClusterRecommendationReq
+		maxPoolNodes int
+		nodePools    []NodePool
+		check        func(nps []NodePool, warning *Warning, err error)
+	}{
+		{
+			name: "enough pod capacity",
+			req:  SingleClusterRecommendationReq{ClusterRecommendationReq: ClusterRecommendationReq{SumPods: 50}},
+			nodePools: []NodePool{
+				{VmType: VirtualMachine{OnDemandPrice: 1, MaxPods: 29}, SumNodes: 2, VmClass: Regular, Role: Worker},
+			},
+			check: func(nps []NodePool, warning *Warning, err error) {
+				assert.Nil(t, err)
+				assert.Nil(t, warning)
+				assert.Equal(t, 2, nps[0].SumNodes)
+			},
+		},
+		{
+			name: "reserved pools count towards the pod capacity",
+			req:  SingleClusterRecommendationReq{ClusterRecommendationReq: ClusterRecommendationReq{SumPods: 100}},
+			nodePools: []NodePool{
+				{VmType: VirtualMachine{OnDemandPrice: 1, MaxPods: 29}, SumNodes: 2, VmClass: Regular, Role: Worker},
+				{VmType: VirtualMachine{AvgPrice: 0.5, MaxPods: 17}, SumNodes: 1, VmClass: Spot, Role: Worker},
+				{VmType: VirtualMachine{MaxPods: 58}, SumNodes: 1, VmClass: Reserved, Role: Worker},
+			},
+			check: func(nps []NodePool, warning *Warning, err error) {
+				// the capacity is 58 + 17 + 58 pods
+				assert.Nil(t, err)
+				assert.Nil(t, warning)
+				assert.Equal(t, 1, nps[1].SumNodes)
+			},
+		},
+		{
+			name: "cheapest pool per pod is grown",
+			req:  SingleClusterRecommendationReq{ClusterRecommendationReq: ClusterRecommendationReq{SumPods: 200}},
+			nodePools: []NodePool{
+				{VmType: VirtualMachine{OnDemandPrice: 1, MaxPods: 29}, SumNodes: 2, VmClass: Regular, Role: Worker},
+				{VmType: VirtualMachine{AvgPrice: 0.5, MaxPods: 17}, SumNodes: 1, VmClass: Spot, Role: Worker},
+			},
+			check: func(nps []NodePool, warning *Warning, err error) {
+				assert.Nil(t, err)
+				assert.NotNil(t, warning)
+				assert.Equal(t, WarnPodCapacityAdjusted, warning.Code)
+				assert.Equal(t, 2, nps[0].SumNodes)
+				// 200 - 75 = 125 pods on 17 pod nodes
+				assert.Equal(t, 9, nps[1].SumNodes)
+			},
+		},
+		{
+			name: "on-demand share is kept",
+			req:  SingleClusterRecommendationReq{ClusterRecommendationReq: ClusterRecommendationReq{OnDemandPct: 50, MaxNodes: 10, SumPods: 60}},
+			nodePools: []NodePool{
+				{VmType: VirtualMachine{OnDemandPrice: 1, MaxPods: 10}, SumNodes: 1, VmClass: Regular, Role: Worker},
+				{VmType: VirtualMachine{AvgPrice: 0.5, MaxPods: 10}, SumNodes: 1, VmClass: Spot, Role: Worker},
+			},
+			check: func(nps []NodePool, warning *Warning, err error) {
+				assert.Nil(t, err)
+				assert.NotNil(t, warning)
+				assert.Equal(t, 3, nps[0].SumNodes)
+				assert.Equal(t, 3, nps[1].SumNodes)
+			},
+		},
+		{
+			name: "pods not fitting into the maximum number of nodes",
+			req:  SingleClusterRecommendationReq{ClusterRecommendationReq: ClusterRecommendationReq{OnDemandPct: 50, MaxNodes: 5, SumPods: 500}},
+			nodePools: []NodePool{
+				{VmType: VirtualMachine{OnDemandPrice: 1, MaxPods: 10}, SumNodes: 1, VmClass: Regular, Role: Worker},
+				{VmType: VirtualMachine{AvgPrice: 0.5, MaxPods: 10}, SumNodes: 1, VmClass: Spot, Role: Worker},
+			},
+			check: func(nps []NodePool, warning *Warning, err error) {
+				assert.Equal(t, ErrPodsDontFit, errors.Cause(err))
+			},
+		},
+		{
+			name:         "pools at the maximum size of the service are split",
+			req:          SingleClusterRecommendationReq{ClusterRecommendationReq: ClusterRecommendationReq{SumPods: 50}},
+			maxPoolNodes: 3,
+			nodePools: []NodePool{
+				{VmType: VirtualMachine{AvgPrice: 0.5, MaxPods: 10}, SumNodes: 1, VmClass: Spot, Role: Worker},
+			},
+			check: func(nps []NodePool, warning *Warning, err error) {
+				assert.Nil(t, err)
+				if !assert.Len(t, nps, 2) {
+					return
+				}
+				assert.Equal(t, 3, nps[0].SumNodes)
+				assert.Equal(t, 2, nps[1].SumNodes)
+			},
+		},
+	}
+	for _, test := range tests {
+		test := test
+		t.Run(test.name, func(t *testing.T) {
+			engine := NewEngine(logur.NewTestLogger(), nil, nil, nil)
+			test.check(engine.ensurePodCapacity(test.req, test.maxPoolNodes, test.nodePools))
+		})
+	}
+}
@@ -0,0 +1,111 @@
+// Copyright © 2019 Banzai Cloud
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package recommender
+
+import "github.com/pkg/errors"
+
+// Domain errors returned by the recommender.
+// The returned errors carry context (tags, key-value pairs), compare errors.Cause(err) to these values to identify them.
+// The context is added by emperror and pkg/errors, their wrappers don't implement Unwrap, so errors.Is and errors.As
+// of the standard library don't find these values in the returned errors.
+var (
+	// ErrUnsupportedAttribute is returned when the recommendation is requested for an attribute other than cpu or memory
+	ErrUnsupportedAttribute = errors.New("unsupported attribute")
+
+	// ErrNoFeasibleLayout is returned when no node pool layout satisfies the requested resources
+	ErrNoFeasibleLayout = errors.New("could not recommend cluster with the requested resources")
+
+	// ErrMinGreaterThanMax is returned when the requested minimum number of nodes exceeds the maximum
+	ErrMinGreaterThanMax = errors.New("minimum number of nodes is greater than the maximum")
+
+	// ErrProfileNotFound is returned when the referenced recommendation profile doesn't exist
+	ErrProfileNotFound = errors.New("recommendation profile not found")
+
+	// ErrInvalidRegionShares is returned when the regions of a multi-region request are not distinct or their shares don't add up to 100%
+	ErrInvalidRegionShares = errors.New("region shares must be distinct regions adding up to 100%")
+
+	// ErrOnDemandCountConflict is returned when both the on-demand percentage and the on-demand count are requested
+	ErrOnDemandCountConflict = errors.New("onDemandCount and onDemandPct are mutually exclusive")
+
+	// ErrOnDemandCountExceedsMax is returned when the requested number of on-demand nodes exceeds the maximum number of nodes
+	ErrOnDemandCountExceedsMax = errors.New("onDemandCount is greater than the maximum number of nodes")
+
+	// ErrInvalidNodeBounds is returned when a node count bound of an attribute is negative
+	ErrInvalidNodeBounds = errors.New("node count bounds must not be negative")
+
+	// ErrUnsupportedBoundsAttribute is returned when node count bounds are requested for an attribute other than cpu or
+	// memory; the node pools are recommended by these two attributes only, there are no separate gpu node pools to bound
+	ErrUnsupportedBoundsAttribute = errors.New("node count bounds are supported for the cpu and memory attributes only, gpu nodes aren't recommended in separate node pools")
+
+	// ErrMinPerNodeGreaterThanMax is returned when the requested minimum value of an attribute per node exceeds the maximum
+	ErrMinPerNodeGreaterThanMax = errors.New("minimum value per node is greater than the maximum")
+
+	// ErrInvalidZoneWeights is returned when a zone weight is negative or none of the weights is positive
+	ErrInvalidZoneWeights = errors.New("zone weights must not be negative and at least one must be positive")
+
+	// ErrInvalidRequireAllZones is returned when the zone coverage is required without the zone weights of a multi-zone recommendation
+	ErrInvalidRequireAllZones = errors.New("requireAllZones needs zoneWeights and a multi-zone recommendation")
+
+	// ErrPodsDontFit is returned when the requested pods can't be scheduled on the maximum number of nodes
+	ErrPodsDontFit = errors.New("the requested pods don't fit into the maximum number of nodes")
+
+	// ErrZonesNotCovered is returned when the worker nodes can't be placed in every requested zone, but all of them are required
+	ErrZonesNotCovered = errors.New("capacity can't be placed in every requested zone")
+
+	// ErrInvalidZoneNodes is returned when a zone node count of a layout is negative or the layout has no zones
+	ErrInvalidZoneNodes = errors.New("zone node counts must not be negative and the layout must have zones")
+
+	// ErrUnknownCanaryStrategy is returned when the experimental strategy of the canary evaluation doesn't exist
+	ErrUnknownCanaryStrategy = errors.New("unknown canary strategy")
+
+	// ErrSnapshotNotFound is returned when the referenced product snapshot doesn't exist (anymore)
+	ErrSnapshotNotFound = errors.New("product snapshot not found")
+
+	// ErrCallBudgetExceeded is returned when a recommendation needs more calls to the cloud info service than the configured budget
+	ErrCallBudgetExceeded = errors.New("the recommendation exceeded its budget of cloud info calls")
+
+	// ErrPriceHistoryUnavailable is returned when a backtest is requested, but no spot price history is configured
+	ErrPriceHistoryUnavailable = errors.New("spot price history is not available")
+
+	// ErrInvalidBacktestWindow is returned when the backtest window is empty or has too many samples
+	ErrInvalidBacktestWindow = errors.New("backtest window must end after its start and have at most 11000 samples")
+
+	// ErrUnknownInstanceType is returned when an instance type of the layout is not offered in the region
+	ErrUnknownInstanceType = errors.New("unknown instance type")
+
+	// ErrProviderNotEnabled is returned when a recommendation is requested for a provider not enabled for the tenant
+	ErrProviderNotEnabled = errors.New("provider is not enabled for the tenant")
+
+	// ErrUnknownTenant is returned when the token of an API call doesn't belong to any tenant
+	ErrUnknownTenant = errors.New("unknown tenant")
+
+	// ErrAdminTokenRequired is returned when an admin endpoint of a multi-tenant API is called without an admin token
+	ErrAdminTokenRequired = errors.New("the call needs an admin token")
+
+	// ErrRegionNotAllowed is returned when a requested region doesn't satisfy the geo constraint of the request
+	ErrRegionNotAllowed = errors.New("region doesn't satisfy the geo constraint")
+
+	// ErrUnknownPriority is returned when the priority class of a recommendation is neither interactive nor batch
+	ErrUnknownPriority = errors.New("unknown priority class")
+
+	// ErrQueueFull is returned when too many recommendations of the priority class are waiting to be served
+	ErrQueueFull = errors.New("too many recommendations are waiting to be served")
+
+	// ErrUnknownOperation is returned when a fault is injected into a cloud info operation that doesn't exist
+	ErrUnknownOperation = errors.New("unknown cloud info operation")
+
+	// ErrInjectedFault is returned by the cloud info calls failed by an injected fault
+	ErrInjectedFault = errors.New("injected cloud info failure")
+)
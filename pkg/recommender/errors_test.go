@@ -0,0 +1,58 @@
+// Copyright © 2019 Banzai Cloud
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+
+package recommender
+
+import (
+	"testing"
+
+	"github.com/goph/emperror"
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDomainErrors_cause(t *testing.T) {
+	tests := []struct {
+		name     string
+		err      error
+		expected error
+	}{
+		{
+			name:     "error with context",
+			err:      validateAttributeNodes(ClusterRecommendationReq{MinNodes: 1, MaxNodes: 10, AttributeNodes: map[string]NodeBounds{Cpu: {MinNodes: 20}}}),
+			expected: ErrMinGreaterThanMax,
+		},
+		{
+			name:     "error with context wrapped again",
+			err:      emperror.WrapWith(emperror.With(ErrNoFeasibleLayout, RecommenderErrorTag), "failed to recommend cluster", "region", "eu-west-1"),
+			expected: ErrNoFeasibleLayout,
+		},
+		{
+			name:     "error with a message",
+			err:      errors.WithMessage(emperror.With(ErrUnsupportedAttribute, "attribute", "gpu"), "invalid request"),
+			expected: ErrUnsupportedAttribute,
+		},
+	}
+	for _, test := range tests {
+		test := test
+		t.Run(test.name, func(t *testing.T) {
+			if !assert.Error(t, test.err) {
+				return
+			}
+			assert.NotEqual(t, test.expected, test.err, "the domain error should carry context")
+			assert.Equal(t, test.expected, errors.Cause(test.err))
+		})
+	}
+}
@@ -0,0 +1,65 @@
+// Copyright © 2019 Banzai Cloud
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package recommender
+
+import (
+	"encoding/json"
+	"strings"
+	"unicode"
+)
+
+// InstanceFamilies lists instance families (eg. m5), decoded from a single family name or a list of them
+type InstanceFamilies []string
+
+// UnmarshalJSON decodes a single family name or a list of family names
+func (f *InstanceFamilies) UnmarshalJSON(data []byte) error {
+	var family string
+	if err := json.Unmarshal(data, &family); err == nil {
+		*f = InstanceFamilies{family}
+		return nil
+	}
+
+	var families []string
+	if err := json.Unmarshal(data, &families); err != nil {
+		return err
+	}
+	*f = families
+	return nil
+}
+
+// Contains checks whether the family belongs to the family tree of any of the families: the family itself and its
+// variants, marked by letter suffixes (eg. m5a, m5d and m5dn belong to m5)
+func (f InstanceFamilies) Contains(family string) bool {
+	if family == "" {
+		return false
+	}
+	for _, tree := range f {
+		if !strings.HasPrefix(family, tree) {
+			continue
+		}
+		variant := strings.TrimPrefix(family, tree)
+		if strings.IndexFunc(variant, func(r rune) bool { return !unicode.IsLetter(r) }) == -1 {
+			return true
+		}
+	}
+	return false
+}
+
+// applyInstanceFamilies sets the family of the given vms, if it's implied by the type name
+func applyInstanceFamilies(provider string, vms []VirtualMachine) {
+	for i := range vms {
+		vms[i].Family = instanceFamily(provider, vms[i].Type)
+	}
+}
@@ -0,0 +1,65 @@
+// Copyright © 2019 Banzai Cloud
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package recommender
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestInstanceFamilies_UnmarshalJSON(t *testing.T) {
+	var req ClusterRecommendationReq
+	if assert.NoError(t, json.Unmarshal([]byte(`{"family": "m5"}`), &req)) {
+		assert.Equal(t, InstanceFamilies{"m5"}, req.Family)
+	}
+	if assert.NoError(t, json.Unmarshal([]byte(`{"family": ["m5", "c5"]}`), &req)) {
+		assert.Equal(t, InstanceFamilies{"m5", "c5"}, req.Family)
+	}
+	assert.Error(t, json.Unmarshal([]byte(`{"family": 5}`), &req))
+}
+
+func TestInstanceFamilies_Contains(t *testing.T) {
+	families := InstanceFamilies{"m5", "n2"}
+
+	assert.True(t, families.Contains("m5"))
+	assert.True(t, families.Contains("m5ad"), "variants should belong to the family tree")
+	assert.True(t, families.Contains("n2d"))
+	assert.False(t, families.Contains("m50"), "other generations should not belong to the family tree")
+	assert.False(t, families.Contains("m6i"))
+	assert.False(t, families.Contains(""))
+}
+
+func TestVirtualMachine_Generation(t *testing.T) {
+	tests := []struct {
+		family     string
+		generation int
+	}{
+		{family: "m5", generation: 5},
+		{family: "m5dn", generation: 5},
+		{family: "m6i", generation: 6},
+		{family: "n2d", generation: 2},
+		{family: "x1e", generation: 1},
+		{family: "", generation: 0},
+	}
+	for _, test := range tests {
+		test := test
+		t.Run(test.family, func(t *testing.T) {
+			vm := VirtualMachine{Family: test.family}
+			assert.Equal(t, test.generation, vm.Generation())
+		})
+	}
+}
@@ -0,0 +1,216 @@
+// Copyright © 2019 Banzai Cloud
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package recommender
+
+import (
+	"math/rand"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/banzaicloud/telescopes/.gen/cloudinfo"
+	"github.com/goph/emperror"
+)
+
+// Cloud info operations faults can be injected into
+const (
+	ProductsOperation   = "products"
+	RegionsOperation    = "regions"
+	ZonesOperation      = "zones"
+	ContinentsOperation = "continents"
+	ProviderOperation   = "provider"
+	ServiceOperation    = "service"
+	RegionOperation     = "region"
+)
+
+var faultOperations = map[string]bool{
+	ProductsOperation:   true,
+	RegionsOperation:    true,
+	ZonesOperation:      true,
+	ContinentsOperation: true,
+	ProviderOperation:   true,
+	ServiceOperation:    true,
+	RegionOperation:     true,
+}
+
+// Fault is an artificial latency and/or failure injected into the cloud info calls of an operation and provider
+// swagger:model fault
+type Fault struct {
+	// Cloud info operation (products, regions, zones, continents, provider, service or region), all operations if empty
+	Operation string `json:"operation,omitempty"`
+	// Provider the fault is injected for, all providers if empty
+	Provider string `json:"provider,omitempty"`
+	// Latency added to the calls, in milliseconds
+	LatencyMs int `json:"latencyMs" binding:"min=0"`
+	// Percentage of the calls failing
+	FailurePct float64 `json:"failurePct" binding:"min=0,max=100"`
+}
+
+// FaultInjector is a CloudInfoSource injecting artificial latencies and failures into the calls of the source, so
+// the timeouts and the failure handling can be tested without tampering with the network
+// The most specific fault matching a call is injected: the fault of the operation and the provider, then the fault of
+// the operation, then the fault of the provider, and finally the fault of all calls
+type FaultInjector struct {
+	CloudInfoSource
+
+	mux    sync.RWMutex
+	faults map[[2]string]Fault
+}
+
+// NewFaultInjector creates a fault injector over the source, without any faults
+func NewFaultInjector(source CloudInfoSource) *FaultInjector {
+	return &FaultInjector{
+		CloudInfoSource: source,
+		faults:          make(map[[2]string]Fault),
+	}
+}
+
+// SetFault injects the fault, replacing the one injected for the same operation and provider
+func (fi *FaultInjector) SetFault(fault Fault) error {
+	if fault.Operation != "" && !faultOperations[fault.Operation] {
+		return emperror.With(ErrUnknownOperation, "operation", fault.Operation)
+	}
+
+	fi.mux.Lock()
+	defer fi.mux.Unlock()
+
+	fi.faults[[2]string{fault.Operation, fault.Provider}] = fault
+	return nil
+}
+
+// ClearFaults removes all faults injected
+func (fi *FaultInjector) ClearFaults() {
+	fi.mux.Lock()
+	defer fi.mux.Unlock()
+
+	fi.faults = make(map[[2]string]Fault)
+}
+
+// Faults gets the faults injected, ordered by operation and provider
+func (fi *FaultInjector) Faults() []Fault {
+	fi.mux.RLock()
+	defer fi.mux.RUnlock()
+
+	faults := make([]Fault, 0, len(fi.faults))
+	for _, fault := range fi.faults {
+		faults = append(faults, fault)
+	}
+	sort.Slice(faults, func(i, j int) bool {
+		if faults[i].Operation != faults[j].Operation {
+			return faults[i].Operation < faults[j].Operation
+		}
+		return faults[i].Provider < faults[j].Provider
+	})
+	return faults
+}
+
+// inject applies the fault matching the call, the error returned fails the call
+func (fi *FaultInjector) inject(operation, provider string) error {
+	fault, ok := fi.match(operation, provider)
+	if !ok {
+		return nil
+	}
+
+	if fault.LatencyMs > 0 {
+		time.Sleep(time.Duration(fault.LatencyMs) * time.Millisecond)
+	}
+	if fault.FailurePct > 0 && rand.Float64()*100 < fault.FailurePct {
+		return emperror.With(ErrInjectedFault, "operation", operation, "provider", provider)
+	}
+	return nil
+}
+
+// match finds the most specific fault of the call
+func (fi *FaultInjector) match(operation, provider string) (Fault, bool) {
+	fi.mux.RLock()
+	defer fi.mux.RUnlock()
+
+	for _, key := range [][2]string{{operation, provider}, {operation, ""}, {"", provider}, {"", ""}} {
+		if fault, ok := fi.faults[key]; ok {
+			return fault, true
+		}
+	}
+	return Fault{}, false
+}
+
+func (fi *FaultInjector) GetProductDetails(provider string, service string, region string) ([]VirtualMachine, error) {
+	if err := fi.inject(ProductsOperation, provider); err != nil {
+		return nil, err
+	}
+	return fi.CloudInfoSource.GetProductDetails(provider, service, region)
+}
+
+func (fi *FaultInjector) GetProductDetailsFiltered(provider string, service string, region string, filter ProductFilter) ([]VirtualMachine, error) {
+	if err := fi.inject(ProductsOperation, provider); err != nil {
+		return nil, err
+	}
+	return fi.CloudInfoSource.GetProductDetailsFiltered(provider, service, region, filter)
+}
+
+func (fi *FaultInjector) GetRegions(provider, service string) ([]cloudinfo.Region, error) {
+	if err := fi.inject(RegionsOperation, provider); err != nil {
+		return nil, err
+	}
+	return fi.CloudInfoSource.GetRegions(provider, service)
+}
+
+func (fi *FaultInjector) GetContinentsData(provider, service string) ([]cloudinfo.Continent, error) {
+	if err := fi.inject(ContinentsOperation, provider); err != nil {
+		return nil, err
+	}
+	return fi.CloudInfoSource.GetContinentsData(provider, service)
+}
+
+func (fi *FaultInjector) GetZones(provider, service, region string) ([]string, error) {
+	if err := fi.inject(ZonesOperation, provider); err != nil {
+		return nil, err
+	}
+	return fi.CloudInfoSource.GetZones(provider, service, region)
+}
+
+func (fi *FaultInjector) GetContinents() ([]string, error) {
+	if err := fi.inject(ContinentsOperation, ""); err != nil {
+		return nil, err
+	}
+	return fi.CloudInfoSource.GetContinents()
+}
+
+func (fi *FaultInjector) GetRegion(provider string, service string, region string) (string, error) {
+	if err := fi.inject(RegionOperation, provider); err != nil {
+		return "", err
+	}
+	return fi.CloudInfoSource.GetRegion(provider, service, region)
+}
+
+func (fi *FaultInjector) GetProviders() ([]cloudinfo.Provider, error) {
+	if err := fi.inject(ProviderOperation, ""); err != nil {
+		return nil, err
+	}
+	return fi.CloudInfoSource.GetProviders()
+}
+
+func (fi *FaultInjector) GetProvider(provider string) (string, error) {
+	if err := fi.inject(ProviderOperation, provider); err != nil {
+		return "", err
+	}
+	return fi.CloudInfoSource.GetProvider(provider)
+}
+
+func (fi *FaultInjector) GetService(provider string, service string) (string, error) {
+	if err := fi.inject(ServiceOperation, provider); err != nil {
+		return "", err
+	}
+	return fi.CloudInfoSource.GetService(provider, service)
+}
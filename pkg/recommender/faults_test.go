@@ -0,0 +1,60 @@
+// Copyright © 2019 Banzai Cloud
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package recommender
+
+import (
+	"testing"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFaultInjector(t *testing.T) {
+	products := &candidateProducts{vms: []VirtualMachine{{Type: "m5.xlarge", Cpus: 4, Mem: 16, OnDemandPrice: 0.192}}}
+	injector := NewFaultInjector(products)
+
+	vms, err := injector.GetProductDetails("amazon", "compute", "eu-west-1")
+	assert.NoError(t, err, "no faults should be injected by default")
+	assert.Len(t, vms, 1)
+
+	assert.NoError(t, injector.SetFault(Fault{Operation: ProductsOperation, Provider: "amazon", FailurePct: 100}))
+	assert.NoError(t, injector.SetFault(Fault{Operation: ProductsOperation, LatencyMs: 20}))
+
+	_, err = injector.GetProductDetails("amazon", "compute", "eu-west-1")
+	assert.Equal(t, ErrInjectedFault, errors.Cause(err), "the fault of the provider should be injected")
+
+	start := time.Now()
+	_, err = injector.GetProductDetailsFiltered("google", "compute", "europe-west1", ProductFilter{})
+	assert.NoError(t, err)
+	assert.True(t, time.Since(start) >= 20*time.Millisecond, "the latency of the operation should be injected")
+
+	assert.NoError(t, injector.SetFault(Fault{Operation: ZonesOperation, Provider: "google", FailurePct: 100}))
+	_, err = injector.GetZones("google", "compute", "europe-west1")
+	assert.Equal(t, ErrInjectedFault, errors.Cause(err))
+	_, err = injector.GetProductDetails("google", "compute", "europe-west1")
+	assert.NoError(t, err, "the faults of other operations should not be injected")
+
+	err = injector.SetFault(Fault{Operation: "prices", FailurePct: 100})
+	assert.Equal(t, ErrUnknownOperation, errors.Cause(err))
+
+	assert.Len(t, injector.Faults(), 3)
+	assert.Equal(t, "", injector.Faults()[0].Provider, "the faults should be ordered by operation and provider")
+
+	injector.ClearFaults()
+	assert.Empty(t, injector.Faults())
+	_, err = injector.GetProductDetails("amazon", "compute", "eu-west-1")
+	assert.NoError(t, err)
+}
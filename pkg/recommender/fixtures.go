@@ -0,0 +1,231 @@
+// Copyright © 2019 Banzai Cloud
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package recommender
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/banzaicloud/telescopes/.gen/cloudinfo"
+	"github.com/goph/emperror"
+	"github.com/pkg/errors"
+)
+
+const continentsFixture = "continents.json"
+
+// fixtureSource is a CloudInfoSource serving product and region data from fixture files, without any remote calls
+// The fixture directory is laid out as <provider>/<service>/<region>.json holding cloud info product responses,
+// and optionally <provider>/<service>/continents.json holding the continents data of the service
+type fixtureSource struct {
+	products   map[string][]VirtualMachine
+	continents map[string][]cloudinfo.Continent
+	// services holds the region ids per provider/service, in lexical order
+	services map[string][]string
+}
+
+// NewFixtureCloudInfoSource loads the fixtures from the given directory
+func NewFixtureCloudInfoSource(dir string) (CloudInfoSource, error) {
+	if info, err := os.Stat(dir); err != nil || !info.IsDir() {
+		return nil, emperror.With(errors.New("fixture directory not found"), "dir", dir)
+	}
+
+	fs := &fixtureSource{
+		products:   make(map[string][]VirtualMachine),
+		continents: make(map[string][]cloudinfo.Continent),
+		services:   make(map[string][]string),
+	}
+
+	files, err := filepath.Glob(filepath.Join(dir, "*", "*", "*.json"))
+	if err != nil {
+		return nil, emperror.WrapWith(err, "failed to list fixtures", "dir", dir)
+	}
+	sort.Strings(files)
+
+	for _, file := range files {
+		rel, _ := filepath.Rel(dir, file)
+		parts := strings.Split(filepath.ToSlash(rel), "/")
+		provider, service, name := parts[0], parts[1], parts[2]
+		svcKey := strings.Join([]string{provider, service}, "/")
+
+		data, err := ioutil.ReadFile(file)
+		if err != nil {
+			return nil, emperror.WrapWith(err, "failed to read fixture", "file", file)
+		}
+
+		if name == continentsFixture {
+			var continents []cloudinfo.Continent
+			if err := json.Unmarshal(data, &continents); err != nil {
+				return nil, emperror.WrapWith(err, "failed to parse continents fixture", "file", file)
+			}
+			fs.continents[svcKey] = continents
+			continue
+		}
+
+		var products productsDTO
+		if err := json.Unmarshal(data, &products); err != nil {
+			return nil, emperror.WrapWith(err, "failed to parse products fixture", "file", file)
+		}
+
+		region := strings.TrimSuffix(name, filepath.Ext(name))
+		vms := make([]VirtualMachine, 0, len(products.Products))
+		for _, p := range products.Products {
+			vms = append(vms, p.toVirtualMachine())
+		}
+		fs.products[freshnessKey(provider, service, region)] = vms
+		fs.services[svcKey] = append(fs.services[svcKey], region)
+	}
+
+	if len(fs.products) == 0 {
+		return nil, emperror.With(errors.New("no product fixtures found"), "dir", dir)
+	}
+
+	return fs, nil
+}
+
+// GetProductDetails returns a copy of the product fixture, so the engine can't alter it
+func (fs *fixtureSource) GetProductDetails(provider string, service string, region string) ([]VirtualMachine, error) {
+	vms, ok := fs.products[freshnessKey(provider, service, region)]
+	if !ok {
+		return nil, emperror.With(errors.New("no product fixture"), "provider", provider, "service", service, "region", region)
+	}
+	return append([]VirtualMachine(nil), vms...), nil
+}
+
+// GetProductDetailsFiltered returns a filtered copy of the product fixture
+func (fs *fixtureSource) GetProductDetailsFiltered(provider string, service string, region string, filter ProductFilter) ([]VirtualMachine, error) {
+	vms, err := fs.GetProductDetails(provider, service, region)
+	if err != nil {
+		return nil, err
+	}
+	return filter.Apply(vms), nil
+}
+
+func (fs *fixtureSource) GetRegions(provider, service string) ([]cloudinfo.Region, error) {
+	var regions []cloudinfo.Region
+	for _, region := range fs.services[strings.Join([]string{provider, service}, "/")] {
+		regions = append(regions, cloudinfo.Region{Id: region, Name: fs.regionName(provider, service, region)})
+	}
+	return regions, nil
+}
+
+func (fs *fixtureSource) GetContinentsData(provider, service string) ([]cloudinfo.Continent, error) {
+	return fs.continents[strings.Join([]string{provider, service}, "/")], nil
+}
+
+// GetZones collects the zones of the products in the region
+func (fs *fixtureSource) GetZones(provider, service, region string) ([]string, error) {
+	vms, err := fs.GetProductDetails(provider, service, region)
+	if err != nil {
+		return nil, err
+	}
+
+	zoneSet := make(map[string]bool)
+	for _, vm := range vms {
+		for _, zone := range vm.Zones {
+			zoneSet[zone] = true
+		}
+	}
+
+	zones := make([]string, 0, len(zoneSet))
+	for zone := range zoneSet {
+		zones = append(zones, zone)
+	}
+	sort.Strings(zones)
+	return zones, nil
+}
+
+func (fs *fixtureSource) GetContinents() ([]string, error) {
+	continentSet := make(map[string]bool)
+	for _, continents := range fs.continents {
+		for _, continent := range continents {
+			continentSet[continent.Name] = true
+		}
+	}
+
+	continents := make([]string, 0, len(continentSet))
+	for continent := range continentSet {
+		continents = append(continents, continent)
+	}
+	sort.Strings(continents)
+	return continents, nil
+}
+
+func (fs *fixtureSource) GetRegion(provider string, service string, region string) (string, error) {
+	if _, ok := fs.products[freshnessKey(provider, service, region)]; !ok {
+		return "", nil
+	}
+	return fs.regionName(provider, service, region), nil
+}
+
+// GetProviders collects the providers and services of the fixtures, in lexical order
+func (fs *fixtureSource) GetProviders() ([]cloudinfo.Provider, error) {
+	svcKeys := make([]string, 0, len(fs.services))
+	for svcKey := range fs.services {
+		svcKeys = append(svcKeys, svcKey)
+	}
+	sort.Strings(svcKeys)
+
+	var providers []cloudinfo.Provider
+	for _, svcKey := range svcKeys {
+		parts := strings.SplitN(svcKey, "/", 2)
+		if len(providers) == 0 || providers[len(providers)-1].Provider != parts[0] {
+			providers = append(providers, cloudinfo.Provider{Provider: parts[0]})
+		}
+		last := &providers[len(providers)-1]
+		last.Services = append(last.Services, cloudinfo.Service{Service: parts[1]})
+	}
+	return providers, nil
+}
+
+func (fs *fixtureSource) GetProvider(provider string) (string, error) {
+	for svcKey := range fs.services {
+		if strings.HasPrefix(svcKey, provider+"/") {
+			return provider, nil
+		}
+	}
+	return "", nil
+}
+
+func (fs *fixtureSource) GetService(provider string, service string) (string, error) {
+	if _, ok := fs.services[strings.Join([]string{provider, service}, "/")]; !ok {
+		return "", nil
+	}
+	return service, nil
+}
+
+// GetDataFreshness is not tracked for fixtures, recommendations must not depend on the time they are made
+func (fs *fixtureSource) GetDataFreshness(provider string, service string, region string) (DataFreshness, bool) {
+	return DataFreshness{}, false
+}
+
+func (fs *fixtureSource) GetAllDataFreshness() []DataFreshness {
+	return []DataFreshness{}
+}
+
+// regionName looks up the name of the region in the continents fixture, defaults to the region id
+func (fs *fixtureSource) regionName(provider, service, region string) string {
+	for _, continent := range fs.continents[strings.Join([]string{provider, service}, "/")] {
+		for _, r := range continent.Regions {
+			if r.Id == region && r.Name != "" {
+				return r.Name
+			}
+		}
+	}
+	return region
+}
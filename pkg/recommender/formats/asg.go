@@ -0,0 +1,178 @@
+// Copyright © 2019 Banzai Cloud
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package formats
+
+import (
+	"math"
+	"sort"
+	"strconv"
+
+	"github.com/banzaicloud/telescopes/pkg/recommender"
+	"github.com/pkg/errors"
+)
+
+const (
+	// AsgMixed is the name of the AWS AutoScalingGroup MixedInstancesPolicy output format
+	AsgMixed = "asg-mixed"
+	// AsgCapacityOptimized is the name of the AWS AutoScalingGroup MixedInstancesPolicy output format with the
+	// capacity-optimized-prioritized spot allocation strategy, weighted by the node pool weights
+	AsgCapacityOptimized = "asg-capacity-optimized"
+)
+
+// AutoScalingGroup holds the AutoScalingGroup properties derived from a recommendation, as in CloudFormation
+type AutoScalingGroup struct {
+	MixedInstancesPolicy MixedInstancesPolicy `json:"MixedInstancesPolicy"`
+	// DesiredCapacity is the total weighted capacity of the worker node pools
+	DesiredCapacity string `json:"DesiredCapacity"`
+}
+
+// MixedInstancesPolicy describes the instance types and the on-demand/spot distribution of an AutoScalingGroup
+type MixedInstancesPolicy struct {
+	LaunchTemplate        LaunchTemplate        `json:"LaunchTemplate"`
+	InstancesDistribution InstancesDistribution `json:"InstancesDistribution"`
+}
+
+// LaunchTemplate holds the instance type overrides of the launch template
+type LaunchTemplate struct {
+	Overrides []LaunchTemplateOverride `json:"Overrides"`
+}
+
+// LaunchTemplateOverride is an instance type the AutoScalingGroup may launch, weighted by its capacity
+type LaunchTemplateOverride struct {
+	InstanceType     string `json:"InstanceType"`
+	WeightedCapacity string `json:"WeightedCapacity"`
+	// Priority of the instance type for the prioritized allocation strategies, lower is preferred
+	Priority int `json:"Priority,omitempty"`
+}
+
+// InstancesDistribution describes how on-demand and spot instances are mixed
+type InstancesDistribution struct {
+	OnDemandAllocationStrategy          string `json:"OnDemandAllocationStrategy"`
+	OnDemandBaseCapacity                int    `json:"OnDemandBaseCapacity"`
+	OnDemandPercentageAboveBaseCapacity int    `json:"OnDemandPercentageAboveBaseCapacity"`
+	SpotAllocationStrategy              string `json:"SpotAllocationStrategy"`
+	SpotInstancePools                   int    `json:"SpotInstancePools,omitempty"`
+}
+
+// ToAutoScalingGroup converts the worker node pools of an amazon recommendation into AutoScalingGroup properties
+// Overrides are weighted by the cpus of the instance type; on-demand types come first so they are prioritized
+func ToAutoScalingGroup(resp recommender.ClusterRecommendationResp) (*AutoScalingGroup, error) {
+	return toAutoScalingGroup(AsgMixed, resp, cpuWeight, false)
+}
+
+// ToCapacityOptimizedAutoScalingGroup converts the worker node pools of an amazon recommendation into AutoScalingGroup
+// properties for the capacity-optimized-prioritized spot allocation strategy
+// Overrides are weighted by the node pool weights, and prioritized: on-demand types first, then the spot types
+// in the order of their share of the capacity
+func ToCapacityOptimizedAutoScalingGroup(resp recommender.ClusterRecommendationResp) (*AutoScalingGroup, error) {
+	return toAutoScalingGroup(AsgCapacityOptimized, resp, poolWeight, true)
+}
+
+func toAutoScalingGroup(format string, resp recommender.ClusterRecommendationResp, weight func(np recommender.NodePool) int, prioritized bool) (*AutoScalingGroup, error) {
+	if resp.Provider != "amazon" {
+		return nil, errors.Errorf("the %s format is not supported for provider %s", format, resp.Provider)
+	}
+
+	var (
+		overrides     []LaunchTemplateOverride
+		seen          = make(map[string]bool)
+		odCapacity    int
+		totalCapacity int
+		spotPools     []recommender.NodePool
+	)
+
+	addOverride := func(np recommender.NodePool) {
+		if seen[np.VmType.Type] {
+			return
+		}
+		seen[np.VmType.Type] = true
+		override := LaunchTemplateOverride{
+			InstanceType:     np.VmType.Type,
+			WeightedCapacity: strconv.Itoa(weight(np)),
+		}
+		if prioritized {
+			override.Priority = len(overrides) + 1
+		}
+		overrides = append(overrides, override)
+	}
+
+	for _, np := range resp.NodePools {
+		if np.Role == recommender.Master {
+			continue
+		}
+		capacity := np.SumNodes * weight(np)
+		totalCapacity += capacity
+		if np.VmClass == recommender.Regular || np.VmClass == recommender.Reserved {
+			odCapacity += capacity
+			addOverride(np)
+		}
+	}
+
+	for _, np := range resp.NodePools {
+		if np.Role == recommender.Master || np.VmClass != recommender.Spot || np.SumNodes == 0 {
+			continue
+		}
+		spotPools = append(spotPools, np)
+	}
+	if prioritized {
+		sort.SliceStable(spotPools, func(i, j int) bool {
+			return spotPools[i].SumNodes*weight(spotPools[i]) > spotPools[j].SumNodes*weight(spotPools[j])
+		})
+	}
+	for _, np := range spotPools {
+		addOverride(np)
+	}
+
+	if len(overrides) == 0 {
+		return nil, errors.New("there are no worker node pools in the recommendation")
+	}
+
+	distribution := InstancesDistribution{
+		OnDemandAllocationStrategy:          "prioritized",
+		OnDemandBaseCapacity:                odCapacity,
+		OnDemandPercentageAboveBaseCapacity: 0,
+		SpotAllocationStrategy:              "lowest-price",
+		SpotInstancePools:                   int(math.Max(1, float64(len(spotPools)))),
+	}
+	if prioritized {
+		// the number of spot pools only applies to the lowest-price strategy
+		distribution.SpotAllocationStrategy = "capacity-optimized-prioritized"
+		distribution.SpotInstancePools = 0
+	}
+
+	return &AutoScalingGroup{
+		MixedInstancesPolicy: MixedInstancesPolicy{
+			LaunchTemplate:        LaunchTemplate{Overrides: overrides},
+			InstancesDistribution: distribution,
+		},
+		DesiredCapacity: strconv.Itoa(totalCapacity),
+	}, nil
+}
+
+// cpuWeight gets the capacity weight of the instance type of the node pool, which is its number of cpus (at least 1)
+func cpuWeight(np recommender.NodePool) int {
+	if np.VmType.Cpus < 1 {
+		return 1
+	}
+	return int(np.VmType.Cpus)
+}
+
+// poolWeight gets the weight of the node pool (at least 1)
+func poolWeight(np recommender.NodePool) int {
+	if np.Weight < 1 {
+		return 1
+	}
+	return np.Weight
+}
@@ -0,0 +1,93 @@
+// Copyright © 2019 Banzai Cloud
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package formats
+
+import (
+	"testing"
+
+	"github.com/banzaicloud/telescopes/pkg/recommender"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestToAutoScalingGroup(t *testing.T) {
+	tests := []struct {
+		name  string
+		resp  recommender.ClusterRecommendationResp
+		check func(asg *AutoScalingGroup, err error)
+	}{
+		{
+			name: "unsupported provider",
+			resp: recommender.ClusterRecommendationResp{Provider: "google"},
+			check: func(asg *AutoScalingGroup, err error) {
+				assert.Nil(t, asg)
+				assert.NotNil(t, err)
+			},
+		},
+		{
+			name: "mixed node pools",
+			resp: recommender.ClusterRecommendationResp{
+				Provider: "amazon",
+				NodePools: []recommender.NodePool{
+					{VmType: recommender.VirtualMachine{Type: "c5.large", Cpus: 2}, SumNodes: 2, VmClass: recommender.Regular, Role: recommender.Worker},
+					{VmType: recommender.VirtualMachine{Type: "c5.xlarge", Cpus: 4}, SumNodes: 3, VmClass: recommender.Spot, Role: recommender.Worker},
+					{VmType: recommender.VirtualMachine{Type: "m5.xlarge", Cpus: 4}, SumNodes: 0, VmClass: recommender.Spot, Role: recommender.Worker},
+					{VmType: recommender.VirtualMachine{Type: "c5.large", Cpus: 2}, SumNodes: 1, VmClass: recommender.Spot, Role: recommender.Worker},
+					{VmType: recommender.VirtualMachine{Type: "EKS Control Plane"}, SumNodes: 1, VmClass: recommender.Regular, Role: recommender.Master},
+				},
+			},
+			check: func(asg *AutoScalingGroup, err error) {
+				assert.Nil(t, err)
+				assert.Equal(t, []LaunchTemplateOverride{
+					{InstanceType: "c5.large", WeightedCapacity: "2"},
+					{InstanceType: "c5.xlarge", WeightedCapacity: "4"},
+				}, asg.MixedInstancesPolicy.LaunchTemplate.Overrides)
+				assert.Equal(t, 4, asg.MixedInstancesPolicy.InstancesDistribution.OnDemandBaseCapacity)
+				assert.Equal(t, 2, asg.MixedInstancesPolicy.InstancesDistribution.SpotInstancePools)
+				assert.Equal(t, "18", asg.DesiredCapacity)
+			},
+		},
+	}
+	for _, test := range tests {
+		test := test // scopelint
+		t.Run(test.name, func(t *testing.T) {
+			test.check(ToAutoScalingGroup(test.resp))
+		})
+	}
+}
+
+func TestToCapacityOptimizedAutoScalingGroup(t *testing.T) {
+	resp := recommender.ClusterRecommendationResp{
+		Provider: "amazon",
+		NodePools: []recommender.NodePool{
+			{VmType: recommender.VirtualMachine{Type: "c5.large", Cpus: 2}, SumNodes: 2, VmClass: recommender.Regular, Role: recommender.Worker, Weight: 1},
+			{VmType: recommender.VirtualMachine{Type: "c5.xlarge", Cpus: 4}, SumNodes: 1, VmClass: recommender.Spot, Role: recommender.Worker, Weight: 2},
+			{VmType: recommender.VirtualMachine{Type: "m5.2xlarge", Cpus: 8}, SumNodes: 2, VmClass: recommender.Spot, Role: recommender.Worker, Weight: 4},
+		},
+	}
+
+	asg, err := ToCapacityOptimizedAutoScalingGroup(resp)
+	if !assert.Nil(t, err) {
+		return
+	}
+	assert.Equal(t, []LaunchTemplateOverride{
+		{InstanceType: "c5.large", WeightedCapacity: "1", Priority: 1},
+		{InstanceType: "m5.2xlarge", WeightedCapacity: "4", Priority: 2},
+		{InstanceType: "c5.xlarge", WeightedCapacity: "2", Priority: 3},
+	}, asg.MixedInstancesPolicy.LaunchTemplate.Overrides)
+	assert.Equal(t, "capacity-optimized-prioritized", asg.MixedInstancesPolicy.InstancesDistribution.SpotAllocationStrategy)
+	assert.Equal(t, 0, asg.MixedInstancesPolicy.InstancesDistribution.SpotInstancePools)
+	assert.Equal(t, 2, asg.MixedInstancesPolicy.InstancesDistribution.OnDemandBaseCapacity)
+	assert.Equal(t, "12", asg.DesiredCapacity)
+}
@@ -0,0 +1,379 @@
+// Copyright © 2019 Banzai Cloud
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package formats
+
+import (
+	"bytes"
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/banzaicloud/telescopes/pkg/recommender"
+	"github.com/pkg/errors"
+	"gopkg.in/yaml.v2"
+)
+
+// ClusterAPI is the name of the Kubernetes Cluster API MachineDeployment output format
+const ClusterAPI = "capi"
+
+// ClusterAPIMachinePool is the name of the Kubernetes Cluster API MachinePool output format
+const ClusterAPIMachinePool = "capi-machinepool"
+
+const (
+	capiVersion          = "cluster.x-k8s.io/v1beta1"
+	capiBootstrapVersion = "bootstrap.cluster.x-k8s.io/v1beta1"
+	clusterNameLabel     = "cluster.x-k8s.io/cluster-name"
+	vmClassLabel         = "telescopes.banzaicloud.io/vm-class"
+)
+
+// dns1123Label matches the names valid as DNS-1123 labels, the cluster names of Cluster API
+var dns1123Label = regexp.MustCompile(`^[a-z0-9]([-a-z0-9]*[a-z0-9])?$`)
+
+// infrastructure describes the machine template and the machine pool of a Cluster API infrastructure provider
+type infrastructure struct {
+	apiVersion string
+	kind       string
+	// spec assembles the machine spec of the given instance type
+	spec func(vmType string, spot bool) map[string]interface{}
+	// poolKind is the kind of the machine pool of the provider, empty if MachinePools are not supported
+	poolKind string
+	// poolSpec assembles the machine pool spec of the given instance type
+	poolSpec func(vmType string, spot bool, replicas int) map[string]interface{}
+}
+
+var infrastructures = map[string]infrastructure{
+	"amazon": {
+		apiVersion: "infrastructure.cluster.x-k8s.io/v1beta2",
+		kind:       "AWSMachineTemplate",
+		spec: func(vmType string, spot bool) map[string]interface{} {
+			spec := map[string]interface{}{"instanceType": vmType}
+			if spot {
+				spec["spotMarketOptions"] = map[string]interface{}{}
+			}
+			return spec
+		},
+		poolKind: "AWSMachinePool",
+		poolSpec: func(vmType string, spot bool, replicas int) map[string]interface{} {
+			template := map[string]interface{}{"instanceType": vmType}
+			if spot {
+				template["spotMarketOptions"] = map[string]interface{}{}
+			}
+			return map[string]interface{}{"minSize": replicas, "maxSize": replicas, "awsLaunchTemplate": template}
+		},
+	},
+	"google": {
+		apiVersion: "infrastructure.cluster.x-k8s.io/v1beta1",
+		kind:       "GCPMachineTemplate",
+		spec: func(vmType string, spot bool) map[string]interface{} {
+			spec := map[string]interface{}{"instanceType": vmType}
+			if spot {
+				spec["preemptible"] = true
+			}
+			return spec
+		},
+	},
+	"azure": {
+		apiVersion: "infrastructure.cluster.x-k8s.io/v1beta1",
+		kind:       "AzureMachineTemplate",
+		spec: func(vmType string, spot bool) map[string]interface{} {
+			spec := map[string]interface{}{"vmSize": vmType}
+			if spot {
+				spec["spotVMOptions"] = map[string]interface{}{}
+			}
+			return spec
+		},
+		poolKind: "AzureMachinePool",
+		poolSpec: func(vmType string, spot bool, replicas int) map[string]interface{} {
+			template := map[string]interface{}{"vmSize": vmType}
+			if spot {
+				template["spotVMOptions"] = map[string]interface{}{}
+			}
+			return map[string]interface{}{"template": template}
+		},
+	},
+}
+
+// ObjectMeta holds the metadata of a Kubernetes object
+type ObjectMeta struct {
+	Name   string            `yaml:"name"`
+	Labels map[string]string `yaml:"labels,omitempty"`
+}
+
+// ObjectReference references a Kubernetes object by its kind and name
+type ObjectReference struct {
+	APIVersion string `yaml:"apiVersion"`
+	Kind       string `yaml:"kind"`
+	Name       string `yaml:"name"`
+}
+
+// MachineTemplate is the infrastructure provider specific template of the machines of a node pool
+type MachineTemplate struct {
+	APIVersion string              `yaml:"apiVersion"`
+	Kind       string              `yaml:"kind"`
+	Metadata   ObjectMeta          `yaml:"metadata"`
+	Spec       MachineTemplateSpec `yaml:"spec"`
+}
+
+// MachineTemplateSpec wraps the provider specific machine spec
+type MachineTemplateSpec struct {
+	Template struct {
+		Spec map[string]interface{} `yaml:"spec"`
+	} `yaml:"template"`
+}
+
+// MachineDeployment is the Cluster API representation of a node pool
+type MachineDeployment struct {
+	APIVersion string                `yaml:"apiVersion"`
+	Kind       string                `yaml:"kind"`
+	Metadata   ObjectMeta            `yaml:"metadata"`
+	Spec       MachineDeploymentSpec `yaml:"spec"`
+}
+
+// MachineDeploymentSpec holds the desired state of a MachineDeployment
+type MachineDeploymentSpec struct {
+	ClusterName string `yaml:"clusterName"`
+	Replicas    int    `yaml:"replicas"`
+	Selector    struct {
+		MatchLabels map[string]string `yaml:"matchLabels"`
+	} `yaml:"selector"`
+	Template struct {
+		Metadata struct {
+			Labels map[string]string `yaml:"labels"`
+		} `yaml:"metadata"`
+		Spec MachineSpec `yaml:"spec"`
+	} `yaml:"template"`
+}
+
+// MachineSpec references the bootstrap config and the infrastructure template of the machines
+type MachineSpec struct {
+	ClusterName string `yaml:"clusterName"`
+	Bootstrap   struct {
+		ConfigRef ObjectReference `yaml:"configRef"`
+	} `yaml:"bootstrap"`
+	InfrastructureRef ObjectReference `yaml:"infrastructureRef"`
+}
+
+// ClusterAPINodePool holds the Cluster API objects of a single node pool
+type ClusterAPINodePool struct {
+	MachineTemplate   MachineTemplate
+	MachineDeployment MachineDeployment
+}
+
+// ClusterAPIManifests holds the Cluster API objects of the worker node pools of a recommendation
+type ClusterAPIManifests []ClusterAPINodePool
+
+// ToClusterAPI converts the worker node pools of a recommendation into Cluster API MachineDeployments and machine templates
+// The bootstrap config is expected to be a KubeadmConfigTemplate named after the cluster; master node pools are skipped
+// as the control plane is not managed by MachineDeployments
+func ToClusterAPI(resp recommender.ClusterRecommendationResp, clusterName string) (ClusterAPIManifests, error) {
+	infra, ok := infrastructures[resp.Provider]
+	if !ok {
+		return nil, errors.Errorf("the %s format is not supported for provider %s", ClusterAPI, resp.Provider)
+	}
+	if err := validateClusterName(clusterName); err != nil {
+		return nil, err
+	}
+
+	var manifests ClusterAPIManifests
+	for i, np := range resp.NodePools {
+		if np.Role == recommender.Master || np.SumNodes == 0 {
+			continue
+		}
+
+		name := resourceName(clusterName, i, np.VmClass, np.VmType.Type)
+		labels := map[string]string{
+			clusterNameLabel: clusterName,
+			vmClassLabel:     np.VmClass,
+		}
+
+		tpl := MachineTemplate{
+			APIVersion: infra.apiVersion,
+			Kind:       infra.kind,
+			Metadata:   ObjectMeta{Name: name},
+		}
+		tpl.Spec.Template.Spec = infra.spec(np.VmType.Type, np.VmClass == recommender.Spot)
+
+		md := MachineDeployment{
+			APIVersion: capiVersion,
+			Kind:       "MachineDeployment",
+			Metadata:   ObjectMeta{Name: name, Labels: labels},
+		}
+		md.Spec.ClusterName = clusterName
+		md.Spec.Replicas = np.SumNodes
+		md.Spec.Selector.MatchLabels = labels
+		md.Spec.Template.Metadata.Labels = labels
+		md.Spec.Template.Spec.ClusterName = clusterName
+		md.Spec.Template.Spec.Bootstrap.ConfigRef = ObjectReference{
+			APIVersion: capiBootstrapVersion,
+			Kind:       "KubeadmConfigTemplate",
+			Name:       clusterName,
+		}
+		md.Spec.Template.Spec.InfrastructureRef = ObjectReference{
+			APIVersion: infra.apiVersion,
+			Kind:       infra.kind,
+			Name:       name,
+		}
+
+		manifests = append(manifests, ClusterAPINodePool{MachineTemplate: tpl, MachineDeployment: md})
+	}
+
+	if len(manifests) == 0 {
+		return nil, errors.New("there are no worker node pools in the recommendation")
+	}
+
+	return manifests, nil
+}
+
+// YAML renders the manifests as a multi-document YAML stream, the machine template preceding its MachineDeployment
+func (m ClusterAPIManifests) YAML() ([]byte, error) {
+	var objs []interface{}
+	for _, np := range m {
+		objs = append(objs, np.MachineTemplate, np.MachineDeployment)
+	}
+	return renderYAML(objs)
+}
+
+// InfrastructureMachinePool is the infrastructure provider specific machine pool of a node pool
+type InfrastructureMachinePool struct {
+	APIVersion string                 `yaml:"apiVersion"`
+	Kind       string                 `yaml:"kind"`
+	Metadata   ObjectMeta             `yaml:"metadata"`
+	Spec       map[string]interface{} `yaml:"spec"`
+}
+
+// MachinePool is the Cluster API representation of a node pool managed by the infrastructure provider
+// (eg. an AWS AutoScalingGroup or an Azure scale set)
+type MachinePool struct {
+	APIVersion string          `yaml:"apiVersion"`
+	Kind       string          `yaml:"kind"`
+	Metadata   ObjectMeta      `yaml:"metadata"`
+	Spec       MachinePoolSpec `yaml:"spec"`
+}
+
+// MachinePoolSpec holds the desired state of a MachinePool
+type MachinePoolSpec struct {
+	ClusterName string `yaml:"clusterName"`
+	Replicas    int    `yaml:"replicas"`
+	Template    struct {
+		Spec MachineSpec `yaml:"spec"`
+	} `yaml:"template"`
+}
+
+// MachinePoolNodePool holds the Cluster API objects of a single node pool rendered as a MachinePool
+type MachinePoolNodePool struct {
+	InfrastructureMachinePool InfrastructureMachinePool
+	MachinePool               MachinePool
+}
+
+// MachinePoolManifests holds the Cluster API MachinePools of the worker node pools of a recommendation
+type MachinePoolManifests []MachinePoolNodePool
+
+// ToClusterAPIMachinePools converts the worker node pools of a recommendation into Cluster API MachinePools and
+// infrastructure machine pools; providers without machine pools in Cluster API (eg. google) are not supported
+// The bootstrap config is expected to be a KubeadmConfig named after the cluster; master node pools are skipped
+func ToClusterAPIMachinePools(resp recommender.ClusterRecommendationResp, clusterName string) (MachinePoolManifests, error) {
+	infra, ok := infrastructures[resp.Provider]
+	if !ok || infra.poolKind == "" {
+		return nil, errors.Errorf("the %s format is not supported for provider %s", ClusterAPIMachinePool, resp.Provider)
+	}
+	if err := validateClusterName(clusterName); err != nil {
+		return nil, err
+	}
+
+	var pools MachinePoolManifests
+	for i, np := range resp.NodePools {
+		if np.Role == recommender.Master || np.SumNodes == 0 {
+			continue
+		}
+
+		name := resourceName(clusterName, i, np.VmClass, np.VmType.Type)
+		labels := map[string]string{
+			clusterNameLabel: clusterName,
+			vmClassLabel:     np.VmClass,
+		}
+
+		infraPool := InfrastructureMachinePool{
+			APIVersion: infra.apiVersion,
+			Kind:       infra.poolKind,
+			Metadata:   ObjectMeta{Name: name},
+			Spec:       infra.poolSpec(np.VmType.Type, np.VmClass == recommender.Spot, np.SumNodes),
+		}
+
+		mp := MachinePool{
+			APIVersion: capiVersion,
+			Kind:       "MachinePool",
+			Metadata:   ObjectMeta{Name: name, Labels: labels},
+		}
+		mp.Spec.ClusterName = clusterName
+		mp.Spec.Replicas = np.SumNodes
+		mp.Spec.Template.Spec.ClusterName = clusterName
+		mp.Spec.Template.Spec.Bootstrap.ConfigRef = ObjectReference{
+			APIVersion: capiBootstrapVersion,
+			Kind:       "KubeadmConfig",
+			Name:       clusterName,
+		}
+		mp.Spec.Template.Spec.InfrastructureRef = ObjectReference{
+			APIVersion: infra.apiVersion,
+			Kind:       infra.poolKind,
+			Name:       name,
+		}
+
+		pools = append(pools, MachinePoolNodePool{InfrastructureMachinePool: infraPool, MachinePool: mp})
+	}
+
+	if len(pools) == 0 {
+		return nil, errors.New("there are no worker node pools in the recommendation")
+	}
+
+	return pools, nil
+}
+
+// YAML renders the machine pools as a multi-document YAML stream, the infrastructure machine pool preceding its MachinePool
+func (m MachinePoolManifests) YAML() ([]byte, error) {
+	var objs []interface{}
+	for _, np := range m {
+		objs = append(objs, np.InfrastructureMachinePool, np.MachinePool)
+	}
+	return renderYAML(objs)
+}
+
+// renderYAML renders the objects as a multi-document YAML stream
+func renderYAML(objs []interface{}) ([]byte, error) {
+	var buf bytes.Buffer
+	for _, obj := range objs {
+		data, err := yaml.Marshal(obj)
+		if err != nil {
+			return nil, errors.Wrap(err, "failed to marshal manifest")
+		}
+		buf.WriteString("---\n")
+		buf.Write(data)
+	}
+	return buf.Bytes(), nil
+}
+
+// validateClusterName checks that the cluster name is a valid DNS-1123 label, as Cluster API requires
+func validateClusterName(clusterName string) error {
+	if len(clusterName) > 63 || !dns1123Label.MatchString(clusterName) {
+		return errors.Errorf("invalid cluster name %q, it must be a DNS-1123 label: at most 63 lower case alphanumeric characters or '-', starting and ending with an alphanumeric character", clusterName)
+	}
+	return nil
+}
+
+// resourceName assembles a valid Kubernetes resource name for the node pool, suffixed with the index of the node pool
+// as node pools of the same class and instance type (eg. split by the node pool size limits) would clash otherwise
+func resourceName(clusterName string, index int, vmClass string, vmType string) string {
+	name := strings.ToLower(fmt.Sprintf("%s-%s-%s-%d", clusterName, vmClass, vmType, index))
+	return strings.NewReplacer(".", "-", "_", "-", " ", "-").Replace(name)
+}
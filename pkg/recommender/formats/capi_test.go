@@ -0,0 +1,157 @@
+// Copyright © 2019 Banzai Cloud
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package formats
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/banzaicloud/telescopes/pkg/recommender"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestToClusterAPI(t *testing.T) {
+	tests := []struct {
+		name  string
+		resp  recommender.ClusterRecommendationResp
+		check func(manifests ClusterAPIManifests, err error)
+	}{
+		{
+			name: "unsupported provider",
+			resp: recommender.ClusterRecommendationResp{Provider: "oracle"},
+			check: func(manifests ClusterAPIManifests, err error) {
+				assert.Nil(t, manifests)
+				assert.NotNil(t, err)
+			},
+		},
+		{
+			name: "no worker node pools",
+			resp: recommender.ClusterRecommendationResp{
+				Provider: "amazon",
+				NodePools: []recommender.NodePool{
+					{VmType: recommender.VirtualMachine{Type: "m5.xlarge"}, SumNodes: 0, VmClass: recommender.Spot, Role: recommender.Worker},
+					{VmType: recommender.VirtualMachine{Type: "EKS Control Plane"}, SumNodes: 1, VmClass: recommender.Regular, Role: recommender.Master},
+				},
+			},
+			check: func(manifests ClusterAPIManifests, err error) {
+				assert.Nil(t, manifests)
+				assert.NotNil(t, err)
+			},
+		},
+		{
+			name: "amazon node pools",
+			resp: recommender.ClusterRecommendationResp{
+				Provider: "amazon",
+				NodePools: []recommender.NodePool{
+					{VmType: recommender.VirtualMachine{Type: "c5.large"}, SumNodes: 2, VmClass: recommender.Regular, Role: recommender.Worker},
+					{VmType: recommender.VirtualMachine{Type: "c5.xlarge"}, SumNodes: 3, VmClass: recommender.Spot, Role: recommender.Worker},
+					{VmType: recommender.VirtualMachine{Type: "EKS Control Plane"}, SumNodes: 1, VmClass: recommender.Regular, Role: recommender.Master},
+				},
+			},
+			check: func(manifests ClusterAPIManifests, err error) {
+				assert.Nil(t, err)
+				assert.Len(t, manifests, 2)
+
+				od := manifests[0]
+				assert.Equal(t, "test-regular-c5-large-0", od.MachineDeployment.Metadata.Name)
+				assert.Equal(t, 2, od.MachineDeployment.Spec.Replicas)
+				assert.Equal(t, "AWSMachineTemplate", od.MachineDeployment.Spec.Template.Spec.InfrastructureRef.Kind)
+				assert.Equal(t, od.MachineTemplate.Metadata.Name, od.MachineDeployment.Spec.Template.Spec.InfrastructureRef.Name)
+				assert.Equal(t, map[string]interface{}{"instanceType": "c5.large"}, od.MachineTemplate.Spec.Template.Spec)
+
+				spot := manifests[1]
+				assert.Equal(t, 3, spot.MachineDeployment.Spec.Replicas)
+				assert.Contains(t, spot.MachineTemplate.Spec.Template.Spec, "spotMarketOptions")
+
+				data, err := manifests.YAML()
+				assert.Nil(t, err)
+				assert.Equal(t, 4, strings.Count(string(data), "---\n"))
+				assert.Contains(t, string(data), "kind: MachineDeployment")
+			},
+		},
+		{
+			name: "node pools of the same class and type",
+			resp: recommender.ClusterRecommendationResp{
+				Provider: "amazon",
+				NodePools: []recommender.NodePool{
+					{VmType: recommender.VirtualMachine{Type: "c5.xlarge"}, SumNodes: 100, VmClass: recommender.Spot, Role: recommender.Worker},
+					{VmType: recommender.VirtualMachine{Type: "c5.xlarge"}, SumNodes: 20, VmClass: recommender.Spot, Role: recommender.Worker},
+				},
+			},
+			check: func(manifests ClusterAPIManifests, err error) {
+				assert.Nil(t, err)
+				if !assert.Len(t, manifests, 2) {
+					return
+				}
+				assert.NotEqual(t, manifests[0].MachineDeployment.Metadata.Name, manifests[1].MachineDeployment.Metadata.Name)
+				assert.NotEqual(t, manifests[0].MachineTemplate.Metadata.Name, manifests[1].MachineTemplate.Metadata.Name)
+			},
+		},
+	}
+	for _, test := range tests {
+		test := test
+		t.Run(test.name, func(t *testing.T) {
+			test.check(ToClusterAPI(test.resp, "test"))
+		})
+	}
+}
+
+func TestToClusterAPI_ClusterName(t *testing.T) {
+	resp := recommender.ClusterRecommendationResp{
+		Provider: "amazon",
+		NodePools: []recommender.NodePool{
+			{VmType: recommender.VirtualMachine{Type: "c5.large"}, SumNodes: 2, VmClass: recommender.Regular, Role: recommender.Worker},
+		},
+	}
+	for _, name := range []string{"", "Prod", "prod_1", "-prod", "prod-", strings.Repeat("a", 64)} {
+		_, err := ToClusterAPI(resp, name)
+		assert.NotNil(t, err, "%q should be rejected", name)
+		_, err = ToClusterAPIMachinePools(resp, name)
+		assert.NotNil(t, err, "%q should be rejected", name)
+	}
+	_, err := ToClusterAPI(resp, "prod-1")
+	assert.Nil(t, err)
+}
+
+func TestToClusterAPIMachinePools(t *testing.T) {
+	nodePools := []recommender.NodePool{
+		{VmType: recommender.VirtualMachine{Type: "c5.large"}, SumNodes: 2, VmClass: recommender.Regular, Role: recommender.Worker},
+		{VmType: recommender.VirtualMachine{Type: "c5.xlarge"}, SumNodes: 3, VmClass: recommender.Spot, Role: recommender.Worker},
+		{VmType: recommender.VirtualMachine{Type: "EKS Control Plane"}, SumNodes: 1, VmClass: recommender.Regular, Role: recommender.Master},
+	}
+
+	_, err := ToClusterAPIMachinePools(recommender.ClusterRecommendationResp{Provider: "google", NodePools: nodePools}, "test")
+	assert.NotNil(t, err, "google has no machine pools")
+
+	pools, err := ToClusterAPIMachinePools(recommender.ClusterRecommendationResp{Provider: "amazon", NodePools: nodePools}, "test")
+	if !assert.Nil(t, err) || !assert.Len(t, pools, 2) {
+		return
+	}
+	od := pools[0]
+	assert.Equal(t, "test-regular-c5-large-0", od.MachinePool.Metadata.Name)
+	assert.Equal(t, 2, od.MachinePool.Spec.Replicas)
+	assert.Equal(t, "AWSMachinePool", od.MachinePool.Spec.Template.Spec.InfrastructureRef.Kind)
+	assert.Equal(t, "KubeadmConfig", od.MachinePool.Spec.Template.Spec.Bootstrap.ConfigRef.Kind)
+	assert.Equal(t, od.InfrastructureMachinePool.Metadata.Name, od.MachinePool.Spec.Template.Spec.InfrastructureRef.Name)
+	assert.Equal(t, 2, od.InfrastructureMachinePool.Spec["maxSize"])
+
+	spot := pools[1]
+	assert.Contains(t, spot.InfrastructureMachinePool.Spec["awsLaunchTemplate"], "spotMarketOptions")
+
+	data, err := pools.YAML()
+	assert.Nil(t, err)
+	assert.Equal(t, 4, strings.Count(string(data), "---\n"))
+	assert.Contains(t, string(data), "kind: MachinePool")
+}
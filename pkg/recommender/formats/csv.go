@@ -0,0 +1,109 @@
+// Copyright © 2019 Banzai Cloud
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package formats
+
+import (
+	"bytes"
+	"encoding/csv"
+	"strconv"
+
+	"github.com/banzaicloud/telescopes/pkg/recommender"
+	"github.com/pkg/errors"
+)
+
+const (
+	// CSV is the name of the comma separated values output format, for consuming recommendations in spreadsheets
+	CSV = "csv"
+	// CSVContentType is the media type of the CSV output format
+	CSVContentType = "text/csv"
+)
+
+var csvHeader = []string{
+	"role", "vmClass", "vmType", "nodes", "cpusPerVm", "memPerVm", "gpusPerVm",
+	"hourlyPricePerVm", "hourlyCost", "monthlyCost",
+}
+
+// ToCSV renders the node pools of the recommendation as CSV rows, followed by a row of the totals
+// The totals sum the rows above, the master node pools included, so all the columns of the totals cover the same nodes
+// Monthly costs are estimated with the billing model of the provider
+func ToCSV(resp recommender.ClusterRecommendationResp) ([]byte, error) {
+	bm := recommender.BillingModelFor(resp.Provider)
+
+	rows := [][]string{csvHeader}
+	var (
+		sumNodes                      int
+		sumCpus, sumMem, sumGpus      float64
+		sumHourlyCost, sumMonthlyCost float64
+	)
+	for _, np := range resp.NodePools {
+		nodes := float64(np.SumNodes)
+		hourlyCost, monthlyCost := np.PoolPrice(), bm.MonthlyCost(np)
+		sumNodes += np.SumNodes
+		sumCpus += nodes * np.VmType.Cpus
+		sumMem += nodes * np.VmType.Mem
+		sumGpus += nodes * np.VmType.Gpus
+		sumHourlyCost += hourlyCost
+		sumMonthlyCost += monthlyCost
+		rows = append(rows, []string{
+			np.Role,
+			np.VmClass,
+			np.VmType.Type,
+			strconv.Itoa(np.SumNodes),
+			formatValue(np.VmType.Cpus),
+			formatValue(np.VmType.Mem),
+			formatValue(np.VmType.Gpus),
+			formatHourly(unitPrice(np)),
+			formatHourly(hourlyCost),
+			formatMonthly(monthlyCost),
+		})
+	}
+
+	rows = append(rows, []string{
+		"total", "", "",
+		strconv.Itoa(sumNodes),
+		formatValue(sumCpus),
+		formatValue(sumMem),
+		formatValue(sumGpus),
+		"",
+		formatHourly(sumHourlyCost),
+		formatMonthly(sumMonthlyCost),
+	})
+
+	var buf bytes.Buffer
+	w := csv.NewWriter(&buf)
+	if err := w.WriteAll(rows); err != nil {
+		return nil, errors.Wrap(err, "failed to write csv")
+	}
+	return buf.Bytes(), nil
+}
+
+// unitPrice gets the hourly price of a single node of the pool
+func unitPrice(np recommender.NodePool) float64 {
+	single := np
+	single.SumNodes = 1
+	return single.PoolPrice()
+}
+
+func formatValue(v float64) string {
+	return strconv.FormatFloat(v, 'f', -1, 64)
+}
+
+func formatHourly(price float64) string {
+	return strconv.FormatFloat(price, 'f', 4, 64)
+}
+
+func formatMonthly(price float64) string {
+	return strconv.FormatFloat(price, 'f', 2, 64)
+}
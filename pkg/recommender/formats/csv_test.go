@@ -0,0 +1,50 @@
+// Copyright © 2019 Banzai Cloud
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package formats
+
+import (
+	"bytes"
+	"encoding/csv"
+	"testing"
+
+	"github.com/banzaicloud/telescopes/pkg/recommender"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestToCSV(t *testing.T) {
+	resp := recommender.ClusterRecommendationResp{
+		Provider: "amazon",
+		NodePools: []recommender.NodePool{
+			{VmType: recommender.VirtualMachine{Type: "m5.xlarge", Cpus: 4, Mem: 16, OnDemandPrice: 0.192, AvgPrice: 0.07}, SumNodes: 2, VmClass: recommender.Regular, Role: recommender.Worker},
+			{VmType: recommender.VirtualMachine{Type: "c5.xlarge", Cpus: 4, Mem: 8, OnDemandPrice: 0.17, AvgPrice: 0.06}, SumNodes: 3, VmClass: recommender.Spot, Role: recommender.Worker},
+			{VmType: recommender.VirtualMachine{Type: "m5.large", Cpus: 2, Mem: 8, OnDemandPrice: 0.096}, SumNodes: 1, VmClass: recommender.Regular, Role: recommender.Master},
+		},
+		// the accuracy counts the worker nodes only, the totals should sum the rows instead
+		Accuracy: recommender.ClusterRecommendationAccuracy{RecNodes: 5, RecCpu: 20, RecMem: 56, RecTotalPrice: 0.66, RecMonthlyPrice: 481.8},
+	}
+
+	data, err := ToCSV(resp)
+	assert.NoError(t, err)
+
+	rows, err := csv.NewReader(bytes.NewReader(data)).ReadAll()
+	assert.NoError(t, err)
+	assert.Equal(t, [][]string{
+		{"role", "vmClass", "vmType", "nodes", "cpusPerVm", "memPerVm", "gpusPerVm", "hourlyPricePerVm", "hourlyCost", "monthlyCost"},
+		{"worker", "regular", "m5.xlarge", "2", "4", "16", "0", "0.1920", "0.3840", "280.32"},
+		{"worker", "spot", "c5.xlarge", "3", "4", "8", "0", "0.0600", "0.1800", "131.40"},
+		{"master", "regular", "m5.large", "1", "2", "8", "0", "0.0960", "0.0960", "70.08"},
+		{"total", "", "", "6", "22", "64", "0", "", "0.6600", "481.80"},
+	}, rows)
+}
@@ -0,0 +1,116 @@
+// Copyright © 2019 Banzai Cloud
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package formats
+
+import (
+	"fmt"
+	"sort"
+
+	"github.com/banzaicloud/telescopes/pkg/recommender"
+	"github.com/pkg/errors"
+)
+
+// Mig is the name of the GCP regional managed instance group output format
+const Mig = "mig"
+
+// ManagedInstanceGroup holds the managed instance group properties of the spot node pools of a recommendation,
+// as in the Compute Engine API
+type ManagedInstanceGroup struct {
+	// TargetSize is the number of spot nodes
+	TargetSize                int                       `json:"targetSize"`
+	DistributionPolicy        DistributionPolicy        `json:"distributionPolicy"`
+	InstanceFlexibilityPolicy InstanceFlexibilityPolicy `json:"instanceFlexibilityPolicy"`
+}
+
+// DistributionPolicy describes the zones the instances of the group are distributed to
+type DistributionPolicy struct {
+	Zones       []DistributionPolicyZone `json:"zones,omitempty"`
+	TargetShape string                   `json:"targetShape"`
+}
+
+// DistributionPolicyZone is a zone of the distribution policy
+type DistributionPolicyZone struct {
+	Zone string `json:"zone"`
+}
+
+// InstanceFlexibilityPolicy holds the machine types the group may create
+type InstanceFlexibilityPolicy struct {
+	InstanceSelections map[string]InstanceSelection `json:"instanceSelections"`
+}
+
+// InstanceSelection is a set of machine types with a preference, a lower rank is preferred
+type InstanceSelection struct {
+	Rank         int      `json:"rank"`
+	MachineTypes []string `json:"machineTypes"`
+}
+
+// ToManagedInstanceGroup converts the spot worker node pools of a google recommendation into the properties of a
+// regional managed instance group; the machine types are ranked by their share of the weighted spot capacity
+func ToManagedInstanceGroup(resp recommender.ClusterRecommendationResp) (*ManagedInstanceGroup, error) {
+	if resp.Provider != "google" {
+		return nil, errors.Errorf("the %s format is not supported for provider %s", Mig, resp.Provider)
+	}
+
+	var (
+		spotPools  []recommender.NodePool
+		targetSize int
+		zones      = make(map[string]bool)
+	)
+	for _, np := range resp.NodePools {
+		if np.Role == recommender.Master || np.VmClass != recommender.Spot || np.SumNodes == 0 {
+			continue
+		}
+		spotPools = append(spotPools, np)
+		targetSize += np.SumNodes
+		for _, zone := range np.Zones {
+			zones[zone] = true
+		}
+	}
+	if len(spotPools) == 0 {
+		return nil, errors.New("there are no spot node pools in the recommendation")
+	}
+
+	sort.SliceStable(spotPools, func(i, j int) bool {
+		return spotPools[i].SumNodes*poolWeight(spotPools[i]) > spotPools[j].SumNodes*poolWeight(spotPools[j])
+	})
+
+	selections := make(map[string]InstanceSelection, len(spotPools))
+	for i, np := range spotPools {
+		selections[fmt.Sprintf("pool-%d", i+1)] = InstanceSelection{
+			Rank:         i + 1,
+			MachineTypes: []string{np.VmType.Type},
+		}
+	}
+
+	if resp.Zone != "" {
+		zones = map[string]bool{resp.Zone: true}
+	}
+	policy := DistributionPolicy{TargetShape: "BALANCED"}
+	for zone := range zones {
+		policy.Zones = append(policy.Zones, DistributionPolicyZone{Zone: zone})
+	}
+	sort.Slice(policy.Zones, func(i, j int) bool {
+		return policy.Zones[i].Zone < policy.Zones[j].Zone
+	})
+	if len(policy.Zones) == 1 {
+		policy.TargetShape = "ANY_SINGLE_ZONE"
+	}
+
+	return &ManagedInstanceGroup{
+		TargetSize:                targetSize,
+		DistributionPolicy:        policy,
+		InstanceFlexibilityPolicy: InstanceFlexibilityPolicy{InstanceSelections: selections},
+	}, nil
+}
@@ -0,0 +1,52 @@
+// Copyright © 2019 Banzai Cloud
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package formats
+
+import (
+	"testing"
+
+	"github.com/banzaicloud/telescopes/pkg/recommender"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestToManagedInstanceGroup(t *testing.T) {
+	_, err := ToManagedInstanceGroup(recommender.ClusterRecommendationResp{Provider: "amazon"})
+	assert.NotNil(t, err, "the format should not be supported for amazon")
+
+	resp := recommender.ClusterRecommendationResp{
+		Provider: "google",
+		NodePools: []recommender.NodePool{
+			{VmType: recommender.VirtualMachine{Type: "n1-standard-2"}, SumNodes: 2, VmClass: recommender.Regular, Role: recommender.Worker, Weight: 1},
+			{VmType: recommender.VirtualMachine{Type: "n1-standard-4"}, SumNodes: 1, VmClass: recommender.Spot, Role: recommender.Worker, Weight: 2,
+				Zones: []string{"europe-west1-b"}},
+			{VmType: recommender.VirtualMachine{Type: "n1-standard-8"}, SumNodes: 2, VmClass: recommender.Spot, Role: recommender.Worker, Weight: 4,
+				Zones: []string{"europe-west1-c"}},
+		},
+	}
+
+	mig, err := ToManagedInstanceGroup(resp)
+	if !assert.Nil(t, err) {
+		return
+	}
+	assert.Equal(t, 3, mig.TargetSize)
+	assert.Equal(t, map[string]InstanceSelection{
+		"pool-1": {Rank: 1, MachineTypes: []string{"n1-standard-8"}},
+		"pool-2": {Rank: 2, MachineTypes: []string{"n1-standard-4"}},
+	}, mig.InstanceFlexibilityPolicy.InstanceSelections)
+	assert.Equal(t, DistributionPolicy{
+		Zones:       []DistributionPolicyZone{{Zone: "europe-west1-b"}, {Zone: "europe-west1-c"}},
+		TargetShape: "BALANCED",
+	}, mig.DistributionPolicy)
+}
@@ -0,0 +1,54 @@
+// Copyright © 2019 Banzai Cloud
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package recommender
+
+import (
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// DefaultNewestGenPricePct is the price difference within which the newest generation is preferred, unless requested
+const DefaultNewestGenPricePct = 5
+
+// Generation gets the generation of the instance family of the vm, the number in the family name (eg. 5 for m5a),
+// 0 if the family isn't known
+func (v *VirtualMachine) Generation() int {
+	start := strings.IndexFunc(v.Family, unicode.IsDigit)
+	if start == -1 {
+		return 0
+	}
+	end := strings.IndexFunc(v.Family[start:], func(r rune) bool { return !unicode.IsDigit(r) })
+	if end == -1 {
+		end = len(v.Family) - start
+	}
+	gen, err := strconv.Atoi(v.Family[start : start+end])
+	if err != nil {
+		return 0
+	}
+	return gen
+}
+
+// NewestGenPricePct gets the percentage of price difference (per unit) within which the newest generation families
+// are preferred, 0 if they're not preferred
+func (r ClusterRecommendationReq) NewestGenPricePct() int {
+	if !r.PreferNewestGen {
+		return 0
+	}
+	if r.NewestGenTolerancePct > 0 {
+		return r.NewestGenTolerancePct
+	}
+	return DefaultNewestGenPricePct
+}
@@ -0,0 +1,230 @@
+// Copyright © 2019 Banzai Cloud
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package recommender
+
+import (
+	"math"
+	"strings"
+
+	"github.com/goph/emperror"
+)
+
+// earthRadiusKm is the mean radius of the Earth, used for the great-circle distances
+const earthRadiusKm = 6371
+
+// GeoConstraint restricts the regions of a recommendation by their location, eg. for data residency or latency requirements
+type GeoConstraint struct {
+	// Countries the regions must be located in (ISO 3166-1 alpha-2 codes, eg. DE)
+	Countries []string `json:"countries,omitempty"`
+	// Continents the regions must be located in, as named by cloud info (eg. Europe)
+	Continents []string `json:"continents,omitempty"`
+	// Locations of the end users, the regions must be close to at least one of them
+	EndUsers []GeoLocation `json:"endUsers,omitempty" binding:"omitempty,dive"`
+	// Maximum distance (km) of the regions from the nearest end user location
+	MaxDistanceKm float64 `json:"maxDistanceKm,omitempty" binding:"min=0"`
+}
+
+// GeoLocation is a point on the Earth
+type GeoLocation struct {
+	Latitude  float64 `json:"latitude" binding:"min=-90,max=90"`
+	Longitude float64 `json:"longitude" binding:"min=-180,max=180"`
+}
+
+// RegionLocation is the location of a region's data centers
+type RegionLocation struct {
+	// Country of the region (ISO 3166-1 alpha-2 code)
+	Country string
+	GeoLocation
+}
+
+// regionLocations holds the approximate location of the regions, per provider;
+// the cloud info service only exposes the continent of the regions
+var regionLocations = map[string]map[string]RegionLocation{
+	"amazon": {
+		"us-east-1":      {"US", GeoLocation{38.9, -77.4}},
+		"us-east-2":      {"US", GeoLocation{40.0, -83.0}},
+		"us-west-1":      {"US", GeoLocation{37.4, -122.0}},
+		"us-west-2":      {"US", GeoLocation{45.8, -119.7}},
+		"ca-central-1":   {"CA", GeoLocation{45.5, -73.6}},
+		"sa-east-1":      {"BR", GeoLocation{-23.5, -46.6}},
+		"eu-west-1":      {"IE", GeoLocation{53.3, -6.3}},
+		"eu-west-2":      {"GB", GeoLocation{51.5, -0.1}},
+		"eu-west-3":      {"FR", GeoLocation{48.9, 2.4}},
+		"eu-central-1":   {"DE", GeoLocation{50.1, 8.7}},
+		"eu-north-1":     {"SE", GeoLocation{59.3, 18.1}},
+		"eu-south-1":     {"IT", GeoLocation{45.5, 9.2}},
+		"me-south-1":     {"BH", GeoLocation{26.1, 50.6}},
+		"af-south-1":     {"ZA", GeoLocation{-33.9, 18.4}},
+		"ap-east-1":      {"HK", GeoLocation{22.3, 114.2}},
+		"ap-south-1":     {"IN", GeoLocation{19.1, 72.9}},
+		"ap-northeast-1": {"JP", GeoLocation{35.7, 139.7}},
+		"ap-northeast-2": {"KR", GeoLocation{37.6, 127.0}},
+		"ap-northeast-3": {"JP", GeoLocation{34.7, 135.5}},
+		"ap-southeast-1": {"SG", GeoLocation{1.4, 103.8}},
+		"ap-southeast-2": {"AU", GeoLocation{-33.9, 151.2}},
+	},
+	"google": {
+		"us-central1":             {"US", GeoLocation{41.3, -95.9}},
+		"us-east1":                {"US", GeoLocation{33.2, -80.0}},
+		"us-east4":                {"US", GeoLocation{39.0, -77.5}},
+		"us-west1":                {"US", GeoLocation{45.6, -121.2}},
+		"us-west2":                {"US", GeoLocation{34.1, -118.2}},
+		"northamerica-northeast1": {"CA", GeoLocation{45.5, -73.6}},
+		"southamerica-east1":      {"BR", GeoLocation{-23.5, -46.6}},
+		"europe-west1":            {"BE", GeoLocation{50.4, 3.8}},
+		"europe-west2":            {"GB", GeoLocation{51.5, -0.1}},
+		"europe-west3":            {"DE", GeoLocation{50.1, 8.7}},
+		"europe-west4":            {"NL", GeoLocation{53.4, 6.8}},
+		"europe-west6":            {"CH", GeoLocation{47.4, 8.5}},
+		"europe-north1":           {"FI", GeoLocation{60.6, 27.2}},
+		"asia-east1":              {"TW", GeoLocation{24.1, 120.5}},
+		"asia-east2":              {"HK", GeoLocation{22.3, 114.2}},
+		"asia-northeast1":         {"JP", GeoLocation{35.7, 139.7}},
+		"asia-northeast2":         {"JP", GeoLocation{34.7, 135.5}},
+		"asia-south1":             {"IN", GeoLocation{19.1, 72.9}},
+		"asia-southeast1":         {"SG", GeoLocation{1.4, 103.8}},
+		"australia-southeast1":    {"AU", GeoLocation{-33.9, 151.2}},
+	},
+	"azure": {
+		"eastus":             {"US", GeoLocation{37.4, -79.4}},
+		"eastus2":            {"US", GeoLocation{36.7, -78.4}},
+		"centralus":          {"US", GeoLocation{41.6, -93.6}},
+		"westus":             {"US", GeoLocation{37.8, -122.4}},
+		"westus2":            {"US", GeoLocation{47.2, -119.9}},
+		"canadacentral":      {"CA", GeoLocation{43.7, -79.4}},
+		"brazilsouth":        {"BR", GeoLocation{-23.6, -46.6}},
+		"northeurope":        {"IE", GeoLocation{53.3, -6.3}},
+		"westeurope":         {"NL", GeoLocation{52.4, 4.9}},
+		"uksouth":            {"GB", GeoLocation{51.5, -0.1}},
+		"francecentral":      {"FR", GeoLocation{46.3, 2.4}},
+		"germanywestcentral": {"DE", GeoLocation{50.1, 8.7}},
+		"centralindia":       {"IN", GeoLocation{18.6, 73.9}},
+		"japaneast":          {"JP", GeoLocation{35.7, 139.7}},
+		"southeastasia":      {"SG", GeoLocation{1.3, 103.8}},
+		"australiaeast":      {"AU", GeoLocation{-33.9, 151.2}},
+	},
+	"alibaba": {
+		"cn-beijing":     {"CN", GeoLocation{39.9, 116.4}},
+		"cn-hangzhou":    {"CN", GeoLocation{30.3, 120.2}},
+		"cn-shanghai":    {"CN", GeoLocation{31.2, 121.5}},
+		"cn-shenzhen":    {"CN", GeoLocation{22.5, 114.1}},
+		"ap-southeast-1": {"SG", GeoLocation{1.4, 103.8}},
+		"eu-central-1":   {"DE", GeoLocation{50.1, 8.7}},
+		"us-west-1":      {"US", GeoLocation{37.4, -122.0}},
+		"us-east-1":      {"US", GeoLocation{38.9, -77.4}},
+	},
+}
+
+// LocationOf gets the location of the region, returns false if it's unknown
+func LocationOf(provider, region string) (RegionLocation, bool) {
+	location, ok := regionLocations[provider][region]
+	return location, ok
+}
+
+// distanceKm gets the great-circle distance of two locations
+func distanceKm(a, b GeoLocation) float64 {
+	lat1, lat2 := a.Latitude*math.Pi/180, b.Latitude*math.Pi/180
+	dLat, dLon := lat2-lat1, (b.Longitude-a.Longitude)*math.Pi/180
+
+	h := math.Sin(dLat/2)*math.Sin(dLat/2) + math.Cos(lat1)*math.Cos(lat2)*math.Sin(dLon/2)*math.Sin(dLon/2)
+	return 2 * earthRadiusKm * math.Asin(math.Sqrt(h))
+}
+
+// locationAllowed checks the location of a region against the country allowlist and the maximum distance from the end users
+// Regions of unknown location aren't allowed if either of them is set
+func (g *GeoConstraint) locationAllowed(provider, region string) bool {
+	if len(g.Countries) == 0 && (len(g.EndUsers) == 0 || g.MaxDistanceKm == 0) {
+		return true
+	}
+	location, ok := LocationOf(provider, region)
+	if !ok {
+		return false
+	}
+
+	if len(g.Countries) > 0 && !containsFold(g.Countries, location.Country) {
+		return false
+	}
+
+	if len(g.EndUsers) == 0 || g.MaxDistanceKm == 0 {
+		return true
+	}
+	for _, endUser := range g.EndUsers {
+		if distanceKm(location.GeoLocation, endUser) <= g.MaxDistanceKm {
+			return true
+		}
+	}
+	return false
+}
+
+// geoRegions selects the regions satisfying the geo constraint, the continents of the regions are retrieved from cloud info if needed
+func (e *Engine) geoRegions(provider, service string, regions []string, geo *GeoConstraint) ([]string, error) {
+	if geo == nil {
+		return regions, nil
+	}
+
+	var continentOf map[string]string
+	if len(geo.Continents) > 0 {
+		continents, err := e.ciSource.GetContinentsData(provider, service)
+		if err != nil {
+			return nil, err
+		}
+		continentOf = make(map[string]string)
+		for _, continent := range continents {
+			for _, region := range continent.Regions {
+				continentOf[region.Id] = continent.Name
+			}
+		}
+	}
+
+	var allowed []string
+	for _, region := range regions {
+		if continentOf != nil && !containsFold(geo.Continents, continentOf[region]) {
+			continue
+		}
+		if !geo.locationAllowed(provider, region) {
+			continue
+		}
+		allowed = append(allowed, region)
+	}
+	return allowed, nil
+}
+
+// checkGeoRegions checks that all the regions satisfy the geo constraint
+func (e *Engine) checkGeoRegions(provider, service string, regions []string, geo *GeoConstraint) error {
+	allowed, err := e.geoRegions(provider, service, regions, geo)
+	if err != nil {
+		return err
+	}
+	if len(allowed) == len(regions) {
+		return nil
+	}
+
+	for _, region := range regions {
+		if !containsFold(allowed, region) {
+			return emperror.With(ErrRegionNotAllowed, RecommenderErrorTag, "provider", provider, "region", region)
+		}
+	}
+	return nil
+}
+
+// containsFold checks whether the slice contains the string, case insensitively
+func containsFold(slice []string, s string) bool {
+	for _, item := range slice {
+		if strings.EqualFold(item, s) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,81 @@
+// Copyright © 2019 Banzai Cloud
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package recommender
+
+import (
+	"testing"
+
+	"github.com/banzaicloud/telescopes/.gen/cloudinfo"
+	"github.com/goph/logur"
+	"github.com/stretchr/testify/assert"
+)
+
+// continentProducts serves the continents of the amazon regions
+type continentProducts struct {
+	dummyProducts
+}
+
+func (p *continentProducts) GetContinentsData(provider, service string) ([]cloudinfo.Continent, error) {
+	return []cloudinfo.Continent{
+		{Name: "Europe", Regions: []cloudinfo.Region{{Id: "eu-west-1"}, {Id: "eu-central-1"}, {Id: "eu-north-1"}}},
+		{Name: "North America", Regions: []cloudinfo.Region{{Id: "us-east-1"}}},
+	}, nil
+}
+
+func Test_distanceKm(t *testing.T) {
+	dublin, _ := LocationOf("amazon", "eu-west-1")
+	frankfurt, _ := LocationOf("amazon", "eu-central-1")
+
+	assert.InDelta(t, 1090, distanceKm(dublin.GeoLocation, frankfurt.GeoLocation), 20)
+	assert.Equal(t, 0.0, distanceKm(dublin.GeoLocation, dublin.GeoLocation))
+}
+
+func TestEngine_geoRegions(t *testing.T) {
+	regions := []string{"eu-west-1", "eu-central-1", "eu-north-1", "us-east-1", "eu-unknown-1"}
+	tests := []struct {
+		name    string
+		geo     *GeoConstraint
+		regions []string
+	}{
+		{
+			name:    "no constraint",
+			regions: regions,
+		},
+		{
+			name:    "continents",
+			geo:     &GeoConstraint{Continents: []string{"europe"}},
+			regions: []string{"eu-west-1", "eu-central-1", "eu-north-1"},
+		},
+		{
+			name:    "countries",
+			geo:     &GeoConstraint{Countries: []string{"DE", "SE"}},
+			regions: []string{"eu-central-1", "eu-north-1"},
+		},
+		{
+			name:    "distance from the end users",
+			geo:     &GeoConstraint{EndUsers: []GeoLocation{{Latitude: 52.5, Longitude: 13.4}}, MaxDistanceKm: 1000},
+			regions: []string{"eu-central-1", "eu-north-1"},
+		},
+	}
+	for _, test := range tests {
+		test := test
+		t.Run(test.name, func(t *testing.T) {
+			engine := NewEngine(logur.NewTestLogger(), &continentProducts{}, &dummyVms{}, &dummyNodePools{})
+			allowed, err := engine.geoRegions("amazon", "compute", regions, test.geo)
+			assert.Nil(t, err, "the error should be nil")
+			assert.Equal(t, test.regions, allowed)
+		})
+	}
+}
@@ -0,0 +1,207 @@
+// Copyright © 2019 Banzai Cloud
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package recommender
+
+import (
+	"fmt"
+	"strings"
+)
+
+// gpuTypes maps instance families to the model of the attached accelerator, per provider;
+// the cloud info service only exposes the number of GPUs, the model is implied by the instance family
+var gpuTypes = map[string]map[string]string{
+	"amazon": {
+		"p2":   "nvidia-k80",
+		"p3":   "nvidia-v100",
+		"p3dn": "nvidia-v100",
+		"p4d":  "nvidia-a100",
+		"p4de": "nvidia-a100",
+		"p5":   "nvidia-h100",
+		"p5e":  "nvidia-h200",
+		"g3":   "nvidia-m60",
+		"g3s":  "nvidia-m60",
+		"g4dn": "nvidia-t4",
+		"g5":   "nvidia-a10g",
+		"g4ad": "amd-radeon-pro-v520",
+	},
+	"google": {
+		"a2": "nvidia-a100",
+		"g2": "nvidia-l4",
+	},
+}
+
+// gpuMemory holds the memory of an accelerator (GB) attached to the instance families, per provider;
+// families with several memory sizes are keyed by the longer prefix of the type names (eg. a2-ultragpu)
+var gpuMemory = map[string]map[string]float64{
+	"amazon": {
+		"p2":   12,
+		"p3":   16,
+		"p3dn": 32,
+		"p4d":  40,
+		"p4de": 80,
+		"p5":   80,
+		"p5e":  141,
+		"g3":   8,
+		"g3s":  8,
+		"g4dn": 16,
+		"g5":   24,
+		"g4ad": 8,
+	},
+	"google": {
+		"a2":          40,
+		"a2-ultragpu": 80,
+		"g2":          24,
+	},
+}
+
+// capacityReservationFamilies holds the accelerated instance families that can only be launched
+// into capacity reservations (eg. capacity blocks for ML), neither on demand nor as spot instances
+var capacityReservationFamilies = map[string]map[string]bool{
+	"amazon": {
+		"p4de": true,
+		"p5":   true,
+		"p5e":  true,
+	},
+}
+
+// instanceFamily gets the family part of the instance type name
+func instanceFamily(provider string, vmType string) string {
+	switch provider {
+	case "amazon":
+		return strings.SplitN(vmType, ".", 2)[0]
+	case "google":
+		return strings.SplitN(vmType, "-", 2)[0]
+	}
+	return ""
+}
+
+// GpuType gets the accelerator model of the instance type, returns false if it's unknown
+func GpuType(provider string, vmType string) (string, bool) {
+	gpuType, ok := gpuTypes[provider][instanceFamily(provider, vmType)]
+	return gpuType, ok
+}
+
+// GpuMemPerGpu gets the memory of an accelerator (GB) attached to the instance type, returns false if it's unknown
+func GpuMemPerGpu(provider string, vmType string) (float64, bool) {
+	if provider == "google" {
+		if parts := strings.SplitN(vmType, "-", 3); len(parts) == 3 {
+			if mem, ok := gpuMemory[provider][parts[0]+"-"+parts[1]]; ok {
+				return mem, true
+			}
+		}
+	}
+	mem, ok := gpuMemory[provider][instanceFamily(provider, vmType)]
+	return mem, ok
+}
+
+// applyGpuTypes sets the accelerator model and memory, and the launch restrictions of the given vms that have GPUs attached
+func applyGpuTypes(provider string, vms []VirtualMachine) {
+	for i := range vms {
+		if vms[i].Gpus <= 0 {
+			continue
+		}
+		if gpuType, ok := GpuType(provider, vms[i].Type); ok {
+			vms[i].GpuType = gpuType
+		}
+		if mem, ok := GpuMemPerGpu(provider, vms[i].Type); ok {
+			vms[i].GpuMemPerGpu = mem
+		}
+		vms[i].CapacityReservationOnly = capacityReservationFamilies[provider][instanceFamily(provider, vms[i].Type)]
+	}
+}
+
+// launchableIn checks whether the vm can be launched in the zone; vms without zone info are assumed to be launchable anywhere
+func (v *VirtualMachine) launchableIn(zone string) bool {
+	if len(v.Zones) == 0 {
+		return true
+	}
+	for _, z := range v.Zones {
+		if z == zone {
+			return true
+		}
+	}
+	return false
+}
+
+// excludeUnlaunchableGpus drops the GPU vms that can't be launched in the requested zone
+func excludeUnlaunchableGpus(zone string, vms []VirtualMachine) []VirtualMachine {
+	if zone == "" {
+		return vms
+	}
+	launchable := make([]VirtualMachine, 0, len(vms))
+	for _, vm := range vms {
+		if vm.Gpus > 0 && !vm.launchableIn(zone) {
+			continue
+		}
+		launchable = append(launchable, vm)
+	}
+	return launchable
+}
+
+// excludeUnreservedGpus drops the vms that can only be launched into capacity reservations, unless a reservation of
+// the request covers their instance type; the vms kept are not offered as spot instances, their spot price is cleared
+func excludeUnreservedGpus(reservations []Reservation, vms []VirtualMachine) []VirtualMachine {
+	reserved := make(map[string]bool, len(reservations))
+	for _, r := range reservations {
+		reserved[r.Type] = true
+	}
+
+	launchable := make([]VirtualMachine, 0, len(vms))
+	for _, vm := range vms {
+		if vm.CapacityReservationOnly {
+			if !reserved[vm.Type] {
+				continue
+			}
+			vm.AvgPrice = 0
+		}
+		launchable = append(launchable, vm)
+	}
+	return launchable
+}
+
+// pinGpuNodePools pins the GPU node pools to the zones their instance type is launchable in,
+// and collects warnings about the pools that can't be pinned or launched as recommended (eg. regular pools of a
+// capacity reservation only type exceeding the reservation)
+func pinGpuNodePools(zone string, nodePools []NodePool) []Warning {
+	var warnings []Warning
+	for i := range nodePools {
+		vm := nodePools[i].VmType
+		if vm.Gpus <= 0 || nodePools[i].SumNodes == 0 {
+			continue
+		}
+
+		switch {
+		case zone != "":
+			nodePools[i].Zones = []string{zone}
+		case len(nodePools[i].Zones) > 0:
+			// pinned already, eg. to the zone of a capacity reservation
+		case len(vm.Zones) > 0:
+			nodePools[i].Zones = append([]string(nil), vm.Zones...)
+		default:
+			warnings = append(warnings, Warning{
+				Code:    WarnGpuZonesUnknown,
+				Message: fmt.Sprintf("zones of %s are unknown, the node pool may not be launchable in every zone", vm.Type),
+			})
+		}
+
+		if vm.CapacityReservationOnly && nodePools[i].VmClass != Reserved {
+			warnings = append(warnings, Warning{
+				Code:    WarnGpuCapacityReservation,
+				Message: fmt.Sprintf("%s can only be launched into a capacity reservation", vm.Type),
+			})
+		}
+	}
+	return warnings
+}
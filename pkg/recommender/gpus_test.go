@@ -0,0 +1,155 @@
+// Copyright © 2019 Banzai Cloud
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package recommender
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_excludeUnlaunchableGpus(t *testing.T) {
+	vms := []VirtualMachine{
+		{Type: "p3.2xlarge", Gpus: 1, Zones: []string{"us-east-1a", "us-east-1b"}},
+		{Type: "p4d.24xlarge", Gpus: 8, Zones: []string{"us-east-1c"}},
+		{Type: "g4dn.xlarge", Gpus: 1},
+		{Type: "m5.xlarge", Zones: []string{"us-east-1c"}},
+	}
+
+	assert.Equal(t, vms, excludeUnlaunchableGpus("", vms), "all vms should be kept without a requested zone")
+
+	launchable := excludeUnlaunchableGpus("us-east-1a", vms)
+	var types []string
+	for _, vm := range launchable {
+		types = append(types, vm.Type)
+	}
+	assert.Equal(t, []string{"p3.2xlarge", "g4dn.xlarge", "m5.xlarge"}, types)
+}
+
+func Test_pinGpuNodePools(t *testing.T) {
+	tests := []struct {
+		name  string
+		zone  string
+		pools []NodePool
+		check func(pools []NodePool, warnings []Warning)
+	}{
+		{
+			name: "gpu pools are pinned to the zones of the instance type",
+			pools: []NodePool{
+				{SumNodes: 2, VmClass: Regular, VmType: VirtualMachine{Type: "p3.2xlarge", Gpus: 1, Zones: []string{"us-east-1a", "us-east-1b"}}},
+				{SumNodes: 3, VmClass: Spot, VmType: VirtualMachine{Type: "m5.xlarge", Zones: []string{"us-east-1c"}}},
+			},
+			check: func(pools []NodePool, warnings []Warning) {
+				assert.Equal(t, []string{"us-east-1a", "us-east-1b"}, pools[0].Zones)
+				assert.Nil(t, pools[1].Zones, "non-gpu pools should not be pinned")
+				assert.Empty(t, warnings)
+			},
+		},
+		{
+			name: "gpu pools are pinned to the requested zone",
+			zone: "us-east-1b",
+			pools: []NodePool{
+				{SumNodes: 2, VmClass: Regular, VmType: VirtualMachine{Type: "p3.2xlarge", Gpus: 1, Zones: []string{"us-east-1a", "us-east-1b"}}},
+			},
+			check: func(pools []NodePool, warnings []Warning) {
+				assert.Equal(t, []string{"us-east-1b"}, pools[0].Zones)
+				assert.Empty(t, warnings)
+			},
+		},
+		{
+			name: "warnings for unknown zones and capacity reservation only types",
+			pools: []NodePool{
+				{SumNodes: 1, VmClass: Regular, VmType: VirtualMachine{Type: "g4dn.xlarge", Gpus: 1}},
+				{SumNodes: 1, VmClass: Regular, VmType: VirtualMachine{Type: "p5.48xlarge", Gpus: 8, CapacityReservationOnly: true, Zones: []string{"us-east-1a"}}},
+				{SumNodes: 1, VmClass: Reserved, VmType: VirtualMachine{Type: "p5.48xlarge", Gpus: 8, CapacityReservationOnly: true, Zones: []string{"us-east-1a"}}},
+				{SumNodes: 0, VmClass: Spot, VmType: VirtualMachine{Type: "g5.xlarge", Gpus: 1}},
+			},
+			check: func(pools []NodePool, warnings []Warning) {
+				assert.Nil(t, pools[0].Zones)
+				assert.Equal(t, []string{"us-east-1a"}, pools[1].Zones)
+				if assert.Len(t, warnings, 2) {
+					assert.Equal(t, WarnGpuZonesUnknown, warnings[0].Code)
+					assert.Equal(t, WarnGpuCapacityReservation, warnings[1].Code)
+				}
+			},
+		},
+	}
+	for _, test := range tests {
+		test := test
+		t.Run(test.name, func(t *testing.T) {
+			warnings := pinGpuNodePools(test.zone, test.pools)
+			test.check(test.pools, warnings)
+		})
+	}
+}
+
+func Test_excludeUnreservedGpus(t *testing.T) {
+	vms := []VirtualMachine{
+		{Type: "p5.48xlarge", Gpus: 8, CapacityReservationOnly: true, AvgPrice: 30},
+		{Type: "p5e.48xlarge", Gpus: 8, CapacityReservationOnly: true, AvgPrice: 40},
+		{Type: "p3.2xlarge", Gpus: 1, AvgPrice: 1},
+	}
+
+	launchable := excludeUnreservedGpus(nil, vms)
+	if assert.Len(t, launchable, 1) {
+		assert.Equal(t, "p3.2xlarge", launchable[0].Type)
+	}
+
+	launchable = excludeUnreservedGpus([]Reservation{{Type: "p5.48xlarge", Count: 2}}, vms)
+	if assert.Len(t, launchable, 2) {
+		assert.Equal(t, "p5.48xlarge", launchable[0].Type, "reserved types should be kept")
+		assert.Equal(t, 0.0, launchable[0].AvgPrice, "reserved types should not be offered as spot instances")
+		assert.Equal(t, 1.0, launchable[1].AvgPrice)
+	}
+	assert.Equal(t, 30.0, vms[0].AvgPrice, "the products should not be modified")
+}
+
+func Test_applyGpuTypes(t *testing.T) {
+	vms := []VirtualMachine{
+		{Type: "p5.48xlarge", Gpus: 8},
+		{Type: "p3.2xlarge", Gpus: 1},
+		{Type: "m5.xlarge"},
+	}
+	applyGpuTypes("amazon", vms)
+
+	assert.Equal(t, "nvidia-h100", vms[0].GpuType)
+	assert.Equal(t, 80.0, vms[0].GpuMemPerGpu)
+	assert.True(t, vms[0].CapacityReservationOnly)
+	assert.Equal(t, "nvidia-v100", vms[1].GpuType)
+	assert.Equal(t, 16.0, vms[1].GpuMemPerGpu)
+	assert.False(t, vms[1].CapacityReservationOnly)
+	assert.Equal(t, "", vms[2].GpuType)
+	assert.Equal(t, 0.0, vms[2].GpuMemPerGpu)
+}
+
+func TestGpuMemPerGpu(t *testing.T) {
+	tests := []struct {
+		provider string
+		vmType   string
+		mem      float64
+		known    bool
+	}{
+		{provider: "amazon", vmType: "p3dn.24xlarge", mem: 32, known: true},
+		{provider: "google", vmType: "a2-highgpu-1g", mem: 40, known: true},
+		{provider: "google", vmType: "a2-ultragpu-1g", mem: 80, known: true},
+		{provider: "google", vmType: "n1-standard-4", known: false},
+		{provider: "azure", vmType: "Standard_NC6", known: false},
+	}
+	for _, test := range tests {
+		mem, ok := GpuMemPerGpu(test.provider, test.vmType)
+		assert.Equal(t, test.known, ok, test.vmType)
+		assert.Equal(t, test.mem, mem, test.vmType)
+	}
+}
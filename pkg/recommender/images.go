@@ -0,0 +1,90 @@
+// Copyright © 2019 Banzai Cloud
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package recommender
+
+const (
+	// node images
+	NodeImageUbuntu       = "ubuntu"
+	NodeImageRhel         = "rhel"
+	NodeImageSles         = "sles"
+	NodeImageBottlerocket = "bottlerocket"
+)
+
+// licenseSurcharge describes the hourly license fee of a paid node image, tiered by the number of cpus of the vm
+type licenseSurcharge struct {
+	// Vms with at most this many cpus are charged the small price
+	SmallMaxCpus float64
+	// Hourly surcharge of small vms
+	Small float64
+	// Hourly surcharge of the rest of the vms
+	Large float64
+}
+
+// nodeImages holds the supported node images
+var nodeImages = map[string]bool{ // nolint: gochecknoglobals
+	NodeImageUbuntu:       true,
+	NodeImageRhel:         true,
+	NodeImageSles:         true,
+	NodeImageBottlerocket: true,
+}
+
+// licenseSurcharges holds the license fees of the paid node images per provider; images not listed are free
+var licenseSurcharges = map[string]map[string]licenseSurcharge{ // nolint: gochecknoglobals
+	"amazon": {
+		NodeImageRhel: {SmallMaxCpus: 4, Small: 0.06, Large: 0.13},
+		NodeImageSles: {SmallMaxCpus: 1, Small: 0.02, Large: 0.10},
+	},
+	"google": {
+		NodeImageRhel: {SmallMaxCpus: 4, Small: 0.06, Large: 0.13},
+		NodeImageSles: {SmallMaxCpus: 1, Small: 0.02, Large: 0.11},
+	},
+	"azure": {
+		NodeImageRhel: {SmallMaxCpus: 4, Small: 0.06, Large: 0.13},
+		NodeImageSles: {SmallMaxCpus: 2, Small: 0.03, Large: 0.08},
+	},
+}
+
+// IsNodeImage checks whether the node image is supported
+func IsNodeImage(image string) bool {
+	return nodeImages[image]
+}
+
+// LicenseSurcharge gets the hourly license fee of the node image on the given vm
+// Products without cpus (eg. the managed control planes) run no node image, so they aren't charged
+func LicenseSurcharge(provider string, image string, vm VirtualMachine) float64 {
+	ls, ok := licenseSurcharges[provider][image]
+	if !ok || vm.Cpus <= 0 {
+		return 0
+	}
+	if vm.Cpus <= ls.SmallMaxCpus {
+		return ls.Small
+	}
+	return ls.Large
+}
+
+// applyLicenseSurcharges adds the license fee of the node image to the prices of the given vms
+// The fee is charged regardless of the purchase option, so spot prices are raised as well
+func applyLicenseSurcharges(provider string, image string, vms []VirtualMachine) {
+	for i := range vms {
+		surcharge := LicenseSurcharge(provider, image, vms[i])
+		if surcharge == 0 {
+			continue
+		}
+		vms[i].OnDemandPrice += surcharge
+		if vms[i].AvgPrice > 0 {
+			vms[i].AvgPrice += surcharge
+		}
+	}
+}
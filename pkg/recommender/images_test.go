@@ -0,0 +1,82 @@
+// Copyright © 2019 Banzai Cloud
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package recommender
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_applyLicenseSurcharges(t *testing.T) {
+	tests := []struct {
+		name     string
+		provider string
+		image    string
+		vms      []VirtualMachine
+		check    func(vms []VirtualMachine)
+	}{
+		{
+			name:     "free image leaves prices unchanged",
+			provider: "amazon",
+			image:    NodeImageUbuntu,
+			vms:      []VirtualMachine{{Type: "m5.xlarge", Cpus: 4, OnDemandPrice: 0.192, AvgPrice: 0.07}},
+			check: func(vms []VirtualMachine) {
+				assert.Equal(t, 0.192, vms[0].OnDemandPrice)
+				assert.Equal(t, 0.07, vms[0].AvgPrice)
+			},
+		},
+		{
+			name:     "paid image surcharge is tiered by cpus",
+			provider: "amazon",
+			image:    NodeImageRhel,
+			vms: []VirtualMachine{
+				{Type: "m5.xlarge", Cpus: 4, OnDemandPrice: 0.2, AvgPrice: 0.1},
+				{Type: "m5.2xlarge", Cpus: 8, OnDemandPrice: 0.4},
+			},
+			check: func(vms []VirtualMachine) {
+				assert.InDelta(t, 0.26, vms[0].OnDemandPrice, 0.0001)
+				assert.InDelta(t, 0.16, vms[0].AvgPrice, 0.0001)
+				assert.InDelta(t, 0.53, vms[1].OnDemandPrice, 0.0001)
+				assert.Equal(t, float64(0), vms[1].AvgPrice, "missing spot price should not be set")
+			},
+		},
+		{
+			name:     "no surcharge for the control plane",
+			provider: "amazon",
+			image:    NodeImageRhel,
+			vms:      []VirtualMachine{{Type: "EKS Control Plane", Cpus: 0, OnDemandPrice: 0.1}},
+			check: func(vms []VirtualMachine) {
+				assert.Equal(t, 0.1, vms[0].OnDemandPrice)
+			},
+		},
+		{
+			name:     "no surcharge for providers without license fees",
+			provider: "oracle",
+			image:    NodeImageSles,
+			vms:      []VirtualMachine{{Type: "VM.Standard2.1", Cpus: 1, OnDemandPrice: 0.06}},
+			check: func(vms []VirtualMachine) {
+				assert.Equal(t, 0.06, vms[0].OnDemandPrice)
+			},
+		},
+	}
+	for _, test := range tests {
+		test := test
+		t.Run(test.name, func(t *testing.T) {
+			applyLicenseSurcharges(test.provider, test.image, test.vms)
+			test.check(test.vms)
+		})
+	}
+}
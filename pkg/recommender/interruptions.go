@@ -0,0 +1,165 @@
+// Copyright © 2019 Banzai Cloud
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package recommender
+
+import (
+	"bytes"
+	"fmt"
+	"net/url"
+	"sync"
+	"text/template"
+	"time"
+
+	"github.com/goph/emperror"
+)
+
+// DefaultInterruptionQuery is the Prometheus query of the number of recent spot interruptions per instance type and zone
+const DefaultInterruptionQuery = `sum by (type, zone) (increase(spot_interruptions_total{provider="{{.Provider}}",region="{{.Region}}"}[6h]))`
+
+// InterruptionHeat retrieves how hot the spot capacity pools of a region are, based on the recent interruptions
+type InterruptionHeat interface {
+	// Heat retrieves the heat of the capacity pools of the region by instance type and zone,
+	// a score in [0, 1) growing with the number of recent interruptions; pools without interruptions are omitted
+	Heat(provider, service, region string) (map[string]map[string]float64, error)
+}
+
+// heatScore converts the number of recent interruptions to a heat score
+func heatScore(interruptions float64) float64 {
+	if interruptions <= 0 {
+		return 0
+	}
+	return interruptions / (interruptions + 1)
+}
+
+// applyInterruptionHeat sets the heat of the vms, the heat of the zone if given, the average over the zones of the vm otherwise
+func applyInterruptionHeat(zone string, heat map[string]map[string]float64, vms []VirtualMachine) {
+	for i := range vms {
+		zones := heat[vms[i].Type]
+		if zone != "" {
+			vms[i].InterruptionHeat = zones[zone]
+			continue
+		}
+		var sum float64
+		for _, z := range vms[i].Zones {
+			sum += zones[z]
+		}
+		vms[i].InterruptionHeat = 0
+		if len(vms[i].Zones) > 0 {
+			vms[i].InterruptionHeat = sum / float64(len(vms[i].Zones))
+		}
+	}
+}
+
+// prometheusInterruptionHeat maintains the heat of the capacity pools from the interruption events (eg. Hollowtrees
+// or CloudWatch spot interruption warnings) exported to Prometheus; the heat of a region is cached for the given time
+type prometheusInterruptionHeat struct {
+	client prometheusClient
+	query  *template.Template
+	ttl    time.Duration
+
+	mu    sync.Mutex
+	cache map[string]heatEntry
+}
+
+type heatEntry struct {
+	heat    map[string]map[string]float64
+	expires time.Time
+}
+
+// NewPrometheusInterruptionHeat creates an interruption heat source querying the Prometheus server at the address,
+// the query template gets the Provider, Service and Region, and must return series labeled with type and zone
+func NewPrometheusInterruptionHeat(address, query string, ttl time.Duration) (InterruptionHeat, error) {
+	if query == "" {
+		query = DefaultInterruptionQuery
+	}
+	tmpl, err := template.New("query").Parse(query)
+	if err != nil {
+		return nil, emperror.WrapWith(err, "failed to parse the interruption query", "query", query)
+	}
+
+	return &prometheusInterruptionHeat{
+		client: newPrometheusClient(address),
+		query:  tmpl,
+		ttl:    ttl,
+		cache:  make(map[string]heatEntry),
+	}, nil
+}
+
+// Heat runs an instant query, unless the heat of the region was retrieved recently
+func (p *prometheusInterruptionHeat) Heat(provider, service, region string) (map[string]map[string]float64, error) {
+	key := provider + "/" + service + "/" + region
+
+	p.mu.Lock()
+	entry, ok := p.cache[key]
+	p.mu.Unlock()
+	if ok && time.Now().Before(entry.expires) {
+		return entry.heat, nil
+	}
+
+	var query bytes.Buffer
+	err := p.query.Execute(&query, map[string]string{"Provider": provider, "Service": service, "Region": region})
+	if err != nil {
+		return nil, emperror.Wrap(err, "failed to render the interruption query")
+	}
+
+	params := url.Values{}
+	params.Set("query", query.String())
+	result, err := p.client.query("/api/v1/query", params)
+	if err != nil {
+		return nil, emperror.Wrap(err, "failed to query the recent interruptions")
+	}
+
+	heat := make(map[string]map[string]float64)
+	for _, series := range result {
+		_, interruptions, err := parseSample(series.Value)
+		if err != nil {
+			return nil, err
+		}
+		vmType, zone := series.Metric["type"], series.Metric["zone"]
+		if score := heatScore(interruptions); score > 0 && vmType != "" && zone != "" {
+			if heat[vmType] == nil {
+				heat[vmType] = make(map[string]float64)
+			}
+			heat[vmType][zone] = score
+		}
+	}
+
+	p.mu.Lock()
+	p.cache[key] = heatEntry{heat: heat, expires: time.Now().Add(p.ttl)}
+	p.mu.Unlock()
+
+	return heat, nil
+}
+
+// applyInterruptionHeat sets the interruption heat of the vms, the recommendation proceeds without it
+// (with a warning) if the recent interruptions are unknown
+func (e *Engine) applyInterruptionHeat(provider, service, region, zone string, vms []VirtualMachine) *Warning {
+	if e.interruptionHeat == nil {
+		return &Warning{
+			Code:    WarnInterruptionsUnknown,
+			Message: "avoidRecentlyInterrupted ignored, no interruption data source is configured",
+		}
+	}
+	heat, err := e.interruptionHeat.Heat(provider, service, region)
+	if err != nil {
+		e.log.Warn("failed to retrieve the recent interruptions", map[string]interface{}{"provider": provider, "region": region, "error": err.Error()})
+		return &Warning{
+			Code:    WarnInterruptionsUnknown,
+			Message: fmt.Sprintf("avoidRecentlyInterrupted ignored, the recent interruptions in %s are unknown", region),
+		}
+	}
+	applyInterruptionHeat(zone, heat, vms)
+	return nil
+}
@@ -0,0 +1,116 @@
+// Copyright © 2019 Banzai Cloud
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package recommender
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/goph/logur"
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+)
+
+type dummyInterruptionHeat struct {
+	heat map[string]map[string]float64
+	err  error
+}
+
+func (d dummyInterruptionHeat) Heat(provider, service, region string) (map[string]map[string]float64, error) {
+	return d.heat, d.err
+}
+
+func Test_applyInterruptionHeat(t *testing.T) {
+	heat := map[string]map[string]float64{
+		"m5.xlarge": {"eu-west-1a": 0.5, "eu-west-1b": 0.25},
+	}
+	vms := []VirtualMachine{
+		{Type: "m5.xlarge", AvgPrice: 0.1, Zones: []string{"eu-west-1a", "eu-west-1b", "eu-west-1c"}},
+		{Type: "c5.xlarge", AvgPrice: 0.1, Zones: []string{"eu-west-1a"}},
+	}
+
+	applyInterruptionHeat("", heat, vms)
+	assert.Equal(t, 0.25, vms[0].InterruptionHeat, "the heat should be averaged over the zones")
+	assert.Equal(t, 0.0, vms[1].InterruptionHeat)
+	assert.InDelta(t, 0.125, vms[0].RankingPrice(), 1e-9)
+	assert.Equal(t, 0.1, vms[1].RankingPrice())
+
+	applyInterruptionHeat("eu-west-1a", heat, vms)
+	assert.Equal(t, 0.5, vms[0].InterruptionHeat, "the heat of the requested zone should be used")
+}
+
+func TestPrometheusInterruptionHeat_Heat(t *testing.T) {
+	var (
+		query    string
+		requests int32
+	)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&requests, 1)
+		assert.Equal(t, "/api/v1/query", r.URL.Path)
+		query = r.URL.Query().Get("query")
+		_, _ = w.Write([]byte(`{"status":"success","data":{"resultType":"vector","result":[` +
+			`{"metric":{"type":"m5.xlarge","zone":"eu-west-1a"},"value":[1556668800,"3"]},` +
+			`{"metric":{"type":"c5.xlarge","zone":"eu-west-1b"},"value":[1556668800,"0"]}]}}`))
+	}))
+	defer server.Close()
+
+	source, err := NewPrometheusInterruptionHeat(server.URL, "", time.Minute)
+	assert.Nil(t, err, "the error should be nil")
+
+	heat, err := source.Heat("amazon", "compute", "eu-west-1")
+	assert.Nil(t, err, "the error should be nil")
+	assert.Equal(t, `sum by (type, zone) (increase(spot_interruptions_total{provider="amazon",region="eu-west-1"}[6h]))`, query)
+	assert.Equal(t, map[string]map[string]float64{"m5.xlarge": {"eu-west-1a": 0.75}}, heat)
+
+	_, err = source.Heat("amazon", "compute", "eu-west-1")
+	assert.Nil(t, err, "the error should be nil")
+	assert.Equal(t, int32(1), atomic.LoadInt32(&requests), "the heat of the region should be cached")
+
+	_, err = NewPrometheusInterruptionHeat(server.URL, "{{.Region", time.Minute)
+	assert.NotNil(t, err, "an invalid query template should be refused")
+}
+
+func TestEngine_RecommendClusterAvoidRecentlyInterrupted(t *testing.T) {
+	req := SingleClusterRecommendationReq{
+		ClusterRecommendationReq: ClusterRecommendationReq{
+			MinNodes:                 1,
+			MaxNodes:                 1,
+			SumMem:                   32,
+			SumCpu:                   16,
+			OnDemandPct:              50,
+			AvoidRecentlyInterrupted: true,
+		},
+	}
+
+	engine := NewEngine(logur.NewTestLogger(), &dummyProducts{}, &onDemandPctVms{}, &dummyNodePools{})
+	resp, err := engine.RecommendCluster("amazon", "compute", "eu-west-1", req, nil)
+	assert.Nil(t, err, "the error should be nil")
+	assert.Contains(t, warningCodes(resp.Warnings), WarnInterruptionsUnknown)
+
+	engine = NewEngine(logur.NewTestLogger(), &dummyProducts{}, &onDemandPctVms{}, &dummyNodePools{},
+		WithInterruptionHeat(dummyInterruptionHeat{err: errors.New("unavailable")}))
+	resp, err = engine.RecommendCluster("amazon", "compute", "eu-west-1", req, nil)
+	assert.Nil(t, err, "the recommendation should proceed without the interruptions")
+	assert.Contains(t, warningCodes(resp.Warnings), WarnInterruptionsUnknown)
+
+	engine = NewEngine(logur.NewTestLogger(), &dummyProducts{}, &onDemandPctVms{}, &dummyNodePools{},
+		WithInterruptionHeat(dummyInterruptionHeat{heat: map[string]map[string]float64{}}))
+	resp, err = engine.RecommendCluster("amazon", "compute", "eu-west-1", req, nil)
+	assert.Nil(t, err, "the error should be nil")
+	assert.NotContains(t, warningCodes(resp.Warnings), WarnInterruptionsUnknown)
+}
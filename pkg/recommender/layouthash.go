@@ -0,0 +1,53 @@
+// Copyright © 2019 Banzai Cloud
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package recommender
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// layoutHash computes a deterministic hash of the node pool set: the hash only depends on the instance types,
+// classes, roles, node counts and zones of the node pools, not on their order, the prices or the labels
+// Node pools without nodes aren't part of the layout, they're left out
+func layoutHash(nodePools []NodePool) string {
+	pools := make([]string, 0, len(nodePools))
+	for _, np := range nodePools {
+		if np.SumNodes == 0 {
+			continue
+		}
+		zones := append([]string(nil), np.Zones...)
+		sort.Strings(zones)
+
+		zoneNodes := make([]string, 0, len(np.ZoneNodes))
+		for zone, nodes := range np.ZoneNodes {
+			zoneNodes = append(zoneNodes, fmt.Sprintf("%s=%d", zone, nodes))
+		}
+		sort.Strings(zoneNodes)
+
+		pools = append(pools, fmt.Sprintf("%s|%s|%s|%d|%s|%s", np.VmType.Type, np.VmClass, np.Role, np.SumNodes,
+			strings.Join(zones, ","), strings.Join(zoneNodes, ",")))
+	}
+	sort.Strings(pools)
+
+	h := sha256.New()
+	for _, pool := range pools {
+		fmt.Fprintln(h, pool)
+	}
+	return hex.EncodeToString(h.Sum(nil))
+}
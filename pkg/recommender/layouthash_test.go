@@ -0,0 +1,91 @@
+// Copyright © 2019 Banzai Cloud
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package recommender
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLayoutHash(t *testing.T) {
+	layout := []NodePool{
+		{VmType: VirtualMachine{Type: "m5.xlarge", OnDemandPrice: 0.2}, SumNodes: 3, VmClass: Regular, Role: Worker, Zones: []string{"eu-west-1b", "eu-west-1a"}},
+		{VmType: VirtualMachine{Type: "c5.large", AvgPrice: 0.03}, SumNodes: 5, VmClass: Spot, Role: Worker, ZoneNodes: map[string]int{"eu-west-1a": 3, "eu-west-1b": 2}},
+	}
+	hash := layoutHash(layout)
+
+	tests := []struct {
+		name    string
+		layout  []NodePool
+		changed bool
+	}{
+		{
+			name: "node pools and zones reordered",
+			layout: []NodePool{
+				{VmType: VirtualMachine{Type: "c5.large", AvgPrice: 0.03}, SumNodes: 5, VmClass: Spot, Role: Worker, ZoneNodes: map[string]int{"eu-west-1b": 2, "eu-west-1a": 3}},
+				{VmType: VirtualMachine{Type: "m5.xlarge", OnDemandPrice: 0.2}, SumNodes: 3, VmClass: Regular, Role: Worker, Zones: []string{"eu-west-1a", "eu-west-1b"}},
+			},
+		},
+		{
+			name: "prices, labels and empty node pools ignored",
+			layout: []NodePool{
+				{VmType: VirtualMachine{Type: "m5.xlarge", OnDemandPrice: 0.25}, SumNodes: 3, VmClass: Regular, Role: Worker, Zones: []string{"eu-west-1a", "eu-west-1b"}, Labels: map[string]string{"team": "a"}},
+				{VmType: VirtualMachine{Type: "c5.large", AvgPrice: 0.04}, SumNodes: 5, VmClass: Spot, Role: Worker, ZoneNodes: map[string]int{"eu-west-1a": 3, "eu-west-1b": 2}},
+				{VmType: VirtualMachine{Type: "r5.large"}, SumNodes: 0, VmClass: Regular, Role: Worker},
+			},
+		},
+		{
+			name: "node count changed",
+			layout: []NodePool{
+				{VmType: VirtualMachine{Type: "m5.xlarge"}, SumNodes: 4, VmClass: Regular, Role: Worker, Zones: []string{"eu-west-1a", "eu-west-1b"}},
+				{VmType: VirtualMachine{Type: "c5.large"}, SumNodes: 5, VmClass: Spot, Role: Worker, ZoneNodes: map[string]int{"eu-west-1a": 3, "eu-west-1b": 2}},
+			},
+			changed: true,
+		},
+		{
+			name: "zone split changed",
+			layout: []NodePool{
+				{VmType: VirtualMachine{Type: "m5.xlarge"}, SumNodes: 3, VmClass: Regular, Role: Worker, Zones: []string{"eu-west-1a", "eu-west-1b"}},
+				{VmType: VirtualMachine{Type: "c5.large"}, SumNodes: 5, VmClass: Spot, Role: Worker, ZoneNodes: map[string]int{"eu-west-1a": 2, "eu-west-1b": 3}},
+			},
+			changed: true,
+		},
+	}
+	for _, test := range tests {
+		test := test
+		t.Run(test.name, func(t *testing.T) {
+			if test.changed {
+				assert.NotEqual(t, hash, layoutHash(test.layout))
+			} else {
+				assert.Equal(t, hash, layoutHash(test.layout))
+			}
+		})
+	}
+}
+
+func TestClusterRecommendationResp_RecalculateAccuracyLayoutHash(t *testing.T) {
+	resp := &ClusterRecommendationResp{
+		Provider:  "amazon",
+		NodePools: []NodePool{{VmType: VirtualMachine{Type: "m5.xlarge"}, SumNodes: 3, VmClass: Regular, Role: Worker}},
+	}
+	resp.RecalculateAccuracy()
+	hash := resp.LayoutHash
+	assert.Len(t, hash, 64)
+
+	resp.NodePools[0].SumNodes++
+	resp.RecalculateAccuracy()
+	assert.NotEqual(t, hash, resp.LayoutHash, "the hash should follow the node pools")
+}
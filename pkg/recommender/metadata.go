@@ -0,0 +1,125 @@
+// Copyright © 2019 Banzai Cloud
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package recommender
+
+import (
+	"sync"
+	"time"
+)
+
+// Metadata lists the providers with their services, and the regions and zones of the services
+type Metadata struct {
+	Providers []ProviderMetadata `json:"providers"`
+}
+
+// ProviderMetadata lists the services of a provider
+type ProviderMetadata struct {
+	Provider string            `json:"provider"`
+	Services []ServiceMetadata `json:"services"`
+}
+
+// ServiceMetadata lists the regions of a service
+type ServiceMetadata struct {
+	Service string           `json:"service"`
+	Regions []RegionMetadata `json:"regions"`
+}
+
+// RegionMetadata lists the zones of a region
+type RegionMetadata struct {
+	Id    string   `json:"id"`
+	Name  string   `json:"name"`
+	Zones []string `json:"zones"`
+}
+
+// metadataCache keeps the metadata for a while, collecting it takes a call per region
+type metadataCache struct {
+	ttl time.Duration
+	now func() time.Time
+
+	mux       sync.Mutex
+	metadata  *Metadata
+	expiresAt time.Time
+}
+
+func newMetadataCache(ttl time.Duration) *metadataCache {
+	return &metadataCache{
+		ttl: ttl,
+		now: time.Now,
+	}
+}
+
+func (mc *metadataCache) get() (*Metadata, bool) {
+	mc.mux.Lock()
+	defer mc.mux.Unlock()
+
+	if mc.metadata == nil || mc.now().After(mc.expiresAt) {
+		return nil, false
+	}
+	return mc.metadata, true
+}
+
+func (mc *metadataCache) put(metadata *Metadata) {
+	mc.mux.Lock()
+	defer mc.mux.Unlock()
+
+	mc.metadata = metadata
+	mc.expiresAt = mc.now().Add(mc.ttl)
+}
+
+// Metadata collects the providers, services, regions and zones in a single payload, eg. for the selection lists of
+// user interfaces; the providers not enabled for the tenant of the engine are left out
+func (e *Engine) Metadata() (*Metadata, error) {
+	if e.metadata != nil {
+		if metadata, ok := e.metadata.get(); ok {
+			return metadata, nil
+		}
+	}
+
+	providers, err := e.ciSource.GetProviders()
+	if err != nil {
+		return nil, err
+	}
+
+	metadata := &Metadata{Providers: make([]ProviderMetadata, 0, len(providers))}
+	for _, provider := range providers {
+		if e.checkTenantProvider(provider.Provider) != nil {
+			continue
+		}
+
+		pm := ProviderMetadata{Provider: provider.Provider, Services: make([]ServiceMetadata, 0, len(provider.Services))}
+		for _, service := range provider.Services {
+			regions, err := e.ciSource.GetRegions(provider.Provider, service.Service)
+			if err != nil {
+				return nil, err
+			}
+
+			sm := ServiceMetadata{Service: service.Service, Regions: make([]RegionMetadata, 0, len(regions))}
+			for _, region := range regions {
+				zones, err := e.ciSource.GetZones(provider.Provider, service.Service, region.Id)
+				if err != nil {
+					return nil, err
+				}
+				sm.Regions = append(sm.Regions, RegionMetadata{Id: region.Id, Name: region.Name, Zones: zones})
+			}
+			pm.Services = append(pm.Services, sm)
+		}
+		metadata.Providers = append(metadata.Providers, pm)
+	}
+
+	if e.metadata != nil {
+		e.metadata.put(metadata)
+	}
+	return metadata, nil
+}
@@ -0,0 +1,84 @@
+// Copyright © 2019 Banzai Cloud
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package recommender
+
+import (
+	"testing"
+	"time"
+
+	"github.com/banzaicloud/telescopes/.gen/cloudinfo"
+	"github.com/goph/logur"
+	"github.com/stretchr/testify/assert"
+)
+
+// metadataProducts serves the providers, regions and zones, counting the calls
+type metadataProducts struct {
+	dummyProducts
+	calls int
+}
+
+func (p *metadataProducts) GetProviders() ([]cloudinfo.Provider, error) {
+	p.calls++
+	return []cloudinfo.Provider{
+		{Provider: "amazon", Services: []cloudinfo.Service{{Service: "compute"}, {Service: "eks"}}},
+		{Provider: "google", Services: []cloudinfo.Service{{Service: "compute"}}},
+	}, nil
+}
+
+func (p *metadataProducts) GetRegions(provider, service string) ([]cloudinfo.Region, error) {
+	p.calls++
+	if provider == "google" {
+		return []cloudinfo.Region{{Id: "europe-west1", Name: "Belgium"}}, nil
+	}
+	return []cloudinfo.Region{{Id: "eu-west-1", Name: "EU (Ireland)"}}, nil
+}
+
+func (p *metadataProducts) GetZones(provider, service, region string) ([]string, error) {
+	p.calls++
+	return []string{region + "a", region + "b"}, nil
+}
+
+func TestEngine_Metadata(t *testing.T) {
+	source := &metadataProducts{}
+	engine := NewEngine(logur.NewTestLogger(), source, nil, nil, WithMetadataCache(time.Minute))
+	now := time.Now()
+	engine.metadata.now = func() time.Time { return now }
+
+	metadata, err := engine.Metadata()
+	if !assert.NoError(t, err) {
+		return
+	}
+	if assert.Len(t, metadata.Providers, 2) && assert.Len(t, metadata.Providers[0].Services, 2) {
+		assert.Equal(t, "eks", metadata.Providers[0].Services[1].Service)
+		assert.Equal(t, []RegionMetadata{{Id: "eu-west-1", Name: "EU (Ireland)", Zones: []string{"eu-west-1a", "eu-west-1b"}}},
+			metadata.Providers[0].Services[1].Regions)
+	}
+	assert.Equal(t, 7, source.calls)
+
+	_, err = engine.Metadata()
+	assert.NoError(t, err)
+	assert.Equal(t, 7, source.calls, "the metadata should be cached")
+
+	now = now.Add(2 * time.Minute)
+	_, err = engine.Metadata()
+	assert.NoError(t, err)
+	assert.Equal(t, 14, source.calls, "the expired metadata should be retrieved again")
+
+	tenantEngine := engine.forTenant(Tenant{Name: "team-a", Providers: []string{"google"}}, &metadataProducts{})
+	metadata, err = tenantEngine.Metadata()
+	if assert.NoError(t, err) && assert.Len(t, metadata.Providers, 1) {
+		assert.Equal(t, "google", metadata.Providers[0].Provider)
+	}
+}
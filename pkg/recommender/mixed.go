@@ -0,0 +1,59 @@
+// Copyright © 2019 Banzai Cloud
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package recommender
+
+// splitMixedPools splits the mixed node pools of the layout into a regular and a spot node pool of the same instance
+// type, the way an ASG MixedInstancesPolicy distributes the nodes: the on-demand base capacity is regular, the nodes
+// above it are spot by the spot percentage, rounded in favor of the regular nodes
+func splitMixedPools(layoutDesc []NodePoolDesc) []NodePoolDesc {
+	if layoutDesc == nil {
+		return nil
+	}
+	split := make([]NodePoolDesc, 0, len(layoutDesc))
+	for _, npd := range layoutDesc {
+		if npd.VmClass != Mixed {
+			split = append(split, npd)
+			continue
+		}
+
+		base := clamp(npd.OnDemandBaseCapacity, 0, npd.SumNodes)
+		spotNodes := (npd.SumNodes - base) * clamp(npd.SpotPct, 0, 100) / 100
+
+		regular, spot := npd, npd
+		regular.VmClass, regular.SumNodes = Regular, npd.SumNodes-spotNodes
+		spot.VmClass, spot.SumNodes = Spot, spotNodes
+		regular.OnDemandBaseCapacity, regular.SpotPct = 0, 0
+		spot.OnDemandBaseCapacity, spot.SpotPct = 0, 0
+
+		if regular.SumNodes > 0 || spotNodes == 0 {
+			split = append(split, regular)
+		}
+		if spotNodes > 0 {
+			split = append(split, spot)
+		}
+	}
+	return split
+}
+
+// clamp limits the value to the range
+func clamp(value, min, max int) int {
+	if value < min {
+		return min
+	}
+	if value > max {
+		return max
+	}
+	return value
+}
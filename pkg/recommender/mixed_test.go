@@ -0,0 +1,39 @@
+// Copyright © 2019 Banzai Cloud
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package recommender
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_splitMixedPools(t *testing.T) {
+	layout := []NodePoolDesc{
+		{InstanceType: "m5.xlarge", VmClass: Mixed, SumNodes: 10, OnDemandBaseCapacity: 2, SpotPct: 70},
+		{InstanceType: "c5.xlarge", VmClass: Mixed, SumNodes: 3, OnDemandBaseCapacity: 5, SpotPct: 100},
+		{InstanceType: "r5.xlarge", VmClass: Mixed, SumNodes: 4, SpotPct: 100, Pinned: true},
+		{InstanceType: "m5.large", VmClass: Spot, SumNodes: 2},
+	}
+
+	assert.Equal(t, []NodePoolDesc{
+		{InstanceType: "m5.xlarge", VmClass: Regular, SumNodes: 5},
+		{InstanceType: "m5.xlarge", VmClass: Spot, SumNodes: 5},
+		{InstanceType: "c5.xlarge", VmClass: Regular, SumNodes: 3},
+		{InstanceType: "r5.xlarge", VmClass: Spot, SumNodes: 4, Pinned: true},
+		{InstanceType: "m5.large", VmClass: Spot, SumNodes: 2},
+	}, splitMixedPools(layout), "the nodes above the base capacity should be split rounding in favor of the regular nodes")
+	assert.Nil(t, splitMixedPools(nil))
+}
@@ -0,0 +1,174 @@
+// Copyright © 2019 Banzai Cloud
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package recommender
+
+import (
+	"math"
+
+	"github.com/goph/emperror"
+	"golang.org/x/sync/errgroup"
+)
+
+// RegionShare is the share of the requested capacity placed in a region
+type RegionShare struct {
+	// Region the capacity is placed in
+	Region string `json:"region" binding:"required"`
+	// Percentage of the requested capacity placed in the region
+	Pct int `json:"pct" binding:"min=1,max=100"`
+}
+
+// MultiRegionRecommendationReq encapsulates the input data of a recommendation spread across regions
+// swagger:model recommendMultiRegionRequest
+type MultiRegionRecommendationReq struct {
+	// Regions the requested capacity is split across, the percentages must add up to 100
+	Regions []RegionShare `json:"regions" binding:"required,min=2,dive"`
+	// Embedded struct
+	ClusterRecommendationReq
+	// Excludes is a blacklist - a slice with vm types to be excluded from the recommendation
+	Excludes []string `json:"excludes,omitempty"`
+	// Includes is a whitelist - a slice with vm types to be contained in the recommendation
+	Includes []string `json:"includes,omitempty"`
+	// Geo restricts the regions by their location, the recommendation is refused if any of the regions doesn't satisfy it
+	Geo *GeoConstraint `json:"geo,omitempty"`
+	// Units of the memory of the request and the memory and prices of the response
+	Units Units `json:"units,omitempty"`
+}
+
+// MultiRegionRecommendationResp encapsulates the recommendations per region and their combined accuracy
+type MultiRegionRecommendationResp struct {
+	// The cloud provider
+	Provider string `json:"provider"`
+	// Provider's service
+	Service string `json:"service"`
+	// Recommendations per region, in the order of the request
+	Regions []ClusterRecommendationResp `json:"regions"`
+	// Combined accuracy of the regional recommendations
+	Accuracy ClusterRecommendationAccuracy `json:"accuracy"`
+	// Units of the memory and the prices of the recommendations, omitted for the default GiB and hourly prices
+	Units *Units `json:"units,omitempty"`
+	// Number of calls made to the cloud info service for the recommendation, returned in a response header
+	CloudInfoCalls int `json:"-"`
+}
+
+// RecommendMultiRegion recommends a node pool set per region, the requested capacity split by the region shares
+func (e *Engine) RecommendMultiRegion(provider string, service string, req MultiRegionRecommendationReq) (*MultiRegionRecommendationResp, error) {
+	if err := validateRegionShares(req.Regions); err != nil {
+		return nil, err
+	}
+
+	responses := make([]ClusterRecommendationResp, len(req.Regions))
+	budgeted, budget := e.withCallBudget()
+
+	regions := make([]string, 0, len(req.Regions))
+	for _, share := range req.Regions {
+		regions = append(regions, share.Region)
+	}
+	if err := budgeted.checkGeoRegions(provider, service, regions, req.Geo); err != nil {
+		return nil, err
+	}
+
+	var g errgroup.Group
+	for i, share := range req.Regions {
+		i, share := i, share
+		g.Go(func() error {
+			regionReq := SingleClusterRecommendationReq{
+				ClusterRecommendationReq: splitClusterRecommendationReq(req.ClusterRecommendationReq, share.Pct),
+				Excludes:                 req.Excludes,
+				Includes:                 req.Includes,
+			}
+			resp, err := budgeted.recommendSingleCluster(provider, service, share.Region, regionReq, nil)
+			if err != nil {
+				return emperror.With(err, "region", share.Region)
+			}
+			responses[i] = *resp
+			return nil
+		})
+	}
+	if err := g.Wait(); err != nil {
+		return nil, err
+	}
+
+	return &MultiRegionRecommendationResp{
+		Provider:       provider,
+		Service:        service,
+		Regions:        responses,
+		Accuracy:       combineAccuracies(responses),
+		CloudInfoCalls: budget.count(),
+	}, nil
+}
+
+// RecalculateAccuracy updates the combined accuracy after the regional recommendations were changed
+func (r *MultiRegionRecommendationResp) RecalculateAccuracy() {
+	r.Accuracy = combineAccuracies(r.Regions)
+}
+
+// validateRegionShares checks that the regions are distinct and their shares add up to 100%
+func validateRegionShares(shares []RegionShare) error {
+	var (
+		sumPct  int
+		regions = make(map[string]bool)
+	)
+	for _, share := range shares {
+		if regions[share.Region] {
+			return emperror.With(ErrInvalidRegionShares, RecommenderErrorTag, "region", share.Region)
+		}
+		regions[share.Region] = true
+		sumPct += share.Pct
+	}
+	if sumPct != 100 {
+		return emperror.With(ErrInvalidRegionShares, RecommenderErrorTag, "sumPct", sumPct)
+	}
+	return nil
+}
+
+// splitClusterRecommendationReq scales the requested resources and node counts to the share of a region
+// Reservations are dropped, as they are regional and the request doesn't tell which region they belong to
+func splitClusterRecommendationReq(req ClusterRecommendationReq, pct int) ClusterRecommendationReq {
+	share := float64(pct) / 100
+
+	req.SumCpu *= share
+	req.SumMem *= share
+	req.SumGpu = int(math.Ceil(float64(req.SumGpu) * share))
+	req.SumPods = int(math.Ceil(float64(req.SumPods) * share))
+	req.OnDemandCount = int(math.Ceil(float64(req.OnDemandCount) * share))
+	req.MinNodes = int(math.Max(1, math.Ceil(float64(req.MinNodes)*share)))
+	req.MaxNodes = int(math.Max(float64(req.MinNodes), math.Ceil(float64(req.MaxNodes)*share)))
+	req.AttributeNodes = splitNodeBounds(req.AttributeNodes, share)
+	req.Reservations = nil
+
+	return req
+}
+
+// combineAccuracies sums the accuracies of the regional recommendations
+func combineAccuracies(responses []ClusterRecommendationResp) ClusterRecommendationAccuracy {
+	var combined ClusterRecommendationAccuracy
+	for _, resp := range responses {
+		acc := resp.Accuracy
+		combined.RecMem += acc.RecMem
+		combined.RecCpu += acc.RecCpu
+		combined.RecNodes += acc.RecNodes
+		combined.RecNodePools += acc.RecNodePools
+		combined.RecRegularPrice += acc.RecRegularPrice
+		combined.RecRegularNodes += acc.RecRegularNodes
+		combined.RecSpotPrice += acc.RecSpotPrice
+		combined.RecSpotNodes += acc.RecSpotNodes
+		combined.RecWorkerPrice += acc.RecWorkerPrice
+		combined.RecMasterPrice += acc.RecMasterPrice
+		combined.RecTotalPrice += acc.RecTotalPrice
+		combined.RecMonthlyPrice += acc.RecMonthlyPrice
+		combined.RecYearlyPrice += acc.RecYearlyPrice
+	}
+	return combined
+}
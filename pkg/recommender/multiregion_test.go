@@ -0,0 +1,107 @@
+// Copyright © 2019 Banzai Cloud
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package recommender
+
+import (
+	"testing"
+
+	"github.com/goph/logur"
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_splitClusterRecommendationReq(t *testing.T) {
+	req := ClusterRecommendationReq{
+		SumCpu:       100,
+		SumMem:       200,
+		SumGpu:       3,
+		MinNodes:     5,
+		MaxNodes:     10,
+		Reservations: []Reservation{{Type: "m5.xlarge", Count: 2, RemainingMonths: 12}},
+	}
+
+	split := splitClusterRecommendationReq(req, 30)
+
+	assert.InDelta(t, 30, split.SumCpu, 0.0001)
+	assert.InDelta(t, 60, split.SumMem, 0.0001)
+	assert.Equal(t, 1, split.SumGpu)
+	assert.Equal(t, 2, split.MinNodes)
+	assert.Equal(t, 3, split.MaxNodes)
+	assert.Nil(t, split.Reservations)
+	assert.Equal(t, float64(100), req.SumCpu, "the original request should not change")
+}
+
+func TestEngine_RecommendMultiRegion(t *testing.T) {
+	tests := []struct {
+		name    string
+		regions []RegionShare
+		geo     *GeoConstraint
+		check   func(resp *MultiRegionRecommendationResp, err error)
+	}{
+		{
+			name:    "shares not adding up to 100%",
+			regions: []RegionShare{{Region: "eu-west-1", Pct: 70}, {Region: "eu-central-1", Pct: 20}},
+			check: func(resp *MultiRegionRecommendationResp, err error) {
+				assert.Nil(t, resp)
+				assert.Equal(t, ErrInvalidRegionShares, errors.Cause(err))
+			},
+		},
+		{
+			name:    "duplicate regions",
+			regions: []RegionShare{{Region: "eu-west-1", Pct: 50}, {Region: "eu-west-1", Pct: 50}},
+			check: func(resp *MultiRegionRecommendationResp, err error) {
+				assert.Nil(t, resp)
+				assert.Equal(t, ErrInvalidRegionShares, errors.Cause(err))
+			},
+		},
+		{
+			name:    "regions outside the geo constraint",
+			regions: []RegionShare{{Region: "eu-west-1", Pct: 70}, {Region: "eu-central-1", Pct: 30}},
+			geo:     &GeoConstraint{Countries: []string{"DE"}},
+			check: func(resp *MultiRegionRecommendationResp, err error) {
+				assert.Nil(t, resp)
+				assert.Equal(t, ErrRegionNotAllowed, errors.Cause(err))
+			},
+		},
+		{
+			name:    "recommendation per region",
+			regions: []RegionShare{{Region: "eu-west-1", Pct: 70}, {Region: "eu-central-1", Pct: 30}},
+			check: func(resp *MultiRegionRecommendationResp, err error) {
+				assert.Nil(t, err)
+				assert.Len(t, resp.Regions, 2)
+				assert.Equal(t, "eu-west-1", resp.Regions[0].Region)
+				assert.Equal(t, "eu-central-1", resp.Regions[1].Region)
+				assert.Equal(t, resp.Regions[0].Accuracy.RecCpu+resp.Regions[1].Accuracy.RecCpu, resp.Accuracy.RecCpu)
+				assert.Equal(t, resp.Regions[0].Accuracy.RecTotalPrice+resp.Regions[1].Accuracy.RecTotalPrice, resp.Accuracy.RecTotalPrice)
+			},
+		},
+	}
+	for _, test := range tests {
+		test := test
+		t.Run(test.name, func(t *testing.T) {
+			engine := NewEngine(logur.NewTestLogger(), &dummyProducts{}, &dummyVms{}, &dummyNodePools{})
+			test.check(engine.RecommendMultiRegion("dummyProvider", "dummyService", MultiRegionRecommendationReq{
+				Regions: test.regions,
+				Geo:     test.geo,
+				ClusterRecommendationReq: ClusterRecommendationReq{
+					MinNodes: 1,
+					MaxNodes: 4,
+					SumMem:   32,
+					SumCpu:   16,
+				},
+			}))
+		})
+	}
+}
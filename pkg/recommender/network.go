@@ -0,0 +1,98 @@
+// Copyright © 2019 Banzai Cloud
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package recommender
+
+import (
+	"regexp"
+	"strconv"
+)
+
+// NetworkBandwidthMapper maps the network performance reported for the instance types of a provider to a bandwidth
+// range, as the network performance categories of the providers don't compare
+type NetworkBandwidthMapper interface {
+	// Bandwidth gets the guaranteed and the peak bandwidth in Gbps of the network performance, false if it's unknown
+	Bandwidth(networkPerf string) (min float64, max float64, ok bool)
+}
+
+// networkBandwidthMappers holds the network bandwidth mappers of the providers
+var networkBandwidthMappers = map[string]NetworkBandwidthMapper{
+	"amazon": ec2NetworkBandwidth{},
+	"google": gceNetworkBandwidth{},
+}
+
+var (
+	ec2GigabitRe = regexp.MustCompile(`^(Up to )?([0-9.]+) Gigabit$`)
+	gceGbitRe    = regexp.MustCompile(`^([0-9.]+) Gbit/s$`)
+)
+
+// ec2NamedBandwidths holds the bandwidth ranges of the named network performances of the older EC2 instance types
+var ec2NamedBandwidths = map[string][2]float64{
+	"Very Low":        {0.05, 0.1},
+	"Low":             {0.1, 0.3},
+	"Low to Moderate": {0.3, 0.5},
+	"Moderate":        {0.5, 1},
+	"High":            {1, 10},
+}
+
+// ec2NetworkBandwidth maps the EC2 network performances, eg. "Moderate", "10 Gigabit" or "Up to 25 Gigabit"; the
+// burstable bandwidths ("Up to") have no guaranteed bandwidth known
+type ec2NetworkBandwidth struct{}
+
+func (ec2NetworkBandwidth) Bandwidth(networkPerf string) (float64, float64, bool) {
+	if bw, ok := ec2NamedBandwidths[networkPerf]; ok {
+		return bw[0], bw[1], true
+	}
+	m := ec2GigabitRe.FindStringSubmatch(networkPerf)
+	if m == nil {
+		return 0, 0, false
+	}
+	gbps, err := strconv.ParseFloat(m[2], 64)
+	if err != nil {
+		return 0, 0, false
+	}
+	if m[1] != "" {
+		return 0, gbps, true
+	}
+	return gbps, gbps, true
+}
+
+// gceNetworkBandwidth maps the GCE egress bandwidth caps, eg. "16 Gbit/s"
+type gceNetworkBandwidth struct{}
+
+func (gceNetworkBandwidth) Bandwidth(networkPerf string) (float64, float64, bool) {
+	m := gceGbitRe.FindStringSubmatch(networkPerf)
+	if m == nil {
+		return 0, 0, false
+	}
+	gbps, err := strconv.ParseFloat(m[1], 64)
+	if err != nil {
+		return 0, 0, false
+	}
+	return gbps, gbps, true
+}
+
+// applyNetworkBandwidths sets the bandwidth range of the given vms, if the network performance of the provider is known
+func applyNetworkBandwidths(provider string, vms []VirtualMachine) {
+	mapper, ok := networkBandwidthMappers[provider]
+	if !ok {
+		return
+	}
+	for i := range vms {
+		if min, max, ok := mapper.Bandwidth(vms[i].NetworkPerf); ok {
+			vms[i].NetworkGbpsMin = min
+			vms[i].NetworkGbpsMax = max
+		}
+	}
+}
@@ -0,0 +1,46 @@
+// Copyright © 2019 Banzai Cloud
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package recommender
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_applyNetworkBandwidths(t *testing.T) {
+	tests := []struct {
+		provider    string
+		networkPerf string
+		min, max    float64
+	}{
+		{provider: "amazon", networkPerf: "Moderate", min: 0.5, max: 1},
+		{provider: "amazon", networkPerf: "10 Gigabit", min: 10, max: 10},
+		{provider: "amazon", networkPerf: "Up to 25 Gigabit", min: 0, max: 25},
+		{provider: "amazon", networkPerf: "12.5 Gigabit", min: 12.5, max: 12.5},
+		{provider: "amazon", networkPerf: "unknown"},
+		{provider: "google", networkPerf: "16 Gbit/s", min: 16, max: 16},
+		{provider: "azure", networkPerf: "10 Gigabit"},
+	}
+	for _, test := range tests {
+		test := test
+		t.Run(test.provider+" "+test.networkPerf, func(t *testing.T) {
+			vms := []VirtualMachine{{Type: "vm", NetworkPerf: test.networkPerf}}
+			applyNetworkBandwidths(test.provider, vms)
+			assert.Equal(t, test.min, vms[0].NetworkGbpsMin)
+			assert.Equal(t, test.max, vms[0].NetworkGbpsMax)
+		})
+	}
+}
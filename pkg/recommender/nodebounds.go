@@ -0,0 +1,131 @@
+// Copyright © 2019 Banzai Cloud
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package recommender
+
+import (
+	"math"
+
+	"github.com/goph/emperror"
+)
+
+// NodeBounds overrides the node count bounds of the request for the node pools recommended for an attribute
+type NodeBounds struct {
+	// Minimum number of nodes, the minimum of the request if not set
+	MinNodes int `json:"minNodes,omitempty"`
+	// Maximum number of nodes, the maximum of the request if not set
+	MaxNodes int `json:"maxNodes,omitempty"`
+}
+
+// nodeBounds returns the minimum and maximum number of nodes of the node pools recommended for the attribute
+func (r ClusterRecommendationReq) nodeBounds(attr string) (int, int) {
+	minNodes, maxNodes := r.MinNodes, r.MaxNodes
+	if bounds, ok := r.AttributeNodes[attr]; ok {
+		if bounds.MinNodes > 0 {
+			minNodes = bounds.MinNodes
+		}
+		if bounds.MaxNodes > 0 {
+			maxNodes = bounds.MaxNodes
+		}
+	}
+	return minNodes, maxNodes
+}
+
+// maxNodesBound returns the largest maximum number of nodes of any attribute
+func (r ClusterRecommendationReq) maxNodesBound() int {
+	maxNodes := r.MaxNodes
+	for _, bounds := range r.AttributeNodes {
+		if bounds.MaxNodes > maxNodes {
+			maxNodes = bounds.MaxNodes
+		}
+	}
+	return maxNodes
+}
+
+// PerNodeBounds returns the minimum and maximum value of the attribute per node requested explicitly, zero if not set
+func (r ClusterRecommendationReq) PerNodeBounds(attr string) (float64, float64) {
+	switch attr {
+	case Cpu:
+		return r.MinCpuPerNode, r.MaxCpuPerNode
+	case Memory:
+		return r.MinMemPerNode, r.MaxMemPerNode
+	default:
+		return 0, 0
+	}
+}
+
+// minPerNode gets the minimum value per node: the explicit minimum if set, otherwise the derived one, lowered to the
+// explicit maximum if it's less
+func minPerNode(derived, min, max float64) float64 {
+	if min > 0 {
+		return min
+	}
+	if max > 0 && max < derived {
+		return max
+	}
+	return derived
+}
+
+// validatePerNodeBounds checks that the explicit minimum values per node don't exceed the maximums
+func validatePerNodeBounds(req ClusterRecommendationReq) error {
+	for _, attr := range []string{Cpu, Memory} {
+		min, max := req.PerNodeBounds(attr)
+		if min > 0 && max > 0 && min > max {
+			return emperror.With(ErrMinPerNodeGreaterThanMax, RecommenderErrorTag, "attribute", attr, "min", min, "max", max)
+		}
+	}
+	return nil
+}
+
+// validateAttributeNodes checks the attribute specific node bounds against the supported attributes and the rest of the request
+func validateAttributeNodes(req ClusterRecommendationReq) error {
+	for attr, bounds := range req.AttributeNodes {
+		if attr != Cpu && attr != Memory {
+			return emperror.With(ErrUnsupportedBoundsAttribute, RecommenderErrorTag, "attribute", attr)
+		}
+		if bounds.MinNodes < 0 || bounds.MaxNodes < 0 {
+			return emperror.With(ErrInvalidNodeBounds, RecommenderErrorTag, "attribute", attr,
+				"minNodes", bounds.MinNodes, "maxNodes", bounds.MaxNodes)
+		}
+
+		minNodes, maxNodes := req.nodeBounds(attr)
+		if minNodes > maxNodes {
+			return emperror.With(ErrMinGreaterThanMax, RecommenderErrorTag, "attribute", attr, "minNodes", minNodes, "maxNodes", maxNodes)
+		}
+		if req.OnDemandCount > maxNodes {
+			return emperror.With(ErrOnDemandCountExceedsMax, RecommenderErrorTag, "attribute", attr,
+				"onDemandCount", req.OnDemandCount, "maxNodes", maxNodes)
+		}
+	}
+	return nil
+}
+
+// splitNodeBounds scales the attribute specific node bounds to the given share, unset bounds stay unset
+func splitNodeBounds(attributeNodes map[string]NodeBounds, share float64) map[string]NodeBounds {
+	if attributeNodes == nil {
+		return nil
+	}
+
+	split := make(map[string]NodeBounds, len(attributeNodes))
+	for attr, bounds := range attributeNodes {
+		if bounds.MinNodes > 0 {
+			bounds.MinNodes = int(math.Max(1, math.Ceil(float64(bounds.MinNodes)*share)))
+		}
+		if bounds.MaxNodes > 0 {
+			bounds.MaxNodes = int(math.Max(float64(bounds.MinNodes), math.Ceil(float64(bounds.MaxNodes)*share)))
+		}
+		split[attr] = bounds
+	}
+	return split
+}
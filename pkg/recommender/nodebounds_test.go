@@ -0,0 +1,113 @@
+// Copyright © 2019 Banzai Cloud
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package recommender
+
+import (
+	"testing"
+
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestClusterRecommendationReq_nodeBounds(t *testing.T) {
+	req := ClusterRecommendationReq{
+		MinNodes: 1,
+		MaxNodes: 50,
+		AttributeNodes: map[string]NodeBounds{
+			Memory: {MaxNodes: 3},
+			Cpu:    {MinNodes: 5, MaxNodes: 60},
+		},
+	}
+
+	minNodes, maxNodes := req.nodeBounds(Memory)
+	assert.Equal(t, 1, minNodes, "an unset bound should default to the request")
+	assert.Equal(t, 3, maxNodes)
+
+	minNodes, maxNodes = req.nodeBounds(Cpu)
+	assert.Equal(t, 5, minNodes)
+	assert.Equal(t, 60, maxNodes)
+
+	assert.Equal(t, 60, req.maxNodesBound())
+
+	req.AttributeNodes = nil
+	minNodes, maxNodes = req.nodeBounds(Cpu)
+	assert.Equal(t, 1, minNodes)
+	assert.Equal(t, 50, maxNodes)
+}
+
+func Test_validateAttributeNodes(t *testing.T) {
+	tests := []struct {
+		name           string
+		attributeNodes map[string]NodeBounds
+		onDemandCount  int
+		expected       error
+	}{
+		{
+			name:           "valid bounds",
+			attributeNodes: map[string]NodeBounds{Memory: {MinNodes: 2, MaxNodes: 3}},
+		},
+		{
+			name:           "unsupported attribute",
+			attributeNodes: map[string]NodeBounds{"gpu": {MaxNodes: 3}},
+			expected:       ErrUnsupportedBoundsAttribute,
+		},
+		{
+			name:           "minimum greater than the maximum of the request",
+			attributeNodes: map[string]NodeBounds{Cpu: {MinNodes: 20}},
+			expected:       ErrMinGreaterThanMax,
+		},
+		{
+			name:           "negative bound",
+			attributeNodes: map[string]NodeBounds{Cpu: {MaxNodes: -1}},
+			expected:       ErrInvalidNodeBounds,
+		},
+		{
+			name:           "on-demand count greater than the maximum",
+			attributeNodes: map[string]NodeBounds{Memory: {MaxNodes: 3}},
+			onDemandCount:  4,
+			expected:       ErrOnDemandCountExceedsMax,
+		},
+	}
+	for _, test := range tests {
+		test := test
+		t.Run(test.name, func(t *testing.T) {
+			req := ClusterRecommendationReq{MinNodes: 1, MaxNodes: 10, OnDemandCount: test.onDemandCount, AttributeNodes: test.attributeNodes}
+
+			assert.Equal(t, test.expected, errors.Cause(validateAttributeNodes(req)))
+		})
+	}
+}
+
+func Test_validatePerNodeBounds(t *testing.T) {
+	assert.Nil(t, validatePerNodeBounds(ClusterRecommendationReq{MinCpuPerNode: 4, MaxCpuPerNode: 8, MaxMemPerNode: 16}))
+	assert.Equal(t, ErrMinPerNodeGreaterThanMax, errors.Cause(validatePerNodeBounds(ClusterRecommendationReq{MinMemPerNode: 32, MaxMemPerNode: 16})))
+}
+
+func Test_minPerNode(t *testing.T) {
+	assert.Equal(t, 2.0, minPerNode(5, 2, 0), "the explicit minimum should override the derived one")
+	assert.Equal(t, 4.0, minPerNode(5, 0, 4), "the derived minimum should be lowered to the explicit maximum")
+	assert.Equal(t, 5.0, minPerNode(5, 0, 8))
+}
+
+func Test_splitNodeBounds(t *testing.T) {
+	split := splitNodeBounds(map[string]NodeBounds{
+		Memory: {MaxNodes: 3},
+		Cpu:    {MinNodes: 4, MaxNodes: 10},
+	}, 0.5)
+
+	assert.Equal(t, NodeBounds{MaxNodes: 2}, split[Memory], "unset bounds should stay unset")
+	assert.Equal(t, NodeBounds{MinNodes: 2, MaxNodes: 5}, split[Cpu])
+	assert.Nil(t, splitNodeBounds(nil, 0.5))
+}
@@ -0,0 +1,87 @@
+// Copyright © 2019 Banzai Cloud
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package recommender
+
+import (
+	"fmt"
+	"sort"
+)
+
+// NodeCosts holds the hourly costs tied to the number of nodes rather than to the instance types (eg. root volumes,
+// NAT data processing allowances, monitoring agent licenses) per provider and cost name
+type NodeCosts map[string]map[string]float64
+
+// perNode gets the sum of the hourly costs of a node of the provider
+func (c NodeCosts) perNode(provider string) float64 {
+	var sum float64
+	for _, price := range c[provider] {
+		sum += price
+	}
+	return sum
+}
+
+// estimates gets the estimated costs of the layout per cost name, ordered by name
+func (c NodeCosts) estimates(provider string, nodePools []NodePool) []NodeCostEstimate {
+	estimates := make([]NodeCostEstimate, 0, len(c[provider]))
+	for name, price := range c[provider] {
+		estimate := NodeCostEstimate{Name: name, PricePerNode: price}
+		estimate.update(nodePools)
+		estimates = append(estimates, estimate)
+	}
+	sort.Slice(estimates, func(i, j int) bool { return estimates[i].Name < estimates[j].Name })
+	return estimates
+}
+
+// NodeCostEstimate is the estimated hourly cost of the layout tied to its number of nodes
+type NodeCostEstimate struct {
+	// Name of the cost, eg. rootVolume
+	Name string `json:"name"`
+	// Hourly price per node
+	PricePerNode float64 `json:"pricePerNode"`
+	// Number of nodes charged, reserved nodes are already paid for
+	Nodes int `json:"nodes"`
+	// Hourly price of the nodes
+	Price float64 `json:"price"`
+}
+
+// update counts the charged nodes of the node pools
+func (e *NodeCostEstimate) update(nodePools []NodePool) {
+	e.Nodes = 0
+	for _, np := range nodePools {
+		if np.VmClass == Regular || np.VmClass == Spot {
+			e.Nodes += np.SumNodes
+		}
+	}
+	e.Price = e.PricePerNode * float64(e.Nodes)
+}
+
+// applyNodeCosts adds the hourly costs of a node to the prices of the vms, so that layouts of many small nodes are
+// charged for their nodes; the costs apply regardless of the purchase option, like the license fees
+func (e *Engine) applyNodeCosts(provider string, vms []VirtualMachine) *Warning {
+	perNode := e.nodeCosts.perNode(provider)
+	if perNode == 0 {
+		return &Warning{
+			Code:    WarnNodeCostsUnknown,
+			Message: fmt.Sprintf("includeNodeCosts ignored, there are no per node costs configured for %s", provider),
+		}
+	}
+	for i := range vms {
+		vms[i].OnDemandPrice += perNode
+		if vms[i].AvgPrice > 0 {
+			vms[i].AvgPrice += perNode
+		}
+	}
+	return nil
+}
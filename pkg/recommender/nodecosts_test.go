@@ -0,0 +1,89 @@
+// Copyright © 2019 Banzai Cloud
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package recommender
+
+import (
+	"testing"
+
+	"github.com/goph/logur"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestEngine_applyNodeCosts(t *testing.T) {
+	costs := NodeCosts{"amazon": {"root_volume": 0.01, "monitoring_agent": 0.02}}
+	tests := []struct {
+		name     string
+		provider string
+		check    func(vms []VirtualMachine, warning *Warning)
+	}{
+		{
+			name:     "costs added to the prices",
+			provider: "amazon",
+			check: func(vms []VirtualMachine, warning *Warning) {
+				assert.Nil(t, warning)
+				assert.InDelta(t, 0.23, vms[0].OnDemandPrice, 1e-9)
+				assert.InDelta(t, 0.1, vms[0].AvgPrice, 1e-9)
+				assert.Equal(t, float64(0), vms[1].AvgPrice, "vms without spot price should not get one")
+			},
+		},
+		{
+			name:     "no costs configured for the provider",
+			provider: "google",
+			check: func(vms []VirtualMachine, warning *Warning) {
+				if assert.NotNil(t, warning) {
+					assert.Equal(t, WarnNodeCostsUnknown, warning.Code)
+				}
+				assert.Equal(t, 0.2, vms[0].OnDemandPrice)
+			},
+		},
+	}
+	for _, test := range tests {
+		test := test
+		t.Run(test.name, func(t *testing.T) {
+			engine := NewEngine(logur.NewTestLogger(), nil, nil, nil, WithNodeCosts(costs))
+			vms := []VirtualMachine{
+				{Type: "m5.xlarge", OnDemandPrice: 0.2, AvgPrice: 0.07},
+				{Type: "m5.large", OnDemandPrice: 0.1},
+			}
+			warning := engine.applyNodeCosts(test.provider, vms)
+			test.check(vms, warning)
+		})
+	}
+}
+
+func TestNodeCosts_estimates(t *testing.T) {
+	costs := NodeCosts{"amazon": {"root_volume": 0.01, "monitoring_agent": 0.02}}
+	resp := &ClusterRecommendationResp{
+		Provider: "amazon",
+		NodePools: []NodePool{
+			{VmType: VirtualMachine{Cpus: 4, OnDemandPrice: 0.2}, SumNodes: 2, VmClass: Regular, Role: Worker},
+			{VmType: VirtualMachine{Cpus: 4, AvgPrice: 0.07}, SumNodes: 3, VmClass: Spot, Role: Worker},
+			{VmType: VirtualMachine{Cpus: 4, OnDemandPrice: 0.2}, SumNodes: 4, VmClass: Reserved, Role: Worker},
+		},
+	}
+	resp.NodeCosts = costs.estimates(resp.Provider, resp.NodePools)
+
+	assert.Equal(t, []NodeCostEstimate{
+		{Name: "monitoring_agent", PricePerNode: 0.02, Nodes: 5, Price: 0.1},
+		{Name: "root_volume", PricePerNode: 0.01, Nodes: 5, Price: 0.05},
+	}, resp.NodeCosts, "reserved nodes should not be charged")
+
+	resp.NodePools[1].SumNodes++
+	resp.RecalculateAccuracy()
+	assert.Equal(t, 6, resp.NodeCosts[0].Nodes, "the estimates should follow the node pools")
+	assert.InDelta(t, 0.12, resp.NodeCosts[0].Price, 1e-9)
+
+	assert.Empty(t, costs.estimates("google", resp.NodePools))
+}
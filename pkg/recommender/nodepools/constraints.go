@@ -0,0 +1,80 @@
+// Copyright © 2019 Banzai Cloud
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package nodepools
+
+import (
+	"github.com/banzaicloud/telescopes/pkg/recommender"
+)
+
+// Constraints holds the node pool size limits accepted by a managed service
+type Constraints struct {
+	// MinNodes is the minimum number of nodes in a node pool
+	MinNodes int
+	// MaxNodes is the maximum number of nodes in a node pool, 0 means no limit
+	MaxNodes int
+}
+
+// DefaultConstraints returns the node pool constraints of the supported managed services
+func DefaultConstraints() map[string]Constraints {
+	return map[string]Constraints{
+		"eks": {MinNodes: 1, MaxNodes: 100},
+		"gke": {MinNodes: 1, MaxNodes: 1000},
+		"aks": {MinNodes: 1, MaxNodes: 100},
+	}
+}
+
+// MaxPoolNodes gets the maximum number of nodes of a node pool of the service, 0 if unlimited
+func (s *nodePoolSelector) MaxPoolNodes(service string) int {
+	return s.constraints[service].MaxNodes
+}
+
+// applyConstraints adjusts the node pools to the limits of the given service:
+// empty pools are removed, undersized pools are grown and oversized pools are split
+func (s *nodePoolSelector) applyConstraints(service string, nps []recommender.NodePool) []recommender.NodePool {
+	c, ok := s.constraints[service]
+	if !ok {
+		return nps
+	}
+
+	constrained := make([]recommender.NodePool, 0, len(nps))
+	for _, np := range nps {
+		if np.SumNodes == 0 && c.MinNodes > 0 {
+			s.log.Debug("removing empty node pool", map[string]interface{}{"service": service, "type": np.VmType.Type})
+			continue
+		}
+
+		if np.SumNodes < c.MinNodes {
+			s.log.Debug("growing node pool to the minimum size", map[string]interface{}{"service": service,
+				"type": np.VmType.Type, "sumNodes": np.SumNodes, "minNodes": c.MinNodes})
+			np.SumNodes = c.MinNodes
+		}
+
+		for c.MaxNodes > 0 && np.SumNodes > c.MaxNodes {
+			s.log.Debug("splitting node pool exceeding the maximum size", map[string]interface{}{"service": service,
+				"type": np.VmType.Type, "sumNodes": np.SumNodes, "maxNodes": c.MaxNodes})
+			split := np
+			split.SumNodes = c.MaxNodes
+			constrained = append(constrained, split)
+			np.SumNodes -= c.MaxNodes
+			if np.SumNodes < c.MinNodes {
+				np.SumNodes = c.MinNodes
+			}
+		}
+
+		constrained = append(constrained, np)
+	}
+
+	return constrained
+}
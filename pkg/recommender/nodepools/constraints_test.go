@@ -0,0 +1,89 @@
+// Copyright © 2019 Banzai Cloud
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package nodepools
+
+import (
+	"testing"
+
+	"github.com/banzaicloud/telescopes/pkg/recommender"
+	"github.com/goph/logur"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNodePoolSelector_applyConstraints(t *testing.T) {
+	tests := []struct {
+		name    string
+		service string
+		nps     []recommender.NodePool
+		check   func(nps []recommender.NodePool)
+	}{
+		{
+			name:    "no constraints for the service",
+			service: "compute",
+			nps: []recommender.NodePool{
+				{SumNodes: 0, VmClass: recommender.Spot},
+				{SumNodes: 3, VmClass: recommender.Regular},
+			},
+			check: func(nps []recommender.NodePool) {
+				assert.Equal(t, 2, len(nps))
+			},
+		},
+		{
+			name:    "empty pools are removed",
+			service: "eks",
+			nps: []recommender.NodePool{
+				{SumNodes: 0, VmClass: recommender.Spot},
+				{SumNodes: 3, VmClass: recommender.Regular},
+			},
+			check: func(nps []recommender.NodePool) {
+				assert.Equal(t, 1, len(nps))
+				assert.Equal(t, 3, nps[0].SumNodes)
+			},
+		},
+		{
+			name:    "undersized pools are grown",
+			service: "test",
+			nps: []recommender.NodePool{
+				{SumNodes: 1, VmClass: recommender.Spot},
+			},
+			check: func(nps []recommender.NodePool) {
+				assert.Equal(t, 1, len(nps))
+				assert.Equal(t, 2, nps[0].SumNodes)
+			},
+		},
+		{
+			name:    "oversized pools are split",
+			service: "test",
+			nps: []recommender.NodePool{
+				{SumNodes: 11, VmClass: recommender.Spot},
+			},
+			check: func(nps []recommender.NodePool) {
+				assert.Equal(t, 3, len(nps))
+				assert.Equal(t, 5, nps[0].SumNodes)
+				assert.Equal(t, 5, nps[1].SumNodes)
+				assert.Equal(t, 2, nps[2].SumNodes)
+			},
+		},
+	}
+	for _, test := range tests {
+		test := test // scopelint
+		t.Run(test.name, func(t *testing.T) {
+			constraints := DefaultConstraints()
+			constraints["test"] = Constraints{MinNodes: 2, MaxNodes: 5}
+			selector := NewNodePoolSelector(logur.NewTestLogger(), constraints)
+			test.check(selector.applyConstraints(test.service, test.nps))
+		})
+	}
+}
@@ -25,16 +25,19 @@ import (
 
 type nodePoolSelector struct {
 	log logur.Logger
+	// constraints holds the node pool size limits per service
+	constraints map[string]Constraints
 }
 
-func NewNodePoolSelector(log logur.Logger) *nodePoolSelector {
+func NewNodePoolSelector(log logur.Logger, constraints map[string]Constraints) *nodePoolSelector {
 	return &nodePoolSelector{
-		log: log,
+		log:         log,
+		constraints: constraints,
 	}
 }
 
 // RecommendNodePools finds the slice of NodePools that may participate in the recommendation process
-func (s *nodePoolSelector) RecommendNodePools(attr string, req recommender.SingleClusterRecommendationReq,
+func (s *nodePoolSelector) RecommendNodePools(service string, attr string, req recommender.SingleClusterRecommendationReq,
 	layout []recommender.NodePool,
 	odVms []recommender.VirtualMachine,
 	spotVms []recommender.VirtualMachine) []recommender.NodePool {
@@ -45,24 +48,64 @@ func (s *nodePoolSelector) RecommendNodePools(attr string, req recommender.Singl
 	// recommend on-demands
 	odNps := make([]recommender.NodePool, 0)
 
+	// reserved pools are kept as they are, they are already paid for
+	reservedNps := make([]recommender.NodePool, 0)
+
 	//TODO: validate if there's no on-demand in layout but we want to add ondemands
 	for _, np := range layout {
-		if np.VmClass == recommender.Regular {
+		switch np.VmClass {
+		case recommender.Regular:
 			odNps = append(odNps, np)
+		case recommender.Reserved:
+			reservedNps = append(reservedNps, np)
 		}
 	}
 	var actualOnDemandResources float64
-	var odNodesToAdd int
-	if len(odVms) > 0 && req.OnDemandPct != 0 {
+	var odNodesToAdd, reservedNodes int
+	if len(odVms) > 0 && req.HasOnDemand() {
+		candidates := odVms
+		if pinned := pinnedOnDemandVms(layout, odVms); len(pinned) > 0 {
+			// the on-demand nodes of a pinned layout are added to its existing regular node pools
+			candidates = pinned
+		}
+
 		// find cheapest onDemand instance from the list - based on price per attribute
-		selectedOnDemand := odVms[0]
-		for _, vm := range odVms {
-			if vm.OnDemandPrice/vm.GetAttrValue(attr) < selectedOnDemand.OnDemandPrice/selectedOnDemand.GetAttrValue(attr) {
+		selectedOnDemand := candidates[0]
+		for _, vm := range candidates {
+			if vm.PricePerUnit(vm.OnDemandPrice, attr, req.OptimizeFor) < selectedOnDemand.PricePerUnit(selectedOnDemand.OnDemandPrice, attr, req.OptimizeFor) {
 				selectedOnDemand = vm
 			}
 		}
-		odNodesToAdd = int(math.Ceil(sumOnDemandValue / selectedOnDemand.GetAttrValue(attr)))
+		if pct := req.NewestGenPricePct(); pct > 0 {
+			selectedOnDemand = newestGenWithin(candidates, selectedOnDemand, func(vm recommender.VirtualMachine) float64 {
+				return vm.PricePerUnit(vm.OnDemandPrice, attr, req.OptimizeFor)
+			}, pct)
+		}
+
+		if req.OnDemandCount > 0 {
+			// the requested number of on-demand nodes is converted to resources of the selected instance type
+			sumOnDemandValue = float64(req.OnDemandCount) * selectedOnDemand.GetAttrValue(attr)
+			s.log.Debug(fmt.Sprintf("on demand sum value for [%d] nodes: [%f]", req.OnDemandCount, sumOnDemandValue))
+			// the on-demand nodes are raised to keep the spot capacity within the disruption budget
+			if minValue := sum(req, attr) * float64(req.DisruptionBudget.MinOnDemandPct()) / 100; minValue > sumOnDemandValue {
+				sumOnDemandValue = minValue
+				s.log.Debug(fmt.Sprintf("on demand sum value raised to [%f] for the disruption budget", sumOnDemandValue))
+			}
+		}
+
 		if layout == nil {
+			// unused reservations are already paid for, they cover the on-demand resources first
+			nps, reservedValue := s.fillReservedNodePools(attr, req.Zone, sumOnDemandValue, req.Reservations, odVms)
+			for _, np := range nps {
+				reservedNodes += np.SumNodes
+			}
+			reservedNps = append(reservedNps, nps...)
+			sumOnDemandValue = math.Max(0, sumOnDemandValue-reservedValue)
+			actualOnDemandResources += reservedValue
+		}
+
+		odNodesToAdd = int(math.Ceil(sumOnDemandValue / selectedOnDemand.GetAttrValue(attr)))
+		if layout == nil && odNodesToAdd > 0 {
 			odNps = append(odNps, recommender.NodePool{
 				SumNodes: odNodesToAdd,
 				VmClass:  recommender.Regular,
@@ -76,7 +119,7 @@ func (s *nodePoolSelector) RecommendNodePools(attr string, req recommender.Singl
 				}
 			}
 		}
-		actualOnDemandResources = selectedOnDemand.GetAttrValue(attr) * float64(odNodesToAdd)
+		actualOnDemandResources += selectedOnDemand.GetAttrValue(attr) * float64(odNodesToAdd)
 	}
 
 	spotNps := make([]recommender.NodePool, 0)
@@ -89,14 +132,50 @@ func (s *nodePoolSelector) RecommendNodePools(attr string, req recommender.Singl
 		// recommend spot pools
 		excludedSpotNps := make([]recommender.NodePool, 0)
 
-		s.sortByAttrValue(attr, spotVms)
+		s.sortByAttrValue(attr, req.OptimizeFor, spotVms)
+		if pct := req.NewestGenPricePct(); pct > 0 {
+			preferNewestGen(spotVms, func(vm recommender.VirtualMachine) float64 {
+				return vm.PricePerUnit(vm.RankingPrice(), attr, req.OptimizeFor)
+			}, pct)
+		}
+
+		// the spot pools are spread by the share of the capacity actually left for them, eg. by an on-demand count
+		var spotPct float64
+		if total := sum(req, attr); total > 0 {
+			spotPct = math.Max(0, 100*sumSpotValue/total)
+		}
+		minSpotPools, maxPoolSharePct := req.SpotSpread(spotPct)
 
 		var N int
 		if layout == nil {
+			maxTypes := maxSpotTypes(req.MaxDistinctTypes, odNps, reservedNps)
+			if maxTypes == 0 {
+				// no new types fit, the spot pools reuse the types of the regular and reserved pools; if none of them
+				// has a spot price the limit can't be met, a single new type is used
+				maxTypes = 1
+				if reused := reusedSpotVms(spotVms, odNps, reservedNps); len(reused) > 0 {
+					spotVms = reused
+					maxTypes = len(reused)
+				}
+			}
 			// the "magic" number of machines for diversifying the types
-			N = int(math.Min(float64(findN(avgSpotNodeCount(req.MinNodes, req.MaxNodes, odNodesToAdd))), float64(len(spotVms))))
+			N = int(math.Min(float64(findN(avgSpotNodeCount(req.MinNodes, req.MaxNodes, odNodesToAdd+reservedNodes))), float64(len(spotVms))))
+			if req.PreferLargerNodes {
+				// fewer, larger nodes are spread across fewer types
+				N = (N + 1) / 2
+			}
+			if N < minSpotPools {
+				N = int(math.Min(float64(minSpotPools), float64(len(spotVms))))
+			}
+			if maxTypes > 0 && N > maxTypes {
+				// the capacity is redistributed among the fewer types
+				N = maxTypes
+			}
 			// the second "magic" number for diversifying the layout
 			M := findM(N, spotVms)
+			if maxTypes > 0 && M > maxTypes {
+				M = maxTypes
+			}
 			s.log.Debug(fmt.Sprintf("Magic 'Marton' numbers: N=%d, M=%d", N, M))
 
 			// the first M vm-s
@@ -133,26 +212,114 @@ func (s *nodePoolSelector) RecommendNodePools(attr string, req recommender.Singl
 				}
 			}
 			N = findNWithLayout(nonZeroNPs, len(spotVms))
+			if maxTypes := maxSpotTypes(req.MaxDistinctTypes, odNps, reservedNps); maxTypes >= 0 && N > maxTypes {
+				// at least one spot pool is kept to place the spot capacity
+				N = int(math.Max(float64(maxTypes), 1))
+			}
+			if recommender.PinnedLayout(layout) {
+				var emptyNps []recommender.NodePool
+				spotNps, emptyNps = splitEmptyNodePools(spotNps)
+				if len(spotNps) > 0 {
+					// the empty pools of a pinned layout are not used, the instance type mix is kept
+					N = int(math.Min(float64(N), float64(len(spotNps))))
+					excludedSpotNps = append(excludedSpotNps, emptyNps...)
+				} else {
+					spotNps = emptyNps
+				}
+			}
 			s.log.Debug(fmt.Sprintf("Magic 'Marton' number: N=%d", N))
 		}
 		spotNps = s.fillSpotNodePools(sumSpotValue, N, spotNps, attr)
+		if layout == nil && maxPoolSharePct < 100 {
+			spotNps = s.spreadSpotNodePools(sumSpotValue, actualOnDemandResources, N, spotNps, attr, maxPoolSharePct)
+		}
 		if len(excludedSpotNps) > 0 {
 			spotNps = append(spotNps, excludedSpotNps...)
 		}
 	}
 
-	s.log.Debug(fmt.Sprintf("created [%d] regular and [%d] spot price node pools", len(odNps), len(spotNps)))
+	s.log.Debug(fmt.Sprintf("created [%d] regular, [%d] reserved and [%d] spot price node pools", len(odNps), len(reservedNps), len(spotNps)))
 
-	return append(odNps, spotNps...)
+	return s.applyConstraints(service, append(append(odNps, spotNps...), reservedNps...))
+}
+
+// pinnedOnDemandVms selects the vms of the non-empty regular node pools of a pinned layout
+func pinnedOnDemandVms(layout []recommender.NodePool, odVms []recommender.VirtualMachine) []recommender.VirtualMachine {
+	if !recommender.PinnedLayout(layout) {
+		return nil
+	}
+
+	var vms []recommender.VirtualMachine
+	for _, vm := range odVms {
+		for _, np := range layout {
+			if np.VmClass == recommender.Regular && np.SumNodes > 0 && np.VmType.Type == vm.Type {
+				vms = append(vms, vm)
+				break
+			}
+		}
+	}
+	return vms
+}
+
+// splitEmptyNodePools separates the node pools without nodes, keeping the order of the node pools
+func splitEmptyNodePools(nps []recommender.NodePool) ([]recommender.NodePool, []recommender.NodePool) {
+	nonEmpty := make([]recommender.NodePool, 0, len(nps))
+	var empty []recommender.NodePool
+	for _, np := range nps {
+		if np.SumNodes > 0 {
+			nonEmpty = append(nonEmpty, np)
+		} else {
+			empty = append(empty, np)
+		}
+	}
+	return nonEmpty, empty
+}
+
+// newestGenWithin selects the vm of the newest generation among the ones priced within the percentage of the cheapest
+// one, the cheaper one of the same generation
+func newestGenWithin(vms []recommender.VirtualMachine, cheapest recommender.VirtualMachine,
+	price func(recommender.VirtualMachine) float64, pct int) recommender.VirtualMachine {
+	limit := price(cheapest) * (1 + float64(pct)/100)
+	selected := cheapest
+	for _, vm := range vms {
+		if price(vm) > limit {
+			continue
+		}
+		if vm.Generation() > selected.Generation() || vm.Generation() == selected.Generation() && price(vm) < price(selected) {
+			selected = vm
+		}
+	}
+	return selected
+}
+
+// preferNewestGen reorders the vms sorted by price so the newer generations come first within the bands of vms priced
+// within the percentage of the cheapest vm of the band, the price order is kept otherwise
+func preferNewestGen(vms []recommender.VirtualMachine, price func(recommender.VirtualMachine) float64, pct int) {
+	for start := 0; start < len(vms); {
+		limit := price(vms[start]) * (1 + float64(pct)/100)
+		end := start + 1
+		for end < len(vms) && price(vms[end]) <= limit {
+			end++
+		}
+		band := vms[start:end]
+		sort.SliceStable(band, func(i, j int) bool {
+			return band[i].Generation() > band[j].Generation()
+		})
+		start = end
+	}
 }
 
 // sortByAttrValue returns the slice for
-func (s *nodePoolSelector) sortByAttrValue(attr string, vms []recommender.VirtualMachine) {
+func (s *nodePoolSelector) sortByAttrValue(attr string, optimizeFor string, vms []recommender.VirtualMachine) {
 	// sort and cut
 	switch attr {
 	case recommender.Memory:
 		sort.Sort(ByAvgPricePerMemory(vms))
 	case recommender.Cpu:
+		if optimizeFor == recommender.OptimizeForBenchmark {
+			sort.Sort(ByAvgPricePerBenchmark(vms))
+			return
+		}
 		sort.Sort(ByAvgPricePerCpu(vms))
 	default:
 		s.log.Error("unsupported attribute", map[string]interface{}{"attribute": attr})
@@ -165,8 +332,8 @@ type ByAvgPricePerCpu []recommender.VirtualMachine
 func (a ByAvgPricePerCpu) Len() int      { return len(a) }
 func (a ByAvgPricePerCpu) Swap(i, j int) { a[i], a[j] = a[j], a[i] }
 func (a ByAvgPricePerCpu) Less(i, j int) bool {
-	pricePerCpu1 := a[i].AvgPrice / a[i].Cpus
-	pricePerCpu2 := a[j].AvgPrice / a[j].Cpus
+	pricePerCpu1 := a[i].RankingPrice() / a[i].Cpus
+	pricePerCpu2 := a[j].RankingPrice() / a[j].Cpus
 	return pricePerCpu1 < pricePerCpu2
 }
 
@@ -176,11 +343,22 @@ type ByAvgPricePerMemory []recommender.VirtualMachine
 func (a ByAvgPricePerMemory) Len() int      { return len(a) }
 func (a ByAvgPricePerMemory) Swap(i, j int) { a[i], a[j] = a[j], a[i] }
 func (a ByAvgPricePerMemory) Less(i, j int) bool {
-	pricePerMem1 := a[i].AvgPrice / a[i].Mem
-	pricePerMem2 := a[j].AvgPrice / a[j].Mem
+	pricePerMem1 := a[i].RankingPrice() / a[i].Mem
+	pricePerMem2 := a[j].RankingPrice() / a[j].Mem
 	return pricePerMem1 < pricePerMem2
 }
 
+// ByAvgPricePerBenchmark type for custom sorting of a slice of vms by price per benchmark unit
+type ByAvgPricePerBenchmark []recommender.VirtualMachine
+
+func (a ByAvgPricePerBenchmark) Len() int      { return len(a) }
+func (a ByAvgPricePerBenchmark) Swap(i, j int) { a[i], a[j] = a[j], a[i] }
+func (a ByAvgPricePerBenchmark) Less(i, j int) bool {
+	pricePerScore1 := a[i].PricePerUnit(a[i].RankingPrice(), recommender.Cpu, recommender.OptimizeForBenchmark)
+	pricePerScore2 := a[j].PricePerUnit(a[j].RankingPrice(), recommender.Cpu, recommender.OptimizeForBenchmark)
+	return pricePerScore1 < pricePerScore2
+}
+
 type ByNonZeroNodePools []recommender.NodePool
 
 func (a ByNonZeroNodePools) Len() int      { return len(a) }
@@ -252,6 +430,32 @@ func (s *nodePoolSelector) fillSpotNodePools(sumSpotValue float64, n int, nps []
 	return nps
 }
 
+// spreadSpotNodePools refills the spot node pools using more and more pools until none of the pools holds more than
+// the allowed share of the worker capacity, or there are no more pools to use
+func (s *nodePoolSelector) spreadSpotNodePools(sumSpotValue, odValue float64, n int, nps []recommender.NodePool, attr string, maxSharePct int) []recommender.NodePool {
+	for ; maxPoolSharePct(nps, odValue, attr) > float64(maxSharePct) && n < len(nps); n++ {
+		s.log.Debug(fmt.Sprintf("spot pool share exceeds [%d]%%, refilling [%d] spot pools", maxSharePct, n+1))
+		for i := range nps {
+			nps[i].SumNodes = 0
+		}
+		nps = s.fillSpotNodePools(sumSpotValue, n+1, nps, attr)
+	}
+	return nps
+}
+
+// maxPoolSharePct gets the largest percentage of the worker capacity held by a single spot node pool
+func maxPoolSharePct(nps []recommender.NodePool, odValue float64, attr string) float64 {
+	total, max := odValue, float64(0)
+	for _, np := range nps {
+		total += np.GetSum(attr)
+		max = math.Max(max, np.GetSum(attr))
+	}
+	if total == 0 {
+		return 0
+	}
+	return max / total * 100
+}
+
 // findN returns the number of nodes required
 func findN(avg int) int {
 	var n int
@@ -272,6 +476,44 @@ func findN(avg int) int {
 	return n
 }
 
+// maxSpotTypes gets the number of new spot instance types fitting in the distinct instance types besides the ones of
+// the regular and reserved node pools; -1 means no limit, 0 means the regular and reserved node pools use up the types
+func maxSpotTypes(maxDistinctTypes int, nodePools ...[]recommender.NodePool) int {
+	if maxDistinctTypes <= 0 {
+		return -1
+	}
+	types := usedTypes(nodePools...)
+	if len(types) >= maxDistinctTypes {
+		return 0
+	}
+	return maxDistinctTypes - len(types)
+}
+
+// usedTypes gets the instance types of the node pools having nodes
+func usedTypes(nodePools ...[]recommender.NodePool) map[string]bool {
+	types := make(map[string]bool)
+	for _, nps := range nodePools {
+		for _, np := range nps {
+			if np.SumNodes > 0 {
+				types[np.VmType.Type] = true
+			}
+		}
+	}
+	return types
+}
+
+// reusedSpotVms selects the spot vms of the instance types already used by the node pools, keeping their order
+func reusedSpotVms(spotVms []recommender.VirtualMachine, nodePools ...[]recommender.NodePool) []recommender.VirtualMachine {
+	types := usedTypes(nodePools...)
+	var vms []recommender.VirtualMachine
+	for _, vm := range spotVms {
+		if types[vm.Type] {
+			vms = append(vms, vm)
+		}
+	}
+	return vms
+}
+
 func findM(n int, spotVms []recommender.VirtualMachine) int {
 	if n > 0 {
 		return int(math.Min(math.Ceil(float64(n)*1.5), float64(len(spotVms))))
@@ -15,8 +15,11 @@
 package nodepools
 
 import (
+	"sort"
 	"testing"
 
+	"github.com/banzaicloud/telescopes/pkg/recommender"
+	"github.com/goph/logur"
 	"github.com/stretchr/testify/assert"
 )
 
@@ -63,3 +66,371 @@ func Test_avgSpotNodeCount(t *testing.T) {
 		})
 	}
 }
+
+func TestNodePoolSelector_spreadSpotNodePools(t *testing.T) {
+	tests := []struct {
+		name        string
+		maxSharePct int
+		check       func(nps []recommender.NodePool)
+	}{
+		{
+			name:        "pools within the allowed share",
+			maxSharePct: 100,
+			check: func(nps []recommender.NodePool) {
+				assert.Equal(t, 8, nps[0].SumNodes)
+				assert.Equal(t, 0, nps[1].SumNodes)
+			},
+		},
+		{
+			name:        "capacity spread to more pools",
+			maxSharePct: 35,
+			check: func(nps []recommender.NodePool) {
+				for _, np := range nps {
+					assert.True(t, np.GetSum(recommender.Cpu) <= 16*0.35, "pool share should be at most 35%")
+				}
+			},
+		},
+	}
+	for _, test := range tests {
+		test := test //pin - scopelint
+		t.Run(test.name, func(t *testing.T) {
+			nps := []recommender.NodePool{
+				{VmType: recommender.VirtualMachine{Cpus: 2}, VmClass: recommender.Spot},
+				{VmType: recommender.VirtualMachine{Cpus: 2}, VmClass: recommender.Spot},
+				{VmType: recommender.VirtualMachine{Cpus: 2}, VmClass: recommender.Spot},
+				{VmType: recommender.VirtualMachine{Cpus: 2}, VmClass: recommender.Spot},
+			}
+			selector := NewNodePoolSelector(logur.NewTestLogger(), nil)
+			nps = selector.fillSpotNodePools(16, 1, nps, recommender.Cpu)
+			test.check(selector.spreadSpotNodePools(16, 0, 1, nps, recommender.Cpu, test.maxSharePct))
+		})
+	}
+}
+
+func TestNodePoolSelector_RecommendNodePoolsOnDemand(t *testing.T) {
+	vms := []recommender.VirtualMachine{
+		{Type: "m5.xlarge", Cpus: 4, Mem: 16, OnDemandPrice: 0.192, AvgPrice: 0.07},
+		{Type: "c5.xlarge", Cpus: 4, Mem: 8, OnDemandPrice: 0.17, AvgPrice: 0.06},
+	}
+	tests := []struct {
+		name  string
+		req   recommender.ClusterRecommendationReq
+		check func(nps []recommender.NodePool)
+	}{
+		{
+			name: "on-demand percentage",
+			req:  recommender.ClusterRecommendationReq{SumCpu: 32, MinNodes: 1, MaxNodes: 10, OnDemandPct: 50},
+			check: func(nps []recommender.NodePool) {
+				assert.Equal(t, 4, regularNodes(nps))
+			},
+		},
+		{
+			name: "absolute on-demand count",
+			req:  recommender.ClusterRecommendationReq{SumCpu: 32, MinNodes: 1, MaxNodes: 10, OnDemandCount: 3},
+			check: func(nps []recommender.NodePool) {
+				assert.Equal(t, 3, regularNodes(nps))
+				var spotCpus float64
+				for _, np := range nps {
+					if np.VmClass == recommender.Spot {
+						spotCpus += np.GetSum(recommender.Cpu)
+					}
+				}
+				assert.Equal(t, float64(20), spotCpus, "spot pools should cover the rest")
+			},
+		},
+		{
+			name: "on-demand count raised for the disruption budget",
+			req: recommender.ClusterRecommendationReq{SumCpu: 32, MinNodes: 1, MaxNodes: 10, OnDemandCount: 1,
+				DisruptionBudget: recommender.DisruptionBudget{MaxSpotPct: 50}},
+			check: func(nps []recommender.NodePool) {
+				assert.Equal(t, 4, regularNodes(nps), "at most half of the cpus should be on spot instances")
+				var spotCpus float64
+				for _, np := range nps {
+					if np.VmClass == recommender.Spot {
+						spotCpus += np.GetSum(recommender.Cpu)
+					}
+				}
+				assert.Equal(t, float64(16), spotCpus)
+			},
+		},
+	}
+	for _, test := range tests {
+		test := test //pin - scopelint
+		t.Run(test.name, func(t *testing.T) {
+			selector := NewNodePoolSelector(logur.NewTestLogger(), nil)
+			req := recommender.SingleClusterRecommendationReq{ClusterRecommendationReq: test.req}
+			odVms := append([]recommender.VirtualMachine(nil), vms...)
+			spotVms := append([]recommender.VirtualMachine(nil), vms...)
+			test.check(selector.RecommendNodePools("compute", recommender.Cpu, req, nil, odVms, spotVms))
+		})
+	}
+}
+
+func regularNodes(nps []recommender.NodePool) int {
+	var nodes int
+	for _, np := range nps {
+		if np.VmClass == recommender.Regular {
+			nodes += np.SumNodes
+		}
+	}
+	return nodes
+}
+
+func TestNodePoolSelector_RecommendNodePoolsPinned(t *testing.T) {
+	m5 := recommender.VirtualMachine{Type: "m5.xlarge", Cpus: 4, Mem: 16, OnDemandPrice: 0.192, AvgPrice: 0.07}
+	c5 := recommender.VirtualMachine{Type: "c5.xlarge", Cpus: 4, Mem: 8, OnDemandPrice: 0.17, AvgPrice: 0.06}
+	layout := func(pinned bool) []recommender.NodePool {
+		return []recommender.NodePool{
+			{VmType: m5, SumNodes: 2, VmClass: recommender.Regular, Role: recommender.Worker, Pinned: pinned},
+			{VmType: c5, SumNodes: 0, VmClass: recommender.Regular, Role: recommender.Worker},
+			{VmType: m5, SumNodes: 3, VmClass: recommender.Spot, Role: recommender.Worker, Pinned: pinned},
+			{VmType: c5, SumNodes: 0, VmClass: recommender.Spot, Role: recommender.Worker},
+		}
+	}
+	nodes := func(nps []recommender.NodePool, vmType, vmClass string) int {
+		for _, np := range nps {
+			if np.VmType.Type == vmType && np.VmClass == vmClass {
+				return np.SumNodes
+			}
+		}
+		return 0
+	}
+	tests := []struct {
+		name   string
+		layout []recommender.NodePool
+		check  func(nps []recommender.NodePool)
+	}{
+		{
+			name:   "the cheapest instance type is added to a layout that is not pinned",
+			layout: layout(false),
+			check: func(nps []recommender.NodePool) {
+				assert.Equal(t, 2, nodes(nps, "m5.xlarge", recommender.Regular))
+				assert.Equal(t, 2, nodes(nps, "c5.xlarge", recommender.Regular))
+			},
+		},
+		{
+			name:   "only the node counts of a pinned layout change",
+			layout: layout(true),
+			check: func(nps []recommender.NodePool) {
+				assert.Equal(t, 4, nodes(nps, "m5.xlarge", recommender.Regular))
+				assert.Equal(t, 0, nodes(nps, "c5.xlarge", recommender.Regular))
+				assert.Equal(t, 5, nodes(nps, "m5.xlarge", recommender.Spot))
+				assert.Equal(t, 0, nodes(nps, "c5.xlarge", recommender.Spot))
+			},
+		},
+	}
+	for _, test := range tests {
+		test := test //pin - scopelint
+		t.Run(test.name, func(t *testing.T) {
+			selector := NewNodePoolSelector(logur.NewTestLogger(), nil)
+			req := recommender.SingleClusterRecommendationReq{
+				ClusterRecommendationReq: recommender.ClusterRecommendationReq{SumCpu: 16, MinNodes: 1, MaxNodes: 10, OnDemandPct: 50},
+			}
+			vms := []recommender.VirtualMachine{m5, c5}
+			test.check(selector.RecommendNodePools("compute", recommender.Cpu, req, test.layout, vms, vms))
+		})
+	}
+}
+
+func TestNodePoolSelector_RecommendNodePoolsDisruptionBudget(t *testing.T) {
+	vms := []recommender.VirtualMachine{
+		{Type: "c5.xlarge", Cpus: 4, Mem: 8, OnDemandPrice: 0.17, AvgPrice: 0.04},
+		{Type: "m5.xlarge", Cpus: 4, Mem: 16, OnDemandPrice: 0.192, AvgPrice: 0.07},
+		{Type: "m4.xlarge", Cpus: 4, Mem: 16, OnDemandPrice: 0.2, AvgPrice: 0.075},
+		{Type: "r5.xlarge", Cpus: 4, Mem: 32, OnDemandPrice: 0.252, AvgPrice: 0.08},
+	}
+	req := recommender.SingleClusterRecommendationReq{
+		ClusterRecommendationReq: recommender.ClusterRecommendationReq{
+			SumCpu:           64,
+			MinNodes:         1,
+			MaxNodes:         4,
+			DisruptionBudget: recommender.DisruptionBudget{MaxPoolSharePct: 30},
+		},
+	}
+
+	selector := NewNodePoolSelector(logur.NewTestLogger(), nil)
+	nps := selector.RecommendNodePools("compute", recommender.Cpu, req, nil, nil, append([]recommender.VirtualMachine(nil), vms...))
+
+	var total float64
+	for _, np := range nps {
+		total += np.GetSum(recommender.Cpu)
+	}
+	for _, np := range nps {
+		assert.True(t, np.GetSum(recommender.Cpu) <= total*0.3, "the share of %s should be at most 30%%", np.VmType.Type)
+	}
+}
+
+func TestNodePoolSelector_RecommendNodePoolsPreferLargerNodes(t *testing.T) {
+	var vms []recommender.VirtualMachine
+	for i, vmType := range []string{"c5.xlarge", "m5.xlarge", "m4.xlarge", "r5.xlarge", "c4.xlarge", "m5a.xlarge", "r4.xlarge", "t3.xlarge"} {
+		vms = append(vms, recommender.VirtualMachine{Type: vmType, Cpus: 4, Mem: 16, OnDemandPrice: 0.2, AvgPrice: 0.04 + float64(i)*0.01})
+	}
+	req := recommender.SingleClusterRecommendationReq{
+		ClusterRecommendationReq: recommender.ClusterRecommendationReq{
+			SumCpu:   32,
+			MinNodes: 8,
+			MaxNodes: 8,
+		},
+	}
+
+	usedPools := func(nps []recommender.NodePool) int {
+		var used int
+		for _, np := range nps {
+			if np.SumNodes > 0 {
+				used++
+			}
+		}
+		return used
+	}
+
+	selector := NewNodePoolSelector(logur.NewTestLogger(), nil)
+	nps := selector.RecommendNodePools("compute", recommender.Cpu, req, nil, nil, append([]recommender.VirtualMachine(nil), vms...))
+	assert.Equal(t, 4, usedPools(nps))
+
+	req.PreferLargerNodes = true
+	nps = selector.RecommendNodePools("compute", recommender.Cpu, req, nil, nil, append([]recommender.VirtualMachine(nil), vms...))
+	assert.Equal(t, 2, usedPools(nps), "the nodes should be spread across fewer types")
+}
+
+func TestNodePoolSelector_RecommendNodePoolsMaxDistinctTypes(t *testing.T) {
+	var vms []recommender.VirtualMachine
+	for i, vmType := range []string{"c5.xlarge", "m5.xlarge", "m4.xlarge", "r5.xlarge", "c4.xlarge", "m5a.xlarge", "r4.xlarge", "t3.xlarge"} {
+		vms = append(vms, recommender.VirtualMachine{Type: vmType, Cpus: 4, Mem: 16, OnDemandPrice: 0.2 + float64(i)*0.01, AvgPrice: 0.04 + float64(i)*0.01})
+	}
+	req := recommender.SingleClusterRecommendationReq{
+		ClusterRecommendationReq: recommender.ClusterRecommendationReq{
+			SumCpu:           64,
+			MinNodes:         16,
+			MaxNodes:         16,
+			OnDemandPct:      25,
+			MaxDistinctTypes: 3,
+		},
+	}
+
+	distinctTypes := func(nps []recommender.NodePool) (int, float64) {
+		types := make(map[string]bool)
+		var total float64
+		for _, np := range nps {
+			types[np.VmType.Type] = true
+			total += np.GetSum(recommender.Cpu)
+		}
+		return len(types), total
+	}
+
+	selector := NewNodePoolSelector(logur.NewTestLogger(), nil)
+	nps := selector.RecommendNodePools("compute", recommender.Cpu, req, nil,
+		append([]recommender.VirtualMachine(nil), vms...), append([]recommender.VirtualMachine(nil), vms...))
+	types, total := distinctTypes(nps)
+	assert.True(t, types <= 3, "the node pools should have at most 3 instance types, got %d", types)
+	assert.True(t, total >= 64, "the capacity should be redistributed among the fewer types")
+
+	req.MaxDistinctTypes = 0
+	nps = selector.RecommendNodePools("compute", recommender.Cpu, req, nil,
+		append([]recommender.VirtualMachine(nil), vms...), append([]recommender.VirtualMachine(nil), vms...))
+	types, _ = distinctTypes(nps)
+	assert.True(t, types > 3, "the node pools should have more instance types without a limit")
+
+	req.MaxDistinctTypes = 1
+	nps = selector.RecommendNodePools("compute", recommender.Cpu, req, nil,
+		append([]recommender.VirtualMachine(nil), vms...), append([]recommender.VirtualMachine(nil), vms...))
+	types, total = distinctTypes(nps)
+	assert.Equal(t, 1, types, "the spot pools should reuse the type of the on-demand pool")
+	assert.True(t, total >= 64, "the capacity should be placed on the single type")
+
+	// the cheapest on-demand type has no spot price, the limit can't be met
+	nps = selector.RecommendNodePools("compute", recommender.Cpu, req, nil,
+		append([]recommender.VirtualMachine(nil), vms...), append([]recommender.VirtualMachine(nil), vms[1:]...))
+	types, total = distinctTypes(nps)
+	assert.Equal(t, 2, types, "a single spot type should be added")
+	assert.True(t, total >= 64, "the spot capacity should still be placed")
+}
+
+func Test_maxSpotTypes(t *testing.T) {
+	odNps := []recommender.NodePool{
+		{VmType: recommender.VirtualMachine{Type: "m5.xlarge"}, SumNodes: 2, VmClass: recommender.Regular},
+		{VmType: recommender.VirtualMachine{Type: "c5.xlarge"}, SumNodes: 0, VmClass: recommender.Regular},
+	}
+	reservedNps := []recommender.NodePool{
+		{VmType: recommender.VirtualMachine{Type: "m5.xlarge"}, SumNodes: 1, VmClass: recommender.Reserved},
+		{VmType: recommender.VirtualMachine{Type: "r5.xlarge"}, SumNodes: 1, VmClass: recommender.Reserved},
+	}
+
+	assert.Equal(t, -1, maxSpotTypes(0, odNps, reservedNps), "no limit")
+	assert.Equal(t, 3, maxSpotTypes(5, odNps, reservedNps))
+	assert.Equal(t, 0, maxSpotTypes(2, odNps, reservedNps), "the regular and reserved pools use up the types")
+}
+
+func Test_reusedSpotVms(t *testing.T) {
+	odNps := []recommender.NodePool{
+		{VmType: recommender.VirtualMachine{Type: "m5.xlarge"}, SumNodes: 2, VmClass: recommender.Regular},
+		{VmType: recommender.VirtualMachine{Type: "c5.xlarge"}, SumNodes: 0, VmClass: recommender.Regular},
+	}
+	spotVms := []recommender.VirtualMachine{{Type: "c5.xlarge"}, {Type: "r5.xlarge"}, {Type: "m5.xlarge"}}
+
+	reused := reusedSpotVms(spotVms, odNps)
+	if !assert.Len(t, reused, 1) {
+		return
+	}
+	assert.Equal(t, "m5.xlarge", reused[0].Type, "only the types of the pools with nodes should be reused")
+}
+
+func TestByAvgPricePerCpu_InterruptionHeat(t *testing.T) {
+	vms := []recommender.VirtualMachine{
+		{Type: "m5.xlarge", Cpus: 4, AvgPrice: 0.07, InterruptionHeat: 0.75},
+		{Type: "c5.xlarge", Cpus: 4, AvgPrice: 0.08},
+	}
+
+	sort.Sort(ByAvgPricePerCpu(vms))
+	assert.Equal(t, "c5.xlarge", vms[0].Type, "the recently interrupted type should be ranked lower")
+	assert.Equal(t, 0.07, vms[1].AvgPrice, "the price should not be changed")
+}
+
+func TestNodePoolSelector_RecommendNodePoolsPreferNewestGen(t *testing.T) {
+	vms := []recommender.VirtualMachine{
+		{Type: "m4.xlarge", Family: "m4", Cpus: 4, Mem: 16, OnDemandPrice: 0.2, AvgPrice: 0.060},
+		{Type: "m5.xlarge", Family: "m5", Cpus: 4, Mem: 16, OnDemandPrice: 0.205, AvgPrice: 0.062},
+		{Type: "m6i.xlarge", Family: "m6i", Cpus: 4, Mem: 16, OnDemandPrice: 0.23, AvgPrice: 0.08},
+	}
+	tests := []struct {
+		name  string
+		req   recommender.ClusterRecommendationReq
+		check func(nps []recommender.NodePool)
+	}{
+		{
+			name: "cheapest by default",
+			req:  recommender.ClusterRecommendationReq{SumCpu: 8, MinNodes: 1, MaxNodes: 2, OnDemandPct: 50},
+			check: func(nps []recommender.NodePool) {
+				assert.Equal(t, "m4.xlarge", nps[0].VmType.Type)
+				assert.Equal(t, "m4.xlarge", nps[1].VmType.Type)
+			},
+		},
+		{
+			name: "newest generation within the default tolerance",
+			req:  recommender.ClusterRecommendationReq{SumCpu: 8, MinNodes: 1, MaxNodes: 2, OnDemandPct: 50, PreferNewestGen: true},
+			check: func(nps []recommender.NodePool) {
+				assert.Equal(t, "m5.xlarge", nps[0].VmType.Type)
+				assert.Equal(t, "m5.xlarge", nps[1].VmType.Type)
+				assert.Equal(t, "m4.xlarge", nps[2].VmType.Type)
+			},
+		},
+		{
+			name: "newest generation within the requested tolerance",
+			req: recommender.ClusterRecommendationReq{SumCpu: 8, MinNodes: 1, MaxNodes: 2, OnDemandPct: 50, PreferNewestGen: true,
+				NewestGenTolerancePct: 40},
+			check: func(nps []recommender.NodePool) {
+				assert.Equal(t, "m6i.xlarge", nps[0].VmType.Type)
+				assert.Equal(t, "m6i.xlarge", nps[1].VmType.Type)
+			},
+		},
+	}
+	for _, test := range tests {
+		test := test //pin - scopelint
+		t.Run(test.name, func(t *testing.T) {
+			selector := NewNodePoolSelector(logur.NewTestLogger(), nil)
+			req := recommender.SingleClusterRecommendationReq{ClusterRecommendationReq: test.req}
+			odVms := append([]recommender.VirtualMachine(nil), vms...)
+			spotVms := append([]recommender.VirtualMachine(nil), vms...)
+			test.check(selector.RecommendNodePools("compute", recommender.Cpu, req, nil, odVms, spotVms))
+		})
+	}
+}
@@ -0,0 +1,95 @@
+// Copyright © 2019 Banzai Cloud
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package nodepools
+
+import (
+	"fmt"
+	"math"
+	"sort"
+
+	"github.com/banzaicloud/telescopes/pkg/recommender"
+)
+
+// fillReservedNodePools creates reserved node pools from the reservations covering the on-demand vm candidates,
+// until the on-demand value is reached; the covered types cheapest per attribute unit are used first, as those are the
+// types the on-demand pools would be filled with, so the reservations replace the nodes that would be bought otherwise
+// The node pools of zonal reservations are pinned to their zone, the zonal reservations of other zones than the
+// requested one are left unused
+// Returns the reserved node pools and the attribute value they provide
+func (s *nodePoolSelector) fillReservedNodePools(attr string, zone string, odValue float64,
+	reservations []recommender.Reservation, odVms []recommender.VirtualMachine) ([]recommender.NodePool, float64) {
+	if len(reservations) == 0 || odValue <= 0 {
+		return nil, 0
+	}
+
+	available := make(map[[2]string]int)
+	zones := make(map[string][]string)
+	for _, r := range reservations {
+		if zone != "" && r.Zone != "" && r.Zone != zone {
+			continue
+		}
+		key := [2]string{r.Type, r.Zone}
+		if _, ok := available[key]; !ok {
+			zones[r.Type] = append(zones[r.Type], r.Zone)
+		}
+		available[key] += r.Count
+	}
+
+	type reservedSlot struct {
+		vm   recommender.VirtualMachine
+		zone string
+	}
+	covered := make([]reservedSlot, 0)
+	for _, vm := range odVms {
+		if vm.GetAttrValue(attr) <= 0 {
+			continue
+		}
+		vmZones := zones[vm.Type]
+		sort.Strings(vmZones)
+		for _, z := range vmZones {
+			covered = append(covered, reservedSlot{vm: vm, zone: z})
+		}
+	}
+	sort.SliceStable(covered, func(i, j int) bool {
+		return covered[i].vm.OnDemandPrice/covered[i].vm.GetAttrValue(attr) < covered[j].vm.OnDemandPrice/covered[j].vm.GetAttrValue(attr)
+	})
+
+	var (
+		nps           []recommender.NodePool
+		reservedValue float64
+	)
+	for _, slot := range covered {
+		if reservedValue >= odValue {
+			break
+		}
+		key := [2]string{slot.vm.Type, slot.zone}
+		nodes := int(math.Min(float64(available[key]), math.Ceil((odValue-reservedValue)/slot.vm.GetAttrValue(attr))))
+		available[key] -= nodes
+		reservedValue += float64(nodes) * slot.vm.GetAttrValue(attr)
+		np := recommender.NodePool{
+			SumNodes: nodes,
+			VmClass:  recommender.Reserved,
+			VmType:   slot.vm,
+			Role:     recommender.Worker,
+		}
+		if slot.zone != "" {
+			np.Zones = []string{slot.zone}
+		}
+		nps = append(nps, np)
+		s.log.Debug(fmt.Sprintf("using [%d] reserved [%s] vms", nodes, slot.vm.Type), map[string]interface{}{"zone": slot.zone})
+	}
+
+	return nps, reservedValue
+}
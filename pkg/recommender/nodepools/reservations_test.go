@@ -0,0 +1,122 @@
+// Copyright © 2019 Banzai Cloud
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package nodepools
+
+import (
+	"testing"
+
+	"github.com/banzaicloud/telescopes/pkg/recommender"
+	"github.com/goph/logur"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNodePoolSelector_fillReservedNodePools(t *testing.T) {
+	odVms := []recommender.VirtualMachine{
+		{Type: "m5.xlarge", Cpus: 4, OnDemandPrice: 0.192},
+		{Type: "c5.xlarge", Cpus: 4, OnDemandPrice: 0.17},
+		{Type: "m5.large", Cpus: 2, OnDemandPrice: 0.096},
+	}
+	tests := []struct {
+		name         string
+		zone         string
+		odValue      float64
+		reservations []recommender.Reservation
+		check        func(nps []recommender.NodePool, value float64)
+	}{
+		{
+			name:    "no reservations",
+			odValue: 16,
+			check: func(nps []recommender.NodePool, value float64) {
+				assert.Nil(t, nps)
+				assert.Equal(t, float64(0), value)
+			},
+		},
+		{
+			name:    "reservations not covering the candidates are ignored",
+			odValue: 16,
+			reservations: []recommender.Reservation{
+				{Type: "r5.xlarge", Count: 4, RemainingMonths: 12},
+			},
+			check: func(nps []recommender.NodePool, value float64) {
+				assert.Nil(t, nps)
+				assert.Equal(t, float64(0), value)
+			},
+		},
+		{
+			name:    "reservations cover part of the on-demand value, cheapest first",
+			odValue: 16,
+			reservations: []recommender.Reservation{
+				{Type: "m5.xlarge", Count: 2, RemainingMonths: 12},
+				{Type: "c5.xlarge", Count: 1, RemainingMonths: 6},
+			},
+			check: func(nps []recommender.NodePool, value float64) {
+				assert.Equal(t, []recommender.NodePool{
+					{SumNodes: 1, VmClass: recommender.Reserved, VmType: odVms[1], Role: recommender.Worker},
+					{SumNodes: 2, VmClass: recommender.Reserved, VmType: odVms[0], Role: recommender.Worker},
+				}, nps)
+				assert.Equal(t, float64(12), value)
+			},
+		},
+		{
+			name:    "only the needed reserved slots are used",
+			odValue: 6,
+			reservations: []recommender.Reservation{
+				{Type: "m5.xlarge", Count: 10, RemainingMonths: 12},
+			},
+			check: func(nps []recommender.NodePool, value float64) {
+				assert.Len(t, nps, 1)
+				assert.Equal(t, 2, nps[0].SumNodes)
+				assert.Equal(t, float64(8), value)
+			},
+		},
+		{
+			name:    "zonal reservations are pinned to their zone",
+			odValue: 16,
+			reservations: []recommender.Reservation{
+				{Type: "m5.xlarge", Count: 1, Zone: "eu-west-1b"},
+				{Type: "m5.xlarge", Count: 1, RemainingMonths: 12},
+			},
+			check: func(nps []recommender.NodePool, value float64) {
+				assert.Equal(t, []recommender.NodePool{
+					{SumNodes: 1, VmClass: recommender.Reserved, VmType: odVms[0], Role: recommender.Worker},
+					{SumNodes: 1, VmClass: recommender.Reserved, VmType: odVms[0], Role: recommender.Worker, Zones: []string{"eu-west-1b"}},
+				}, nps)
+				assert.Equal(t, float64(8), value)
+			},
+		},
+		{
+			name:    "zonal reservations of other zones are ignored",
+			zone:    "eu-west-1a",
+			odValue: 16,
+			reservations: []recommender.Reservation{
+				{Type: "m5.xlarge", Count: 2, Zone: "eu-west-1b"},
+				{Type: "c5.xlarge", Count: 1, Zone: "eu-west-1a"},
+			},
+			check: func(nps []recommender.NodePool, value float64) {
+				assert.Equal(t, []recommender.NodePool{
+					{SumNodes: 1, VmClass: recommender.Reserved, VmType: odVms[1], Role: recommender.Worker, Zones: []string{"eu-west-1a"}},
+				}, nps)
+				assert.Equal(t, float64(4), value)
+			},
+		},
+	}
+	for _, test := range tests {
+		test := test //pin - scopelint
+		t.Run(test.name, func(t *testing.T) {
+			selector := NewNodePoolSelector(logur.NewTestLogger(), nil)
+			test.check(selector.fillReservedNodePools(recommender.Cpu, test.zone, test.odValue, test.reservations, odVms))
+		})
+	}
+}
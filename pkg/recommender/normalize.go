@@ -0,0 +1,47 @@
+// Copyright © 2019 Banzai Cloud
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package recommender
+
+// sharedCoreCpus maps the shared-core instance types to the share of a physical core they are entitled to, per provider;
+// the cloud info service reports the vCPUs these types can burst to, which doesn't compare to the dedicated vCPUs of other types
+var sharedCoreCpus = map[string]map[string]float64{
+	"google": {
+		"e2-micro":  0.25,
+		"e2-small":  0.5,
+		"e2-medium": 1,
+		"f1-micro":  0.2,
+		"g1-small":  0.5,
+	},
+}
+
+// NormalizedCpus gets the number of cpus of the instance type comparable across providers
+func NormalizedCpus(provider string, vmType string, cpus float64) float64 {
+	if share, ok := sharedCoreCpus[provider][vmType]; ok {
+		return share
+	}
+	return cpus
+}
+
+// normalizeAttributes replaces the reported attribute values of the given vms with the normalized ones,
+// so the per node limits and the cpu/memory ratios compare like with like across providers
+func normalizeAttributes(provider string, vms []VirtualMachine) {
+	for i := range vms {
+		cpus := NormalizedCpus(provider, vms[i].Type, vms[i].Cpus)
+		if cpus != vms[i].Cpus {
+			vms[i].ReportedCpus = vms[i].Cpus
+			vms[i].Cpus = cpus
+		}
+	}
+}
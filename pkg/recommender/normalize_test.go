@@ -0,0 +1,38 @@
+// Copyright © 2019 Banzai Cloud
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package recommender
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_normalizeAttributes(t *testing.T) {
+	vms := []VirtualMachine{
+		{Type: "e2-micro", Cpus: 2, Mem: 1},
+		{Type: "e2-standard-2", Cpus: 2, Mem: 8},
+	}
+	normalizeAttributes("google", vms)
+
+	assert.Equal(t, 0.25, vms[0].Cpus)
+	assert.Equal(t, float64(2), vms[0].ReportedCpus)
+	assert.Equal(t, float64(2), vms[1].Cpus, "dedicated vCPUs should not change")
+	assert.Equal(t, float64(0), vms[1].ReportedCpus)
+
+	aws := []VirtualMachine{{Type: "e2-micro", Cpus: 2}}
+	normalizeAttributes("amazon", aws)
+	assert.Equal(t, float64(2), aws[0].Cpus, "the shared-core types are provider specific")
+}
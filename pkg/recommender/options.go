@@ -0,0 +1,143 @@
+// Copyright © 2019 Banzai Cloud
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package recommender
+
+import "time"
+
+// EngineOption configures optional features of the Engine
+type EngineOption func(e *Engine)
+
+// WithBenchmarks makes the engine able to optimize for price per benchmark unit
+func WithBenchmarks(benchmarks Benchmarks) EngineOption {
+	return func(e *Engine) {
+		e.benchmarks = benchmarks
+	}
+}
+
+// WithMaxPods sets the maximum number of pods per instance type the engine checks the pod capacity against
+func WithMaxPods(maxPods MaxPods) EngineOption {
+	return func(e *Engine) {
+		e.maxPods = maxPods
+	}
+}
+
+// WithPriceAdjuster registers a price adjuster applied to the prices before optimization, adjusters are applied in the order of registration
+func WithPriceAdjuster(adjuster PriceAdjuster) EngineOption {
+	return func(e *Engine) {
+		e.priceAdjusters = append(e.priceAdjusters, adjuster)
+	}
+}
+
+// WithAfterburner enables the fast path of repeated scale out recommendations:
+// the candidate vms of a layout, with their prices, are reused for the given time instead of retrieving the products again
+func WithAfterburner(ttl time.Duration) EngineOption {
+	return func(e *Engine) {
+		e.afterburner = newAfterburner(ttl)
+	}
+}
+
+// WithMetadataCache keeps the providers, services, regions and zones served as metadata for the given time
+func WithMetadataCache(ttl time.Duration) EngineOption {
+	return func(e *Engine) {
+		e.metadata = newMetadataCache(ttl)
+	}
+}
+
+// WithCallBudget limits the number of calls a single recommendation may make to the cloud info service,
+// recommendations exceeding the budget are aborted; unlimited if zero
+func WithCallBudget(calls int) EngineOption {
+	return func(e *Engine) {
+		e.callBudget = calls
+	}
+}
+
+// WithPriceHistory makes the engine able to backtest layouts against the spot price history
+func WithPriceHistory(history PriceHistory) EngineOption {
+	return func(e *Engine) {
+		e.priceHistory = history
+	}
+}
+
+// WithInterruptionHeat makes the engine able to deprioritize the spot capacity pools interrupted recently
+func WithInterruptionHeat(heat InterruptionHeat) EngineOption {
+	return func(e *Engine) {
+		e.interruptionHeat = heat
+	}
+}
+
+// WithMinSpotCoverage excludes the vms from the spot pools whose spot price is known in less than the given ratio
+// of their zones, as their average spot price is unreliable
+func WithMinSpotCoverage(ratio float64) EngineOption {
+	return func(e *Engine) {
+		e.minSpotCoverage = ratio
+	}
+}
+
+// WithMaxSpotPriceRatio excludes the vms from the spot pools whose spot price is at least the given ratio of their
+// on-demand price; spot prices above the on-demand price are excluded regardless
+func WithMaxSpotPriceRatio(ratio float64) EngineOption {
+	return func(e *Engine) {
+		e.maxSpotRatio = ratio
+	}
+}
+
+// WithSpotDisabled makes the engine recommend on-demand instances only for the given providers, eg. to follow
+// an organization policy; the requests asking for spot instances get a warning
+func WithSpotDisabled(providers ...string) EngineOption {
+	return func(e *Engine) {
+		e.spotDisabled = make(map[string]bool, len(providers))
+		for _, provider := range providers {
+			e.spotDisabled[provider] = true
+		}
+	}
+}
+
+// WithDecisionEmitter makes the engine record its decisions with the emitter, eg. for training better heuristics offline
+func WithDecisionEmitter(emitter DecisionEmitter) EngineOption {
+	return func(e *Engine) {
+		e.decisions = emitter
+	}
+}
+
+// WithNodeCosts sets the hourly costs per node of the providers, added to the prices of the requests including them
+func WithNodeCosts(costs NodeCosts) EngineOption {
+	return func(e *Engine) {
+		e.nodeCosts = costs
+	}
+}
+
+// WithIncompatibleTypes sets the instance types the node pools of the managed services can't be launched with, they
+// are left out of the new layouts of the services
+func WithIncompatibleTypes(incompatible IncompatibleTypes) EngineOption {
+	return func(e *Engine) {
+		e.incompatible = incompatible
+	}
+}
+
+// WithDeprecations sets the instance types scheduled for deprecation per provider, the node pools of the
+// recommendations using them are marked with a suggested replacement
+func WithDeprecations(deprecations Deprecations) EngineOption {
+	return func(e *Engine) {
+		e.deprecations = deprecations
+	}
+}
+
+// WithSnapshots makes the engine store the products and prices each recommendation is made with, and reference the
+// snapshot in the response, so the recommendation can be reproduced later
+func WithSnapshots(store SnapshotStore) EngineOption {
+	return func(e *Engine) {
+		e.snapshots = store
+	}
+}
@@ -0,0 +1,94 @@
+// Copyright © 2019 Banzai Cloud
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package recommender
+
+import (
+	"fmt"
+	"math"
+)
+
+// maxOverheadPasses is the number of times the node pools are recommended again for the overhead of their nodes
+const maxOverheadPasses = 3
+
+// Overhead describes the resources used beside the requested ones, eg. by the sidecars of a service mesh or the daemon sets
+type Overhead struct {
+	// CPUs used per pod, eg. by a service mesh sidecar; added to the requested CPUs for the requested pods
+	PodCpu float64 `json:"podCpu,omitempty" binding:"min=0"`
+	// Memory used per pod (GiB by default); added to the requested memory for the requested pods
+	PodMem float64 `json:"podMem,omitempty" binding:"min=0"`
+	// CPUs used per node, eg. by the daemon sets; added to the requested CPUs for the recommended worker nodes
+	NodeCpu float64 `json:"nodeCpu,omitempty" binding:"min=0"`
+	// Memory used per node (GiB by default); added to the requested memory for the recommended worker nodes
+	NodeMem float64 `json:"nodeMem,omitempty" binding:"min=0"`
+}
+
+// hasPodOverhead checks whether overhead per pod is set
+func (o Overhead) hasPodOverhead() bool {
+	return o.PodCpu > 0 || o.PodMem > 0
+}
+
+// applyPodOverhead adds the overhead of the requested pods to the requested resources, it warns if the overhead per pod
+// is set but the number of pods is not
+func applyPodOverhead(req *SingleClusterRecommendationReq) *Warning {
+	if !req.Overhead.hasPodOverhead() {
+		return nil
+	}
+	if req.SumPods <= 0 {
+		return &Warning{
+			Code:    WarnPodOverheadIgnored,
+			Message: "the overhead per pod is ignored, sumPods is not set",
+		}
+	}
+	req.SumCpu += req.Overhead.PodCpu * float64(req.SumPods)
+	req.SumMem += req.Overhead.PodMem * float64(req.SumPods)
+	return nil
+}
+
+// workerNodes sums the nodes of the worker node pools
+func workerNodes(nodePools []NodePool) int {
+	var nodes int
+	for _, np := range nodePools {
+		if np.Role == Worker {
+			nodes += np.SumNodes
+		}
+	}
+	return nodes
+}
+
+// getCheapestNodePoolSetWithOverhead gets the cheapest node pools for the requested resources and the overhead of
+// their worker nodes; as the overhead depends on the number of nodes, the requested resources are raised by the
+// overhead of the nodes of the previous layout until the layout covers them, in a few passes at most
+func (e *Engine) getCheapestNodePoolSetWithOverhead(provider, service string, req *SingleClusterRecommendationReq,
+	layoutDesc []NodePoolDesc, allProducts []VirtualMachine) ([]NodePool, []AttributeLayout, error) {
+	sumCpu, sumMem := req.SumCpu, req.SumMem
+	for pass := 1; ; pass++ {
+		nodePools, attrLayouts, err := e.getCheapestNodePoolSet(provider, service, *req, layoutDesc, allProducts)
+		if err != nil {
+			return nil, nil, err
+		}
+
+		nodes := float64(workerNodes(nodePools))
+		cpu, mem := sumCpu+nodes*req.Overhead.NodeCpu, sumMem+nodes*req.Overhead.NodeMem
+		if cpu <= req.SumCpu && mem <= req.SumMem {
+			return nodePools, attrLayouts, nil
+		}
+		if pass == maxOverheadPasses {
+			e.log.Warn(fmt.Sprintf("the node pools may not cover the overhead of their %v worker nodes", nodes),
+				map[string]interface{}{"sumCpu": req.SumCpu, "sumMem": req.SumMem, "neededCpu": cpu, "neededMem": mem})
+			return nodePools, attrLayouts, nil
+		}
+		req.SumCpu, req.SumMem = math.Max(cpu, req.SumCpu), math.Max(mem, req.SumMem)
+	}
+}
@@ -0,0 +1,93 @@
+// Copyright © 2019 Banzai Cloud
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package recommender
+
+import (
+	"math"
+	"testing"
+
+	"github.com/goph/logur"
+	"github.com/stretchr/testify/assert"
+)
+
+// overheadNodePools recommends a single regular pool of 4 CPU nodes for the requested CPUs
+type overheadNodePools struct {
+	dummyNodePools
+}
+
+func (nps *overheadNodePools) RecommendNodePools(service string, attr string, req SingleClusterRecommendationReq, layout []NodePool, odVms []VirtualMachine, spotVms []VirtualMachine) []NodePool {
+	return []NodePool{{
+		VmType:   VirtualMachine{Cpus: 4, Mem: 16, OnDemandPrice: 1},
+		SumNodes: int(math.Ceil(req.SumCpu / 4)),
+		VmClass:  Regular,
+		Role:     Worker,
+	}}
+}
+
+func Test_applyPodOverhead(t *testing.T) {
+	req := SingleClusterRecommendationReq{ClusterRecommendationReq: ClusterRecommendationReq{
+		SumCpu: 8, SumMem: 32, SumPods: 40, Overhead: Overhead{PodCpu: 0.1, PodMem: 0.125},
+	}}
+	assert.Nil(t, applyPodOverhead(&req))
+	assert.InDelta(t, 12, req.SumCpu, 0.0001)
+	assert.InDelta(t, 37, req.SumMem, 0.0001)
+
+	req = SingleClusterRecommendationReq{ClusterRecommendationReq: ClusterRecommendationReq{
+		SumCpu: 8, SumMem: 32, Overhead: Overhead{PodCpu: 0.1},
+	}}
+	warning := applyPodOverhead(&req)
+	if !assert.NotNil(t, warning, "the overhead per pod needs the number of pods") {
+		return
+	}
+	assert.Equal(t, WarnPodOverheadIgnored, warning.Code)
+	assert.Equal(t, float64(8), req.SumCpu)
+}
+
+func TestEngine_getCheapestNodePoolSetWithOverhead(t *testing.T) {
+	tests := []struct {
+		name     string
+		overhead Overhead
+		sumCpu   float64
+		nodes    int
+	}{
+		{
+			name:   "no overhead",
+			sumCpu: 16,
+			nodes:  4,
+		},
+		{
+			name:     "overhead of the nodes added",
+			overhead: Overhead{NodeCpu: 0.5},
+			sumCpu:   18.5,
+			nodes:    5,
+		},
+	}
+	for _, test := range tests {
+		test := test
+		t.Run(test.name, func(t *testing.T) {
+			engine := NewEngine(logur.NewTestLogger(), nil, &dummyVms{}, &overheadNodePools{})
+			req := SingleClusterRecommendationReq{ClusterRecommendationReq: ClusterRecommendationReq{
+				SumCpu: 16, SumMem: 16, MinNodes: 1, MaxNodes: 10, Overhead: test.overhead,
+			}}
+
+			nodePools, _, err := engine.getCheapestNodePoolSetWithOverhead("amazon", "compute", &req, nil, nil)
+			if !assert.NoError(t, err) {
+				return
+			}
+			assert.Equal(t, test.sumCpu, req.SumCpu)
+			assert.Equal(t, test.nodes, workerNodes(nodePools))
+		})
+	}
+}
@@ -0,0 +1,55 @@
+// Copyright © 2019 Banzai Cloud
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package recommender
+
+import "fmt"
+
+// PinnedLayout checks whether the instance type mix of the scale out layout is to be kept
+func PinnedLayout(layout []NodePool) bool {
+	for _, np := range layout {
+		if np.Pinned {
+			return true
+		}
+	}
+	return false
+}
+
+// pinnedLayoutWarnings warns about the instance types the scale out adds to a pinned layout, as it couldn't be avoided
+func pinnedLayoutWarnings(layoutDesc []NodePoolDesc, nodePools []NodePool) []Warning {
+	pinned := false
+	existing := make(map[string]bool)
+	for _, npd := range layoutDesc {
+		pinned = pinned || npd.Pinned
+		if npd.SumNodes > 0 {
+			existing[npd.InstanceType+"/"+npd.GetVmClass()] = true
+		}
+	}
+	if !pinned {
+		return nil
+	}
+
+	var warnings []Warning
+	for _, np := range nodePools {
+		if np.Role == Master || np.SumNodes == 0 || existing[np.VmType.Type+"/"+np.VmClass] {
+			continue
+		}
+		warnings = append(warnings, Warning{
+			Code: WarnPinnedLayoutExtended,
+			Message: fmt.Sprintf("%d %s %s nodes added to the pinned layout, the existing node pools can't provide the requested resources",
+				np.SumNodes, np.VmClass, np.VmType.Type),
+		})
+	}
+	return warnings
+}
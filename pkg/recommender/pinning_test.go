@@ -0,0 +1,45 @@
+// Copyright © 2019 Banzai Cloud
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package recommender
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_pinnedLayoutWarnings(t *testing.T) {
+	nodePools := []NodePool{
+		{VmType: VirtualMachine{Type: "m5.xlarge"}, SumNodes: 4, VmClass: Regular, Role: Worker},
+		{VmType: VirtualMachine{Type: "c5.xlarge"}, SumNodes: 2, VmClass: Spot, Role: Worker},
+		{VmType: VirtualMachine{Type: "r5.xlarge"}, SumNodes: 0, VmClass: Spot, Role: Worker},
+		{VmType: VirtualMachine{Type: "m5.large"}, SumNodes: 1, VmClass: Regular, Role: Master},
+	}
+	layout := []NodePoolDesc{
+		{InstanceType: "m5.xlarge", VmClass: Regular, SumNodes: 2, Pinned: true},
+		{InstanceType: "c5.xlarge", VmClass: Spot, SumNodes: 0},
+		{InstanceType: "r5.xlarge", VmClass: Spot, SumNodes: 0},
+	}
+
+	warnings := pinnedLayoutWarnings(layout, nodePools)
+	if assert.Len(t, warnings, 1) {
+		assert.Equal(t, WarnPinnedLayoutExtended, warnings[0].Code)
+		assert.Contains(t, warnings[0].Message, "c5.xlarge")
+	}
+
+	layout[0].Pinned = false
+	assert.Empty(t, pinnedLayoutWarnings(layout, nodePools), "a layout that is not pinned may be extended")
+	assert.Empty(t, pinnedLayoutWarnings(nil, nodePools))
+}
@@ -0,0 +1,158 @@
+// Copyright © 2019 Banzai Cloud
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package recommender
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+
+	"github.com/goph/emperror"
+)
+
+// DefaultMaxPods is the kubelet default limit of pods per node
+const DefaultMaxPods = 110
+
+// MaxPods holds the maximum number of pods per node per provider and instance type (eg. limited by the CNI on EKS)
+type MaxPods map[string]map[string]int
+
+// LoadMaxPods reads the max pods table from a JSON file in the {"provider": {"instanceType": maxPods}} format
+func LoadMaxPods(path string) (MaxPods, error) {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, emperror.WrapWith(err, "failed to read max pods file", "path", path)
+	}
+
+	var maxPods MaxPods
+	if err := json.Unmarshal(data, &maxPods); err != nil {
+		return nil, emperror.WrapWith(err, "failed to parse max pods file", "path", path)
+	}
+
+	return maxPods, nil
+}
+
+// Get gets the maximum number of pods for the instance type, defaults to the kubelet default
+func (mp MaxPods) Get(provider string, vmType string) int {
+	if maxPods, ok := mp[provider][vmType]; ok && maxPods > 0 {
+		return maxPods
+	}
+	return DefaultMaxPods
+}
+
+// applyMaxPods sets the maximum number of pods of the given vms
+func (mp MaxPods) applyMaxPods(provider string, vms []VirtualMachine) {
+	for i := range vms {
+		vms[i].MaxPods = mp.Get(provider, vms[i].Type)
+	}
+}
+
+// ensurePodCapacity grows the worker node pools until they can schedule the requested number of pods
+// Nodes are added one by one to the pool with the lowest price per pod, alternating between the regular and spot pools
+// to keep the requested on-demand share; reserved pools are never grown. The worker nodes are kept within the maximum
+// number of nodes of the request, and pools reaching the maximum size of a pool of the service are split.
+// An error is returned if the pods don't fit into the maximum number of nodes.
+func (e *Engine) ensurePodCapacity(req SingleClusterRecommendationReq, maxPoolNodes int, nodePools []NodePool) ([]NodePool, *Warning, error) {
+	if req.SumPods <= 0 {
+		return nodePools, nil, nil
+	}
+
+	var podCapacity, workerNodes, onDemandNodes int
+	for _, np := range nodePools {
+		if np.Role != Worker {
+			continue
+		}
+		podCapacity += np.SumNodes * np.VmType.MaxPods
+		workerNodes += np.SumNodes
+		if np.VmClass != Spot {
+			onDemandNodes += np.SumNodes
+		}
+	}
+	if podCapacity >= req.SumPods {
+		return nodePools, nil, nil
+	}
+
+	initialCapacity, nodesAdded := podCapacity, 0
+	for podCapacity < req.SumPods {
+		if req.MaxNodes > 0 && workerNodes >= req.MaxNodes {
+			return nil, nil, emperror.With(ErrPodsDontFit, RecommenderErrorTag, "sumPods", req.SumPods,
+				"podCapacity", podCapacity, "maxNodes", req.MaxNodes)
+		}
+
+		// with onDemandCount the regular nodes are fixed, the pods are fit on spot nodes
+		class := Spot
+		if req.OnDemandCount == 0 && onDemandNodes*100 < req.OnDemandPct*(workerNodes+1) {
+			class = Regular
+		}
+		i := cheapestPoolPerPod(nodePools, class, maxPoolNodes)
+		if i == -1 {
+			if i = cheapestPoolPerPod(nodePools, "", maxPoolNodes); i == -1 {
+				return nil, nil, emperror.With(ErrPodsDontFit, RecommenderErrorTag, "sumPods", req.SumPods,
+					"podCapacity", podCapacity)
+			}
+		}
+		if maxPoolNodes > 0 && nodePools[i].SumNodes >= maxPoolNodes {
+			split := nodePools[i]
+			split.SumNodes = 0
+			nodePools = append(nodePools, split)
+			i = len(nodePools) - 1
+		}
+
+		nodePools[i].SumNodes++
+		podCapacity += nodePools[i].VmType.MaxPods
+		workerNodes++
+		nodesAdded++
+		if nodePools[i].VmClass != Spot {
+			onDemandNodes++
+		}
+	}
+
+	e.log.Debug("node pools grown to fit the requested pods", map[string]interface{}{"sumPods": req.SumPods,
+		"podCapacity": initialCapacity, "nodesAdded": nodesAdded})
+
+	return nodePools, &Warning{
+		Code: WarnPodCapacityAdjusted,
+		Message: fmt.Sprintf("added %d node(s) to fit %d pods, the layout could only schedule %d pods",
+			nodesAdded, req.SumPods, initialCapacity),
+	}, nil
+}
+
+// cheapestPoolPerPod gets the index of the growable worker pool of the class with the lowest price per pod,
+// any class if empty; pools below the maximum size are preferred on equal prices, -1 if there is no such pool
+func cheapestPoolPerPod(nodePools []NodePool, class string, maxPoolNodes int) int {
+	full := func(np NodePool) bool {
+		return maxPoolNodes > 0 && np.SumNodes >= maxPoolNodes
+	}
+
+	cheapest := -1
+	for i, np := range nodePools {
+		if np.Role != Worker || np.VmClass == Reserved || np.VmType.MaxPods <= 0 || (class != "" && np.VmClass != class) {
+			continue
+		}
+		if cheapest == -1 || np.pricePerPod() < nodePools[cheapest].pricePerPod() ||
+			(np.pricePerPod() == nodePools[cheapest].pricePerPod() && full(nodePools[cheapest]) && !full(np)) {
+			cheapest = i
+		}
+	}
+	return cheapest
+}
+
+// pricePerPod gets the price of a single node of the pool divided by the pods it can schedule
+func (n *NodePool) pricePerPod() float64 {
+	price := n.VmType.OnDemandPrice
+	if n.VmClass == Spot {
+		price = n.VmType.AvgPrice
+	}
+	return price / float64(n.VmType.MaxPods)
+}
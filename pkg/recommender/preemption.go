@@ -0,0 +1,59 @@
+// Copyright © 2019 Banzai Cloud
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package recommender
+
+// Preemption describes how the spot/preemptible instances of a provider are reclaimed
+type Preemption struct {
+	// Maximum runtime of an instance in hours, it is preempted at the latest after that; unlimited if zero
+	MaxRuntimeHours int `json:"maxRuntimeHours,omitempty"`
+	// Notice given before an instance is reclaimed, in seconds
+	NoticeSeconds int `json:"noticeSeconds"`
+	// What happens to a reclaimed instance, eg. terminate, stop or deallocate
+	EvictionPolicy string `json:"evictionPolicy"`
+}
+
+// preemptions holds the default spot/preemptible semantics per provider
+var preemptions = map[string]Preemption{
+	"amazon":  {NoticeSeconds: 120, EvictionPolicy: "terminate"},
+	"google":  {MaxRuntimeHours: 24, NoticeSeconds: 30, EvictionPolicy: "stop"},
+	"azure":   {NoticeSeconds: 30, EvictionPolicy: "deallocate"},
+	"alibaba": {NoticeSeconds: 300, EvictionPolicy: "release"},
+}
+
+// preemptionOf returns the spot/preemptible semantics of the provider
+func preemptionOf(provider string) (Preemption, bool) {
+	p, ok := preemptions[provider]
+	return p, ok
+}
+
+// spotRuntimeSupported checks whether the spot instances of the provider may run for the given number of hours
+func spotRuntimeSupported(provider string, hours int) bool {
+	p, ok := preemptionOf(provider)
+	return !ok || p.MaxRuntimeHours == 0 || p.MaxRuntimeHours >= hours
+}
+
+// applyPreemption attaches the spot/preemptible semantics of the provider to the spot node pools
+func applyPreemption(provider string, nodePools []NodePool) {
+	p, ok := preemptionOf(provider)
+	if !ok {
+		return
+	}
+	for i := range nodePools {
+		if nodePools[i].VmClass == Spot {
+			preemption := p
+			nodePools[i].Preemption = &preemption
+		}
+	}
+}
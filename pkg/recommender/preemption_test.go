@@ -0,0 +1,92 @@
+// Copyright © 2019 Banzai Cloud
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package recommender
+
+import (
+	"sync"
+	"testing"
+
+	"github.com/goph/logur"
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_spotRuntimeSupported(t *testing.T) {
+	assert.True(t, spotRuntimeSupported("google", 0), "no runtime requested")
+	assert.True(t, spotRuntimeSupported("google", 24))
+	assert.False(t, spotRuntimeSupported("google", 48), "preemptible vms run for at most 24 hours")
+	assert.True(t, spotRuntimeSupported("amazon", 48), "spot instances run until reclaimed")
+	assert.True(t, spotRuntimeSupported("unknown", 48), "unknown providers are not restricted")
+}
+
+func Test_applyPreemption(t *testing.T) {
+	nodePools := []NodePool{
+		{VmType: VirtualMachine{Type: "n1-standard-4"}, SumNodes: 2, VmClass: Regular},
+		{VmType: VirtualMachine{Type: "n1-standard-4"}, SumNodes: 3, VmClass: Spot},
+	}
+
+	applyPreemption("google", nodePools)
+
+	assert.Nil(t, nodePools[0].Preemption, "regular node pools are not preempted")
+	assert.Equal(t, &Preemption{MaxRuntimeHours: 24, NoticeSeconds: 30, EvictionPolicy: "stop"}, nodePools[1].Preemption)
+}
+
+// onDemandPctVms records the on-demand percentage the vms are recommended for
+type onDemandPctVms struct {
+	dummyVms
+	mux         sync.Mutex
+	onDemandPct int
+}
+
+func (v *onDemandPctVms) RecommendVms(provider string, vms []VirtualMachine, attr string, req SingleClusterRecommendationReq, layout []NodePool) ([]VirtualMachine, []VirtualMachine, error) {
+	v.mux.Lock()
+	defer v.mux.Unlock()
+	v.onDemandPct = req.OnDemandPct
+
+	_, spotVms, err := v.dummyVms.RecommendVms(provider, vms, attr, req, layout)
+	return spotVms, spotVms, err
+}
+
+func TestEngine_RecommendClusterMinSpotRuntime(t *testing.T) {
+	req := SingleClusterRecommendationReq{
+		ClusterRecommendationReq: ClusterRecommendationReq{
+			MinNodes:            1,
+			MaxNodes:            1,
+			SumMem:              32,
+			SumCpu:              16,
+			OnDemandPct:         50,
+			MinSpotRuntimeHours: 48,
+		},
+	}
+	vms := &onDemandPctVms{}
+	engine := NewEngine(logur.NewTestLogger(), &dummyProducts{}, vms, &dummyNodePools{})
+
+	resp, err := engine.RecommendCluster("google", "compute", "europe-west1", req, nil)
+	assert.Nil(t, err, "the error should be nil")
+	assert.Contains(t, warningCodes(resp.Warnings), WarnSpotRuntimeExceeded)
+	assert.Equal(t, 100, vms.onDemandPct, "preemptible vms should be excluded")
+
+	resp, err = engine.RecommendCluster("amazon", "compute", "eu-west-1", req, nil)
+	assert.Nil(t, err, "the error should be nil")
+	assert.NotContains(t, warningCodes(resp.Warnings), WarnSpotRuntimeExceeded)
+	assert.Equal(t, 50, vms.onDemandPct)
+}
+
+func warningCodes(warnings []Warning) []string {
+	codes := make([]string, 0, len(warnings))
+	for _, w := range warnings {
+		codes = append(codes, w.Code)
+	}
+	return codes
+}
@@ -0,0 +1,92 @@
+// Copyright © 2019 Banzai Cloud
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package recommender
+
+import (
+	"bytes"
+	"net/url"
+	"strconv"
+	"text/template"
+	"time"
+
+	"github.com/goph/emperror"
+)
+
+// DefaultSpotPriceQuery is the Prometheus query of the average spot price of an instance type, across the zones of the region
+const DefaultSpotPriceQuery = `avg(cloudinfo_spot_price{provider="{{.Provider}}",region="{{.Region}}",type="{{.Type}}"})`
+
+// PricePoint is a price sampled at a point in time
+type PricePoint struct {
+	Time  time.Time
+	Price float64
+}
+
+// PriceHistory retrieves the historical spot prices of instance types
+type PriceHistory interface {
+	// SpotPrices retrieves the spot prices of the instance type in the region, sampled by step within the window
+	SpotPrices(provider, service, region, vmType string, start, end time.Time, step time.Duration) ([]PricePoint, error)
+}
+
+// prometheusPriceHistory retrieves the spot price history from the metrics cloud info exports to Prometheus
+type prometheusPriceHistory struct {
+	client prometheusClient
+	query  *template.Template
+}
+
+// NewPrometheusPriceHistory creates a price history querying the Prometheus server at the address,
+// the query template gets the Provider, Service, Region and Type of the instance type
+func NewPrometheusPriceHistory(address, query string) (PriceHistory, error) {
+	if query == "" {
+		query = DefaultSpotPriceQuery
+	}
+	tmpl, err := template.New("query").Parse(query)
+	if err != nil {
+		return nil, emperror.WrapWith(err, "failed to parse the spot price query", "query", query)
+	}
+
+	return &prometheusPriceHistory{client: newPrometheusClient(address), query: tmpl}, nil
+}
+
+// SpotPrices runs a range query, the samples of all the returned series are merged
+func (p *prometheusPriceHistory) SpotPrices(provider, service, region, vmType string, start, end time.Time, step time.Duration) ([]PricePoint, error) {
+	var query bytes.Buffer
+	err := p.query.Execute(&query, map[string]string{"Provider": provider, "Service": service, "Region": region, "Type": vmType})
+	if err != nil {
+		return nil, emperror.Wrap(err, "failed to render the spot price query")
+	}
+
+	params := url.Values{}
+	params.Set("query", query.String())
+	params.Set("start", strconv.FormatInt(start.Unix(), 10))
+	params.Set("end", strconv.FormatInt(end.Unix(), 10))
+	params.Set("step", strconv.FormatFloat(step.Seconds(), 'f', -1, 64))
+
+	result, err := p.client.query("/api/v1/query_range", params)
+	if err != nil {
+		return nil, emperror.Wrap(err, "failed to query the spot price history")
+	}
+
+	var points []PricePoint
+	for _, series := range result {
+		for _, sample := range series.Values {
+			ts, price, err := parseSample(sample)
+			if err != nil {
+				return nil, err
+			}
+			points = append(points, PricePoint{Time: ts, Price: price})
+		}
+	}
+	return points, nil
+}
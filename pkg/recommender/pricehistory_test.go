@@ -0,0 +1,65 @@
+// Copyright © 2019 Banzai Cloud
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package recommender
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPrometheusPriceHistory_SpotPrices(t *testing.T) {
+	var query string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "/api/v1/query_range", r.URL.Path)
+		assert.Equal(t, "3600", r.URL.Query().Get("step"))
+		query = r.URL.Query().Get("query")
+		_, _ = w.Write([]byte(`{"status":"success","data":{"resultType":"matrix","result":[` +
+			`{"metric":{},"values":[[1556668800,"0.0612"],[1556672400,"0.0705"]]}]}}`))
+	}))
+	defer server.Close()
+
+	history, err := NewPrometheusPriceHistory(server.URL+"/", "")
+	assert.Nil(t, err, "the error should be nil")
+
+	start := time.Unix(1556668800, 0)
+	points, err := history.SpotPrices("amazon", "compute", "eu-west-1", "m5.xlarge", start, start.Add(time.Hour), time.Hour)
+	assert.Nil(t, err, "the error should be nil")
+	assert.Equal(t, `avg(cloudinfo_spot_price{provider="amazon",region="eu-west-1",type="m5.xlarge"})`, query)
+	assert.Equal(t, []PricePoint{
+		{Time: start, Price: 0.0612},
+		{Time: start.Add(time.Hour), Price: 0.0705},
+	}, points)
+}
+
+func TestPrometheusPriceHistory_SpotPricesError(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusBadRequest)
+		_, _ = w.Write([]byte(`{"status":"error","errorType":"bad_data","error":"parse error"}`))
+	}))
+	defer server.Close()
+
+	history, err := NewPrometheusPriceHistory(server.URL, "cloudinfo_spot_price{type=\"{{.Type}}\"}")
+	assert.Nil(t, err, "the error should be nil")
+
+	_, err = history.SpotPrices("amazon", "compute", "eu-west-1", "m5.xlarge", time.Now().Add(-time.Hour), time.Now(), time.Minute)
+	assert.NotNil(t, err, "the error should not be nil")
+
+	_, err = NewPrometheusPriceHistory(server.URL, "{{.Type")
+	assert.NotNil(t, err, "an invalid query template should be refused")
+}
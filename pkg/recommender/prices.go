@@ -0,0 +1,101 @@
+// Copyright © 2019 Banzai Cloud
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package recommender
+
+import (
+	"fmt"
+	"strings"
+)
+
+// PriceAdjuster adjusts the prices the engine optimizes for, eg. to apply enterprise discounts,
+// internal chargeback multipliers or carbon taxes
+type PriceAdjuster interface {
+	// AdjustPrice gets the adjusted hourly price of the vm in the given class (regular or spot)
+	AdjustPrice(provider string, vm VirtualMachine, vmClass string) float64
+}
+
+// PriceAdjusterFunc is a function implementing the PriceAdjuster interface
+type PriceAdjusterFunc func(provider string, vm VirtualMachine, vmClass string) float64
+
+// AdjustPrice calls the function
+func (f PriceAdjusterFunc) AdjustPrice(provider string, vm VirtualMachine, vmClass string) float64 {
+	return f(provider, vm, vmClass)
+}
+
+// applyPriceAdjusters adjusts the on-demand and spot prices of the given vms, the adjusters are applied in order
+func applyPriceAdjusters(provider string, adjusters []PriceAdjuster, vms []VirtualMachine) {
+	for _, adjuster := range adjusters {
+		for i := range vms {
+			vm := vms[i]
+			vms[i].OnDemandPrice = adjuster.AdjustPrice(provider, vm, Regular)
+			if vm.AvgPrice > 0 {
+				vms[i].AvgPrice = adjuster.AdjustPrice(provider, vm, Spot)
+			}
+		}
+	}
+}
+
+// excludeUncoveredSpots drops the spot prices of the vms whose spot price is known in less than the minimum ratio of
+// their zones, so they are left out of the spot pools; vms of unknown coverage are kept
+func excludeUncoveredSpots(minCoverage float64, vms []VirtualMachine) *Warning {
+	if minCoverage <= 0 {
+		return nil
+	}
+	var excluded []string
+	for i := range vms {
+		if vms[i].AvgPrice > 0 && vms[i].SpotCoverage > 0 && vms[i].SpotCoverage < minCoverage {
+			vms[i].AvgPrice = 0
+			excluded = append(excluded, fmt.Sprintf("%s (%.0f%%)", vms[i].Type, vms[i].SpotCoverage*100))
+		}
+	}
+	if len(excluded) == 0 {
+		return nil
+	}
+	return &Warning{
+		Code: WarnSpotCoverageLow,
+		Message: fmt.Sprintf("spot prices are known in less than %.0f%% of the zones of %s, excluded from the spot pools",
+			minCoverage*100, strings.Join(excluded, ", ")),
+	}
+}
+
+// excludeImplausibleSpots drops the spot prices of the vms whose spot price is above, or at least the given ratio of,
+// their on-demand price: these prices are stale or bogus, the vms are left out of the spot pools before the node pools
+// are assembled; vms of unknown on-demand price are kept
+func excludeImplausibleSpots(maxRatio float64, vms []VirtualMachine) *Warning {
+	var (
+		excluded []string
+		unpriced int
+	)
+	for i := range vms {
+		vm := vms[i]
+		switch {
+		case vm.AvgPrice == 0:
+			unpriced++
+		case vm.OnDemandPrice <= 0:
+		case vm.AvgPrice > vm.OnDemandPrice || (maxRatio > 0 && vm.AvgPrice >= maxRatio*vm.OnDemandPrice):
+			vms[i].AvgPrice = 0
+			excluded = append(excluded, fmt.Sprintf("%s (%.0f%%)", vm.Type, vm.AvgPrice/vm.OnDemandPrice*100))
+		}
+	}
+	if len(excluded) == 0 {
+		return nil
+	}
+	return &Warning{
+		Code: WarnSpotPriceImplausible,
+		Message: fmt.Sprintf("%d instance types excluded from the spot pools, their spot price is close to or above "+
+			"the on-demand price: %s; %d instance types have no spot price",
+			len(excluded), strings.Join(excluded, ", "), unpriced),
+	}
+}
@@ -0,0 +1,118 @@
+// Copyright © 2019 Banzai Cloud
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package recommender
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_applyPriceAdjusters(t *testing.T) {
+	discount := PriceAdjusterFunc(func(provider string, vm VirtualMachine, vmClass string) float64 {
+		if provider == "amazon" && vmClass == Regular {
+			return vm.OnDemandPrice * 0.8
+		}
+		if vmClass == Spot {
+			return vm.AvgPrice
+		}
+		return vm.OnDemandPrice
+	})
+	carbonTax := PriceAdjusterFunc(func(provider string, vm VirtualMachine, vmClass string) float64 {
+		if vmClass == Spot {
+			return vm.AvgPrice + 0.01
+		}
+		return vm.OnDemandPrice + 0.01
+	})
+
+	vms := []VirtualMachine{
+		{Type: "m5.xlarge", OnDemandPrice: 0.2, AvgPrice: 0.1},
+		{Type: "m4.xlarge", OnDemandPrice: 0.3},
+	}
+	applyPriceAdjusters("amazon", []PriceAdjuster{discount, carbonTax}, vms)
+
+	assert.InDelta(t, 0.17, vms[0].OnDemandPrice, 0.0001)
+	assert.InDelta(t, 0.11, vms[0].AvgPrice, 0.0001)
+	assert.InDelta(t, 0.25, vms[1].OnDemandPrice, 0.0001)
+	assert.Equal(t, float64(0), vms[1].AvgPrice, "missing spot price should not be set")
+}
+
+func Test_excludeUncoveredSpots(t *testing.T) {
+	vms := []VirtualMachine{
+		{Type: "m5.xlarge", AvgPrice: 0.07, SpotCoverage: 1},
+		{Type: "c5.xlarge", AvgPrice: 0.06, SpotCoverage: 1.0 / 3},
+		{Type: "r5.xlarge", AvgPrice: 0.08},
+	}
+
+	assert.Nil(t, excludeUncoveredSpots(0, vms), "coverage should not be checked without a minimum")
+	assert.Equal(t, 0.06, vms[1].AvgPrice)
+
+	warning := excludeUncoveredSpots(0.5, vms)
+	if !assert.NotNil(t, warning) {
+		return
+	}
+	assert.Equal(t, WarnSpotCoverageLow, warning.Code)
+	assert.Contains(t, warning.Message, "c5.xlarge (33%)")
+	assert.Equal(t, 0.07, vms[0].AvgPrice)
+	assert.Equal(t, 0.0, vms[1].AvgPrice)
+	assert.Equal(t, 0.08, vms[2].AvgPrice, "vms of unknown coverage should be kept")
+}
+
+func Test_excludeImplausibleSpots(t *testing.T) {
+	vms := []VirtualMachine{
+		{Type: "m5.xlarge", OnDemandPrice: 0.2, AvgPrice: 0.07},
+		{Type: "c5.xlarge", OnDemandPrice: 0.2, AvgPrice: 0.195},
+		{Type: "r5.xlarge", OnDemandPrice: 0.2, AvgPrice: 0.3},
+		{Type: "p3.2xlarge", OnDemandPrice: 3},
+		{Type: "x1.16xlarge", AvgPrice: 2},
+	}
+
+	warning := excludeImplausibleSpots(0, vms)
+	if !assert.NotNil(t, warning) {
+		return
+	}
+	assert.Equal(t, WarnSpotPriceImplausible, warning.Code)
+	assert.Contains(t, warning.Message, "1 instance types excluded")
+	assert.Contains(t, warning.Message, "r5.xlarge (150%)")
+	assert.Contains(t, warning.Message, "1 instance types have no spot price")
+	assert.Equal(t, 0.195, vms[1].AvgPrice, "spot prices below the on-demand price should be kept without a ratio")
+	assert.Equal(t, 0.0, vms[2].AvgPrice)
+
+	warning = excludeImplausibleSpots(0.95, vms)
+	if !assert.NotNil(t, warning) {
+		return
+	}
+	assert.Contains(t, warning.Message, "c5.xlarge (98%)")
+	assert.Equal(t, 0.07, vms[0].AvgPrice)
+	assert.Equal(t, 0.0, vms[1].AvgPrice)
+	assert.Equal(t, 2.0, vms[4].AvgPrice, "vms of unknown on-demand price should be kept")
+
+	assert.Nil(t, excludeImplausibleSpots(0.95, vms), "no warning is expected without implausible spot prices")
+}
+
+func Test_avg(t *testing.T) {
+	prices := []zonePriceDTO{{Zone: "a", Price: 0.02}, {Zone: "b", Price: 0.04}}
+
+	price, coverage := avg(prices, []string{"a", "b", "c", "d"})
+	assert.InDelta(t, 0.03, price, 0.0001)
+	assert.Equal(t, 0.5, coverage)
+
+	_, coverage = avg(prices, nil)
+	assert.Equal(t, 1.0, coverage, "the zones priced should be considered offered if the zones are unknown")
+
+	price, coverage = avg(nil, []string{"a"})
+	assert.Equal(t, 0.0, price)
+	assert.Equal(t, 0.0, coverage)
+}
@@ -16,11 +16,18 @@ package recommender
 
 import (
 	"context"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
 
 	"github.com/banzaicloud/telescopes/.gen/cloudinfo"
 	"github.com/go-openapi/runtime"
 	"github.com/goph/emperror"
 	"github.com/goph/logur"
+	"golang.org/x/sync/singleflight"
 )
 
 // CloudInfoSource declares operations for retrieving information required for the recommender engine
@@ -28,6 +35,9 @@ type CloudInfoSource interface {
 	// GetProductDetails retrieves the product details for the provider and region
 	GetProductDetails(provider string, service string, region string) ([]VirtualMachine, error)
 
+	// GetProductDetailsFiltered retrieves the product details for the provider and region passing the filter
+	GetProductDetailsFiltered(provider string, service string, region string, filter ProductFilter) ([]VirtualMachine, error)
+
 	// GetRegions retrieves the regions
 	GetRegions(provider, service string) ([]cloudinfo.Region, error)
 
@@ -43,11 +53,20 @@ type CloudInfoSource interface {
 	//GetRegion retrieves the region for the provided arguments, returns error if not found
 	GetRegion(provider string, service string, region string) (string, error)
 
+	// GetProviders retrieves the providers with their services
+	GetProviders() ([]cloudinfo.Provider, error)
+
 	//GetProvider retrieves the given provider,returns error if not found
 	GetProvider(provider string) (string, error)
 
 	//GetService  retrieves the given service, returns error if not found
 	GetService(provider string, service string) (string, error)
+
+	// GetDataFreshness retrieves the freshness of the product data last retrieved for the provider, service and region
+	GetDataFreshness(provider string, service string, region string) (DataFreshness, bool)
+
+	// GetAllDataFreshness retrieves the freshness of all the product data retrieved so far
+	GetAllDataFreshness() []DataFreshness
 }
 
 // cloudInfoClient component struct to retrieve data for the recommender; wraps the generated product info client
@@ -55,23 +74,42 @@ type CloudInfoSource interface {
 type cloudInfoClient struct {
 	logger logur.Logger
 	*cloudinfo.APIClient
+
+	// basePath and httpClient are used for the requests not going through the generated client
+	basePath   string
+	httpClient *http.Client
+
+	// freshness holds the data freshness info keyed by provider/service/region
+	freshness    map[string]DataFreshness
+	freshnessMux sync.RWMutex
+
+	// graphQLUnsupported is set once cloud info turns out not to serve the filtered product query
+	graphQLUnsupported int32
+
+	// inflight collapses concurrent zone and region lookups for the same key into a single upstream call
+	inflight singleflight.Group
 }
 
 const (
 	cloudInfoService         = "cloud-info"
 	cloudInfoClientComponent = "cloud-info-client"
+	userAgent                = "Telescopes/go"
 )
 
 // NewCloudInfoClient creates a new product info client wrapper instance
 func NewCloudInfoClient(ciUrl string, logger logur.Logger) CloudInfoSource {
-	apiCli := cloudinfo.NewAPIClient(&cloudinfo.Configuration{
+	cfg := &cloudinfo.Configuration{
 		BasePath:      ciUrl,
 		DefaultHeader: make(map[string]string),
-		UserAgent:     "Telescopes/go",
-	})
+		UserAgent:     userAgent,
+		HTTPClient:    http.DefaultClient,
+	}
 	return &cloudInfoClient{
-		APIClient: apiCli,
-		logger:    logur.WithFields(logger, map[string]interface{}{"cli": cloudInfoClientComponent}),
+		APIClient:  cloudinfo.NewAPIClient(cfg),
+		basePath:   ciUrl,
+		httpClient: cfg.HTTPClient,
+		logger:     logur.WithFields(logger, map[string]interface{}{"cli": cloudInfoClientComponent}),
+		freshness:  make(map[string]DataFreshness),
 	}
 }
 
@@ -80,45 +118,135 @@ func (ciCli *cloudInfoClient) GetProductDetails(provider string, service string,
 	tags := map[string]interface{}{"provider": provider, "service": service, "region": region}
 	ciCli.logger.Info("retrieving product details", tags)
 
-	allProducts, _, err := ciCli.ProductsApi.GetProducts(context.Background(), provider, service, region)
+	allProducts, err := ciCli.getProducts(context.Background(), provider, service, region)
 	if err != nil {
 
 		ciCli.logger.Error("failed to retrieve product details", tags)
 		return nil, discriminateErrCtx(err)
 	}
 
-	vms := make([]VirtualMachine, 0)
+	ciCli.recordFreshness(provider, service, region, allProducts.ScrapingTime, allProducts.hasSpotPrices())
 
+	vms := make([]VirtualMachine, 0, len(allProducts.Products))
 	for _, p := range allProducts.Products {
-		vms = append(vms, VirtualMachine{
-			Category:       p.Category,
-			Type:           p.Type,
-			OnDemandPrice:  p.OnDemandPrice,
-			AvgPrice:       avg(p.SpotPrice),
-			Cpus:           p.CpusPerVm,
-			Mem:            p.MemPerVm,
-			Gpus:           p.GpusPerVm,
-			Burst:          p.Burst,
-			NetworkPerf:    p.NtwPerf,
-			NetworkPerfCat: p.NtwPerfCategory,
-			CurrentGen:     p.CurrentGen,
-			Zones:          p.Zones,
-		})
+		vms = append(vms, p.toVirtualMachine())
 	}
 
 	ciCli.logger.Info("retrieved product details", tags)
 	return vms, nil
 }
 
-func avg(prices []cloudinfo.ZonePrice) float64 {
+// recordFreshness stores the retrieval and scraping time of the product details
+func (ciCli *cloudInfoClient) recordFreshness(provider, service, region string, scrapingTime string, hasSpotPrices bool) {
+	df := DataFreshness{
+		Provider:      provider,
+		Service:       service,
+		Region:        region,
+		RetrievedAt:   time.Now(),
+		hasSpotPrices: hasSpotPrices,
+	}
+
+	if scrapingTime != "" {
+		// cloud info reports the scraping time in milliseconds since epoch
+		if ms, err := strconv.ParseInt(scrapingTime, 10, 64); err == nil {
+			scrapedAt := time.Unix(0, ms*int64(time.Millisecond))
+			df.ScrapedAt = &scrapedAt
+		} else {
+			ciCli.logger.Warn("failed to parse scraping time", map[string]interface{}{"scrapingTime": scrapingTime})
+		}
+	}
+
+	ciCli.freshnessMux.Lock()
+	defer ciCli.freshnessMux.Unlock()
+	ciCli.freshness[freshnessKey(provider, service, region)] = df
+}
+
+// GetDataFreshness gets the freshness of the product details last retrieved for the provider, service and region
+func (ciCli *cloudInfoClient) GetDataFreshness(provider string, service string, region string) (DataFreshness, bool) {
+	ciCli.freshnessMux.RLock()
+	defer ciCli.freshnessMux.RUnlock()
+
+	df, ok := ciCli.freshness[freshnessKey(provider, service, region)]
+	if !ok {
+		return DataFreshness{}, false
+	}
+	return df.WithAges(time.Now()), true
+}
+
+// GetAllDataFreshness gets the freshness of all the product details retrieved so far, ordered by key
+func (ciCli *cloudInfoClient) GetAllDataFreshness() []DataFreshness {
+	ciCli.freshnessMux.RLock()
+	defer ciCli.freshnessMux.RUnlock()
+
+	keys := make([]string, 0, len(ciCli.freshness))
+	for k := range ciCli.freshness {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	now := time.Now()
+	dfs := make([]DataFreshness, 0, len(keys))
+	for _, k := range keys {
+		dfs = append(dfs, ciCli.freshness[k].WithAges(now))
+	}
+	return dfs
+}
+
+func freshnessKey(provider, service, region string) string {
+	return strings.Join([]string{provider, service, region}, "/")
+}
+
+// avg gets the average of the zone prices and the coverage of the zones: the ratio of the zones having a price to
+// the zones the product is offered in; all the zones priced are considered offered if the zones are unknown
+func avg(prices []zonePriceDTO, zones []string) (float64, float64) {
 	if len(prices) == 0 {
-		return 0.0
+		return 0.0, 0.0
 	}
 	avgPrice := 0.0
+	priced := make(map[string]bool, len(prices))
 	for _, price := range prices {
 		avgPrice += price.Price
+		priced[price.Zone] = true
+	}
+	avgPrice /= float64(len(prices))
+
+	if len(zones) == 0 {
+		return avgPrice, 1.0
+	}
+	covered := 0
+	for _, zone := range zones {
+		if priced[zone] {
+			covered++
+		}
+	}
+	return avgPrice, float64(covered) / float64(len(zones))
+}
+
+// zonePrices maps the spot prices by zone
+func zonePrices(prices []zonePriceDTO) map[string]float64 {
+	if len(prices) == 0 {
+		return nil
+	}
+	byZone := make(map[string]float64, len(prices))
+	for _, price := range prices {
+		byZone[price.Zone] = price.Price
+	}
+	return byZone
+}
+
+// GetProviders gets the providers with their services
+func (ciCli *cloudInfoClient) GetProviders() ([]cloudinfo.Provider, error) {
+	ciCli.logger.Info("retrieving providers")
+
+	r, _, err := ciCli.ProvidersApi.GetProviders(context.Background())
+	if err != nil {
+
+		ciCli.logger.Error("failed to retrieve providers")
+		return nil, discriminateErrCtx(err)
 	}
-	return avgPrice / float64(len(prices))
+
+	ciCli.logger.Info("retrieved providers")
+	return r.Providers, nil
 }
 
 // GetProvider validates provider
@@ -174,7 +302,13 @@ func (ciCli *cloudInfoClient) GetZones(provider, service, region string) ([]stri
 	tags := map[string]interface{}{"provider": provider, "service": service, "region": region}
 	ciCli.logger.Info("retrieving zones", tags)
 
-	r, _, err := ciCli.RegionApi.GetRegion(context.Background(), provider, service, region)
+	v, err, _ := ciCli.inflight.Do(strings.Join([]string{"zones", provider, service, region}, "/"), func() (interface{}, error) {
+		r, _, err := ciCli.RegionApi.GetRegion(context.Background(), provider, service, region)
+		if err != nil {
+			return nil, err
+		}
+		return r.Zones, nil
+	})
 	if err != nil {
 
 		ciCli.logger.Error("failed to retrieve zones", tags)
@@ -182,7 +316,8 @@ func (ciCli *cloudInfoClient) GetZones(provider, service, region string) ([]stri
 	}
 
 	ciCli.logger.Info("retrieved zones", tags)
-	return r.Zones, nil
+	// the result of the collapsed call is shared by the callers, each of them gets a copy
+	return append([]string(nil), v.([]string)...), nil
 }
 
 // GetRegions gets regions
@@ -191,7 +326,10 @@ func (ciCli *cloudInfoClient) GetRegions(provider, service string) ([]cloudinfo.
 	tags := map[string]interface{}{"provider": provider, "service": service}
 	ciCli.logger.Info("retrieving regions", tags)
 
-	r, _, err := ciCli.RegionsApi.GetRegions(context.Background(), provider, service)
+	v, err, _ := ciCli.inflight.Do(strings.Join([]string{"regions", provider, service}, "/"), func() (interface{}, error) {
+		r, _, err := ciCli.RegionsApi.GetRegions(context.Background(), provider, service)
+		return r, err
+	})
 	if err != nil {
 
 		ciCli.logger.Error("failed to retrieve regions", tags)
@@ -199,7 +337,8 @@ func (ciCli *cloudInfoClient) GetRegions(provider, service string) ([]cloudinfo.
 	}
 
 	ciCli.logger.Info("retrieved regions", tags)
-	return r, nil
+	// the result of the collapsed call is shared by the callers, each of them gets a copy
+	return append([]cloudinfo.Region(nil), v.([]cloudinfo.Region)...), nil
 }
 
 func (ciCli *cloudInfoClient) GetContinentsData(provider, service string) ([]cloudinfo.Continent, error) {
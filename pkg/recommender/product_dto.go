@@ -0,0 +1,137 @@
+// Copyright © 2019 Banzai Cloud
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package recommender
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+
+	"github.com/go-openapi/runtime"
+	"github.com/goph/emperror"
+)
+
+// productsDTO is the slim version of the cloud info products response, holding only the fields used by the engine
+type productsDTO struct {
+	Products     []productDTO `json:"products"`
+	ScrapingTime string       `json:"scrapingTime"`
+}
+
+// productDTO is the slim version of the cloud info product details; the attribute map is not decoded
+type productDTO struct {
+	Type            string         `json:"type"`
+	Category        string         `json:"category"`
+	OnDemandPrice   float64        `json:"onDemandPrice"`
+	SpotPrice       []zonePriceDTO `json:"spotPrice"`
+	CpusPerVm       float64        `json:"cpusPerVm"`
+	MemPerVm        float64        `json:"memPerVm"`
+	GpusPerVm       float64        `json:"gpusPerVm"`
+	Burst           bool           `json:"burst"`
+	NtwPerf         string         `json:"ntwPerf"`
+	NtwPerfCategory string         `json:"ntwPerfCategory"`
+	CurrentGen      bool           `json:"currentGen"`
+	Zones           []string       `json:"zones"`
+	Attributes      attributesDTO  `json:"attributes"`
+}
+
+// attributesDTO holds the string forms of the cpu and memory attributes reported by the provider, eg. "3,904 GiB"
+type attributesDTO struct {
+	Cpu    string `json:"cpu"`
+	Vcpu   string `json:"vcpu"`
+	Memory string `json:"memory"`
+}
+
+type zonePriceDTO struct {
+	Zone  string  `json:"zone"`
+	Price float64 `json:"price"`
+}
+
+// toVirtualMachine converts the product to the engine's representation
+func (p productDTO) toVirtualMachine() VirtualMachine {
+	avgPrice, spotCoverage := avg(p.SpotPrice, p.Zones)
+	return VirtualMachine{
+		Category:       p.Category,
+		Type:           p.Type,
+		OnDemandPrice:  p.OnDemandPrice,
+		AvgPrice:       avgPrice,
+		SpotCoverage:   spotCoverage,
+		SpotPrices:     zonePrices(p.SpotPrice),
+		Cpus:           p.CpusPerVm,
+		Mem:            p.MemPerVm,
+		Gpus:           p.GpusPerVm,
+		Burst:          p.Burst,
+		NetworkPerf:    p.NtwPerf,
+		NetworkPerfCat: p.NtwPerfCategory,
+		CurrentGen:     p.CurrentGen,
+		Zones:          p.Zones,
+		CpuLabel:       firstNonEmpty(p.Attributes.Vcpu, p.Attributes.Cpu),
+		MemLabel:       p.Attributes.Memory,
+	}
+}
+
+func firstNonEmpty(values ...string) string {
+	for _, v := range values {
+		if v != "" {
+			return v
+		}
+	}
+	return ""
+}
+
+// hasSpotPrices checks whether any of the products has spot prices
+func (p productsDTO) hasSpotPrices() bool {
+	for _, product := range p.Products {
+		if len(product.SpotPrice) > 0 {
+			return true
+		}
+	}
+	return false
+}
+
+// getProducts retrieves the products directly from cloud info, decoding the response body as a stream into the slim DTO
+// cloud info doesn't support field selection; the response is gzip compressed by the transport if the server supports it
+func (ciCli *cloudInfoClient) getProducts(ctx context.Context, provider, service, region string) (*productsDTO, error) {
+	path := fmt.Sprintf("%s/providers/%s/services/%s/regions/%s/products", ciCli.basePath,
+		url.PathEscape(provider), url.PathEscape(service), url.PathEscape(region))
+
+	req, err := http.NewRequest(http.MethodGet, path, nil)
+	if err != nil {
+		return nil, err
+	}
+	req = req.WithContext(ctx)
+	req.Header.Set("Accept", "application/json")
+	req.Header.Set("User-Agent", userAgent)
+
+	resp, err := ciCli.httpClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode >= http.StatusMultipleChoices {
+		body, _ := ioutil.ReadAll(resp.Body)
+		return nil, runtime.NewAPIError("getProducts", string(body), resp.StatusCode)
+	}
+
+	var products productsDTO
+	if err := json.NewDecoder(resp.Body).Decode(&products); err != nil {
+		return nil, emperror.Wrap(err, "failed to decode product details")
+	}
+
+	return &products, nil
+}
@@ -0,0 +1,80 @@
+// Copyright © 2019 Banzai Cloud
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package recommender
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/go-openapi/runtime"
+	"github.com/goph/logur"
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCloudInfoClient_GetProductDetails(t *testing.T) {
+	tests := []struct {
+		name   string
+		status int
+		body   string
+		check  func(vms []VirtualMachine, err error)
+	}{
+		{
+			name:   "products are converted",
+			status: http.StatusOK,
+			body: `{"products": [{"type": "c5.large", "category": "Compute optimized", "onDemandPrice": 0.1,
+				"spotPrice": [{"zone": "a", "price": 0.02}, {"zone": "b", "price": 0.04}], "cpusPerVm": 2, "memPerVm": 4,
+				"attributes": {"cpu": "2", "memory": "4"}, "zones": ["a", "b"]}], "scrapingTime": "1556000000000"}`,
+			check: func(vms []VirtualMachine, err error) {
+				assert.Nil(t, err)
+				assert.Equal(t, 1, len(vms))
+				assert.Equal(t, "c5.large", vms[0].Type)
+				assert.Equal(t, float64(2), vms[0].Cpus)
+				assert.InDelta(t, 0.03, vms[0].AvgPrice, 0.0001)
+				assert.Equal(t, 1.0, vms[0].SpotCoverage)
+				assert.Equal(t, map[string]float64{"a": 0.02, "b": 0.04}, vms[0].SpotPrices)
+				assert.Equal(t, []string{"a", "b"}, vms[0].Zones)
+				assert.Equal(t, "2", vms[0].CpuLabel)
+				assert.Equal(t, "4", vms[0].MemLabel)
+			},
+		},
+		{
+			name:   "cloud info error",
+			status: http.StatusBadRequest,
+			body:   `{"message": "invalid region"}`,
+			check: func(vms []VirtualMachine, err error) {
+				assert.Nil(t, vms)
+				_, ok := err.(*runtime.APIError)
+				assert.True(t, ok, "the error should be an api error")
+			},
+		},
+	}
+	for _, test := range tests {
+		test := test
+		t.Run(test.name, func(t *testing.T) {
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				assert.Equal(t, "/providers/amazon/services/compute/regions/eu-west-1/products", r.URL.Path)
+				w.WriteHeader(test.status)
+				_, _ = w.Write([]byte(test.body))
+			}))
+			defer server.Close()
+
+			ciCli := NewCloudInfoClient(server.URL, logur.NewTestLogger())
+			vms, err := ciCli.GetProductDetails("amazon", "compute", "eu-west-1")
+			test.check(vms, errors.Cause(err))
+		})
+	}
+}
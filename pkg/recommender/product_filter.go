@@ -0,0 +1,213 @@
+// Copyright © 2019 Banzai Cloud
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package recommender
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"sync/atomic"
+
+	"github.com/go-openapi/runtime"
+	"github.com/goph/emperror"
+	"github.com/pkg/errors"
+)
+
+// ProductFilter restricts the products retrieved by their cpu and memory, zero values are unbounded
+type ProductFilter struct {
+	MinCpu float64
+	MaxCpu float64
+	MinMem float64
+	MaxMem float64
+}
+
+// IsZero checks whether the filter lets all products pass
+func (f ProductFilter) IsZero() bool {
+	return f == ProductFilter{}
+}
+
+// Apply filters the vms on the client side
+func (f ProductFilter) Apply(vms []VirtualMachine) []VirtualMachine {
+	if f.IsZero() {
+		return vms
+	}
+	filtered := make([]VirtualMachine, 0, len(vms))
+	for _, vm := range vms {
+		if inRange(vm.Cpus, f.MinCpu, f.MaxCpu) && inRange(vm.Mem, f.MinMem, f.MaxMem) {
+			filtered = append(filtered, vm)
+		}
+	}
+	return filtered
+}
+
+func inRange(v, min, max float64) bool {
+	return (min == 0 || v >= min) && (max == 0 || v <= max)
+}
+
+// graphQLFilter assembles the instance type filter of the cloud info GraphQL query
+func (f ProductFilter) graphQLFilter() map[string]interface{} {
+	bounds := func(min, max float64) map[string]interface{} {
+		b := make(map[string]interface{})
+		if min > 0 {
+			b["gte"] = min
+		}
+		if max > 0 {
+			b["lte"] = max
+		}
+		return b
+	}
+
+	filter := make(map[string]interface{})
+	if b := bounds(f.MinCpu, f.MaxCpu); len(b) > 0 {
+		filter["cpu"] = b
+	}
+	if b := bounds(f.MinMem, f.MaxMem); len(b) > 0 {
+		filter["memory"] = b
+	}
+	return filter
+}
+
+const instanceTypesQuery = `query($provider: String!, $service: String!, $region: String!, $filter: InstanceTypeQueryInput) {
+  instanceTypes(provider: $provider, service: $service, region: $region, filter: $filter) {
+    name category price spotPrice { zone price } cpusPerVm memPerVm gpusPerVm burst ntwPerf ntwPerfCategory currentGen zones
+  }
+}`
+
+// instanceTypeDTO is an instance type as returned by the cloud info GraphQL API
+type instanceTypeDTO struct {
+	Name            string         `json:"name"`
+	Category        string         `json:"category"`
+	Price           float64        `json:"price"`
+	SpotPrice       []zonePriceDTO `json:"spotPrice"`
+	CpusPerVm       float64        `json:"cpusPerVm"`
+	MemPerVm        float64        `json:"memPerVm"`
+	GpusPerVm       float64        `json:"gpusPerVm"`
+	Burst           bool           `json:"burst"`
+	NtwPerf         string         `json:"ntwPerf"`
+	NtwPerfCategory string         `json:"ntwPerfCategory"`
+	CurrentGen      bool           `json:"currentGen"`
+	Zones           []string       `json:"zones"`
+}
+
+func (it instanceTypeDTO) toVirtualMachine() VirtualMachine {
+	return productDTO{
+		Type:            it.Name,
+		Category:        it.Category,
+		OnDemandPrice:   it.Price,
+		SpotPrice:       it.SpotPrice,
+		CpusPerVm:       it.CpusPerVm,
+		MemPerVm:        it.MemPerVm,
+		GpusPerVm:       it.GpusPerVm,
+		Burst:           it.Burst,
+		NtwPerf:         it.NtwPerf,
+		NtwPerfCategory: it.NtwPerfCategory,
+		CurrentGen:      it.CurrentGen,
+		Zones:           it.Zones,
+	}.toVirtualMachine()
+}
+
+// errGraphQLUnsupported signals that the cloud info service doesn't serve the GraphQL query
+var errGraphQLUnsupported = errors.New("cloud info GraphQL query is not supported")
+
+// GetProductDetailsFiltered gets the product details passing the filter; the filter is sent to cloud info if it
+// supports filtering, otherwise all the products are retrieved and filtered on the client side
+func (ciCli *cloudInfoClient) GetProductDetailsFiltered(provider string, service string, region string, filter ProductFilter) ([]VirtualMachine, error) {
+	if filter.IsZero() {
+		return ciCli.GetProductDetails(provider, service, region)
+	}
+
+	if atomic.LoadInt32(&ciCli.graphQLUnsupported) == 0 {
+		vms, err := ciCli.queryProducts(context.Background(), provider, service, region, filter)
+		if err == nil {
+			return vms, nil
+		}
+		if errors.Cause(err) == errGraphQLUnsupported {
+			atomic.StoreInt32(&ciCli.graphQLUnsupported, 1)
+		}
+		ciCli.logger.Warn("failed to query filtered product details, filtering on the client side",
+			map[string]interface{}{"provider": provider, "service": service, "region": region, "err": err.Error()})
+	}
+
+	vms, err := ciCli.GetProductDetails(provider, service, region)
+	if err != nil {
+		return nil, err
+	}
+	return filter.Apply(vms), nil
+}
+
+// queryProducts queries the instance types passing the filter from the cloud info GraphQL API, served next to the REST API
+func (ciCli *cloudInfoClient) queryProducts(ctx context.Context, provider, service, region string, filter ProductFilter) ([]VirtualMachine, error) {
+	body, err := json.Marshal(map[string]interface{}{
+		"query": instanceTypesQuery,
+		"variables": map[string]interface{}{
+			"provider": provider,
+			"service":  service,
+			"region":   region,
+			"filter":   filter.graphQLFilter(),
+		},
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	path := strings.TrimSuffix(strings.TrimSuffix(ciCli.basePath, "/"), "/api/v1") + "/graphql"
+	req, err := http.NewRequest(http.MethodPost, path, bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	req = req.WithContext(ctx)
+	req.Header.Set("Accept", "application/json")
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", userAgent)
+
+	resp, err := ciCli.httpClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	switch {
+	case resp.StatusCode == http.StatusNotFound || resp.StatusCode == http.StatusMethodNotAllowed:
+		return nil, errGraphQLUnsupported
+	case resp.StatusCode >= http.StatusMultipleChoices:
+		data, _ := ioutil.ReadAll(resp.Body)
+		return nil, runtime.NewAPIError("queryProducts", string(data), resp.StatusCode)
+	}
+
+	var result struct {
+		Data struct {
+			InstanceTypes []instanceTypeDTO `json:"instanceTypes"`
+		} `json:"data"`
+		Errors []struct {
+			Message string `json:"message"`
+		} `json:"errors"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return nil, emperror.Wrap(err, "failed to decode instance types")
+	}
+	if len(result.Errors) > 0 {
+		// the schema doesn't match the query, it's not going to change until cloud info is upgraded
+		return nil, emperror.With(errGraphQLUnsupported, "message", result.Errors[0].Message)
+	}
+
+	vms := make([]VirtualMachine, 0, len(result.Data.InstanceTypes))
+	for _, it := range result.Data.InstanceTypes {
+		vms = append(vms, it.toVirtualMachine())
+	}
+	return vms, nil
+}
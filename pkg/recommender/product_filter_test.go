@@ -0,0 +1,97 @@
+// Copyright © 2019 Banzai Cloud
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package recommender
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/goph/logur"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCloudInfoClient_GetProductDetailsFiltered(t *testing.T) {
+	const products = `{"products": [
+		{"type": "c5.large", "onDemandPrice": 0.1, "cpusPerVm": 2, "memPerVm": 4},
+		{"type": "c5.2xlarge", "onDemandPrice": 0.4, "cpusPerVm": 8, "memPerVm": 16}]}`
+
+	tests := []struct {
+		name    string
+		graphQL func(w http.ResponseWriter, r *http.Request)
+		check   func(vms []VirtualMachine, err error, restCalls int)
+	}{
+		{
+			name: "filtered by cloud info",
+			graphQL: func(w http.ResponseWriter, r *http.Request) {
+				var body struct {
+					Variables struct {
+						Filter map[string]map[string]float64 `json:"filter"`
+					} `json:"variables"`
+				}
+				assert.Nil(t, json.NewDecoder(r.Body).Decode(&body))
+				assert.Equal(t, map[string]map[string]float64{"cpu": {"gte": 4}}, body.Variables.Filter)
+				_, _ = w.Write([]byte(`{"data": {"instanceTypes": [{"name": "c5.2xlarge", "price": 0.4, "cpusPerVm": 8, "memPerVm": 16}]}}`))
+			},
+			check: func(vms []VirtualMachine, err error, restCalls int) {
+				assert.Nil(t, err)
+				assert.Equal(t, 0, restCalls)
+				assert.Equal(t, []VirtualMachine{{Type: "c5.2xlarge", OnDemandPrice: 0.4, Cpus: 8, Mem: 16}}, vms)
+			},
+		},
+		{
+			name: "filtered on the client side when cloud info doesn't serve the query",
+			graphQL: func(w http.ResponseWriter, r *http.Request) {
+				w.WriteHeader(http.StatusNotFound)
+			},
+			check: func(vms []VirtualMachine, err error, restCalls int) {
+				assert.Nil(t, err)
+				assert.Equal(t, 1, restCalls)
+				assert.Len(t, vms, 1)
+				assert.Equal(t, "c5.2xlarge", vms[0].Type)
+			},
+		},
+		{
+			name: "filtered on the client side when the query doesn't match the schema",
+			graphQL: func(w http.ResponseWriter, r *http.Request) {
+				_, _ = w.Write([]byte(`{"errors": [{"message": "Cannot query field \"burst\" on type \"InstanceType\"."}]}`))
+			},
+			check: func(vms []VirtualMachine, err error, restCalls int) {
+				assert.Nil(t, err)
+				assert.Equal(t, 1, restCalls)
+				assert.Len(t, vms, 1)
+			},
+		},
+	}
+	for _, test := range tests {
+		test := test
+		t.Run(test.name, func(t *testing.T) {
+			var restCalls int
+			mux := http.NewServeMux()
+			mux.HandleFunc("/graphql", test.graphQL)
+			mux.HandleFunc("/api/v1/providers/amazon/services/compute/regions/eu-west-1/products", func(w http.ResponseWriter, r *http.Request) {
+				restCalls++
+				_, _ = w.Write([]byte(products))
+			})
+			server := httptest.NewServer(mux)
+			defer server.Close()
+
+			ciCli := NewCloudInfoClient(server.URL+"/api/v1", logur.NewTestLogger())
+			vms, err := ciCli.GetProductDetailsFiltered("amazon", "compute", "eu-west-1", ProductFilter{MinCpu: 4})
+			test.check(vms, err, restCalls)
+		})
+	}
+}
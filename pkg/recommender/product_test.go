@@ -0,0 +1,109 @@
+// Copyright © 2019 Banzai Cloud
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package recommender
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/banzaicloud/telescopes/.gen/cloudinfo"
+	"github.com/goph/logur"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCloudInfoClient_ConcurrentLookups(t *testing.T) {
+	tests := []struct {
+		name     string
+		path     string
+		response string
+		lookup   func(ciCli CloudInfoSource) (interface{}, error)
+		mutate   func(result interface{})
+	}{
+		{
+			name:     "zones",
+			path:     "/api/v1/providers/amazon/services/compute/regions/eu-west-1",
+			response: `{"id": "eu-west-1", "name": "EU (Ireland)", "zones": ["eu-west-1a", "eu-west-1b"]}`,
+			lookup: func(ciCli CloudInfoSource) (interface{}, error) {
+				return ciCli.GetZones("amazon", "compute", "eu-west-1")
+			},
+			mutate: func(result interface{}) {
+				result.([]string)[0] = "changed"
+			},
+		},
+		{
+			name:     "regions",
+			path:     "/api/v1/providers/amazon/services/compute/regions",
+			response: `[{"id": "eu-west-1", "name": "EU (Ireland)"}]`,
+			lookup: func(ciCli CloudInfoSource) (interface{}, error) {
+				return ciCli.GetRegions("amazon", "compute")
+			},
+			mutate: func(result interface{}) {
+				result.([]cloudinfo.Region)[0].Name = "changed"
+			},
+		},
+	}
+	for _, test := range tests {
+		test := test
+		t.Run(test.name, func(t *testing.T) {
+			var calls int32
+			arrived := make(chan struct{}, 1)
+			release := make(chan struct{})
+
+			mux := http.NewServeMux()
+			mux.HandleFunc(test.path, func(w http.ResponseWriter, r *http.Request) {
+				atomic.AddInt32(&calls, 1)
+				arrived <- struct{}{}
+				<-release
+				w.Header().Set("Content-Type", "application/json")
+				_, _ = w.Write([]byte(test.response))
+			})
+			server := httptest.NewServer(mux)
+			defer server.Close()
+
+			ciCli := NewCloudInfoClient(server.URL+"/api/v1", logur.NewTestLogger())
+
+			const lookups = 5
+			var wg sync.WaitGroup
+			results := make([]interface{}, lookups)
+			errs := make([]error, lookups)
+			for i := 0; i < lookups; i++ {
+				wg.Add(1)
+				go func(i int) {
+					defer wg.Done()
+					results[i], errs[i] = test.lookup(ciCli)
+				}(i)
+			}
+
+			<-arrived
+			// give the remaining lookups time to join the in-flight call
+			time.Sleep(100 * time.Millisecond)
+			close(release)
+			wg.Wait()
+
+			assert.Equal(t, int32(1), atomic.LoadInt32(&calls))
+			for i := 0; i < lookups; i++ {
+				assert.NoError(t, errs[i])
+				assert.Equal(t, results[0], results[i])
+			}
+
+			test.mutate(results[0])
+			assert.NotEqual(t, results[0], results[1], "the callers should get their own copy of the result")
+		})
+	}
+}
@@ -0,0 +1,157 @@
+// Copyright © 2019 Banzai Cloud
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package recommender
+
+import (
+	"sort"
+	"sync"
+)
+
+// Profile is a named set of recommendation request defaults; requests referencing the profile override its fields
+type Profile struct {
+	// Name of the profile
+	Name string `json:"name"`
+	// Vm types to be excluded from the recommendation
+	Excludes []string `json:"excludes,omitempty"`
+	// Vm types to be contained in the recommendation
+	Includes []string `json:"includes,omitempty"`
+	// Network performance categories
+	NetworkPerf []string `json:"networkPerf,omitempty" binding:"omitempty,dive,networkPerf"`
+	// Virtual machine categories
+	Category []string `json:"category,omitempty" binding:"omitempty,dive,category"`
+	// Instance families the recommendation is restricted to
+	Family InstanceFamilies `json:"family,omitempty"`
+	// Percentage of regular (on-demand) nodes
+	OnDemandPct *int `json:"onDemandPct,omitempty" binding:"omitempty,min=0,max=100"`
+	// Are burst instances allowed
+	AllowBurst *bool `json:"allowBurst,omitempty"`
+	// Are older generations of virtual machines allowed
+	AllowOlderGen *bool `json:"allowOlderGen,omitempty"`
+	// Resiliency level (low, medium or high)
+	Resiliency string `json:"resiliency,omitempty" binding:"omitempty,resiliency"`
+	// Resources used per pod and per node beside the requested ones, eg. by the sidecars of a service mesh
+	Overhead *Overhead `json:"overhead,omitempty"`
+}
+
+// Defaults assembles a request holding the defaults of the profile, the request body is meant to be decoded on top of it
+func (p Profile) Defaults() SingleClusterRecommendationReq {
+	req := SingleClusterRecommendationReq{Profile: p.Name}
+	p.ApplyTo(&req)
+	return req
+}
+
+// ApplyTo sets the fields of the request the profile holds defaults for, the other fields are kept; it layers the
+// defaults, eg. the ones of a profile on top of the ones of the provider
+// The request gets copies of the slices and pointers of the profile, so decoding a request body on top of the
+// defaults doesn't change the stored profile
+func (p Profile) ApplyTo(req *SingleClusterRecommendationReq) {
+	if len(p.Excludes) > 0 {
+		req.Excludes = append([]string(nil), p.Excludes...)
+	}
+	if len(p.Includes) > 0 {
+		req.Includes = append([]string(nil), p.Includes...)
+	}
+	if len(p.NetworkPerf) > 0 {
+		req.NetworkPerf = append([]string(nil), p.NetworkPerf...)
+	}
+	if len(p.Category) > 0 {
+		req.Category = append([]string(nil), p.Category...)
+	}
+	if len(p.Family) > 0 {
+		req.Family = append(InstanceFamilies(nil), p.Family...)
+	}
+	if p.OnDemandPct != nil {
+		req.OnDemandPct = *p.OnDemandPct
+	}
+	if p.AllowBurst != nil {
+		allowBurst := *p.AllowBurst
+		req.AllowBurst = &allowBurst
+	}
+	if p.AllowOlderGen != nil {
+		allowOlderGen := *p.AllowOlderGen
+		req.AllowOlderGen = &allowOlderGen
+	}
+	if p.Resiliency != "" {
+		req.Resiliency = p.Resiliency
+	}
+	if p.Overhead != nil {
+		req.Overhead = *p.Overhead
+	}
+}
+
+// ProfileStore stores the recommendation profiles
+type ProfileStore interface {
+	// Get gets the profile with the given name, returns false if it doesn't exist
+	Get(name string) (Profile, bool)
+	// List lists the profiles ordered by name
+	List() []Profile
+	// Put creates or replaces the profile
+	Put(profile Profile)
+	// Delete deletes the profile with the given name, returns false if it doesn't exist
+	Delete(name string) bool
+}
+
+// inMemoryProfileStore is a ProfileStore keeping the profiles in memory
+type inMemoryProfileStore struct {
+	mux      sync.RWMutex
+	profiles map[string]Profile
+}
+
+// NewInMemoryProfileStore creates a profile store keeping the profiles in memory, the profiles are lost on restart
+func NewInMemoryProfileStore() ProfileStore {
+	return &inMemoryProfileStore{
+		profiles: make(map[string]Profile),
+	}
+}
+
+func (s *inMemoryProfileStore) Get(name string) (Profile, bool) {
+	s.mux.RLock()
+	defer s.mux.RUnlock()
+
+	p, ok := s.profiles[name]
+	return p, ok
+}
+
+func (s *inMemoryProfileStore) List() []Profile {
+	s.mux.RLock()
+	defer s.mux.RUnlock()
+
+	profiles := make([]Profile, 0, len(s.profiles))
+	for _, p := range s.profiles {
+		profiles = append(profiles, p)
+	}
+	sort.Slice(profiles, func(i, j int) bool {
+		return profiles[i].Name < profiles[j].Name
+	})
+	return profiles
+}
+
+func (s *inMemoryProfileStore) Put(profile Profile) {
+	s.mux.Lock()
+	defer s.mux.Unlock()
+
+	s.profiles[profile.Name] = profile
+}
+
+func (s *inMemoryProfileStore) Delete(name string) bool {
+	s.mux.Lock()
+	defer s.mux.Unlock()
+
+	if _, ok := s.profiles[name]; !ok {
+		return false
+	}
+	delete(s.profiles, name)
+	return true
+}
@@ -0,0 +1,134 @@
+// Copyright © 2019 Banzai Cloud
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package recommender
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestProfile_Defaults(t *testing.T) {
+	onDemandPct := 40
+	profile := Profile{
+		Name:        "prod-eu",
+		Excludes:    []string{"t2.micro"},
+		NetworkPerf: []string{"high"},
+		OnDemandPct: &onDemandPct,
+		Overhead:    &Overhead{PodCpu: 0.1},
+	}
+
+	tests := []struct {
+		name  string
+		body  string
+		check func(req SingleClusterRecommendationReq)
+	}{
+		{
+			name: "profile defaults are kept",
+			body: `{"sumCpu": 8, "sumMem": 32, "profile": "prod-eu"}`,
+			check: func(req SingleClusterRecommendationReq) {
+				assert.Equal(t, "prod-eu", req.Profile)
+				assert.Equal(t, []string{"t2.micro"}, req.Excludes)
+				assert.Equal(t, []string{"high"}, req.NetworkPerf)
+				assert.Equal(t, 40, req.OnDemandPct)
+				assert.Equal(t, Overhead{PodCpu: 0.1}, req.Overhead)
+				assert.Equal(t, float64(8), req.SumCpu)
+			},
+		},
+		{
+			name: "request fields override the profile defaults",
+			body: `{"sumCpu": 8, "sumMem": 32, "profile": "prod-eu", "onDemandPct": 0, "excludes": ["m5.large"]}`,
+			check: func(req SingleClusterRecommendationReq) {
+				assert.Equal(t, []string{"m5.large"}, req.Excludes)
+				assert.Equal(t, []string{"high"}, req.NetworkPerf)
+				assert.Equal(t, 0, req.OnDemandPct)
+			},
+		},
+	}
+	for _, test := range tests {
+		test := test
+		t.Run(test.name, func(t *testing.T) {
+			req := profile.Defaults()
+			assert.Nil(t, json.Unmarshal([]byte(test.body), &req))
+			test.check(req)
+		})
+	}
+}
+
+func TestProfile_ApplyTo(t *testing.T) {
+	allowOlderGen := false
+	providerDefaults := Profile{NetworkPerf: []string{"high"}, AllowOlderGen: &allowOlderGen, Resiliency: "medium"}
+	profile := Profile{Name: "prod-eu", NetworkPerf: []string{"extra"}, Overhead: &Overhead{PodCpu: 0.1, PodMem: 0.125}}
+
+	req := SingleClusterRecommendationReq{}
+	providerDefaults.ApplyTo(&req)
+	profile.ApplyTo(&req)
+	assert.Nil(t, json.Unmarshal([]byte(`{"sumCpu": 8, "sumMem": 32, "resiliency": "high"}`), &req))
+
+	assert.Equal(t, []string{"extra"}, req.NetworkPerf, "the profile should override the provider defaults")
+	assert.Equal(t, &allowOlderGen, req.AllowOlderGen, "the provider defaults should be kept")
+	assert.Equal(t, "high", req.Resiliency, "the request should override the defaults")
+	assert.Equal(t, Overhead{PodCpu: 0.1, PodMem: 0.125}, req.Overhead)
+	assert.Equal(t, "", req.Profile)
+}
+
+func TestProfile_ApplyTo_profileUnchanged(t *testing.T) {
+	allowBurst := true
+	store := NewInMemoryProfileStore()
+	store.Put(Profile{
+		Name:        "prod-eu",
+		Excludes:    []string{"t2.micro", "t2.small"},
+		NetworkPerf: []string{"high"},
+		Family:      InstanceFamilies{"m5", "c5"},
+		AllowBurst:  &allowBurst,
+	})
+
+	// the request bodies are decoded on top of the defaults of the profile, like the API binds them
+	for _, body := range []string{
+		`{"sumCpu": 8, "sumMem": 32, "excludes": ["m5.xlarge", "m5.2xlarge"], "family": ["r5", "x1"], "allowBurst": false}`,
+		`{"sumCpu": 8, "sumMem": 32, "networkPerf": ["low"]}`,
+	} {
+		profile, _ := store.Get("prod-eu")
+		req := profile.Defaults()
+		assert.Nil(t, json.Unmarshal([]byte(body), &req))
+	}
+
+	profile, _ := store.Get("prod-eu")
+	assert.Equal(t, []string{"t2.micro", "t2.small"}, profile.Excludes)
+	assert.Equal(t, []string{"high"}, profile.NetworkPerf)
+	assert.Equal(t, InstanceFamilies{"m5", "c5"}, profile.Family)
+	assert.True(t, *profile.AllowBurst)
+}
+
+func TestInMemoryProfileStore(t *testing.T) {
+	store := NewInMemoryProfileStore()
+
+	store.Put(Profile{Name: "prod-us"})
+	store.Put(Profile{Name: "prod-eu"})
+
+	profiles := store.List()
+	assert.Len(t, profiles, 2)
+	assert.Equal(t, "prod-eu", profiles[0].Name, "profiles should be ordered by name")
+
+	_, ok := store.Get("prod-eu")
+	assert.True(t, ok)
+
+	assert.True(t, store.Delete("prod-eu"))
+	assert.False(t, store.Delete("prod-eu"))
+
+	_, ok = store.Get("prod-eu")
+	assert.False(t, ok)
+}
@@ -0,0 +1,90 @@
+// Copyright © 2019 Banzai Cloud
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package recommender
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/goph/emperror"
+	"github.com/pkg/errors"
+)
+
+// prometheusClient runs queries against the HTTP API of a Prometheus server
+type prometheusClient struct {
+	address    string
+	httpClient *http.Client
+}
+
+func newPrometheusClient(address string) prometheusClient {
+	return prometheusClient{address: strings.TrimSuffix(address, "/"), httpClient: http.DefaultClient}
+}
+
+// prometheusSeries is a series of a query result, holding a single sample (value) of an instant query
+// or the samples (values) of a range query
+type prometheusSeries struct {
+	Metric map[string]string `json:"metric"`
+	Value  [2]interface{}    `json:"value"`
+	Values [][2]interface{}  `json:"values"`
+}
+
+// query runs the query at the API path (eg. /api/v1/query_range) with the given parameters
+func (c prometheusClient) query(path string, params url.Values) ([]prometheusSeries, error) {
+	resp, err := c.httpClient.Get(c.address + path + "?" + params.Encode())
+	if err != nil {
+		return nil, emperror.Wrap(err, "failed to query Prometheus")
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode >= http.StatusMultipleChoices {
+		body, _ := ioutil.ReadAll(resp.Body)
+		return nil, emperror.With(errors.New("failed to query Prometheus"), "status", resp.StatusCode, "body", string(body))
+	}
+
+	var result struct {
+		Status string `json:"status"`
+		Error  string `json:"error"`
+		Data   struct {
+			Result []prometheusSeries `json:"result"`
+		} `json:"data"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return nil, emperror.Wrap(err, "failed to decode the Prometheus response")
+	}
+	if result.Status != "success" {
+		return nil, emperror.With(errors.New("failed to query Prometheus"), "error", result.Error)
+	}
+
+	return result.Data.Result, nil
+}
+
+// parseSample parses a Prometheus sample, a [<unix time>, "<value>"] pair
+func parseSample(sample [2]interface{}) (time.Time, float64, error) {
+	ts, ok := sample[0].(float64)
+	if !ok {
+		return time.Time{}, 0, emperror.With(errors.New("invalid sample time"), "time", sample[0])
+	}
+	value, err := strconv.ParseFloat(fmt.Sprint(sample[1]), 64)
+	if err != nil {
+		return time.Time{}, 0, emperror.WrapWith(err, "invalid sample value", "value", sample[1])
+	}
+	return time.Unix(0, int64(ts*float64(time.Second))), value, nil
+}
@@ -0,0 +1,141 @@
+// Copyright © 2019 Banzai Cloud
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package recommender
+
+import (
+	"context"
+	"sync"
+
+	"github.com/goph/emperror"
+	"github.com/pkg/errors"
+)
+
+// Priority classes of the recommendations
+const (
+	// PriorityInteractive is the class of the latency sensitive recommendations, served first
+	PriorityInteractive = "interactive"
+	// PriorityBatch is the class of the large or bulk recommendations, served when no interactive ones are waiting
+	PriorityBatch = "batch"
+)
+
+// WorkQueue bounds the number of the recommendations served at the same time and the number of the ones waiting;
+// waiting interactive recommendations are served before the batch ones, and the batch recommendations can't occupy
+// all the workers, so they can't starve the interactive ones
+type WorkQueue struct {
+	workers      int
+	batchWorkers int
+	capacity     int
+
+	mux     sync.Mutex
+	running map[string]int
+	waiting map[string][]chan struct{}
+}
+
+// NewWorkQueue creates a work queue serving the given number of recommendations at the same time, at most batchWorkers
+// of them of the batch class (one less than the workers if zero or not less than the workers), with at most capacity
+// recommendations waiting per class; at least two workers are needed, as one is always kept for the interactive class
+func NewWorkQueue(workers, batchWorkers, capacity int) (*WorkQueue, error) {
+	if workers < 2 {
+		return nil, emperror.With(errors.New("the work queue needs at least two workers, one is kept for the interactive recommendations"),
+			"workers", workers)
+	}
+	if batchWorkers <= 0 || batchWorkers >= workers {
+		batchWorkers = workers - 1
+	}
+	return &WorkQueue{
+		workers:      workers,
+		batchWorkers: batchWorkers,
+		capacity:     capacity,
+		running:      make(map[string]int),
+		waiting:      make(map[string][]chan struct{}),
+	}, nil
+}
+
+// ParsePriority gets the priority class by its name, interactive if empty
+func ParsePriority(name string) (string, error) {
+	switch name {
+	case "", PriorityInteractive:
+		return PriorityInteractive, nil
+	case PriorityBatch:
+		return PriorityBatch, nil
+	default:
+		return "", ErrUnknownPriority
+	}
+}
+
+// Acquire waits for a worker for a recommendation of the priority class, until the context is done; the worker must
+// be released when the recommendation is served. It fails right away if the queue of the class is full
+func (q *WorkQueue) Acquire(ctx context.Context, priority string) error {
+	q.mux.Lock()
+	if len(q.waiting[priority]) == 0 && q.available(priority) {
+		q.running[priority]++
+		q.mux.Unlock()
+		return nil
+	}
+	if len(q.waiting[priority]) >= q.capacity {
+		q.mux.Unlock()
+		return ErrQueueFull
+	}
+	granted := make(chan struct{})
+	q.waiting[priority] = append(q.waiting[priority], granted)
+	q.mux.Unlock()
+
+	select {
+	case <-granted:
+		return nil
+	case <-ctx.Done():
+		q.mux.Lock()
+		defer q.mux.Unlock()
+		for i, ch := range q.waiting[priority] {
+			if ch == granted {
+				q.waiting[priority] = append(q.waiting[priority][:i], q.waiting[priority][i+1:]...)
+				return ctx.Err()
+			}
+		}
+		// the worker was granted meanwhile, it's handed over to the next one waiting
+		q.running[priority]--
+		q.dispatch()
+		return ctx.Err()
+	}
+}
+
+// Release releases the worker of a recommendation of the priority class
+func (q *WorkQueue) Release(priority string) {
+	q.mux.Lock()
+	defer q.mux.Unlock()
+
+	q.running[priority]--
+	q.dispatch()
+}
+
+// available checks whether a worker is available for the priority class
+func (q *WorkQueue) available(priority string) bool {
+	running := q.running[PriorityInteractive] + q.running[PriorityBatch]
+	if running >= q.workers {
+		return false
+	}
+	return priority != PriorityBatch || q.running[PriorityBatch] < q.batchWorkers
+}
+
+// dispatch grants the available workers to the waiting recommendations, the interactive ones first
+func (q *WorkQueue) dispatch() {
+	for _, priority := range []string{PriorityInteractive, PriorityBatch} {
+		for len(q.waiting[priority]) > 0 && q.available(priority) {
+			q.running[priority]++
+			close(q.waiting[priority][0])
+			q.waiting[priority] = q.waiting[priority][1:]
+		}
+	}
+}
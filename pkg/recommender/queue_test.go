@@ -0,0 +1,117 @@
+// Copyright © 2019 Banzai Cloud
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package recommender
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// acquireAsync acquires a worker in the background, the channel receives the result
+func acquireAsync(ctx context.Context, q *WorkQueue, priority string) <-chan error {
+	result := make(chan error, 1)
+	go func() {
+		result <- q.Acquire(ctx, priority)
+	}()
+	return result
+}
+
+// waitForQueue waits until the given number of recommendations wait in the queue of the class
+func waitForQueue(q *WorkQueue, priority string, n int) {
+	for i := 0; i < 100; i++ {
+		q.mux.Lock()
+		waiting := len(q.waiting[priority])
+		q.mux.Unlock()
+		if waiting == n {
+			return
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
+
+func TestWorkQueue(t *testing.T) {
+	q, err := NewWorkQueue(2, 0, 1)
+	if !assert.NoError(t, err) {
+		return
+	}
+	ctx := context.Background()
+
+	assert.NoError(t, q.Acquire(ctx, PriorityBatch))
+
+	batch := acquireAsync(ctx, q, PriorityBatch)
+	waitForQueue(q, PriorityBatch, 1)
+	assert.Equal(t, ErrQueueFull, q.Acquire(ctx, PriorityBatch), "the full queue should reject the recommendations")
+
+	assert.NoError(t, q.Acquire(ctx, PriorityInteractive), "a worker should be kept for the interactive recommendations")
+	interactive := acquireAsync(ctx, q, PriorityInteractive)
+	waitForQueue(q, PriorityInteractive, 1)
+
+	q.Release(PriorityBatch)
+	assert.NoError(t, <-interactive, "the interactive recommendations should be served first")
+	select {
+	case <-batch:
+		t.Error("the batch recommendation should still wait")
+	default:
+	}
+
+	q.Release(PriorityInteractive)
+	assert.NoError(t, <-batch)
+}
+
+func TestNewWorkQueue(t *testing.T) {
+	_, err := NewWorkQueue(1, 0, 10)
+	assert.Error(t, err, "a single worker could be held by a batch recommendation")
+
+	q, err := NewWorkQueue(3, 3, 10)
+	if !assert.NoError(t, err) {
+		return
+	}
+	assert.Equal(t, 2, q.batchWorkers, "one worker should be kept for the interactive recommendations")
+}
+
+func TestWorkQueue_cancel(t *testing.T) {
+	q, err := NewWorkQueue(2, 0, 10)
+	if !assert.NoError(t, err) {
+		return
+	}
+	assert.NoError(t, q.Acquire(context.Background(), PriorityInteractive))
+	assert.NoError(t, q.Acquire(context.Background(), PriorityInteractive))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancelled := acquireAsync(ctx, q, PriorityInteractive)
+	waitForQueue(q, PriorityInteractive, 1)
+	cancel()
+	assert.Equal(t, context.Canceled, <-cancelled)
+
+	q.Release(PriorityInteractive)
+	assert.NoError(t, q.Acquire(context.Background(), PriorityInteractive), "the cancelled recommendation should not hold a worker")
+	assert.Equal(t, 2, q.running[PriorityInteractive])
+}
+
+func TestParsePriority(t *testing.T) {
+	p, err := ParsePriority("")
+	assert.NoError(t, err)
+	assert.Equal(t, PriorityInteractive, p)
+
+	p, err = ParsePriority("batch")
+	assert.NoError(t, err)
+	assert.Equal(t, PriorityBatch, p)
+
+	_, err = ParsePriority("urgent")
+	assert.Equal(t, ErrUnknownPriority, err)
+}
@@ -0,0 +1,338 @@
+// Copyright © 2019 Banzai Cloud
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package recommender
+
+import (
+	"fmt"
+	"sort"
+
+	"github.com/goph/emperror"
+)
+
+// RebalanceReq encapsulates the layout of an existing cluster to even out between the zones
+// swagger:model rebalanceRequest
+type RebalanceReq struct {
+	// The layout with the number of nodes of the node pools per zone
+	Layout []ZonalNodePoolDesc `json:"layout" binding:"required,dive"`
+	// Zones to balance the nodes between, the zones of the layout by default
+	Zones []string `json:"zones,omitempty"`
+	// Maximum increase of the hourly cost of the layout in percent, the moves raising the cost above it are left out
+	MaxCostIncreasePct float64 `json:"maxCostIncreasePct,omitempty" binding:"min=0"`
+}
+
+// ZonalNodePoolDesc describes a node pool of an existing layout with its nodes per zone
+type ZonalNodePoolDesc struct {
+	// Instance type of VMs in the node pool
+	InstanceType string `json:"instanceType" binding:"required"`
+	// Signals that the node pool consists of regular, reserved or spot/preemptible instance types
+	VmClass string `json:"vmClass" binding:"required"`
+	// Number of VMs in the node pool per zone
+	ZoneNodes map[string]int `json:"zoneNodes" binding:"required"`
+}
+
+// GetVmClass gets the class of the VMs of the node pool the same way as for the node pools of a scale out
+func (d *ZonalNodePoolDesc) GetVmClass() string {
+	return (&NodePoolDesc{VmClass: d.VmClass}).GetVmClass()
+}
+
+// Rebalance is the plan evening out the nodes of a layout between the zones: the nodes moved between the zones and
+// the resulting node counts of the node pools per zone
+type Rebalance struct {
+	// Node pools of the layout with their current and target nodes per zone
+	NodePools []PoolRebalance `json:"nodePools"`
+	// Nodes to move between the zones
+	Moves []ZoneMove `json:"moves"`
+	// Current number of nodes per zone
+	ZoneNodes map[string]int `json:"zoneNodes"`
+	// Number of nodes per zone after the moves
+	TargetZoneNodes map[string]int `json:"targetZoneNodes"`
+	// Current hourly cost of the layout
+	Cost float64 `json:"cost"`
+	// Hourly cost of the layout after the moves
+	TargetCost float64 `json:"targetCost"`
+	// Increase of the hourly cost in percent, negative if the moves lower the cost
+	CostIncreasePct float64 `json:"costIncreasePct"`
+	// Warnings about the plan that API consumers should be aware of
+	Warnings []Warning `json:"warnings,omitempty"`
+}
+
+// PoolRebalance is a node pool of the rebalanced layout, the pools of the zones are resized to the target nodes
+type PoolRebalance struct {
+	// Instance type of the pool
+	VmType string `json:"vmType"`
+	// Signals that the pool consists of regular, reserved or spot/preemptible instances
+	VmClass string `json:"vmClass"`
+	// Current number of nodes per zone
+	ZoneNodes map[string]int `json:"zoneNodes"`
+	// Number of nodes per zone after the moves
+	TargetZoneNodes map[string]int `json:"targetZoneNodes"`
+}
+
+// ZoneMove moves nodes of a node pool from one zone to another
+type ZoneMove struct {
+	// Instance type of the moved nodes
+	VmType string `json:"vmType"`
+	// Signals that the moved nodes are regular, reserved or spot/preemptible instances
+	VmClass string `json:"vmClass"`
+	// Zone the nodes are removed from
+	From string `json:"from"`
+	// Zone the nodes are added to
+	To string `json:"to"`
+	// Number of nodes moved
+	Nodes int `json:"nodes"`
+	// Change of the hourly cost of the layout by the move
+	CostDelta float64 `json:"costDelta"`
+}
+
+// unitMove moves a node of a node pool of the layout
+type unitMove struct {
+	pool     int
+	from, to string
+	delta    float64
+}
+
+// Rebalance recommends moving the nodes of the node pools between the zones so that each pool is spread evenly
+// between the zones it can be launched in; the moves are taken from the cheapest to the most expensive one while the
+// cost of the layout stays within the allowed increase
+func (e *Engine) Rebalance(provider string, service string, region string, req RebalanceReq) (*Rebalance, error) {
+	if err := e.checkTenantProvider(provider); err != nil {
+		return nil, err
+	}
+	zones, err := req.zones()
+	if err != nil {
+		return nil, err
+	}
+
+	products, err := e.ciSource.GetProductDetails(provider, service, region)
+	if err != nil {
+		return nil, err
+	}
+	vms := make(map[string]VirtualMachine, len(products))
+	for _, vm := range products {
+		vms[vm.Type] = vm
+	}
+
+	rebalance := &Rebalance{
+		NodePools:       make([]PoolRebalance, 0, len(req.Layout)),
+		Moves:           make([]ZoneMove, 0),
+		ZoneNodes:       make(map[string]int, len(zones)),
+		TargetZoneNodes: make(map[string]int, len(zones)),
+	}
+	var moves []unitMove
+	for i, npd := range req.Layout {
+		vm, ok := vms[npd.InstanceType]
+		if !ok {
+			return nil, emperror.With(ErrUnknownInstanceType, RecommenderErrorTag, "instanceType", npd.InstanceType)
+		}
+
+		pool := PoolRebalance{
+			VmType:          npd.InstanceType,
+			VmClass:         npd.GetVmClass(),
+			ZoneNodes:       make(map[string]int, len(npd.ZoneNodes)),
+			TargetZoneNodes: make(map[string]int, len(npd.ZoneNodes)),
+		}
+		for zone, nodes := range npd.ZoneNodes {
+			if nodes > 0 {
+				pool.ZoneNodes[zone] = nodes
+				pool.TargetZoneNodes[zone] = nodes
+			}
+		}
+		price := zonePrice(vm, pool.VmClass)
+		for zone, nodes := range pool.ZoneNodes {
+			rebalance.Cost += price(zone) * float64(nodes)
+		}
+
+		var eligible []string
+		for _, zone := range zones {
+			if vm.launchableIn(zone) {
+				eligible = append(eligible, zone)
+			}
+		}
+		moves = append(moves, poolMoves(i, pool.ZoneNodes, evenZoneNodes(pool.ZoneNodes, eligible, price), price)...)
+
+		rebalance.NodePools = append(rebalance.NodePools, pool)
+	}
+
+	// the cheapest moves first, the ones lowering the cost make room for the others
+	sort.SliceStable(moves, func(a, b int) bool { return moves[a].delta < moves[b].delta })
+	budget := rebalance.Cost * (1 + req.MaxCostIncreasePct/100)
+	rebalance.TargetCost = rebalance.Cost
+	skipped := 0
+	for _, m := range moves {
+		if rebalance.TargetCost+m.delta > budget+1e-9 {
+			skipped++
+			continue
+		}
+		target := rebalance.NodePools[m.pool].TargetZoneNodes
+		target[m.from]--
+		if target[m.from] == 0 {
+			delete(target, m.from)
+		}
+		target[m.to]++
+		rebalance.TargetCost += m.delta
+		rebalance.addMove(m)
+	}
+	if skipped > 0 {
+		rebalance.Warnings = append(rebalance.Warnings, Warning{
+			Code:    WarnRebalanceCostLimited,
+			Message: fmt.Sprintf("%d node moves were left out to keep the cost increase within %g%%", skipped, req.MaxCostIncreasePct),
+		})
+	}
+
+	for _, zone := range zones {
+		rebalance.ZoneNodes[zone] = 0
+		rebalance.TargetZoneNodes[zone] = 0
+	}
+	for _, pool := range rebalance.NodePools {
+		for zone, nodes := range pool.ZoneNodes {
+			rebalance.ZoneNodes[zone] += nodes
+		}
+		for zone, nodes := range pool.TargetZoneNodes {
+			rebalance.TargetZoneNodes[zone] += nodes
+		}
+	}
+	if rebalance.Cost > 0 {
+		rebalance.CostIncreasePct = (rebalance.TargetCost - rebalance.Cost) / rebalance.Cost * 100
+	}
+
+	return rebalance, nil
+}
+
+// zones gets the sorted zones to balance the nodes between, it checks the node counts of the layout
+func (r RebalanceReq) zones() ([]string, error) {
+	zoneSet := make(map[string]bool)
+	for _, zone := range r.Zones {
+		zoneSet[zone] = true
+	}
+	for _, npd := range r.Layout {
+		for zone, nodes := range npd.ZoneNodes {
+			if nodes < 0 {
+				return nil, emperror.With(ErrInvalidZoneNodes, RecommenderErrorTag, "instanceType", npd.InstanceType,
+					"zone", zone, "nodes", nodes)
+			}
+			if len(r.Zones) == 0 {
+				zoneSet[zone] = true
+			}
+		}
+	}
+	if len(zoneSet) == 0 {
+		return nil, emperror.With(ErrInvalidZoneNodes, RecommenderErrorTag)
+	}
+
+	zones := make([]string, 0, len(zoneSet))
+	for zone := range zoneSet {
+		zones = append(zones, zone)
+	}
+	sort.Strings(zones)
+	return zones, nil
+}
+
+// addMove adds a node to the move of the same node pool between the same zones, or a new move
+func (r *Rebalance) addMove(m unitMove) {
+	pool := r.NodePools[m.pool]
+	for i, move := range r.Moves {
+		if move.VmType == pool.VmType && move.VmClass == pool.VmClass && move.From == m.from && move.To == m.to {
+			r.Moves[i].Nodes++
+			r.Moves[i].CostDelta += m.delta
+			return
+		}
+	}
+	r.Moves = append(r.Moves, ZoneMove{VmType: pool.VmType, VmClass: pool.VmClass, From: m.from, To: m.to, Nodes: 1, CostDelta: m.delta})
+}
+
+// zonePrice gets the hourly price of a node of the vm in a zone: the zone's spot price of spot nodes if it's known,
+// the on-demand price of regular nodes; reserved nodes are already paid for
+func zonePrice(vm VirtualMachine, vmClass string) func(zone string) float64 {
+	return func(zone string) float64 {
+		switch vmClass {
+		case Regular:
+			return vm.OnDemandPrice
+		case Spot:
+			if price, ok := vm.SpotPrices[zone]; ok {
+				return price
+			}
+			return vm.AvgPrice
+		default:
+			return 0
+		}
+	}
+}
+
+// evenZoneNodes splits the nodes of a node pool evenly between the zones; the remainders go to the zones with the
+// most nodes already, then to the cheapest ones
+// The nodes stay where they are if the pool can't be launched in any of the zones
+func evenZoneNodes(zoneNodes map[string]int, zones []string, price func(zone string) float64) map[string]int {
+	if len(zones) == 0 {
+		return zoneNodes
+	}
+	sum := 0
+	for _, nodes := range zoneNodes {
+		sum += nodes
+	}
+
+	ordered := append([]string(nil), zones...)
+	sort.SliceStable(ordered, func(a, b int) bool {
+		if zoneNodes[ordered[a]] != zoneNodes[ordered[b]] {
+			return zoneNodes[ordered[a]] > zoneNodes[ordered[b]]
+		}
+		return price(ordered[a]) < price(ordered[b])
+	})
+	target := make(map[string]int, len(zones))
+	for i, zone := range ordered {
+		target[zone] = sum / len(zones)
+		if i < sum%len(zones) {
+			target[zone]++
+		}
+	}
+	return target
+}
+
+// poolMoves pairs the surplus nodes of the zones of a node pool with the missing ones: the nodes in the most expensive
+// zones are moved to the cheapest ones
+func poolMoves(pool int, zoneNodes, target map[string]int, price func(zone string) float64) []unitMove {
+	var surplus, deficit []string
+	for _, zone := range sortedZones(zoneNodes, target) {
+		for n := zoneNodes[zone]; n > target[zone]; n-- {
+			surplus = append(surplus, zone)
+		}
+		for n := zoneNodes[zone]; n < target[zone]; n++ {
+			deficit = append(deficit, zone)
+		}
+	}
+	sort.SliceStable(surplus, func(a, b int) bool { return price(surplus[a]) > price(surplus[b]) })
+	sort.SliceStable(deficit, func(a, b int) bool { return price(deficit[a]) < price(deficit[b]) })
+
+	moves := make([]unitMove, 0, len(surplus))
+	for i := 0; i < len(surplus) && i < len(deficit); i++ {
+		moves = append(moves, unitMove{pool: pool, from: surplus[i], to: deficit[i], delta: price(deficit[i]) - price(surplus[i])})
+	}
+	return moves
+}
+
+// sortedZones gets the sorted zones of the node counts
+func sortedZones(nodes ...map[string]int) []string {
+	zoneSet := make(map[string]bool)
+	for _, n := range nodes {
+		for zone := range n {
+			zoneSet[zone] = true
+		}
+	}
+	zones := make([]string, 0, len(zoneSet))
+	for zone := range zoneSet {
+		zones = append(zones, zone)
+	}
+	sort.Strings(zones)
+	return zones
+}
@@ -0,0 +1,113 @@
+// Copyright © 2019 Banzai Cloud
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package recommender
+
+import (
+	"testing"
+
+	"github.com/goph/logur"
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestEngine_Rebalance(t *testing.T) {
+	vms := []VirtualMachine{
+		{Type: "m5.xlarge", Cpus: 4, Mem: 16, OnDemandPrice: 0.2, AvgPrice: 0.07, Zones: []string{"a", "b", "c"},
+			SpotPrices: map[string]float64{"a": 0.06, "b": 0.07, "c": 0.09}},
+		{Type: "c5.xlarge", Cpus: 4, Mem: 8, OnDemandPrice: 0.17, AvgPrice: 0.06, Zones: []string{"a", "b"}},
+	}
+	tests := []struct {
+		name  string
+		req   RebalanceReq
+		check func(rebalance *Rebalance, err error)
+	}{
+		{
+			name: "regular pool evened out",
+			req: RebalanceReq{Layout: []ZonalNodePoolDesc{
+				{InstanceType: "m5.xlarge", VmClass: Regular, ZoneNodes: map[string]int{"a": 4, "b": 1, "c": 1}},
+			}},
+			check: func(rebalance *Rebalance, err error) {
+				assert.Nil(t, err, "the error should be nil")
+				assert.Equal(t, map[string]int{"a": 2, "b": 2, "c": 2}, rebalance.NodePools[0].TargetZoneNodes)
+				assert.Equal(t, map[string]int{"a": 4, "b": 1, "c": 1}, rebalance.ZoneNodes)
+				assert.Equal(t, map[string]int{"a": 2, "b": 2, "c": 2}, rebalance.TargetZoneNodes)
+				assert.Equal(t, []ZoneMove{
+					{VmType: "m5.xlarge", VmClass: Regular, From: "a", To: "b", Nodes: 1},
+					{VmType: "m5.xlarge", VmClass: Regular, From: "a", To: "c", Nodes: 1},
+				}, rebalance.Moves)
+				assert.InDelta(t, 1.2, rebalance.TargetCost, 1e-9)
+				assert.Equal(t, float64(0), rebalance.CostIncreasePct)
+				assert.Empty(t, rebalance.Warnings)
+			},
+		},
+		{
+			name: "spot moves within the cost increase",
+			req: RebalanceReq{
+				Layout: []ZonalNodePoolDesc{
+					{InstanceType: "m5.xlarge", VmClass: Spot, ZoneNodes: map[string]int{"a": 3}},
+				},
+				Zones:              []string{"a", "b", "c"},
+				MaxCostIncreasePct: 10,
+			},
+			check: func(rebalance *Rebalance, err error) {
+				assert.Nil(t, err, "the error should be nil")
+				assert.Equal(t, map[string]int{"a": 2, "b": 1}, rebalance.NodePools[0].TargetZoneNodes)
+				assert.Equal(t, map[string]int{"a": 2, "b": 1, "c": 0}, rebalance.TargetZoneNodes)
+				assert.InDelta(t, 0.18, rebalance.Cost, 1e-9)
+				assert.InDelta(t, 0.19, rebalance.TargetCost, 1e-9)
+				assert.InDelta(t, 0.01, rebalance.Moves[0].CostDelta, 1e-9)
+				assert.Equal(t, WarnRebalanceCostLimited, rebalance.Warnings[0].Code)
+			},
+		},
+		{
+			name: "instance type not offered in a zone",
+			req: RebalanceReq{Layout: []ZonalNodePoolDesc{
+				{InstanceType: "m5.xlarge", VmClass: Regular, ZoneNodes: map[string]int{"a": 1, "b": 1, "c": 1}},
+				{InstanceType: "c5.xlarge", VmClass: Regular, ZoneNodes: map[string]int{"a": 3}},
+			}},
+			check: func(rebalance *Rebalance, err error) {
+				assert.Nil(t, err, "the error should be nil")
+				assert.Equal(t, map[string]int{"a": 2, "b": 1}, rebalance.NodePools[1].TargetZoneNodes)
+				assert.Equal(t, map[string]int{"a": 3, "b": 2, "c": 1}, rebalance.TargetZoneNodes)
+			},
+		},
+		{
+			name: "unknown instance type",
+			req: RebalanceReq{Layout: []ZonalNodePoolDesc{
+				{InstanceType: "x1.32xlarge", VmClass: Regular, ZoneNodes: map[string]int{"a": 1}},
+			}},
+			check: func(rebalance *Rebalance, err error) {
+				assert.Equal(t, ErrUnknownInstanceType, errors.Cause(err))
+			},
+		},
+		{
+			name: "negative node count",
+			req: RebalanceReq{Layout: []ZonalNodePoolDesc{
+				{InstanceType: "m5.xlarge", VmClass: Regular, ZoneNodes: map[string]int{"a": -1}},
+			}},
+			check: func(rebalance *Rebalance, err error) {
+				assert.Equal(t, ErrInvalidZoneNodes, errors.Cause(err))
+			},
+		},
+	}
+	for _, test := range tests {
+		test := test
+		t.Run(test.name, func(t *testing.T) {
+			engine := NewEngine(logur.NewTestLogger(), &candidateProducts{vms: vms}, nil, nil)
+
+			test.check(engine.Rebalance("amazon", "compute", "eu-west-1", test.req))
+		})
+	}
+}
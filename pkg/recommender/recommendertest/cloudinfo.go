@@ -0,0 +1,204 @@
+// Copyright © 2019 Banzai Cloud
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package recommendertest
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync/atomic"
+
+	"github.com/banzaicloud/telescopes/.gen/cloudinfo"
+	"github.com/banzaicloud/telescopes/pkg/recommender"
+)
+
+// apiBasePath is the base path of the cloud info REST API
+const apiBasePath = "/api/v1"
+
+// CloudInfoServer mocks the cloud info REST API, serving the fixtures of a directory laid out as for
+// recommender.NewFixtureCloudInfoSource; the products are served as they are in the fixture files
+// The GraphQL API is not served, clients filter the products on their side
+type CloudInfoServer struct {
+	*httptest.Server
+
+	fixtureDir string
+	source     recommender.CloudInfoSource
+	requests   int32
+}
+
+// NewCloudInfoServer starts a cloud info mock serving the fixtures of the directory, the caller must close it
+func NewCloudInfoServer(fixtureDir string) (*CloudInfoServer, error) {
+	source, err := recommender.NewFixtureCloudInfoSource(fixtureDir)
+	if err != nil {
+		return nil, err
+	}
+
+	s := &CloudInfoServer{fixtureDir: fixtureDir, source: source}
+	s.Server = httptest.NewServer(http.HandlerFunc(s.serve))
+	return s, nil
+}
+
+// BasePath returns the address of the cloud info REST API, as configured for the recommender
+func (s *CloudInfoServer) BasePath() string {
+	return s.URL + apiBasePath
+}
+
+// RequestCount returns the number of requests served so far
+func (s *CloudInfoServer) RequestCount() int {
+	return int(atomic.LoadInt32(&s.requests))
+}
+
+func (s *CloudInfoServer) serve(w http.ResponseWriter, r *http.Request) {
+	atomic.AddInt32(&s.requests, 1)
+
+	if r.Method != http.MethodGet || !strings.HasPrefix(r.URL.Path, apiBasePath+"/") {
+		notFound(w, r.URL.Path)
+		return
+	}
+
+	// continents, providers, <provider>, services, <service>, continents|regions, <region>, images|products|versions
+	parts := strings.Split(strings.Trim(strings.TrimPrefix(r.URL.Path, apiBasePath), "/"), "/")
+	switch {
+	case len(parts) == 1 && parts[0] == "continents":
+		continents, err := s.source.GetContinents()
+		respond(w, continents, err)
+	case len(parts) == 1 && parts[0] == "providers":
+		respondJSON(w, cloudinfo.ProvidersResponse{Providers: s.providers()})
+	case len(parts) < 2 || parts[0] != "providers" || !s.hasProvider(parts[1]):
+		notFound(w, r.URL.Path)
+	case len(parts) == 2:
+		respondJSON(w, cloudinfo.ProviderResponse{Provider: s.provider(parts[1])})
+	case len(parts) == 3 && parts[2] == "services":
+		respondJSON(w, cloudinfo.ServicesResponse{Services: s.provider(parts[1]).Services})
+	case parts[2] != "services" || !s.hasService(parts[1], parts[3]):
+		notFound(w, r.URL.Path)
+	default:
+		s.serveService(w, r, parts[1], parts[3], parts[4:])
+	}
+}
+
+// serveService serves the paths under /providers/<provider>/services/<service>
+func (s *CloudInfoServer) serveService(w http.ResponseWriter, r *http.Request, provider, service string, parts []string) {
+	switch {
+	case len(parts) == 0:
+		respondJSON(w, cloudinfo.ServiceResponse{Service: cloudinfo.Service{Service: service}})
+	case len(parts) == 1 && parts[0] == "continents":
+		continents, err := s.source.GetContinentsData(provider, service)
+		respond(w, continents, err)
+	case len(parts) == 1 && parts[0] == "regions":
+		regions, err := s.source.GetRegions(provider, service)
+		respond(w, regions, err)
+	case parts[0] != "regions" || !s.hasRegion(provider, service, parts[1]):
+		notFound(w, r.URL.Path)
+	case len(parts) == 2:
+		s.serveRegion(w, provider, service, parts[1])
+	case len(parts) == 3 && parts[2] == "products":
+		data, err := ioutil.ReadFile(filepath.Join(s.fixtureDir, provider, service, parts[1]+".json"))
+		if err != nil {
+			respond(w, nil, err)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write(data)
+	case len(parts) == 3 && parts[2] == "images":
+		respondJSON(w, cloudinfo.ImagesResponse{Images: []cloudinfo.Image{}})
+	case len(parts) == 3 && parts[2] == "versions":
+		respondJSON(w, cloudinfo.VersionsResponse{Versions: []cloudinfo.LocationVersion{}})
+	default:
+		notFound(w, r.URL.Path)
+	}
+}
+
+func (s *CloudInfoServer) serveRegion(w http.ResponseWriter, provider, service, region string) {
+	name, err := s.source.GetRegion(provider, service, region)
+	if err != nil {
+		respond(w, nil, err)
+		return
+	}
+	zones, err := s.source.GetZones(provider, service, region)
+	respond(w, cloudinfo.GetRegionResp{Id: region, Name: name, Zones: zones}, err)
+}
+
+// respond serves the result of a fixture lookup
+func respond(w http.ResponseWriter, v interface{}, err error) {
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	respondJSON(w, v)
+}
+
+func (s *CloudInfoServer) providers() []cloudinfo.Provider {
+	var providers []cloudinfo.Provider
+	for _, dir := range s.dirs(s.fixtureDir) {
+		providers = append(providers, s.provider(dir))
+	}
+	return providers
+}
+
+func (s *CloudInfoServer) provider(provider string) cloudinfo.Provider {
+	p := cloudinfo.Provider{Provider: provider}
+	for _, service := range s.dirs(filepath.Join(s.fixtureDir, provider)) {
+		p.Services = append(p.Services, cloudinfo.Service{Service: service})
+	}
+	return p
+}
+
+func (s *CloudInfoServer) hasProvider(provider string) bool {
+	p, err := s.source.GetProvider(provider)
+	return err == nil && p != ""
+}
+
+func (s *CloudInfoServer) hasService(provider, service string) bool {
+	svc, err := s.source.GetService(provider, service)
+	return err == nil && svc != ""
+}
+
+func (s *CloudInfoServer) hasRegion(provider, service, region string) bool {
+	name, err := s.source.GetRegion(provider, service, region)
+	return err == nil && name != ""
+}
+
+// dirs lists the sub-directories of the fixture directory in lexical order
+func (s *CloudInfoServer) dirs(dir string) []string {
+	infos, err := ioutil.ReadDir(dir)
+	if err != nil {
+		return nil
+	}
+
+	var dirs []string
+	for _, info := range infos {
+		if info.IsDir() {
+			dirs = append(dirs, info.Name())
+		}
+	}
+	sort.Strings(dirs)
+	return dirs
+}
+
+func respondJSON(w http.ResponseWriter, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(v)
+}
+
+func notFound(w http.ResponseWriter, path string) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusNotFound)
+	_ = json.NewEncoder(w).Encode(map[string]string{"message": "not found", "path": path})
+}
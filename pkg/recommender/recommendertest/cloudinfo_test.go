@@ -0,0 +1,155 @@
+// Copyright © 2019 Banzai Cloud
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package recommendertest
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/banzaicloud/telescopes/pkg/recommender"
+	"github.com/goph/logur"
+	"github.com/stretchr/testify/assert"
+	"gopkg.in/yaml.v2"
+)
+
+// cloudInfoSpec is the OpenAPI spec the cloud info client is generated from
+const cloudInfoSpec = "../../../.gen/cloudinfo/api/openapi.yaml"
+
+func TestCloudInfoServer_OpenAPIContract(t *testing.T) {
+	data, err := ioutil.ReadFile(cloudInfoSpec)
+	if !assert.Nil(t, err, "failed to read the cloud info spec") {
+		return
+	}
+
+	var spec struct {
+		Paths map[string]map[string]interface{} `yaml:"paths"`
+	}
+	if !assert.Nil(t, yaml.Unmarshal(data, &spec), "failed to parse the cloud info spec") {
+		return
+	}
+	if !assert.NotEmpty(t, spec.Paths) {
+		return
+	}
+
+	server, err := NewCloudInfoServer(fixtureDir)
+	if !assert.Nil(t, err, "the error should be nil") {
+		return
+	}
+	defer server.Close()
+
+	params := strings.NewReplacer("{provider}", "amazon", "{service}", "compute", "{region}", "eu-west-1")
+	for path, operations := range spec.Paths {
+		if _, ok := operations["get"]; !ok {
+			continue
+		}
+		path := path
+		t.Run(path, func(t *testing.T) {
+			resp, err := http.Get(server.BasePath() + params.Replace(path))
+			if !assert.Nil(t, err, "the error should be nil") {
+				return
+			}
+			defer resp.Body.Close()
+
+			assert.Equal(t, http.StatusOK, resp.StatusCode)
+			assert.Equal(t, "application/json", resp.Header.Get("Content-Type"))
+			var body interface{}
+			assert.Nil(t, json.NewDecoder(resp.Body).Decode(&body), "the response should be JSON")
+		})
+	}
+
+	resp, err := http.Get(server.BasePath() + "/providers/amazon/services/compute/regions/us-east-1/products")
+	if !assert.Nil(t, err, "the error should be nil") {
+		return
+	}
+	resp.Body.Close()
+	assert.Equal(t, http.StatusNotFound, resp.StatusCode, "regions without fixtures should not be found")
+}
+
+func TestCloudInfoServer_CloudInfoClient(t *testing.T) {
+	server, err := NewCloudInfoServer(fixtureDir)
+	if !assert.Nil(t, err, "the error should be nil") {
+		return
+	}
+	defer server.Close()
+
+	client := recommender.NewCloudInfoClient(server.BasePath(), logur.NewTestLogger())
+
+	provider, err := client.GetProvider("amazon")
+	assert.Nil(t, err, "the error should be nil")
+	assert.Equal(t, "amazon", provider)
+
+	providers, err := client.GetProviders()
+	assert.Nil(t, err, "the error should be nil")
+	if assert.Len(t, providers, 1) {
+		assert.Equal(t, "amazon", providers[0].Provider)
+		assert.Equal(t, "compute", providers[0].Services[0].Service)
+	}
+
+	region, err := client.GetRegion("amazon", "compute", "eu-west-1")
+	assert.Nil(t, err, "the error should be nil")
+	assert.Equal(t, "EU (Ireland)", region)
+
+	zones, err := client.GetZones("amazon", "compute", "eu-west-1")
+	assert.Nil(t, err, "the error should be nil")
+	assert.Equal(t, []string{"eu-west-1a", "eu-west-1b", "eu-west-1c"}, zones)
+
+	continents, err := client.GetContinents()
+	assert.Nil(t, err, "the error should be nil")
+	assert.Equal(t, []string{"Europe"}, continents)
+
+	_, err = client.GetRegion("amazon", "compute", "us-east-1")
+	assert.NotNil(t, err, "the error should not be nil")
+}
+
+func TestNewCloudInfoEngine(t *testing.T) {
+	req := recommender.SingleClusterRecommendationReq{
+		ClusterRecommendationReq: recommender.ClusterRecommendationReq{
+			SumCpu:      32,
+			SumMem:      64,
+			MinNodes:    3,
+			MaxNodes:    12,
+			OnDemandPct: 30,
+		},
+	}
+
+	server, err := NewCloudInfoServer(fixtureDir)
+	if !assert.Nil(t, err, "the error should be nil") {
+		return
+	}
+	defer server.Close()
+
+	fixtureEngine, err := NewFixtureEngine(logur.NewTestLogger(), fixtureDir)
+	if !assert.Nil(t, err, "the error should be nil") {
+		return
+	}
+	expected, err := fixtureEngine.RecommendCluster("amazon", "compute", "eu-west-1", req, nil)
+	if !assert.Nil(t, err, "the error should be nil") {
+		return
+	}
+
+	engine := NewCloudInfoEngine(logur.NewTestLogger(), server.BasePath())
+	resp, err := engine.RecommendCluster("amazon", "compute", "eu-west-1", req, nil)
+	if !assert.Nil(t, err, "the error should be nil") {
+		return
+	}
+
+	assert.Equal(t, expected.NodePools, resp.NodePools, "the recommendation should not depend on the source of the products")
+	assert.Equal(t, expected.Accuracy, resp.Accuracy)
+	assert.NotNil(t, resp.DataFreshness)
+	assert.True(t, server.RequestCount() > 0, "the products should be retrieved from the server")
+}
@@ -0,0 +1,189 @@
+// Copyright © 2019 Banzai Cloud
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package recommendertest
+
+import (
+	"fmt"
+	"sort"
+
+	"github.com/banzaicloud/telescopes/pkg/recommender"
+)
+
+// Conformance checks of the cloud info sources
+const (
+	// CheckRegions checks that the service has regions
+	CheckRegions = "regions"
+	// CheckRegion checks that the region resolves
+	CheckRegion = "region"
+	// CheckProducts checks that the region has products
+	CheckProducts = "products"
+	// CheckProductAttributes checks that the products have a type, cpus and memory
+	CheckProductAttributes = "productAttributes"
+	// CheckDuplicateTypes checks that the instance types of the region are listed once
+	CheckDuplicateTypes = "duplicateTypes"
+	// CheckOnDemandPrice checks that the products have an on-demand price
+	CheckOnDemandPrice = "onDemandPrice"
+	// CheckSpotPrice checks that the spot prices are not negative and not above the on-demand prices
+	CheckSpotPrice = "spotPrice"
+	// CheckZones checks that the products are offered in the zones of the region only
+	CheckZones = "zones"
+	// CheckFilteredProducts checks that the filtered products are the products passing the filter
+	CheckFilteredProducts = "filteredProducts"
+)
+
+// Violation is a conformance check failed by a cloud info source
+type Violation struct {
+	Provider string
+	Service  string
+	Region   string
+	// Check failed
+	Check string
+	// Message describing the failure
+	Message string
+}
+
+func (v Violation) String() string {
+	return fmt.Sprintf("%s/%s/%s: %s: %s", v.Provider, v.Service, v.Region, v.Check, v.Message)
+}
+
+// CheckConformance checks that the cloud info source serves sane products for the regions of the service, all of
+// them if no region is given; it catches the drift of the provider APIs the products are scraped from
+// The error is returned if the source can't be queried at all, the failed checks are returned as violations
+func CheckConformance(source recommender.CloudInfoSource, provider, service string, regions ...string) ([]Violation, error) {
+	var violations []Violation
+	if len(regions) == 0 {
+		all, err := source.GetRegions(provider, service)
+		if err != nil {
+			return nil, err
+		}
+		if len(all) == 0 {
+			violations = append(violations, Violation{Provider: provider, Service: service, Check: CheckRegions,
+				Message: "no regions"})
+		}
+		for _, r := range all {
+			regions = append(regions, r.Id)
+		}
+	}
+
+	for _, region := range regions {
+		c := &conformance{source: source, provider: provider, service: service, region: region}
+		c.checkRegion()
+		violations = append(violations, c.violations...)
+	}
+	return violations, nil
+}
+
+// conformance collects the violations of a region
+type conformance struct {
+	source                    recommender.CloudInfoSource
+	provider, service, region string
+	violations                []Violation
+}
+
+func (c *conformance) violate(check, format string, args ...interface{}) {
+	c.violations = append(c.violations, Violation{Provider: c.provider, Service: c.service, Region: c.region,
+		Check: check, Message: fmt.Sprintf(format, args...)})
+}
+
+func (c *conformance) checkRegion() {
+	name, err := c.source.GetRegion(c.provider, c.service, c.region)
+	if err != nil {
+		c.violate(CheckRegion, "failed to get the region: %s", err)
+		return
+	}
+	if name == "" {
+		c.violate(CheckRegion, "unknown region")
+		return
+	}
+	products, err := c.source.GetProductDetails(c.provider, c.service, c.region)
+	if err != nil {
+		c.violate(CheckProducts, "failed to get the products: %s", err)
+		return
+	}
+	if len(products) == 0 {
+		c.violate(CheckProducts, "no products")
+		return
+	}
+	zones, err := c.source.GetZones(c.provider, c.service, c.region)
+	if err != nil {
+		c.violate(CheckZones, "failed to get the zones: %s", err)
+	}
+
+	c.checkProducts(products, zones)
+	c.checkFilteredProducts(products)
+}
+
+func (c *conformance) checkProducts(products []recommender.VirtualMachine, zones []string) {
+	regionZones := make(map[string]bool, len(zones))
+	for _, zone := range zones {
+		regionZones[zone] = true
+	}
+	seen := make(map[string]bool, len(products))
+	for _, vm := range products {
+		if vm.Type == "" || vm.Cpus <= 0 || vm.Mem <= 0 || vm.Gpus < 0 {
+			c.violate(CheckProductAttributes, "%q has %g cpus, %g memory and %g gpus", vm.Type, vm.Cpus, vm.Mem, vm.Gpus)
+		}
+		if seen[vm.Type] {
+			c.violate(CheckDuplicateTypes, "%s is listed more than once", vm.Type)
+		}
+		seen[vm.Type] = true
+		if vm.OnDemandPrice <= 0 {
+			c.violate(CheckOnDemandPrice, "%s has an on-demand price of %g", vm.Type, vm.OnDemandPrice)
+		}
+		if vm.AvgPrice < 0 || (vm.OnDemandPrice > 0 && vm.AvgPrice > vm.OnDemandPrice) {
+			c.violate(CheckSpotPrice, "%s has a spot price of %g, its on-demand price is %g", vm.Type, vm.AvgPrice, vm.OnDemandPrice)
+		}
+		for _, zone := range vm.Zones {
+			if len(regionZones) > 0 && !regionZones[zone] {
+				c.violate(CheckZones, "%s is offered in %s, not a zone of the region", vm.Type, zone)
+			}
+		}
+	}
+}
+
+// checkFilteredProducts checks the products filtered by the median cpus and memory against the ones filtered locally
+func (c *conformance) checkFilteredProducts(products []recommender.VirtualMachine) {
+	filter := recommender.ProductFilter{MinCpu: median(products, recommender.Cpu), MinMem: median(products, recommender.Memory)}
+	filtered, err := c.source.GetProductDetailsFiltered(c.provider, c.service, c.region, filter)
+	if err != nil {
+		c.violate(CheckFilteredProducts, "failed to get the filtered products: %s", err)
+		return
+	}
+	expected, actual := types(filter.Apply(products)), types(filtered)
+	if fmt.Sprint(expected) != fmt.Sprint(actual) {
+		c.violate(CheckFilteredProducts, "%d products pass the filter %+v, %d are served: %v", len(expected), filter,
+			len(actual), actual)
+	}
+}
+
+// median gets the median value of the attribute of the products
+func median(products []recommender.VirtualMachine, attr string) float64 {
+	values := make([]float64, 0, len(products))
+	for _, vm := range products {
+		values = append(values, vm.GetAttrValue(attr))
+	}
+	sort.Float64s(values)
+	return values[len(values)/2]
+}
+
+// types gets the sorted instance types of the products
+func types(products []recommender.VirtualMachine) []string {
+	names := make([]string, 0, len(products))
+	for _, vm := range products {
+		names = append(names, vm.Type)
+	}
+	sort.Strings(names)
+	return names
+}
@@ -0,0 +1,106 @@
+// Copyright © 2019 Banzai Cloud
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package recommendertest
+
+import (
+	"testing"
+
+	"github.com/banzaicloud/telescopes/pkg/recommender"
+	"github.com/stretchr/testify/assert"
+)
+
+// driftedSource serves the fixture products drifted by the change
+type driftedSource struct {
+	recommender.CloudInfoSource
+	drift func([]recommender.VirtualMachine) []recommender.VirtualMachine
+}
+
+func (s driftedSource) GetProductDetails(provider, service, region string) ([]recommender.VirtualMachine, error) {
+	products, err := s.CloudInfoSource.GetProductDetails(provider, service, region)
+	if err != nil {
+		return nil, err
+	}
+	return s.drift(append([]recommender.VirtualMachine(nil), products...)), nil
+}
+
+func TestCheckConformance(t *testing.T) {
+	fixtures, err := recommender.NewFixtureCloudInfoSource(fixtureDir)
+	if !assert.Nil(t, err, "the error should be nil") {
+		return
+	}
+
+	tests := []struct {
+		name    string
+		drift   func([]recommender.VirtualMachine) []recommender.VirtualMachine
+		regions []string
+		check   func(violations []Violation, err error)
+	}{
+		{
+			name: "the fixtures conform",
+			check: func(violations []Violation, err error) {
+				assert.Nil(t, err, "the error should be nil")
+				assert.Empty(t, violations)
+			},
+		},
+		{
+			name: "missing prices and duplicate types",
+			drift: func(vms []recommender.VirtualMachine) []recommender.VirtualMachine {
+				vms[0].OnDemandPrice = 0
+				vms[1].AvgPrice = vms[1].OnDemandPrice * 2
+				return append(vms, vms[2])
+			},
+			check: func(violations []Violation, err error) {
+				assert.Nil(t, err, "the error should be nil")
+				checks := make(map[string]bool)
+				for _, v := range violations {
+					assert.Equal(t, "eu-west-1", v.Region)
+					checks[v.Check] = true
+				}
+				assert.Equal(t, map[string]bool{CheckOnDemandPrice: true, CheckSpotPrice: true,
+					CheckDuplicateTypes: true, CheckFilteredProducts: true}, checks)
+			},
+		},
+		{
+			name: "no products",
+			drift: func(vms []recommender.VirtualMachine) []recommender.VirtualMachine {
+				return nil
+			},
+			check: func(violations []Violation, err error) {
+				assert.Nil(t, err, "the error should be nil")
+				assert.Equal(t, []Violation{{Provider: "amazon", Service: "compute", Region: "eu-west-1",
+					Check: CheckProducts, Message: "no products"}}, violations)
+			},
+		},
+		{
+			name:    "unknown region",
+			regions: []string{"moon-1"},
+			check: func(violations []Violation, err error) {
+				assert.Nil(t, err, "the error should be nil")
+				if assert.Len(t, violations, 1) {
+					assert.Equal(t, CheckRegion, violations[0].Check)
+				}
+			},
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			source := fixtures
+			if test.drift != nil {
+				source = driftedSource{CloudInfoSource: fixtures, drift: test.drift}
+			}
+			test.check(CheckConformance(source, "amazon", "compute", test.regions...))
+		})
+	}
+}
@@ -0,0 +1,45 @@
+// Copyright © 2019 Banzai Cloud
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package recommendertest provides utilities for running the recommendation engine in tests and demos.
+package recommendertest
+
+import (
+	"github.com/banzaicloud/telescopes/pkg/recommender"
+	"github.com/banzaicloud/telescopes/pkg/recommender/nodepools"
+	"github.com/banzaicloud/telescopes/pkg/recommender/vms"
+	"github.com/goph/logur"
+)
+
+// NewFixtureEngine creates an engine wired with the default selectors, serving product and price data from the
+// fixture directory; the engine does no remote calls and gives the same recommendation for the same request
+func NewFixtureEngine(log logur.Logger, fixtureDir string, opts ...recommender.EngineOption) (*recommender.Engine, error) {
+	ciSource, err := recommender.NewFixtureCloudInfoSource(fixtureDir)
+	if err != nil {
+		return nil, err
+	}
+
+	return newEngine(log, ciSource, opts...), nil
+}
+
+// NewCloudInfoEngine creates an engine wired with the default selectors, retrieving the product and price data from
+// the cloud info REST API at the given address, eg. the BasePath of a CloudInfoServer
+func NewCloudInfoEngine(log logur.Logger, ciUrl string, opts ...recommender.EngineOption) *recommender.Engine {
+	return newEngine(log, recommender.NewCloudInfoClient(ciUrl, log), opts...)
+}
+
+func newEngine(log logur.Logger, ciSource recommender.CloudInfoSource, opts ...recommender.EngineOption) *recommender.Engine {
+	return recommender.NewEngine(log, ciSource, vms.NewVmSelector(log),
+		nodepools.NewNodePoolSelector(log, nodepools.DefaultConstraints()), opts...)
+}
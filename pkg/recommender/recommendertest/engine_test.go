@@ -0,0 +1,79 @@
+// Copyright © 2019 Banzai Cloud
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package recommendertest
+
+import (
+	"testing"
+
+	"github.com/banzaicloud/telescopes/pkg/recommender"
+	"github.com/goph/logur"
+	"github.com/stretchr/testify/assert"
+)
+
+const fixtureDir = "testdata/fixtures"
+
+func TestNewFixtureEngine(t *testing.T) {
+	tests := []struct {
+		name       string
+		fixtureDir string
+		request    recommender.SingleClusterRecommendationReq
+		check      func(first, second *recommender.ClusterRecommendationResp, err error)
+	}{
+		{
+			name:       "recommendations are reproducible",
+			fixtureDir: fixtureDir,
+			request: recommender.SingleClusterRecommendationReq{
+				ClusterRecommendationReq: recommender.ClusterRecommendationReq{
+					SumCpu:      32,
+					SumMem:      64,
+					MinNodes:    3,
+					MaxNodes:    12,
+					OnDemandPct: 30,
+				},
+			},
+			check: func(first, second *recommender.ClusterRecommendationResp, err error) {
+				assert.Nil(t, err, "the error should be nil")
+				assert.NotEmpty(t, first.NodePools)
+				assert.Equal(t, first, second, "the recommendations should be the same")
+				assert.Nil(t, first.DataFreshness)
+			},
+		},
+		{
+			name:       "missing fixture directory",
+			fixtureDir: "testdata/missing",
+			check: func(first, second *recommender.ClusterRecommendationResp, err error) {
+				assert.NotNil(t, err, "the error should not be nil")
+			},
+		},
+	}
+	for _, test := range tests {
+		test := test
+		t.Run(test.name, func(t *testing.T) {
+			engine, err := NewFixtureEngine(logur.NewTestLogger(), test.fixtureDir)
+			if err != nil {
+				test.check(nil, nil, err)
+				return
+			}
+
+			first, err := engine.RecommendCluster("amazon", "compute", "eu-west-1", test.request, nil)
+			if err != nil {
+				test.check(nil, nil, err)
+				return
+			}
+			second, err := engine.RecommendCluster("amazon", "compute", "eu-west-1", test.request, nil)
+			test.check(first, second, err)
+		})
+	}
+}
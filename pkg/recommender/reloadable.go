@@ -0,0 +1,96 @@
+// Copyright © 2019 Banzai Cloud
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package recommender
+
+import (
+	"sync/atomic"
+
+	"github.com/banzaicloud/telescopes/.gen/cloudinfo"
+)
+
+// ReloadableCloudInfoSource is a CloudInfoSource delegating to a source that can be swapped at runtime,
+// eg. when the cloud info address changes; calls in flight finish with the source they started with
+type ReloadableCloudInfoSource struct {
+	source atomic.Value
+}
+
+// sourceHolder wraps the sources, as atomic.Value only stores values of the same concrete type
+type sourceHolder struct {
+	CloudInfoSource
+}
+
+// NewReloadableCloudInfoSource creates a reloadable source delegating to the given source
+func NewReloadableCloudInfoSource(source CloudInfoSource) *ReloadableCloudInfoSource {
+	r := &ReloadableCloudInfoSource{}
+	r.Swap(source)
+	return r
+}
+
+// Swap replaces the source the calls are delegated to
+func (r *ReloadableCloudInfoSource) Swap(source CloudInfoSource) {
+	r.source.Store(sourceHolder{source})
+}
+
+func (r *ReloadableCloudInfoSource) current() CloudInfoSource {
+	return r.source.Load().(sourceHolder).CloudInfoSource
+}
+
+func (r *ReloadableCloudInfoSource) GetProductDetails(provider string, service string, region string) ([]VirtualMachine, error) {
+	return r.current().GetProductDetails(provider, service, region)
+}
+
+func (r *ReloadableCloudInfoSource) GetProductDetailsFiltered(provider string, service string, region string, filter ProductFilter) ([]VirtualMachine, error) {
+	return r.current().GetProductDetailsFiltered(provider, service, region, filter)
+}
+
+func (r *ReloadableCloudInfoSource) GetRegions(provider, service string) ([]cloudinfo.Region, error) {
+	return r.current().GetRegions(provider, service)
+}
+
+func (r *ReloadableCloudInfoSource) GetContinentsData(provider, service string) ([]cloudinfo.Continent, error) {
+	return r.current().GetContinentsData(provider, service)
+}
+
+func (r *ReloadableCloudInfoSource) GetZones(provider, service, region string) ([]string, error) {
+	return r.current().GetZones(provider, service, region)
+}
+
+func (r *ReloadableCloudInfoSource) GetContinents() ([]string, error) {
+	return r.current().GetContinents()
+}
+
+func (r *ReloadableCloudInfoSource) GetRegion(provider string, service string, region string) (string, error) {
+	return r.current().GetRegion(provider, service, region)
+}
+
+func (r *ReloadableCloudInfoSource) GetProviders() ([]cloudinfo.Provider, error) {
+	return r.current().GetProviders()
+}
+
+func (r *ReloadableCloudInfoSource) GetProvider(provider string) (string, error) {
+	return r.current().GetProvider(provider)
+}
+
+func (r *ReloadableCloudInfoSource) GetService(provider string, service string) (string, error) {
+	return r.current().GetService(provider, service)
+}
+
+func (r *ReloadableCloudInfoSource) GetDataFreshness(provider string, service string, region string) (DataFreshness, bool) {
+	return r.current().GetDataFreshness(provider, service, region)
+}
+
+func (r *ReloadableCloudInfoSource) GetAllDataFreshness() []DataFreshness {
+	return r.current().GetAllDataFreshness()
+}
@@ -0,0 +1,183 @@
+// Copyright © 2019 Banzai Cloud
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package recommender
+
+import (
+	"math"
+	"sort"
+
+	"github.com/goph/emperror"
+)
+
+// DefaultMaxReplacements is the number of replacements listed per instance type, unless requested otherwise
+const DefaultMaxReplacements = 3
+
+// networkPerfRanks orders the network performance categories
+var networkPerfRanks = map[string]int{"low": 1, "medium": 2, "high": 3, "extra": 4}
+
+// ReplacementsReq encapsulates the instance types to find current generation replacements for
+// swagger:model replacementsRequest
+type ReplacementsReq struct {
+	// Instance types to replace, eg. the types of an aging cluster
+	InstanceTypes []string `json:"instanceTypes" binding:"required,min=1"`
+	// Maximum number of replacements listed per instance type, 3 by default
+	MaxReplacements int `json:"maxReplacements,omitempty" binding:"min=0"`
+}
+
+// Replacements lists the closest current generation equivalents of the instance types
+type Replacements struct {
+	// Replacements per requested instance type, in the order of the request
+	InstanceTypes []TypeReplacements `json:"instanceTypes"`
+}
+
+// TypeReplacements lists the replacements of an instance type, the closest one first
+type TypeReplacements struct {
+	// The instance type to replace
+	InstanceType string `json:"instanceType"`
+	// Signals that the instance type is of current generation
+	CurrentGen bool `json:"currentGen"`
+	// Hourly on-demand price of the instance type
+	OnDemandPrice float64 `json:"onDemandPrice"`
+	// Current generation instance types providing at least the cpus, memory, gpus and network performance of the type
+	Replacements []Replacement `json:"replacements"`
+}
+
+// Replacement is a current generation equivalent of an instance type
+type Replacement struct {
+	// The replacement instance type
+	VmType string `json:"vmType"`
+	// Number of CPUs in the instance type
+	Cpus float64 `json:"cpusPerVm"`
+	// Available memory in the instance type (GiB)
+	Mem float64 `json:"memPerVm"`
+	// Number of GPUs in the instance type
+	Gpus float64 `json:"gpusPerVm"`
+	// Network performance category of the instance type
+	NetworkPerfCat string `json:"networkPerfCategory"`
+	// Hourly on-demand price of the instance type
+	OnDemandPrice float64 `json:"onDemandPrice"`
+	// Change of the hourly on-demand price by the replacement, negative if the replacement is cheaper
+	PriceDelta float64 `json:"priceDelta"`
+	// Change of the hourly on-demand price in percent
+	PriceDeltaPct float64 `json:"priceDeltaPct"`
+	// Relative difference of the cpus and memory of the instance types, 0 for identical sizes
+	Distance float64 `json:"distance"`
+}
+
+// Replacements finds the closest current generation equivalents of the instance types by their cpus, memory, gpus
+// and network performance: a replacement provides at least the same resources, the ones closest in size come first,
+// then the cheaper ones
+func (e *Engine) Replacements(provider string, service string, region string, req ReplacementsReq) (*Replacements, error) {
+	if err := e.checkTenantProvider(provider); err != nil {
+		return nil, err
+	}
+
+	products, err := e.ciSource.GetProductDetails(provider, service, region)
+	if err != nil {
+		return nil, err
+	}
+	normalizeAttributes(provider, products)
+
+	excludes := make(map[string]bool)
+	for _, t := range e.tenantExcludes(nil) {
+		excludes[t] = true
+	}
+	vms := make(map[string]VirtualMachine, len(products))
+	for _, vm := range products {
+		vms[vm.Type] = vm
+	}
+
+	max := req.MaxReplacements
+	if max == 0 {
+		max = DefaultMaxReplacements
+	}
+
+	replacements := &Replacements{InstanceTypes: make([]TypeReplacements, 0, len(req.InstanceTypes))}
+	for _, instanceType := range req.InstanceTypes {
+		vm, ok := vms[instanceType]
+		if !ok {
+			return nil, emperror.With(ErrUnknownInstanceType, RecommenderErrorTag, "instanceType", instanceType)
+		}
+
+		candidates := make([]Replacement, 0)
+		for _, candidate := range products {
+			if candidate.Type == vm.Type || excludes[candidate.Type] || !replaces(candidate, vm) {
+				continue
+			}
+			candidates = append(candidates, newReplacement(vm, candidate))
+		}
+		sort.SliceStable(candidates, func(i, j int) bool {
+			if candidates[i].Distance != candidates[j].Distance {
+				return candidates[i].Distance < candidates[j].Distance
+			}
+			if candidates[i].OnDemandPrice != candidates[j].OnDemandPrice {
+				return candidates[i].OnDemandPrice < candidates[j].OnDemandPrice
+			}
+			return candidates[i].VmType < candidates[j].VmType
+		})
+		if len(candidates) > max {
+			candidates = candidates[:max]
+		}
+
+		replacements.InstanceTypes = append(replacements.InstanceTypes, TypeReplacements{
+			InstanceType:  vm.Type,
+			CurrentGen:    vm.CurrentGen,
+			OnDemandPrice: vm.OnDemandPrice,
+			Replacements:  candidates,
+		})
+	}
+	return replacements, nil
+}
+
+// replaces checks whether the candidate is a current generation instance type providing at least the resources and
+// the network performance of the vm; burst types only replace burst types
+func replaces(candidate, vm VirtualMachine) bool {
+	if !candidate.CurrentGen || candidate.OnDemandPrice <= 0 {
+		return false
+	}
+	if candidate.Burst && !vm.Burst {
+		return false
+	}
+	if candidate.Cpus < vm.Cpus || candidate.Mem < vm.Mem || candidate.Gpus < vm.Gpus {
+		return false
+	}
+	return networkPerfRanks[candidate.NetworkPerfCat] >= networkPerfRanks[vm.NetworkPerfCat]
+}
+
+// newReplacement describes the candidate as a replacement of the vm
+func newReplacement(vm, candidate VirtualMachine) Replacement {
+	replacement := Replacement{
+		VmType:         candidate.Type,
+		Cpus:           candidate.Cpus,
+		Mem:            candidate.Mem,
+		Gpus:           candidate.Gpus,
+		NetworkPerfCat: candidate.NetworkPerfCat,
+		OnDemandPrice:  candidate.OnDemandPrice,
+		PriceDelta:     candidate.OnDemandPrice - vm.OnDemandPrice,
+		Distance:       relativeDiff(vm.Cpus, candidate.Cpus) + relativeDiff(vm.Mem, candidate.Mem),
+	}
+	if vm.OnDemandPrice > 0 {
+		replacement.PriceDeltaPct = replacement.PriceDelta / vm.OnDemandPrice * 100
+	}
+	return replacement
+}
+
+// relativeDiff gets the difference of the values relative to the first one
+func relativeDiff(base, value float64) float64 {
+	if base == 0 {
+		return value
+	}
+	return math.Abs(value-base) / base
+}
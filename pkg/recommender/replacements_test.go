@@ -0,0 +1,90 @@
+// Copyright © 2019 Banzai Cloud
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package recommender
+
+import (
+	"testing"
+
+	"github.com/goph/logur"
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestEngine_Replacements(t *testing.T) {
+	vms := []VirtualMachine{
+		{Type: "m4.xlarge", Cpus: 4, Mem: 16, OnDemandPrice: 0.2, NetworkPerfCat: "high"},
+		{Type: "m5.xlarge", Cpus: 4, Mem: 16, OnDemandPrice: 0.192, NetworkPerfCat: "high", CurrentGen: true},
+		{Type: "m5a.xlarge", Cpus: 4, Mem: 16, OnDemandPrice: 0.172, NetworkPerfCat: "high", CurrentGen: true},
+		{Type: "r5.xlarge", Cpus: 4, Mem: 32, OnDemandPrice: 0.252, NetworkPerfCat: "high", CurrentGen: true},
+		{Type: "c5.xlarge", Cpus: 4, Mem: 8, OnDemandPrice: 0.17, NetworkPerfCat: "high", CurrentGen: true},
+		{Type: "m5.large", Cpus: 2, Mem: 8, OnDemandPrice: 0.096, NetworkPerfCat: "medium", CurrentGen: true},
+		{Type: "t3.xlarge", Cpus: 4, Mem: 16, OnDemandPrice: 0.1664, NetworkPerfCat: "high", CurrentGen: true, Burst: true},
+		{Type: "m5.2xlarge", Cpus: 8, Mem: 32, OnDemandPrice: 0.384, NetworkPerfCat: "low", CurrentGen: true},
+	}
+	tests := []struct {
+		name  string
+		req   ReplacementsReq
+		check func(replacements *Replacements, err error)
+	}{
+		{
+			name: "closest current generation types first",
+			req:  ReplacementsReq{InstanceTypes: []string{"m4.xlarge"}},
+			check: func(replacements *Replacements, err error) {
+				if !assert.Nil(t, err, "the error should be nil") || !assert.Len(t, replacements.InstanceTypes, 1) {
+					return
+				}
+				replacement := replacements.InstanceTypes[0]
+				assert.False(t, replacement.CurrentGen)
+				types := make([]string, 0, len(replacement.Replacements))
+				for _, r := range replacement.Replacements {
+					types = append(types, r.VmType)
+				}
+				assert.Equal(t, []string{"m5a.xlarge", "m5.xlarge", "r5.xlarge"}, types,
+					"smaller, burst or slower network types should not replace the type")
+				assert.InDelta(t, -0.028, replacement.Replacements[0].PriceDelta, 1e-9)
+				assert.InDelta(t, -14, replacement.Replacements[0].PriceDeltaPct, 1e-9)
+				assert.Equal(t, 0.0, replacement.Replacements[0].Distance)
+			},
+		},
+		{
+			name: "number of replacements limited",
+			req:  ReplacementsReq{InstanceTypes: []string{"m4.xlarge", "m5.large"}, MaxReplacements: 1},
+			check: func(replacements *Replacements, err error) {
+				if !assert.Nil(t, err, "the error should be nil") || !assert.Len(t, replacements.InstanceTypes, 2) {
+					return
+				}
+				assert.Len(t, replacements.InstanceTypes[0].Replacements, 1)
+				assert.Equal(t, "m5.large", replacements.InstanceTypes[1].InstanceType)
+				assert.True(t, replacements.InstanceTypes[1].CurrentGen)
+			},
+		},
+		{
+			name: "unknown instance type",
+			req:  ReplacementsReq{InstanceTypes: []string{"m1.small"}},
+			check: func(replacements *Replacements, err error) {
+				assert.Nil(t, replacements, "the response should be nil")
+				assert.Equal(t, ErrUnknownInstanceType, errors.Cause(err))
+			},
+		},
+	}
+	for _, test := range tests {
+		test := test
+		t.Run(test.name, func(t *testing.T) {
+			engine := NewEngine(logur.NewTestLogger(), &candidateProducts{vms: vms}, nil, nil)
+
+			test.check(engine.Replacements("amazon", "compute", "eu-west-1", test.req))
+		})
+	}
+}
@@ -0,0 +1,114 @@
+// Copyright © 2019 Banzai Cloud
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package recommender
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Reservation describes reserved capacity (reserved instances, savings plans, on-demand capacity reservations) the
+// caller already pays for
+type Reservation struct {
+	// Instance type covered by the reservation
+	Type string `json:"type" binding:"required"`
+	// Number of instances covered by the reservation and not used yet
+	Count int `json:"count" binding:"min=1"`
+	// Remaining term of the reservation in months, zero for the capacity reservations without a term; the instances
+	// are billed at the on-demand price after the term ends
+	RemainingMonths int `json:"remainingMonths" binding:"min=0"`
+	// Availability zone of a zonal reservation (eg. an on-demand capacity reservation), empty for regional reservations
+	Zone string `json:"zone,omitempty"`
+}
+
+// ReservationUsage reports how many slots of a reservation the recommended layout consumes
+type ReservationUsage struct {
+	// Instance type covered by the reservation
+	Type string `json:"type"`
+	// Availability zone of the reservation, empty for regional reservations
+	Zone string `json:"zone,omitempty"`
+	// Number of reserved instances used by the layout
+	Used int `json:"used"`
+	// Number of reserved instances available
+	Available int `json:"available"`
+	// Shortest remaining term in months of the reservations, zero if none of them has a term
+	RemainingMonths int `json:"remainingMonths,omitempty"`
+}
+
+// reservationUsage sums the reserved worker nodes of the layout per reserved instance type and zone; the nodes of
+// the node pools pinned to a single zone use the reservations of the zone first, then the regional ones
+func reservationUsage(reservations []Reservation, nodePools []NodePool) []ReservationUsage {
+	if len(reservations) == 0 {
+		return nil
+	}
+
+	var (
+		usage []ReservationUsage
+		index = make(map[[2]string]int)
+	)
+	for _, r := range reservations {
+		key := [2]string{r.Type, r.Zone}
+		if i, ok := index[key]; ok {
+			usage[i].Available += r.Count
+			if r.RemainingMonths > 0 && (usage[i].RemainingMonths == 0 || r.RemainingMonths < usage[i].RemainingMonths) {
+				usage[i].RemainingMonths = r.RemainingMonths
+			}
+			continue
+		}
+		index[key] = len(usage)
+		usage = append(usage, ReservationUsage{Type: r.Type, Zone: r.Zone, Available: r.Count, RemainingMonths: r.RemainingMonths})
+	}
+
+	for _, np := range nodePools {
+		if np.Role != Worker || np.VmClass != Reserved {
+			continue
+		}
+		nodes := np.SumNodes
+		if len(np.Zones) == 1 {
+			if i, ok := index[[2]string{np.VmType.Type, np.Zones[0]}]; ok {
+				used := usage[i].Available - usage[i].Used
+				if used > nodes {
+					used = nodes
+				}
+				usage[i].Used += used
+				nodes -= used
+			}
+		}
+		if i, ok := index[[2]string{np.VmType.Type, ""}]; ok {
+			usage[i].Used += nodes
+		}
+	}
+
+	return usage
+}
+
+// reservationExpiryWarning warns if the layout uses reservations ending within a month, the reserved nodes are billed
+// at the on-demand price afterwards
+func reservationExpiryWarning(usage []ReservationUsage) *Warning {
+	var types []string
+	for _, u := range usage {
+		if u.Used > 0 && u.RemainingMonths == 1 {
+			types = append(types, u.Type)
+		}
+	}
+	if len(types) == 0 {
+		return nil
+	}
+	return &Warning{
+		Code: WarnReservationExpiring,
+		Message: fmt.Sprintf("reservations of %s used by the layout end within a month, the nodes are billed at the on-demand price afterwards",
+			strings.Join(types, ", ")),
+	}
+}
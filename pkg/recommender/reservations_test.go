@@ -0,0 +1,59 @@
+// Copyright © 2019 Banzai Cloud
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package recommender
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_reservationUsage(t *testing.T) {
+	reservations := []Reservation{
+		{Type: "m5.xlarge", Count: 2, RemainingMonths: 12},
+		{Type: "m5.xlarge", Count: 1, Zone: "eu-west-1b"},
+		{Type: "c5.xlarge", Count: 4, Zone: "eu-west-1a"},
+		{Type: "m5.xlarge", Count: 1, RemainingMonths: 6},
+	}
+	nodePools := []NodePool{
+		{VmType: VirtualMachine{Type: "m5.xlarge"}, SumNodes: 2, VmClass: Reserved, Role: Worker, Zones: []string{"eu-west-1b"}},
+		{VmType: VirtualMachine{Type: "c5.xlarge"}, SumNodes: 3, VmClass: Reserved, Role: Worker, Zones: []string{"eu-west-1a"}},
+		{VmType: VirtualMachine{Type: "c5.xlarge"}, SumNodes: 5, VmClass: Regular, Role: Worker},
+	}
+
+	assert.Nil(t, reservationUsage(nil, nodePools))
+	assert.Equal(t, []ReservationUsage{
+		{Type: "m5.xlarge", Used: 1, Available: 3, RemainingMonths: 6},
+		{Type: "m5.xlarge", Zone: "eu-west-1b", Used: 1, Available: 1},
+		{Type: "c5.xlarge", Zone: "eu-west-1a", Used: 3, Available: 4},
+	}, reservationUsage(reservations, nodePools), "the zonal reservations should be used first")
+}
+
+func Test_reservationExpiryWarning(t *testing.T) {
+	assert.Nil(t, reservationExpiryWarning([]ReservationUsage{
+		{Type: "m5.xlarge", Used: 2, Available: 2, RemainingMonths: 6},
+		{Type: "c5.xlarge", Used: 0, Available: 1, RemainingMonths: 1},
+		{Type: "r5.xlarge", Used: 1, Available: 1},
+	}), "unused and long-running reservations should not be warned about")
+
+	warning := reservationExpiryWarning([]ReservationUsage{
+		{Type: "m5.xlarge", Used: 2, Available: 2, RemainingMonths: 1},
+	})
+	if !assert.NotNil(t, warning) {
+		return
+	}
+	assert.Equal(t, WarnReservationExpiring, warning.Code)
+	assert.Contains(t, warning.Message, "m5.xlarge")
+}
@@ -0,0 +1,47 @@
+// Copyright © 2019 Banzai Cloud
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package recommender
+
+const (
+	// resiliency levels
+	ResiliencyLow    = "low"
+	ResiliencyMedium = "medium"
+	ResiliencyHigh   = "high"
+)
+
+// ResiliencyConstraints holds the concrete engine constraints a resiliency level maps to
+type ResiliencyConstraints struct {
+	// The requested resiliency level
+	Level string `json:"level"`
+	// Minimum number of spot node pools to diversify the spot capacity
+	MinSpotPools int `json:"minSpotPools"`
+	// Maximum percentage of the worker capacity allowed in a single node pool
+	MaxPoolSharePct int `json:"maxPoolSharePct"`
+	// Minimum percentage of on-demand capacity
+	MinOnDemandPct int `json:"minOnDemandPct"`
+}
+
+// resiliencyLevels maps the resiliency levels to engine constraints
+var resiliencyLevels = map[string]ResiliencyConstraints{ // nolint: gochecknoglobals
+	ResiliencyLow:    {Level: ResiliencyLow, MinSpotPools: 1, MaxPoolSharePct: 100, MinOnDemandPct: 0},
+	ResiliencyMedium: {Level: ResiliencyMedium, MinSpotPools: 2, MaxPoolSharePct: 60, MinOnDemandPct: 10},
+	ResiliencyHigh:   {Level: ResiliencyHigh, MinSpotPools: 4, MaxPoolSharePct: 35, MinOnDemandPct: 30},
+}
+
+// ResiliencyFor gets the constraints of the resiliency level, returns false for unknown levels
+func ResiliencyFor(level string) (ResiliencyConstraints, bool) {
+	c, ok := resiliencyLevels[level]
+	return c, ok
+}
@@ -0,0 +1,195 @@
+// Copyright © 2019 Banzai Cloud
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package recommender
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"sync"
+	"time"
+	"unsafe"
+)
+
+// ProductSnapshot holds the products and prices a recommendation was made with, as retrieved from cloud info
+type ProductSnapshot struct {
+	// ID of the snapshot, derived from its content
+	ID       string `json:"id"`
+	Provider string `json:"provider"`
+	Service  string `json:"service"`
+	Region   string `json:"region"`
+	// Time the snapshot was first taken
+	TakenAt  time.Time        `json:"takenAt"`
+	Products []VirtualMachine `json:"products"`
+}
+
+// SnapshotStore stores the product snapshots the recommendations were made with
+type SnapshotStore interface {
+	// Put stores the snapshot, a snapshot with the same ID is kept as it is
+	Put(snapshot ProductSnapshot)
+	// Get gets the snapshot with the given ID, returns false if it doesn't exist
+	Get(id string) (ProductSnapshot, bool)
+}
+
+// Snapshot eviction reasons
+const (
+	// EvictedForCapacity means the snapshot was evicted to keep the number of snapshots within the capacity
+	EvictedForCapacity = "capacity"
+	// EvictedForSize means the snapshot was evicted to keep the approximate size of the snapshots within the limit
+	EvictedForSize = "size"
+)
+
+// inMemorySnapshotStore is a SnapshotStore keeping the most recent snapshots in memory
+type inMemorySnapshotStore struct {
+	mux       sync.Mutex
+	capacity  int
+	maxBytes  int64
+	bytes     int64
+	snapshots map[string]ProductSnapshot
+	sizes     map[string]int64
+	// IDs in the order of their last use, the least recently used first
+	order     []string
+	listeners []func(reason string)
+}
+
+// SnapshotStoreOption configures optional features of the in-memory snapshot store
+type SnapshotStoreOption func(s *inMemorySnapshotStore)
+
+// WithMaxBytes limits the approximate memory used by the snapshots, the least recently used snapshots are evicted
+// over the limit; the most recent snapshot is always kept
+func WithMaxBytes(maxBytes int64) SnapshotStoreOption {
+	return func(s *inMemorySnapshotStore) {
+		s.maxBytes = maxBytes
+	}
+}
+
+// WithEvictionListener registers a listener notified with the reason of the evictions
+func WithEvictionListener(listener func(reason string)) SnapshotStoreOption {
+	return func(s *inMemorySnapshotStore) {
+		s.listeners = append(s.listeners, listener)
+	}
+}
+
+// NewInMemorySnapshotStore creates a snapshot store keeping the given number of the most recently used snapshots in memory
+func NewInMemorySnapshotStore(capacity int, opts ...SnapshotStoreOption) SnapshotStore {
+	s := &inMemorySnapshotStore{
+		capacity:  capacity,
+		snapshots: make(map[string]ProductSnapshot, capacity),
+		sizes:     make(map[string]int64, capacity),
+	}
+	for _, opt := range opts {
+		opt(s)
+	}
+	return s
+}
+
+func (s *inMemorySnapshotStore) Put(snapshot ProductSnapshot) {
+	s.mux.Lock()
+	defer s.mux.Unlock()
+
+	if _, ok := s.snapshots[snapshot.ID]; !ok {
+		s.snapshots[snapshot.ID] = snapshot
+		s.sizes[snapshot.ID] = approximateSize(snapshot)
+		s.bytes += s.sizes[snapshot.ID]
+	}
+	s.touch(snapshot.ID)
+
+	for len(s.order) > s.capacity {
+		s.evict(EvictedForCapacity)
+	}
+	for s.maxBytes > 0 && s.bytes > s.maxBytes && len(s.order) > 1 {
+		s.evict(EvictedForSize)
+	}
+}
+
+// evict drops the least recently used snapshot
+func (s *inMemorySnapshotStore) evict(reason string) {
+	id := s.order[0]
+	s.bytes -= s.sizes[id]
+	delete(s.snapshots, id)
+	delete(s.sizes, id)
+	s.order = s.order[1:]
+
+	for _, listener := range s.listeners {
+		listener(reason)
+	}
+}
+
+func (s *inMemorySnapshotStore) Get(id string) (ProductSnapshot, bool) {
+	s.mux.Lock()
+	defer s.mux.Unlock()
+
+	snapshot, ok := s.snapshots[id]
+	return snapshot, ok
+}
+
+// touch moves the ID to the end of the usage order
+func (s *inMemorySnapshotStore) touch(id string) {
+	for i, o := range s.order {
+		if o == id {
+			s.order = append(s.order[:i], s.order[i+1:]...)
+			break
+		}
+	}
+	s.order = append(s.order, id)
+}
+
+// approximateSize estimates the memory used by the snapshot: the fixed size of the products, the length of their strings
+// and the entries of their spot prices (the length of the zone and the price)
+func approximateSize(snapshot ProductSnapshot) int64 {
+	size := int64(unsafe.Sizeof(snapshot)) + int64(len(snapshot.ID)+len(snapshot.Provider)+len(snapshot.Service)+len(snapshot.Region))
+	for _, vm := range snapshot.Products {
+		size += int64(unsafe.Sizeof(vm))
+		size += int64(len(vm.GpuType) + len(vm.Category) + len(vm.Family) + len(vm.Type) + len(vm.NetworkPerf) + len(vm.NetworkPerfCat))
+		for _, zone := range vm.Zones {
+			size += int64(unsafe.Sizeof(zone)) + int64(len(zone))
+		}
+		for zone, price := range vm.SpotPrices {
+			size += int64(len(zone)) + int64(unsafe.Sizeof(price))
+		}
+	}
+	return size
+}
+
+// takeSnapshot stores a copy of the products retrieved for a recommendation, and returns the ID of the snapshot;
+// empty if snapshots are not enabled
+func (e *Engine) takeSnapshot(provider, service, region string, vms []VirtualMachine) string {
+	if e.snapshots == nil {
+		return ""
+	}
+
+	data, err := json.Marshal(struct {
+		Provider, Service, Region string
+		Products                  []VirtualMachine
+	}{provider, service, region, vms})
+	if err != nil {
+		e.log.Warn("failed to take a snapshot of the products", map[string]interface{}{"err": err.Error()})
+		return ""
+	}
+	sum := sha256.Sum256(data)
+	id := hex.EncodeToString(sum[:16])
+
+	products := make([]VirtualMachine, len(vms))
+	copy(products, vms)
+	e.snapshots.Put(ProductSnapshot{
+		ID:       id,
+		Provider: provider,
+		Service:  service,
+		Region:   region,
+		TakenAt:  time.Now(),
+		Products: products,
+	})
+	return id
+}
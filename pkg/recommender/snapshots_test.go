@@ -0,0 +1,112 @@
+// Copyright © 2019 Banzai Cloud
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package recommender
+
+import (
+	"testing"
+
+	"github.com/goph/logur"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestInMemorySnapshotStore(t *testing.T) {
+	store := NewInMemorySnapshotStore(2)
+
+	store.Put(ProductSnapshot{ID: "a", Region: "eu-west-1"})
+	store.Put(ProductSnapshot{ID: "b"})
+	store.Put(ProductSnapshot{ID: "a", Region: "eu-central-1"})
+	store.Put(ProductSnapshot{ID: "c"})
+
+	a, ok := store.Get("a")
+	assert.True(t, ok, "the recently used snapshot should be kept")
+	assert.Equal(t, "eu-west-1", a.Region, "the snapshot stored first should be kept")
+	_, ok = store.Get("b")
+	assert.False(t, ok, "the least recently used snapshot should be dropped")
+	_, ok = store.Get("c")
+	assert.True(t, ok)
+}
+
+func Test_approximateSize(t *testing.T) {
+	vm := VirtualMachine{Type: "m5.xlarge", Zones: []string{"eu-west-1a", "eu-west-1b"}}
+	size := approximateSize(ProductSnapshot{ID: "a", Products: []VirtualMachine{vm}})
+
+	vm.SpotPrices = map[string]float64{"eu-west-1a": 0.07, "eu-west-1b": 0.08}
+	withSpotPrices := approximateSize(ProductSnapshot{ID: "a", Products: []VirtualMachine{vm}})
+	assert.Equal(t, size+2*(10+8), withSpotPrices, "the zones and the prices of the spot prices should be counted")
+}
+
+func TestInMemorySnapshotStore_maxBytes(t *testing.T) {
+	products := []VirtualMachine{{Type: "m5.xlarge", Zones: []string{"eu-west-1a", "eu-west-1b"}}}
+	size := approximateSize(ProductSnapshot{ID: "a", Products: products})
+	var evictions []string
+	store := NewInMemorySnapshotStore(10, WithMaxBytes(2*size), WithEvictionListener(func(reason string) {
+		evictions = append(evictions, reason)
+	}))
+
+	store.Put(ProductSnapshot{ID: "a", Products: products})
+	store.Put(ProductSnapshot{ID: "b", Products: products})
+	assert.Empty(t, evictions)
+
+	store.Put(ProductSnapshot{ID: "c", Products: products})
+	assert.Equal(t, []string{EvictedForSize}, evictions)
+	_, ok := store.Get("a")
+	assert.False(t, ok, "the least recently used snapshot should be evicted over the size limit")
+
+	store.Put(ProductSnapshot{ID: "d", Products: append(products, products[0], products[0])})
+	_, ok = store.Get("d")
+	assert.True(t, ok, "the most recent snapshot should be kept even over the size limit")
+	assert.Len(t, evictions, 3)
+}
+
+func TestEngine_snapshots(t *testing.T) {
+	products := &candidateProducts{vms: []VirtualMachine{
+		{Type: "m5.xlarge", Cpus: 4, Mem: 16, OnDemandPrice: 0.192, AvgPrice: 0.07},
+		{Type: "r5.xlarge", Cpus: 4, Mem: 32, OnDemandPrice: 0.252, AvgPrice: 0.05},
+	}}
+	store := NewInMemorySnapshotStore(10)
+	double := PriceAdjusterFunc(func(provider string, vm VirtualMachine, vmClass string) float64 {
+		if vmClass == Spot {
+			return vm.AvgPrice * 2
+		}
+		return vm.OnDemandPrice * 2
+	})
+	engine := NewEngine(logur.NewTestLogger(), products, &passthroughVms{}, &dummyNodePools{},
+		WithSnapshots(store), WithPriceAdjuster(double))
+	req := SingleClusterRecommendationReq{
+		ClusterRecommendationReq: ClusterRecommendationReq{SumCpu: 8, SumMem: 32, MinNodes: 1, MaxNodes: 2},
+	}
+
+	resp, err := engine.Candidates("amazon", "compute", "eu-west-1", req)
+	if !assert.NoError(t, err) {
+		return
+	}
+	snapshot, ok := store.Get(resp.Snapshot)
+	if !assert.True(t, ok, "the snapshot of the response should be stored") {
+		return
+	}
+	assert.Equal(t, "eu-west-1", snapshot.Region)
+	if assert.Len(t, snapshot.Products, 2) {
+		assert.Equal(t, 0.192, snapshot.Products[0].OnDemandPrice, "the products should be stored as retrieved")
+	}
+
+	products.vms = []VirtualMachine{
+		{Type: "m5.xlarge", Cpus: 4, Mem: 16, OnDemandPrice: 0.192, AvgPrice: 0.07},
+		{Type: "r5.xlarge", Cpus: 4, Mem: 32, OnDemandPrice: 0.252, AvgPrice: 0.05},
+	}
+	again, err := engine.Candidates("amazon", "compute", "eu-west-1", req)
+	if assert.NoError(t, err) {
+		assert.Equal(t, resp.Snapshot, again.Snapshot, "the same products should have the same snapshot")
+	}
+}
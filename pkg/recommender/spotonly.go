@@ -0,0 +1,46 @@
+// Copyright © 2019 Banzai Cloud
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package recommender
+
+import "fmt"
+
+// spotOnlyWarning warns if the on-demand share of the worker CPUs falls below the desired percentage,
+// as a spot only scale out doesn't add on-demand nodes to keep it
+func spotOnlyWarning(desiredOdPct int, nodePools []NodePool) *Warning {
+	var odCpus, sumCpus float64
+	for _, np := range nodePools {
+		if np.Role == Master {
+			continue
+		}
+		cpus := float64(np.SumNodes) * np.VmType.Cpus
+		sumCpus += cpus
+		if np.VmClass != Spot {
+			odCpus += cpus
+		}
+	}
+	if sumCpus == 0 {
+		return nil
+	}
+
+	odPct := int(odCpus / sumCpus * 100)
+	if odPct >= desiredOdPct {
+		return nil
+	}
+	return &Warning{
+		Code: WarnOnDemandPctNotMet,
+		Message: fmt.Sprintf("on-demand nodes provide %d%% of the CPUs instead of the desired %d%%, the spot only scale out doesn't add on-demand nodes",
+			odPct, desiredOdPct),
+	}
+}
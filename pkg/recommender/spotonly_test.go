@@ -0,0 +1,40 @@
+// Copyright © 2019 Banzai Cloud
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package recommender
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_spotOnlyWarning(t *testing.T) {
+	nodePools := []NodePool{
+		{VmType: VirtualMachine{Type: "m5.xlarge", Cpus: 4}, SumNodes: 2, VmClass: Regular, Role: Worker},
+		{VmType: VirtualMachine{Type: "m5.xlarge", Cpus: 4}, SumNodes: 1, VmClass: Reserved, Role: Worker},
+		{VmType: VirtualMachine{Type: "c5.xlarge", Cpus: 4}, SumNodes: 5, VmClass: Spot, Role: Worker},
+		{VmType: VirtualMachine{Type: "m5.large", Cpus: 2}, SumNodes: 3, VmClass: Regular, Role: Master},
+	}
+
+	warning := spotOnlyWarning(50, nodePools)
+	if assert.NotNil(t, warning) {
+		assert.Equal(t, WarnOnDemandPctNotMet, warning.Code)
+		assert.Contains(t, warning.Message, "37%")
+	}
+
+	assert.Nil(t, spotOnlyWarning(30, nodePools), "the reserved nodes count as on-demand")
+	assert.Nil(t, spotOnlyWarning(0, nodePools))
+	assert.Nil(t, spotOnlyWarning(50, nil))
+}
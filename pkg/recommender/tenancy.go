@@ -0,0 +1,212 @@
+// Copyright © 2019 Banzai Cloud
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package recommender
+
+import (
+	"encoding/json"
+	"io/ioutil"
+
+	"github.com/goph/emperror"
+	"github.com/pkg/errors"
+)
+
+// Tenant is a team served by a shared deployment, isolated by its own providers, product data, excludes and prices
+type Tenant struct {
+	// Name of the tenant
+	Name string `json:"name"`
+	// Tokens authenticating the API calls of the tenant
+	Tokens []string `json:"tokens"`
+	// Providers enabled for the tenant, all providers are enabled if empty
+	Providers []string `json:"providers,omitempty"`
+	// CloudInfoAddress is the address of the cloud info service retrieving the products with the credentials of the tenant,
+	// the shared cloud info service is used if empty
+	CloudInfoAddress string `json:"cloudInfoAddress,omitempty"`
+	// Excludes lists the vm types excluded from every recommendation of the tenant
+	Excludes []string `json:"excludes,omitempty"`
+	// PriceOverrides replace or discount the on-demand prices, eg. by the agreements of the tenant with the provider
+	PriceOverrides []PriceOverride `json:"priceOverrides,omitempty"`
+}
+
+// PriceOverride overrides the on-demand price of an instance type, or all instance types of a provider
+type PriceOverride struct {
+	// Provider the override applies to
+	Provider string `json:"provider"`
+	// Type is the instance type the override applies to, all instance types of the provider if empty
+	Type string `json:"type,omitempty"`
+	// OnDemandPrice replaces the hourly on-demand price
+	OnDemandPrice float64 `json:"onDemandPrice,omitempty"`
+	// DiscountPct discounts the on-demand price, if no price is given
+	DiscountPct float64 `json:"discountPct,omitempty"`
+}
+
+// LoadTenants reads the tenants from a JSON file holding a list of tenants
+func LoadTenants(path string) ([]Tenant, error) {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, emperror.WrapWith(err, "failed to read tenants file", "path", path)
+	}
+
+	var tenants []Tenant
+	if err := json.Unmarshal(data, &tenants); err != nil {
+		return nil, emperror.WrapWith(err, "failed to parse tenants file", "path", path)
+	}
+
+	return tenants, nil
+}
+
+// ProviderEnabled checks whether the provider is enabled for the tenant
+func (t Tenant) ProviderEnabled(provider string) bool {
+	if len(t.Providers) == 0 {
+		return true
+	}
+	for _, p := range t.Providers {
+		if p == provider {
+			return true
+		}
+	}
+	return false
+}
+
+// AdjustPrice applies the price overrides of the tenant to the on-demand prices, the override of the instance type
+// takes precedence over the one of the provider; spot prices are left unchanged
+func (t Tenant) AdjustPrice(provider string, vm VirtualMachine, vmClass string) float64 {
+	if vmClass != Regular {
+		return vm.AvgPrice
+	}
+
+	var override *PriceOverride
+	for i, o := range t.PriceOverrides {
+		if o.Provider != provider {
+			continue
+		}
+		if o.Type == vm.Type {
+			override = &t.PriceOverrides[i]
+			break
+		}
+		if o.Type == "" {
+			override = &t.PriceOverrides[i]
+		}
+	}
+
+	switch {
+	case override == nil:
+		return vm.OnDemandPrice
+	case override.OnDemandPrice > 0:
+		return override.OnDemandPrice
+	default:
+		return vm.OnDemandPrice * (1 - override.DiscountPct/100)
+	}
+}
+
+// TenantContext holds the engine, the product data source and the recommendation profiles serving a tenant
+type TenantContext struct {
+	Tenant Tenant
+	Engine *Engine
+	// Recommender serves the recommendations of the tenant, the engine of the tenant behind the wrappers of the shared one
+	Recommender ClusterRecommender
+	CiSource    CloudInfoSource
+	Profiles    ProfileStore
+}
+
+// Tenancy resolves the tenants of the API tokens
+type Tenancy struct {
+	byToken map[string]*TenantContext
+}
+
+// NewTenancy sets up the engines serving the tenants on top of the shared engine and product data source,
+// newSource creates the product data source of the tenants with their own cloud info service, wrap puts the engines
+// of the tenants behind the same wrappers (eg. the canary evaluation) as the shared engine, if not nil
+func NewTenancy(engine *Engine, ciSource CloudInfoSource, tenants []Tenant, newSource func(address string) (CloudInfoSource, error),
+	wrap func(ClusterRecommender) ClusterRecommender) (*Tenancy, error) {
+	tenancy := &Tenancy{byToken: make(map[string]*TenantContext)}
+	names := make(map[string]bool)
+
+	for _, tenant := range tenants {
+		if tenant.Name == "" || names[tenant.Name] {
+			return nil, emperror.With(errors.New("tenant names must be distinct and non-empty"), "tenant", tenant.Name)
+		}
+		names[tenant.Name] = true
+		if len(tenant.Tokens) == 0 {
+			return nil, emperror.With(errors.New("tenant has no tokens"), "tenant", tenant.Name)
+		}
+
+		source := ciSource
+		if tenant.CloudInfoAddress != "" {
+			var err error
+			if source, err = newSource(tenant.CloudInfoAddress); err != nil {
+				return nil, emperror.WrapWith(err, "failed to create the cloud info source of the tenant", "tenant", tenant.Name)
+			}
+		}
+
+		te := engine.forTenant(tenant, source)
+		var served ClusterRecommender = te
+		if wrap != nil {
+			served = wrap(te)
+		}
+		tc := &TenantContext{
+			Tenant:      tenant,
+			Engine:      te,
+			Recommender: served,
+			CiSource:    source,
+			Profiles:    NewInMemoryProfileStore(),
+		}
+		for _, token := range tenant.Tokens {
+			if _, ok := tenancy.byToken[token]; ok || token == "" {
+				return nil, emperror.With(errors.New("tenant tokens must be distinct and non-empty"), "tenant", tenant.Name)
+			}
+			tenancy.byToken[token] = tc
+		}
+	}
+
+	return tenancy, nil
+}
+
+// Resolve gets the tenant of the token, returns false if the token doesn't belong to a tenant
+func (t *Tenancy) Resolve(token string) (*TenantContext, bool) {
+	tc, ok := t.byToken[token]
+	return tc, ok
+}
+
+// forTenant creates a copy of the engine serving the tenant from the given product data source
+// The afterburner and the metadata cache aren't shared, the candidate vms and the providers of the tenants may differ
+func (e *Engine) forTenant(tenant Tenant, ciSource CloudInfoSource) *Engine {
+	te := *e
+	te.tenant = &tenant
+	te.ciSource = ciSource
+	te.priceAdjusters = append(append([]PriceAdjuster(nil), e.priceAdjusters...), tenant)
+	if e.afterburner != nil {
+		te.afterburner = newAfterburner(e.afterburner.ttl)
+	}
+	if e.metadata != nil {
+		te.metadata = newMetadataCache(e.metadata.ttl)
+	}
+	return &te
+}
+
+// checkTenantProvider checks that the provider is enabled for the tenant of the engine, if any
+func (e *Engine) checkTenantProvider(provider string) error {
+	if e.tenant != nil && !e.tenant.ProviderEnabled(provider) {
+		return emperror.With(ErrProviderNotEnabled, RecommenderErrorTag, "tenant", e.tenant.Name, "provider", provider)
+	}
+	return nil
+}
+
+// tenantExcludes appends the instance types excluded by the tenant of the engine to the excludes of a request
+func (e *Engine) tenantExcludes(excludes []string) []string {
+	if e.tenant == nil || len(e.tenant.Excludes) == 0 {
+		return excludes
+	}
+	return append(append([]string(nil), excludes...), e.tenant.Excludes...)
+}
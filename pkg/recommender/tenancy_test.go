@@ -0,0 +1,129 @@
+// Copyright © 2019 Banzai Cloud
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package recommender
+
+import (
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/goph/logur"
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+)
+
+// excludesVms records the excludes of the last request
+type excludesVms struct {
+	onDemandPctVms
+	excludes []string
+	mu       sync.Mutex
+}
+
+func (v *excludesVms) RecommendVms(provider string, vms []VirtualMachine, attr string, req SingleClusterRecommendationReq, layout []NodePool) ([]VirtualMachine, []VirtualMachine, error) {
+	v.mu.Lock()
+	v.excludes = req.Excludes
+	v.mu.Unlock()
+	return v.onDemandPctVms.RecommendVms(provider, vms, attr, req, layout)
+}
+
+func TestTenant_AdjustPrice(t *testing.T) {
+	tenant := Tenant{
+		PriceOverrides: []PriceOverride{
+			{Provider: "amazon", DiscountPct: 10},
+			{Provider: "amazon", Type: "m5.xlarge", OnDemandPrice: 0.15},
+		},
+	}
+	m5 := VirtualMachine{Type: "m5.xlarge", OnDemandPrice: 0.192, AvgPrice: 0.07}
+	c5 := VirtualMachine{Type: "c5.xlarge", OnDemandPrice: 0.17, AvgPrice: 0.06}
+
+	assert.Equal(t, 0.15, tenant.AdjustPrice("amazon", m5, Regular), "the override of the type should take precedence")
+	assert.InDelta(t, 0.153, tenant.AdjustPrice("amazon", c5, Regular), 1e-9)
+	assert.Equal(t, 0.07, tenant.AdjustPrice("amazon", m5, Spot), "spot prices should be left unchanged")
+	assert.Equal(t, 0.192, tenant.AdjustPrice("google", m5, Regular))
+}
+
+func TestNewTenancy(t *testing.T) {
+	engine := NewEngine(logur.NewTestLogger(), &dummyProducts{}, &dummyVms{}, &dummyNodePools{}, WithAfterburner(time.Minute))
+	tenantSource := &dummyProducts{}
+
+	tenancy, err := NewTenancy(engine, &dummyProducts{}, []Tenant{
+		{Name: "team-a", Tokens: []string{"a1", "a2"}, Providers: []string{"amazon"}},
+		{Name: "team-b", Tokens: []string{"b1"}, CloudInfoAddress: "http://cloudinfo-b"},
+	}, func(address string) (CloudInfoSource, error) {
+		assert.Equal(t, "http://cloudinfo-b", address)
+		return tenantSource, nil
+	}, func(r ClusterRecommender) ClusterRecommender {
+		return &Canary{ClusterRecommender: r}
+	})
+	assert.Nil(t, err, "the error should be nil")
+
+	tc, ok := tenancy.Resolve("a2")
+	assert.True(t, ok, "the token should resolve to a tenant")
+	assert.Equal(t, "team-a", tc.Tenant.Name)
+	assert.Len(t, tc.Engine.priceAdjusters, 1)
+	assert.False(t, tc.Engine.afterburner == engine.afterburner, "the afterburner should not be shared")
+	if canary, ok := tc.Recommender.(*Canary); assert.True(t, ok, "the engine of the tenant should be wrapped") {
+		assert.True(t, canary.ClusterRecommender == tc.Engine)
+	}
+
+	tc.Profiles.Put(Profile{Name: "small"})
+	tcA1, _ := tenancy.Resolve("a1")
+	_, ok = tcA1.Profiles.Get("small")
+	assert.True(t, ok, "the tokens of a tenant should share its profiles")
+
+	tc, ok = tenancy.Resolve("b1")
+	assert.True(t, ok, "the token should resolve to a tenant")
+	assert.True(t, tc.CiSource == tenantSource, "the tenant should use its own cloud info")
+	_, ok = tc.Profiles.Get("small")
+	assert.False(t, ok, "the profiles of a tenant should not be visible to other tenants")
+
+	_, ok = tenancy.Resolve("unknown")
+	assert.False(t, ok, "an unknown token should not resolve")
+
+	_, err = NewTenancy(engine, &dummyProducts{}, []Tenant{
+		{Name: "team-a", Tokens: []string{"token"}},
+		{Name: "team-b", Tokens: []string{"token"}},
+	}, nil, nil)
+	assert.NotNil(t, err, "tokens shared by tenants should be refused")
+
+	_, err = NewTenancy(engine, &dummyProducts{}, []Tenant{{Name: "team-a", CloudInfoAddress: "x", Tokens: []string{"a"}}},
+		func(address string) (CloudInfoSource, error) {
+			return nil, errors.New("invalid address")
+		}, nil)
+	assert.NotNil(t, err, "the error of the tenant source should be returned")
+}
+
+func TestEngine_RecommendClusterTenant(t *testing.T) {
+	req := SingleClusterRecommendationReq{
+		ClusterRecommendationReq: ClusterRecommendationReq{
+			MinNodes: 1,
+			MaxNodes: 1,
+			SumMem:   32,
+			SumCpu:   16,
+		},
+		Excludes: []string{"t2.micro"},
+	}
+	vms := &excludesVms{}
+	engine := NewEngine(logur.NewTestLogger(), &dummyProducts{}, vms, &dummyNodePools{}).
+		forTenant(Tenant{Name: "team-a", Providers: []string{"amazon"}, Excludes: []string{"m5.24xlarge"}}, &dummyProducts{})
+
+	_, err := engine.RecommendCluster("google", "compute", "europe-west1", req, nil)
+	assert.Equal(t, ErrProviderNotEnabled, errors.Cause(err))
+
+	_, err = engine.RecommendCluster("amazon", "compute", "eu-west-1", req, nil)
+	assert.Nil(t, err, "the error should be nil")
+	assert.Equal(t, []string{"t2.micro", "m5.24xlarge"}, vms.excludes, "the excludes of the tenant should be added")
+	assert.Equal(t, []string{"t2.micro"}, req.Excludes, "the request should not be changed")
+}
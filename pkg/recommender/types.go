@@ -14,21 +14,55 @@
 
 package recommender
 
+import "time"
+
 const (
 	// vm types - regular and ondemand means the same, they are both accepted on the API
 	Regular  = "regular"
 	Ondemand = "ondemand"
 	Spot     = "spot"
+	// Reserved signals already paid reserved capacity, from existing layouts or the reservations of the request
+	Reserved = "reserved"
+	// Mixed signals a node pool of an existing layout mixing regular and spot instances, eg. an ASG with a MixedInstancesPolicy
+	Mixed = "mixed"
 	// Memory represents the memory attribute for the recommender
 	Memory = "memory"
 	// Cpu represents the cpu attribute for the recommender
 	Cpu = "cpu"
 
+	// OptimizeForBenchmark optimizes for price per benchmark unit instead of price per cpu
+	OptimizeForBenchmark = "benchmark"
+
 	// nodepool roles
 	Master = "master"
 	Worker = "worker"
 
 	RecommenderErrorTag = "recommender"
+
+	// warning codes
+	WarnOnDemandPctIgnored      = "onDemandPctIgnored"
+	WarnOptimizeForIgnored      = "optimizeForIgnored"
+	WarnResourceOvershoot       = "resourceOvershoot"
+	WarnPodCapacityAdjusted     = "podCapacityAdjusted"
+	WarnOnDemandPctRaised       = "onDemandPctRaised"
+	WarnGpuZonesUnknown         = "gpuZonesUnknown"
+	WarnGpuCapacityReservation  = "gpuCapacityReservation"
+	WarnSpotRuntimeExceeded     = "spotRuntimeExceeded"
+	WarnPinnedLayoutExtended    = "pinnedLayoutExtended"
+	WarnSpotPriceHistoryMissing = "spotPriceHistoryMissing"
+	WarnInterruptionsUnknown    = "interruptionsUnknown"
+	WarnDrainSuggested          = "drainSuggested"
+	WarnSpotCoverageLow         = "spotCoverageLow"
+	WarnRebalanceCostLimited    = "rebalanceCostLimited"
+	WarnNodeCostsUnknown        = "nodeCostsUnknown"
+	WarnSpotPriceImplausible    = "spotPriceImplausible"
+	WarnSpotDisabled            = "spotDisabled"
+	WarnCostConfidenceUnknown   = "costConfidenceUnknown"
+	WarnOnDemandPctNotMet       = "onDemandPctNotMet"
+	WarnTypeDeprecated          = "typeDeprecated"
+	WarnDistinctTypesExceeded   = "distinctTypesExceeded"
+	WarnReservationExpiring     = "reservationExpiring"
+	WarnPodOverheadIgnored      = "podOverheadIgnored"
 )
 
 // ClusterRecommender is the main entry point for cluster recommendation
@@ -41,16 +75,45 @@ type ClusterRecommender interface {
 
 	// RecommendMultiCluster performs recommendations
 	RecommendMultiCluster(req MultiClusterRecommendationReq) (map[string][]*ClusterRecommendationResp, error)
+
+	// RecommendMultiRegion performs recommendations for a cluster spread across regions
+	RecommendMultiRegion(provider string, service string, req MultiRegionRecommendationReq) (*MultiRegionRecommendationResp, error)
+
+	// Backtest replays a layout against the spot price history
+	Backtest(provider string, service string, region string, req BacktestReq) (*Backtest, error)
+
+	// Candidates lists the vms the node pools of the requested cluster would be selected from, per attribute
+	Candidates(provider string, service string, region string, req SingleClusterRecommendationReq) (*CandidatesResp, error)
+
+	// DebugVm explains why the vm type was excluded from the candidates of the requested cluster
+	DebugVm(provider string, service string, region string, req SingleClusterRecommendationReq, vmType string) (*VmDebug, error)
+
+	// Rebalance recommends moving the nodes of an existing layout between the zones to even out their distribution
+	Rebalance(provider string, service string, region string, req RebalanceReq) (*Rebalance, error)
+
+	// Replacements finds the closest current generation equivalents of the instance types
+	Replacements(provider string, service string, region string, req ReplacementsReq) (*Replacements, error)
+
+	// Metadata collects the providers, services, regions and zones in a single payload
+	Metadata() (*Metadata, error)
+
+	// AttributeValues lists the distinct values of the attribute of the instance types of the region
+	AttributeValues(provider string, service string, region string, attr string, units Units) (*AttributeValues, error)
 }
 
 type VmRecommender interface {
 	RecommendVms(provider string, vms []VirtualMachine, attr string, req SingleClusterRecommendationReq, layout []NodePool) ([]VirtualMachine, []VirtualMachine, error)
 
 	FindVmsWithAttrValues(attr string, req SingleClusterRecommendationReq, layoutDesc []NodePoolDesc, allProducts []VirtualMachine) ([]VirtualMachine, error)
+
+	// RejectingFilters lists the names of the filters turned on by the request that reject the vm for the attribute
+	RejectingFilters(provider string, vm VirtualMachine, attr string, req SingleClusterRecommendationReq) ([]string, error)
 }
 
 type NodePoolRecommender interface {
-	RecommendNodePools(attr string, req SingleClusterRecommendationReq, layout []NodePool, odVms []VirtualMachine, spotVms []VirtualMachine) []NodePool
+	RecommendNodePools(service string, attr string, req SingleClusterRecommendationReq, layout []NodePool, odVms []VirtualMachine, spotVms []VirtualMachine) []NodePool
+	// MaxPoolNodes gets the maximum number of nodes of a node pool of the service, 0 if unlimited
+	MaxPoolNodes(service string) int
 }
 
 // SingleClusterRecommendationReq encapsulates the recommendation input data
@@ -64,13 +127,27 @@ type SingleClusterRecommendationReq struct {
 	Includes []string `json:"includes,omitempty"`
 	// Availability zone that the cluster should expand to
 	Zone string `json:"zone,omitempty"`
+	// ZoneWeights weights the zones of a multi-zone recommendation, eg. by the subnet capacity; the spot prices are
+	// averaged and the nodes of the node pools are split by the weights
+	ZoneWeights map[string]float64 `json:"zoneWeights,omitempty"`
+	// RequireAllZones refuses the recommendation if the worker nodes can't be placed in every zone weighted positively,
+	// instead of concentrating them in a subset of the zones
+	RequireAllZones bool `json:"requireAllZones,omitempty"`
+	// Profile is the name of the recommendation profile holding the defaults of the request
+	Profile string `json:"profile,omitempty"`
+	// Units of the memory of the request and the memory and prices of the response
+	Units Units `json:"units,omitempty"`
+	// suggestRemovals marks the dead weight node pools of the scale out layout to be drained
+	suggestRemovals bool
+	// spotOnly keeps the on-demand node pools of the scale out layout as they are, only spot nodes are added
+	spotOnly bool
 }
 
 // ClusterRecommendationReq encapsulates the recommendation input data
 type ClusterRecommendationReq struct {
 	// Total number of CPUs requested for the cluster
 	SumCpu float64 `json:"sumCpu" binding:"min=1"`
-	// Total memory requested for the cluster (GB)
+	// Total memory requested for the cluster (GiB, unless the units of the request say otherwise)
 	SumMem float64 `json:"sumMem" binding:"min=1"`
 	// Minimum number of nodes in the recommended cluster
 	MinNodes int `json:"minNodes,omitempty" binding:"min=1,ltefield=MaxNodes"`
@@ -80,16 +157,95 @@ type ClusterRecommendationReq struct {
 	SameSize bool `json:"sameSize,omitempty"`
 	// Percentage of regular (on-demand) nodes in the recommended cluster
 	OnDemandPct int `json:"onDemandPct,omitempty" binding:"min=0,max=100"`
+	// Number of regular (on-demand) nodes in the recommended cluster, an alternative to OnDemandPct
+	OnDemandCount int `json:"onDemandCount,omitempty" binding:"min=0"`
 	// Total number of GPUs requested for the cluster
 	SumGpu int `json:"sumGpu,omitempty"`
+	// GpuType restricts the recommendation to instance types with the given accelerator model (eg. nvidia-t4)
+	GpuType string `json:"gpuType,omitempty"`
+	// MinGpuMemPerGpu restricts the recommendation to instance types with accelerators having at least the given memory (GB)
+	MinGpuMemPerGpu float64 `json:"minGpuMemPerGpu,omitempty" binding:"min=0"`
 	// Are burst instances allowed in recommendation
 	AllowBurst *bool `json:"allowBurst,omitempty"`
+	// RequireNitro restricts the recommendation to instance types built on the Nitro system (applies for EC2 only)
+	RequireNitro bool `json:"requireNitro,omitempty"`
+	// RequireEna restricts the recommendation to instance types supporting the Elastic Network Adapter (applies for EC2 only)
+	RequireEna bool `json:"requireEna,omitempty"`
+	// RequireIpv6 restricts the recommendation to instance types supporting IPv6 addresses (applies for EC2 only)
+	RequireIpv6 bool `json:"requireIpv6,omitempty"`
 	// NetworkPerf specifies the network performance category
 	NetworkPerf []string `json:"networkPerf" binding:"omitempty,dive,networkPerf"`
+	// MinNetworkGbps restricts the recommendation to instance types with at least the given guaranteed network bandwidth (Gbps)
+	MinNetworkGbps float64 `json:"minNetworkGbps,omitempty" binding:"min=0"`
 	// AllowOlderGen allow older generations of virtual machines (applies for EC2 only)
 	AllowOlderGen *bool `json:"allowOlderGen,omitempty"`
 	// Category specifies the virtual machine category
 	Category []string `json:"category" binding:"omitempty,dive,category"`
+	// Family restricts the recommendation to the family trees of the listed instance families (eg. m5, including m5a or m5d)
+	Family InstanceFamilies `json:"family,omitempty"`
+	// OptimizeFor specifies the unit the cpu price is compared by, "benchmark" uses benchmark scores instead of cpus
+	OptimizeFor string `json:"optimizeFor,omitempty" binding:"omitempty,optimizeFor"`
+	// Metadata is an opaque map echoed in the response, eg. to correlate recommendations with tenants or projects
+	Metadata map[string]string `json:"metadata,omitempty"`
+	// Total number of pods the recommended cluster should be able to schedule
+	SumPods int `json:"sumPods,omitempty" binding:"min=0"`
+	// Overhead is the resources used per pod (with sumPods) and per node beside the requested ones, eg. by the
+	// sidecars of a service mesh
+	Overhead Overhead `json:"overhead,omitempty"`
+	// Resiliency level of the recommended cluster (low, medium or high), drives the spot diversification
+	Resiliency string `json:"resiliency,omitempty" binding:"omitempty,resiliency"`
+	// NodeImage is the operating system image of the nodes (ubuntu, rhel, sles or bottlerocket), paid images add license fees to the prices
+	NodeImage string `json:"nodeImage,omitempty" binding:"omitempty,nodeImage"`
+	// CandidateWindow widens the range of vm sizes considered for the recommendation
+	CandidateWindow CandidateWindow `json:"candidateWindow,omitempty"`
+	// Reservations lists the unused reserved capacity, preferred when filling the regular node pools
+	Reservations []Reservation `json:"reservations,omitempty" binding:"omitempty,dive"`
+	// Minimum number of CPUs per node, overrides the one derived from the total CPUs and the maximum number of nodes
+	MinCpuPerNode float64 `json:"minCpuPerNode,omitempty" binding:"min=0"`
+	// Maximum number of CPUs per node, overrides the one derived from the total CPUs and the minimum number of nodes
+	MaxCpuPerNode float64 `json:"maxCpuPerNode,omitempty" binding:"min=0"`
+	// Minimum memory per node (GiB by default), overrides the one derived from the total memory and the maximum number of nodes
+	MinMemPerNode float64 `json:"minMemPerNode,omitempty" binding:"min=0"`
+	// Maximum memory per node (GiB by default), overrides the one derived from the total memory and the minimum number of nodes
+	MaxMemPerNode float64 `json:"maxMemPerNode,omitempty" binding:"min=0"`
+	// AttributeNodes overrides the node count bounds for the node pools recommended for an attribute (cpu or memory)
+	AttributeNodes map[string]NodeBounds `json:"attributeNodes,omitempty"`
+	// Minimum number of hours the nodes must be able to run, spot instances preempted earlier (eg. after 24h on GCP) are not recommended
+	MinSpotRuntimeHours int `json:"minSpotRuntimeHours,omitempty" binding:"min=0"`
+	// DisruptionBudget limits the share of the capacity on spot instances, in total and in a single node pool
+	DisruptionBudget DisruptionBudget `json:"disruptionBudget,omitempty"`
+	// AvoidRecentlyInterrupted deprioritizes the spot capacity pools (instance type and zone) interrupted recently
+	AvoidRecentlyInterrupted bool `json:"avoidRecentlyInterrupted,omitempty"`
+	// PreferLargerNodes biases the recommendation toward fewer, larger nodes, eg. for JVM monoliths or databases
+	PreferLargerNodes bool `json:"preferLargerNodes,omitempty"`
+	// MaxDistinctTypes limits the number of distinct instance types of the worker node pools, eg. for provisioning tools
+	// managing only a handful of them; 0 means no limit
+	MaxDistinctTypes int `json:"maxDistinctTypes,omitempty" binding:"min=0"`
+	// PreferNewestGen prefers the newest generation families among the instance types of about the same price,
+	// for their better performance per cpu
+	PreferNewestGen bool `json:"preferNewestGen,omitempty"`
+	// NewestGenTolerancePct is the difference of the price per unit within which the prices are considered the same
+	// when the newest generation is preferred, 5% by default
+	NewestGenTolerancePct int `json:"newestGenTolerancePct,omitempty" binding:"min=0,max=100"`
+	// IncludeNodeCosts adds the costs tied to the number of nodes configured for the provider (eg. root volumes) to
+	// the prices, so layouts of many small nodes are compared to the ones of a few large nodes with their fixed costs
+	IncludeNodeCosts bool `json:"includeNodeCosts,omitempty"`
+	// IncludeCostConfidence adds the ranges the prices of the spot node pools and the total price are expected to be
+	// in, based on the variance of the spot price history
+	IncludeCostConfidence bool `json:"includeCostConfidence,omitempty"`
+}
+
+// HasOnDemand checks whether regular (on-demand) nodes are requested, either by percentage or by count
+func (r ClusterRecommendationReq) HasOnDemand() bool {
+	return r.OnDemandPct > 0 || r.OnDemandCount > 0
+}
+
+// CandidateWindow describes how the range of per node attribute values (vm sizes) derived from the request is widened
+type CandidateWindow struct {
+	// Number of values nearest to the range to consider on each side, in addition to the values in the range
+	NearestValues int `json:"nearestValues,omitempty" binding:"min=0"`
+	// Percentage by which the per node minimum is lowered and maximum is raised
+	RelaxPct int `json:"relaxPct,omitempty" binding:"min=0,max=100"`
 }
 
 // MultiClusterRecommendationReq encapsulates the recommendation input data
@@ -97,6 +253,8 @@ type ClusterRecommendationReq struct {
 type MultiClusterRecommendationReq struct {
 	Providers  []Provider `json:"providers" binding:"required"`
 	Continents []string   `json:"continents"`
+	// Geo restricts the regions of the recommendations by their location
+	Geo *GeoConstraint `json:"geo,omitempty"`
 	// Embedded struct
 	ClusterRecommendationReq
 	// Excludes is a blacklist - a slice with vm types to be excluded from the recommendation
@@ -105,6 +263,8 @@ type MultiClusterRecommendationReq struct {
 	Includes map[string]map[string][]string `json:"includes,omitempty"`
 	// Maximum number of response per service
 	RespPerService int `json:"respPerService" binding:"required"`
+	// Units of the memory of the request and the memory and prices of the response
+	Units Units `json:"units,omitempty"`
 }
 
 type Provider struct {
@@ -117,10 +277,12 @@ type Provider struct {
 type ClusterScaleoutRecommendationReq struct {
 	// Total desired number of CPUs in the cluster after the scale out
 	DesiredCpu float64 `json:"desiredCpu" binding:"min=1"`
-	// Total desired memory (GB) in the cluster after the scale out
+	// Total desired memory (GiB, unless the units of the request say otherwise) in the cluster after the scale out
 	DesiredMem float64 `json:"desiredMem" binding:"min=1"`
 	// Total desired number of GPUs in the cluster after the scale out
 	DesiredGpu int `json:"desiredGpu" binding:"min=0"`
+	// Total desired number of pods the cluster should be able to schedule after the scale out
+	DesiredPods int `json:"desiredPods,omitempty" binding:"min=0"`
 	// Percentage of regular (on-demand) nodes among the scale out nodes
 	OnDemandPct int `json:"onDemandPct,omitempty" binding:"min=0,max=100"`
 	// Availability zone to be included in the recommendation
@@ -130,22 +292,37 @@ type ClusterScaleoutRecommendationReq struct {
 	// Description of the current cluster layout
 	// in:body
 	ActualLayout []NodePoolDesc `json:"actualLayout" binding:"required"`
+	// Metadata is an opaque map echoed in the response, eg. to correlate recommendations with tenants or projects
+	Metadata map[string]string `json:"metadata,omitempty"`
+	// SuggestRemovals marks the node pools of the layout that should be drained: pools of instance types no longer offered,
+	// and spot pools whose spot price isn't below the on-demand price
+	SuggestRemovals bool `json:"suggestRemovals,omitempty"`
+	// SpotOnly reuses the existing on-demand capacity, the scale out grows the spot node pools only regardless of the on-demand percentage
+	SpotOnly bool `json:"spotOnly,omitempty"`
+	// Units of the memory of the request and the memory and prices of the response
+	Units Units `json:"units,omitempty"`
 }
 
 type NodePoolDesc struct {
 	// Instance type of VMs in the node pool
 	InstanceType string `json:"instanceType" binding:"required"`
-	// Signals that the node pool consists of regular or spot/preemptible instance types
+	// Signals that the node pool consists of regular, reserved or spot/preemptible instance types
 	VmClass string `json:"vmClass" binding:"required"`
 	// Number of VMs in the node pool
 	SumNodes int `json:"sumNodes" binding:"required"`
+	// Pinned keeps the instance type mix of the layout, the scale out only changes the node counts of the existing node pools where possible
+	Pinned bool `json:"pinned,omitempty"`
+	// OnDemandBaseCapacity is the number of regular nodes of a mixed node pool, filled before the spot percentage applies
+	OnDemandBaseCapacity int `json:"onDemandBaseCapacity,omitempty" binding:"min=0"`
+	// SpotPct is the percentage of spot nodes above the on-demand base capacity of a mixed node pool, the rest are regular
+	SpotPct int `json:"spotPct,omitempty" binding:"min=0,max=100"`
 	// TODO: AZ?
 	// Zones []string `json:"zones,omitempty" binding:"dive,zone"`
 }
 
 func (n *NodePoolDesc) GetVmClass() string {
 	switch n.VmClass {
-	case Regular, Spot:
+	case Regular, Spot, Reserved:
 		return n.VmClass
 	case Ondemand:
 		return Regular
@@ -168,6 +345,77 @@ type ClusterRecommendationResp struct {
 	NodePools []NodePool `json:"nodePools"`
 	// Accuracy of the recommendation
 	Accuracy ClusterRecommendationAccuracy `json:"accuracy"`
+	// Deterministic hash of the recommended node pool set, it only changes if the layout does
+	LayoutHash string `json:"layoutHash"`
+	// Freshness of the product and price data the recommendation is based on
+	DataFreshness *DataFreshness `json:"dataFreshness,omitempty"`
+	// ID of the snapshot of the products and prices the recommendation is based on, if snapshots are enabled
+	Snapshot string `json:"snapshot,omitempty"`
+	// Warnings about the request or the recommendation that API consumers should be aware of
+	Warnings []Warning `json:"warnings,omitempty"`
+	// Metadata echoed from the request
+	Metadata map[string]string `json:"metadata,omitempty"`
+	// Effective request the recommendation was made for, echoed if provider defaults or a profile were merged into it
+	Request *SingleClusterRecommendationReq `json:"request,omitempty"`
+	// Constraints applied for the requested resiliency level
+	Resiliency *ResiliencyConstraints `json:"resiliency,omitempty"`
+	// Estimated costs of the layout tied to its number of nodes, included in the prices if requested
+	NodeCosts []NodeCostEstimate `json:"nodeCosts,omitempty"`
+	// Usage of the reservations of the request by the recommended layout
+	ReservationUsage []ReservationUsage `json:"reservationUsage,omitempty"`
+	// Units of the memory and the prices of the recommendation, omitted for the default GiB and hourly prices
+	Units *Units `json:"units,omitempty"`
+	// Reasons the vm type requested for debugging was excluded from the candidates
+	VmDebug *VmDebug `json:"vmDebug,omitempty"`
+	// Number of calls made to the cloud info service for the recommendation, returned in a response header
+	CloudInfoCalls int `json:"-"`
+}
+
+// Warning describes a fact about the recommendation that may need attention
+type Warning struct {
+	// Machine readable code of the warning
+	Code string `json:"code"`
+	// Human readable description of the warning
+	Message string `json:"message"`
+}
+
+// DataFreshness describes how old the product and price data retrieved for a provider/service/region is
+type DataFreshness struct {
+	// The cloud provider
+	Provider string `json:"provider"`
+	// Provider's service
+	Service string `json:"service"`
+	// Service's region
+	Region string `json:"region"`
+	// Time the product details were last retrieved from cloud info
+	RetrievedAt time.Time `json:"retrievedAt"`
+	// Time the product details were last scraped by cloud info, omitted if cloud info doesn't report it
+	ScrapedAt *time.Time `json:"scrapedAt,omitempty"`
+	// Age of the product data in seconds
+	ProductDataAge float64 `json:"productDataAge"`
+	// Age of the spot prices in seconds, omitted if there are no spot prices in the region
+	SpotPriceAge *float64 `json:"spotPriceAge,omitempty"`
+	// Checksum of the full product catalog of the region as last retrieved by the catalog watcher, the recommendations
+	// made with the same catalog have the same version; omitted without the catalog watcher and from the status of
+	// the data freshness
+	CatalogVersion string `json:"catalogVersion,omitempty"`
+	// hasSpotPrices signals that spot prices were available when the data was retrieved
+	hasSpotPrices bool
+}
+
+// WithAges returns a copy of the freshness info with the ages computed relative to the given time
+func (df DataFreshness) WithAges(now time.Time) DataFreshness {
+	dataTime := df.RetrievedAt
+	if df.ScrapedAt != nil {
+		dataTime = *df.ScrapedAt
+	}
+	df.ProductDataAge = now.Sub(dataTime).Seconds()
+	df.SpotPriceAge = nil
+	if df.hasSpotPrices {
+		age := df.ProductDataAge
+		df.SpotPriceAge = &age
+	}
+	return df
 }
 
 // NodePool represents a set of instances with a specific vm type
@@ -180,9 +428,28 @@ type NodePool struct {
 	VmClass string `json:"vmClass"`
 	// Role in the cluster, eg. master or worker
 	Role string `json:"role"`
+	// Zones the node pool is pinned to, omitted if the node pool expands to all zones of the recommendation
+	Zones []string `json:"zones,omitempty"`
+	// ZoneNodes is the number of nodes per zone, split by the zone weights of the request
+	ZoneNodes map[string]int `json:"zoneNodes,omitempty"`
+	// How the instances of a spot node pool are reclaimed by the provider
+	Preemption *Preemption `json:"preemption,omitempty"`
+	// Signals that the node pool was pinned in the layout of a scale out
+	Pinned bool `json:"pinned,omitempty"`
+	// Labels of the nodes of the node pool, eg. added by the response processors of the deployment
+	Labels map[string]string `json:"labels,omitempty"`
+	// Weight is the capacity a node of the pool provides in the optimized attribute, in units of the smallest node
+	// of the recommendation; it maps to the weighted capacity of provider side allocation strategies
+	Weight int `json:"weight,omitempty"`
+	// SuggestedAction is the action suggested for the node pool of a scale out layout, eg. drain
+	SuggestedAction string `json:"suggestedAction,omitempty"`
+	// CostConfidence is the range the price of a spot node pool is expected to be in, if requested
+	CostConfidence *CostConfidence `json:"costConfidence,omitempty"`
+	// Deprecation signals that the instance type of the node pool is scheduled for deprecation
+	Deprecation *TypeDeprecation `json:"deprecation,omitempty"`
 }
 
-// PoolPrice calculates the price of the pool
+// PoolPrice calculates the price of the pool, reserved pools are already paid for so they cost nothing
 func (n *NodePool) PoolPrice() float64 {
 	var sum = float64(0)
 	switch n.VmClass {
@@ -223,34 +490,129 @@ type ClusterRecommendationAccuracy struct {
 	RecMasterPrice float64 `json:"masterPrice"`
 	// Total price in the recommended cluster
 	RecTotalPrice float64 `json:"totalPrice"`
+	// Estimated monthly bill of the recommended cluster, with the provider's billing model (eg. discounts) applied
+	RecMonthlyPrice float64 `json:"monthlyPrice"`
+	// Projected yearly bill of the recommended cluster, the monthly bill for twelve months
+	RecYearlyPrice float64 `json:"yearlyPrice"`
+	// Number of non-empty worker node pools in the recommended cluster
+	RecNodePools int `json:"nodePools"`
+	// Attribute (cpu or memory) the recommended node pools were selected for
+	RecAttribute string `json:"attribute,omitempty"`
+	// Worker node pools recommended for the attributes, the cheapest ones are selected
+	AttrLayouts []AttributeLayout `json:"attributeLayouts,omitempty"`
+	// Range the total price is expected to be in, given the ranges of the spot node pools, if requested
+	CostConfidence *CostConfidence `json:"costConfidence,omitempty"`
+}
+
+// AttributeLayout summarizes the worker node pools recommended for an attribute, as they were compared by the engine
+type AttributeLayout struct {
+	// Attribute (cpu or memory) the node pools were recommended for
+	Attribute string `json:"attribute"`
+	// Hourly price of the node pools
+	Price float64 `json:"price"`
+	// Number of nodes in the node pools
+	Nodes int `json:"nodes"`
+	// Signals the node pools that were recommended
+	Selected bool `json:"selected"`
+}
+
+// RecalculateAccuracy updates the accuracy of the recommendation after its node pools were changed
+func (r *ClusterRecommendationResp) RecalculateAccuracy() {
+	r.Accuracy = findResponseSum(r.Zone, r.NodePools, r.Accuracy.AttrLayouts)
+	r.Accuracy.RecMonthlyPrice = monthlyCost(r.Provider, r.NodePools)
+	r.Accuracy.RecYearlyPrice = yearlyCost(r.Provider, r.NodePools)
+	r.LayoutHash = layoutHash(r.NodePools)
+	updateCostConfidence(r.NodePools, &r.Accuracy)
+	for i := range r.NodeCosts {
+		r.NodeCosts[i].update(r.NodePools)
+	}
+}
+
+// FilterCapability describes a filter of the vm types taking part in the recommendation
+type FilterCapability struct {
+	// Name of the filter
+	Name string `json:"name"`
+	// Description of what the filter does
+	Description string `json:"description"`
+	// Providers the filter applies to, all providers if empty
+	Providers []string `json:"providers,omitempty"`
+	// Attributes (cpu or memory) the filter applies to, all attributes if empty
+	Attributes []string `json:"attributes,omitempty"`
+	// Request fields controlling the filter
+	Fields []string `json:"fields"`
 }
 
 // VirtualMachine describes an instance type
 type VirtualMachine struct {
 	// Average price of the instance (differs from on demand price in case of spot or preemptible instances)
 	AvgPrice float64 `json:"avgPrice"`
+	// Spot prices per zone
+	SpotPrices map[string]float64 `json:"spotPrices,omitempty"`
+	// Ratio of the zones the spot price is known in to the zones the instance type is offered in
+	SpotCoverage float64 `json:"spotCoverage,omitempty"`
 	// Regular price of the instance type
 	OnDemandPrice float64 `json:"onDemandPrice"`
 	// Number of CPUs in the instance type
 	Cpus float64 `json:"cpusPerVm"`
-	// Available memory in the instance type (GB)
+	// Number of CPUs reported by the provider, if it differs from the normalized number of CPUs (eg. shared-core types)
+	ReportedCpus float64 `json:"reportedCpusPerVm,omitempty"`
+	// Available memory in the instance type (GiB, unless the units of the request say otherwise)
 	Mem float64 `json:"memPerVm"`
 	// Number of GPUs in the instance type
 	Gpus float64 `json:"gpusPerVm"`
+	// GpuType holds the model of the attached accelerator, if known
+	GpuType string `json:"gpuType,omitempty"`
+	// GpuMemPerGpu holds the memory of an attached accelerator (GB), if known
+	GpuMemPerGpu float64 `json:"gpuMemPerGpu,omitempty"`
+	// CapacityReservationOnly signals that the instance type can only be launched into a capacity reservation
+	CapacityReservationOnly bool `json:"capacityReservationOnly,omitempty"`
 	// Burst signals a burst type instance
 	Burst bool `json:"burst"`
 	// CurrentGen the vm is of current generation
 	CurrentGen bool `json:"currentGen"`
+	// Nitro signals an instance type built on the Nitro system (amazon only)
+	Nitro bool `json:"nitro,omitempty"`
+	// Ena signals an instance type supporting the Elastic Network Adapter (amazon only)
+	Ena bool `json:"ena,omitempty"`
+	// Ipv6 signals an instance type supporting IPv6 addresses (amazon only)
+	Ipv6 bool `json:"ipv6,omitempty"`
 	// Zones
 	Zones []string `json:"zones"`
 	// Instance type category
 	Category string `json:"category"`
+	// Family of the instance type, if it's implied by the type name (eg. m5)
+	Family string `json:"family,omitempty"`
 	// Instance type
 	Type string `json:"type"`
 	// NetworkPerf holds the network performance
 	NetworkPerf string `json:"networkPerf"`
+	// CpuLabel and MemLabel hold the cpu and memory attributes as reported by the provider (eg. "3,904 GiB"), if known
+	CpuLabel string `json:"-"`
+	MemLabel string `json:"-"`
 	// NetworkPerfCat holds the network performance category
 	NetworkPerfCat string `json:"networkPerfCategory"`
+	// NetworkGbpsMin and NetworkGbpsMax hold the guaranteed and the peak network bandwidth in Gbps, if known
+	NetworkGbpsMin float64 `json:"networkGbpsMin,omitempty"`
+	NetworkGbpsMax float64 `json:"networkGbpsMax,omitempty"`
+	// BenchmarkScore holds the performance score of the instance type, if available
+	BenchmarkScore float64 `json:"benchmarkScore,omitempty"`
+	// MaxPods holds the maximum number of pods a node of this instance type can run
+	MaxPods int `json:"maxPods,omitempty"`
+	// InterruptionHeat holds how hot the spot capacity of the instance type is, based on the recent interruptions
+	InterruptionHeat float64 `json:"interruptionHeat,omitempty"`
+}
+
+// RankingPrice gets the average price the spot vms are ranked by, raised by the interruption heat
+func (v *VirtualMachine) RankingPrice() float64 {
+	return v.AvgPrice * (1 + v.InterruptionHeat)
+}
+
+// PricePerUnit gets the given price per attribute unit; for cpu it's the price per benchmark unit if requested and available
+func (v *VirtualMachine) PricePerUnit(price float64, attr string, optimizeFor string) float64 {
+	if attr == Cpu && optimizeFor == OptimizeForBenchmark && v.BenchmarkScore > 0 {
+		return price / v.BenchmarkScore
+	}
+	return price / v.GetAttrValue(attr)
 }
 
 func (v *VirtualMachine) GetAttrValue(attr string) float64 {
@@ -0,0 +1,161 @@
+// Copyright © 2019 Banzai Cloud
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package recommender
+
+// Units of the memory and the prices of the requests and the responses
+const (
+	// GiB is the unit of the memory of the instance types (2^30 bytes), the default
+	GiB = "GiB"
+	// GB is the decimal unit of memory (10^9 bytes)
+	GB = "GB"
+	// PerHour prices are the hourly prices of the instance types, the default
+	PerHour = "hour"
+	// PerMonth prices are the hourly prices for every hour of the month
+	PerMonth = "month"
+)
+
+// gbPerGiB is the number of GBs in a GiB
+const gbPerGiB = 1.073741824
+
+// Units selects the units of the memory and the prices of a recommendation
+type Units struct {
+	// Memory unit of the request and the response: GiB (the default, the instance types are specified in GiB) or GB
+	Memory string `json:"memory,omitempty" binding:"omitempty,memoryUnit"`
+	// Price unit of the response: hour (the default) or month
+	Price string `json:"price,omitempty" binding:"omitempty,priceUnit"`
+}
+
+// IsMemoryUnit checks whether the memory unit is supported
+func IsMemoryUnit(unit string) bool {
+	return unit == GiB || unit == GB
+}
+
+// IsPriceUnit checks whether the price unit is supported
+func IsPriceUnit(unit string) bool {
+	return unit == PerHour || unit == PerMonth
+}
+
+// IsZero checks whether the default units are selected
+func (u Units) IsZero() bool {
+	return u == Units{}
+}
+
+// memFactor gets the multiplier converting GiB to the memory unit
+func (u Units) memFactor() float64 {
+	if u.Memory == GB {
+		return gbPerGiB
+	}
+	return 1
+}
+
+// priceFactor gets the multiplier converting hourly prices to the price unit
+func (u Units) priceFactor() float64 {
+	if u.Price == PerMonth {
+		return HoursPerMonth
+	}
+	return 1
+}
+
+// NormalizeUnits converts the memory of the request to GiB, the unit of the instance types, so the request is
+// validated against and matched with the instance types in the same unit
+func (req *SingleClusterRecommendationReq) NormalizeUnits() {
+	req.ClusterRecommendationReq.normalizeMem(req.Units.memFactor())
+}
+
+// NormalizeUnits converts the memory of the multi-region request to GiB, before it's split between the regions
+func (req *MultiRegionRecommendationReq) NormalizeUnits() {
+	req.ClusterRecommendationReq.normalizeMem(req.Units.memFactor())
+}
+
+// NormalizeUnits converts the memory of the multicloud request to GiB, the unit of the instance types of every provider
+func (req *MultiClusterRecommendationReq) NormalizeUnits() {
+	req.ClusterRecommendationReq.normalizeMem(req.Units.memFactor())
+}
+
+// normalizeMem divides the memory of the request by the factor of its memory unit
+func (req *ClusterRecommendationReq) normalizeMem(f float64) {
+	req.SumMem /= f
+	req.MinMemPerNode /= f
+	req.MaxMemPerNode /= f
+	req.Overhead.PodMem /= f
+	req.Overhead.NodeMem /= f
+}
+
+// NormalizeUnits converts the memory of the scale out request to GiB, the unit of the instance types
+func (req *ClusterScaleoutRecommendationReq) NormalizeUnits() {
+	req.DesiredMem /= req.Units.memFactor()
+}
+
+// ConvertUnits converts the memory and the hourly prices of the recommendation to the units; it's the last step
+// before the recommendation is returned, the accuracy can't be recalculated from the converted node pools
+func (r *ClusterRecommendationResp) ConvertUnits(u Units) {
+	if u.IsZero() {
+		return
+	}
+	units := u
+	r.Units = &units
+
+	mf, pf := u.memFactor(), u.priceFactor()
+	for i := range r.NodePools {
+		vm := &r.NodePools[i].VmType
+		vm.Mem *= mf
+		vm.AvgPrice *= pf
+		vm.OnDemandPrice *= pf
+		if vm.SpotPrices != nil {
+			// the spot prices may be shared with the products
+			prices := make(map[string]float64, len(vm.SpotPrices))
+			for zone, price := range vm.SpotPrices {
+				prices[zone] = price * pf
+			}
+			vm.SpotPrices = prices
+		}
+		r.NodePools[i].CostConfidence = r.NodePools[i].CostConfidence.convert(pf)
+	}
+	r.Accuracy.convertUnits(mf, pf)
+}
+
+// ConvertUnits converts the memory and the hourly prices of the regional recommendations and their combined accuracy
+// to the units
+func (r *MultiRegionRecommendationResp) ConvertUnits(u Units) {
+	if u.IsZero() {
+		return
+	}
+	units := u
+	r.Units = &units
+
+	for i := range r.Regions {
+		r.Regions[i].ConvertUnits(u)
+	}
+	r.Accuracy.convertUnits(u.memFactor(), u.priceFactor())
+}
+
+// convertUnits multiplies the memory of the accuracy by the memory factor and its hourly prices by the price factor
+func (acc *ClusterRecommendationAccuracy) convertUnits(mf, pf float64) {
+	acc.RecMem *= mf
+	acc.RecRegularPrice *= pf
+	acc.RecSpotPrice *= pf
+	acc.RecWorkerPrice *= pf
+	acc.RecMasterPrice *= pf
+	acc.RecTotalPrice *= pf
+	acc.CostConfidence = acc.CostConfidence.convert(pf)
+	if acc.AttrLayouts != nil {
+		layouts := make([]AttributeLayout, len(acc.AttrLayouts))
+		for i, layout := range acc.AttrLayouts {
+			layout.Price *= pf
+			layouts[i] = layout
+		}
+		acc.AttrLayouts = layouts
+	}
+}
@@ -0,0 +1,149 @@
+// Copyright © 2019 Banzai Cloud
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package recommender
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSingleClusterRecommendationReq_NormalizeUnits(t *testing.T) {
+	tests := []struct {
+		name  string
+		units Units
+		check func(req SingleClusterRecommendationReq)
+	}{
+		{
+			name: "GiB by default",
+			check: func(req SingleClusterRecommendationReq) {
+				assert.Equal(t, float64(64), req.SumMem)
+				assert.Equal(t, float64(8), req.MinMemPerNode)
+				assert.Equal(t, float64(0), req.MaxMemPerNode)
+			},
+		},
+		{
+			name:  "GB converted to GiB",
+			units: Units{Memory: GB},
+			check: func(req SingleClusterRecommendationReq) {
+				assert.InDelta(t, 59.6046, req.SumMem, 0.0001)
+				assert.InDelta(t, 7.4506, req.MinMemPerNode, 0.0001)
+				assert.Equal(t, float64(0), req.MaxMemPerNode)
+				assert.InDelta(t, 0.9313, req.Overhead.NodeMem, 0.0001)
+			},
+		},
+	}
+	for _, test := range tests {
+		test := test
+		t.Run(test.name, func(t *testing.T) {
+			req := SingleClusterRecommendationReq{
+				ClusterRecommendationReq: ClusterRecommendationReq{SumCpu: 16, SumMem: 64, MinMemPerNode: 8, Overhead: Overhead{NodeMem: 1}},
+				Units:                    test.units,
+			}
+			req.NormalizeUnits()
+			test.check(req)
+		})
+	}
+}
+
+func TestClusterRecommendationResp_ConvertUnits(t *testing.T) {
+	spotPrices := map[string]float64{"eu-west-1a": 0.03}
+	newResp := func() *ClusterRecommendationResp {
+		resp := &ClusterRecommendationResp{
+			Provider: "amazon",
+			NodePools: []NodePool{
+				{VmType: VirtualMachine{Type: "m5.xlarge", Cpus: 4, Mem: 16, OnDemandPrice: 0.2}, SumNodes: 1, VmClass: Regular, Role: Worker},
+				{VmType: VirtualMachine{Type: "m5.large", Cpus: 2, Mem: 8, OnDemandPrice: 0.1, AvgPrice: 0.03, SpotPrices: spotPrices},
+					SumNodes: 2, VmClass: Spot, Role: Worker},
+			},
+		}
+		resp.RecalculateAccuracy()
+		return resp
+	}
+
+	tests := []struct {
+		name  string
+		units Units
+		check func(resp *ClusterRecommendationResp)
+	}{
+		{
+			name: "default units",
+			check: func(resp *ClusterRecommendationResp) {
+				assert.Equal(t, newResp(), resp)
+			},
+		},
+		{
+			name:  "GB and monthly prices",
+			units: Units{Memory: GB, Price: PerMonth},
+			check: func(resp *ClusterRecommendationResp) {
+				assert.Equal(t, &Units{Memory: GB, Price: PerMonth}, resp.Units)
+				assert.InDelta(t, 17.1799, resp.NodePools[0].VmType.Mem, 0.0001)
+				assert.InDelta(t, 146, resp.NodePools[0].VmType.OnDemandPrice, 0.0001)
+				assert.InDelta(t, 21.9, resp.NodePools[1].VmType.AvgPrice, 0.0001)
+				assert.InDelta(t, 21.9, resp.NodePools[1].VmType.SpotPrices["eu-west-1a"], 0.0001)
+				assert.Equal(t, 0.03, spotPrices["eu-west-1a"], "the spot prices of the products should be kept")
+				assert.InDelta(t, 32*gbPerGiB, resp.Accuracy.RecMem, 0.0001)
+				assert.InDelta(t, 0.26*HoursPerMonth, resp.Accuracy.RecTotalPrice, 0.0001)
+				assert.InDelta(t, 0.26*HoursPerMonth, resp.Accuracy.RecMonthlyPrice, 0.0001, "the monthly price should be kept")
+			},
+		},
+	}
+	for _, test := range tests {
+		test := test
+		t.Run(test.name, func(t *testing.T) {
+			resp := newResp()
+			resp.ConvertUnits(test.units)
+			test.check(resp)
+		})
+	}
+}
+
+func TestMultiRegionRecommendationReq_NormalizeUnits(t *testing.T) {
+	req := MultiRegionRecommendationReq{
+		ClusterRecommendationReq: ClusterRecommendationReq{SumCpu: 16, SumMem: 64, MaxMemPerNode: 16},
+		Units:                    Units{Memory: GB},
+	}
+	req.NormalizeUnits()
+
+	assert.InDelta(t, 59.6046, req.SumMem, 0.0001)
+	assert.InDelta(t, 14.9012, req.MaxMemPerNode, 0.0001)
+}
+
+func TestMultiRegionRecommendationResp_ConvertUnits(t *testing.T) {
+	regions := make([]ClusterRecommendationResp, 2)
+	for i, region := range []string{"eu-west-1", "eu-central-1"} {
+		regions[i] = ClusterRecommendationResp{
+			Provider: "amazon",
+			Region:   region,
+			NodePools: []NodePool{
+				{VmType: VirtualMachine{Type: "m5.xlarge", Cpus: 4, Mem: 16, OnDemandPrice: 0.2}, SumNodes: 1, VmClass: Regular, Role: Worker},
+			},
+		}
+		regions[i].RecalculateAccuracy()
+	}
+	resp := &MultiRegionRecommendationResp{Provider: "amazon", Regions: regions}
+	resp.RecalculateAccuracy()
+
+	resp.ConvertUnits(Units{Memory: GB, Price: PerMonth})
+
+	assert.Equal(t, &Units{Memory: GB, Price: PerMonth}, resp.Units)
+	for _, region := range resp.Regions {
+		assert.Equal(t, &Units{Memory: GB, Price: PerMonth}, region.Units)
+		assert.InDelta(t, 17.1799, region.NodePools[0].VmType.Mem, 0.0001)
+		assert.InDelta(t, 0.2*HoursPerMonth, region.Accuracy.RecTotalPrice, 0.0001)
+	}
+	assert.InDelta(t, 32*gbPerGiB, resp.Accuracy.RecMem, 0.0001)
+	assert.InDelta(t, 0.4*HoursPerMonth, resp.Accuracy.RecTotalPrice, 0.0001)
+}
@@ -0,0 +1,223 @@
+// Copyright © 2019 Banzai Cloud
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package recommender
+
+import (
+	"sort"
+	"sync"
+	"time"
+)
+
+// kinds of the recommendations tracked in the usage records
+const (
+	UsageCluster     = "cluster"
+	UsageScaleOut    = "scaleOut"
+	UsageMultiCloud  = "multicloud"
+	UsageMultiRegion = "multiregion"
+)
+
+// topUsageCount is the number of providers and regions listed in the usage summary
+const topUsageCount = 5
+
+// UsageRecord describes a recommendation served
+type UsageRecord struct {
+	// Time the recommendation was served
+	Time time.Time
+	// Kind of the recommendation: cluster, scaleOut, multicloud or multiregion
+	Kind string
+	// Providers of the recommended layouts
+	Providers []string
+	// Regions of the recommended layouts
+	Regions []string
+	// Total number of CPUs requested
+	SumCpu float64
+	// Total memory requested (GB)
+	SumMem float64
+	// Percentage of spot nodes in the recommended layouts
+	SpotPct float64
+}
+
+// UsageOf creates the usage record of a recommendation consisting of the given responses
+func UsageOf(kind string, sumCpu, sumMem float64, responses ...ClusterRecommendationResp) UsageRecord {
+	record := UsageRecord{Time: time.Now(), Kind: kind, SumCpu: sumCpu, SumMem: sumMem}
+
+	var sumNodes, spotNodes int
+	for _, resp := range responses {
+		record.Providers = appendDistinct(record.Providers, resp.Provider)
+		record.Regions = appendDistinct(record.Regions, resp.Region)
+		for _, np := range resp.NodePools {
+			sumNodes += np.SumNodes
+			if np.VmClass == Spot {
+				spotNodes += np.SumNodes
+			}
+		}
+	}
+	if sumNodes > 0 {
+		record.SpotPct = float64(spotNodes) / float64(sumNodes) * 100
+	}
+	return record
+}
+
+func appendDistinct(values []string, value string) []string {
+	for _, v := range values {
+		if v == value {
+			return values
+		}
+	}
+	return append(values, value)
+}
+
+// UsageStore stores the usage records of the recommendations
+type UsageStore interface {
+	// Record stores the usage record
+	Record(record UsageRecord)
+	// Since lists the records made since the given time, in the order they were made
+	Since(t time.Time) []UsageRecord
+	// Window gets the time the records are kept for
+	Window() time.Duration
+}
+
+// inMemoryUsageStore is a UsageStore keeping the records of a sliding window in memory
+type inMemoryUsageStore struct {
+	mux     sync.RWMutex
+	window  time.Duration
+	records []UsageRecord
+}
+
+// NewInMemoryUsageStore creates a usage store keeping the records of the given sliding window in memory, the records are lost on restart
+func NewInMemoryUsageStore(window time.Duration) UsageStore {
+	return &inMemoryUsageStore{
+		window: window,
+	}
+}
+
+func (s *inMemoryUsageStore) Record(record UsageRecord) {
+	s.mux.Lock()
+	defer s.mux.Unlock()
+
+	records := s.since(record.Time.Add(-s.window))
+	// records of concurrent recommendations may arrive slightly out of order
+	i := sort.Search(len(records), func(i int) bool {
+		return records[i].Time.After(record.Time)
+	})
+	records = append(records, UsageRecord{})
+	copy(records[i+1:], records[i:])
+	records[i] = record
+	s.records = records
+}
+
+func (s *inMemoryUsageStore) Since(t time.Time) []UsageRecord {
+	s.mux.RLock()
+	defer s.mux.RUnlock()
+
+	return append([]UsageRecord(nil), s.since(t)...)
+}
+
+func (s *inMemoryUsageStore) Window() time.Duration {
+	return s.window
+}
+
+// since gets the records made since the given time; the records are ordered by time
+func (s *inMemoryUsageStore) since(t time.Time) []UsageRecord {
+	i := sort.Search(len(s.records), func(i int) bool {
+		return !s.records[i].Time.Before(t)
+	})
+	return s.records[i:]
+}
+
+// UsageSummary summarizes the recommendations served over a window
+type UsageSummary struct {
+	// Start of the window
+	Since time.Time `json:"since"`
+	// Number of recommendations served
+	Recommendations int `json:"recommendations"`
+	// Number of recommendations served per kind (cluster, scaleOut, multicloud or multiregion)
+	Kinds map[string]int `json:"kinds"`
+	// Providers recommended for the most
+	TopProviders []UsageCount `json:"topProviders"`
+	// Regions recommended for the most
+	TopRegions []UsageCount `json:"topRegions"`
+	// Average number of CPUs requested
+	AvgSumCpu float64 `json:"avgSumCpu"`
+	// Average memory requested (GB)
+	AvgSumMem float64 `json:"avgSumMem"`
+	// Percentage of the recommendations having spot nodes
+	SpotAdoptionPct float64 `json:"spotAdoptionPct"`
+	// Average percentage of spot nodes in the recommended layouts
+	AvgSpotPct float64 `json:"avgSpotPct"`
+}
+
+// UsageCount is the number of recommendations of a provider or region
+type UsageCount struct {
+	Name            string `json:"name"`
+	Recommendations int    `json:"recommendations"`
+}
+
+// SummarizeUsage summarizes the usage records of the window starting at the given time
+func SummarizeUsage(records []UsageRecord, since time.Time) UsageSummary {
+	summary := UsageSummary{
+		Since: since,
+		Kinds: make(map[string]int),
+	}
+	providers := make(map[string]int)
+	regions := make(map[string]int)
+
+	var spotRecs int
+	for _, record := range records {
+		summary.Recommendations++
+		summary.Kinds[record.Kind]++
+		for _, provider := range record.Providers {
+			providers[provider]++
+		}
+		for _, region := range record.Regions {
+			regions[region]++
+		}
+		summary.AvgSumCpu += record.SumCpu
+		summary.AvgSumMem += record.SumMem
+		summary.AvgSpotPct += record.SpotPct
+		if record.SpotPct > 0 {
+			spotRecs++
+		}
+	}
+
+	summary.TopProviders = topUsageCounts(providers)
+	summary.TopRegions = topUsageCounts(regions)
+	if n := float64(summary.Recommendations); n > 0 {
+		summary.AvgSumCpu /= n
+		summary.AvgSumMem /= n
+		summary.AvgSpotPct /= n
+		summary.SpotAdoptionPct = float64(spotRecs) / n * 100
+	}
+
+	return summary
+}
+
+// topUsageCounts gets the names with the most recommendations, ties are ordered by name
+func topUsageCounts(counts map[string]int) []UsageCount {
+	top := make([]UsageCount, 0, len(counts))
+	for name, count := range counts {
+		top = append(top, UsageCount{Name: name, Recommendations: count})
+	}
+	sort.Slice(top, func(i, j int) bool {
+		if top[i].Recommendations != top[j].Recommendations {
+			return top[i].Recommendations > top[j].Recommendations
+		}
+		return top[i].Name < top[j].Name
+	})
+	if len(top) > topUsageCount {
+		top = top[:topUsageCount]
+	}
+	return top
+}
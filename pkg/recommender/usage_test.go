@@ -0,0 +1,83 @@
+// Copyright © 2019 Banzai Cloud
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package recommender
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestUsageOf(t *testing.T) {
+	record := UsageOf(UsageMultiRegion, 32, 64,
+		ClusterRecommendationResp{Provider: "amazon", Region: "eu-west-1", NodePools: []NodePool{
+			{SumNodes: 3, VmClass: Regular},
+			{SumNodes: 1, VmClass: Spot},
+		}},
+		ClusterRecommendationResp{Provider: "amazon", Region: "eu-central-1", NodePools: []NodePool{
+			{SumNodes: 4, VmClass: Spot},
+		}},
+	)
+
+	assert.Equal(t, UsageMultiRegion, record.Kind)
+	assert.Equal(t, []string{"amazon"}, record.Providers)
+	assert.Equal(t, []string{"eu-west-1", "eu-central-1"}, record.Regions)
+	assert.Equal(t, 62.5, record.SpotPct)
+}
+
+func TestInMemoryUsageStore(t *testing.T) {
+	store := NewInMemoryUsageStore(time.Hour)
+	now := time.Now()
+
+	store.Record(UsageRecord{Time: now.Add(-2 * time.Hour), Kind: "old"})
+	store.Record(UsageRecord{Time: now.Add(-10 * time.Minute), Kind: "second"})
+	store.Record(UsageRecord{Time: now.Add(-20 * time.Minute), Kind: "first"})
+	store.Record(UsageRecord{Time: now, Kind: "third"})
+
+	kinds := func(records []UsageRecord) []string {
+		var kinds []string
+		for _, r := range records {
+			kinds = append(kinds, r.Kind)
+		}
+		return kinds
+	}
+	assert.Equal(t, []string{"first", "second", "third"}, kinds(store.Since(now.Add(-time.Hour))), "records out of the window should be dropped")
+	assert.Equal(t, []string{"second", "third"}, kinds(store.Since(now.Add(-15*time.Minute))))
+	assert.Equal(t, time.Hour, store.Window())
+}
+
+func TestSummarizeUsage(t *testing.T) {
+	since := time.Now().Add(-time.Hour)
+	summary := SummarizeUsage([]UsageRecord{
+		{Kind: UsageCluster, Providers: []string{"amazon"}, Regions: []string{"eu-west-1"}, SumCpu: 10, SumMem: 40, SpotPct: 50},
+		{Kind: UsageCluster, Providers: []string{"amazon"}, Regions: []string{"eu-central-1"}, SumCpu: 20, SumMem: 80},
+		{Kind: UsageMultiCloud, Providers: []string{"amazon", "google"}, Regions: []string{"eu-west-1", "europe-west1"}, SumCpu: 30, SumMem: 120, SpotPct: 100},
+	}, since)
+
+	assert.Equal(t, since, summary.Since)
+	assert.Equal(t, 3, summary.Recommendations)
+	assert.Equal(t, map[string]int{UsageCluster: 2, UsageMultiCloud: 1}, summary.Kinds)
+	assert.Equal(t, []UsageCount{{Name: "amazon", Recommendations: 3}, {Name: "google", Recommendations: 1}}, summary.TopProviders)
+	assert.Equal(t, UsageCount{Name: "eu-west-1", Recommendations: 2}, summary.TopRegions[0])
+	assert.Equal(t, 20.0, summary.AvgSumCpu)
+	assert.Equal(t, 80.0, summary.AvgSumMem)
+	assert.Equal(t, 50.0, summary.AvgSpotPct)
+	assert.InDelta(t, 66.67, summary.SpotAdoptionPct, 0.01)
+
+	empty := SummarizeUsage(nil, since)
+	assert.Equal(t, 0, empty.Recommendations)
+	assert.Empty(t, empty.TopProviders)
+}
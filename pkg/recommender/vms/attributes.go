@@ -84,3 +84,49 @@ func (av AttributeValues) SelectAttributeValues(min float64, max float64) ([]flo
 	}
 	return selectedValues, nil
 }
+
+// SelectNearestValues selects at most k values below the min and k values above the max, the ones closest to the limits
+func (av AttributeValues) SelectNearestValues(min float64, max float64, k int) []float64 {
+	var below, above []float64
+
+	av.sort()
+	for _, v := range av {
+		switch {
+		case v < min:
+			below = append(below, v)
+		case v > max:
+			above = append(above, v)
+		}
+	}
+
+	if len(below) > k {
+		below = below[len(below)-k:]
+	}
+	if len(above) > k {
+		above = above[:k]
+	}
+
+	return append(below, above...)
+}
+
+// SelectUpperValues selects the values in the upper half of the interval between min and max; the highest value is
+// selected if there are none
+func (av AttributeValues) SelectUpperValues(min float64, max float64) []float64 {
+	if len(av) == 0 {
+		return nil
+	}
+
+	av.sort()
+	mid := (min + max) / 2
+	var upper []float64
+	for _, v := range av {
+		if v >= mid {
+			upper = append(upper, v)
+		}
+	}
+
+	if len(upper) == 0 {
+		return []float64{av[len(av)-1]}
+	}
+	return upper
+}
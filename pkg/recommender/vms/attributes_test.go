@@ -86,3 +86,85 @@ func TestAttributeValues_SelectAttributeValues(t *testing.T) {
 		})
 	}
 }
+
+func TestAttributeValues_SelectNearestValues(t *testing.T) {
+	tests := []struct {
+		name     string
+		values   AttributeValues
+		min      float64
+		max      float64
+		k        int
+		expected []float64
+	}{
+		{
+			name:     "nearest values on both sides",
+			values:   AttributeValues{32, 1, 16, 2, 8, 4, 64},
+			min:      5,
+			max:      10,
+			k:        2,
+			expected: []float64{2, 4, 16, 32},
+		},
+		{
+			name:     "less values than requested on a side",
+			values:   AttributeValues{1, 2, 8, 16},
+			min:      5,
+			max:      10,
+			k:        3,
+			expected: []float64{1, 2, 16},
+		},
+		{
+			name:     "no values outside the limits",
+			values:   AttributeValues{6, 8},
+			min:      5,
+			max:      10,
+			k:        1,
+			expected: []float64{},
+		},
+	}
+	for _, test := range tests {
+		test := test
+		t.Run(test.name, func(t *testing.T) {
+			selected := test.values.SelectNearestValues(test.min, test.max, test.k)
+			assert.ElementsMatch(t, test.expected, selected, "invalid selection")
+		})
+	}
+}
+
+func TestAttributeValues_SelectUpperValues(t *testing.T) {
+	tests := []struct {
+		name     string
+		values   AttributeValues
+		min      float64
+		max      float64
+		expected []float64
+	}{
+		{
+			name:     "values in the upper half",
+			values:   AttributeValues{16, 2, 8, 4},
+			min:      2,
+			max:      16,
+			expected: []float64{16},
+		},
+		{
+			name:     "values on the midpoint",
+			values:   AttributeValues{4, 6, 8},
+			min:      4,
+			max:      8,
+			expected: []float64{6, 8},
+		},
+		{
+			name:     "no values in the upper half",
+			values:   AttributeValues{2, 3},
+			min:      2,
+			max:      16,
+			expected: []float64{3},
+		},
+	}
+	for _, test := range tests {
+		test := test
+		t.Run(test.name, func(t *testing.T) {
+			selected := test.values.SelectUpperValues(test.min, test.max)
+			assert.ElementsMatch(t, test.expected, selected, "invalid selection")
+		})
+	}
+}
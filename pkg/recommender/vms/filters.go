@@ -15,61 +15,256 @@
 package vms
 
 import (
+	"strings"
+
 	"github.com/banzaicloud/telescopes/pkg/recommender"
 	"github.com/goph/emperror"
-	"github.com/pkg/errors"
 )
 
 type vmFilter func(vm recommender.VirtualMachine, req recommender.SingleClusterRecommendationReq) bool
 
-// filtersForAttr returns the slice for
-func (s *vmSelector) filtersForAttr(attr string, provider string, req recommender.SingleClusterRecommendationReq) ([]vmFilter, error) {
-	var filters []vmFilter
-	// generic filters - not depending on providers and attributes
-	if len(req.Includes) != 0 {
-		filters = append(filters, s.includesFilter)
-	}
+// filterSpec registers a vm filter with the providers and attributes it applies to and the request fields controlling it
+type filterSpec struct {
+	recommender.FilterCapability
+	// enabled checks whether the request turns the filter on
+	enabled func(req recommender.SingleClusterRecommendationReq) bool
+	filter  func(s *vmSelector, vm recommender.VirtualMachine, req recommender.SingleClusterRecommendationReq) bool
+}
 
-	if len(req.Excludes) != 0 {
-		filters = append(filters, s.excludesFilter)
+// filterRegistry holds the vm filters in the order they are applied
+var filterRegistry = []filterSpec{
+	{
+		FilterCapability: recommender.FilterCapability{
+			Name:        "includes",
+			Description: "keeps the listed vm types only",
+			Fields:      []string{"includes"},
+		},
+		enabled: func(req recommender.SingleClusterRecommendationReq) bool { return len(req.Includes) != 0 },
+		filter:  (*vmSelector).includesFilter,
+	},
+	{
+		FilterCapability: recommender.FilterCapability{
+			Name:        "excludes",
+			Description: "removes the listed vm types",
+			Fields:      []string{"excludes"},
+		},
+		enabled: func(req recommender.SingleClusterRecommendationReq) bool { return len(req.Excludes) != 0 },
+		filter:  (*vmSelector).excludesFilter,
+	},
+	{
+		FilterCapability: recommender.FilterCapability{
+			Name:        "category",
+			Description: "keeps the vm types of the listed categories",
+			Fields:      []string{"category"},
+		},
+		enabled: func(req recommender.SingleClusterRecommendationReq) bool { return len(req.Category) != 0 },
+		filter:  (*vmSelector).categoryFilter,
+	},
+	{
+		FilterCapability: recommender.FilterCapability{
+			Name:        "family",
+			Description: "keeps the vm types of the family trees of the listed instance families",
+			Providers:   []string{"amazon", "google"},
+			Fields:      []string{"family"},
+		},
+		enabled: func(req recommender.SingleClusterRecommendationReq) bool { return len(req.Family) != 0 },
+		filter:  (*vmSelector).familyFilter,
+	},
+	{
+		FilterCapability: recommender.FilterCapability{
+			Name:        "zone",
+			Description: "keeps the vm types available in the zone",
+			Fields:      []string{"zone"},
+		},
+		enabled: func(req recommender.SingleClusterRecommendationReq) bool { return req.Zone != "" },
+		filter:  (*vmSelector).zonesFilter,
+	},
+	{
+		FilterCapability: recommender.FilterCapability{
+			Name:        "networkPerf",
+			Description: "keeps the vm types of the listed network performance categories",
+			Fields:      []string{"networkPerf"},
+		},
+		enabled: func(req recommender.SingleClusterRecommendationReq) bool { return len(req.NetworkPerf) != 0 },
+		filter:  (*vmSelector).ntwPerformanceFilter,
+	},
+	{
+		FilterCapability: recommender.FilterCapability{
+			Name:        "networkBandwidth",
+			Description: "keeps the vm types with at least the requested guaranteed network bandwidth",
+			Providers:   []string{"amazon", "google"},
+			Fields:      []string{"minNetworkGbps"},
+		},
+		enabled: func(req recommender.SingleClusterRecommendationReq) bool { return req.MinNetworkGbps > 0 },
+		filter:  (*vmSelector).ntwBandwidthFilter,
+	},
+	{
+		FilterCapability: recommender.FilterCapability{
+			Name:        "benchmark",
+			Description: "removes the vm types without benchmark score when optimizing for price per benchmark unit",
+			Fields:      []string{"optimizeFor"},
+		},
+		enabled: func(req recommender.SingleClusterRecommendationReq) bool {
+			return req.OptimizeFor == recommender.OptimizeForBenchmark
+		},
+		filter: (*vmSelector).benchmarkFilter,
+	},
+	{
+		FilterCapability: recommender.FilterCapability{
+			Name:        "gpuType",
+			Description: "keeps the vm types with the requested accelerator model",
+			Fields:      []string{"gpuType"},
+		},
+		enabled: func(req recommender.SingleClusterRecommendationReq) bool { return req.GpuType != "" },
+		filter:  (*vmSelector).gpuTypeFilter,
+	},
+	{
+		FilterCapability: recommender.FilterCapability{
+			Name:        "gpuMemory",
+			Description: "keeps the vm types with accelerators having at least the requested memory",
+			Fields:      []string{"minGpuMemPerGpu"},
+		},
+		enabled: func(req recommender.SingleClusterRecommendationReq) bool { return req.MinGpuMemPerGpu > 0 },
+		filter:  (*vmSelector).gpuMemoryFilter,
+	},
+	{
+		FilterCapability: recommender.FilterCapability{
+			Name:        "burst",
+			Description: "removes the burst vm types unless they are allowed",
+			Providers:   []string{"amazon"},
+			Fields:      []string{"allowBurst"},
+		},
+		enabled: func(req recommender.SingleClusterRecommendationReq) bool {
+			return req.AllowBurst != nil && !*req.AllowBurst
+		},
+		filter: (*vmSelector).burstFilter,
+	},
+	{
+		FilterCapability: recommender.FilterCapability{
+			Name:        "currentGen",
+			Description: "removes the vm types of older generations unless they are allowed",
+			Providers:   []string{"amazon"},
+			Fields:      []string{"allowOlderGen"},
+		},
+		enabled: func(req recommender.SingleClusterRecommendationReq) bool {
+			return req.AllowOlderGen == nil || !*req.AllowOlderGen
+		},
+		filter: (*vmSelector).currentGenFilter,
+	},
+	{
+		FilterCapability: recommender.FilterCapability{
+			Name:        "nitro",
+			Description: "keeps the vm types built on the Nitro system",
+			Providers:   []string{"amazon"},
+			Fields:      []string{"requireNitro"},
+		},
+		enabled: func(req recommender.SingleClusterRecommendationReq) bool { return req.RequireNitro },
+		filter:  (*vmSelector).nitroFilter,
+	},
+	{
+		FilterCapability: recommender.FilterCapability{
+			Name:        "ena",
+			Description: "keeps the vm types supporting the Elastic Network Adapter",
+			Providers:   []string{"amazon"},
+			Fields:      []string{"requireEna"},
+		},
+		enabled: func(req recommender.SingleClusterRecommendationReq) bool { return req.RequireEna },
+		filter:  (*vmSelector).enaFilter,
+	},
+	{
+		FilterCapability: recommender.FilterCapability{
+			Name:        "ipv6",
+			Description: "keeps the vm types supporting IPv6 addresses",
+			Providers:   []string{"amazon"},
+			Fields:      []string{"requireIpv6"},
+		},
+		enabled: func(req recommender.SingleClusterRecommendationReq) bool { return req.RequireIpv6 },
+		filter:  (*vmSelector).ipv6Filter,
+	},
+	{
+		FilterCapability: recommender.FilterCapability{
+			Name:        "minMemRatio",
+			Description: "removes the vm types with less memory per cpu than requested",
+			Attributes:  []string{recommender.Cpu},
+			Fields:      []string{"sumCpu", "sumMem"},
+		},
+		filter: (*vmSelector).minMemRatioFilter,
+	},
+	{
+		FilterCapability: recommender.FilterCapability{
+			Name:        "minCpuRatio",
+			Description: "removes the vm types with less cpu per memory than requested",
+			Attributes:  []string{recommender.Memory},
+			Fields:      []string{"sumCpu", "sumMem"},
+		},
+		filter: (*vmSelector).minCpuRatioFilter,
+	},
+}
+
+// Filters lists the capabilities of the vm filters, in the order they are applied
+func Filters() []recommender.FilterCapability {
+	capabilities := make([]recommender.FilterCapability, 0, len(filterRegistry))
+	for _, spec := range filterRegistry {
+		capabilities = append(capabilities, spec.FilterCapability)
 	}
+	return capabilities
+}
+
+// appliesTo checks whether the filter applies to the provider and attribute, an empty list means all of them
+func (f filterSpec) appliesTo(provider, attr string) bool {
+	return (len(f.Providers) == 0 || contains(f.Providers, provider)) &&
+		(len(f.Attributes) == 0 || contains(f.Attributes, attr))
+}
 
-	if len(req.Category) != 0 {
-		filters = append(filters, s.categoryFilter)
+// filtersForAttr returns the filters of the registry applying to the attribute and provider, turned on by the request
+func (s *vmSelector) filtersForAttr(attr string, provider string, req recommender.SingleClusterRecommendationReq) ([]vmFilter, error) {
+	specs, err := enabledFilters(attr, provider, req)
+	if err != nil {
+		return nil, err
 	}
 
-	if req.Zone != "" {
-		filters = append(filters, s.zonesFilter)
+	var filters []vmFilter
+	for _, spec := range specs {
+		filter := spec.filter
+		filters = append(filters, func(vm recommender.VirtualMachine, req recommender.SingleClusterRecommendationReq) bool {
+			return filter(s, vm, req)
+		})
 	}
 
-	if len(req.NetworkPerf) != 0 {
-		filters = append(filters, s.ntwPerformanceFilter)
+	s.log.Debug("filters are successfully registered", map[string]interface{}{"numberOfFilters": len(filters)})
+	return filters, nil
+}
+
+// RejectingFilters lists the names of the filters applying to the attribute and provider, turned on by the request,
+// that reject the vm
+func (s *vmSelector) RejectingFilters(provider string, vm recommender.VirtualMachine, attr string, req recommender.SingleClusterRecommendationReq) ([]string, error) {
+	specs, err := enabledFilters(attr, provider, req)
+	if err != nil {
+		return nil, err
 	}
 
-	// provider specific filters
-	switch provider {
-	case "amazon":
-		// burst is not allowed
-		if req.AllowBurst != nil && !*req.AllowBurst {
-			filters = append(filters, s.burstFilter)
-		}
-		if req.AllowOlderGen == nil || !*req.AllowOlderGen {
-			filters = append(filters, s.currentGenFilter)
+	var names []string
+	for _, spec := range specs {
+		if !spec.filter(s, vm, req) {
+			names = append(names, spec.Name)
 		}
 	}
+	return names, nil
+}
 
-	// attribute specific filters
-	switch attr {
-	case recommender.Cpu:
-		filters = append(filters, s.minMemRatioFilter)
-	case recommender.Memory:
-		filters = append(filters, s.minCpuRatioFilter)
-	default:
-		return nil, emperror.With(errors.New("unsupported attribute"), "attribute", attr)
+// enabledFilters returns the specs of the filters applying to the attribute and provider, turned on by the request
+func enabledFilters(attr string, provider string, req recommender.SingleClusterRecommendationReq) ([]filterSpec, error) {
+	if attr != recommender.Cpu && attr != recommender.Memory {
+		return nil, emperror.With(recommender.ErrUnsupportedAttribute, "attribute", attr)
 	}
 
-	s.log.Debug("filters are successfully registered", map[string]interface{}{"numberOfFilters": len(filters)})
-	return filters, nil
+	var specs []filterSpec
+	for _, spec := range filterRegistry {
+		if spec.appliesTo(provider, attr) && (spec.enabled == nil || spec.enabled(req)) {
+			specs = append(specs, spec)
+		}
+	}
+	return specs, nil
 }
 
 // filtersApply returns true if all the filters apply for the given vm
@@ -113,6 +308,27 @@ func (s *vmSelector) categoryFilter(vm recommender.VirtualMachine, req recommend
 	return s.contains(req.Category, vm.Category)
 }
 
+// benchmarkFilter removes instance types without benchmark score, they can't be compared by price per benchmark unit
+func (s *vmSelector) benchmarkFilter(vm recommender.VirtualMachine, req recommender.SingleClusterRecommendationReq) bool {
+	return vm.BenchmarkScore > 0
+}
+
+// gpuTypeFilter checks whether the vm has the accelerator model requested
+func (s *vmSelector) gpuTypeFilter(vm recommender.VirtualMachine, req recommender.SingleClusterRecommendationReq) bool {
+	return vm.Gpus > 0 && strings.EqualFold(vm.GpuType, req.GpuType)
+}
+
+// ntwBandwidthFilter checks whether the vm has the guaranteed network bandwidth requested, vms with unknown or
+// burstable bandwidth don't pass
+func (s *vmSelector) ntwBandwidthFilter(vm recommender.VirtualMachine, req recommender.SingleClusterRecommendationReq) bool {
+	return vm.NetworkGbpsMin >= req.MinNetworkGbps
+}
+
+// gpuMemoryFilter checks whether the accelerators of the vm have the memory requested, vms with unknown accelerator memory don't pass
+func (s *vmSelector) gpuMemoryFilter(vm recommender.VirtualMachine, req recommender.SingleClusterRecommendationReq) bool {
+	return vm.Gpus > 0 && vm.GpuMemPerGpu >= req.MinGpuMemPerGpu
+}
+
 // excludeFilter checks for the vm type in the request' exclude list, the filter  passes if the type is not excluded
 func (s *vmSelector) excludesFilter(vm recommender.VirtualMachine, req recommender.SingleClusterRecommendationReq) bool {
 	if s.contains(req.Excludes, vm.Type) {
@@ -143,6 +359,26 @@ func (s *vmSelector) filterSpots(vms []recommender.VirtualMachine) []recommender
 	return fvms
 }
 
+// familyFilter checks whether the vm belongs to the family tree of any of the requested families
+func (s *vmSelector) familyFilter(vm recommender.VirtualMachine, req recommender.SingleClusterRecommendationReq) bool {
+	return req.Family.Contains(vm.Family)
+}
+
+// nitroFilter checks whether the vm is built on the Nitro system (amazon only)
+func (s *vmSelector) nitroFilter(vm recommender.VirtualMachine, req recommender.SingleClusterRecommendationReq) bool {
+	return vm.Nitro
+}
+
+// enaFilter checks whether the vm supports the Elastic Network Adapter (amazon only)
+func (s *vmSelector) enaFilter(vm recommender.VirtualMachine, req recommender.SingleClusterRecommendationReq) bool {
+	return vm.Ena
+}
+
+// ipv6Filter checks whether the vm supports IPv6 addresses (amazon only)
+func (s *vmSelector) ipv6Filter(vm recommender.VirtualMachine, req recommender.SingleClusterRecommendationReq) bool {
+	return vm.Ipv6
+}
+
 // currentGenFilter removes instance types that are not the current generation (amazon only)
 func (s *vmSelector) currentGenFilter(vm recommender.VirtualMachine, req recommender.SingleClusterRecommendationReq) bool {
 	// filter by current generation
@@ -151,6 +387,10 @@ func (s *vmSelector) currentGenFilter(vm recommender.VirtualMachine, req recomme
 
 // contains is a helper function to check if a slice contains a string
 func (s *vmSelector) contains(slice []string, str string) bool {
+	return contains(slice, str)
+}
+
+func contains(slice []string, str string) bool {
 	for _, e := range slice {
 		if e == str {
 			return true
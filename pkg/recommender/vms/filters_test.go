@@ -19,6 +19,7 @@ import (
 
 	"github.com/banzaicloud/telescopes/pkg/recommender"
 	"github.com/goph/logur"
+	"github.com/pkg/errors"
 	"github.com/stretchr/testify/assert"
 )
 
@@ -118,6 +119,30 @@ func TestVmSelector_filtersApply(t *testing.T) {
 	}
 }
 
+func TestVmSelector_RejectingFilters(t *testing.T) {
+	selector := NewVmSelector(logur.NewTestLogger())
+	req := recommender.SingleClusterRecommendationReq{
+		ClusterRecommendationReq: recommender.ClusterRecommendationReq{
+			SumCpu:     4,
+			SumMem:     8,
+			AllowBurst: boolref(false),
+		},
+		Excludes: []string{"t3.xlarge"},
+	}
+	vm := recommender.VirtualMachine{Type: "t3.xlarge", Cpus: 4, Mem: 1, Burst: true, CurrentGen: true}
+
+	filters, err := selector.RejectingFilters("amazon", vm, recommender.Cpu, req)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"excludes", "burst", "minMemRatio"}, filters)
+
+	filters, err = selector.RejectingFilters("google", vm, recommender.Memory, req)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"excludes"}, filters, "the filters of other providers and attributes should not apply")
+
+	_, err = selector.RejectingFilters("amazon", vm, "gpu", req)
+	assert.Error(t, err)
+}
+
 func TestVmSelector_minCpuRatioFilter(t *testing.T) {
 	tests := []struct {
 		name  string
@@ -467,3 +492,254 @@ func TestVmSelector_currentGenFilter(t *testing.T) {
 		})
 	}
 }
+
+func TestVmSelector_benchmarkFilter(t *testing.T) {
+	tests := []struct {
+		name  string
+		vm    recommender.VirtualMachine
+		check func(passed bool)
+	}{
+		{
+			name: "filter should apply when vm has a benchmark score",
+			vm: recommender.VirtualMachine{
+				Type:           "instance type",
+				BenchmarkScore: 1200,
+			},
+			check: func(passed bool) {
+				assert.True(t, passed, "vm should pass the filter")
+			},
+		},
+		{
+			name: "filter should not apply when vm has no benchmark score",
+			vm: recommender.VirtualMachine{
+				Type: "instance type",
+			},
+			check: func(passed bool) {
+				assert.False(t, passed, "vm should not pass the filter")
+			},
+		},
+	}
+	for _, test := range tests {
+		test := test // scopelint
+		t.Run(test.name, func(t *testing.T) {
+			selector := NewVmSelector(logur.NewTestLogger())
+			test.check(selector.benchmarkFilter(test.vm, recommender.SingleClusterRecommendationReq{}))
+		})
+	}
+}
+
+func TestVmSelector_gpuTypeFilter(t *testing.T) {
+	tests := []struct {
+		name  string
+		vm    recommender.VirtualMachine
+		check func(passed bool)
+	}{
+		{
+			name: "filter should apply when the vm has the requested gpu type",
+			vm: recommender.VirtualMachine{
+				Type:    "g4dn.xlarge",
+				Gpus:    1,
+				GpuType: "nvidia-t4",
+			},
+			check: func(passed bool) {
+				assert.True(t, passed, "vm should pass the filter")
+			},
+		},
+		{
+			name: "filter should not apply when the vm has a different gpu type",
+			vm: recommender.VirtualMachine{
+				Type:    "p3.2xlarge",
+				Gpus:    1,
+				GpuType: "nvidia-v100",
+			},
+			check: func(passed bool) {
+				assert.False(t, passed, "vm should not pass the filter")
+			},
+		},
+		{
+			name: "filter should not apply when the vm has no gpus",
+			vm: recommender.VirtualMachine{
+				Type: "m5.xlarge",
+			},
+			check: func(passed bool) {
+				assert.False(t, passed, "vm should not pass the filter")
+			},
+		},
+	}
+	for _, test := range tests {
+		test := test // scopelint
+		t.Run(test.name, func(t *testing.T) {
+			selector := NewVmSelector(logur.NewTestLogger())
+			test.check(selector.gpuTypeFilter(test.vm, recommender.SingleClusterRecommendationReq{
+				ClusterRecommendationReq: recommender.ClusterRecommendationReq{GpuType: "NVIDIA-T4"},
+			}))
+		})
+	}
+}
+
+func TestVmSelector_gpuMemoryFilter(t *testing.T) {
+	tests := []struct {
+		name  string
+		vm    recommender.VirtualMachine
+		check func(passed bool)
+	}{
+		{
+			name: "filter should apply when the gpus of the vm have enough memory",
+			vm: recommender.VirtualMachine{
+				Type:         "g5.xlarge",
+				Gpus:         1,
+				GpuMemPerGpu: 24,
+			},
+			check: func(passed bool) {
+				assert.True(t, passed, "vm should pass the filter")
+			},
+		},
+		{
+			name: "filter should not apply when the gpus of the vm have less memory",
+			vm: recommender.VirtualMachine{
+				Type:         "g4dn.xlarge",
+				Gpus:         1,
+				GpuMemPerGpu: 16,
+			},
+			check: func(passed bool) {
+				assert.False(t, passed, "vm should not pass the filter")
+			},
+		},
+		{
+			name: "filter should not apply when the gpu memory of the vm is unknown",
+			vm: recommender.VirtualMachine{
+				Type: "Standard_NC6",
+				Gpus: 1,
+			},
+			check: func(passed bool) {
+				assert.False(t, passed, "vm should not pass the filter")
+			},
+		},
+	}
+	for _, test := range tests {
+		test := test // scopelint
+		t.Run(test.name, func(t *testing.T) {
+			selector := NewVmSelector(logur.NewTestLogger())
+			test.check(selector.gpuMemoryFilter(test.vm, recommender.SingleClusterRecommendationReq{
+				ClusterRecommendationReq: recommender.ClusterRecommendationReq{MinGpuMemPerGpu: 20},
+			}))
+		})
+	}
+}
+
+func TestVmSelector_ntwBandwidthFilter(t *testing.T) {
+	tests := []struct {
+		name  string
+		vm    recommender.VirtualMachine
+		check func(passed bool)
+	}{
+		{
+			name: "filter should apply when the vm has the guaranteed bandwidth",
+			vm:   recommender.VirtualMachine{Type: "c5n.2xlarge", NetworkGbpsMin: 25, NetworkGbpsMax: 25},
+			check: func(passed bool) {
+				assert.True(t, passed, "vm should pass the filter")
+			},
+		},
+		{
+			name: "filter should not apply when the bandwidth of the vm is burstable",
+			vm:   recommender.VirtualMachine{Type: "m5.large", NetworkGbpsMax: 10},
+			check: func(passed bool) {
+				assert.False(t, passed, "vm should not pass the filter")
+			},
+		},
+		{
+			name: "filter should not apply when the bandwidth of the vm is unknown",
+			vm:   recommender.VirtualMachine{Type: "Standard_D4s_v3"},
+			check: func(passed bool) {
+				assert.False(t, passed, "vm should not pass the filter")
+			},
+		},
+	}
+	for _, test := range tests {
+		test := test // scopelint
+		t.Run(test.name, func(t *testing.T) {
+			selector := NewVmSelector(logur.NewTestLogger())
+			test.check(selector.ntwBandwidthFilter(test.vm, recommender.SingleClusterRecommendationReq{
+				ClusterRecommendationReq: recommender.ClusterRecommendationReq{MinNetworkGbps: 10},
+			}))
+		})
+	}
+}
+
+func TestVmSelector_capabilityFilters(t *testing.T) {
+	selector := NewVmSelector(logur.NewTestLogger())
+	req := recommender.SingleClusterRecommendationReq{}
+	xen := recommender.VirtualMachine{Type: "r4.large", Ena: true, Ipv6: true}
+	nitro := recommender.VirtualMachine{Type: "r5.large", Nitro: true, Ena: true, Ipv6: true}
+
+	assert.False(t, selector.nitroFilter(xen, req), "xen vm should not pass the filter")
+	assert.True(t, selector.nitroFilter(nitro, req), "nitro vm should pass the filter")
+	assert.True(t, selector.enaFilter(xen, req), "vm should pass the filter")
+	assert.False(t, selector.enaFilter(recommender.VirtualMachine{Type: "c4.large"}, req), "vm should not pass the filter")
+	assert.True(t, selector.ipv6Filter(xen, req), "vm should pass the filter")
+	assert.False(t, selector.ipv6Filter(recommender.VirtualMachine{Type: "m3.large"}, req), "vm should not pass the filter")
+
+	filters, err := selector.filtersForAttr(recommender.Cpu, "amazon", recommender.SingleClusterRecommendationReq{
+		ClusterRecommendationReq: recommender.ClusterRecommendationReq{RequireNitro: true, RequireEna: true, RequireIpv6: true},
+	})
+	assert.Nil(t, err, "the error should be nil")
+	// nitro, ena, ipv6, currentGen and minMemRatio
+	assert.Len(t, filters, 5)
+
+	filters, err = selector.filtersForAttr(recommender.Memory, "google", recommender.SingleClusterRecommendationReq{
+		ClusterRecommendationReq: recommender.ClusterRecommendationReq{RequireNitro: true},
+	})
+	assert.Nil(t, err, "the error should be nil")
+	// minCpuRatio
+	assert.Len(t, filters, 1)
+}
+
+func TestVmSelector_familyFilter(t *testing.T) {
+	selector := NewVmSelector(logur.NewTestLogger())
+	req := recommender.SingleClusterRecommendationReq{
+		ClusterRecommendationReq: recommender.ClusterRecommendationReq{Family: recommender.InstanceFamilies{"m5"}},
+	}
+
+	assert.True(t, selector.familyFilter(recommender.VirtualMachine{Type: "m5.large", Family: "m5"}, req), "vm should pass the filter")
+	assert.True(t, selector.familyFilter(recommender.VirtualMachine{Type: "m5dn.large", Family: "m5dn"}, req), "variant should pass the filter")
+	assert.False(t, selector.familyFilter(recommender.VirtualMachine{Type: "m6i.large", Family: "m6i"}, req), "vm should not pass the filter")
+	assert.False(t, selector.familyFilter(recommender.VirtualMachine{Type: "Standard_D2s_v3"}, req), "vm of unknown family should not pass the filter")
+
+	filters, err := selector.filtersForAttr(recommender.Cpu, "azure", req)
+	assert.Nil(t, err, "the error should be nil")
+	// minMemRatio
+	assert.Len(t, filters, 1)
+}
+
+func TestFilters(t *testing.T) {
+	filters := Filters()
+	assert.Len(t, filters, len(filterRegistry))
+
+	names := make(map[string]bool)
+	for _, f := range filters {
+		assert.False(t, names[f.Name], "filter names should be distinct")
+		names[f.Name] = true
+		assert.NotEmpty(t, f.Fields, "the request fields of %s should be listed", f.Name)
+	}
+}
+
+func TestVmSelector_filtersForAttr(t *testing.T) {
+	selector := NewVmSelector(logur.NewTestLogger())
+	req := recommender.SingleClusterRecommendationReq{
+		ClusterRecommendationReq: recommender.ClusterRecommendationReq{AllowBurst: boolref(false), AllowOlderGen: boolref(true)},
+		Excludes:                 []string{"t2.micro"},
+	}
+
+	filters, err := selector.filtersForAttr(recommender.Cpu, "amazon", req)
+	assert.Nil(t, err, "the error should be nil")
+	// excludes, burst and minMemRatio
+	assert.Len(t, filters, 3)
+
+	filters, err = selector.filtersForAttr(recommender.Memory, "google", req)
+	assert.Nil(t, err, "the error should be nil")
+	// excludes and minCpuRatio
+	assert.Len(t, filters, 2)
+
+	_, err = selector.filtersForAttr("gpu", "amazon", req)
+	assert.Equal(t, recommender.ErrUnsupportedAttribute, errors.Cause(err))
+}
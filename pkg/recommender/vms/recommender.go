@@ -16,11 +16,11 @@ package vms
 
 import (
 	"fmt"
+	"sort"
 
 	"github.com/banzaicloud/telescopes/pkg/recommender"
 	"github.com/goph/emperror"
 	"github.com/goph/logur"
-	"github.com/pkg/errors"
 )
 
 type vmSelector struct {
@@ -63,6 +63,10 @@ func (s *vmSelector) RecommendVms(provider string,
 		odVms, spotVms = filteredVms, filteredVms
 	} else {
 		for _, np := range layout {
+			if np.VmClass == recommender.Reserved {
+				// reserved capacity is fixed, reserved pools are not scaled out
+				continue
+			}
 			for _, vm := range filteredVms {
 				if np.VmType.Type == vm.Type {
 					if np.VmClass == recommender.Regular {
@@ -121,7 +125,7 @@ func (s *vmSelector) FindVmsWithAttrValues(attr string,
 						included = true
 					}
 				default:
-					return nil, errors.New("unsupported attribute")
+					return nil, emperror.With(recommender.ErrUnsupportedAttribute, "attribute", attr)
 				}
 			}
 		}
@@ -152,17 +156,55 @@ func (s *vmSelector) recommendAttrValues(allProducts []recommender.VirtualMachin
 		allValues = append(allValues, attr)
 	}
 
-	s.log.Debug("selecting attributes", map[string]interface{}{"attribute": attr, "values": allValues})
-	values, err := AttributeValues(allValues).SelectAttributeValues(minValuePerVm(req, attr), maxValuePerVm(req, attr))
+	window := req.CandidateWindow
+	min, max := minValuePerVm(req, attr), maxValuePerVm(req, attr)
+	if min > max {
+		// one of the bounds is requested explicitly, it takes precedence over the derived one
+		if explicitMin, _ := req.PerNodeBounds(attr); explicitMin > 0 {
+			max = min
+		} else {
+			min = max
+		}
+	}
+	min *= 1 - float64(window.RelaxPct)/100
+	max *= 1 + float64(window.RelaxPct)/100
+
+	s.log.Debug("selecting attributes", map[string]interface{}{"attribute": attr, "values": allValues, "min": min, "max": max})
+	values, err := AttributeValues(allValues).SelectAttributeValues(min, max)
 	if err != nil {
 		return nil, emperror.With(err, recommender.RecommenderErrorTag, "attributes")
 	}
 
+	if req.PreferLargerNodes {
+		values = AttributeValues(values).SelectUpperValues(min, max)
+	}
+
+	if window.NearestValues > 0 {
+		values = mergeValues(values, AttributeValues(allValues).SelectNearestValues(min, max, window.NearestValues))
+	}
+
 	return values, nil
 }
 
-// maxValuePerVm calculates the maximum value per node for the given attribute
+// mergeValues merges the attribute values into a sorted slice without duplicates
+func mergeValues(values []float64, others []float64) []float64 {
+	valueSet := make(map[float64]bool)
+	merged := make([]float64, 0, len(values)+len(others))
+	for _, v := range append(values, others...) {
+		if !valueSet[v] {
+			valueSet[v] = true
+			merged = append(merged, v)
+		}
+	}
+	sort.Float64s(merged)
+	return merged
+}
+
+// maxValuePerVm calculates the maximum value per node for the given attribute, unless it's requested explicitly
 func maxValuePerVm(req recommender.SingleClusterRecommendationReq, attr string) float64 {
+	if _, max := req.PerNodeBounds(attr); max > 0 {
+		return max
+	}
 	switch attr {
 	case recommender.Cpu:
 		return req.SumCpu / float64(req.MinNodes)
@@ -173,8 +215,11 @@ func maxValuePerVm(req recommender.SingleClusterRecommendationReq, attr string)
 	}
 }
 
-// minValuePerVm calculates the minimum value per node for the given attribute
+// minValuePerVm calculates the minimum value per node for the given attribute, unless it's requested explicitly
 func minValuePerVm(req recommender.SingleClusterRecommendationReq, attr string) float64 {
+	if min, _ := req.PerNodeBounds(attr); min > 0 {
+		return min
+	}
 	switch attr {
 	case recommender.Cpu:
 		return req.SumCpu / float64(req.MaxNodes)
@@ -190,6 +190,93 @@ func TestVmSelector_recommendAttrValues(t *testing.T) {
 
 			},
 		},
+		{
+			name: "nearest values widen the recommended attribute values",
+			request: recommender.SingleClusterRecommendationReq{
+				ClusterRecommendationReq: recommender.ClusterRecommendationReq{
+					MinNodes:        5,
+					MaxNodes:        10,
+					SumMem:          100,
+					SumCpu:          100,
+					CandidateWindow: recommender.CandidateWindow{NearestValues: 1},
+				},
+			},
+			attribute: recommender.Cpu,
+			check: func(values []float64, err error) {
+				assert.Nil(t, err, "should not get error when recommending attributes")
+				assert.Equal(t, []float64{8, 16, 17, 32}, values, "recommended values are not as expected")
+			},
+		},
+		{
+			name: "relaxed bounds widen the recommended attribute values",
+			request: recommender.SingleClusterRecommendationReq{
+				ClusterRecommendationReq: recommender.ClusterRecommendationReq{
+					MinNodes:        5,
+					MaxNodes:        10,
+					SumMem:          100,
+					SumCpu:          100,
+					CandidateWindow: recommender.CandidateWindow{RelaxPct: 20},
+				},
+			},
+			attribute: recommender.Cpu,
+			check: func(values []float64, err error) {
+				assert.Nil(t, err, "should not get error when recommending attributes")
+				assert.Equal(t, []float64{8, 16, 17}, values, "recommended values are not as expected")
+			},
+		},
+		{
+			name: "explicit bounds per node override the derived ones",
+			request: recommender.SingleClusterRecommendationReq{
+				ClusterRecommendationReq: recommender.ClusterRecommendationReq{
+					MinNodes:      5,
+					MaxNodes:      10,
+					SumMem:        100,
+					SumCpu:        100,
+					MinCpuPerNode: 4,
+					MaxCpuPerNode: 8,
+				},
+			},
+			attribute: recommender.Cpu,
+			check: func(values []float64, err error) {
+				assert.Nil(t, err, "should not get error when recommending attributes")
+				assert.Equal(t, []float64{4, 8}, values, "recommended values are not as expected")
+			},
+		},
+		{
+			name: "explicit maximum per node below the derived minimum",
+			request: recommender.SingleClusterRecommendationReq{
+				ClusterRecommendationReq: recommender.ClusterRecommendationReq{
+					MinNodes:      5,
+					MaxNodes:      10,
+					SumMem:        100,
+					SumCpu:        100,
+					MaxCpuPerNode: 8,
+				},
+			},
+			attribute: recommender.Cpu,
+			check: func(values []float64, err error) {
+				assert.Nil(t, err, "should not get error when recommending attributes")
+				assert.Equal(t, []float64{8}, values, "recommended values are not as expected")
+			},
+		},
+		{
+			name: "larger nodes are preferred",
+			request: recommender.SingleClusterRecommendationReq{
+				ClusterRecommendationReq: recommender.ClusterRecommendationReq{
+					MinNodes:          5,
+					MaxNodes:          10,
+					SumMem:            100,
+					SumCpu:            100,
+					CandidateWindow:   recommender.CandidateWindow{RelaxPct: 20},
+					PreferLargerNodes: true,
+				},
+			},
+			attribute: recommender.Cpu,
+			check: func(values []float64, err error) {
+				assert.Nil(t, err, "should not get error when recommending attributes")
+				assert.Equal(t, []float64{16, 17}, values, "recommended values are not as expected")
+			},
+		},
 	}
 	for _, test := range tests {
 		test := test // scopelint
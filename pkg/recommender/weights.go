@@ -0,0 +1,38 @@
+// Copyright © 2019 Banzai Cloud
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package recommender
+
+import "math"
+
+// applyPoolWeights sets the weights of the worker node pools: the capacity units a node of the pool provides in the
+// optimized attribute, relative to the smallest node of the set, so provider side allocation strategies (eg. AWS
+// capacity-optimized-prioritized) fill the capacity the same way the node pools do
+func applyPoolWeights(attr string, nodePools []NodePool) {
+	var unit float64
+	for _, np := range nodePools {
+		if v := np.VmType.GetAttrValue(attr); np.Role == Worker && v > 0 && (unit == 0 || v < unit) {
+			unit = v
+		}
+	}
+	if unit == 0 {
+		return
+	}
+	for i, np := range nodePools {
+		if np.Role != Worker {
+			continue
+		}
+		nodePools[i].Weight = int(math.Max(1, math.Round(np.VmType.GetAttrValue(attr)/unit)))
+	}
+}
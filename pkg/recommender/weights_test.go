@@ -0,0 +1,36 @@
+// Copyright © 2019 Banzai Cloud
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package recommender
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_applyPoolWeights(t *testing.T) {
+	nodePools := []NodePool{
+		{VmType: VirtualMachine{Type: "m5.large", Cpus: 2, Mem: 8}, Role: Worker},
+		{VmType: VirtualMachine{Type: "m5.xlarge", Cpus: 4, Mem: 16}, Role: Worker},
+		{VmType: VirtualMachine{Type: "r5.2xlarge", Cpus: 8, Mem: 64}, Role: Worker},
+		{VmType: VirtualMachine{Type: "c5.large", Cpus: 2, Mem: 4}, Role: Master},
+	}
+
+	applyPoolWeights(Cpu, nodePools)
+	assert.Equal(t, []int{1, 2, 4, 0}, []int{nodePools[0].Weight, nodePools[1].Weight, nodePools[2].Weight, nodePools[3].Weight})
+
+	applyPoolWeights(Memory, nodePools)
+	assert.Equal(t, []int{1, 2, 8, 0}, []int{nodePools[0].Weight, nodePools[1].Weight, nodePools[2].Weight, nodePools[3].Weight})
+}
@@ -0,0 +1,178 @@
+// Copyright © 2019 Banzai Cloud
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package recommender
+
+import (
+	"math"
+	"sort"
+
+	"github.com/goph/emperror"
+)
+
+// validateZoneWeights checks that the zone weights are not negative and at least one of them is positive
+func validateZoneWeights(weights map[string]float64) error {
+	if len(weights) == 0 {
+		return nil
+	}
+	sum := 0.0
+	for zone, weight := range weights {
+		if weight < 0 || math.IsNaN(weight) || math.IsInf(weight, 0) {
+			return emperror.With(ErrInvalidZoneWeights, RecommenderErrorTag, "zone", zone, "weight", weight)
+		}
+		sum += weight
+	}
+	if sum == 0 {
+		return emperror.With(ErrInvalidZoneWeights, RecommenderErrorTag, "sumWeights", sum)
+	}
+	return nil
+}
+
+// validateRequireAllZones checks that the zone coverage is only required for multi-zone recommendations with zone weights,
+// as the coverage is checked against the weighted zones
+func validateRequireAllZones(req SingleClusterRecommendationReq) error {
+	if !req.RequireAllZones {
+		return nil
+	}
+	if req.Zone != "" || len(req.ZoneWeights) == 0 {
+		return emperror.With(ErrInvalidRequireAllZones, RecommenderErrorTag, "zone", req.Zone, "zoneWeights", len(req.ZoneWeights))
+	}
+	return nil
+}
+
+// applyZoneWeights sets the spot price of the vms to the average of their zone prices weighted by the zone weights;
+// vms without a spot price in any of the weighted zones keep the plain average
+func applyZoneWeights(weights map[string]float64, vms []VirtualMachine) {
+	if len(weights) == 0 {
+		return
+	}
+	for i := range vms {
+		var sum, sumWeights float64
+		for zone, price := range vms[i].SpotPrices {
+			sum += weights[zone] * price
+			sumWeights += weights[zone]
+		}
+		if sumWeights > 0 {
+			vms[i].AvgPrice = sum / sumWeights
+		}
+	}
+}
+
+// distributeZoneNodes splits the nodes of the worker node pools between the weighted zones the pools can be launched
+// in, in proportion to the weights; the remainders go to the zones with the largest fractions
+func distributeZoneNodes(weights map[string]float64, nodePools []NodePool) {
+	if len(weights) == 0 {
+		return
+	}
+	for i, np := range nodePools {
+		if np.Role == Master {
+			continue
+		}
+		if np.SumNodes == 0 {
+			nodePools[i].ZoneNodes = nil
+			continue
+		}
+		zones := make([]string, 0, len(weights))
+		sumWeights := 0.0
+		for zone, weight := range weights {
+			if weight > 0 && np.VmType.launchableIn(zone) && np.expandsTo(zone) {
+				zones = append(zones, zone)
+				sumWeights += weight
+			}
+		}
+		if len(zones) == 0 {
+			continue
+		}
+		sort.Strings(zones)
+
+		nodes := make(map[string]int, len(zones))
+		fractions := make(map[string]float64, len(zones))
+		assigned := 0
+		for _, zone := range zones {
+			exact := float64(np.SumNodes) * weights[zone] / sumWeights
+			nodes[zone] = int(math.Floor(exact))
+			fractions[zone] = exact - math.Floor(exact)
+			assigned += nodes[zone]
+		}
+		sort.SliceStable(zones, func(a, b int) bool { return fractions[zones[a]] > fractions[zones[b]] })
+		for j := 0; assigned < np.SumNodes; j++ {
+			nodes[zones[j%len(zones)]]++
+			assigned++
+		}
+		for zone, n := range nodes {
+			if n == 0 {
+				delete(nodes, zone)
+			}
+		}
+		nodePools[i].ZoneNodes = nodes
+	}
+}
+
+// checkZoneCoverage returns ErrZonesNotCovered if the request requires every weighted zone to be covered, but
+// some of them have no worker nodes
+func checkZoneCoverage(req SingleClusterRecommendationReq, nodePools []NodePool) error {
+	if !req.RequireAllZones {
+		return nil
+	}
+	if zones := uncoveredZones(req.ZoneWeights, nodePools); len(zones) > 0 {
+		return emperror.With(ErrZonesNotCovered, RecommenderErrorTag, "zones", zones)
+	}
+	return nil
+}
+
+// RedistributeZoneNodes splits the worker nodes of the recommendation between the weighted zones of the request
+// again, eg. after response processors changed the node counts; the zone coverage required by the request is
+// checked on the new split
+func RedistributeZoneNodes(req SingleClusterRecommendationReq, resp *ClusterRecommendationResp) error {
+	if req.Zone != "" || len(req.ZoneWeights) == 0 {
+		return nil
+	}
+	distributeZoneNodes(req.ZoneWeights, resp.NodePools)
+	return checkZoneCoverage(req, resp.NodePools)
+}
+
+// uncoveredZones lists the zones weighted positively that none of the worker nodes are placed in, in lexical order
+func uncoveredZones(weights map[string]float64, nodePools []NodePool) []string {
+	var zones []string
+	for zone, weight := range weights {
+		if weight <= 0 {
+			continue
+		}
+		covered := false
+		for _, np := range nodePools {
+			if np.Role != Master && np.ZoneNodes[zone] > 0 {
+				covered = true
+				break
+			}
+		}
+		if !covered {
+			zones = append(zones, zone)
+		}
+	}
+	sort.Strings(zones)
+	return zones
+}
+
+// expandsTo checks whether the node pool expands to the zone, node pools not pinned to zones expand to every zone
+func (n *NodePool) expandsTo(zone string) bool {
+	if len(n.Zones) == 0 {
+		return true
+	}
+	for _, z := range n.Zones {
+		if z == zone {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,113 @@
+// Copyright © 2019 Banzai Cloud
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package recommender
+
+import (
+	"testing"
+
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestValidateZoneWeights(t *testing.T) {
+	assert.NoError(t, validateZoneWeights(nil))
+	assert.NoError(t, validateZoneWeights(map[string]float64{"eu-west-1a": 0.5, "eu-west-1b": 0}))
+	assert.Equal(t, ErrInvalidZoneWeights, errors.Cause(validateZoneWeights(map[string]float64{"eu-west-1a": -0.5})))
+	assert.Equal(t, ErrInvalidZoneWeights, errors.Cause(validateZoneWeights(map[string]float64{"eu-west-1a": 0})))
+}
+
+func TestValidateRequireAllZones(t *testing.T) {
+	weights := map[string]float64{"eu-west-1a": 0.5, "eu-west-1b": 0.5}
+	req := func(requireAll bool, zone string, weights map[string]float64) SingleClusterRecommendationReq {
+		return SingleClusterRecommendationReq{Zone: zone, ZoneWeights: weights, RequireAllZones: requireAll}
+	}
+
+	assert.NoError(t, validateRequireAllZones(req(false, "eu-west-1a", nil)))
+	assert.NoError(t, validateRequireAllZones(req(true, "", weights)))
+	assert.Equal(t, ErrInvalidRequireAllZones, errors.Cause(validateRequireAllZones(req(true, "", nil))), "without zone weights")
+	assert.Equal(t, ErrInvalidRequireAllZones, errors.Cause(validateRequireAllZones(req(true, "eu-west-1a", weights))), "single zone")
+}
+
+func TestApplyZoneWeights(t *testing.T) {
+	vms := []VirtualMachine{
+		{Type: "m5.xlarge", AvgPrice: 0.06, SpotPrices: map[string]float64{"eu-west-1a": 0.04, "eu-west-1b": 0.08}},
+		{Type: "r5.xlarge", AvgPrice: 0.05, SpotPrices: map[string]float64{"eu-west-1c": 0.05}},
+	}
+	applyZoneWeights(map[string]float64{"eu-west-1a": 0.75, "eu-west-1b": 0.25}, vms)
+
+	assert.InDelta(t, 0.05, vms[0].AvgPrice, 0.0001, "the spot price should be weighted by the zones")
+	assert.Equal(t, 0.05, vms[1].AvgPrice, "vms not priced in the weighted zones should keep the average")
+}
+
+func TestDistributeZoneNodes(t *testing.T) {
+	weights := map[string]float64{"eu-west-1a": 0.5, "eu-west-1b": 0.3, "eu-west-1c": 0.2}
+	nodePools := []NodePool{
+		{VmType: VirtualMachine{Type: "m5.xlarge"}, SumNodes: 10},
+		{VmType: VirtualMachine{Type: "c5.xlarge"}, SumNodes: 3},
+		{VmType: VirtualMachine{Type: "p3.2xlarge", Zones: []string{"eu-west-1a", "eu-west-1c"}}, SumNodes: 7},
+		{VmType: VirtualMachine{Type: "r5.xlarge"}, SumNodes: 4, Zones: []string{"eu-west-1b"}},
+		{VmType: VirtualMachine{Type: "m5.large"}, SumNodes: 0},
+	}
+	distributeZoneNodes(weights, nodePools)
+
+	assert.Equal(t, map[string]int{"eu-west-1a": 5, "eu-west-1b": 3, "eu-west-1c": 2}, nodePools[0].ZoneNodes)
+	assert.Equal(t, map[string]int{"eu-west-1a": 1, "eu-west-1b": 1, "eu-west-1c": 1}, nodePools[1].ZoneNodes,
+		"the remainders should go to the largest fractions")
+	assert.Equal(t, map[string]int{"eu-west-1a": 5, "eu-west-1c": 2}, nodePools[2].ZoneNodes,
+		"the nodes should be split between the zones the vm is launchable in")
+	assert.Equal(t, map[string]int{"eu-west-1b": 4}, nodePools[3].ZoneNodes, "pinned node pools should keep their zones")
+	assert.Nil(t, nodePools[4].ZoneNodes)
+}
+
+func TestUncoveredZones(t *testing.T) {
+	weights := map[string]float64{"eu-west-1a": 0.5, "eu-west-1b": 0.3, "eu-west-1c": 0.2, "eu-west-1d": 0}
+	nodePools := []NodePool{
+		{VmType: VirtualMachine{Type: "p3.2xlarge", Zones: []string{"eu-west-1a", "eu-west-1b"}}, SumNodes: 2, Role: Worker},
+		{VmType: VirtualMachine{Type: "m5.large"}, SumNodes: 3, Role: Master},
+	}
+	distributeZoneNodes(weights, nodePools)
+	assert.Equal(t, []string{"eu-west-1c"}, uncoveredZones(weights, nodePools),
+		"the master nodes and the zones weighted zero should not count")
+
+	nodePools = append(nodePools, NodePool{VmType: VirtualMachine{Type: "m5.xlarge"}, SumNodes: 10, Role: Worker})
+	distributeZoneNodes(weights, nodePools)
+	assert.Empty(t, uncoveredZones(weights, nodePools))
+	assert.Empty(t, uncoveredZones(nil, nodePools))
+}
+
+func TestRedistributeZoneNodes(t *testing.T) {
+	req := SingleClusterRecommendationReq{
+		ZoneWeights:     map[string]float64{"eu-west-1a": 0.6, "eu-west-1b": 0.4},
+		RequireAllZones: true,
+	}
+	resp := &ClusterRecommendationResp{NodePools: []NodePool{
+		{VmType: VirtualMachine{Type: "m5.xlarge"}, SumNodes: 1, Role: Worker},
+		{VmType: VirtualMachine{Type: "m5.large"}, SumNodes: 1, Role: Master},
+	}}
+	assert.Equal(t, ErrZonesNotCovered, errors.Cause(RedistributeZoneNodes(req, resp)), "a single node can't cover two zones")
+
+	// eg. a response processor rounding up the node counts
+	resp.NodePools[0].SumNodes = 2
+	assert.Nil(t, RedistributeZoneNodes(req, resp))
+	assert.Equal(t, map[string]int{"eu-west-1a": 1, "eu-west-1b": 1}, resp.NodePools[0].ZoneNodes,
+		"the split should add up to the new node count")
+	assert.Nil(t, resp.NodePools[1].ZoneNodes, "the master nodes should not be split")
+
+	req.Zone = "eu-west-1a"
+	resp.NodePools[0].SumNodes = 4
+	assert.Nil(t, RedistributeZoneNodes(req, resp))
+	assert.Equal(t, map[string]int{"eu-west-1a": 1, "eu-west-1b": 1}, resp.NodePools[0].ZoneNodes,
+		"single zone recommendations should not be split")
+}